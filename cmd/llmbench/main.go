@@ -0,0 +1,171 @@
+// Command llmbench measures the latency and throughput of LLM providers. It
+// sends synthetic prompts of the given sizes at the given concurrencies to
+// each target and reports p50/p95/p99 latency, output tokens per second and
+// error rates.
+//
+// Targets are given as provider[:model][@region], e.g.
+//
+//	llmbench -targets openai:gpt-4o-mini,anthropic:claude-3-5-haiku-latest,vertex:gemini-1.5-flash@europe-west4 \
+//	  -prompt-tokens 200,4000 -concurrency 1,8 -requests 40
+//
+// Credentials and defaults are read from the environment, see
+// docs/environment_variables.md. The region applies to Vertex AI.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/run-bigpig/llm-agent/pkg/bench"
+	"github.com/run-bigpig/llm-agent/pkg/config"
+	"github.com/run-bigpig/llm-agent/pkg/llm/provider"
+)
+
+func main() {
+	targets := flag.String("targets", "", "comma-separated provider[:model][@region] targets; defaults to the configured provider")
+	promptTokens := flag.String("prompt-tokens", "200,2000", "comma-separated prompt sizes in tokens")
+	concurrency := flag.String("concurrency", "1,4", "comma-separated numbers of concurrent requests")
+	requests := flag.Int("requests", 20, "requests per target and workload")
+	outputWords := flag.Int("output-words", 100, "approximate answer length in words")
+	timeout := flag.Duration("timeout", 2*time.Minute, "timeout of each request")
+	format := flag.String("format", "table", "output format: table or json")
+	flag.Parse()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	benchTargets, err := buildTargets(ctx, *targets)
+	if err != nil {
+		log.Fatal(err)
+	}
+	workloads, err := buildWorkloads(*promptTokens, *concurrency, *requests, *outputWords, *timeout)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	runner := bench.NewRunner(bench.WithProgress(func(target bench.Target, workload bench.Workload, done int) {
+		fmt.Fprintf(os.Stderr, "\r%s %s: %d/%d", target.Name, workload.Name(), done, workload.Requests)
+		if done == workload.Requests {
+			fmt.Fprintln(os.Stderr)
+		}
+	}))
+	results, err := runner.Run(ctx, benchTargets, workloads)
+	if err != nil {
+		log.Printf("Benchmark stopped: %v", err)
+	}
+
+	if *format == "json" {
+		err = bench.WriteJSON(os.Stdout, results)
+	} else {
+		err = bench.WriteTable(os.Stdout, results)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// buildTargets creates an LLM client for each target
+func buildTargets(ctx context.Context, spec string) ([]bench.Target, error) {
+	if spec == "" {
+		llm, err := provider.FromConfig(ctx, nil)
+		if err != nil {
+			return nil, err
+		}
+		return []bench.Target{{Name: llm.Name(), LLM: llm}}, nil
+	}
+
+	var targets []bench.Target
+	for _, item := range splitList(spec) {
+		cfg := *config.Get()
+		name, region, _ := strings.Cut(item, "@")
+		providerName, model, _ := strings.Cut(name, ":")
+		cfg.LLM.Provider = providerName
+		switch providerName {
+		case provider.OpenAI:
+			if model != "" {
+				cfg.LLM.OpenAI.Model = model
+			}
+		case provider.Anthropic:
+			if model != "" {
+				cfg.LLM.Anthropic.Model = model
+			}
+		case provider.Vertex:
+			if model != "" {
+				cfg.LLM.Vertex.Model = model
+			}
+			if region != "" {
+				cfg.LLM.Vertex.Location = region
+			}
+		}
+		if region != "" && providerName != provider.Vertex {
+			return nil, fmt.Errorf("target %s: regions are only supported for vertex", item)
+		}
+
+		llm, err := provider.FromConfig(ctx, &cfg)
+		if err != nil {
+			return nil, fmt.Errorf("target %s: %w", item, err)
+		}
+		targets = append(targets, bench.Target{Name: item, LLM: llm})
+	}
+	return targets, nil
+}
+
+// buildWorkloads creates a workload for each prompt size and concurrency
+func buildWorkloads(promptTokens, concurrency string, requests, outputWords int, timeout time.Duration) ([]bench.Workload, error) {
+	sizes, err := parseInts(promptTokens)
+	if err != nil {
+		return nil, fmt.Errorf("invalid -prompt-tokens: %w", err)
+	}
+	levels, err := parseInts(concurrency)
+	if err != nil {
+		return nil, fmt.Errorf("invalid -concurrency: %w", err)
+	}
+
+	var workloads []bench.Workload
+	for _, size := range sizes {
+		for _, level := range levels {
+			workloads = append(workloads, bench.Workload{
+				PromptTokens: size,
+				OutputWords:  outputWords,
+				Concurrency:  level,
+				Requests:     requests,
+				Timeout:      timeout,
+			})
+		}
+	}
+	return workloads, nil
+}
+
+// parseInts parses a comma-separated list of positive integers
+func parseInts(list string) ([]int, error) {
+	var values []int
+	for _, item := range splitList(list) {
+		value, err := strconv.Atoi(item)
+		if err != nil || value <= 0 {
+			return nil, fmt.Errorf("%q is not a positive integer", item)
+		}
+		values = append(values, value)
+	}
+	if len(values) == 0 {
+		return nil, fmt.Errorf("no values given")
+	}
+	return values, nil
+}
+
+// splitList splits a comma-separated list, dropping empty items
+func splitList(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,131 @@
+package testsupport
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/go-redis/redis/v8"
+
+	// lib/pq registers the "postgres" driver
+	_ "github.com/lib/pq"
+)
+
+// Images of the services, matching the versions the storage backends are
+// tested against
+const (
+	WeaviateImage = "semitechnologies/weaviate:1.31.2"
+	RedisImage    = "redis:7-alpine"
+	PostgresImage = "postgres:16-alpine"
+)
+
+// Weaviate starts a Weaviate server without vectorizer modules or
+// authentication and returns its host:port, for interfaces.VectorStoreConfig
+// with the "http" scheme. WEAVIATE_TEST_HOST points to an existing server.
+func Weaviate(t testing.TB) string {
+	t.Helper()
+	if host, ok := fromEnv("WEAVIATE_TEST_HOST"); ok {
+		return host
+	}
+	container := StartContainer(t, ContainerSpec{
+		Image: WeaviateImage,
+		Port:  "8080",
+		Env: map[string]string{
+			"AUTHENTICATION_ANONYMOUS_ACCESS_ENABLED": "true",
+			"DEFAULT_VECTORIZER_MODULE":               "none",
+			"PERSISTENCE_DATA_PATH":                   "/var/lib/weaviate",
+			"CLUSTER_HOSTNAME":                        "node1",
+		},
+		Ready: func(ctx context.Context, addr string) error {
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://"+addr+"/v1/.well-known/ready", nil)
+			if err != nil {
+				return err
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				return err
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				return fmt.Errorf("status %d", resp.StatusCode)
+			}
+			return nil
+		},
+	})
+	return container.Addr
+}
+
+// Redis starts a Redis server and returns a client for it, closed when the
+// test finishes. REDIS_TEST_ADDR points to an existing server; tests sharing
+// it should use distinct key prefixes.
+func Redis(t testing.TB) *redis.Client {
+	t.Helper()
+	addr, ok := fromEnv("REDIS_TEST_ADDR")
+	if !ok {
+		addr = StartContainer(t, ContainerSpec{
+			Image: RedisImage,
+			Port:  "6379",
+			Ready: func(ctx context.Context, addr string) error {
+				client := redis.NewClient(&redis.Options{Addr: addr})
+				defer client.Close()
+				return client.Ping(ctx).Err()
+			},
+		}).Addr
+	}
+
+	client := redis.NewClient(&redis.Options{Addr: addr})
+	t.Cleanup(func() {
+		_ = client.Close()
+	})
+	return client
+}
+
+// Postgres starts a Postgres server and returns the DSN of an empty
+// database, for stores built on database/sql with the lib/pq driver.
+// POSTGRES_TEST_DSN points to an existing database.
+func Postgres(t testing.TB) string {
+	t.Helper()
+	if dsn, ok := fromEnv("POSTGRES_TEST_DSN"); ok {
+		return dsn
+	}
+	dsn := func(addr string) string {
+		return fmt.Sprintf("postgres://test:test@%s/test?sslmode=disable", addr)
+	}
+	container := StartContainer(t, ContainerSpec{
+		Image: PostgresImage,
+		Port:  "5432",
+		Env: map[string]string{
+			"POSTGRES_USER":     "test",
+			"POSTGRES_PASSWORD": "test",
+			"POSTGRES_DB":       "test",
+		},
+		Ready: func(ctx context.Context, addr string) error {
+			if err := dialReady(ctx, addr); err != nil {
+				return err
+			}
+			db, err := sql.Open("postgres", dsn(addr))
+			if err != nil {
+				return err
+			}
+			defer db.Close()
+			return db.PingContext(ctx)
+		},
+	})
+	return dsn(container.Addr)
+}
+
+// PostgresDB starts a Postgres server like Postgres and returns an open
+// connection pool, closed when the test finishes
+func PostgresDB(t testing.TB) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("postgres", Postgres(t))
+	if err != nil {
+		t.Fatalf("failed to open Postgres: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = db.Close()
+	})
+	return db
+}
@@ -0,0 +1,180 @@
+// Package testsupport starts the services that storage backends depend on,
+// like Weaviate, Redis and Postgres, in Docker containers for integration
+// tests. Integration tests are kept out of normal test runs with the
+// integration build tag:
+//
+//	//go:build integration
+//
+// and run with:
+//
+//	go test -tags integration ./...
+//
+// Tests are skipped when Docker is not available. To use services that are
+// already running, e.g. CI service containers, set WEAVIATE_TEST_HOST,
+// REDIS_TEST_ADDR or POSTGRES_TEST_DSN.
+package testsupport
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// DefaultStartTimeout is how long a container may take to become ready
+const DefaultStartTimeout = 2 * time.Minute
+
+// ContainerSpec describes a container to start
+type ContainerSpec struct {
+	// Image is the image to run, e.g. "redis:7-alpine"
+	Image string
+
+	// Port is the container port to publish, e.g. "6379"
+	Port string
+
+	// Env are environment variables of the container
+	Env map[string]string
+
+	// Args are passed to the image's entrypoint
+	Args []string
+
+	// Ready returns nil once the service at addr accepts requests
+	Ready func(ctx context.Context, addr string) error
+
+	// StartTimeout limits how long to wait for Ready; defaults to
+	// DefaultStartTimeout
+	StartTimeout time.Duration
+}
+
+// Container is a running container
+type Container struct {
+	// ID is the Docker container ID
+	ID string
+
+	// Addr is the host:port the container port is published on
+	Addr string
+}
+
+var (
+	dockerOnce sync.Once
+	dockerErr  error
+)
+
+// RequireDocker skips the test if the Docker CLI or daemon is not available
+func RequireDocker(t testing.TB) {
+	t.Helper()
+	dockerOnce.Do(func() {
+		if _, err := exec.LookPath("docker"); err != nil {
+			dockerErr = err
+			return
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		_, dockerErr = docker(ctx, "info", "--format", "{{.ServerVersion}}")
+	})
+	if dockerErr != nil {
+		t.Skipf("Docker is not available: %v", dockerErr)
+	}
+}
+
+// StartContainer starts a container, waits until it is ready and removes it
+// when the test finishes. The test is skipped if Docker is not available.
+func StartContainer(t testing.TB, spec ContainerSpec) *Container {
+	t.Helper()
+	RequireDocker(t)
+
+	timeout := spec.StartTimeout
+	if timeout <= 0 {
+		timeout = DefaultStartTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	args := []string{"run", "--detach", "--rm", "--publish", "127.0.0.1::" + spec.Port}
+	for key, value := range spec.Env {
+		args = append(args, "--env", key+"="+value)
+	}
+	args = append(args, spec.Image)
+	args = append(args, spec.Args...)
+
+	id, err := docker(ctx, args...)
+	if err != nil {
+		t.Fatalf("failed to start %s: %v", spec.Image, err)
+	}
+	container := &Container{ID: id}
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if _, err := docker(ctx, "rm", "--force", "--volumes", id); err != nil {
+			t.Logf("failed to remove container %s: %v", id, err)
+		}
+	})
+
+	ports, err := docker(ctx, "port", id, spec.Port+"/tcp")
+	if err != nil {
+		t.Fatalf("failed to get the published port of %s: %v", spec.Image, err)
+	}
+	container.Addr = strings.TrimSpace(strings.SplitN(ports, "\n", 2)[0])
+
+	if spec.Ready != nil {
+		if err := waitReady(ctx, container.Addr, spec.Ready); err != nil {
+			logs, _ := docker(context.Background(), "logs", "--tail", "50", id)
+			t.Fatalf("%s did not become ready: %v\n%s", spec.Image, err, logs)
+		}
+	}
+	return container
+}
+
+// waitReady polls ready until it succeeds or the context ends
+func waitReady(ctx context.Context, addr string, ready func(ctx context.Context, addr string) error) error {
+	ticker := time.NewTicker(250 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		attemptCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		err := ready(attemptCtx, addr)
+		cancel()
+		if err == nil {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%w (last error: %v)", ctx.Err(), err)
+		case <-ticker.C:
+		}
+	}
+}
+
+// docker runs a Docker CLI command and returns its trimmed output
+func docker(ctx context.Context, args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "docker", args...) // #nosec G204 - arguments come from test code
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("docker %s: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// dialReady reports whether a TCP connection to addr can be opened
+func dialReady(ctx context.Context, addr string) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// fromEnv returns the value of an environment variable that points to an
+// existing service
+func fromEnv(name string) (string, bool) {
+	value := os.Getenv(name)
+	return value, value != ""
+}
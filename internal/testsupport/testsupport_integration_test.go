@@ -0,0 +1,36 @@
+//go:build integration
+
+package testsupport_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/run-bigpig/llm-agent/internal/testsupport"
+)
+
+func TestPostgres(t *testing.T) {
+	db := testsupport.PostgresDB(t)
+	ctx := context.Background()
+
+	if _, err := db.ExecContext(ctx, `CREATE TABLE items (id SERIAL PRIMARY KEY, name TEXT NOT NULL)`); err != nil {
+		t.Fatalf("failed to create table: %v", err)
+	}
+	if _, err := db.ExecContext(ctx, `INSERT INTO items (name) VALUES ($1)`, "first"); err != nil {
+		t.Fatalf("failed to insert: %v", err)
+	}
+	var name string
+	if err := db.QueryRowContext(ctx, `SELECT name FROM items WHERE id = 1`).Scan(&name); err != nil || name != "first" {
+		t.Fatalf("expected to read the row back, got %q (%v)", name, err)
+	}
+}
+
+func TestRedis(t *testing.T) {
+	client := testsupport.Redis(t)
+	if err := client.Set(context.Background(), "key", "value", 0).Err(); err != nil {
+		t.Fatalf("failed to set key: %v", err)
+	}
+	if value, err := client.Get(context.Background(), "key").Result(); err != nil || value != "value" {
+		t.Fatalf("expected to read the key back, got %q (%v)", value, err)
+	}
+}
@@ -2,15 +2,31 @@ package agent
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
-
+	"sync"
+	"time"
+
+	"github.com/run-bigpig/llm-agent/pkg/approval"
+	"github.com/run-bigpig/llm-agent/pkg/artifact"
+	"github.com/run-bigpig/llm-agent/pkg/classification"
+	"github.com/run-bigpig/llm-agent/pkg/debug"
+	"github.com/run-bigpig/llm-agent/pkg/events"
 	"github.com/run-bigpig/llm-agent/pkg/executionplan"
+	"github.com/run-bigpig/llm-agent/pkg/feedback"
 	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+	"github.com/run-bigpig/llm-agent/pkg/lease"
+	"github.com/run-bigpig/llm-agent/pkg/lifecycle"
 	"github.com/run-bigpig/llm-agent/pkg/llm/openai"
 	"github.com/run-bigpig/llm-agent/pkg/mcp"
+	"github.com/run-bigpig/llm-agent/pkg/memory"
 	"github.com/run-bigpig/llm-agent/pkg/multitenancy"
+	"github.com/run-bigpig/llm-agent/pkg/prompts"
+	"github.com/run-bigpig/llm-agent/pkg/rbac"
+	"github.com/run-bigpig/llm-agent/pkg/runctx"
 )
 
 // Agent represents an AI agent
@@ -21,7 +37,7 @@ type Agent struct {
 	orgID                string
 	tracer               interfaces.Tracer
 	guardrails           interfaces.Guardrails
-	systemPrompt         string
+	systemPrompt         *prompts.SystemPrompt    // Sections of the system prompt
 	name                 string                   // Name of the agent, e.g., "PlatformOps", "Math", "Research"
 	requirePlanApproval  bool                     // New field to control whether execution plans require approval
 	planStore            *executionplan.Store     // Store for execution plans
@@ -31,7 +47,51 @@ type Agent struct {
 	generatedTaskConfigs TaskConfigs
 	responseFormat       *interfaces.ResponseFormat // Response format for the agent
 	llmConfig            *interfaces.LLMConfig
-	mcpServers           []interfaces.MCPServer // MCP servers for the agent
+	mcpServers           []interfaces.MCPServer     // MCP servers for the agent
+	toolSelector         interfaces.ToolSelector    // Selects the tools relevant to each query
+	runEventHandler      RunEventHandler            // Receives tool call and run events
+	lastReport           *RunReport                 // Report of the most recent run
+	debugRecorder        *debug.Recorder            // Records run transcripts for debugging
+	lifecycle            *lifecycle.Manager         // Tracks in-flight runs for graceful shutdown
+	toolResults          *memory.ToolResultStore    // Reuses tool results across turns
+	artifactStore        artifact.Store             // Stores task output files
+	requiredCapabilities interfaces.Capabilities    // Capabilities the LLM must support
+	promptJSON           bool                       // Request the response format in the prompt
+	authorizer           interfaces.Authorizer      // Decides who may run the agent and its tools
+	approvals            *approval.Queue            // Queues execution plans for review
+	planObserver         executionplan.Observer     // Observes execution plan steps, e.g. for tracing
+	planHistory          executionplan.HistoryStore // Records executed plans
+	planOutbox           executionplan.Outbox       // Records calls of side-effecting tools in plans
+	outboxTools          []string                   // Tools whose calls are recorded in the outbox
+	planReconciler       executionplan.Reconciler   // Reconciles unconfirmed side effects on resume
+	checkpoints          CheckpointStore            // Saves a checkpoint before every run
+	leases               *lease.Manager             // Leases conversations to one replica at a time
+	reportMu             sync.RWMutex
+	preparedMu           sync.RWMutex
+	preparedSystemPrompt string                      // System prompt rendered by Prepare
+	systemPromptPrepared bool                        // Whether Prepare rendered the system prompt
+	toolExamplesInPrompt bool                        // Add tool examples to the system prompt
+	toolSanitizer        interfaces.ContentSanitizer // Sanitizes tool results
+	responseLanguage     string                      // Language all responses must be in
+	detectLanguage       bool                        // Answer in the language of the input
+	mcpCacheOptions      []mcp.CacheOption           // Configure the MCP tool list caches
+	classifier           classification.Classifier   // Classifies each input
+	classificationSinks  []classification.Sink       // Receive input classifications
+	feedback             *feedback.Recorder          // Records user feedback on responses
+	semanticCache        *memory.SemanticCache       // Answers queries similar to earlier ones
+	toolResultLimits     map[string]ToolResultLimit  // Per-tool limits on result size
+	defaultResultLimit   ToolResultLimit             // Limit for tools without their own
+	countToolTokens      func(text string) int       // Measures tool results against their limits
+	eventPublisher       events.Publisher            // Publishes tool calls, runs and audit records
+	dryRun               bool                        // Simulate side effects of every run
+	sideEffectTools      []string                    // Tools that change external systems
+	version              string                      // Version of the agent's definition
+	resultSpill          ToolResultSpill             // Saves large tool results as artifacts
+	spillStore           artifact.Store              // Stores spilled results without an artifact store
+	toolFailureWindow    time.Duration               // Tells the LLM about tools that failed this recently
+	conversationTitles   ConversationTitles          // Generates conversation titles and topics
+	followUpQuestions    FollowUpQuestions           // Suggests questions to ask next
+	promptCompression    *PromptCompression          // Compresses long history and tool results
 }
 
 // Option represents an option for configuring an agent
@@ -79,8 +139,34 @@ func WithGuardrails(guardrails interfaces.Guardrails) Option {
 	}
 }
 
-// WithSystemPrompt sets the system prompt for the agent
+// WithSystemPrompt sets the system prompt for the agent. It is the
+// instructions section of the prompt, see WithSystemPromptSection.
 func WithSystemPrompt(prompt string) Option {
+	return func(a *Agent) {
+		a.promptSections().Set(prompts.Section{Name: prompts.SectionInstructions, Content: prompt})
+	}
+}
+
+// WithSystemPromptSection adds a section to the system prompt, or replaces the
+// section of the same name, e.g. prompts.SectionSafety
+func WithSystemPromptSection(section prompts.Section) Option {
+	return func(a *Agent) {
+		a.promptSections().Set(section)
+	}
+}
+
+// WithTenantSystemPromptSection replaces a section of the system prompt for
+// runs of one organization
+func WithTenantSystemPromptSection(orgID string, section prompts.Section) Option {
+	return func(a *Agent) {
+		a.promptSections().SetOverride(orgID, section)
+	}
+}
+
+// WithSystemPromptBuilder composes the system prompt from the sections of a
+// prompts.SystemPrompt, which can be shared by several agents. Pass it before
+// the options that set sections, which add them to it.
+func WithSystemPromptBuilder(prompt *prompts.SystemPrompt) Option {
 	return func(a *Agent) {
 		a.systemPrompt = prompt
 	}
@@ -104,7 +190,7 @@ func WithName(name string) Option {
 func WithAgentConfig(config AgentConfig, variables map[string]string) Option {
 	return func(a *Agent) {
 		systemPrompt := FormatSystemPromptFromConfig(config, variables)
-		a.systemPrompt = systemPrompt
+		a.promptSections().Set(prompts.Section{Name: prompts.SectionInstructions, Content: systemPrompt})
 	}
 }
 
@@ -124,14 +210,129 @@ func WithLLMConfig(config interfaces.LLMConfig) Option {
 // WithMCPServers sets the MCP servers for the agent
 func WithMCPServers(mcpServers []interfaces.MCPServer) Option {
 	return func(a *Agent) {
-		a.mcpServers = mcpServers
+		a.mcpServers = append([]interfaces.MCPServer(nil), mcpServers...)
+	}
+}
+
+// WithMCPToolsTTL sets how long the tool lists of MCP servers are reused
+// before they are refreshed in the background. Zero keeps them until
+// InvalidateMCPTools is called. Defaults to 5 minutes.
+func WithMCPToolsTTL(ttl time.Duration) Option {
+	return func(a *Agent) {
+		a.mcpCacheOptions = append(a.mcpCacheOptions, mcp.WithToolsTTL(ttl))
+	}
+}
+
+// WithToolSelector sets a selector that narrows the tools passed to the LLM to those relevant to the input
+func WithToolSelector(selector interfaces.ToolSelector) Option {
+	return func(a *Agent) {
+		a.toolSelector = selector
+	}
+}
+
+// WithRunEventHandler sets a handler that receives tool call and run events as they happen
+func WithRunEventHandler(handler RunEventHandler) Option {
+	return func(a *Agent) {
+		a.runEventHandler = handler
+	}
+}
+
+// WithDebugRecorder records a transcript of every run (prompts, tool calls,
+// raw responses and timings) for debugging
+func WithDebugRecorder(recorder *debug.Recorder) Option {
+	return func(a *Agent) {
+		a.debugRecorder = recorder
+	}
+}
+
+// WithLifecycle tracks runs in the lifecycle manager so that shutdown waits
+// for them to finish. Runs started after shutdown has begun are rejected, and
+// runs still in flight at the shutdown deadline are cancelled.
+func WithLifecycle(manager *lifecycle.Manager) Option {
+	return func(a *Agent) {
+		a.lifecycle = manager
+	}
+}
+
+// WithToolResultStore reuses the results of identical tool calls within a
+// conversation until they go stale
+func WithToolResultStore(store *memory.ToolResultStore) Option {
+	return func(a *Agent) {
+		a.toolResults = store
+	}
+}
+
+// WithArtifactStore writes task output files to the store instead of the
+// local filesystem and lets tools exchange artifacts through it
+func WithArtifactStore(store artifact.Store) Option {
+	return func(a *Agent) {
+		a.artifactStore = store
+	}
+}
+
+// WithRequiredCapabilities makes NewAgent fail if the LLM does not support
+// the capabilities, e.g. interfaces.Capabilities{Vision: true}
+func WithRequiredCapabilities(required interfaces.Capabilities) Option {
+	return func(a *Agent) {
+		a.requiredCapabilities = required
+	}
+}
+
+// WithAuthorizer checks every run and tool call against the authorizer, using
+// the identity in the context. Tools the caller may not use are not offered to the LLM.
+func WithAuthorizer(authorizer interfaces.Authorizer) Option {
+	return func(a *Agent) {
+		a.authorizer = authorizer
+	}
+}
+
+// WithApprovalQueue submits execution plans to the queue, which notifies
+// reviewers. Approved plans are executed by the agent when the decision is made.
+func WithApprovalQueue(queue *approval.Queue) Option {
+	return func(a *Agent) {
+		a.approvals = queue
+	}
+}
+
+// WithPlanObserver reports the execution of plans and their steps to the
+// observer, e.g. a *tracing.LangfuseTracer
+func WithPlanObserver(observer executionplan.Observer) Option {
+	return func(a *Agent) {
+		a.planObserver = observer
+	}
+}
+
+// WithPlanHistory records every executed or rejected plan, with its step
+// outputs and approval, in the store. Use GetRunHistory to query it.
+func WithPlanHistory(store executionplan.HistoryStore) Option {
+	return func(a *Agent) {
+		a.planHistory = store
+	}
+}
+
+// WithOutbox records the calls of the named side-effecting tools in plans in
+// the outbox, so that a plan executed again after a crash does not repeat
+// confirmed calls and reconciles unconfirmed ones
+func WithOutbox(outbox executionplan.Outbox, toolNames ...string) Option {
+	return func(a *Agent) {
+		a.planOutbox = outbox
+		a.outboxTools = toolNames
+	}
+}
+
+// WithOutboxReconciler sets how side effects that were recorded but never
+// confirmed are handled when a plan is resumed
+func WithOutboxReconciler(reconciler executionplan.Reconciler) Option {
+	return func(a *Agent) {
+		a.planReconciler = reconciler
 	}
 }
 
 // NewAgent creates a new agent with the given options
 func NewAgent(options ...Option) (*Agent, error) {
 	agent := &Agent{
-		requirePlanApproval: true, // Default to requiring approval
+		requirePlanApproval:  true, // Default to requiring approval
+		toolExamplesInPrompt: true,
 	}
 
 	for _, option := range options {
@@ -143,10 +344,46 @@ func NewAgent(options ...Option) (*Agent, error) {
 		return nil, fmt.Errorf("LLM is required")
 	}
 
+	if err := agent.checkCapabilities(); err != nil {
+		return nil, err
+	}
+
+	// Save large tool results instead of adding them to the conversation
+	agent.setupResultSpill()
+
+	// Publish run events along with any run event handler
+	agent.publishRunEvents()
+
+	// Cache the tool lists of MCP servers instead of listing them on every run
+	for i, server := range agent.mcpServers {
+		if _, ok := server.(*mcp.CachedServer); !ok {
+			agent.mcpServers[i] = mcp.NewCachedServer(server, agent.mcpCacheOptions...)
+		}
+	}
+
 	// Initialize execution plan components
 	agent.planStore = executionplan.NewStore()
-	agent.planGenerator = executionplan.NewGenerator(agent.llm, agent.tools, agent.systemPrompt)
-	agent.planExecutor = executionplan.NewExecutor(agent.tools)
+	agent.planGenerator = executionplan.NewGenerator(agent.llm, agent.tools, agent.baseSystemPrompt())
+	var executorOptions []executionplan.ExecutorOption
+	if agent.planObserver != nil {
+		executorOptions = append(executorOptions, executionplan.WithObserver(agent.planObserver))
+	}
+	if agent.planHistory != nil {
+		executorOptions = append(executorOptions, executionplan.WithHistory(agent.planHistory, agent.name))
+	}
+	if agent.planOutbox != nil {
+		executorOptions = append(executorOptions, executionplan.WithOutbox(agent.planOutbox, agent.outboxTools...))
+	}
+	if agent.planReconciler != nil {
+		executorOptions = append(executorOptions, executionplan.WithReconciler(agent.planReconciler))
+	}
+	if len(agent.sideEffectTools) > 0 {
+		executorOptions = append(executorOptions, executionplan.WithSideEffects(agent.sideEffectTools...))
+	}
+	agent.planExecutor = executionplan.NewExecutor(agent.authorizeTools(agent.sanitizeTools(agent.spillToolResults(agent.tools))), executorOptions...)
+	if agent.approvals != nil {
+		agent.approvals.Handle(agent.name, agent.handleApprovalDecision)
+	}
 
 	return agent, nil
 }
@@ -167,9 +404,9 @@ func NewAgentWithAutoConfig(ctx context.Context, options ...Option) (*Agent, err
 
 	// If the system prompt is provided but no configuration was explicitly set,
 	// generate configuration using the LLM
-	if agent.systemPrompt != "" {
+	if agent.baseSystemPrompt() != "" {
 		// Generate agent and task configurations from the system prompt
-		agentConfig, taskConfigs, err := GenerateConfigFromSystemPrompt(ctx, agent.llm, agent.systemPrompt)
+		agentConfig, taskConfigs, err := GenerateConfigFromSystemPrompt(ctx, agent.llm, agent.baseSystemPrompt())
 		if err != nil {
 			// If we fail to generate configs, just continue with the manual system prompt
 			// We don't want to fail agent creation just because auto-config failed
@@ -221,11 +458,129 @@ func CreateAgentForTask(taskName string, agentConfigs AgentConfigs, taskConfigs
 
 // Run runs the agent with the given input
 func (a *Agent) Run(ctx context.Context, input string) (string, error) {
+	response, _, err := a.RunWithReport(ctx, input)
+	return response, err
+}
+
+// RunWithReport runs the agent with the given input and returns a report of
+// the tools that were called during the run
+func (a *Agent) RunWithReport(ctx context.Context, input string) (string, *RunReport, error) {
+	if a.lifecycle != nil {
+		done, err := a.lifecycle.Begin("agent:" + a.name)
+		if err != nil {
+			return "", nil, err
+		}
+		defer done()
+
+		// Abort the run if it's still going at the shutdown deadline
+		var cancel context.CancelFunc
+		ctx, cancel = a.lifecycle.Bind(ctx)
+		defer cancel()
+	}
+
+	// Attribute the run to the agent's version in logs, traces and events
+	if a.version != "" {
+		ctx = runctx.WithTag(ctx, VersionTag, a.version)
+	}
+
+	report := newRunReport(input)
+	report.Version = a.version
+
+	var transcript *debug.Transcript
+	if a.debugRecorder != nil {
+		transcript = a.debugRecorder.Start(a.name, input)
+		ctx = debug.WithTranscript(ctx, transcript)
+	}
+
+	// Add up the token usage the LLM clients report, passing it on to the
+	// caller's usage callback
+	runCtx := interfaces.WithUsageCallback(ctx, func(usage interfaces.Usage) {
+		report.addUsage(usage)
+		interfaces.ReportUsage(ctx, usage)
+	})
+
+	// Pass the reasoning of LLMs with extended thinking on as events
+	runCtx = interfaces.WithThinkingCallback(runCtx, func(thinking string) {
+		a.emitRunEvent(runCtx, RunEvent{Type: RunEventThinking, Timestamp: time.Now(), Content: thinking})
+		interfaces.ReportThinking(ctx, thinking)
+	})
+
+	// Collect the sources of grounded responses
+	runCtx = interfaces.WithCitationsCallback(runCtx, func(citations []interfaces.Citation) {
+		report.addCitations(citations)
+		interfaces.ReportCitations(ctx, citations)
+	})
+
+	response, err := a.run(runCtx, input, report)
+	if err == nil {
+		streamResponse(ctx, response)
+		a.suggestFollowUpQuestions(runCtx, input, response, report)
+	}
+	report.finish(err)
+
+	if transcript != nil {
+		transcript.SetIDs(report.RequestID, report.TraceID)
+		a.debugRecorder.Finish(transcript, response, err)
+	}
+
+	a.reportMu.Lock()
+	a.lastReport = report
+	a.reportMu.Unlock()
+
+	if err == nil {
+		a.recordConversation(ctx, input, response)
+	}
+
+	if a.runEventHandler != nil {
+		a.runEventHandler(ctx, RunEvent{Type: RunEventRunFinished, Timestamp: report.FinishedAt, Report: report})
+	}
+
+	return response, report, err
+}
+
+// LastRunReport returns the report of the most recent run, or nil if the agent has not run yet
+func (a *Agent) LastRunReport() *RunReport {
+	a.reportMu.RLock()
+	defer a.reportMu.RUnlock()
+	return a.lastReport
+}
+
+// run runs the agent with the given input, recording tool calls in the report
+func (a *Agent) run(ctx context.Context, input string, report *RunReport) (string, error) {
 	// If orgID is set on the agent, add it to the context
 	if a.orgID != "" {
 		ctx = multitenancy.WithOrgID(ctx, a.orgID)
 	}
 
+	// Give the run a request ID so logs and traces can be correlated
+	ctx = runctx.EnsureRequestID(ctx)
+
+	// Simulate side effects if the agent always dry-runs
+	if a.dryRun {
+		ctx = runctx.WithDryRun(ctx, true)
+	}
+
+	// Check that the caller may run this agent
+	if a.authorizer != nil {
+		if err := rbac.AuthorizeAgent(ctx, a.authorizer, a.name); err != nil {
+			if errors.Is(err, rbac.ErrDenied) {
+				a.publish(ctx, events.Audit(ctx, a.name, rbac.ActionRunAgent, a.name, "denied", nil))
+			}
+			return "", err
+		}
+	}
+
+	// Let tools save and load artifacts
+	if a.artifactStore != nil {
+		if _, ok := artifact.ManagerFromContext(ctx); !ok {
+			ctx = artifact.WithManager(ctx, artifact.NewManager(a.artifactStore))
+		}
+	} else if a.spillStore != nil {
+		if _, ok := artifact.ManagerFromContext(ctx); !ok {
+			ctx = artifact.WithManager(ctx, artifact.NewManager(a.spillStore))
+		}
+	}
+
 	// Start tracing if available
 	var span interfaces.Span
 	if a.tracer != nil {
@@ -233,12 +588,36 @@ func (a *Agent) Run(ctx context.Context, input string) (string, error) {
 		defer span.End()
 	}
 
+	// Report the IDs that feedback on the response refers to
+	report.setIDs(runctx.RequestID(ctx), runctx.TraceID(ctx))
+
+	// Keep other replicas from running the same conversation at the same time
+	ctx, release, err := a.acquireConversationLease(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer release()
+
+	// Save the state before the run, so that a failed run can be retried
+	if a.checkpoints != nil {
+		if _, err := a.Checkpoint(ctx, "before run"); err != nil {
+			return "", err
+		}
+	}
+
+	// Classify the input for routing and analytics
+	ctx, inputClass := a.classifyInput(ctx, input, report)
+
 	// Add user message to memory
 	if a.memory != nil {
-		if err := a.memory.AddMessage(ctx, interfaces.Message{
+		message := interfaces.Message{
 			Role:    "user",
 			Content: input,
-		}); err != nil {
+		}
+		if inputClass != nil {
+			message.Metadata = map[string]interface{}{classification.MetadataKey: inputClass.Metadata()}
+		}
+		if err := a.memory.AddMessage(ctx, message); err != nil {
 			return "", fmt.Errorf("failed to add user message to memory: %w", err)
 		}
 	}
@@ -259,7 +638,7 @@ func (a *Agent) Run(ctx context.Context, input string) (string, error) {
 	}
 
 	// Check if the user is asking about the agent's role or identity
-	if a.systemPrompt != "" && a.isAskingAboutRole(input) {
+	if a.baseSystemPrompt() != "" && a.isAskingAboutRole(input) {
 		response := a.generateRoleResponse()
 
 		// Add the role response to memory if available
@@ -275,6 +654,11 @@ func (a *Agent) Run(ctx context.Context, input string) (string, error) {
 		return response, nil
 	}
 
+	// Answer from the semantic cache if a similar query was answered before
+	if response, ok, err := a.answerFromCache(ctx, input, report); ok || err != nil {
+		return response, err
+	}
+
 	allTools := a.tools
 
 	// Add MCP tools if available
@@ -287,41 +671,102 @@ func (a *Agent) Run(ctx context.Context, input string) (string, error) {
 			allTools = append(allTools, mcpTools...)
 		}
 	}
+
+	// Narrow the tools down to those relevant to the input
+	if a.toolSelector != nil && len(allTools) > 0 {
+		selectedTools, err := a.toolSelector.Select(ctx, input, allTools)
+		if err != nil {
+			// Log the error but continue with all tools
+			fmt.Printf("Failed to select tools: %v\n", err)
+		} else {
+			allTools = selectedTools
+		}
+	}
+
+	// Save large results as artifacts instead of adding them to the context
+	allTools = a.spillToolResults(allTools)
+
+	// Simulate calls that would change external systems in a dry run
+	if runctx.DryRun(ctx) {
+		allTools = a.simulateTools(allTools)
+	}
+
+	// Only offer the tools the caller may use
+	if a.authorizer != nil {
+		permittedTools, err := a.permittedTools(ctx, allTools)
+		if err != nil {
+			return "", err
+		}
+		// Check each call as well, for authorizers that decide on arguments
+		allTools = a.authorizeTools(permittedTools)
+	}
+
+	// Neutralize instructions injected into tool results
+	allTools = a.sanitizeTools(allTools)
+
+	// Reuse results of identical tool calls from earlier turns, but don't
+	// mix simulated results with real ones
+	if a.toolResults != nil && !runctx.DryRun(ctx) {
+		cachedTools := make([]interfaces.Tool, len(allTools))
+		for i, tool := range allTools {
+			cachedTools[i] = a.toolResults.Wrap(tool)
+		}
+		allTools = cachedTools
+	}
+
+	// Compress long tool results with respect to the input
+	allTools = a.compressTools(allTools, input)
+
+	// Keep long tool results from blowing up the context
+	allTools = a.limitToolResults(allTools)
+
+	// Record tool calls in the run report
+	allTools = a.instrumentTools(allTools, report)
+
 	// If tools are available and plan approval is required, generate an execution plan
 	if (len(allTools) > 0) && a.requirePlanApproval {
-		a.planGenerator = executionplan.NewGenerator(a.llm, allTools, a.systemPrompt)
+		planPrompt := a.withToolFailures(ctx, withToolExamples(a.baseSystemPrompt(), a.toolExamplesPrompt(allTools)), allTools)
+		a.planGenerator = executionplan.NewGenerator(a.llm, allTools, planPrompt)
 		return a.runWithExecutionPlan(ctx, input)
 	}
 
 	// Otherwise, run without an execution plan
-	return a.runWithoutExecutionPlanWithTools(ctx, input, allTools)
+	response, err := a.runWithoutExecutionPlanWithTools(ctx, input, allTools, report)
+	if err == nil && !runctx.DryRun(ctx) {
+		a.storeCachedAnswer(ctx, input, response, report)
+	}
+	return response, err
 }
 
-// collectMCPTools collects tools from all MCP servers
+// collectMCPTools collects tools from all MCP servers. The tool lists are
+// cached, see WithMCPToolsTTL.
 func (a *Agent) collectMCPTools(ctx context.Context) ([]interfaces.Tool, error) {
 	var mcpTools []interfaces.Tool
-
 	for _, server := range a.mcpServers {
-		// List tools from this server
-		tools, err := server.ListTools(ctx)
+		tools, err := listMCPTools(ctx, server)
 		if err != nil {
 			fmt.Printf("Failed to list tools from MCP server: %v\n", err)
 			continue
 		}
-
-		// Convert MCP tools to agent tools
-		for _, mcpTool := range tools {
-			// Create a new MCPTool
-			tool := mcp.NewMCPTool(mcpTool.Name, mcpTool.Description, mcpTool.Schema, server)
-			mcpTools = append(mcpTools, tool)
-		}
+		mcpTools = append(mcpTools, tools...)
 	}
 
 	return mcpTools, nil
 }
 
+// InvalidateMCPTools discards the cached tool lists of the MCP servers, so that
+// the next run lists their tools again. Call it when a server reports that its
+// tools changed.
+func (a *Agent) InvalidateMCPTools() {
+	for _, server := range a.mcpServers {
+		if cached, ok := server.(*mcp.CachedServer); ok {
+			cached.Invalidate()
+		}
+	}
+}
+
 // runWithoutExecutionPlanWithTools runs the agent without an execution plan but with the specified tools
-func (a *Agent) runWithoutExecutionPlanWithTools(ctx context.Context, input string, tools []interfaces.Tool) (string, error) {
+func (a *Agent) runWithoutExecutionPlanWithTools(ctx context.Context, input string, tools []interfaces.Tool, report *RunReport) (string, error) {
 	// Get conversation history if memory is available
 	var prompt string
 	if a.memory != nil {
@@ -331,7 +776,7 @@ func (a *Agent) runWithoutExecutionPlanWithTools(ctx context.Context, input stri
 		}
 
 		// Format history into prompt
-		prompt = formatHistoryIntoPrompt(history)
+		prompt = formatHistoryIntoPrompt(a.compressHistory(ctx, history, input))
 	} else {
 		prompt = input
 	}
@@ -342,12 +787,14 @@ func (a *Agent) runWithoutExecutionPlanWithTools(ctx context.Context, input stri
 
 	// Add system prompt as a generate option
 	generateOptions := []interfaces.GenerateOption{}
-	if a.systemPrompt != "" {
-		generateOptions = append(generateOptions, openai.WithSystemMessage(a.systemPrompt))
+	responseLanguage := a.responseLanguageFor(input)
+	systemPrompt := a.systemPromptWithTools(ctx, tools, responseLanguage)
+	if systemPrompt != "" {
+		generateOptions = append(generateOptions, openai.WithSystemMessage(systemPrompt))
 	}
 
 	// Add response format as a generate option if available
-	if a.responseFormat != nil {
+	if a.responseFormat != nil && !a.promptJSON {
 		generateOptions = append(generateOptions, openai.WithResponseFormat(*a.responseFormat))
 	}
 
@@ -357,16 +804,26 @@ func (a *Agent) runWithoutExecutionPlanWithTools(ctx context.Context, input stri
 		})
 	}
 
+	startedAt := time.Now()
 	if len(tools) > 0 {
 		response, err = a.llm.GenerateWithTools(ctx, prompt, tools, generateOptions...)
+	} else if a.canStreamTokens(ctx, responseLanguage) {
+		response, err = a.generateStream(ctx, prompt, generateOptions)
 	} else {
 		response, err = a.llm.Generate(ctx, prompt, generateOptions...)
 	}
+	a.recordLLMCall(ctx, startedAt, prompt, response, tools, err)
 
 	if err != nil {
 		return "", fmt.Errorf("failed to generate response: %w", err)
 	}
 
+	// Make sure the response is in the expected language
+	response, err = a.ensureLanguage(ctx, response, responseLanguage)
+	if err != nil {
+		return "", err
+	}
+
 	// Apply guardrails to output if available
 	if a.guardrails != nil {
 		guardedResponse, err := a.guardrails.ProcessOutput(ctx, response)
@@ -376,12 +833,19 @@ func (a *Agent) runWithoutExecutionPlanWithTools(ctx context.Context, input stri
 		response = guardedResponse
 	}
 
-	// Add agent message to memory
+	// Add agent message to memory, with the tools it called for analytics
 	if a.memory != nil {
-		if err := a.memory.AddMessage(ctx, interfaces.Message{
+		message := interfaces.Message{
 			Role:    "assistant",
 			Content: response,
-		}); err != nil {
+		}
+		if names := report.toolNames(); len(names) > 0 {
+			message.Metadata = map[string]interface{}{"tool_calls": names}
+			if failures := report.toolFailures(); len(failures) > 0 {
+				message.Metadata[ToolFailuresMetadataKey] = failures
+			}
+		}
+		if err := a.memory.AddMessage(ctx, message); err != nil {
 			return "", fmt.Errorf("failed to add agent message to memory: %w", err)
 		}
 	}
@@ -389,6 +853,35 @@ func (a *Agent) runWithoutExecutionPlanWithTools(ctx context.Context, input stri
 	return response, nil
 }
 
+// recordLLMCall adds an LLM call to the debug transcript of the run, if one is being recorded
+func (a *Agent) recordLLMCall(ctx context.Context, startedAt time.Time, prompt, response string, tools []interfaces.Tool, err error) {
+	if _, ok := debug.TranscriptFromContext(ctx); !ok {
+		return
+	}
+
+	toolNames := make([]string, len(tools))
+	for i, tool := range tools {
+		toolNames[i] = tool.Name()
+	}
+
+	entry := debug.Entry{
+		Type:      debug.EntryLLMCall,
+		Name:      a.llm.Name(),
+		StartedAt: startedAt,
+		Duration:  time.Since(startedAt),
+		Input:     prompt,
+		Output:    response,
+		Metadata: map[string]interface{}{
+			"system_prompt": a.systemPromptForRun(ctx),
+			"tools":         strings.Join(toolNames, ", "),
+		},
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	debug.Record(ctx, entry)
+}
+
 // extractPlanAction attempts to extract a plan action from the user input
 // Returns taskID, action, and remaining input
 func (a *Agent) extractPlanAction(input string) (string, string, string) {
@@ -422,6 +915,14 @@ func (a *Agent) handlePlanAction(ctx context.Context, taskID, action, input stri
 func (a *Agent) approvePlan(ctx context.Context, plan *executionplan.ExecutionPlan) (string, error) {
 	plan.UserApproved = true
 	plan.Status = executionplan.StatusApproved
+	if _, ok := executionplan.ApprovalFromContext(ctx); !ok {
+		ctx = executionplan.WithApproval(ctx, executionplan.Approval{
+			Approved:  true,
+			DecidedBy: runctx.UserID(ctx),
+			DecidedAt: time.Now(),
+			Source:    "user",
+		})
+	}
 
 	// Add the approval to memory
 	if a.memory != nil {
@@ -472,6 +973,7 @@ func (a *Agent) modifyPlan(ctx context.Context, plan *executionplan.ExecutionPla
 
 	// Update the plan in the store
 	a.planStore.StorePlan(modifiedPlan)
+	streamRunEvent(ctx, RunEvent{Type: RunEventPlanGenerated, Timestamp: time.Now(), Plan: modifiedPlan})
 
 	// Format the modified plan
 	formattedPlan := executionplan.FormatExecutionPlan(modifiedPlan)
@@ -514,6 +1016,15 @@ func (a *Agent) runWithExecutionPlan(ctx context.Context, input string) (string,
 
 	// Store the plan
 	a.planStore.StorePlan(plan)
+	streamRunEvent(ctx, RunEvent{Type: RunEventPlanGenerated, Timestamp: time.Now(), Plan: plan})
+
+	// Queue the plan for review
+	if a.approvals != nil {
+		if _, err := a.approvals.Submit(ctx, a.name, plan); err != nil {
+			return "", fmt.Errorf("failed to submit execution plan for approval: %w", err)
+		}
+		plan.Status = executionplan.StatusPendingApproval
+	}
 
 	// Format the plan for display
 	formattedPlan := executionplan.FormatExecutionPlan(plan)
@@ -548,6 +1059,60 @@ func formatHistoryIntoPrompt(history []interfaces.Message) string {
 	return prompt
 }
 
+// handleApprovalDecision executes or cancels a plan decided in the approval queue
+func (a *Agent) handleApprovalDecision(ctx context.Context, req *approval.Request) (string, error) {
+	plan, ok := a.planStore.GetPlanByTaskID(req.ID)
+	if !ok {
+		// The plan was queued before a restart
+		plan = req.Plan
+		a.planStore.StorePlan(plan)
+	}
+
+	ctx = executionplan.WithApproval(ctx, executionplan.Approval{
+		Approved:  req.Status == approval.StatusApproved,
+		DecidedBy: req.DecidedBy,
+		DecidedAt: req.DecidedAt,
+		Source:    "approval_queue",
+		Reason:    req.Reason,
+	})
+	if req.Status == approval.StatusApproved {
+		return a.approvePlan(ctx, plan)
+	}
+
+	result, err := a.cancelPlan(plan)
+	a.recordRejection(ctx, plan)
+	return result, err
+}
+
+// recordRejection records a plan that was rejected without being executed
+func (a *Agent) recordRejection(ctx context.Context, plan *executionplan.ExecutionPlan) {
+	if a.planHistory == nil {
+		return
+	}
+	record := executionplan.NewRunRecord(ctx, a.name, plan)
+	record.FinishedAt = record.StartedAt
+	if err := a.planHistory.SaveRun(ctx, record); err != nil {
+		// Log the error but don't fail the decision
+		fmt.Printf("Failed to record execution plan history: %v\n", err)
+	}
+}
+
+// GetRunHistory returns the executed and rejected plans of the agent that
+// match the filter, most recent first. It requires WithPlanHistory.
+func (a *Agent) GetRunHistory(ctx context.Context, filter executionplan.HistoryFilter) ([]*executionplan.RunRecord, error) {
+	if a.planHistory == nil {
+		return nil, fmt.Errorf("plan history is not configured")
+	}
+	if filter.Agent == "" {
+		filter.Agent = a.name
+	}
+	runs, err := a.planHistory.ListRuns(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list plan history: %w", err)
+	}
+	return runs, nil
+}
+
 // ApproveExecutionPlan approves an execution plan for execution
 func (a *Agent) ApproveExecutionPlan(ctx context.Context, plan *executionplan.ExecutionPlan) (string, error) {
 	return a.approvePlan(ctx, plan)
@@ -604,7 +1169,7 @@ func (a *Agent) isAskingAboutRole(input string) bool {
 // generateRoleResponse creates a response based on the agent's system prompt
 func (a *Agent) generateRoleResponse() string {
 	// If the prompt is empty, return a generic response
-	if a.systemPrompt == "" || a.llm == nil {
+	if a.baseSystemPrompt() == "" || a.llm == nil {
 		return "I'm an AI assistant designed to help you with various tasks and answer your questions. How can I assist you today?"
 	}
 
@@ -629,13 +1194,13 @@ Your response should:
 3. Mention 2-3 key areas you can help with
 4. End with a friendly question about how you can assist the user
 
-Response:`, agentName, a.systemPrompt, agentName)
+Response:`, agentName, a.baseSystemPrompt(), agentName)
 
 	// Generate a response using the LLM with the system prompt as context
 	generateOptions := []interfaces.GenerateOption{}
 
 	// Use the same system prompt to ensure consistent persona
-	generateOptions = append(generateOptions, openai.WithSystemMessage(a.systemPrompt))
+	generateOptions = append(generateOptions, openai.WithSystemMessage(a.baseSystemPrompt()))
 
 	// Generate the response
 	response, err := a.llm.Generate(context.Background(), prompt, generateOptions...)
@@ -678,6 +1243,14 @@ func (a *Agent) ExecuteTaskFromConfig(ctx context.Context, taskName string, task
 			outputPath = strings.ReplaceAll(outputPath, placeholder, value)
 		}
 
+		if a.artifactStore != nil {
+			err := a.artifactStore.Put(ctx, a.outputKey(ctx, outputPath), strings.NewReader(result), outputContentType(outputPath))
+			if err != nil {
+				return result, fmt.Errorf("failed to store output file %s: %w", outputPath, err)
+			}
+			return result, nil
+		}
+
 		err := os.WriteFile(outputPath, []byte(result), 0600)
 		if err != nil {
 			return result, fmt.Errorf("failed to write output to file %s: %w", outputPath, err)
@@ -687,6 +1260,44 @@ func (a *Agent) ExecuteTaskFromConfig(ctx context.Context, taskName string, task
 	return result, nil
 }
 
+// OutputFileURL returns a signed URL for a task output file written to the
+// artifact store
+func (a *Agent) OutputFileURL(ctx context.Context, outputPath string, expiry time.Duration) (string, error) {
+	if a.artifactStore == nil {
+		return "", fmt.Errorf("no artifact store configured")
+	}
+	return a.artifactStore.SignedURL(ctx, a.outputKey(ctx, outputPath), expiry)
+}
+
+// outputKey scopes output files to the organization so tenants cannot read
+// each other's outputs
+func (a *Agent) outputKey(ctx context.Context, outputPath string) string {
+	orgID, err := multitenancy.GetOrgID(ctx)
+	if err != nil {
+		orgID = a.orgID
+	}
+	if orgID == "" {
+		return outputPath
+	}
+	return orgID + "/" + outputPath
+}
+
+// outputContentType guesses the content type of an output file from its extension
+func outputContentType(outputPath string) string {
+	switch strings.ToLower(filepath.Ext(outputPath)) {
+	case ".md":
+		return "text/markdown; charset=utf-8"
+	case ".json":
+		return "application/json"
+	case ".html":
+		return "text/html; charset=utf-8"
+	case ".csv":
+		return "text/csv; charset=utf-8"
+	default:
+		return "text/plain; charset=utf-8"
+	}
+}
+
 // GetGeneratedAgentConfig returns the automatically generated agent configuration, if any
 func (a *Agent) GetGeneratedAgentConfig() *AgentConfig {
 	return a.generatedAgentConfig
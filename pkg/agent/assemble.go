@@ -0,0 +1,60 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+)
+
+// AssembledPrompt is what a run sends to the LLM
+type AssembledPrompt struct {
+	// SystemPrompt is the system prompt, with tool examples and language
+	// instructions
+	SystemPrompt string
+
+	// Messages is the conversation the prompt is built from, ending with the
+	// input
+	Messages []interfaces.Message
+
+	// Prompt is the prompt as sent to the LLM
+	Prompt string
+}
+
+// AssemblePrompt returns the system prompt and prompt that a run with the
+// input would send to the LLM, without running the agent or adding the input
+// to memory, e.g. to snapshot them in tests (see the snapshot package). MCP
+// tools and tool selection are not applied.
+func (a *Agent) AssemblePrompt(ctx context.Context, input string) (AssembledPrompt, error) {
+	ctx = a.withOrgID(ctx)
+
+	messages := []interfaces.Message{{Role: "user", Content: input}}
+	prompt := input
+	if a.memory != nil {
+		history, err := a.memory.GetMessages(ctx)
+		if err != nil {
+			return AssembledPrompt{}, fmt.Errorf("failed to get conversation history: %w", err)
+		}
+		messages = a.compressHistory(ctx, append(history, messages...), input)
+		prompt = formatHistoryIntoPrompt(messages)
+	}
+
+	return AssembledPrompt{
+		SystemPrompt: a.systemPromptWithTools(ctx, a.tools, a.responseLanguageFor(input)),
+		Messages:     messages,
+		Prompt:       prompt,
+	}, nil
+}
+
+// systemPromptWithTools returns the system prompt of a run with the examples
+// and recent failures of its tools and the instruction to respond in the
+// language
+func (a *Agent) systemPromptWithTools(ctx context.Context, tools []interfaces.Tool, responseLanguage string) string {
+	systemPrompt := withToolExamples(a.systemPromptForRun(ctx), a.toolExamplesPrompt(tools))
+	systemPrompt = a.withToolFailures(ctx, systemPrompt, tools)
+	if instruction := a.languageInstruction(responseLanguage); instruction != "" {
+		systemPrompt = strings.TrimSpace(systemPrompt + "\n\n" + instruction)
+	}
+	return systemPrompt
+}
@@ -0,0 +1,58 @@
+package agent
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/run-bigpig/llm-agent/pkg/executionplan"
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+	"github.com/run-bigpig/llm-agent/pkg/memory"
+	"github.com/run-bigpig/llm-agent/pkg/prompts"
+	"github.com/run-bigpig/llm-agent/pkg/snapshot"
+)
+
+func TestAssemblePromptSnapshot(t *testing.T) {
+	mem := memory.NewConversationBuffer()
+	agent, err := NewAgent(
+		WithLLM(&MockLLM{}),
+		WithMemory(mem),
+		WithOrgID("org-1"),
+		WithSystemPromptSection(prompts.Section{Name: prompts.SectionIdentity, Content: "You are Billy, a billing assistant."}),
+		WithSystemPromptSection(prompts.Section{Name: prompts.SectionSafety, Content: "Never share credentials."}),
+		WithResponseLanguage("en"),
+		WithTools(exampleTool{
+			specTool: specTool{name: "search_invoices"},
+			examples: []interfaces.ToolExample{{
+				Description: "latest invoices of a customer",
+				Arguments:   map[string]interface{}{"customer": "acme", "limit": 5},
+				Outcome:     "the 5 most recent invoices of Acme",
+			}},
+		}),
+	)
+	require.NoError(t, err)
+
+	ctx := memory.WithConversationID(context.Background(), "conv")
+	require.NoError(t, mem.AddMessage(agent.withOrgID(ctx), interfaces.Message{Role: "user", Content: "Hi"}))
+	require.NoError(t, mem.AddMessage(agent.withOrgID(ctx), interfaces.Message{Role: "assistant", Content: "Hello! How can I help?"}))
+
+	assembled, err := agent.AssemblePrompt(ctx, "Show my latest invoices")
+	require.NoError(t, err)
+	assert.Equal(t, "user: Hi\nassistant: Hello! How can I help?\nuser: Show my latest invoices\n", assembled.Prompt)
+	snapshot.MatchMessages(t, "assembled_prompt", assembled.SystemPrompt, assembled.Messages)
+
+	// Assembling does not add the input to memory
+	messages, err := mem.GetMessages(agent.withOrgID(ctx))
+	require.NoError(t, err)
+	assert.Len(t, messages, 2)
+}
+
+func TestExecutionPlanSnapshot(t *testing.T) {
+	plan := executionplan.NewExecutionPlan("Refund a duplicate charge", []executionplan.ExecutionStep{
+		{ToolName: "search_invoices", Description: "Find the duplicate invoice", Input: `{"customer":"acme","limit":5}`},
+		{ToolName: "refund", Description: "Refund the duplicate", Input: `{"invoice":"INV-42"}`},
+	})
+	snapshot.MatchPlan(t, "execution_plan", plan)
+}
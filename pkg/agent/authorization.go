@@ -0,0 +1,37 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+	"github.com/run-bigpig/llm-agent/pkg/rbac"
+)
+
+// permittedTools returns the tools the caller in the context may use
+func (a *Agent) permittedTools(ctx context.Context, tools []interfaces.Tool) ([]interfaces.Tool, error) {
+	permitted := make([]interfaces.Tool, 0, len(tools))
+	for _, tool := range tools {
+		allowed, err := a.authorizer.CanUseTool(ctx, tool.Name())
+		if err != nil {
+			return nil, fmt.Errorf("failed to authorize tool %s: %w", tool.Name(), err)
+		}
+		if allowed {
+			permitted = append(permitted, tool)
+		}
+	}
+	return permitted, nil
+}
+
+// authorizeTools wraps the tools so that every call is checked with the
+// authorizer. The tools are returned unchanged if there is no authorizer.
+func (a *Agent) authorizeTools(tools []interfaces.Tool) []interfaces.Tool {
+	if a.authorizer == nil {
+		return tools
+	}
+	authorized := make([]interfaces.Tool, len(tools))
+	for i, tool := range tools {
+		authorized[i] = rbac.Wrap(a.authorizer, tool)
+	}
+	return authorized
+}
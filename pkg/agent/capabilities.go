@@ -0,0 +1,42 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+)
+
+// checkCapabilities fails fast when the LLM lacks a capability the agent
+// needs, and falls back to prompt-based JSON when it has no JSON mode
+func (a *Agent) checkCapabilities() error {
+	caps, ok := interfaces.GetCapabilities(a.llm)
+	if !ok {
+		if a.requiredCapabilities != (interfaces.Capabilities{}) {
+			return fmt.Errorf("LLM %s does not report its capabilities, so required capabilities cannot be verified", a.llm.Name())
+		}
+		return nil
+	}
+
+	required := a.requiredCapabilities
+	if len(a.tools) > 0 || len(a.mcpServers) > 0 {
+		required.Tools = true
+	}
+	if missing := caps.Missing(required); len(missing) > 0 {
+		return fmt.Errorf("LLM %s does not support required capabilities: %s", a.llm.Name(), strings.Join(missing, ", "))
+	}
+
+	a.promptJSON = a.responseFormat != nil && !caps.JSONMode
+	return nil
+}
+
+// jsonFormatInstructions describes the response format for LLMs that cannot
+// enforce it natively
+func jsonFormatInstructions(format interfaces.ResponseFormat) string {
+	schema, err := json.MarshalIndent(format.Schema, "", "  ")
+	if err != nil || len(format.Schema) == 0 {
+		return "Respond only with a valid JSON object, without any surrounding text or code fences."
+	}
+	return fmt.Sprintf("Respond only with a valid JSON object matching this JSON schema, without any surrounding text or code fences:\n%s", schema)
+}
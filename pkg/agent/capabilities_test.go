@@ -0,0 +1,89 @@
+package agent
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+)
+
+// capableLLM reports fixed capabilities and records the options of each call
+type capableLLM struct {
+	MockLLM
+	caps    interfaces.Capabilities
+	options interfaces.GenerateOptions
+}
+
+func (m *capableLLM) Capabilities() interfaces.Capabilities { return m.caps }
+
+func (m *capableLLM) Generate(ctx context.Context, prompt string, options ...interfaces.GenerateOption) (string, error) {
+	m.options = interfaces.GenerateOptions{}
+	for _, option := range options {
+		option(&m.options)
+	}
+	return `{"answer":"ok"}`, nil
+}
+
+func (m *capableLLM) GenerateWithTools(ctx context.Context, prompt string, tools []interfaces.Tool, options ...interfaces.GenerateOption) (string, error) {
+	return m.Generate(ctx, prompt, options...)
+}
+
+func TestCheckCapabilities(t *testing.T) {
+	var calls int
+	tool := sendTool{specTool: specTool{name: "send"}, calls: &calls}
+
+	_, err := NewAgent(WithLLM(&capableLLM{}), WithTools(tool))
+	assert.EqualError(t, err, "LLM MockLLM does not support required capabilities: tools")
+
+	_, err = NewAgent(
+		WithLLM(&capableLLM{caps: interfaces.Capabilities{Tools: true}}),
+		WithTools(tool),
+		WithRequiredCapabilities(interfaces.Capabilities{Vision: true, Streaming: true}),
+	)
+	assert.EqualError(t, err, "LLM MockLLM does not support required capabilities: vision, streaming")
+
+	_, err = NewAgent(WithLLM(&capableLLM{caps: interfaces.Capabilities{Tools: true}}), WithTools(tool))
+	assert.NoError(t, err)
+
+	// LLMs that don't report capabilities are trusted unless capabilities are required
+	_, err = NewAgent(WithLLM(&MockLLM{}), WithTools(tool))
+	assert.NoError(t, err)
+	_, err = NewAgent(WithLLM(&MockLLM{}), WithRequiredCapabilities(interfaces.Capabilities{Vision: true}))
+	assert.EqualError(t, err, "LLM MockLLM does not report its capabilities, so required capabilities cannot be verified")
+}
+
+func TestResponseFormatWithoutJSONMode(t *testing.T) {
+	format := interfaces.ResponseFormat{
+		Type:   interfaces.ResponseFormatJSON,
+		Name:   "answer",
+		Schema: interfaces.JSONSchema{"type": "object"},
+	}
+
+	llm := &capableLLM{}
+	agent, err := NewAgent(WithLLM(llm), WithSystemPrompt("Be brief."), WithResponseFormat(format))
+	require.NoError(t, err)
+	_, err = agent.Run(context.Background(), "hi")
+	require.NoError(t, err)
+
+	// The format is asked for in the prompt instead of being sent to the API
+	assert.Nil(t, llm.options.ResponseFormat)
+	assert.Equal(t, "Be brief.\n\nRespond only with a valid JSON object matching this JSON schema, without any surrounding text or code fences:\n{\n  \"type\": \"object\"\n}", llm.options.SystemMessage)
+
+	llm = &capableLLM{caps: interfaces.Capabilities{JSONMode: true}}
+	agent, err = NewAgent(WithLLM(llm), WithSystemPrompt("Be brief."), WithResponseFormat(format))
+	require.NoError(t, err)
+	_, err = agent.Run(context.Background(), "hi")
+	require.NoError(t, err)
+
+	require.NotNil(t, llm.options.ResponseFormat)
+	assert.Equal(t, "answer", llm.options.ResponseFormat.Name)
+	assert.Equal(t, "Be brief.", llm.options.SystemMessage)
+}
+
+func TestJSONFormatInstructionsWithoutSchema(t *testing.T) {
+	assert.Equal(t, "Respond only with a valid JSON object, without any surrounding text or code fences.",
+		jsonFormatInstructions(interfaces.ResponseFormat{Type: interfaces.ResponseFormatJSON}))
+}
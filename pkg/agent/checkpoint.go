@@ -0,0 +1,244 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/run-bigpig/llm-agent/pkg/executionplan"
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+	"github.com/run-bigpig/llm-agent/pkg/memory"
+	"github.com/run-bigpig/llm-agent/pkg/multitenancy"
+)
+
+// Checkpoint is a snapshot of an agent's conversation and execution plans at
+// a point in time
+type Checkpoint struct {
+	// ID identifies the checkpoint
+	ID string
+
+	// Label describes the checkpoint, e.g. "before run"
+	Label string
+
+	// ConversationID is the conversation the snapshot was taken of
+	ConversationID string
+
+	// Messages are the conversation's messages, oldest first
+	Messages []interfaces.Message
+
+	// Plans are the agent's execution plans
+	Plans []*executionplan.ExecutionPlan
+
+	// CreatedAt is when the checkpoint was taken
+	CreatedAt time.Time
+}
+
+// CheckpointStore stores checkpoints
+type CheckpointStore interface {
+	// Save stores a checkpoint
+	Save(ctx context.Context, checkpoint *Checkpoint) error
+
+	// Get returns a checkpoint by ID
+	Get(ctx context.Context, id string) (*Checkpoint, error)
+
+	// List returns the checkpoints of a conversation, oldest first
+	List(ctx context.Context, conversationID string) ([]*Checkpoint, error)
+}
+
+// MemoryCheckpointStore keeps checkpoints in memory
+type MemoryCheckpointStore struct {
+	mu          sync.RWMutex
+	checkpoints map[string]*Checkpoint
+}
+
+// NewMemoryCheckpointStore creates an empty in-memory checkpoint store
+func NewMemoryCheckpointStore() *MemoryCheckpointStore {
+	return &MemoryCheckpointStore{checkpoints: make(map[string]*Checkpoint)}
+}
+
+// Save stores a checkpoint
+func (s *MemoryCheckpointStore) Save(ctx context.Context, checkpoint *Checkpoint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.checkpoints[checkpoint.ID] = checkpoint
+	return nil
+}
+
+// Get returns a checkpoint by ID
+func (s *MemoryCheckpointStore) Get(ctx context.Context, id string) (*Checkpoint, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	checkpoint, ok := s.checkpoints[id]
+	if !ok {
+		return nil, fmt.Errorf("checkpoint %s not found", id)
+	}
+	return checkpoint, nil
+}
+
+// List returns the checkpoints of a conversation, oldest first
+func (s *MemoryCheckpointStore) List(ctx context.Context, conversationID string) ([]*Checkpoint, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var checkpoints []*Checkpoint
+	for _, checkpoint := range s.checkpoints {
+		if checkpoint.ConversationID == conversationID {
+			checkpoints = append(checkpoints, checkpoint)
+		}
+	}
+	sort.Slice(checkpoints, func(i, j int) bool {
+		return checkpoints[i].CreatedAt.Before(checkpoints[j].CreatedAt)
+	})
+	return checkpoints, nil
+}
+
+// WithCheckpointStore saves a checkpoint labeled "before run" at the start of
+// every run, so that a failed run can be retried from just before it with
+// Restore
+func WithCheckpointStore(store CheckpointStore) Option {
+	return func(a *Agent) {
+		a.checkpoints = store
+	}
+}
+
+// Checkpoint takes a snapshot of the conversation in the context and of the
+// agent's execution plans. It is saved in the checkpoint store if the agent
+// has one.
+func (a *Agent) Checkpoint(ctx context.Context, label string) (*Checkpoint, error) {
+	ctx = a.withOrgID(ctx)
+	conversationID, _ := memory.GetConversationID(ctx)
+	checkpoint := &Checkpoint{
+		ID:             uuid.New().String(),
+		Label:          label,
+		ConversationID: conversationID,
+		CreatedAt:      time.Now(),
+	}
+
+	if a.memory != nil {
+		messages, err := a.memory.GetMessages(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read conversation: %w", err)
+		}
+		checkpoint.Messages = copyMessages(messages)
+	}
+	for _, plan := range a.planStore.ListPlans() {
+		checkpoint.Plans = append(checkpoint.Plans, copyPlan(plan))
+	}
+	sort.Slice(checkpoint.Plans, func(i, j int) bool {
+		return checkpoint.Plans[i].CreatedAt.Before(checkpoint.Plans[j].CreatedAt)
+	})
+
+	if a.checkpoints != nil {
+		if err := a.checkpoints.Save(ctx, checkpoint); err != nil {
+			return nil, fmt.Errorf("failed to save checkpoint: %w", err)
+		}
+	}
+	return checkpoint, nil
+}
+
+// Checkpoints returns the saved checkpoints of the conversation in the
+// context, oldest first
+func (a *Agent) Checkpoints(ctx context.Context) ([]*Checkpoint, error) {
+	if a.checkpoints == nil {
+		return nil, fmt.Errorf("no checkpoint store configured")
+	}
+	conversationID, _ := memory.GetConversationID(ctx)
+	return a.checkpoints.List(ctx, conversationID)
+}
+
+// Restore rewinds the conversation in the context and the agent's execution
+// plans to a checkpoint. Messages and plans added after the checkpoint are
+// discarded.
+func (a *Agent) Restore(ctx context.Context, checkpoint *Checkpoint) error {
+	ctx = a.withOrgID(ctx)
+	if err := a.restoreMessages(ctx, checkpoint); err != nil {
+		return err
+	}
+
+	for _, plan := range a.planStore.ListPlans() {
+		a.planStore.DeletePlan(plan.TaskID)
+	}
+	for _, plan := range checkpoint.Plans {
+		a.planStore.StorePlan(copyPlan(plan))
+	}
+	return nil
+}
+
+// Fork branches a new conversation off a checkpoint and returns a context for
+// it. The original conversation is left as it is, so both can be continued
+// independently. Plans are shared by all conversations of an agent: plans of
+// the checkpoint that were deleted since are restored, but existing plans are
+// not rewound.
+func (a *Agent) Fork(ctx context.Context, checkpoint *Checkpoint, conversationID string) (context.Context, error) {
+	if conversationID == "" {
+		conversationID = uuid.New().String()
+	}
+	forked := memory.WithConversationID(ctx, conversationID)
+	if err := a.restoreMessages(a.withOrgID(forked), checkpoint); err != nil {
+		return nil, err
+	}
+
+	for _, plan := range checkpoint.Plans {
+		if _, exists := a.planStore.GetPlanByTaskID(plan.TaskID); !exists {
+			a.planStore.StorePlan(copyPlan(plan))
+		}
+	}
+	return forked, nil
+}
+
+// restoreMessages replaces the messages of the conversation in the context
+// with those of the checkpoint
+func (a *Agent) restoreMessages(ctx context.Context, checkpoint *Checkpoint) error {
+	if a.memory == nil {
+		return nil
+	}
+	if err := a.memory.Clear(ctx); err != nil {
+		return fmt.Errorf("failed to clear conversation: %w", err)
+	}
+	if err := memory.AddMessages(ctx, a.memory, copyMessages(checkpoint.Messages)); err != nil {
+		return fmt.Errorf("failed to restore conversation: %w", err)
+	}
+	return nil
+}
+
+// withOrgID adds the agent's organization ID to the context if it has one
+func (a *Agent) withOrgID(ctx context.Context) context.Context {
+	if a.orgID != "" {
+		return multitenancy.WithOrgID(ctx, a.orgID)
+	}
+	return ctx
+}
+
+// copyMessages copies messages and their metadata
+func copyMessages(messages []interfaces.Message) []interfaces.Message {
+	copied := make([]interfaces.Message, len(messages))
+	for i, msg := range messages {
+		copied[i] = msg
+		if msg.Metadata != nil {
+			copied[i].Metadata = make(map[string]interface{}, len(msg.Metadata))
+			for k, v := range msg.Metadata {
+				copied[i].Metadata[k] = v
+			}
+		}
+	}
+	return copied
+}
+
+// copyPlan copies a plan so that later changes to it don't alter a checkpoint
+func copyPlan(plan *executionplan.ExecutionPlan) *executionplan.ExecutionPlan {
+	copied := *plan
+	copied.Steps = make([]executionplan.ExecutionStep, len(plan.Steps))
+	for i, step := range plan.Steps {
+		copied.Steps[i] = step
+		if step.Parameters != nil {
+			copied.Steps[i].Parameters = make(map[string]interface{}, len(step.Parameters))
+			for k, v := range step.Parameters {
+				copied.Steps[i].Parameters[k] = v
+			}
+		}
+	}
+	return &copied
+}
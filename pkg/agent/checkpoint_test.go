@@ -0,0 +1,67 @@
+package agent
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/run-bigpig/llm-agent/pkg/executionplan"
+	"github.com/run-bigpig/llm-agent/pkg/memory"
+)
+
+func TestCheckpointRestoreAndFork(t *testing.T) {
+	mem := memory.NewConversationBuffer()
+	store := NewMemoryCheckpointStore()
+	agent, err := NewAgent(
+		WithLLM(&MockLLM{}),
+		WithMemory(mem),
+		WithOrgID("org-1"),
+		WithCheckpointStore(store),
+	)
+	require.NoError(t, err)
+
+	ctx := memory.WithConversationID(context.Background(), "conv")
+	_, err = agent.Run(ctx, "first question")
+	require.NoError(t, err)
+	_, err = agent.Run(ctx, "second question")
+	require.NoError(t, err)
+
+	// A checkpoint is saved before every run
+	checkpoints, err := agent.Checkpoints(ctx)
+	require.NoError(t, err)
+	require.Len(t, checkpoints, 2)
+	assert.Equal(t, "before run", checkpoints[1].Label)
+	assert.Len(t, checkpoints[0].Messages, 0)
+	require.Len(t, checkpoints[1].Messages, 2)
+	assert.Equal(t, "first question", checkpoints[1].Messages[0].Content)
+
+	// Restoring drops the messages and plans that came after the checkpoint
+	agent.planStore.StorePlan(executionplan.NewExecutionPlan("later plan", nil))
+	require.NoError(t, agent.Restore(ctx, checkpoints[1]))
+	messages, err := mem.GetMessages(agent.withOrgID(ctx))
+	require.NoError(t, err)
+	assert.Len(t, messages, 2)
+	assert.Empty(t, agent.ListTasks())
+
+	// A fork starts from the checkpoint and leaves the original alone
+	_, err = agent.Run(ctx, "retried question")
+	require.NoError(t, err)
+	forked, err := agent.Fork(ctx, checkpoints[1], "branch")
+	require.NoError(t, err)
+	_, err = agent.Run(forked, "what if question")
+	require.NoError(t, err)
+
+	original, err := mem.GetMessages(agent.withOrgID(ctx))
+	require.NoError(t, err)
+	branch, err := mem.GetMessages(agent.withOrgID(forked))
+	require.NoError(t, err)
+	require.Len(t, original, 4)
+	require.Len(t, branch, 4)
+	assert.Equal(t, "retried question", original[2].Content)
+	assert.Equal(t, "what if question", branch[2].Content)
+
+	// Checkpoints don't change when the conversation does
+	assert.Len(t, checkpoints[1].Messages, 2)
+}
@@ -0,0 +1,38 @@
+package agent
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+)
+
+// groundedLLM reports the sources of its response like a client with search
+// grounding
+type groundedLLM struct {
+	MockLLM
+}
+
+func (m *groundedLLM) Generate(ctx context.Context, prompt string, options ...interfaces.GenerateOption) (string, error) {
+	interfaces.ReportCitations(ctx, []interfaces.Citation{{URL: "https://go.dev", Title: "go.dev", Text: "Go 1.24", EndIndex: 7}})
+	return "Go 1.24", nil
+}
+
+func TestRunReportCitations(t *testing.T) {
+	agent, err := NewAgent(WithLLM(&groundedLLM{}))
+	require.NoError(t, err)
+
+	var reported []interfaces.Citation
+	ctx := interfaces.WithCitationsCallback(context.Background(), func(citations []interfaces.Citation) {
+		reported = append(reported, citations...)
+	})
+	_, report, err := agent.RunWithReport(ctx, "What is the latest Go release?")
+	require.NoError(t, err)
+
+	expected := []interfaces.Citation{{URL: "https://go.dev", Title: "go.dev", Text: "Go 1.24", EndIndex: 7}}
+	assert.Equal(t, expected, report.Citations)
+	assert.Equal(t, expected, reported)
+}
@@ -0,0 +1,47 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/run-bigpig/llm-agent/pkg/classification"
+)
+
+// WithInputClassifier classifies every input by intent, topic and sentiment
+// before the agent answers it. The result is stored in the metadata of the
+// user message under classification.MetadataKey, put in the run's context for
+// classification.FromContext, set on the run report and passed to the sinks.
+// Classification failures are logged and do not fail the run.
+func WithInputClassifier(classifier classification.Classifier, sinks ...classification.Sink) Option {
+	return func(a *Agent) {
+		a.classifier = classifier
+		a.classificationSinks = append(a.classificationSinks, sinks...)
+	}
+}
+
+// classifyInput classifies an input and records the result, returning the
+// context carrying the classification
+func (a *Agent) classifyInput(ctx context.Context, input string, report *RunReport) (context.Context, *classification.Classification) {
+	if a.classifier == nil {
+		return ctx, nil
+	}
+
+	result, err := a.classifier.Classify(ctx, input)
+	if err != nil {
+		fmt.Printf("Failed to classify input: %v\n", err)
+		return ctx, nil
+	}
+	if result == nil {
+		return ctx, nil
+	}
+
+	if report != nil {
+		report.setClassification(result)
+	}
+	for _, sink := range a.classificationSinks {
+		if err := sink.Record(ctx, input, result); err != nil {
+			fmt.Printf("Failed to record input classification: %v\n", err)
+		}
+	}
+	return classification.WithClassification(ctx, result), result
+}
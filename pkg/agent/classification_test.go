@@ -0,0 +1,50 @@
+package agent
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/run-bigpig/llm-agent/pkg/classification"
+	"github.com/run-bigpig/llm-agent/pkg/memory"
+)
+
+func TestInputClassification(t *testing.T) {
+	mem := memory.NewConversationBuffer()
+	var recorded []*classification.Classification
+	sink := classification.SinkFunc(func(ctx context.Context, input string, c *classification.Classification) error {
+		recorded = append(recorded, c)
+		return nil
+	})
+	agent, err := NewAgent(
+		WithLLM(&MockLLM{}),
+		WithMemory(mem),
+		WithOrgID("org-1"),
+		WithInputClassifier(classification.NewRuleClassifier(
+			classification.WithTopicKeywords("billing", "invoice", "charge"),
+		), sink),
+	)
+	require.NoError(t, err)
+
+	ctx := memory.WithConversationID(context.Background(), "conv")
+	_, report, err := agent.RunWithReport(ctx, "Why was I charged twice on my invoice? This is terrible.")
+	require.NoError(t, err)
+
+	// The report and the sink get the classification
+	require.NotNil(t, report.Classification)
+	assert.Equal(t, classification.IntentQuestion, report.Classification.Intent)
+	assert.Equal(t, "billing", report.Classification.Topic)
+	assert.Equal(t, classification.SentimentNegative, report.Classification.Sentiment)
+	require.Len(t, recorded, 1)
+	assert.Same(t, report.Classification, recorded[0])
+
+	// The user message carries it in its metadata
+	messages, err := mem.GetMessages(agent.withOrgID(ctx))
+	require.NoError(t, err)
+	require.NotEmpty(t, messages)
+	stored, ok := classification.FromMetadata(messages[0].Metadata)
+	require.True(t, ok)
+	assert.Equal(t, "billing", stored.Topic)
+}
@@ -0,0 +1,174 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/run-bigpig/llm-agent/pkg/compression"
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+)
+
+// Defaults of PromptCompression
+const (
+	defaultCompressionRatio     = 0.5
+	defaultCompressionMinTokens = 200
+	defaultCompressionRecent    = 2
+)
+
+// PromptCompression compresses long history messages, such as context
+// retrieved by a memory.VectorStoreRetriever, and long tool results before
+// they are sent to the LLM
+type PromptCompression struct {
+	// Compressor compresses the text. Defaults to an extractive compressor
+	// that measures tokens like the tool result limits.
+	Compressor compression.Compressor
+
+	// Ratio is the share of tokens to keep, between 0 and 1. Defaults to
+	// 0.5.
+	Ratio float64
+
+	// MinTokens is the size from which a text is compressed. Defaults to
+	// 200.
+	MinTokens int
+
+	// KeepRecent is the number of most recent history messages, including
+	// the input, that are never compressed. Defaults to 2; a negative value
+	// compresses all of them.
+	KeepRecent int
+
+	// SkipToolResults leaves tool results as they are
+	SkipToolResults bool
+}
+
+// WithPromptCompression compresses long history messages and tool results
+// with respect to the input, to fit more knowledge into the context window.
+// Failures are logged and the text is sent uncompressed.
+func WithPromptCompression(config PromptCompression) Option {
+	return func(a *Agent) {
+		if config.Ratio <= 0 || config.Ratio > 1 {
+			config.Ratio = defaultCompressionRatio
+		}
+		if config.MinTokens <= 0 {
+			config.MinTokens = defaultCompressionMinTokens
+		}
+		if config.KeepRecent == 0 {
+			config.KeepRecent = defaultCompressionRecent
+		}
+		a.promptCompression = &config
+	}
+}
+
+// compressor returns the compressor of the prompt compression
+func (a *Agent) compressor() compression.Compressor {
+	if a.promptCompression.Compressor != nil {
+		return a.promptCompression.Compressor
+	}
+	return compression.NewExtractive(compression.WithTokenCounter(a.countTokens))
+}
+
+// compressText compresses text if it is long enough, returning it unchanged
+// if compression fails
+func (a *Agent) compressText(ctx context.Context, text, query string) string {
+	if a.countTokens(text) < a.promptCompression.MinTokens {
+		return text
+	}
+	compressed, err := a.compressor().Compress(ctx, text, query, a.promptCompression.Ratio)
+	if err != nil {
+		fmt.Printf("Failed to compress prompt text: %v\n", err)
+		return text
+	}
+	return compressed
+}
+
+// compressHistory returns the history with the contents of its older
+// messages compressed with respect to the query
+func (a *Agent) compressHistory(ctx context.Context, history []interfaces.Message, query string) []interfaces.Message {
+	if a.promptCompression == nil {
+		return history
+	}
+	end := len(history)
+	if a.promptCompression.KeepRecent > 0 {
+		end -= a.promptCompression.KeepRecent
+	}
+	if end <= 0 {
+		return history
+	}
+
+	compressed := make([]interfaces.Message, len(history))
+	copy(compressed, history)
+	for i := 0; i < end; i++ {
+		compressed[i].Content = a.compressText(ctx, compressed[i].Content, query)
+	}
+	return compressed
+}
+
+// compressTools wraps tools so that their long results are compressed with
+// respect to the query
+func (a *Agent) compressTools(tools []interfaces.Tool, query string) []interfaces.Tool {
+	if a.promptCompression == nil || a.promptCompression.SkipToolResults {
+		return tools
+	}
+	compressed := make([]interfaces.Tool, len(tools))
+	for i, tool := range tools {
+		compressed[i] = &compressedTool{tool: tool, query: query, agent: a}
+	}
+	return compressed
+}
+
+// compressedTool wraps a tool and compresses its results
+type compressedTool struct {
+	tool  interfaces.Tool
+	query string
+	agent *Agent
+}
+
+// Name returns the name of the tool
+func (t *compressedTool) Name() string {
+	return t.tool.Name()
+}
+
+// Description returns a description of what the tool does
+func (t *compressedTool) Description() string {
+	return t.tool.Description()
+}
+
+// Parameters returns the parameters that the tool accepts
+func (t *compressedTool) Parameters() map[string]interfaces.ParameterSpec {
+	return t.tool.Parameters()
+}
+
+// Examples returns the example invocations of the wrapped tool
+func (t *compressedTool) Examples() []interfaces.ToolExample {
+	return interfaces.ToolExamples(t.tool)
+}
+
+// Run executes the tool with the given input
+func (t *compressedTool) Run(ctx context.Context, input string) (string, error) {
+	result, err := t.tool.Run(ctx, input)
+	return t.compress(ctx, result, err)
+}
+
+// Execute executes the tool with the given arguments
+func (t *compressedTool) Execute(ctx context.Context, args string) (string, error) {
+	result, err := t.tool.Execute(ctx, args)
+	return t.compress(ctx, result, err)
+}
+
+// HasSideEffects reports whether the wrapped tool changes external systems
+func (t *compressedTool) HasSideEffects(args string) bool {
+	return interfaces.HasSideEffects(t.tool, args)
+}
+
+// DryRun simulates a call of the wrapped tool
+func (t *compressedTool) DryRun(ctx context.Context, args string) (string, error) {
+	result, err := interfaces.SimulateToolCall(ctx, t.tool, args)
+	return t.compress(ctx, result, err)
+}
+
+// compress compresses a tool result
+func (t *compressedTool) compress(ctx context.Context, result string, err error) (string, error) {
+	if err != nil {
+		return result, err
+	}
+	return t.agent.compressText(ctx, result, t.query), nil
+}
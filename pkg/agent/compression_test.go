@@ -0,0 +1,76 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/run-bigpig/llm-agent/pkg/compression"
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+	"github.com/run-bigpig/llm-agent/pkg/memory"
+)
+
+func TestPromptCompressionOfHistory(t *testing.T) {
+	// Only the first sentence of each compressed message is kept
+	firstSentence := compression.CompressorFunc(func(ctx context.Context, text, query string, ratio float64) (string, error) {
+		assert.Equal(t, "How long do refunds take?", query)
+		assert.Equal(t, 0.25, ratio)
+		first, _, _ := strings.Cut(text, ". ")
+		return first + ".", nil
+	})
+
+	mem := memory.NewConversationBuffer()
+	agent, err := NewAgent(
+		WithLLM(&MockLLM{}),
+		WithMemory(mem),
+		WithOrgID("acme"),
+		WithPromptCompression(PromptCompression{Compressor: firstSentence, Ratio: 0.25, MinTokens: 10}),
+	)
+	require.NoError(t, err)
+
+	ctx := agent.withOrgID(memory.WithConversationID(context.Background(), "conv"))
+	retrieved := "Refunds take 14 days. " + strings.Repeat("Our store opened in 1998. ", 10)
+	require.NoError(t, mem.AddMessage(ctx, interfaces.Message{Role: "system", Content: retrieved}))
+	require.NoError(t, mem.AddMessage(ctx, interfaces.Message{Role: "user", Content: "Hi"}))
+	require.NoError(t, mem.AddMessage(ctx, interfaces.Message{Role: "assistant", Content: strings.Repeat("Hello there, how can I help? ", 5)}))
+
+	assembled, err := agent.AssemblePrompt(ctx, "How long do refunds take?")
+	require.NoError(t, err)
+
+	// The retrieved context is compressed, short and recent messages are not
+	require.Len(t, assembled.Messages, 4)
+	assert.Equal(t, "Refunds take 14 days.", assembled.Messages[0].Content)
+	assert.Equal(t, "Hi", assembled.Messages[1].Content)
+	assert.Equal(t, strings.Repeat("Hello there, how can I help? ", 5), assembled.Messages[2].Content)
+
+	// The memory itself is unchanged
+	history, err := mem.GetMessages(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, retrieved, history[0].Content)
+}
+
+func TestPromptCompressionOfToolResults(t *testing.T) {
+	failing := compression.CompressorFunc(func(ctx context.Context, text, query string, ratio float64) (string, error) {
+		return "", errors.New("compressor unavailable")
+	})
+	long := strings.Repeat("Order 1234 shipped on Monday. ", 40)
+	tool := fetchTool{specTool: specTool{name: "orders"}, page: long}
+
+	agent, err := NewAgent(WithLLM(&MockLLM{}), WithPromptCompression(PromptCompression{}))
+	require.NoError(t, err)
+	result, err := agent.compressTools([]interfaces.Tool{tool}, "When did order 1234 ship?")[0].Execute(context.Background(), "{}")
+	require.NoError(t, err)
+	assert.Less(t, len(result), len(long)/2+10)
+	assert.Contains(t, result, "Order 1234 shipped on Monday.")
+
+	// Failures leave the result as it is
+	agent, err = NewAgent(WithLLM(&MockLLM{}), WithPromptCompression(PromptCompression{Compressor: failing}))
+	require.NoError(t, err)
+	result, err = agent.compressTools([]interfaces.Tool{tool}, "When did order 1234 ship?")[0].Execute(context.Background(), "{}")
+	require.NoError(t, err)
+	assert.Equal(t, long, result)
+}
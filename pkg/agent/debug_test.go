@@ -0,0 +1,76 @@
+package agent
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/run-bigpig/llm-agent/pkg/debug"
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+)
+
+func TestDebugRecorderTranscript(t *testing.T) {
+	recorder := debug.NewRecorder()
+	var calls int
+	agent, err := NewAgent(
+		WithName("notifier"),
+		WithLLM(&callAllLLM{}),
+		WithRequirePlanApproval(false),
+		WithTools(sendTool{specTool: specTool{name: "send"}, calls: &calls}),
+		WithDebugRecorder(recorder),
+	)
+	require.NoError(t, err)
+
+	response, report, err := agent.RunWithReport(context.Background(), "notify ops")
+	require.NoError(t, err)
+
+	transcript := recorder.Last()
+	require.NotNil(t, transcript)
+	assert.Equal(t, "notifier", transcript.Agent)
+	assert.Equal(t, "notify ops", transcript.Input)
+	assert.Equal(t, response, transcript.Output)
+	assert.Equal(t, report.RequestID, transcript.RequestID)
+	assert.Empty(t, transcript.Error)
+
+	// The tool call is recorded while the LLM call is in progress, which is
+	// recorded when it returns
+	require.Len(t, transcript.Entries, 2)
+	tool := transcript.Entries[0]
+	assert.Equal(t, debug.EntryToolCall, tool.Type)
+	assert.Equal(t, "send", tool.Name)
+	assert.Equal(t, `{"to":"ops"}`, tool.Input)
+	assert.Equal(t, `sent {"to":"ops"}`, tool.Output)
+
+	llm := transcript.Entries[1]
+	assert.Equal(t, debug.EntryLLMCall, llm.Type)
+	assert.Equal(t, "MockLLM", llm.Name)
+	assert.Equal(t, response, llm.Output)
+	assert.Equal(t, "send", llm.Metadata["tools"])
+}
+
+func TestNoTranscriptWithoutDebugRecorder(t *testing.T) {
+	var checked, recorded bool
+	agent, err := NewAgent(WithLLM(&contextCheckingLLM{check: func(ctx context.Context) {
+		checked = true
+		_, recorded = debug.TranscriptFromContext(ctx)
+	}}))
+	require.NoError(t, err)
+
+	_, err = agent.Run(context.Background(), "hi")
+	require.NoError(t, err)
+	assert.True(t, checked)
+	assert.False(t, recorded)
+}
+
+// contextCheckingLLM passes the context of each call to check
+type contextCheckingLLM struct {
+	MockLLM
+	check func(ctx context.Context)
+}
+
+func (m *contextCheckingLLM) Generate(ctx context.Context, prompt string, options ...interfaces.GenerateOption) (string, error) {
+	m.check(ctx)
+	return "hello", nil
+}
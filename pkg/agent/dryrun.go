@@ -0,0 +1,87 @@
+package agent
+
+import (
+	"context"
+	"slices"
+
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+)
+
+// WithDryRun makes every run of the agent a dry run: calls of tools that
+// change external systems are simulated instead of made, and plans report
+// what they would have done. A single run can be made a dry run by passing a
+// context from runctx.WithDryRun.
+func WithDryRun(dryRun bool) Option {
+	return func(a *Agent) {
+		a.dryRun = dryRun
+	}
+}
+
+// WithSideEffectTools names tools that change external systems but don't
+// declare it through interfaces.ToolWithSideEffects, so that dry runs
+// simulate their calls. Tools recorded in the outbox are included.
+func WithSideEffectTools(toolNames ...string) Option {
+	return func(a *Agent) {
+		a.sideEffectTools = append(a.sideEffectTools, toolNames...)
+	}
+}
+
+// hasSideEffects reports whether a call of the tool with the arguments
+// changes an external system
+func (a *Agent) hasSideEffects(tool interfaces.Tool, args string) bool {
+	return slices.Contains(a.sideEffectTools, tool.Name()) ||
+		slices.Contains(a.outboxTools, tool.Name()) ||
+		interfaces.HasSideEffects(tool, args)
+}
+
+// simulateTools wraps tools so that their calls with side effects are
+// simulated
+func (a *Agent) simulateTools(tools []interfaces.Tool) []interfaces.Tool {
+	simulated := make([]interfaces.Tool, len(tools))
+	for i, tool := range tools {
+		simulated[i] = &simulatedTool{tool: tool, agent: a}
+	}
+	return simulated
+}
+
+// simulatedTool wraps a tool and simulates its calls with side effects
+type simulatedTool struct {
+	tool  interfaces.Tool
+	agent *Agent
+}
+
+// Name returns the name of the tool
+func (t *simulatedTool) Name() string {
+	return t.tool.Name()
+}
+
+// Description returns a description of what the tool does
+func (t *simulatedTool) Description() string {
+	return t.tool.Description()
+}
+
+// Parameters returns the parameters that the tool accepts
+func (t *simulatedTool) Parameters() map[string]interfaces.ParameterSpec {
+	return t.tool.Parameters()
+}
+
+// Examples returns the example invocations of the wrapped tool
+func (t *simulatedTool) Examples() []interfaces.ToolExample {
+	return interfaces.ToolExamples(t.tool)
+}
+
+// Run executes the tool with the given input, or simulates it
+func (t *simulatedTool) Run(ctx context.Context, input string) (string, error) {
+	if t.agent.hasSideEffects(t.tool, input) {
+		return interfaces.SimulateToolCall(ctx, t.tool, input)
+	}
+	return t.tool.Run(ctx, input)
+}
+
+// Execute executes the tool with the given arguments, or simulates it
+func (t *simulatedTool) Execute(ctx context.Context, args string) (string, error) {
+	if t.agent.hasSideEffects(t.tool, args) {
+		return interfaces.SimulateToolCall(ctx, t.tool, args)
+	}
+	return t.tool.Execute(ctx, args)
+}
@@ -0,0 +1,133 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/run-bigpig/llm-agent/pkg/executionplan"
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+	"github.com/run-bigpig/llm-agent/pkg/runctx"
+)
+
+// sendTool counts its calls and declares side effects if send is set
+type sendTool struct {
+	specTool
+	send  bool
+	calls *int
+}
+
+func (t sendTool) Description() string { return "Sends things" }
+
+func (t sendTool) Execute(ctx context.Context, args string) (string, error) {
+	*t.calls++
+	return "sent " + args, nil
+}
+
+func (t sendTool) HasSideEffects(args string) bool { return t.send }
+
+// previewTool simulates its own calls
+type previewTool struct {
+	sendTool
+}
+
+func (t previewTool) DryRun(ctx context.Context, args string) (string, error) {
+	return "would send " + args, nil
+}
+
+// callAllLLM calls every tool it is given and joins the results
+type callAllLLM struct {
+	MockLLM
+}
+
+func (m *callAllLLM) GenerateWithTools(ctx context.Context, prompt string, tools []interfaces.Tool, options ...interfaces.GenerateOption) (string, error) {
+	results := make([]string, len(tools))
+	for i, tool := range tools {
+		result, err := tool.Execute(ctx, `{"to":"ops"}`)
+		if err != nil {
+			return "", err
+		}
+		results[i] = result
+	}
+	return strings.Join(results, "\n"), nil
+}
+
+func TestDryRunSimulatesSideEffects(t *testing.T) {
+	var calls int
+	agent, err := NewAgent(
+		WithLLM(&callAllLLM{}),
+		WithRequirePlanApproval(false),
+		WithSideEffectTools("named"),
+		WithTools(
+			sendTool{specTool: specTool{name: "declared"}, send: true, calls: &calls},
+			previewTool{sendTool{specTool: specTool{name: "preview"}, send: true, calls: &calls}},
+			sendTool{specTool: specTool{name: "named"}, calls: &calls},
+			sendTool{specTool: specTool{name: "read"}, calls: &calls},
+		),
+	)
+	require.NoError(t, err)
+
+	response, err := agent.Run(runctx.WithDryRun(context.Background(), true), "notify ops")
+	require.NoError(t, err)
+	lines := strings.Split(response, "\n")
+	require.Len(t, lines, 4)
+	assert.Contains(t, lines[0], "[dry run] declared was not called")
+	assert.Equal(t, `would send {"to":"ops"}`, lines[1])
+	assert.Contains(t, lines[2], "[dry run] named was not called")
+	assert.Equal(t, `sent {"to":"ops"}`, lines[3])
+	assert.Equal(t, 1, calls, "only the read-only tool should be called")
+
+	// Without the flag every tool is called
+	_, err = agent.Run(context.Background(), "notify ops")
+	require.NoError(t, err)
+	assert.Equal(t, 5, calls)
+}
+
+func TestDryRunOption(t *testing.T) {
+	var calls int
+	agent, err := NewAgent(
+		WithLLM(&callAllLLM{}),
+		WithRequirePlanApproval(false),
+		WithDryRun(true),
+		WithTools(sendTool{specTool: specTool{name: "declared"}, send: true, calls: &calls}),
+	)
+	require.NoError(t, err)
+
+	response, err := agent.Run(context.Background(), "notify ops")
+	require.NoError(t, err)
+	assert.Contains(t, response, "[dry run]")
+	assert.Zero(t, calls)
+}
+
+func TestDryRunPlan(t *testing.T) {
+	var calls int
+	agent, err := NewAgent(
+		WithLLM(&MockLLM{}),
+		WithSideEffectTools("named"),
+		WithTools(
+			sendTool{specTool: specTool{name: "named"}, calls: &calls},
+			previewTool{sendTool{specTool: specTool{name: "preview"}, send: true, calls: &calls}},
+			sendTool{specTool: specTool{name: "read"}, calls: &calls},
+		),
+	)
+	require.NoError(t, err)
+
+	plan := executionplan.NewExecutionPlan("Notify", []executionplan.ExecutionStep{
+		{ToolName: "named", Description: "notify", Input: "a"},
+		{ToolName: "preview", Description: "preview", Input: "b"},
+		{ToolName: "read", Description: "read", Input: "c"},
+	})
+	plan.UserApproved = true
+
+	result, err := agent.planExecutor.ExecutePlan(runctx.WithDryRun(context.Background(), true), plan)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(result, "Dry run of execution plan completed"))
+	assert.Contains(t, result, "Step 1 (notify) [dry run: named was not called]")
+	assert.Contains(t, result, "Step 2 (preview) [dry run: preview was not called]: would send b")
+	assert.Contains(t, result, "Step 3 (read): sent c")
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, executionplan.StatusApproved, plan.Status)
+}
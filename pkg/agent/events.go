@@ -0,0 +1,93 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/run-bigpig/llm-agent/pkg/events"
+)
+
+// WithEventPublisher publishes the agent's tool calls and finished runs, and
+// audit records of runs the caller was denied, so that other services can
+// react to them. Events carry sizes, durations and errors but not the inputs
+// and responses. Publishing happens during the run, so wrap remote
+// publishers in events.NewAsyncPublisher. Failures are logged and do not
+// fail the run.
+func WithEventPublisher(publisher events.Publisher) Option {
+	return func(a *Agent) {
+		a.eventPublisher = publisher
+	}
+}
+
+// publishRunEvents chains a handler that publishes run events to the
+// configured run event handler
+func (a *Agent) publishRunEvents() {
+	if a.eventPublisher == nil {
+		return
+	}
+	handler := a.runEventHandler
+	a.runEventHandler = func(ctx context.Context, event RunEvent) {
+		if handler != nil {
+			handler(ctx, event)
+		}
+		if e, ok := a.toEvent(ctx, event); ok {
+			a.publish(ctx, e)
+		}
+	}
+}
+
+// toEvent converts a run event into an event to publish
+func (a *Agent) toEvent(ctx context.Context, event RunEvent) (events.Event, bool) {
+	switch event.Type {
+	case RunEventToolCallStarted:
+		e := events.New(ctx, events.TypeToolCallStarted, a.name, event.ToolCall.ToolName, map[string]interface{}{
+			"input_size": event.ToolCall.InputSize,
+		})
+		e.Time = event.Timestamp
+		return e, true
+	case RunEventToolCallFinished:
+		data := map[string]interface{}{
+			"duration_ms": event.ToolCall.Duration.Milliseconds(),
+			"input_size":  event.ToolCall.InputSize,
+			"output_size": event.ToolCall.OutputSize,
+		}
+		if event.ToolCall.Error != "" {
+			data["error"] = event.ToolCall.Error
+		}
+		e := events.New(ctx, events.TypeToolCallFinished, a.name, event.ToolCall.ToolName, data)
+		e.Time = event.Timestamp
+		return e, true
+	case RunEventRunFinished:
+		report := event.Report
+		data := map[string]interface{}{
+			"duration_ms": report.Duration.Milliseconds(),
+			"tool_calls":  report.toolNames(),
+		}
+		if report.Error != "" {
+			data["error"] = report.Error
+		}
+		if report.CachedAnswer != nil {
+			data["cached"] = true
+		}
+		e := events.New(ctx, events.TypeRunFinished, a.name, a.name, data)
+		e.Time = event.Timestamp
+		if e.RequestID == "" {
+			e.RequestID = report.RequestID
+		}
+		if e.TraceID == "" {
+			e.TraceID = report.TraceID
+		}
+		return e, true
+	}
+	return events.Event{}, false
+}
+
+// publish publishes an event, logging failures
+func (a *Agent) publish(ctx context.Context, event events.Event) {
+	if a.eventPublisher == nil {
+		return
+	}
+	if err := a.eventPublisher.Publish(ctx, event); err != nil {
+		fmt.Printf("Failed to publish %s event: %v\n", event.Type, err)
+	}
+}
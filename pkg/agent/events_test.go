@@ -0,0 +1,63 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/run-bigpig/llm-agent/pkg/events"
+	"github.com/run-bigpig/llm-agent/pkg/rbac"
+	"github.com/run-bigpig/llm-agent/pkg/runctx"
+)
+
+func TestEventPublisher(t *testing.T) {
+	publisher := events.NewMemoryPublisher()
+	var handled []RunEventType
+	agent, err := NewAgent(
+		WithLLM(&MockLLM{}),
+		WithName("support"),
+		WithRunEventHandler(func(ctx context.Context, event RunEvent) {
+			handled = append(handled, event.Type)
+		}),
+		WithEventPublisher(publisher),
+	)
+	require.NoError(t, err)
+
+	_, report, err := agent.RunWithReport(runctx.WithOrgID(context.Background(), "acme"), "Hello")
+	require.NoError(t, err)
+
+	// The handler still gets the events
+	assert.Equal(t, []RunEventType{RunEventRunFinished}, handled)
+
+	published := publisher.Events()
+	require.Len(t, published, 1)
+	assert.Equal(t, events.TypeRunFinished, published[0].Type)
+	assert.Equal(t, "support", published[0].Source)
+	assert.Equal(t, "acme", published[0].OrgID)
+	assert.Equal(t, report.RequestID, published[0].RequestID)
+}
+
+func TestEventPublisherAuditsDeniedRuns(t *testing.T) {
+	policy, err := rbac.ParsePolicy([]byte("roles:\n  admin:\n    agents: [\"*\"]\n"))
+	require.NoError(t, err)
+	publisher := events.NewMemoryPublisher()
+	agent, err := NewAgent(
+		WithLLM(&MockLLM{}),
+		WithName("support"),
+		WithAuthorizer(policy),
+		WithEventPublisher(publisher),
+	)
+	require.NoError(t, err)
+
+	_, err = agent.Run(runctx.WithUserID(context.Background(), "mallory"), "Hello")
+	require.True(t, errors.Is(err, rbac.ErrDenied))
+
+	published := publisher.Events()
+	require.NotEmpty(t, published)
+	assert.Equal(t, events.TypeAudit, published[0].Type)
+	assert.Equal(t, "denied", published[0].Data["outcome"])
+	assert.Equal(t, "mallory", published[0].UserID)
+}
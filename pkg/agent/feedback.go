@@ -0,0 +1,32 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/run-bigpig/llm-agent/pkg/feedback"
+)
+
+// WithFeedbackRecorder sets where RecordFeedback stores user feedback
+func WithFeedbackRecorder(recorder *feedback.Recorder) Option {
+	return func(a *Agent) {
+		a.feedback = recorder
+	}
+}
+
+// RecordFeedback records a user's feedback on one of the agent's responses,
+// identified by the RequestID or TraceID of the run's report. The agent's
+// name and organization are filled in, and the remaining IDs are taken from
+// the context.
+func (a *Agent) RecordFeedback(ctx context.Context, f feedback.Feedback) (feedback.Feedback, error) {
+	if a.feedback == nil {
+		return feedback.Feedback{}, fmt.Errorf("no feedback recorder configured")
+	}
+	if f.Agent == "" {
+		f.Agent = a.name
+	}
+	if f.OrgID == "" {
+		f.OrgID = a.orgID
+	}
+	return a.feedback.Record(ctx, f)
+}
@@ -0,0 +1,47 @@
+package agent
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/run-bigpig/llm-agent/pkg/feedback"
+	"github.com/run-bigpig/llm-agent/pkg/runctx"
+)
+
+func TestRecordFeedback(t *testing.T) {
+	store := feedback.NewMemoryStore()
+	agent, err := NewAgent(
+		WithLLM(&MockLLM{}),
+		WithName("support"),
+		WithOrgID("org-1"),
+		WithFeedbackRecorder(feedback.NewRecorder(store)),
+	)
+	require.NoError(t, err)
+
+	ctx := runctx.WithTraceID(runctx.WithRequestID(context.Background(), "req-1"), "trace-1")
+	_, report, err := agent.RunWithReport(ctx, "hello")
+	require.NoError(t, err)
+	assert.Equal(t, "req-1", report.RequestID)
+	assert.Equal(t, "trace-1", report.TraceID)
+
+	recorded, err := agent.RecordFeedback(context.Background(), feedback.Feedback{
+		RequestID: report.RequestID,
+		TraceID:   report.TraceID,
+		Rating:    feedback.RatingUp,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "support", recorded.Agent)
+	assert.Equal(t, "org-1", recorded.OrgID)
+
+	stored, err := store.List(context.Background(), feedback.Filter{RequestID: "req-1"})
+	require.NoError(t, err)
+	assert.Len(t, stored, 1)
+
+	withoutRecorder, err := NewAgent(WithLLM(&MockLLM{}))
+	require.NoError(t, err)
+	_, err = withoutRecorder.RecordFeedback(context.Background(), recorded)
+	assert.Error(t, err)
+}
@@ -0,0 +1,109 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+)
+
+// defaultFollowUpQuestions is the number of follow-up questions suggested by
+// default
+const defaultFollowUpQuestions = 3
+
+// FollowUpQuestions suggests questions the user might ask next after each
+// answer, for chat UIs to offer as buttons
+type FollowUpQuestions struct {
+	// LLM suggests the questions. Defaults to the agent's LLM; a small model
+	// is enough.
+	LLM interfaces.LLM
+
+	// Count is the number of questions. Defaults to 3.
+	Count int
+}
+
+// WithFollowUpQuestions suggests follow-up questions after every successful
+// run with a separate LLM call. They are set on the run report and emitted
+// as a follow-up questions event after the response. Failures are logged and
+// do not fail the run.
+func WithFollowUpQuestions(questions FollowUpQuestions) Option {
+	return func(a *Agent) {
+		if questions.Count <= 0 {
+			questions.Count = defaultFollowUpQuestions
+		}
+		a.followUpQuestions = questions
+	}
+}
+
+// suggestFollowUpQuestions generates the follow-up questions of a run and
+// records them
+func (a *Agent) suggestFollowUpQuestions(ctx context.Context, input, response string, report *RunReport) {
+	if a.followUpQuestions.Count <= 0 || strings.TrimSpace(response) == "" {
+		return
+	}
+
+	questions, err := a.generateFollowUpQuestions(ctx, input, response)
+	if err != nil {
+		fmt.Printf("Failed to suggest follow-up questions: %v\n", err)
+		return
+	}
+	if len(questions) == 0 {
+		return
+	}
+
+	report.setFollowUpQuestions(questions)
+	a.emitRunEvent(ctx, RunEvent{Type: RunEventFollowUpQuestions, Timestamp: time.Now(), Questions: questions})
+}
+
+// generateFollowUpQuestions asks the LLM for questions the user might ask
+// after the answer
+func (a *Agent) generateFollowUpQuestions(ctx context.Context, input, response string) ([]string, error) {
+	llm := a.followUpQuestions.LLM
+	if llm == nil {
+		llm = a.llm
+	}
+	count := a.followUpQuestions.Count
+
+	prompt := fmt.Sprintf(`A user asked an assistant a question and got an answer. Suggest %d short questions the user is likely to ask next, written from the user's point of view, in the language of the conversation. Don't repeat the original question or ask about something the answer already covers.
+Answer with a JSON array of strings and nothing else.
+
+Question:
+%s
+
+Answer:
+%s`, count, input, response)
+
+	generated, err := llm.Generate(ctx, prompt)
+	if err != nil {
+		return nil, err
+	}
+
+	// Models sometimes wrap the array in a code block
+	generated = strings.TrimSpace(generated)
+	if start, end := strings.Index(generated, "["), strings.LastIndex(generated, "]"); start >= 0 && end > start {
+		generated = generated[start : end+1]
+	}
+	var suggested []string
+	if err := json.Unmarshal([]byte(generated), &suggested); err != nil {
+		return nil, fmt.Errorf("unexpected follow-up questions response %q: %w", generated, err)
+	}
+
+	var questions []string
+	seen := make(map[string]bool)
+	for _, question := range suggested {
+		question = strings.TrimSpace(question)
+		key := strings.ToLower(question)
+		if question == "" || seen[key] || strings.EqualFold(question, strings.TrimSpace(input)) {
+			continue
+		}
+		seen[key] = true
+		questions = append(questions, question)
+		if len(questions) == count {
+			break
+		}
+	}
+	return questions, nil
+}
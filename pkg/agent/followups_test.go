@@ -0,0 +1,54 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+)
+
+// followUpLLM answers questions and suggests follow-up questions
+type followUpLLM struct {
+	MockLLM
+	suggestions string
+}
+
+func (m *followUpLLM) Generate(ctx context.Context, prompt string, options ...interfaces.GenerateOption) (string, error) {
+	if strings.Contains(prompt, "likely to ask next") {
+		return m.suggestions, nil
+	}
+	return "Paris is the capital of France.", nil
+}
+
+func TestFollowUpQuestions(t *testing.T) {
+	llm := &followUpLLM{suggestions: "```json\n[\"What is the population of Paris?\", \"what is the population of paris?\", \"What is the capital of France?\", \"When was the Eiffel Tower built?\", \"What's the best time to visit?\"]\n```"}
+	agent, err := NewAgent(WithLLM(llm), WithFollowUpQuestions(FollowUpQuestions{}))
+	require.NoError(t, err)
+
+	events := collectRunEvents(agent.RunStream(context.Background(), "What is the capital of France?"))
+	types := make([]RunEventType, len(events))
+	for i, event := range events {
+		types[i] = event.Type
+	}
+	assert.Equal(t, []RunEventType{RunEventToken, RunEventFollowUpQuestions, RunEventRunFinished}, types)
+
+	// Duplicates and the original question are dropped
+	want := []string{"What is the population of Paris?", "When was the Eiffel Tower built?", "What's the best time to visit?"}
+	assert.Equal(t, want, events[1].Questions)
+	assert.Equal(t, want, events[2].Report.FollowUpQuestions)
+	assert.Equal(t, "Paris is the capital of France.", events[2].Content)
+}
+
+func TestFollowUpQuestionsFailureDoesNotFailTheRun(t *testing.T) {
+	agent, err := NewAgent(WithLLM(&followUpLLM{suggestions: "I can't think of any."}), WithFollowUpQuestions(FollowUpQuestions{Count: 2}))
+	require.NoError(t, err)
+
+	response, report, err := agent.RunWithReport(context.Background(), "What is the capital of France?")
+	require.NoError(t, err)
+	assert.Equal(t, "Paris is the capital of France.", response)
+	assert.Empty(t, report.FollowUpQuestions)
+}
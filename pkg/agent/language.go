@@ -0,0 +1,84 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+	"github.com/run-bigpig/llm-agent/pkg/language"
+	"github.com/run-bigpig/llm-agent/pkg/prompts"
+)
+
+// minLanguageConfidence is the confidence from which a detected language is
+// acted on
+const minLanguageConfidence = 0.5
+
+// WithResponseLanguage makes the agent answer in a language, given as an
+// ISO 639-1 code such as "es", whatever the language of the input. It sets
+// the prompts.SectionLanguage section of the system prompt, and responses
+// detected to be in another language are translated.
+func WithResponseLanguage(code string) Option {
+	return func(a *Agent) {
+		a.responseLanguage = code
+		a.promptSections().Set(prompts.Section{
+			Name:    prompts.SectionLanguage,
+			Content: fmt.Sprintf("Always respond in %s, whatever the language of the user's messages.", language.Name(code)),
+		})
+	}
+}
+
+// WithLanguageDetection makes the agent answer in the language of each input,
+// as detected by language.Detect. WithResponseLanguage takes precedence.
+func WithLanguageDetection() Option {
+	return func(a *Agent) {
+		a.detectLanguage = true
+	}
+}
+
+// responseLanguageFor returns the language to answer an input in, or an
+// empty string if any language will do
+func (a *Agent) responseLanguageFor(input string) string {
+	if a.responseLanguage != "" {
+		return a.responseLanguage
+	}
+	if a.detectLanguage {
+		if detection := language.Detect(input); detection.Confidence >= minLanguageConfidence {
+			return detection.Code
+		}
+	}
+	return ""
+}
+
+// languageInstruction asks for an answer in a detected language, for the
+// system prompt. A forced language is already in the system prompt.
+func (a *Agent) languageInstruction(code string) string {
+	if code == "" || a.responseLanguage != "" {
+		return ""
+	}
+	return fmt.Sprintf("Respond in %s, the language of the user's message.", language.Name(code))
+}
+
+// ensureLanguage translates a response that was detected to be in another
+// language than code
+func (a *Agent) ensureLanguage(ctx context.Context, response, code string) (string, error) {
+	if code == "" || strings.TrimSpace(response) == "" {
+		return response, nil
+	}
+	detection := language.Detect(response)
+	if detection.Confidence < minLanguageConfidence || strings.EqualFold(detection.Code, code) {
+		return response, nil
+	}
+
+	prompt := fmt.Sprintf("Translate the following text into %s. Keep its meaning and formatting, and reply with the translation only.\n\n%s", language.Name(code), response)
+	translated, err := a.llm.Generate(ctx, prompt, func(o *interfaces.GenerateOptions) {
+		if o.LLMConfig == nil {
+			o.LLMConfig = &interfaces.LLMConfig{}
+		}
+		o.LLMConfig.Temperature = 0
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to translate response into %s: %w", language.Name(code), err)
+	}
+	return strings.TrimSpace(translated), nil
+}
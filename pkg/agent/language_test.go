@@ -0,0 +1,64 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+)
+
+// languageLLM answers in English and translates on request
+type languageLLM struct {
+	systemMessageLLM
+	prompts []string
+}
+
+func (m *languageLLM) Generate(ctx context.Context, prompt string, options ...interfaces.GenerateOption) (string, error) {
+	m.prompts = append(m.prompts, prompt)
+	if strings.HasPrefix(prompt, "Translate the following text into Spanish") {
+		return "Su factura fue pagada el lunes.", nil
+	}
+	_, _ = m.systemMessageLLM.Generate(ctx, prompt, options...)
+	return "Your invoice was paid on Monday and the receipt is in your inbox.", nil
+}
+
+func TestResponseLanguage(t *testing.T) {
+	llm := &languageLLM{}
+	agent, err := NewAgent(
+		WithLLM(llm),
+		WithSystemPrompt("Answer questions about billing."),
+		WithResponseLanguage("es"),
+		WithRequirePlanApproval(false),
+	)
+	require.NoError(t, err)
+
+	response, err := agent.Run(context.Background(), "When was my invoice paid?")
+	require.NoError(t, err)
+	assert.Equal(t, "Su factura fue pagada el lunes.", response)
+	assert.Equal(t, "Answer questions about billing.\n\nAlways respond in Spanish, whatever the language of the user's messages.", llm.systemMessage)
+	assert.Len(t, llm.prompts, 2)
+}
+
+func TestLanguageDetection(t *testing.T) {
+	llm := &languageLLM{}
+	agent, err := NewAgent(
+		WithLLM(llm),
+		WithLanguageDetection(),
+		WithRequirePlanApproval(false),
+	)
+	require.NoError(t, err)
+
+	_, err = agent.Run(context.Background(), "Hola, ¿cuándo se pagó mi factura? Gracias")
+	require.NoError(t, err)
+	assert.Equal(t, "Respond in Spanish, the language of the user's message.", llm.systemMessage)
+
+	// English input gets the English response unchanged
+	response, err := agent.Run(context.Background(), "Hello, when was my invoice paid?")
+	require.NoError(t, err)
+	assert.Equal(t, "Respond in English, the language of the user's message.", llm.systemMessage)
+	assert.Equal(t, "Your invoice was paid on Monday and the receipt is in your inbox.", response)
+}
@@ -0,0 +1,42 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/run-bigpig/llm-agent/pkg/lease"
+	"github.com/run-bigpig/llm-agent/pkg/memory"
+	"github.com/run-bigpig/llm-agent/pkg/multitenancy"
+)
+
+// WithLeaseManager holds a lease on the conversation for the duration of each
+// run, so that replicas serving the same conversation don't run it at the
+// same time and duplicate tool side effects. A run fails with lease.ErrHeld if
+// another replica holds the lease, and is cancelled if the lease is lost.
+// Runs without a conversation ID in the context are not leased.
+func WithLeaseManager(manager *lease.Manager) Option {
+	return func(a *Agent) {
+		a.leases = manager
+	}
+}
+
+// acquireConversationLease leases the conversation in the context. It returns
+// the context to run under and a function that releases the lease.
+func (a *Agent) acquireConversationLease(ctx context.Context) (context.Context, func(), error) {
+	conversationID, ok := memory.GetConversationID(ctx)
+	if a.leases == nil || !ok {
+		return ctx, func() {}, nil
+	}
+
+	orgID, _ := multitenancy.GetOrgID(ctx)
+	l, err := a.leases.Acquire(ctx, fmt.Sprintf("conversation:%s:%s", orgID, conversationID))
+	if err != nil {
+		return nil, nil, err
+	}
+	release := func() {
+		if err := l.Release(context.WithoutCancel(ctx)); err != nil {
+			fmt.Printf("Failed to release conversation lease: %v\n", err)
+		}
+	}
+	return l.Context(), release, nil
+}
@@ -0,0 +1,52 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+	"github.com/run-bigpig/llm-agent/pkg/lifecycle"
+)
+
+// hangingLLM blocks until its context is done
+type hangingLLM struct {
+	MockLLM
+	started chan struct{}
+}
+
+func (m *hangingLLM) Generate(ctx context.Context, prompt string, options ...interfaces.GenerateOption) (string, error) {
+	close(m.started)
+	<-ctx.Done()
+	return "", context.Cause(ctx)
+}
+
+func TestLifecycleCancelsRunsAtDeadline(t *testing.T) {
+	manager := lifecycle.NewManager(lifecycle.WithTimeout(20 * time.Millisecond))
+	llm := &hangingLLM{started: make(chan struct{})}
+	agent, err := NewAgent(WithLLM(llm), WithLifecycle(manager))
+	require.NoError(t, err)
+
+	runErr := make(chan error, 1)
+	go func() {
+		_, err := agent.Run(context.Background(), "Hello")
+		runErr <- err
+	}()
+	<-llm.started
+
+	assert.Error(t, manager.Shutdown(context.Background()))
+
+	select {
+	case err := <-runErr:
+		assert.True(t, errors.Is(err, lifecycle.ErrShuttingDown), "expected the run to be cancelled by shutdown, got %v", err)
+	case <-time.After(time.Second):
+		t.Fatal("expected the in-flight run to be cancelled at the shutdown deadline")
+	}
+
+	_, err = agent.Run(context.Background(), "Hello again")
+	assert.ErrorIs(t, err, lifecycle.ErrShuttingDown)
+}
@@ -0,0 +1,193 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+	"github.com/run-bigpig/llm-agent/pkg/mcp"
+	"github.com/run-bigpig/llm-agent/pkg/multitenancy"
+	"github.com/run-bigpig/llm-agent/pkg/prompts"
+)
+
+// toolIndexer is implemented by tool selectors that can index tools ahead of
+// the first query, such as tools.SemanticSelector
+type toolIndexer interface {
+	Index(ctx context.Context, tools []interfaces.Tool) error
+}
+
+// validParameterTypes are the JSON schema types a tool parameter can have
+var validParameterTypes = map[string]bool{
+	"string":  true,
+	"number":  true,
+	"integer": true,
+	"boolean": true,
+	"array":   true,
+	"object":  true,
+}
+
+// Prepare does the work that would otherwise slow down the first run, so that
+// it can be done at startup, e.g. in a serverless function's init phase:
+//
+//   - renders the system prompt once instead of on every run, except for
+//     organizations that override sections of it
+//   - lists the tools of the MCP servers into their caches
+//   - validates the parameter schemas of all tools
+//   - indexes the tools in the tool selector, if it supports indexing
+//
+// Calling Prepare is optional. It returns every problem it finds; the agent
+// stays usable after an error.
+func (a *Agent) Prepare(ctx context.Context) error {
+	ctx = a.withOrgID(ctx)
+	var errs []error
+
+	a.preparedMu.Lock()
+	a.preparedSystemPrompt = a.renderSystemPrompt("")
+	a.systemPromptPrepared = true
+	a.preparedMu.Unlock()
+
+	tools := append([]interfaces.Tool(nil), a.tools...)
+	for i, server := range a.mcpServers {
+		serverTools, err := listMCPTools(ctx, server)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("MCP server %d: %w", i, err))
+			continue
+		}
+		tools = append(tools, serverTools...)
+	}
+
+	if err := validateTools(tools); err != nil {
+		errs = append(errs, err)
+	}
+
+	if indexer, ok := a.toolSelector.(toolIndexer); ok && len(tools) > 0 {
+		if err := indexer.Index(ctx, tools); err != nil {
+			errs = append(errs, fmt.Errorf("failed to index tools: %w", err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// systemPromptForRun returns the system prompt for the organization in the
+// context, reusing the prompt rendered by Prepare if it has no overrides
+func (a *Agent) systemPromptForRun(ctx context.Context) string {
+	orgID, _ := multitenancy.GetOrgID(ctx)
+	if a.systemPrompt != nil && a.systemPrompt.HasOverrides(orgID) {
+		return a.renderSystemPrompt(orgID)
+	}
+	a.preparedMu.RLock()
+	defer a.preparedMu.RUnlock()
+	if a.systemPromptPrepared {
+		return a.preparedSystemPrompt
+	}
+	return a.renderSystemPrompt("")
+}
+
+// renderSystemPrompt renders the system prompt sent with every generation,
+// with the section overrides of an organization
+func (a *Agent) renderSystemPrompt(orgID string) string {
+	var systemPrompt string
+	if a.systemPrompt != nil {
+		systemPrompt = a.systemPrompt.Render(orgID)
+	}
+	if a.responseFormat != nil && a.promptJSON {
+		// The LLM cannot enforce the format, so ask for it in the prompt
+		systemPrompt = strings.TrimSpace(systemPrompt + "\n\n" + jsonFormatInstructions(*a.responseFormat))
+	}
+	return systemPrompt
+}
+
+// baseSystemPrompt renders the system prompt sections without tenant
+// overrides or format instructions
+func (a *Agent) baseSystemPrompt() string {
+	if a.systemPrompt == nil {
+		return ""
+	}
+	return a.systemPrompt.Render("")
+}
+
+// promptSections returns the system prompt sections, creating them if needed
+func (a *Agent) promptSections() *prompts.SystemPrompt {
+	if a.systemPrompt == nil {
+		a.systemPrompt = prompts.NewSystemPrompt()
+	}
+	return a.systemPrompt
+}
+
+// listMCPTools lists the tools of an MCP server as agent tools
+func listMCPTools(ctx context.Context, server interfaces.MCPServer) ([]interfaces.Tool, error) {
+	tools, err := server.ListTools(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tools: %w", err)
+	}
+	agentTools := make([]interfaces.Tool, len(tools))
+	for i, tool := range tools {
+		agentTools[i] = mcp.NewMCPTool(tool.Name, tool.Description, tool.Schema, server)
+	}
+	return agentTools, nil
+}
+
+// validateTools checks that tool names are set and unique and that their
+// parameter schemas are well formed
+func validateTools(tools []interfaces.Tool) error {
+	var errs []error
+	seen := make(map[string]bool, len(tools))
+	for _, tool := range tools {
+		name := tool.Name()
+		if name == "" {
+			errs = append(errs, fmt.Errorf("tool without a name"))
+			continue
+		}
+		if seen[name] {
+			errs = append(errs, fmt.Errorf("duplicate tool name %q", name))
+		}
+		seen[name] = true
+
+		for param, spec := range tool.Parameters() {
+			if err := validateParameter(spec); err != nil {
+				errs = append(errs, fmt.Errorf("tool %s: parameter %s: %w", name, param, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// validateParameter checks a parameter spec and its item specs
+func validateParameter(spec interfaces.ParameterSpec) error {
+	if !validParameterTypes[spec.Type] {
+		return fmt.Errorf("invalid type %q", spec.Type)
+	}
+	if spec.Items != nil {
+		if spec.Type != "array" {
+			return fmt.Errorf("items set on a parameter of type %s", spec.Type)
+		}
+		if err := validateParameter(*spec.Items); err != nil {
+			return fmt.Errorf("items: %w", err)
+		}
+	}
+	for _, value := range spec.Enum {
+		if !matchesParameterType(value, spec.Type) {
+			return fmt.Errorf("enum value %v is not of type %s", value, spec.Type)
+		}
+	}
+	return nil
+}
+
+// matchesParameterType reports whether a value fits a JSON schema type
+func matchesParameterType(value interface{}, typ string) bool {
+	switch typ {
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "number", "integer":
+		n, ok := interfaces.FilterNumber(value)
+		return ok && (typ == "number" || n == float64(int64(n)))
+	}
+	return true
+}
@@ -0,0 +1,140 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+	"github.com/run-bigpig/llm-agent/pkg/memory"
+)
+
+// countingMCPServer counts ListTools calls
+type countingMCPServer struct {
+	interfaces.MCPServer
+	calls int
+	err   error
+}
+
+func (s *countingMCPServer) ListTools(ctx context.Context) ([]interfaces.MCPTool, error) {
+	s.calls++
+	return []interfaces.MCPTool{{Name: "read_file", Description: "Reads a file"}}, s.err
+}
+
+// specTool is a tool with fixed parameters
+type specTool struct {
+	interfaces.Tool
+	name   string
+	params map[string]interfaces.ParameterSpec
+}
+
+func (t specTool) Name() string                                    { return t.name }
+func (t specTool) Parameters() map[string]interfaces.ParameterSpec { return t.params }
+
+// recordingIndexer records the tools it was asked to index
+type recordingIndexer struct {
+	interfaces.ToolSelector
+	indexed []string
+}
+
+func (s *recordingIndexer) Select(ctx context.Context, query string, tools []interfaces.Tool) ([]interfaces.Tool, error) {
+	return tools, nil
+}
+
+func (s *recordingIndexer) Index(ctx context.Context, tools []interfaces.Tool) error {
+	for _, tool := range tools {
+		s.indexed = append(s.indexed, tool.Name())
+	}
+	return nil
+}
+
+func TestPrepare(t *testing.T) {
+	server := &countingMCPServer{}
+	selector := &recordingIndexer{}
+	agent, err := NewAgent(
+		WithLLM(&MockLLM{}),
+		WithMemory(memory.NewConversationBuffer()),
+		WithOrgID("org-1"),
+		WithSystemPrompt("You are a test agent."),
+		WithMCPServers([]interfaces.MCPServer{server}),
+		WithToolSelector(selector),
+		WithTools(specTool{name: "search", params: map[string]interfaces.ParameterSpec{
+			"query": {Type: "string", Required: true},
+			"limit": {Type: "integer", Enum: []interface{}{5, 10}},
+		}}),
+		WithRequirePlanApproval(false),
+	)
+	require.NoError(t, err)
+
+	require.NoError(t, agent.Prepare(context.Background()))
+	assert.Equal(t, 1, server.calls)
+	assert.Equal(t, []string{"search", "read_file"}, selector.indexed)
+	assert.Equal(t, "You are a test agent.", agent.systemPromptForRun(context.Background()))
+
+	// Runs reuse the cached MCP tool list
+	ctx := memory.WithConversationID(context.Background(), "conv")
+	_, err = agent.Run(ctx, "hello")
+	require.NoError(t, err)
+	_, err = agent.Run(ctx, "hello again")
+	require.NoError(t, err)
+	assert.Equal(t, 1, server.calls)
+}
+
+func TestPrepareReportsProblems(t *testing.T) {
+	server := &countingMCPServer{err: errors.New("connection refused")}
+	agent, err := NewAgent(
+		WithLLM(&MockLLM{}),
+		WithMCPServers([]interfaces.MCPServer{server}),
+		WithTools(
+			specTool{name: "search", params: map[string]interfaces.ParameterSpec{
+				"query": {Type: "text"},
+				"tags":  {Type: "string", Items: &interfaces.ParameterSpec{Type: "string"}},
+				"limit": {Type: "integer", Enum: []interface{}{5, 2.5}},
+			}},
+			specTool{name: "search"},
+		),
+	)
+	require.NoError(t, err)
+
+	err = agent.Prepare(context.Background())
+	require.Error(t, err)
+	for _, problem := range []string{
+		"connection refused",
+		`duplicate tool name "search"`,
+		`parameter query: invalid type "text"`,
+		"parameter tags: items set on a parameter of type string",
+		"parameter limit: enum value 2.5 is not of type integer",
+	} {
+		assert.Contains(t, err.Error(), problem)
+	}
+
+	// A failed listing isn't cached, so the next listing asks the server again
+	_, _ = agent.collectMCPTools(context.Background())
+	assert.Equal(t, 2, server.calls)
+}
+
+func TestMCPToolsAreCachedUntilInvalidated(t *testing.T) {
+	server := &countingMCPServer{}
+	agent, err := NewAgent(
+		WithLLM(&MockLLM{}),
+		WithMCPServers([]interfaces.MCPServer{server}),
+		WithMCPToolsTTL(0),
+		WithRequirePlanApproval(false),
+	)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		_, err = agent.Run(ctx, "hello")
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 1, server.calls)
+
+	agent.InvalidateMCPTools()
+	_, err = agent.Run(ctx, "hello")
+	require.NoError(t, err)
+	assert.Equal(t, 2, server.calls)
+}
@@ -0,0 +1,381 @@
+package agent
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/run-bigpig/llm-agent/pkg/classification"
+	"github.com/run-bigpig/llm-agent/pkg/debug"
+	"github.com/run-bigpig/llm-agent/pkg/executionplan"
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+	"github.com/run-bigpig/llm-agent/pkg/memory"
+)
+
+// ToolCallRecord records a single tool invocation during a run
+type ToolCallRecord struct {
+	// ToolName is the name of the tool that was called
+	ToolName string `json:"tool_name"`
+
+	// StartedAt is when the call started
+	StartedAt time.Time `json:"started_at"`
+
+	// Duration is how long the call took
+	Duration time.Duration `json:"duration"`
+
+	// InputSize is the size of the tool input in bytes
+	InputSize int `json:"input_size"`
+
+	// OutputSize is the size of the tool output in bytes
+	OutputSize int `json:"output_size"`
+
+	// Error is the error message if the call failed
+	Error string `json:"error,omitempty"`
+
+	// ErrorClass classifies the error, see ClassifyToolError
+	ErrorClass string `json:"error_class,omitempty"`
+}
+
+// ToolStats aggregates the calls made to a single tool during a run
+type ToolStats struct {
+	Calls           int           `json:"calls"`
+	Errors          int           `json:"errors"`
+	TotalDuration   time.Duration `json:"total_duration"`
+	AverageDuration time.Duration `json:"average_duration"`
+	TotalOutputSize int           `json:"total_output_size"`
+}
+
+// RunReport describes what happened during a single Agent.Run
+type RunReport struct {
+	// Input is the user input for the run
+	Input string `json:"input"`
+
+	// RequestID identifies the run in logs and feedback
+	RequestID string `json:"request_id,omitempty"`
+
+	// TraceID is the ID of the trace the run was recorded in, if a tracer
+	// started one
+	TraceID string `json:"trace_id,omitempty"`
+
+	// Version is the version of the agent that ran, if it has one
+	Version string `json:"version,omitempty"`
+
+	// StartedAt is when the run started
+	StartedAt time.Time `json:"started_at"`
+
+	// FinishedAt is when the run finished
+	FinishedAt time.Time `json:"finished_at"`
+
+	// Duration is how long the run took
+	Duration time.Duration `json:"duration"`
+
+	// ToolCalls lists every tool call in the order it started
+	ToolCalls []ToolCallRecord `json:"tool_calls"`
+
+	// Classification is the classification of the input, if the agent has an
+	// input classifier
+	Classification *classification.Classification `json:"classification,omitempty"`
+
+	// CachedAnswer is the semantic cache entry the response was taken from,
+	// if the agent answered from its cache
+	CachedAnswer *memory.CachedAnswer `json:"cached_answer,omitempty"`
+
+	// Usage is the token usage of the run's LLM calls as the LLM clients
+	// report it, with the estimated cost if the LLM is wrapped in cost.LLM
+	Usage interfaces.Usage `json:"usage"`
+
+	// Citations are the sources the LLM clients report for the run's
+	// responses, e.g. web pages found with Gemini's Google Search grounding
+	Citations []interfaces.Citation `json:"citations,omitempty"`
+
+	// FollowUpQuestions are questions the user might ask next, if the agent
+	// suggests follow-up questions
+	FollowUpQuestions []string `json:"follow_up_questions,omitempty"`
+
+	// Error is the error message if the run failed
+	Error string `json:"error,omitempty"`
+
+	mu sync.Mutex
+}
+
+// newRunReport creates a report for a run that is starting now
+func newRunReport(input string) *RunReport {
+	return &RunReport{
+		Input:     input,
+		StartedAt: time.Now(),
+		ToolCalls: []ToolCallRecord{},
+	}
+}
+
+// ToolStats returns the per-tool statistics for the run, keyed by tool name
+func (r *RunReport) ToolStats() map[string]ToolStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stats := make(map[string]ToolStats)
+	for _, call := range r.ToolCalls {
+		s := stats[call.ToolName]
+		s.Calls++
+		if call.Error != "" {
+			s.Errors++
+		}
+		s.TotalDuration += call.Duration
+		s.TotalOutputSize += call.OutputSize
+		s.AverageDuration = s.TotalDuration / time.Duration(s.Calls)
+		stats[call.ToolName] = s
+	}
+
+	return stats
+}
+
+// toolNames returns the names of the tools called so far, in call order
+func (r *RunReport) toolNames() []string {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	names := make([]string, len(r.ToolCalls))
+	for i, call := range r.ToolCalls {
+		names[i] = call.ToolName
+	}
+	return names
+}
+
+// addToolCall appends a finished tool call to the report
+func (r *RunReport) addToolCall(call ToolCallRecord) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.ToolCalls = append(r.ToolCalls, call)
+}
+
+// setIDs records the request and trace IDs of the run
+func (r *RunReport) setIDs(requestID, traceID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.RequestID = requestID
+	r.TraceID = traceID
+}
+
+// setClassification records the classification of the input
+func (r *RunReport) setClassification(c *classification.Classification) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Classification = c
+}
+
+// addUsage adds the token usage of an LLM request
+func (r *RunReport) addUsage(usage interfaces.Usage) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Usage.Add(usage)
+}
+
+// addCitations records sources of an LLM response
+func (r *RunReport) addCitations(citations []interfaces.Citation) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Citations = append(r.Citations, citations...)
+}
+
+// setFollowUpQuestions records the suggested follow-up questions
+func (r *RunReport) setFollowUpQuestions(questions []string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.FollowUpQuestions = questions
+}
+
+// setCachedAnswer records that the response came from the semantic cache
+func (r *RunReport) setCachedAnswer(answer memory.CachedAnswer) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.CachedAnswer = &answer
+}
+
+// finish marks the run as finished
+func (r *RunReport) finish(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.FinishedAt = time.Now()
+	r.Duration = r.FinishedAt.Sub(r.StartedAt)
+	if err != nil {
+		r.Error = err.Error()
+	}
+}
+
+// RunEventType is the type of a run event
+type RunEventType string
+
+const (
+	// RunEventToolCallStarted is emitted before a tool is called
+	RunEventToolCallStarted RunEventType = "tool_call_started"
+
+	// RunEventToolCallFinished is emitted after a tool call returns
+	RunEventToolCallFinished RunEventType = "tool_call_finished"
+
+	// RunEventRunFinished is emitted when the run finishes
+	RunEventRunFinished RunEventType = "run_finished"
+
+	// RunEventToken carries the next piece of the response; only RunStream
+	// emits it
+	RunEventToken RunEventType = "token"
+
+	// RunEventPlanGenerated is emitted when an execution plan was generated
+	// or modified; only RunStream emits it
+	RunEventPlanGenerated RunEventType = "plan_generated"
+
+	// RunEventToolProgress is emitted when a running tool reports progress,
+	// see interfaces.ReportToolProgress
+	RunEventToolProgress RunEventType = "tool_progress"
+
+	// RunEventFollowUpQuestions is emitted after the response with the
+	// suggested follow-up questions
+	RunEventFollowUpQuestions RunEventType = "follow_up_questions"
+
+	// RunEventThinking carries reasoning of the LLM when extended thinking
+	// is enabled, see interfaces.LLMConfig.Reasoning
+	RunEventThinking RunEventType = "thinking"
+)
+
+// RunEvent is emitted while an agent run is in progress
+type RunEvent struct {
+	// Type is the type of the event
+	Type RunEventType
+
+	// Timestamp is when the event occurred
+	Timestamp time.Time
+
+	// ToolCall is set for tool call and tool progress events. For started
+	// and progress events only the tool name, start time and input size are
+	// populated.
+	ToolCall *ToolCallRecord
+
+	// Progress is set for tool progress events
+	Progress *interfaces.ToolProgress
+
+	// Report is set for run finished events
+	Report *RunReport
+
+	// Content is the text of token and thinking events, and the response in
+	// the run finished events of RunStream
+	Content string
+
+	// Plan is set for plan generated events
+	Plan *executionplan.ExecutionPlan
+
+	// Questions is set for follow-up question events
+	Questions []string
+
+	// Err is the error of a failed run in the run finished events of
+	// RunStream
+	Err error
+}
+
+// RunEventHandler receives run events as they happen
+type RunEventHandler func(ctx context.Context, event RunEvent)
+
+// emitRunEvent passes an event to the agent's handler and the run's stream
+func (a *Agent) emitRunEvent(ctx context.Context, event RunEvent) {
+	if a.runEventHandler != nil {
+		a.runEventHandler(ctx, event)
+	}
+	streamRunEvent(ctx, event)
+}
+
+// instrumentedTool wraps a tool and records its calls in a run report
+type instrumentedTool struct {
+	tool    interfaces.Tool
+	report  *RunReport
+	handler RunEventHandler
+}
+
+// Name returns the name of the tool
+func (t *instrumentedTool) Name() string {
+	return t.tool.Name()
+}
+
+// Description returns a description of what the tool does
+func (t *instrumentedTool) Description() string {
+	return t.tool.Description()
+}
+
+// Parameters returns the parameters that the tool accepts
+func (t *instrumentedTool) Parameters() map[string]interfaces.ParameterSpec {
+	return t.tool.Parameters()
+}
+
+// Examples returns the example invocations of the wrapped tool
+func (t *instrumentedTool) Examples() []interfaces.ToolExample {
+	return interfaces.ToolExamples(t.tool)
+}
+
+// Run executes the tool with the given input
+func (t *instrumentedTool) Run(ctx context.Context, input string) (string, error) {
+	return t.record(ctx, input, t.tool.Run)
+}
+
+// Execute executes the tool with the given arguments
+func (t *instrumentedTool) Execute(ctx context.Context, args string) (string, error) {
+	return t.record(ctx, args, t.tool.Execute)
+}
+
+// record calls the tool and records the call in the report
+func (t *instrumentedTool) record(ctx context.Context, input string, call func(context.Context, string) (string, error)) (string, error) {
+	record := ToolCallRecord{
+		ToolName:  t.tool.Name(),
+		StartedAt: time.Now(),
+		InputSize: len(input),
+	}
+
+	started := record
+	t.emit(ctx, RunEvent{Type: RunEventToolCallStarted, Timestamp: record.StartedAt, ToolCall: &started})
+
+	progressCtx := interfaces.WithToolProgress(ctx, func(progress interfaces.ToolProgress) {
+		t.emit(ctx, RunEvent{Type: RunEventToolProgress, Timestamp: time.Now(), ToolCall: &started, Progress: &progress})
+	})
+	output, err := call(progressCtx, input)
+
+	record.Duration = time.Since(record.StartedAt)
+	record.OutputSize = len(output)
+	if err != nil {
+		record.Error = err.Error()
+		record.ErrorClass = ClassifyToolError(err)
+	}
+	t.report.addToolCall(record)
+
+	entry := debug.Entry{
+		Type:      debug.EntryToolCall,
+		Name:      record.ToolName,
+		StartedAt: record.StartedAt,
+		Duration:  record.Duration,
+		Input:     input,
+		Output:    output,
+		Error:     record.Error,
+	}
+	debug.Record(ctx, entry)
+
+	t.emit(ctx, RunEvent{Type: RunEventToolCallFinished, Timestamp: time.Now(), ToolCall: &record})
+
+	return output, err
+}
+
+// emit passes an event to the agent's handler and the run's stream
+func (t *instrumentedTool) emit(ctx context.Context, event RunEvent) {
+	if t.handler != nil {
+		t.handler(ctx, event)
+	}
+	streamRunEvent(ctx, event)
+}
+
+// instrumentTools wraps the tools so their calls are recorded in the report
+func (a *Agent) instrumentTools(tools []interfaces.Tool, report *RunReport) []interfaces.Tool {
+	instrumented := make([]interfaces.Tool, len(tools))
+	for i, tool := range tools {
+		instrumented[i] = &instrumentedTool{
+			tool:    tool,
+			report:  report,
+			handler: a.runEventHandler,
+		}
+	}
+	return instrumented
+}
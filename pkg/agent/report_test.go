@@ -0,0 +1,109 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunWithReportToolCalls(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		events []RunEvent
+		calls  int
+	)
+	agent, err := NewAgent(
+		WithLLM(&toolCallingLLM{}),
+		WithRequirePlanApproval(false),
+		WithTools(
+			sendTool{specTool: specTool{name: "send"}, calls: &calls},
+			failingTool{specTool: specTool{name: "search"}, err: errors.New("429 too many requests")},
+		),
+		WithRunEventHandler(func(ctx context.Context, event RunEvent) {
+			mu.Lock()
+			defer mu.Unlock()
+			events = append(events, event)
+		}),
+	)
+	require.NoError(t, err)
+
+	_, report, err := agent.RunWithReport(context.Background(), "notify ops")
+	require.NoError(t, err)
+
+	require.Len(t, report.ToolCalls, 2)
+	sent := report.ToolCalls[0]
+	assert.Equal(t, "send", sent.ToolName)
+	assert.Equal(t, len("{}"), sent.InputSize)
+	assert.Equal(t, len("sent {}"), sent.OutputSize)
+	assert.Empty(t, sent.Error)
+	assert.Empty(t, sent.ErrorClass)
+	assert.False(t, sent.StartedAt.IsZero())
+
+	failed := report.ToolCalls[1]
+	assert.Equal(t, "search", failed.ToolName)
+	assert.Equal(t, "429 too many requests", failed.Error)
+	assert.Equal(t, ToolErrorRateLimited, failed.ErrorClass)
+	assert.Zero(t, failed.OutputSize)
+
+	stats := report.ToolStats()
+	assert.Equal(t, 1, stats["send"].Calls)
+	assert.Zero(t, stats["send"].Errors)
+	assert.Equal(t, len("sent {}"), stats["send"].TotalOutputSize)
+	assert.Equal(t, 1, stats["search"].Errors)
+
+	mu.Lock()
+	defer mu.Unlock()
+	types := make([]RunEventType, len(events))
+	for i, event := range events {
+		types[i] = event.Type
+	}
+	assert.Equal(t, []RunEventType{
+		RunEventToolCallStarted, RunEventToolCallFinished,
+		RunEventToolCallStarted, RunEventToolCallFinished,
+		RunEventRunFinished,
+	}, types)
+
+	// Started events only carry what is known before the call
+	assert.Equal(t, "send", events[0].ToolCall.ToolName)
+	assert.Zero(t, events[0].ToolCall.OutputSize)
+	assert.Equal(t, sent, *events[1].ToolCall)
+	assert.Equal(t, ToolErrorRateLimited, events[3].ToolCall.ErrorClass)
+	assert.Same(t, report, events[4].Report)
+	assert.Same(t, report, agent.LastRunReport())
+}
+
+func TestRunReportToolStats(t *testing.T) {
+	report := newRunReport("hi")
+	report.addToolCall(ToolCallRecord{ToolName: "search", Duration: 100 * time.Millisecond, OutputSize: 10})
+	report.addToolCall(ToolCallRecord{ToolName: "search", Duration: 300 * time.Millisecond, OutputSize: 5, Error: "timeout"})
+	report.addToolCall(ToolCallRecord{ToolName: "send", Duration: 50 * time.Millisecond})
+
+	stats := report.ToolStats()
+	require.Len(t, stats, 2)
+	assert.Equal(t, ToolStats{
+		Calls:           2,
+		Errors:          1,
+		TotalDuration:   400 * time.Millisecond,
+		AverageDuration: 200 * time.Millisecond,
+		TotalOutputSize: 15,
+	}, stats["search"])
+	assert.Equal(t, ToolStats{
+		Calls:           1,
+		TotalDuration:   50 * time.Millisecond,
+		AverageDuration: 50 * time.Millisecond,
+	}, stats["send"])
+}
+
+func TestRunReportFinish(t *testing.T) {
+	report := newRunReport("hi")
+	report.finish(errors.New("boom"))
+
+	assert.Equal(t, "boom", report.Error)
+	assert.False(t, report.FinishedAt.Before(report.StartedAt))
+	assert.Equal(t, report.FinishedAt.Sub(report.StartedAt), report.Duration)
+}
@@ -0,0 +1,90 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+)
+
+// WithToolResultSanitizer passes the results of all tool calls through a
+// sanitizer before they reach the LLM, e.g. a guardrails.InjectionScanner that
+// neutralizes instructions injected into web pages or API responses
+func WithToolResultSanitizer(sanitizer interfaces.ContentSanitizer) Option {
+	return func(a *Agent) {
+		a.toolSanitizer = sanitizer
+	}
+}
+
+// sanitizeTools wraps tools so that their results are sanitized
+func (a *Agent) sanitizeTools(tools []interfaces.Tool) []interfaces.Tool {
+	if a.toolSanitizer == nil {
+		return tools
+	}
+	sanitized := make([]interfaces.Tool, len(tools))
+	for i, tool := range tools {
+		sanitized[i] = &sanitizedTool{tool: tool, sanitizer: a.toolSanitizer}
+	}
+	return sanitized
+}
+
+// sanitizedTool wraps a tool and sanitizes its results
+type sanitizedTool struct {
+	tool      interfaces.Tool
+	sanitizer interfaces.ContentSanitizer
+}
+
+// Name returns the name of the tool
+func (t *sanitizedTool) Name() string {
+	return t.tool.Name()
+}
+
+// Description returns a description of what the tool does
+func (t *sanitizedTool) Description() string {
+	return t.tool.Description()
+}
+
+// Parameters returns the parameters that the tool accepts
+func (t *sanitizedTool) Parameters() map[string]interfaces.ParameterSpec {
+	return t.tool.Parameters()
+}
+
+// Examples returns the example invocations of the wrapped tool
+func (t *sanitizedTool) Examples() []interfaces.ToolExample {
+	return interfaces.ToolExamples(t.tool)
+}
+
+// Run executes the tool with the given input
+func (t *sanitizedTool) Run(ctx context.Context, input string) (string, error) {
+	result, err := t.tool.Run(ctx, input)
+	return t.sanitize(ctx, result, err)
+}
+
+// Execute executes the tool with the given arguments
+func (t *sanitizedTool) Execute(ctx context.Context, args string) (string, error) {
+	result, err := t.tool.Execute(ctx, args)
+	return t.sanitize(ctx, result, err)
+}
+
+// HasSideEffects reports whether the wrapped tool changes external systems
+func (t *sanitizedTool) HasSideEffects(args string) bool {
+	return interfaces.HasSideEffects(t.tool, args)
+}
+
+// DryRun simulates a call of the wrapped tool
+func (t *sanitizedTool) DryRun(ctx context.Context, args string) (string, error) {
+	result, err := interfaces.SimulateToolCall(ctx, t.tool, args)
+	return t.sanitize(ctx, result, err)
+}
+
+// sanitize sanitizes a tool result
+func (t *sanitizedTool) sanitize(ctx context.Context, result string, err error) (string, error) {
+	if err != nil {
+		return result, err
+	}
+	sanitized, err := t.sanitizer.Sanitize(ctx, result)
+	if err != nil {
+		return "", fmt.Errorf("failed to sanitize result of tool %s: %w", t.tool.Name(), err)
+	}
+	return sanitized, nil
+}
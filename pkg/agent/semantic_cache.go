@@ -0,0 +1,79 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+	"github.com/run-bigpig/llm-agent/pkg/memory"
+)
+
+// semanticCacheMetadataKey marks assistant messages answered from the
+// semantic cache
+const semanticCacheMetadataKey = "semantic_cache"
+
+// WithSemanticCache answers inputs that are similar enough to an earlier
+// input with the earlier answer, without calling the LLM or any tools. It
+// suits agents whose answers don't depend on the conversation, like FAQ bots.
+// Answers are stored after successful runs with the tools they were produced
+// with, and are only reused for callers the authorizer lets use those tools.
+// Cache failures are logged and do not fail the run.
+func WithSemanticCache(cache *memory.SemanticCache) Option {
+	return func(a *Agent) {
+		a.semanticCache = cache
+	}
+}
+
+// answerFromCache returns the cached answer to a similar input, if there is
+// one, and adds it to memory
+func (a *Agent) answerFromCache(ctx context.Context, input string, report *RunReport) (string, bool, error) {
+	if a.semanticCache == nil {
+		return "", false, nil
+	}
+
+	cached, ok, err := a.semanticCache.Lookup(ctx, input)
+	if err != nil {
+		fmt.Printf("Failed to look up semantic cache: %v\n", err)
+		return "", false, nil
+	}
+	if !ok {
+		return "", false, nil
+	}
+
+	// Don't hand out answers based on tools the caller may not use
+	if a.authorizer != nil {
+		for _, tool := range cached.Tools {
+			allowed, err := a.authorizer.CanUseTool(ctx, tool)
+			if err != nil {
+				fmt.Printf("Failed to authorize cached answer: %v\n", err)
+				return "", false, nil
+			}
+			if !allowed {
+				return "", false, nil
+			}
+		}
+	}
+
+	report.setCachedAnswer(cached)
+	if a.memory != nil {
+		if err := a.memory.AddMessage(ctx, interfaces.Message{
+			Role:     "assistant",
+			Content:  cached.Answer,
+			Metadata: map[string]interface{}{semanticCacheMetadataKey: true},
+		}); err != nil {
+			return "", false, fmt.Errorf("failed to add cached response to memory: %w", err)
+		}
+	}
+	return cached.Answer, true, nil
+}
+
+// storeCachedAnswer stores the answer to an input in the semantic cache, with
+// the tools called in the run
+func (a *Agent) storeCachedAnswer(ctx context.Context, input, response string, report *RunReport) {
+	if a.semanticCache == nil {
+		return
+	}
+	if err := a.semanticCache.Store(ctx, input, response, report.toolNames()...); err != nil {
+		fmt.Printf("Failed to store answer in semantic cache: %v\n", err)
+	}
+}
@@ -0,0 +1,118 @@
+package agent
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+	"github.com/run-bigpig/llm-agent/pkg/memory"
+	"github.com/run-bigpig/llm-agent/pkg/multitenancy"
+	"github.com/run-bigpig/llm-agent/pkg/rbac"
+	"github.com/run-bigpig/llm-agent/pkg/runctx"
+)
+
+// countingLLM counts Generate calls
+type countingLLM struct {
+	MockLLM
+	calls int
+}
+
+func (m *countingLLM) Generate(ctx context.Context, prompt string, options ...interfaces.GenerateOption) (string, error) {
+	m.calls++
+	return "We are open from 9 to 5.", nil
+}
+
+// questionEmbedder embeds the two phrasings of the opening hours question
+// close together
+type questionEmbedder struct {
+	interfaces.Embedder
+}
+
+func (e *questionEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	switch text {
+	case "What are your opening hours?":
+		return []float32{1, 0}, nil
+	case "When are you open?":
+		return []float32{0.99, 0.05}, nil
+	}
+	return []float32{0, 1}, nil
+}
+
+func TestSemanticCache(t *testing.T) {
+	llm := &countingLLM{}
+	mem := memory.NewConversationBuffer()
+	agent, err := NewAgent(
+		WithLLM(llm),
+		WithMemory(mem),
+		WithOrgID("org-1"),
+		WithSemanticCache(memory.NewSemanticCache(&questionEmbedder{})),
+	)
+	require.NoError(t, err)
+
+	ctx := memory.WithConversationID(context.Background(), "conv")
+	response, report, err := agent.RunWithReport(ctx, "What are your opening hours?")
+	require.NoError(t, err)
+	assert.Equal(t, "We are open from 9 to 5.", response)
+	assert.Nil(t, report.CachedAnswer)
+	assert.Equal(t, 1, llm.calls)
+
+	// A similar question is answered from the cache
+	response, report, err = agent.RunWithReport(ctx, "When are you open?")
+	require.NoError(t, err)
+	assert.Equal(t, "We are open from 9 to 5.", response)
+	require.NotNil(t, report.CachedAnswer)
+	assert.Equal(t, "What are your opening hours?", report.CachedAnswer.Query)
+	assert.Equal(t, 1, llm.calls)
+
+	messages, err := mem.GetMessages(agent.withOrgID(ctx))
+	require.NoError(t, err)
+	require.Len(t, messages, 4)
+	assert.Equal(t, true, messages[3].Metadata[semanticCacheMetadataKey])
+
+	// A different question goes to the LLM
+	_, _, err = agent.RunWithReport(ctx, "How do I reset my password?")
+	require.NoError(t, err)
+	assert.Equal(t, 2, llm.calls)
+}
+
+func TestSemanticCacheChecksTools(t *testing.T) {
+	policy, err := rbac.ParsePolicy([]byte(`roles:
+  admin:
+    agents: ["*"]
+    tools: ["*"]
+  viewer:
+    agents: ["*"]
+users:
+  alice: [admin]
+  bob: [viewer]
+`))
+	require.NoError(t, err)
+	llm := &countingLLM{}
+	cache := memory.NewSemanticCache(&questionEmbedder{}, memory.WithSemanticCacheScope(memory.SemanticCacheOrgScope))
+	agent, err := NewAgent(
+		WithLLM(llm),
+		WithOrgID("org-1"),
+		WithAuthorizer(policy),
+		WithSemanticCache(cache),
+	)
+	require.NoError(t, err)
+
+	// Alice's answer was produced with a tool bob may not use
+	alice := runctx.WithUserID(multitenancy.WithOrgID(context.Background(), "org-1"), "alice")
+	require.NoError(t, cache.Store(alice, "What are your opening hours?", "From 9 to 5, says the CRM.", "crm_lookup"))
+
+	response, report, err := agent.RunWithReport(runctx.WithUserID(context.Background(), "alice"), "When are you open?")
+	require.NoError(t, err)
+	assert.Equal(t, "From 9 to 5, says the CRM.", response)
+	require.NotNil(t, report.CachedAnswer)
+	assert.Equal(t, []string{"crm_lookup"}, report.CachedAnswer.Tools)
+	assert.Equal(t, 0, llm.calls)
+
+	_, report, err = agent.RunWithReport(runctx.WithUserID(context.Background(), "bob"), "When are you open?")
+	require.NoError(t, err)
+	assert.Nil(t, report.CachedAnswer)
+	assert.Equal(t, 1, llm.calls)
+}
@@ -0,0 +1,214 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/run-bigpig/llm-agent/pkg/artifact"
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+	"github.com/run-bigpig/llm-agent/pkg/tools/artifactreader"
+)
+
+// defaultExcerptBytes is the size of the excerpts of a spilled result
+const defaultExcerptBytes = 1000
+
+// ToolResultSpill saves tool results over a size limit, such as file reads
+// or scrapes of several megabytes, as artifacts. The LLM gets a reference to
+// the artifact with excerpts from its start and end, and can read the rest
+// with the read_artifact tool.
+type ToolResultSpill struct {
+	// MaxBytes is the size above which results are saved instead of added
+	// to the conversation; zero disables spilling
+	MaxBytes int
+
+	// ExcerptBytes is the size of the excerpts from the start and the end
+	// of a saved result. Defaults to 1000 bytes each.
+	ExcerptBytes int
+}
+
+// WithToolResultSpill saves tool results larger than spill.MaxBytes in the
+// artifact store, or in a temporary directory without one, and adds the
+// read_artifact tool. Tools implementing interfaces.ToolWithReader stream
+// their results to the store without holding them in memory.
+func WithToolResultSpill(spill ToolResultSpill) Option {
+	return func(a *Agent) {
+		a.resultSpill = spill
+	}
+}
+
+// setupResultSpill adds the read_artifact tool and, without an artifact
+// store, a store in a temporary directory
+func (a *Agent) setupResultSpill() {
+	if a.resultSpill.MaxBytes <= 0 {
+		return
+	}
+	if a.resultSpill.ExcerptBytes <= 0 {
+		a.resultSpill.ExcerptBytes = defaultExcerptBytes
+	}
+	if a.artifactStore == nil {
+		a.spillStore = artifact.NewLocalStore(filepath.Join(os.TempDir(), "agent-tool-results"))
+	}
+	for _, tool := range a.tools {
+		if tool.Name() == artifactreader.Name {
+			return
+		}
+	}
+	a.tools = append(append([]interfaces.Tool{}, a.tools...), artifactreader.New())
+}
+
+// spillToolResults wraps tools so that their large results are saved as
+// artifacts
+func (a *Agent) spillToolResults(tools []interfaces.Tool) []interfaces.Tool {
+	if a.resultSpill.MaxBytes <= 0 {
+		return tools
+	}
+	spilled := make([]interfaces.Tool, len(tools))
+	for i, tool := range tools {
+		if tool.Name() == artifactreader.Name {
+			spilled[i] = tool
+			continue
+		}
+		spilled[i] = &spilledTool{tool: tool, spill: a.resultSpill}
+	}
+	return spilled
+}
+
+// spilledTool wraps a tool and saves its large results as artifacts
+type spilledTool struct {
+	tool  interfaces.Tool
+	spill ToolResultSpill
+}
+
+// Name returns the name of the tool
+func (t *spilledTool) Name() string {
+	return t.tool.Name()
+}
+
+// Description returns a description of what the tool does
+func (t *spilledTool) Description() string {
+	return t.tool.Description()
+}
+
+// Parameters returns the parameters that the tool accepts
+func (t *spilledTool) Parameters() map[string]interfaces.ParameterSpec {
+	return t.tool.Parameters()
+}
+
+// Examples returns the example invocations of the wrapped tool
+func (t *spilledTool) Examples() []interfaces.ToolExample {
+	return interfaces.ToolExamples(t.tool)
+}
+
+// Run executes the tool with the given input
+func (t *spilledTool) Run(ctx context.Context, input string) (string, error) {
+	result, err := t.tool.Run(ctx, input)
+	return t.spillString(ctx, result, err)
+}
+
+// Execute executes the tool with the given arguments, streaming the result
+// if the tool supports it
+func (t *spilledTool) Execute(ctx context.Context, args string) (string, error) {
+	if streaming, ok := t.tool.(interfaces.ToolWithReader); ok {
+		r, err := streaming.ExecuteReader(ctx, args)
+		if err != nil {
+			return "", err
+		}
+		defer r.Close()
+		return t.spillReader(ctx, r)
+	}
+	result, err := t.tool.Execute(ctx, args)
+	return t.spillString(ctx, result, err)
+}
+
+// HasSideEffects reports whether the wrapped tool changes external systems
+func (t *spilledTool) HasSideEffects(args string) bool {
+	return interfaces.HasSideEffects(t.tool, args)
+}
+
+// DryRun simulates a call of the wrapped tool
+func (t *spilledTool) DryRun(ctx context.Context, args string) (string, error) {
+	return interfaces.SimulateToolCall(ctx, t.tool, args)
+}
+
+// spillString saves a result over the limit
+func (t *spilledTool) spillString(ctx context.Context, result string, err error) (string, error) {
+	if err != nil || len(result) <= t.spill.MaxBytes {
+		return result, err
+	}
+	manager, ok := artifact.ManagerFromContext(ctx)
+	if !ok {
+		return result, nil
+	}
+	saved, err := manager.Save(ctx, t.artifactName(), "", strings.NewReader(result))
+	if err != nil {
+		fmt.Printf("Failed to save result of tool %s: %v\n", t.tool.Name(), err)
+		return result, nil
+	}
+	return t.reference(saved, result, result), nil
+}
+
+// spillReader reads a streamed result, saving it if it is over the limit
+// without holding more than the limit in memory
+func (t *spilledTool) spillReader(ctx context.Context, r io.Reader) (string, error) {
+	head := make([]byte, t.spill.MaxBytes+1)
+	n, err := io.ReadFull(r, head)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return string(head[:n]), nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read result of tool %s: %w", t.tool.Name(), err)
+	}
+
+	manager, ok := artifact.ManagerFromContext(ctx)
+	if !ok {
+		rest, err := io.ReadAll(r)
+		if err != nil {
+			return "", fmt.Errorf("failed to read result of tool %s: %w", t.tool.Name(), err)
+		}
+		return string(head) + string(rest), nil
+	}
+
+	// Keep more than the excerpt so that it can be cut at a line break
+	tail := &tailBuffer{size: 2 * t.spill.ExcerptBytes}
+	content := io.TeeReader(io.MultiReader(bytes.NewReader(head), r), tail)
+	saved, err := manager.Save(ctx, t.artifactName(), "", content)
+	if err != nil {
+		return "", fmt.Errorf("failed to save result of tool %s: %w", t.tool.Name(), err)
+	}
+	return t.reference(saved, string(head), string(tail.buf)), nil
+}
+
+// artifactName is the file name of a saved result
+func (t *spilledTool) artifactName() string {
+	return t.tool.Name() + "-result.txt"
+}
+
+// reference describes a saved result with excerpts from its start and end
+func (t *spilledTool) reference(saved *artifact.Artifact, start, end string) string {
+	excerpt := t.spill.ExcerptBytes
+	return fmt.Sprintf(`[The result of %s is too large to include (%d bytes). It was saved as %s; use the %s tool to read parts of it or search it.]
+
+--- Start of the result ---
+%s
+--- End of the result ---
+%s`, t.tool.Name(), saved.Size, saved.URI(), artifactreader.Name, cutHead(start, excerpt), cutTail(end, excerpt))
+}
+
+// tailBuffer keeps the last size bytes written to it
+type tailBuffer struct {
+	buf  []byte
+	size int
+}
+
+func (b *tailBuffer) Write(p []byte) (int, error) {
+	b.buf = append(b.buf, p...)
+	if len(b.buf) > b.size {
+		b.buf = append([]byte(nil), b.buf[len(b.buf)-b.size:]...)
+	}
+	return len(p), nil
+}
@@ -0,0 +1,92 @@
+package agent
+
+import (
+	"context"
+	"io"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/run-bigpig/llm-agent/pkg/artifact"
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+	"github.com/run-bigpig/llm-agent/pkg/tools/artifactreader"
+)
+
+// streamTool streams a result without building it as a string
+type streamTool struct {
+	specTool
+	lines int
+}
+
+func (t streamTool) ExecuteReader(ctx context.Context, args string) (io.ReadCloser, error) {
+	return io.NopCloser(strings.NewReader(numberedLines(t.lines))), nil
+}
+
+func (t streamTool) Execute(ctx context.Context, args string) (string, error) {
+	panic("expected the result to be streamed")
+}
+
+var artifactURI = regexp.MustCompile(`artifact://[0-9a-f-]+`)
+
+func TestToolResultSpill(t *testing.T) {
+	agent, err := NewAgent(
+		WithLLM(&MockLLM{}),
+		WithArtifactStore(artifact.NewLocalStore(t.TempDir())),
+		WithTools(fetchTool{specTool: specTool{name: "fetch"}, page: numberedLines(2000)}),
+		WithToolResultSpill(ToolResultSpill{MaxBytes: 1000, ExcerptBytes: 100}),
+	)
+	require.NoError(t, err)
+	require.Len(t, agent.tools, 2)
+	assert.Equal(t, artifactreader.Name, agent.tools[1].Name())
+
+	ctx := artifact.WithManager(context.Background(), artifact.NewManager(agent.artifactStore))
+	tools := agent.spillToolResults([]interfaces.Tool{
+		agent.tools[0],
+		fetchTool{specTool: specTool{name: "short"}, page: "row 000"},
+		streamTool{specTool: specTool{name: "stream"}, lines: 2000},
+		streamTool{specTool: specTool{name: "small_stream"}, lines: 2},
+		agent.tools[1],
+	})
+
+	result, err := tools[0].Execute(ctx, "{}")
+	require.NoError(t, err)
+	assert.Contains(t, result, "The result of fetch is too large to include (16999 bytes)")
+	assert.Contains(t, result, "--- Start of the result ---\nrow 000\n")
+	assert.True(t, strings.HasSuffix(result, "\nrow 1999"))
+	assert.Less(t, len(result), 600)
+
+	// The LLM can read the rest through the reader tool
+	uri := artifactURI.FindString(result)
+	require.NotEmpty(t, uri)
+	page, err := tools[4].Execute(ctx, `{"artifact":"`+uri+`","query":"row 1234"}`)
+	require.NoError(t, err)
+	assert.Contains(t, page, "1235: row 1234")
+
+	// Short results are left alone
+	result, err = tools[1].Execute(ctx, "{}")
+	require.NoError(t, err)
+	assert.Equal(t, "row 000", result)
+
+	// Streamed results are saved as they are read
+	result, err = tools[2].Execute(ctx, "{}")
+	require.NoError(t, err)
+	assert.Contains(t, result, "The result of stream is too large to include (16999 bytes)")
+	assert.True(t, strings.HasSuffix(result, "\nrow 1999"))
+	result, err = tools[3].Execute(ctx, "{}")
+	require.NoError(t, err)
+	assert.Equal(t, "row 000\nrow 001", result)
+}
+
+func TestToolResultSpillWithoutArtifactStore(t *testing.T) {
+	agent, err := NewAgent(
+		WithLLM(&MockLLM{}),
+		WithToolResultSpill(ToolResultSpill{MaxBytes: 1000}),
+	)
+	require.NoError(t, err)
+	require.NotNil(t, agent.spillStore)
+	assert.Nil(t, agent.artifactStore)
+	assert.Equal(t, defaultExcerptBytes, agent.resultSpill.ExcerptBytes)
+}
@@ -0,0 +1,100 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+)
+
+// runEventStream receives the events of a run started with RunStream
+type runEventStream struct {
+	send   func(event RunEvent)
+	tokens atomic.Bool // Whether tokens of the response were sent
+}
+
+type runEventStreamKey struct{}
+
+// streamRunEvent sends an event to the stream of the run, if it has one
+func streamRunEvent(ctx context.Context, event RunEvent) {
+	if stream, ok := ctx.Value(runEventStreamKey{}).(*runEventStream); ok {
+		if event.Type == RunEventToken {
+			stream.tokens.Store(true)
+		}
+		stream.send(event)
+	}
+}
+
+// RunStream runs the agent like Run and reports its progress as events: the
+// tool calls as they start and finish, generated execution plans, the tokens
+// of the response and, last, a run finished event with the response, the
+// report and the error of a failed run. The channel is closed after the run
+// finished event. Read it to the end or cancel the context.
+//
+// Tokens are streamed as the LLM generates them when the run calls no tools
+// and the response needs no further processing. Otherwise, e.g. with tools,
+// output guardrails or a response language, the final response is sent as a
+// single token event.
+func (a *Agent) RunStream(ctx context.Context, input string) <-chan RunEvent {
+	events := make(chan RunEvent, 16)
+	stream := &runEventStream{
+		send: func(event RunEvent) {
+			select {
+			case events <- event:
+			case <-ctx.Done():
+			}
+		},
+	}
+
+	go func() {
+		defer close(events)
+		response, report, err := a.RunWithReport(context.WithValue(ctx, runEventStreamKey{}, stream), input)
+		finished := RunEvent{Type: RunEventRunFinished, Timestamp: time.Now(), Content: response, Report: report, Err: err}
+		if report != nil {
+			finished.Timestamp = report.FinishedAt
+		}
+		stream.send(finished)
+	}()
+	return events
+}
+
+// streamResponse sends the response of a run as a single token event if its
+// tokens were not streamed as they were generated
+func streamResponse(ctx context.Context, response string) {
+	if stream, ok := ctx.Value(runEventStreamKey{}).(*runEventStream); ok && response != "" && !stream.tokens.Load() {
+		streamRunEvent(ctx, RunEvent{Type: RunEventToken, Timestamp: time.Now(), Content: response})
+	}
+}
+
+// canStreamTokens reports whether the response of a run can be streamed as
+// the LLM generates it: the run must be streamed and nothing may change the
+// response afterwards
+func (a *Agent) canStreamTokens(ctx context.Context, responseLanguage string) bool {
+	_, ok := ctx.Value(runEventStreamKey{}).(*runEventStream)
+	return ok && a.guardrails == nil && responseLanguage == ""
+}
+
+// generateStream generates a response, streaming its tokens to the run's
+// event stream, and returns the full response
+func (a *Agent) generateStream(ctx context.Context, prompt string, options []interfaces.GenerateOption) (string, error) {
+	events, err := interfaces.GenerateStream(ctx, a.llm, prompt, options...)
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	for event := range events {
+		switch event.Type {
+		case interfaces.StreamEventText:
+			sb.WriteString(event.Content)
+			streamRunEvent(ctx, RunEvent{Type: RunEventToken, Timestamp: time.Now(), Content: event.Content})
+		case interfaces.StreamEventThinking:
+			a.emitRunEvent(ctx, RunEvent{Type: RunEventThinking, Timestamp: time.Now(), Content: event.Content})
+		case interfaces.StreamEventError:
+			return sb.String(), event.Err
+		}
+	}
+	return sb.String(), ctx.Err()
+}
@@ -0,0 +1,82 @@
+package agent
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+)
+
+// streamingLLM streams a fixed response in pieces
+type streamingLLM struct {
+	MockLLM
+	pieces []string
+}
+
+func (m *streamingLLM) GenerateStream(ctx context.Context, prompt string, options ...interfaces.GenerateOption) (<-chan interfaces.StreamEvent, error) {
+	events := make(chan interfaces.StreamEvent, len(m.pieces)+1)
+	for _, piece := range m.pieces {
+		events <- interfaces.StreamEvent{Type: interfaces.StreamEventText, Content: piece}
+	}
+	events <- interfaces.StreamEvent{Type: interfaces.StreamEventDone}
+	close(events)
+	return events, nil
+}
+
+func collectRunEvents(events <-chan RunEvent) []RunEvent {
+	var collected []RunEvent
+	for event := range events {
+		collected = append(collected, event)
+	}
+	return collected
+}
+
+func TestRunStreamTokens(t *testing.T) {
+	agent, err := NewAgent(WithLLM(&streamingLLM{pieces: []string{"Hel", "lo"}}))
+	require.NoError(t, err)
+
+	events := collectRunEvents(agent.RunStream(context.Background(), "hi"))
+	require.Len(t, events, 3)
+	assert.Equal(t, RunEvent{Type: RunEventToken, Timestamp: events[0].Timestamp, Content: "Hel"}, events[0])
+	assert.Equal(t, "lo", events[1].Content)
+
+	finished := events[2]
+	assert.Equal(t, RunEventRunFinished, finished.Type)
+	assert.Equal(t, "Hello", finished.Content)
+	assert.NoError(t, finished.Err)
+	require.NotNil(t, finished.Report)
+}
+
+func TestRunStreamToolCalls(t *testing.T) {
+	var calls int
+	agent, err := NewAgent(
+		WithLLM(&callAllLLM{}),
+		WithRequirePlanApproval(false),
+		WithTools(sendTool{specTool: specTool{name: "send"}, calls: &calls}),
+	)
+	require.NoError(t, err)
+
+	events := collectRunEvents(agent.RunStream(context.Background(), "notify ops"))
+	types := make([]RunEventType, len(events))
+	for i, event := range events {
+		types[i] = event.Type
+	}
+	assert.Equal(t, []RunEventType{RunEventToolCallStarted, RunEventToolCallFinished, RunEventToken, RunEventRunFinished}, types)
+	assert.Equal(t, "send", events[0].ToolCall.ToolName)
+	assert.Equal(t, `sent {"to":"ops"}`, events[2].Content)
+	assert.Equal(t, 1, calls)
+}
+
+func TestRunStreamStopsWhenCancelled(t *testing.T) {
+	agent, err := NewAgent(WithLLM(&streamingLLM{pieces: make([]string, 100)}))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	events := agent.RunStream(ctx, "hi")
+	cancel()
+	for range events {
+	}
+}
@@ -0,0 +1,55 @@
+package agent
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+	"github.com/run-bigpig/llm-agent/pkg/multitenancy"
+	"github.com/run-bigpig/llm-agent/pkg/prompts"
+)
+
+// systemMessageLLM records the system message of the last generation
+type systemMessageLLM struct {
+	MockLLM
+	systemMessage string
+}
+
+func (m *systemMessageLLM) Generate(ctx context.Context, prompt string, options ...interfaces.GenerateOption) (string, error) {
+	var generateOptions interfaces.GenerateOptions
+	for _, option := range options {
+		option(&generateOptions)
+	}
+	m.systemMessage = generateOptions.SystemMessage
+	return "ok", nil
+}
+
+func (m *systemMessageLLM) GenerateWithTools(ctx context.Context, prompt string, tools []interfaces.Tool, options ...interfaces.GenerateOption) (string, error) {
+	return m.Generate(ctx, prompt, options...)
+}
+
+func TestSystemPromptSections(t *testing.T) {
+	llm := &systemMessageLLM{}
+	agent, err := NewAgent(
+		WithLLM(llm),
+		WithSystemPromptSection(prompts.Section{Name: prompts.SectionSafety, Content: "Never share credentials."}),
+		WithSystemPrompt("Answer questions about billing."),
+		WithSystemPromptSection(prompts.Section{Name: prompts.SectionIdentity, Content: "You are Billy."}),
+		WithTenantSystemPromptSection("acme", prompts.Section{Name: prompts.SectionIdentity, Content: "You are the Acme billing assistant."}),
+		WithRequirePlanApproval(false),
+	)
+	require.NoError(t, err)
+
+	_, err = agent.Run(context.Background(), "hello")
+	require.NoError(t, err)
+	assert.Equal(t, "You are Billy.\n\nAnswer questions about billing.\n\nNever share credentials.", llm.systemMessage)
+
+	// A tenant's override replaces the section, even after Prepare
+	require.NoError(t, agent.Prepare(context.Background()))
+	_, err = agent.Run(multitenancy.WithOrgID(context.Background(), "acme"), "hello")
+	require.NoError(t, err)
+	assert.Equal(t, "You are the Acme billing assistant.\n\nAnswer questions about billing.\n\nNever share credentials.", llm.systemMessage)
+}
@@ -0,0 +1,69 @@
+package agent
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+)
+
+// thinkingLLM reports its reasoning like a client with extended thinking
+type thinkingLLM struct {
+	MockLLM
+}
+
+func (m *thinkingLLM) Generate(ctx context.Context, prompt string, options ...interfaces.GenerateOption) (string, error) {
+	interfaces.ReportThinking(ctx, "The user greets me.")
+	return "Hello", nil
+}
+
+func TestRunThinkingEvents(t *testing.T) {
+	var handled []string
+	agent, err := NewAgent(
+		WithLLM(&thinkingLLM{}),
+		WithRunEventHandler(func(ctx context.Context, event RunEvent) {
+			if event.Type == RunEventThinking {
+				handled = append(handled, event.Content)
+			}
+		}),
+	)
+	require.NoError(t, err)
+
+	var reported []string
+	ctx := interfaces.WithThinkingCallback(context.Background(), func(thinking string) {
+		reported = append(reported, thinking)
+	})
+	_, err = agent.Run(ctx, "hi")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"The user greets me."}, handled)
+	assert.Equal(t, []string{"The user greets me."}, reported)
+}
+
+// thinkingStreamLLM streams its reasoning before the response
+type thinkingStreamLLM struct {
+	MockLLM
+}
+
+func (m *thinkingStreamLLM) GenerateStream(ctx context.Context, prompt string, options ...interfaces.GenerateOption) (<-chan interfaces.StreamEvent, error) {
+	events := make(chan interfaces.StreamEvent, 4)
+	events <- interfaces.StreamEvent{Type: interfaces.StreamEventThinking, Content: "The user greets me."}
+	events <- interfaces.StreamEvent{Type: interfaces.StreamEventText, Content: "Hello"}
+	events <- interfaces.StreamEvent{Type: interfaces.StreamEventDone}
+	close(events)
+	return events, nil
+}
+
+func TestRunStreamThinking(t *testing.T) {
+	agent, err := NewAgent(WithLLM(&thinkingStreamLLM{}))
+	require.NoError(t, err)
+
+	events := collectRunEvents(agent.RunStream(context.Background(), "hi"))
+	require.Len(t, events, 3)
+	assert.Equal(t, RunEventThinking, events[0].Type)
+	assert.Equal(t, "The user greets me.", events[0].Content)
+	assert.Equal(t, RunEventToken, events[1].Type)
+	assert.Equal(t, "Hello", events[2].Content)
+}
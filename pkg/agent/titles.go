@@ -0,0 +1,201 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+	"github.com/run-bigpig/llm-agent/pkg/memory"
+	"github.com/run-bigpig/llm-agent/pkg/runctx"
+)
+
+// Defaults of ConversationTitles
+const (
+	defaultMaxTopics        = 3
+	titleHistoryMessages    = 10
+	titleMessageMaxChars    = 500
+	conversationTitleMaxLen = 80
+)
+
+// ConversationTitles generates a short title and topic tags for each
+// conversation after its runs, for the history lists of chat UIs
+type ConversationTitles struct {
+	// Store saves the conversations; required
+	Store memory.ConversationStore
+
+	// LLM generates the titles. Defaults to the agent's LLM; a small model
+	// is enough.
+	LLM interfaces.LLM
+
+	// MaxTopics is the maximum number of topic tags. Defaults to 3.
+	MaxTopics int
+
+	// RefreshEvery regenerates the title and topics every that many turns,
+	// as conversations drift. Zero generates them once, after the first turn.
+	RefreshEvery int
+}
+
+// WithConversationTitles records every conversation in titles.Store after
+// each successful run that has a conversation ID, and generates its title
+// and topics in the background. Failures are logged and do not affect the
+// run.
+func WithConversationTitles(titles ConversationTitles) Option {
+	return func(a *Agent) {
+		a.conversationTitles = titles
+	}
+}
+
+// recordConversation updates the conversation of a finished run in the
+// background, tracked by the lifecycle manager if there is one
+func (a *Agent) recordConversation(ctx context.Context, input, response string) {
+	if a.conversationTitles.Store == nil {
+		return
+	}
+	conversationID := runctx.ConversationID(ctx)
+	if conversationID == "" {
+		return
+	}
+
+	done := func() {}
+	ctx = context.WithoutCancel(a.withOrgID(ctx))
+	if a.lifecycle != nil {
+		finished, err := a.lifecycle.Begin("agent:" + a.name + ":title")
+		if err != nil {
+			return
+		}
+		var cancel context.CancelFunc
+		ctx, cancel = a.lifecycle.Bind(ctx)
+		done = func() {
+			cancel()
+			finished()
+		}
+	}
+	go func() {
+		defer done()
+		if err := a.updateConversation(ctx, conversationID, input, response); err != nil {
+			fmt.Printf("Failed to update conversation title: %v\n", err)
+		}
+	}()
+}
+
+// updateConversation counts the turn of a conversation and generates its
+// title and topics when they are due
+func (a *Agent) updateConversation(ctx context.Context, conversationID, input, response string) error {
+	store := a.conversationTitles.Store
+	orgID := runctx.OrgID(ctx)
+	now := time.Now()
+
+	conversation, err := store.Get(ctx, orgID, conversationID)
+	if errors.Is(err, memory.ErrConversationNotFound) {
+		conversation = memory.Conversation{ID: conversationID, OrgID: orgID, UserID: runctx.UserID(ctx), CreatedAt: now}
+	} else if err != nil {
+		return fmt.Errorf("failed to get conversation: %w", err)
+	}
+	conversation.Turns++
+	conversation.UpdatedAt = now
+
+	refresh := a.conversationTitles.RefreshEvery
+	if conversation.Title == "" || refresh > 0 && conversation.Turns%refresh == 0 {
+		title, topics, err := a.generateTitle(ctx, a.titleTranscript(ctx, input, response))
+		if err != nil {
+			// Count the turn anyway; the title is tried again next turn
+			fmt.Printf("Failed to generate conversation title: %v\n", err)
+		} else {
+			conversation.Title = title
+			conversation.Topics = topics
+		}
+	}
+
+	if err := store.Save(ctx, conversation); err != nil {
+		return fmt.Errorf("failed to save conversation: %w", err)
+	}
+	return nil
+}
+
+// titleTranscript returns the recent messages of the conversation, or the
+// input and response of the run without memory
+func (a *Agent) titleTranscript(ctx context.Context, input, response string) string {
+	messages := []interfaces.Message{
+		{Role: "user", Content: input},
+		{Role: "assistant", Content: response},
+	}
+	if a.memory != nil {
+		history, err := a.memory.GetMessages(ctx, interfaces.WithLimit(titleHistoryMessages), interfaces.WithRoles("user", "assistant"))
+		if err == nil && len(history) > 0 {
+			messages = history
+		}
+	}
+
+	var sb strings.Builder
+	for _, message := range messages {
+		content := strings.TrimSpace(message.Content)
+		if runes := []rune(content); len(runes) > titleMessageMaxChars {
+			content = string(runes[:titleMessageMaxChars]) + "..."
+		}
+		fmt.Fprintf(&sb, "%s: %s\n", message.Role, content)
+	}
+	return sb.String()
+}
+
+// generateTitle asks the LLM for a title and topic tags of a transcript
+func (a *Agent) generateTitle(ctx context.Context, transcript string) (string, []string, error) {
+	llm := a.conversationTitles.LLM
+	if llm == nil {
+		llm = a.llm
+	}
+	maxTopics := a.conversationTitles.MaxTopics
+	if maxTopics <= 0 {
+		maxTopics = defaultMaxTopics
+	}
+
+	prompt := fmt.Sprintf(`Give the following conversation a short title of at most six words, in the language of the conversation, and up to %d topic tags of one or two lowercase words each.
+Answer with a JSON object like {"title": "...", "topics": ["..."]} and nothing else.
+
+Conversation:
+%s`, maxTopics, transcript)
+
+	response, err := llm.Generate(ctx, prompt)
+	if err != nil {
+		return "", nil, err
+	}
+
+	// Models sometimes wrap the object in a code block
+	response = strings.TrimSpace(response)
+	if start, end := strings.Index(response, "{"), strings.LastIndex(response, "}"); start >= 0 && end > start {
+		response = response[start : end+1]
+	}
+	var result struct {
+		Title  string   `json:"title"`
+		Topics []string `json:"topics"`
+	}
+	if err := json.Unmarshal([]byte(response), &result); err != nil {
+		return "", nil, fmt.Errorf("unexpected title response %q: %w", response, err)
+	}
+
+	title := strings.Trim(strings.TrimSpace(result.Title), `"'`)
+	if title == "" {
+		return "", nil, fmt.Errorf("no title in response %q", response)
+	}
+	if runes := []rune(title); len(runes) > conversationTitleMaxLen {
+		title = strings.TrimSpace(string(runes[:conversationTitleMaxLen])) + "..."
+	}
+
+	var topics []string
+	seen := make(map[string]bool)
+	for _, topic := range result.Topics {
+		topic = strings.ToLower(strings.TrimSpace(topic))
+		if topic == "" || seen[topic] {
+			continue
+		}
+		seen[topic] = true
+		topics = append(topics, topic)
+		if len(topics) == maxTopics {
+			break
+		}
+	}
+	return title, topics, nil
+}
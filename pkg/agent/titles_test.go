@@ -0,0 +1,103 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+	"github.com/run-bigpig/llm-agent/pkg/memory"
+	"github.com/run-bigpig/llm-agent/pkg/multitenancy"
+)
+
+// titleLLM answers questions and titles conversations, recording the title
+// prompts
+type titleLLM struct {
+	MockLLM
+	mu           sync.Mutex
+	titlePrompts []string
+}
+
+func (m *titleLLM) Generate(ctx context.Context, prompt string, options ...interfaces.GenerateOption) (string, error) {
+	if !strings.Contains(prompt, "short title") {
+		return "Your refund was issued yesterday.", nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.titlePrompts = append(m.titlePrompts, prompt)
+	return "```json\n{\"title\": \"Refund for order 1234\", \"topics\": [\"Billing\", \"refunds\", \"billing\", \"orders\", \"shipping\"]}\n```", nil
+}
+
+func (m *titleLLM) prompts() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]string(nil), m.titlePrompts...)
+}
+
+func TestConversationTitles(t *testing.T) {
+	llm := &titleLLM{}
+	store := memory.NewMemoryConversationStore()
+	agent, err := NewAgent(
+		WithLLM(llm),
+		WithMemory(memory.NewConversationBuffer()),
+		WithOrgID("acme"),
+		WithConversationTitles(ConversationTitles{Store: store, RefreshEvery: 3}),
+	)
+	require.NoError(t, err)
+	ctx := memory.WithConversationID(context.Background(), "conv")
+
+	turns := func(n int) func() bool {
+		return func() bool {
+			conversation, err := store.Get(ctx, "acme", "conv")
+			return err == nil && conversation.Turns == n
+		}
+	}
+
+	_, err = agent.Run(ctx, "Where is my refund for order 1234?")
+	require.NoError(t, err)
+	require.Eventually(t, turns(1), time.Second, 5*time.Millisecond)
+
+	conversation, err := store.Get(ctx, "acme", "conv")
+	require.NoError(t, err)
+	assert.Equal(t, "Refund for order 1234", conversation.Title)
+	assert.Equal(t, []string{"billing", "refunds", "orders"}, conversation.Topics)
+	require.Len(t, llm.prompts(), 1)
+	assert.Contains(t, llm.prompts()[0], "user: Where is my refund for order 1234?\nassistant: Your refund was issued yesterday.\n")
+
+	// The title is kept until it is due for a refresh
+	_, err = agent.Run(ctx, "Thanks!")
+	require.NoError(t, err)
+	require.Eventually(t, turns(2), time.Second, 5*time.Millisecond)
+	assert.Len(t, llm.prompts(), 1)
+
+	_, err = agent.Run(ctx, "One more question")
+	require.NoError(t, err)
+	require.Eventually(t, turns(3), time.Second, 5*time.Millisecond)
+	assert.Len(t, llm.prompts(), 2)
+
+	listed, err := store.List(ctx, memory.ConversationFilter{OrgID: "acme", Topic: "refunds"})
+	require.NoError(t, err)
+	require.Len(t, listed, 1)
+	assert.Equal(t, "conv", listed[0].ID)
+}
+
+func TestConversationTitlesNeedAConversationID(t *testing.T) {
+	store := memory.NewMemoryConversationStore()
+	agent, err := NewAgent(
+		WithLLM(&titleLLM{}),
+		WithConversationTitles(ConversationTitles{Store: store}),
+	)
+	require.NoError(t, err)
+
+	_, err = agent.Run(multitenancy.WithOrgID(context.Background(), "acme"), "Hello")
+	require.NoError(t, err)
+
+	listed, err := store.List(context.Background(), memory.ConversationFilter{})
+	require.NoError(t, err)
+	assert.Empty(t, listed)
+}
@@ -0,0 +1,59 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+)
+
+// WithToolExamplesInPrompt sets whether the examples of tools implementing
+// interfaces.ToolWithExamples are added to the system prompt. Defaults to true;
+// disable it when the LLM client sends them in a provider-native field
+// instead, e.g. anthropic.WithToolInputExamples.
+func WithToolExamplesInPrompt(include bool) Option {
+	return func(a *Agent) {
+		a.toolExamplesInPrompt = include
+	}
+}
+
+// toolExamplesPrompt describes the examples of the tools for the system
+// prompt, or returns an empty string if no tool has examples
+func (a *Agent) toolExamplesPrompt(tools []interfaces.Tool) string {
+	if !a.toolExamplesInPrompt {
+		return ""
+	}
+
+	var b strings.Builder
+	for _, tool := range tools {
+		for _, example := range interfaces.ToolExamples(tool) {
+			if b.Len() == 0 {
+				b.WriteString("# Tool Examples\nExamples of how to call the available tools:\n")
+			}
+			b.WriteString("\n- ")
+			b.WriteString(tool.Name())
+			if example.Description != "" {
+				b.WriteString(": ")
+				b.WriteString(example.Description)
+			}
+			arguments, err := json.Marshal(example.Arguments)
+			if err != nil {
+				arguments = []byte(fmt.Sprintf("%v", example.Arguments))
+			}
+			fmt.Fprintf(&b, "\n  Arguments: %s", arguments)
+			if example.Outcome != "" {
+				fmt.Fprintf(&b, "\n  Outcome: %s", example.Outcome)
+			}
+		}
+	}
+	return b.String()
+}
+
+// withToolExamples appends the tool examples to a system prompt
+func withToolExamples(systemPrompt, examples string) string {
+	if examples == "" {
+		return systemPrompt
+	}
+	return strings.TrimSpace(systemPrompt + "\n\n" + examples)
+}
@@ -0,0 +1,62 @@
+package agent
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+)
+
+// exampleTool is a tool with example invocations
+type exampleTool struct {
+	specTool
+	examples []interfaces.ToolExample
+}
+
+func (t exampleTool) Examples() []interfaces.ToolExample { return t.examples }
+
+func TestToolExamplesInSystemPrompt(t *testing.T) {
+	tool := exampleTool{
+		specTool: specTool{name: "search_invoices"},
+		examples: []interfaces.ToolExample{{
+			Description: "latest invoices of a customer",
+			Arguments:   map[string]interface{}{"customer": "acme", "limit": 5},
+			Outcome:     "the 5 most recent invoices of Acme",
+		}},
+	}
+	llm := &systemMessageLLM{}
+	agent, err := NewAgent(
+		WithLLM(llm),
+		WithSystemPrompt("Answer questions about billing."),
+		WithTools(tool, specTool{name: "refund"}),
+		WithRequirePlanApproval(false),
+	)
+	require.NoError(t, err)
+
+	_, err = agent.Run(context.Background(), "hello")
+	require.NoError(t, err)
+	assert.Equal(t, `Answer questions about billing.
+
+# Tool Examples
+Examples of how to call the available tools:
+
+- search_invoices: latest invoices of a customer
+  Arguments: {"customer":"acme","limit":5}
+  Outcome: the 5 most recent invoices of Acme`, llm.systemMessage)
+
+	// The examples can be left out, e.g. when the LLM client sends them natively
+	agent, err = NewAgent(
+		WithLLM(llm),
+		WithSystemPrompt("Answer questions about billing."),
+		WithTools(tool),
+		WithRequirePlanApproval(false),
+		WithToolExamplesInPrompt(false),
+	)
+	require.NoError(t, err)
+	_, err = agent.Run(context.Background(), "hello")
+	require.NoError(t, err)
+	assert.Equal(t, "Answer questions about billing.", llm.systemMessage)
+}
@@ -0,0 +1,236 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/run-bigpig/llm-agent/pkg/circuitbreaker"
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+	"github.com/run-bigpig/llm-agent/pkg/rbac"
+)
+
+// ToolFailuresMetadataKey is the metadata key of the assistant messages in
+// memory that lists the tool calls that failed during the turn
+const ToolFailuresMetadataKey = "tool_failures"
+
+// Error classes of failed tool calls
+const (
+	ToolErrorTimeout      = "timeout"
+	ToolErrorRateLimited  = "rate_limited"
+	ToolErrorDenied       = "denied"
+	ToolErrorUnavailable  = "unavailable"
+	ToolErrorInvalidInput = "invalid_input"
+	ToolErrorOther        = "error"
+)
+
+// ToolFailure is a failed tool call remembered in the conversation
+type ToolFailure struct {
+	ToolName   string    `json:"tool_name"`
+	ErrorClass string    `json:"error_class"`
+	Time       time.Time `json:"time"`
+}
+
+// WithToolFailureMemory tells the LLM and the planner which tools failed in
+// the conversation within the window, so that the agent stops calling a
+// broken tool and tells the user which capabilities are degraded. Failures
+// are read from the assistant messages in memory, see
+// ToolFailuresMetadataKey. Calls rejected for invalid input are not counted,
+// since they say nothing about the tool.
+func WithToolFailureMemory(window time.Duration) Option {
+	return func(a *Agent) {
+		a.toolFailureWindow = window
+	}
+}
+
+// ClassifyToolError returns the error class of a failed tool call, e.g.
+// ToolErrorTimeout
+func ClassifyToolError(err error) string {
+	var netErr net.Error
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return ToolErrorTimeout
+	case errors.Is(err, rbac.ErrDenied):
+		return ToolErrorDenied
+	case errors.Is(err, circuitbreaker.ErrOpen):
+		return ToolErrorUnavailable
+	}
+
+	message := strings.ToLower(err.Error())
+	switch {
+	case containsAny(message, "timeout", "timed out", "deadline exceeded"):
+		return ToolErrorTimeout
+	case containsAny(message, "429", "rate limit", "too many requests", "quota"):
+		return ToolErrorRateLimited
+	case containsAny(message, "401", "403", "unauthorized", "forbidden", "permission denied", "access denied"):
+		return ToolErrorDenied
+	case containsAny(message, "502", "503", "504", "unavailable", "connection refused", "connection reset", "no such host", "bad gateway"):
+		return ToolErrorUnavailable
+	case containsAny(message, "invalid", "400", "required", "failed to parse", "unmarshal"):
+		return ToolErrorInvalidInput
+	default:
+		return ToolErrorOther
+	}
+}
+
+// containsAny reports whether s contains one of the substrings
+func containsAny(s string, substrings ...string) bool {
+	for _, substring := range substrings {
+		if strings.Contains(s, substring) {
+			return true
+		}
+	}
+	return false
+}
+
+// RecentToolFailures returns the tool failures of the conversation in the
+// context within the window set with WithToolFailureMemory, oldest first
+func (a *Agent) RecentToolFailures(ctx context.Context) ([]ToolFailure, error) {
+	if a.memory == nil || a.toolFailureWindow <= 0 {
+		return nil, nil
+	}
+	messages, err := a.memory.GetMessages(a.withOrgID(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get conversation history: %w", err)
+	}
+
+	since := time.Now().Add(-a.toolFailureWindow)
+	var failures []ToolFailure
+	for _, message := range messages {
+		for _, failure := range toolFailuresFromMetadata(message.Metadata) {
+			if failure.Time.After(since) && failure.ErrorClass != ToolErrorInvalidInput {
+				failures = append(failures, failure)
+			}
+		}
+	}
+	return failures, nil
+}
+
+// toolFailuresFromMetadata decodes the tool failures of a message, which are
+// a []ToolFailure in memory and generic JSON values after a round trip
+// through a store like Redis
+func toolFailuresFromMetadata(metadata map[string]interface{}) []ToolFailure {
+	value, ok := metadata[ToolFailuresMetadataKey]
+	if !ok {
+		return nil
+	}
+	if failures, ok := value.([]ToolFailure); ok {
+		return failures
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil
+	}
+	var failures []ToolFailure
+	if err := json.Unmarshal(data, &failures); err != nil {
+		return nil
+	}
+	return failures
+}
+
+// withToolFailures adds the recent failures of the offered tools to a
+// system prompt
+func (a *Agent) withToolFailures(ctx context.Context, systemPrompt string, tools []interfaces.Tool) string {
+	if a.toolFailureWindow <= 0 || len(tools) == 0 {
+		return systemPrompt
+	}
+	failures, err := a.RecentToolFailures(ctx)
+	if err != nil {
+		fmt.Printf("Failed to read tool failures: %v\n", err)
+		return systemPrompt
+	}
+	section := toolFailuresPrompt(failures, tools, time.Now())
+	if section == "" {
+		return systemPrompt
+	}
+	return strings.TrimSpace(systemPrompt + "\n\n" + section)
+}
+
+// toolFailuresPrompt describes the failures of the offered tools, most
+// recent first, or returns "" if none of them failed
+func toolFailuresPrompt(failures []ToolFailure, tools []interfaces.Tool, now time.Time) string {
+	offered := make(map[string]bool, len(tools))
+	for _, tool := range tools {
+		offered[tool.Name()] = true
+	}
+
+	type summary struct {
+		name  string
+		count int
+		last  ToolFailure
+	}
+	byTool := make(map[string]*summary)
+	for _, failure := range failures {
+		if !offered[failure.ToolName] {
+			continue
+		}
+		s, ok := byTool[failure.ToolName]
+		if !ok {
+			s = &summary{name: failure.ToolName}
+			byTool[failure.ToolName] = s
+		}
+		s.count++
+		if !failure.Time.Before(s.last.Time) {
+			s.last = failure
+		}
+	}
+	if len(byTool) == 0 {
+		return ""
+	}
+
+	summaries := make([]*summary, 0, len(byTool))
+	for _, s := range byTool {
+		summaries = append(summaries, s)
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		return summaries[i].last.Time.After(summaries[j].last.Time)
+	})
+
+	var sb strings.Builder
+	sb.WriteString("These tools failed recently in this conversation:\n")
+	for _, s := range summaries {
+		calls := "1 failed call"
+		if s.count > 1 {
+			calls = fmt.Sprintf("%d failed calls", s.count)
+		}
+		fmt.Fprintf(&sb, "- %s: %s, %s, last %s\n", s.name, s.last.ErrorClass, calls, ago(now.Sub(s.last.Time)))
+	}
+	sb.WriteString("Don't call these tools again unless the user asks you to retry. If the request needs one of them, tell the user that this capability is degraded at the moment and answer as well as you can without it.")
+	return sb.String()
+}
+
+// ago describes how long ago something happened, in minutes
+func ago(d time.Duration) string {
+	minutes := int(d.Minutes())
+	switch {
+	case minutes < 1:
+		return "less than a minute ago"
+	case minutes == 1:
+		return "1 minute ago"
+	default:
+		return fmt.Sprintf("%d minutes ago", minutes)
+	}
+}
+
+// toolFailures returns the failed tool calls of the run so far
+func (r *RunReport) toolFailures() []ToolFailure {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var failures []ToolFailure
+	for _, call := range r.ToolCalls {
+		if call.Error != "" {
+			failures = append(failures, ToolFailure{ToolName: call.ToolName, ErrorClass: call.ErrorClass, Time: call.StartedAt.Add(call.Duration)})
+		}
+	}
+	return failures
+}
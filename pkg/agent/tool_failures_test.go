@@ -0,0 +1,112 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/run-bigpig/llm-agent/pkg/circuitbreaker"
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+	"github.com/run-bigpig/llm-agent/pkg/memory"
+)
+
+// failingTool fails every call with its error
+type failingTool struct {
+	specTool
+	err error
+}
+
+func (t failingTool) Execute(ctx context.Context, args string) (string, error) {
+	return "", t.err
+}
+
+// toolCallingLLM calls every tool, ignoring errors like providers do, and
+// records the system message
+type toolCallingLLM struct {
+	systemMessageLLM
+}
+
+func (m *toolCallingLLM) GenerateWithTools(ctx context.Context, prompt string, tools []interfaces.Tool, options ...interfaces.GenerateOption) (string, error) {
+	for _, tool := range tools {
+		_, _ = tool.Execute(ctx, "{}")
+	}
+	return m.Generate(ctx, prompt, options...)
+}
+
+func TestToolFailureMemory(t *testing.T) {
+	llm := &toolCallingLLM{}
+	agent, err := NewAgent(
+		WithLLM(llm),
+		WithMemory(memory.NewConversationBuffer()),
+		WithOrgID("acme"),
+		WithRequirePlanApproval(false),
+		WithToolFailureMemory(time.Hour),
+		WithTools(
+			failingTool{specTool: specTool{name: "search"}, err: fmt.Errorf("request failed: %w", context.DeadlineExceeded)},
+			failingTool{specTool: specTool{name: "lookup"}, err: errors.New("invalid order ID")},
+			fetchTool{specTool: specTool{name: "fetch"}, page: "ok"},
+		),
+	)
+	require.NoError(t, err)
+	ctx := memory.WithConversationID(context.Background(), "conv")
+
+	_, err = agent.Run(ctx, "find flights")
+	require.NoError(t, err)
+	assert.NotContains(t, llm.systemMessage, "failed recently")
+
+	// The failures are recorded in the conversation
+	messages, err := agent.memory.GetMessages(agent.withOrgID(ctx))
+	require.NoError(t, err)
+	failures := toolFailuresFromMetadata(messages[len(messages)-1].Metadata)
+	require.Len(t, failures, 2)
+	assert.Equal(t, "search", failures[0].ToolName)
+	assert.Equal(t, ToolErrorTimeout, failures[0].ErrorClass)
+	assert.Equal(t, ToolErrorInvalidInput, failures[1].ErrorClass)
+
+	// and passed to the LLM on the next turn, without invalid input
+	_, err = agent.Run(ctx, "try again")
+	require.NoError(t, err)
+	assert.Contains(t, llm.systemMessage, "These tools failed recently in this conversation:\n- search: timeout, 1 failed call, last less than a minute ago\n")
+	assert.Contains(t, llm.systemMessage, "tell the user that this capability is degraded")
+	assert.NotContains(t, llm.systemMessage, "lookup")
+
+	recent, err := agent.RecentToolFailures(ctx)
+	require.NoError(t, err)
+	assert.Len(t, recent, 2)
+}
+
+func TestToolFailuresPrompt(t *testing.T) {
+	now := time.Now()
+	tools := []interfaces.Tool{specTool{name: "search"}, specTool{name: "weather"}}
+	failures := []ToolFailure{
+		{ToolName: "search", ErrorClass: ToolErrorTimeout, Time: now.Add(-10 * time.Minute)},
+		{ToolName: "weather", ErrorClass: ToolErrorUnavailable, Time: now.Add(-5 * time.Minute)},
+		{ToolName: "search", ErrorClass: ToolErrorRateLimited, Time: now.Add(-2 * time.Minute)},
+		{ToolName: "removed", ErrorClass: ToolErrorOther, Time: now},
+	}
+
+	prompt := toolFailuresPrompt(failures, tools, now)
+	assert.Contains(t, prompt, "- search: rate_limited, 2 failed calls, last 2 minutes ago\n- weather: unavailable, 1 failed call, last 5 minutes ago\n")
+	assert.NotContains(t, prompt, "removed")
+	assert.Empty(t, toolFailuresPrompt(failures, []interfaces.Tool{specTool{name: "other"}}, now))
+}
+
+func TestClassifyToolError(t *testing.T) {
+	tests := map[string]error{
+		ToolErrorTimeout:      context.DeadlineExceeded,
+		ToolErrorRateLimited:  errors.New("HTTP 429: Too Many Requests"),
+		ToolErrorDenied:       errors.New("403 Forbidden"),
+		ToolErrorUnavailable:  fmt.Errorf("search: %w", circuitbreaker.ErrOpen),
+		ToolErrorInvalidInput: errors.New("query is required"),
+		ToolErrorOther:        errors.New("something broke"),
+	}
+	for class, err := range tests {
+		assert.Equal(t, class, ClassifyToolError(err), err.Error())
+	}
+	assert.Empty(t, ClassifyToolError(nil))
+}
@@ -0,0 +1,55 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+)
+
+// crawlTool reports its progress page by page
+type crawlTool struct {
+	specTool
+	pages int
+}
+
+func (t crawlTool) Execute(ctx context.Context, args string) (string, error) {
+	for i := 1; i <= t.pages; i++ {
+		interfaces.ReportToolProgress(ctx, interfaces.ToolProgress{Message: fmt.Sprintf("Fetched page %d", i), Completed: i, Total: t.pages})
+	}
+	return "crawled", nil
+}
+
+func TestToolProgress(t *testing.T) {
+	var handled []interfaces.ToolProgress
+	agent, err := NewAgent(
+		WithLLM(&callAllLLM{}),
+		WithRequirePlanApproval(false),
+		WithTools(crawlTool{specTool: specTool{name: "crawl"}, pages: 2}),
+		WithRunEventHandler(func(ctx context.Context, event RunEvent) {
+			if event.Type == RunEventToolProgress {
+				handled = append(handled, *event.Progress)
+			}
+		}),
+	)
+	require.NoError(t, err)
+
+	events := collectRunEvents(agent.RunStream(context.Background(), "crawl the docs"))
+	types := make([]RunEventType, len(events))
+	for i, event := range events {
+		types[i] = event.Type
+	}
+	assert.Equal(t, []RunEventType{
+		RunEventToolCallStarted, RunEventToolProgress, RunEventToolProgress, RunEventToolCallFinished, RunEventToken, RunEventRunFinished,
+	}, types)
+
+	progress := events[2]
+	assert.Equal(t, "crawl", progress.ToolCall.ToolName)
+	assert.Equal(t, events[0].ToolCall.StartedAt, progress.ToolCall.StartedAt)
+	assert.Equal(t, interfaces.ToolProgress{Message: "Fetched page 2", Completed: 2, Total: 2}, *progress.Progress)
+	assert.Len(t, handled, 2)
+}
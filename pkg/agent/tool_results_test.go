@@ -0,0 +1,43 @@
+package agent
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/run-bigpig/llm-agent/pkg/memory"
+	"github.com/run-bigpig/llm-agent/pkg/multitenancy"
+	"github.com/run-bigpig/llm-agent/pkg/runctx"
+)
+
+func TestToolResultStoreReusesResults(t *testing.T) {
+	var calls int
+	store := memory.NewToolResultStore()
+	agent, err := NewAgent(
+		WithLLM(&callAllLLM{}),
+		WithRequirePlanApproval(false),
+		WithTools(sendTool{specTool: specTool{name: "lookup"}, calls: &calls}),
+		WithToolResultStore(store),
+	)
+	require.NoError(t, err)
+
+	ctx := multitenancy.WithOrgID(context.Background(), "org-1")
+	ctx = memory.WithConversationID(ctx, "conv-1")
+
+	for i := 0; i < 2; i++ {
+		response, err := agent.Run(ctx, "look up ops")
+		require.NoError(t, err)
+		assert.Equal(t, `sent {"to":"ops"}`, response)
+	}
+	assert.Equal(t, 1, calls)
+	require.Len(t, store.List(ctx), 1)
+	assert.Equal(t, "lookup", store.List(ctx)[0].ToolName)
+
+	// Dry runs neither reuse nor store results
+	_, err = agent.Run(runctx.WithDryRun(memory.WithConversationID(ctx, "conv-2"), true), "look up ops")
+	require.NoError(t, err)
+	assert.Equal(t, 2, calls)
+	assert.Empty(t, store.List(memory.WithConversationID(ctx, "conv-2")))
+}
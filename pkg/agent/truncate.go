@@ -0,0 +1,234 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+)
+
+// TruncationStrategy decides how a tool result over its limit is shortened
+type TruncationStrategy string
+
+const (
+	// TruncateHead keeps the start of the result
+	TruncateHead TruncationStrategy = "head"
+
+	// TruncateTail keeps the end of the result, e.g. for logs
+	TruncateTail TruncationStrategy = "tail"
+
+	// TruncateHeadTail keeps the start and the end of the result and drops
+	// the middle
+	TruncateHeadTail TruncationStrategy = "head_tail"
+
+	// TruncateSummarize has the agent's LLM summarize the result within the
+	// limit, falling back to TruncateHeadTail if that fails
+	TruncateSummarize TruncationStrategy = "summarize"
+)
+
+// ToolResultLimit limits the size of a tool's results before they are added
+// to the conversation with the LLM
+type ToolResultLimit struct {
+	// MaxTokens is the most tokens a result may have; zero means no limit
+	MaxTokens int
+
+	// Strategy is how longer results are shortened. Defaults to
+	// TruncateHeadTail.
+	Strategy TruncationStrategy
+}
+
+// WithToolResultLimit limits the results of the named tool, overriding the
+// default limit
+func WithToolResultLimit(toolName string, limit ToolResultLimit) Option {
+	return func(a *Agent) {
+		if a.toolResultLimits == nil {
+			a.toolResultLimits = make(map[string]ToolResultLimit)
+		}
+		a.toolResultLimits[toolName] = limit
+	}
+}
+
+// WithDefaultToolResultLimit limits the results of tools without a limit of
+// their own
+func WithDefaultToolResultLimit(limit ToolResultLimit) Option {
+	return func(a *Agent) {
+		a.defaultResultLimit = limit
+	}
+}
+
+// WithToolResultTokenCounter sets how tool results are measured against their
+// limits. By default a token is estimated as four characters.
+func WithToolResultTokenCounter(counter func(text string) int) Option {
+	return func(a *Agent) {
+		a.countToolTokens = counter
+	}
+}
+
+// toolResultLimit returns the limit for the named tool
+func (a *Agent) toolResultLimit(toolName string) ToolResultLimit {
+	if limit, ok := a.toolResultLimits[toolName]; ok {
+		return limit
+	}
+	return a.defaultResultLimit
+}
+
+// limitToolResults wraps tools that have a result limit so that their results
+// are truncated
+func (a *Agent) limitToolResults(tools []interfaces.Tool) []interfaces.Tool {
+	limited := make([]interfaces.Tool, len(tools))
+	for i, tool := range tools {
+		limit := a.toolResultLimit(tool.Name())
+		if limit.MaxTokens <= 0 {
+			limited[i] = tool
+			continue
+		}
+		if limit.Strategy == "" {
+			limit.Strategy = TruncateHeadTail
+		}
+		limited[i] = &truncatedTool{tool: tool, limit: limit, agent: a}
+	}
+	return limited
+}
+
+// truncatedTool wraps a tool and truncates its results
+type truncatedTool struct {
+	tool  interfaces.Tool
+	limit ToolResultLimit
+	agent *Agent
+}
+
+// Name returns the name of the tool
+func (t *truncatedTool) Name() string {
+	return t.tool.Name()
+}
+
+// Description returns a description of what the tool does
+func (t *truncatedTool) Description() string {
+	return t.tool.Description()
+}
+
+// Parameters returns the parameters that the tool accepts
+func (t *truncatedTool) Parameters() map[string]interfaces.ParameterSpec {
+	return t.tool.Parameters()
+}
+
+// Examples returns the example invocations of the wrapped tool
+func (t *truncatedTool) Examples() []interfaces.ToolExample {
+	return interfaces.ToolExamples(t.tool)
+}
+
+// Run executes the tool with the given input
+func (t *truncatedTool) Run(ctx context.Context, input string) (string, error) {
+	result, err := t.tool.Run(ctx, input)
+	return t.truncate(ctx, input, result, err)
+}
+
+// Execute executes the tool with the given arguments
+func (t *truncatedTool) Execute(ctx context.Context, args string) (string, error) {
+	result, err := t.tool.Execute(ctx, args)
+	return t.truncate(ctx, args, result, err)
+}
+
+// truncate shortens a result over the limit
+func (t *truncatedTool) truncate(ctx context.Context, args, result string, err error) (string, error) {
+	if err != nil {
+		return result, err
+	}
+	tokens := t.agent.countTokens(result)
+	if tokens <= t.limit.MaxTokens {
+		return result, nil
+	}
+
+	if t.limit.Strategy == TruncateSummarize && t.agent.llm != nil {
+		summary, err := t.summarize(ctx, args, result)
+		if err == nil && t.agent.countTokens(summary) <= t.limit.MaxTokens {
+			return summary, nil
+		}
+		if err != nil {
+			fmt.Printf("Failed to summarize result of tool %s: %v\n", t.tool.Name(), err)
+		}
+		return truncateText(result, t.limit.MaxTokens, tokens, TruncateHeadTail), nil
+	}
+	return truncateText(result, t.limit.MaxTokens, tokens, t.limit.Strategy), nil
+}
+
+// summarize has the LLM summarize a result within the limit
+func (t *truncatedTool) summarize(ctx context.Context, args, result string) (string, error) {
+	prompt := fmt.Sprintf(`The tool %q was called with the arguments %s and returned the output below, which is too long to use as is.
+
+Summarize the output in at most %d tokens. Keep the facts, figures, names and identifiers that answer the call; drop repetition and boilerplate. Respond with the summary only.
+
+Output:
+%s`, t.tool.Name(), args, t.limit.MaxTokens, result)
+
+	summary, err := t.agent.llm.Generate(ctx, prompt, func(o *interfaces.GenerateOptions) {
+		if o.LLMConfig == nil {
+			o.LLMConfig = &interfaces.LLMConfig{}
+		}
+		o.LLMConfig.Temperature = 0
+	})
+	if err != nil {
+		return "", err
+	}
+	return "[Summarized tool output]\n" + strings.TrimSpace(summary), nil
+}
+
+// countTokens counts the tokens in a tool result
+func (a *Agent) countTokens(text string) int {
+	if a.countToolTokens != nil {
+		return a.countToolTokens(text)
+	}
+	return (len(text) + 3) / 4
+}
+
+// truncateText shortens text of the given number of tokens to about
+// maxTokens, marking where text was dropped. Cuts are made at line breaks
+// when one is close, so that records are kept whole.
+func truncateText(text string, maxTokens, tokens int, strategy TruncationStrategy) string {
+	// Keep the share of the text that fits, measured in bytes
+	keep := len(text) * maxTokens / tokens
+	dropped := tokens - maxTokens
+	marker := fmt.Sprintf("[... about %d tokens truncated ...]", dropped)
+
+	switch strategy {
+	case TruncateHead:
+		return cutHead(text, keep) + "\n" + marker
+	case TruncateTail:
+		return marker + "\n" + cutTail(text, keep)
+	default:
+		return cutHead(text, keep/2) + "\n" + marker + "\n" + cutTail(text, keep-keep/2)
+	}
+}
+
+// cutHead returns about the first n bytes of text
+func cutHead(text string, n int) string {
+	if n >= len(text) {
+		return text
+	}
+	for n > 0 && !utf8.RuneStart(text[n]) {
+		n--
+	}
+	head := text[:n]
+	if i := strings.LastIndexByte(head, '\n'); i > len(head)*3/4 {
+		head = head[:i]
+	}
+	return head
+}
+
+// cutTail returns about the last n bytes of text
+func cutTail(text string, n int) string {
+	if n >= len(text) {
+		return text
+	}
+	start := len(text) - n
+	for start < len(text) && !utf8.RuneStart(text[start]) {
+		start++
+	}
+	tail := text[start:]
+	if i := strings.IndexByte(tail, '\n'); i >= 0 && i < len(tail)/4 {
+		tail = tail[i+1:]
+	}
+	return tail
+}
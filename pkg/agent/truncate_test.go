@@ -0,0 +1,95 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+)
+
+// summaryLLM returns a fixed summary
+type summaryLLM struct {
+	MockLLM
+	prompts []string
+}
+
+func (m *summaryLLM) Generate(ctx context.Context, prompt string, options ...interfaces.GenerateOption) (string, error) {
+	m.prompts = append(m.prompts, prompt)
+	return "42 rows, all paid", nil
+}
+
+// numberedLines returns n lines of the form "row <i>"
+func numberedLines(n int) string {
+	lines := make([]string, n)
+	for i := range lines {
+		lines[i] = fmt.Sprintf("row %03d", i)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func TestToolResultLimits(t *testing.T) {
+	output := numberedLines(200) // 1599 bytes, about 400 tokens
+	agent, err := NewAgent(
+		WithLLM(&MockLLM{}),
+		WithDefaultToolResultLimit(ToolResultLimit{MaxTokens: 50}),
+		WithToolResultLimit("head", ToolResultLimit{MaxTokens: 50, Strategy: TruncateHead}),
+		WithToolResultLimit("tail", ToolResultLimit{MaxTokens: 50, Strategy: TruncateTail}),
+		WithToolResultLimit("unlimited", ToolResultLimit{}),
+	)
+	require.NoError(t, err)
+
+	tools := agent.limitToolResults([]interfaces.Tool{
+		fetchTool{specTool: specTool{name: "default"}, page: output},
+		fetchTool{specTool: specTool{name: "head"}, page: output},
+		fetchTool{specTool: specTool{name: "tail"}, page: output},
+		fetchTool{specTool: specTool{name: "unlimited"}, page: output},
+		fetchTool{specTool: specTool{name: "short"}, page: "row 000"},
+	})
+	results := make([]string, len(tools))
+	for i, tool := range tools {
+		results[i], err = tool.Execute(context.Background(), "{}")
+		require.NoError(t, err)
+	}
+
+	// The default keeps the start and the end
+	assert.True(t, strings.HasPrefix(results[0], "row 000\n"))
+	assert.True(t, strings.HasSuffix(results[0], "\nrow 199"))
+	assert.Contains(t, results[0], "tokens truncated")
+	assert.Less(t, len(results[0]), 300)
+
+	// Head and tail keep one end, cut at whole lines
+	assert.True(t, strings.HasPrefix(results[1], "row 000\n"))
+	assert.NotContains(t, results[1], "row 199")
+	assert.True(t, strings.HasSuffix(results[2], "\nrow 199"))
+	assert.NotContains(t, results[2], "row 000")
+	for _, line := range strings.Split(results[2], "\n")[1:] {
+		assert.Regexp(t, `^row \d{3}$`, line)
+	}
+
+	// Tools without a limit and short results are left alone
+	assert.Equal(t, output, results[3])
+	assert.Equal(t, "row 000", results[4])
+}
+
+func TestToolResultSummarization(t *testing.T) {
+	llm := &summaryLLM{}
+	agent, err := NewAgent(
+		WithLLM(llm),
+		WithToolResultLimit("sql", ToolResultLimit{MaxTokens: 50, Strategy: TruncateSummarize}),
+	)
+	require.NoError(t, err)
+
+	tools := agent.limitToolResults([]interfaces.Tool{
+		fetchTool{specTool: specTool{name: "sql"}, page: numberedLines(200)},
+	})
+	result, err := tools[0].Execute(context.Background(), `{"query":"SELECT * FROM invoices"}`)
+	require.NoError(t, err)
+	assert.Equal(t, "[Summarized tool output]\n42 rows, all paid", result)
+	require.Len(t, llm.prompts, 1)
+	assert.Contains(t, llm.prompts[0], "SELECT * FROM invoices")
+}
@@ -0,0 +1,55 @@
+package agent
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/run-bigpig/llm-agent/pkg/cost"
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+)
+
+// usageLLM reports the usage of every call like a provider client
+type usageLLM struct {
+	MockLLM
+}
+
+func (m *usageLLM) Generate(ctx context.Context, prompt string, options ...interfaces.GenerateOption) (string, error) {
+	interfaces.ReportUsage(ctx, interfaces.Usage{Model: "test-model-2024", InputTokens: 1000, OutputTokens: 200})
+	return "Hello", nil
+}
+
+func TestRunReportUsage(t *testing.T) {
+	tracker := cost.NewTracker(cost.WithPrices(map[string]cost.Price{
+		"test-model": {InputPerMillion: 1, OutputPerMillion: 10},
+	}))
+	agent, err := NewAgent(
+		WithLLM(cost.NewLLM(&usageLLM{}, tracker, "test-model")),
+		WithFollowUpQuestions(FollowUpQuestions{}),
+	)
+	require.NoError(t, err)
+
+	var reported []interfaces.Usage
+	ctx := interfaces.WithUsageCallback(context.Background(), func(usage interfaces.Usage) {
+		reported = append(reported, usage)
+	})
+	_, report, err := agent.RunWithReport(ctx, "hi")
+	require.NoError(t, err)
+
+	// The answer and the follow-up questions are counted, with their cost
+	assert.Equal(t, interfaces.Usage{Model: "test-model-2024", InputTokens: 2000, OutputTokens: 400, Cost: 0.006}, roundCost(report.Usage))
+	require.Len(t, reported, 2)
+	assert.InDelta(t, 0.003, reported[0].Cost, 1e-9)
+
+	totals := tracker.Totals()
+	require.Len(t, totals, 1)
+	assert.Equal(t, 2000, totals[0].InputTokens)
+}
+
+// roundCost rounds the cost of usage to avoid floating point noise
+func roundCost(usage interfaces.Usage) interfaces.Usage {
+	usage.Cost = float64(int(usage.Cost*1e6+0.5)) / 1e6
+	return usage
+}
@@ -0,0 +1,263 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"sort"
+	"sync"
+
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+	"github.com/run-bigpig/llm-agent/pkg/runctx"
+)
+
+// VersionTag is the attribution tag that records the version of the agent
+// that handled a run, see runctx.WithTags
+const VersionTag = "agent_version"
+
+// VersionHeader is the HTTP header servers conventionally read a requested
+// agent version from, to pass it on with WithRequestedVersion
+const VersionHeader = "X-Agent-Version"
+
+// WithVersion sets the version of the agent's definition. Runs are tagged
+// with it (see VersionTag), so it shows up in logs, traces, cost reports,
+// events and run reports.
+func WithVersion(version string) Option {
+	return func(a *Agent) {
+		a.version = version
+	}
+}
+
+// Version returns the version of the agent's definition
+func (a *Agent) Version() string {
+	return a.version
+}
+
+// AgentVersion is one version of an agent's definition. Fields left empty
+// keep the shared options of the VersionedAgent.
+type AgentVersion struct {
+	// Version names the version, e.g. "v2" or "2024-06-prompt-rewrite"
+	Version string
+
+	// SystemPrompt replaces the system prompt
+	SystemPrompt string
+
+	// LLM replaces the model
+	LLM interfaces.LLM
+
+	// Tools replaces the tools
+	Tools []interfaces.Tool
+
+	// Options are applied after all other options
+	Options []Option
+}
+
+// VersionedAgent serves several versions of an agent and splits traffic
+// between them, so that changes to prompts, tools or models can be rolled
+// out gradually. A request can ask for a version with WithRequestedVersion;
+// other requests are routed by the rollout percentages.
+type VersionedAgent struct {
+	base []Option
+
+	mu       sync.RWMutex
+	versions map[string]*Agent
+	order    []string
+	rollout  []rolloutShare
+}
+
+// rolloutShare is the percentage of traffic routed to a version
+type rolloutShare struct {
+	version string
+	percent int
+}
+
+// NewVersionedAgent creates an agent with the given versions, all built on
+// the shared options. Until SetRollout is called, all traffic goes to the
+// first version.
+func NewVersionedAgent(options []Option, versions ...AgentVersion) (*VersionedAgent, error) {
+	v := &VersionedAgent{
+		base:     options,
+		versions: make(map[string]*Agent),
+	}
+	for _, version := range versions {
+		if err := v.Register(version); err != nil {
+			return nil, err
+		}
+	}
+	return v, nil
+}
+
+// Register adds a version. It gets no traffic until it is requested or added
+// to the rollout, unless it is the first version.
+func (v *VersionedAgent) Register(version AgentVersion) error {
+	if version.Version == "" {
+		return fmt.Errorf("agent version name is required")
+	}
+
+	options := append([]Option{}, v.base...)
+	options = append(options, WithVersion(version.Version))
+	if version.SystemPrompt != "" {
+		options = append(options, WithSystemPrompt(version.SystemPrompt))
+	}
+	if version.LLM != nil {
+		options = append(options, WithLLM(version.LLM))
+	}
+	if version.Tools != nil {
+		options = append(options, WithTools(version.Tools...))
+	}
+	options = append(options, version.Options...)
+
+	agent, err := NewAgent(options...)
+	if err != nil {
+		return fmt.Errorf("failed to create agent version %s: %w", version.Version, err)
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if _, ok := v.versions[version.Version]; ok {
+		return fmt.Errorf("agent version %s is already registered", version.Version)
+	}
+	v.versions[version.Version] = agent
+	v.order = append(v.order, version.Version)
+	if len(v.order) == 1 {
+		v.rollout = []rolloutShare{{version: version.Version, percent: 100}}
+	}
+	return nil
+}
+
+// SetRollout sets the percentage of traffic each version gets. The
+// percentages must add up to 100; versions not named get no traffic.
+func (v *VersionedAgent) SetRollout(percentages map[string]int) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	total := 0
+	rollout := make([]rolloutShare, 0, len(percentages))
+	for version, percent := range percentages {
+		if _, ok := v.versions[version]; !ok {
+			return fmt.Errorf("unknown agent version: %s", version)
+		}
+		if percent < 0 {
+			return fmt.Errorf("rollout percentage of version %s is negative", version)
+		}
+		total += percent
+		rollout = append(rollout, rolloutShare{version: version, percent: percent})
+	}
+	if total != 100 {
+		return fmt.Errorf("rollout percentages add up to %d, not 100", total)
+	}
+
+	// Keep the order stable so that conversations stay on their version
+	sort.Slice(rollout, func(i, j int) bool {
+		return rollout[i].version < rollout[j].version
+	})
+	v.rollout = rollout
+	return nil
+}
+
+// Rollout returns the percentage of traffic each version gets
+func (v *VersionedAgent) Rollout() map[string]int {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	percentages := make(map[string]int, len(v.rollout))
+	for _, share := range v.rollout {
+		percentages[share.version] = share.percent
+	}
+	return percentages
+}
+
+// Versions returns the names of the registered versions in the order they
+// were registered
+func (v *VersionedAgent) Versions() []string {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	return append([]string{}, v.order...)
+}
+
+// Version returns the agent of a version
+func (v *VersionedAgent) Version(version string) (*Agent, bool) {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	agent, ok := v.versions[version]
+	return agent, ok
+}
+
+// Select returns the agent version that should handle a request: the
+// requested version if there is one, and otherwise a version picked by the
+// rollout. Requests of the same conversation, or else the same user, get the
+// same version as long as the rollout doesn't change.
+func (v *VersionedAgent) Select(ctx context.Context) (*Agent, error) {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	if requested := RequestedVersion(ctx); requested != "" {
+		agent, ok := v.versions[requested]
+		if !ok {
+			return nil, fmt.Errorf("unknown agent version: %s", requested)
+		}
+		return agent, nil
+	}
+	if len(v.rollout) == 0 {
+		return nil, fmt.Errorf("no agent versions registered")
+	}
+
+	bucket := rolloutBucket(ctx)
+	for _, share := range v.rollout {
+		if bucket < share.percent {
+			return v.versions[share.version], nil
+		}
+		bucket -= share.percent
+	}
+	return v.versions[v.rollout[len(v.rollout)-1].version], nil
+}
+
+// Run runs the selected version with the given input
+func (v *VersionedAgent) Run(ctx context.Context, input string) (string, error) {
+	agent, err := v.Select(ctx)
+	if err != nil {
+		return "", err
+	}
+	return agent.Run(ctx, input)
+}
+
+// RunWithReport runs the selected version and returns its run report, which
+// records the version
+func (v *VersionedAgent) RunWithReport(ctx context.Context, input string) (string, *RunReport, error) {
+	agent, err := v.Select(ctx)
+	if err != nil {
+		return "", nil, err
+	}
+	return agent.RunWithReport(ctx, input)
+}
+
+// rolloutBucket returns the bucket in [0, 100) of a request, derived from its
+// conversation or user ID so that they stick to a version
+func rolloutBucket(ctx context.Context) int {
+	key := runctx.ConversationID(ctx)
+	if key == "" {
+		key = runctx.UserID(ctx)
+	}
+	if key == "" {
+		return rand.Intn(100) // #nosec G404 - traffic splitting doesn't need a secure random number
+	}
+	hash := fnv.New32a()
+	_, _ = hash.Write([]byte(key))
+	return int(hash.Sum32() % 100)
+}
+
+type requestedVersionKey struct{}
+
+// WithRequestedVersion returns a context that asks a VersionedAgent for a
+// specific version, e.g. one named in the VersionHeader of a request, to test
+// a version before it gets traffic. An empty version leaves the choice to the
+// rollout.
+func WithRequestedVersion(ctx context.Context, version string) context.Context {
+	return context.WithValue(ctx, requestedVersionKey{}, version)
+}
+
+// RequestedVersion returns the agent version requested in the context, or ""
+func RequestedVersion(ctx context.Context) string {
+	version, _ := ctx.Value(requestedVersionKey{}).(string)
+	return version
+}
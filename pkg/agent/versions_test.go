@@ -0,0 +1,100 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/run-bigpig/llm-agent/pkg/events"
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+	"github.com/run-bigpig/llm-agent/pkg/runctx"
+)
+
+// namedLLM answers with its name
+type namedLLM struct {
+	MockLLM
+	name string
+}
+
+func (m *namedLLM) Generate(ctx context.Context, prompt string, options ...interfaces.GenerateOption) (string, error) {
+	return m.name, nil
+}
+
+func newVersionedAgent(t *testing.T, options ...Option) *VersionedAgent {
+	versioned, err := NewVersionedAgent(
+		append([]Option{WithLLM(&namedLLM{name: "stable"}), WithName("support")}, options...),
+		AgentVersion{Version: "v1"},
+		AgentVersion{Version: "v2", LLM: &namedLLM{name: "candidate"}, SystemPrompt: "Be brief."},
+	)
+	require.NoError(t, err)
+	return versioned
+}
+
+func TestVersionedAgentRoutesToFirstVersion(t *testing.T) {
+	versioned := newVersionedAgent(t)
+
+	response, report, err := versioned.RunWithReport(context.Background(), "hello")
+	require.NoError(t, err)
+	assert.Equal(t, "stable", response)
+	assert.Equal(t, "v1", report.Version)
+	assert.Equal(t, []string{"v1", "v2"}, versioned.Versions())
+	assert.Equal(t, map[string]int{"v1": 100}, versioned.Rollout())
+
+	v2, ok := versioned.Version("v2")
+	require.True(t, ok)
+	assert.Equal(t, "Be brief.", v2.baseSystemPrompt())
+}
+
+func TestVersionedAgentRequestedVersion(t *testing.T) {
+	versioned := newVersionedAgent(t)
+
+	response, err := versioned.Run(WithRequestedVersion(context.Background(), "v2"), "hello")
+	require.NoError(t, err)
+	assert.Equal(t, "candidate", response)
+
+	_, err = versioned.Run(WithRequestedVersion(context.Background(), "v3"), "hello")
+	assert.Error(t, err)
+}
+
+func TestVersionedAgentRollout(t *testing.T) {
+	versioned := newVersionedAgent(t)
+
+	assert.Error(t, versioned.SetRollout(map[string]int{"v1": 50, "v2": 40}))
+	assert.Error(t, versioned.SetRollout(map[string]int{"v1": 50, "v3": 50}))
+	require.NoError(t, versioned.SetRollout(map[string]int{"v1": 80, "v2": 20}))
+
+	counts := map[string]int{}
+	for i := 0; i < 1000; i++ {
+		ctx := runctx.WithConversationID(context.Background(), fmt.Sprintf("conversation-%d", i))
+		agent, err := versioned.Select(ctx)
+		require.NoError(t, err)
+		counts[agent.Version()]++
+
+		// A conversation sticks to its version
+		again, err := versioned.Select(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, agent.Version(), again.Version())
+	}
+	assert.InDelta(t, 800, counts["v1"], 60)
+	assert.InDelta(t, 200, counts["v2"], 60)
+
+	require.NoError(t, versioned.SetRollout(map[string]int{"v2": 100}))
+	agent, err := versioned.Select(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "v2", agent.Version())
+}
+
+func TestVersionIsRecordedInEvents(t *testing.T) {
+	publisher := events.NewMemoryPublisher()
+	versioned := newVersionedAgent(t, WithEventPublisher(publisher))
+
+	_, err := versioned.Run(WithRequestedVersion(context.Background(), "v2"), "hello")
+	require.NoError(t, err)
+
+	published := publisher.Events()
+	require.NotEmpty(t, published)
+	assert.Equal(t, "v2", published[len(published)-1].Tags[VersionTag])
+}
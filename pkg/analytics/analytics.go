@@ -0,0 +1,304 @@
+// Package analytics computes quality metrics over stored conversations:
+// turns per conversation, tool usage, resolution rate as judged by an LLM
+// and estimated cost. A Processor walks the conversations of a Source in a
+// batch, e.g. from a nightly job, and writes a Report to its sinks.
+package analytics
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/run-bigpig/llm-agent/pkg/classification"
+	"github.com/run-bigpig/llm-agent/pkg/cost"
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+	"github.com/run-bigpig/llm-agent/pkg/logging"
+	"github.com/run-bigpig/llm-agent/pkg/parallel"
+)
+
+// ToolCallsKey is the metadata key of the tools an assistant message called,
+// as recorded by the agent
+const ToolCallsKey = "tool_calls"
+
+// ConversationMetrics are the metrics of a single conversation
+type ConversationMetrics struct {
+	ConversationRef
+
+	// Turns is the number of user messages
+	Turns int `json:"turns"`
+
+	// ToolCalls counts the calls of each tool
+	ToolCalls map[string]int `json:"tool_calls,omitempty"`
+
+	// Resolved is the judge's verdict, or nil if the conversation wasn't
+	// judged
+	Resolved *bool `json:"resolved,omitempty"`
+
+	// InputTokens and OutputTokens are the estimated tokens sent to and
+	// generated by the LLM
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+
+	// Cost is the estimated cost in dollars
+	Cost float64 `json:"cost"`
+}
+
+// Report aggregates the metrics of a batch of conversations
+type Report struct {
+	// GeneratedAt is when the report was made
+	GeneratedAt time.Time `json:"generated_at"`
+
+	// Conversations is the number of conversations with messages
+	Conversations int `json:"conversations"`
+
+	// Turns is the total number of user messages, and AverageTurns the
+	// number per conversation
+	Turns        int     `json:"turns"`
+	AverageTurns float64 `json:"average_turns"`
+
+	// ToolUsage counts the calls of each tool across conversations
+	ToolUsage map[string]int `json:"tool_usage"`
+
+	// Intents counts the user messages of each intent, for inputs classified
+	// by the agent's input classifier
+	Intents map[string]int `json:"intents,omitempty"`
+
+	// Judged is the number of conversations the judge gave a verdict on, and
+	// Resolved the number it found resolved
+	Judged   int `json:"judged"`
+	Resolved int `json:"resolved"`
+
+	// ResolutionRate is Resolved divided by Judged
+	ResolutionRate float64 `json:"resolution_rate"`
+
+	// JudgeErrors is the number of conversations the judge failed on
+	JudgeErrors int `json:"judge_errors,omitempty"`
+
+	// TotalCost is the estimated cost of all conversations in dollars, and
+	// AverageCost the cost per conversation
+	TotalCost   float64 `json:"total_cost"`
+	AverageCost float64 `json:"average_cost"`
+
+	// PerConversation holds the metrics of each conversation
+	PerConversation []ConversationMetrics `json:"per_conversation"`
+}
+
+// Processor computes a report over the conversations of a source
+type Processor struct {
+	source      Source
+	judge       Judge
+	price       cost.Price
+	countTokens func(text string) int
+	concurrency int
+	sinks       []Sink
+	logger      logging.Logger
+}
+
+// Option configures a Processor
+type Option func(*Processor)
+
+// WithJudge sets the judge of the resolution rate. Without a judge,
+// conversations are not judged.
+func WithJudge(judge Judge) Option {
+	return func(p *Processor) {
+		p.judge = judge
+	}
+}
+
+// WithPrice sets the price of the agent's model for the cost estimate.
+// Memory doesn't keep token usage, so each assistant message is counted as
+// one LLM call sent the whole conversation before it.
+func WithPrice(price cost.Price) Option {
+	return func(p *Processor) {
+		p.price = price
+	}
+}
+
+// WithTokenCounter sets how the tokens in a text are counted. By default a
+// token is estimated as four characters.
+func WithTokenCounter(counter func(text string) int) Option {
+	return func(p *Processor) {
+		p.countTokens = counter
+	}
+}
+
+// WithConcurrency sets how many conversations are processed at once.
+// Defaults to 4.
+func WithConcurrency(n int) Option {
+	return func(p *Processor) {
+		p.concurrency = n
+	}
+}
+
+// WithSinks sets where reports are written
+func WithSinks(sinks ...Sink) Option {
+	return func(p *Processor) {
+		p.sinks = append(p.sinks, sinks...)
+	}
+}
+
+// WithLogger sets the logger
+func WithLogger(logger logging.Logger) Option {
+	return func(p *Processor) {
+		p.logger = logger
+	}
+}
+
+// NewProcessor creates a processor for the conversations of a source
+func NewProcessor(source Source, options ...Option) *Processor {
+	p := &Processor{
+		source: source,
+		countTokens: func(text string) int {
+			return (len(text) + 3) / 4
+		},
+		concurrency: 4,
+		logger:      logging.New(),
+	}
+	for _, option := range options {
+		option(p)
+	}
+	return p
+}
+
+// conversationResult is what processing one conversation yields
+type conversationResult struct {
+	metrics    ConversationMetrics
+	intents    map[string]int
+	empty      bool
+	judgeError bool
+}
+
+// Run computes the report and writes it to the sinks
+func (p *Processor) Run(ctx context.Context) (*Report, error) {
+	refs, err := p.source.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list conversations: %w", err)
+	}
+
+	results, err := parallel.Map(ctx, refs, func(ctx context.Context, _ int, ref ConversationRef) (conversationResult, error) {
+		return p.process(ctx, ref)
+	}, parallel.WithConcurrency(p.concurrency))
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{
+		GeneratedAt:     time.Now(),
+		ToolUsage:       make(map[string]int),
+		Intents:         make(map[string]int),
+		PerConversation: []ConversationMetrics{},
+	}
+	for _, result := range results {
+		if result.empty {
+			continue
+		}
+		m := result.metrics
+		report.Conversations++
+		report.Turns += m.Turns
+		report.TotalCost += m.Cost
+		for tool, calls := range m.ToolCalls {
+			report.ToolUsage[tool] += calls
+		}
+		for intent, count := range result.intents {
+			report.Intents[intent] += count
+		}
+		if m.Resolved != nil {
+			report.Judged++
+			if *m.Resolved {
+				report.Resolved++
+			}
+		}
+		if result.judgeError {
+			report.JudgeErrors++
+		}
+		report.PerConversation = append(report.PerConversation, m)
+	}
+	if report.Conversations > 0 {
+		report.AverageTurns = float64(report.Turns) / float64(report.Conversations)
+		report.AverageCost = report.TotalCost / float64(report.Conversations)
+	}
+	if report.Judged > 0 {
+		report.ResolutionRate = float64(report.Resolved) / float64(report.Judged)
+	}
+
+	for _, sink := range p.sinks {
+		if err := sink.Write(ctx, report); err != nil {
+			return report, fmt.Errorf("failed to write report: %w", err)
+		}
+	}
+	return report, nil
+}
+
+// process computes the metrics of one conversation
+func (p *Processor) process(ctx context.Context, ref ConversationRef) (conversationResult, error) {
+	messages, err := p.source.Messages(ctx, ref)
+	if err != nil {
+		return conversationResult{}, err
+	}
+	if len(messages) == 0 {
+		return conversationResult{empty: true}, nil
+	}
+
+	result := conversationResult{
+		metrics: ConversationMetrics{ConversationRef: ref, ToolCalls: make(map[string]int)},
+		intents: make(map[string]int),
+	}
+	m := &result.metrics
+
+	contextTokens := 0
+	for _, message := range messages {
+		tokens := p.countTokens(message.Content)
+		switch message.Role {
+		case "user":
+			m.Turns++
+			if c, ok := classification.FromMetadata(message.Metadata); ok && c.Intent != "" {
+				result.intents[c.Intent]++
+			}
+		case "assistant":
+			m.InputTokens += contextTokens
+			m.OutputTokens += tokens
+		case "tool":
+			if name, ok := message.Metadata["tool_name"].(string); ok && name != "" {
+				m.ToolCalls[name]++
+			}
+		}
+		for _, name := range toolCalls(message) {
+			m.ToolCalls[name]++
+		}
+		contextTokens += tokens
+	}
+	m.Cost = (float64(m.InputTokens)*p.price.InputPerMillion + float64(m.OutputTokens)*p.price.OutputPerMillion) / 1e6
+
+	if p.judge != nil {
+		resolved, err := p.judge.Resolved(ctx, messages)
+		if err != nil {
+			// One conversation the judge can't handle shouldn't fail the batch
+			p.logger.Warn(ctx, "Failed to judge conversation", map[string]interface{}{
+				"conversation_id": ref.ID,
+				"error":           err.Error(),
+			})
+			result.judgeError = true
+		} else {
+			m.Resolved = &resolved
+		}
+	}
+	return result, nil
+}
+
+// toolCalls returns the names of the tools an assistant message called
+func toolCalls(message interfaces.Message) []string {
+	switch names := message.Metadata[ToolCallsKey].(type) {
+	case []string:
+		return names
+	case []interface{}:
+		result := make([]string, 0, len(names))
+		for _, name := range names {
+			if s, ok := name.(string); ok {
+				result = append(result, s)
+			}
+		}
+		return result
+	default:
+		return nil
+	}
+}
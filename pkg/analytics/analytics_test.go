@@ -0,0 +1,105 @@
+package analytics_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/run-bigpig/llm-agent/pkg/analytics"
+	"github.com/run-bigpig/llm-agent/pkg/classification"
+	"github.com/run-bigpig/llm-agent/pkg/cost"
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+	"github.com/run-bigpig/llm-agent/pkg/memory"
+	"github.com/run-bigpig/llm-agent/pkg/multitenancy"
+)
+
+// judgeLLM resolves conversations that mention "thanks" and fails on "boom"
+type judgeLLM struct {
+	interfaces.LLM
+}
+
+func (l *judgeLLM) Generate(ctx context.Context, prompt string, options ...interfaces.GenerateOption) (string, error) {
+	switch {
+	case strings.Contains(prompt, "boom"):
+		return "", errors.New("judge unavailable")
+	case strings.Contains(prompt, "thanks"):
+		return "RESOLVED", nil
+	default:
+		return "UNRESOLVED", nil
+	}
+}
+
+func addMessages(t *testing.T, mem interfaces.Memory, id string, messages ...interfaces.Message) {
+	ctx := memory.WithConversationID(multitenancy.WithOrgID(context.Background(), "org-1"), id)
+	for _, message := range messages {
+		require.NoError(t, mem.AddMessage(ctx, message))
+	}
+}
+
+func TestProcessor(t *testing.T) {
+	mem := memory.NewConversationBuffer()
+	addMessages(t, mem, "resolved",
+		interfaces.Message{Role: "user", Content: "What's the weather in Paris?", Metadata: map[string]interface{}{
+			classification.MetadataKey: (&classification.Classification{Intent: "question"}).Metadata(),
+		}},
+		interfaces.Message{Role: "assistant", Content: "Sunny.", Metadata: map[string]interface{}{analytics.ToolCallsKey: []string{"weather"}}},
+		interfaces.Message{Role: "user", Content: "thanks"},
+		interfaces.Message{Role: "assistant", Content: "You're welcome."},
+	)
+	addMessages(t, mem, "unresolved",
+		interfaces.Message{Role: "user", Content: "Book a flight"},
+		interfaces.Message{Role: "assistant", Content: "I can't.", Metadata: map[string]interface{}{analytics.ToolCallsKey: []interface{}{"search", "weather"}}},
+	)
+	addMessages(t, mem, "failed",
+		interfaces.Message{Role: "user", Content: "boom"},
+		interfaces.Message{Role: "assistant", Content: "?"},
+	)
+
+	var out bytes.Buffer
+	refs := []analytics.ConversationRef{
+		{OrgID: "org-1", ID: "resolved"},
+		{OrgID: "org-1", ID: "unresolved"},
+		{OrgID: "org-1", ID: "failed"},
+		{OrgID: "org-1", ID: "missing"},
+	}
+	processor := analytics.NewProcessor(analytics.NewMemorySource(mem, refs...),
+		analytics.WithJudge(analytics.NewLLMJudge(&judgeLLM{})),
+		analytics.WithPrice(cost.Price{InputPerMillion: 1e6, OutputPerMillion: 2e6}),
+		analytics.WithTokenCounter(func(text string) int { return 1 }),
+		analytics.WithSinks(analytics.NewJSONSink(&out)),
+	)
+
+	report, err := processor.Run(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, 3, report.Conversations, "conversations without messages are skipped")
+	assert.Equal(t, 4, report.Turns)
+	assert.InDelta(t, 4.0/3, report.AverageTurns, 1e-9)
+	assert.Equal(t, map[string]int{"weather": 2, "search": 1}, report.ToolUsage)
+	assert.Equal(t, map[string]int{"question": 1}, report.Intents)
+	assert.Equal(t, 2, report.Judged)
+	assert.Equal(t, 1, report.Resolved)
+	assert.Equal(t, 0.5, report.ResolutionRate)
+	assert.Equal(t, 1, report.JudgeErrors)
+
+	// Each assistant message is one call sent the conversation before it:
+	// the first conversation sends 1 and then 3 tokens and gets 2 back
+	require.Len(t, report.PerConversation, 3)
+	first := report.PerConversation[0]
+	assert.Equal(t, "resolved", first.ID)
+	assert.Equal(t, 4, first.InputTokens)
+	assert.Equal(t, 2, first.OutputTokens)
+	assert.Equal(t, 8.0, first.Cost)
+	assert.Equal(t, 8.0+3.0+3.0, report.TotalCost)
+
+	var written analytics.Report
+	require.NoError(t, json.Unmarshal(out.Bytes(), &written))
+	assert.Equal(t, report.Conversations, written.Conversations)
+	assert.Equal(t, report.ToolUsage, written.ToolUsage)
+}
@@ -0,0 +1,63 @@
+package analytics
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+)
+
+// Judge decides whether a conversation resolved the user's request
+type Judge interface {
+	// Resolved reports whether the conversation resolved the user's request
+	Resolved(ctx context.Context, messages []interfaces.Message) (bool, error)
+}
+
+// LLMJudge asks an LLM whether a conversation resolved the user's request
+type LLMJudge struct {
+	llm interfaces.LLM
+}
+
+// NewLLMJudge creates a judge backed by an LLM
+func NewLLMJudge(llm interfaces.LLM) *LLMJudge {
+	return &LLMJudge{llm: llm}
+}
+
+// Resolved reports whether the conversation resolved the user's request
+func (j *LLMJudge) Resolved(ctx context.Context, messages []interfaces.Message) (bool, error) {
+	var transcript strings.Builder
+	for _, message := range messages {
+		if message.Role != "user" && message.Role != "assistant" {
+			continue
+		}
+		fmt.Fprintf(&transcript, "%s: %s\n", message.Role, message.Content)
+	}
+
+	prompt := fmt.Sprintf(`Below is a conversation between a user and an AI assistant.
+Decide whether the assistant resolved the user's request: it answered the question or completed the task, and the user was not left without what they came for.
+Answer with RESOLVED or UNRESOLVED and nothing else.
+
+<<<BEGIN CONVERSATION>>>
+%s<<<END CONVERSATION>>>`, transcript.String())
+
+	response, err := j.llm.Generate(ctx, prompt, func(o *interfaces.GenerateOptions) {
+		if o.LLMConfig == nil {
+			o.LLMConfig = &interfaces.LLMConfig{}
+		}
+		o.LLMConfig.Temperature = 0
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to judge conversation: %w", err)
+	}
+
+	verdict := strings.ToUpper(strings.TrimSpace(response))
+	switch {
+	case strings.HasPrefix(verdict, "UNRESOLVED"):
+		return false, nil
+	case strings.HasPrefix(verdict, "RESOLVED"):
+		return true, nil
+	default:
+		return false, fmt.Errorf("unexpected judge response %q", response)
+	}
+}
@@ -0,0 +1,42 @@
+package analytics
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// Sink receives the reports of the processor
+type Sink interface {
+	// Write writes a report
+	Write(ctx context.Context, report *Report) error
+}
+
+// SinkFunc adapts a function to a Sink
+type SinkFunc func(ctx context.Context, report *Report) error
+
+// Write calls the function
+func (f SinkFunc) Write(ctx context.Context, report *Report) error {
+	return f(ctx, report)
+}
+
+// JSONSink writes reports as indented JSON, one after the other
+type JSONSink struct {
+	w io.Writer
+}
+
+// NewJSONSink creates a sink that writes reports to w, e.g. a file
+func NewJSONSink(w io.Writer) *JSONSink {
+	return &JSONSink{w: w}
+}
+
+// Write writes a report
+func (s *JSONSink) Write(ctx context.Context, report *Report) error {
+	encoder := json.NewEncoder(s.w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		return fmt.Errorf("failed to write analytics report: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package analytics
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+	"github.com/run-bigpig/llm-agent/pkg/memory"
+	"github.com/run-bigpig/llm-agent/pkg/multitenancy"
+	"github.com/run-bigpig/llm-agent/pkg/runctx"
+)
+
+// ConversationRef identifies a stored conversation
+type ConversationRef struct {
+	// OrgID is the organization the conversation belongs to
+	OrgID string `json:"org_id"`
+
+	// UserID is the user the conversation is scoped to, if any
+	UserID string `json:"user_id,omitempty"`
+
+	// ID is the conversation ID
+	ID string `json:"conversation_id"`
+}
+
+// Source lists stored conversations and loads their messages
+type Source interface {
+	// List returns the conversations to analyze
+	List(ctx context.Context) ([]ConversationRef, error)
+
+	// Messages returns the messages of a conversation, oldest first
+	Messages(ctx context.Context, ref ConversationRef) ([]interfaces.Message, error)
+}
+
+// MemorySource reads conversations from an agent memory. Memories can't
+// list their conversations, so the caller names them, e.g. from a database
+// of sessions.
+type MemorySource struct {
+	memory interfaces.Memory
+	refs   []ConversationRef
+}
+
+// NewMemorySource creates a source for conversations in a memory
+func NewMemorySource(mem interfaces.Memory, refs ...ConversationRef) *MemorySource {
+	return &MemorySource{memory: mem, refs: refs}
+}
+
+// List returns the conversations to analyze
+func (s *MemorySource) List(ctx context.Context) ([]ConversationRef, error) {
+	return s.refs, nil
+}
+
+// Messages returns the messages of a conversation, oldest first
+func (s *MemorySource) Messages(ctx context.Context, ref ConversationRef) ([]interfaces.Message, error) {
+	ctx = multitenancy.WithOrgID(ctx, ref.OrgID)
+	ctx = memory.WithConversationID(ctx, ref.ID)
+	if ref.UserID != "" {
+		ctx = runctx.WithUserID(ctx, ref.UserID)
+	}
+	messages, err := s.memory.GetMessages(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get messages of conversation %s: %w", ref.ID, err)
+	}
+	return messages, nil
+}
@@ -0,0 +1,311 @@
+// Package approval queues execution plans that need a human decision, notifies
+// reviewers of new plans and expires plans nobody decides on.
+package approval
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/run-bigpig/llm-agent/pkg/executionplan"
+	"github.com/run-bigpig/llm-agent/pkg/logging"
+	"github.com/run-bigpig/llm-agent/pkg/runctx"
+)
+
+// Status is the state of an approval request
+type Status string
+
+const (
+	// StatusPending means the plan is waiting for a decision
+	StatusPending Status = "pending"
+	// StatusApproved means the plan was approved
+	StatusApproved Status = "approved"
+	// StatusRejected means the plan was rejected
+	StatusRejected Status = "rejected"
+	// StatusExpired means nobody decided on the plan before it expired
+	StatusExpired Status = "expired"
+)
+
+var (
+	// ErrNotFound is returned when an approval request does not exist
+	ErrNotFound = errors.New("approval request not found")
+
+	// ErrDecided is returned when deciding on a request that is no longer pending
+	ErrDecided = errors.New("approval request already decided")
+)
+
+// Request is an execution plan waiting for approval
+type Request struct {
+	ID             string                       `json:"id"`
+	Agent          string                       `json:"agent"`
+	OrgID          string                       `json:"org_id,omitempty"`
+	UserID         string                       `json:"user_id,omitempty"`
+	ConversationID string                       `json:"conversation_id,omitempty"`
+	Plan           *executionplan.ExecutionPlan `json:"plan"`
+	Status         Status                       `json:"status"`
+	CreatedAt      time.Time                    `json:"created_at"`
+	ExpiresAt      time.Time                    `json:"expires_at,omitzero"`
+	DecidedAt      time.Time                    `json:"decided_at,omitzero"`
+	DecidedBy      string                       `json:"decided_by,omitempty"`
+	Reason         string                       `json:"reason,omitempty"`
+	Result         string                       `json:"result,omitempty"`
+	Error          string                       `json:"error,omitempty"`
+}
+
+// Context returns ctx with the organization, user and conversation of the
+// run that created the plan, so the plan executes on behalf of that run
+func (r *Request) Context(ctx context.Context) context.Context {
+	ctx, _ = runctx.With(ctx, runctx.RunContext{
+		OrgID:          r.OrgID,
+		UserID:         r.UserID,
+		ConversationID: r.ConversationID,
+	})
+	return ctx
+}
+
+// Handler is called when a request is decided. For approved requests it
+// executes the plan and returns the result, which is stored on the request.
+type Handler func(ctx context.Context, req *Request) (string, error)
+
+// ExpiryAction is what happens to a request when it expires
+type ExpiryAction string
+
+const (
+	// ExpireReject marks expired requests as expired without running them
+	ExpireReject ExpiryAction = "reject"
+	// ExpireApprove approves expired requests
+	ExpireApprove ExpiryAction = "approve"
+)
+
+// Queue holds execution plans awaiting approval
+type Queue struct {
+	backend      Backend
+	notifiers    []Notifier
+	ttl          time.Duration
+	expiryAction ExpiryAction
+	logger       logging.Logger
+
+	mu       sync.RWMutex
+	handlers map[string]Handler
+	decideMu sync.Mutex // Serializes decisions so a request is decided once
+}
+
+// Option represents an option for configuring the queue
+type Option func(*Queue)
+
+// WithNotifiers notifies reviewers of every new request
+func WithNotifiers(notifiers ...Notifier) Option {
+	return func(q *Queue) {
+		q.notifiers = append(q.notifiers, notifiers...)
+	}
+}
+
+// WithExpiry expires pending requests after ttl, applying the action
+func WithExpiry(ttl time.Duration, action ExpiryAction) Option {
+	return func(q *Queue) {
+		q.ttl = ttl
+		q.expiryAction = action
+	}
+}
+
+// WithLogger sets the logger for the queue
+func WithLogger(logger logging.Logger) Option {
+	return func(q *Queue) {
+		q.logger = logger
+	}
+}
+
+// NewQueue creates a new approval queue backed by backend
+func NewQueue(backend Backend, options ...Option) *Queue {
+	q := &Queue{
+		backend:      backend,
+		expiryAction: ExpireReject,
+		logger:       logging.New(),
+		handlers:     make(map[string]Handler),
+	}
+	for _, option := range options {
+		option(q)
+	}
+	return q
+}
+
+// Handle registers the handler for decisions on the agent's requests
+func (q *Queue) Handle(agent string, handler Handler) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.handlers[agent] = handler
+}
+
+// Submit queues a plan of the agent for approval and notifies reviewers
+func (q *Queue) Submit(ctx context.Context, agent string, plan *executionplan.ExecutionPlan) (*Request, error) {
+	rc := runctx.From(ctx)
+	now := time.Now()
+	req := &Request{
+		ID:             plan.TaskID,
+		Agent:          agent,
+		OrgID:          rc.OrgID,
+		UserID:         rc.UserID,
+		ConversationID: rc.ConversationID,
+		Plan:           plan,
+		Status:         StatusPending,
+		CreatedAt:      now,
+	}
+	if q.ttl > 0 {
+		req.ExpiresAt = now.Add(q.ttl)
+	}
+
+	if err := q.backend.Save(ctx, req); err != nil {
+		return nil, fmt.Errorf("failed to save approval request: %w", err)
+	}
+
+	for _, notifier := range q.notifiers {
+		if err := notifier.Notify(ctx, req); err != nil {
+			q.logger.Warn(ctx, "Failed to send approval notification", map[string]interface{}{
+				"request_id": req.ID,
+				"error":      err.Error(),
+			})
+		}
+	}
+
+	return req, nil
+}
+
+// Get returns the request with the given ID
+func (q *Queue) Get(ctx context.Context, id string) (*Request, error) {
+	return q.backend.Get(ctx, id)
+}
+
+// List returns the requests with the given status, oldest first. An empty
+// status lists all requests.
+func (q *Queue) List(ctx context.Context, status Status) ([]*Request, error) {
+	requests, err := q.backend.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list approval requests: %w", err)
+	}
+
+	filtered := make([]*Request, 0, len(requests))
+	for _, req := range requests {
+		if status == "" || req.Status == status {
+			filtered = append(filtered, req)
+		}
+	}
+	sort.Slice(filtered, func(i, j int) bool {
+		return filtered[i].CreatedAt.Before(filtered[j].CreatedAt)
+	})
+	return filtered, nil
+}
+
+// Approve approves a pending request and runs its plan
+func (q *Queue) Approve(ctx context.Context, id, decidedBy string) (*Request, error) {
+	return q.decide(ctx, id, StatusApproved, decidedBy, "")
+}
+
+// Reject rejects a pending request
+func (q *Queue) Reject(ctx context.Context, id, decidedBy, reason string) (*Request, error) {
+	return q.decide(ctx, id, StatusRejected, decidedBy, reason)
+}
+
+// ExpireStale applies the expiry action to pending requests past their
+// expiry time and returns how many were expired
+func (q *Queue) ExpireStale(ctx context.Context) (int, error) {
+	pending, err := q.List(ctx, StatusPending)
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	expired := 0
+	for _, req := range pending {
+		if req.ExpiresAt.IsZero() || now.Before(req.ExpiresAt) {
+			continue
+		}
+
+		status := StatusExpired
+		if q.expiryAction == ExpireApprove {
+			status = StatusApproved
+		}
+		if _, err := q.decide(ctx, req.ID, status, "expiry", "expired"); err != nil {
+			if errors.Is(err, ErrDecided) {
+				continue
+			}
+			q.logger.Warn(ctx, "Failed to expire approval request", map[string]interface{}{
+				"request_id": req.ID,
+				"error":      err.Error(),
+			})
+		}
+		expired++
+	}
+	return expired, nil
+}
+
+// Start expires stale requests every interval until ctx is cancelled
+func (q *Queue) Start(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if _, err := q.ExpireStale(ctx); err != nil {
+					q.logger.Error(ctx, "Failed to expire approval requests", map[string]interface{}{"error": err.Error()})
+				}
+			}
+		}
+	}()
+}
+
+// decide records a decision on a pending request and calls the agent's handler
+func (q *Queue) decide(ctx context.Context, id string, status Status, decidedBy, reason string) (*Request, error) {
+	req, err := q.record(ctx, id, status, decidedBy, reason)
+	if err != nil {
+		return req, err
+	}
+
+	q.mu.RLock()
+	handler := q.handlers[req.Agent]
+	q.mu.RUnlock()
+	if handler == nil {
+		return req, nil
+	}
+
+	result, err := handler(req.Context(ctx), req)
+	req.Result = result
+	if err != nil {
+		req.Error = err.Error()
+	}
+	if saveErr := q.backend.Save(ctx, req); saveErr != nil {
+		return nil, fmt.Errorf("failed to save approval result: %w", saveErr)
+	}
+	if err != nil {
+		return req, fmt.Errorf("failed to handle approval decision: %w", err)
+	}
+	return req, nil
+}
+
+// record marks a pending request as decided
+func (q *Queue) record(ctx context.Context, id string, status Status, decidedBy, reason string) (*Request, error) {
+	q.decideMu.Lock()
+	defer q.decideMu.Unlock()
+
+	req, err := q.backend.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if req.Status != StatusPending {
+		return req, fmt.Errorf("%w: %s is %s", ErrDecided, id, req.Status)
+	}
+
+	req.Status = status
+	req.DecidedAt = time.Now()
+	req.DecidedBy = decidedBy
+	req.Reason = reason
+	if err := q.backend.Save(ctx, req); err != nil {
+		return nil, fmt.Errorf("failed to save approval request: %w", err)
+	}
+	return req, nil
+}
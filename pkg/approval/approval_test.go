@@ -0,0 +1,138 @@
+package approval_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/run-bigpig/llm-agent/pkg/approval"
+	"github.com/run-bigpig/llm-agent/pkg/executionplan"
+	"github.com/run-bigpig/llm-agent/pkg/runctx"
+)
+
+func newPlan() *executionplan.ExecutionPlan {
+	return executionplan.NewExecutionPlan("Restart the web servers", []executionplan.ExecutionStep{
+		{ToolName: "ssh", Description: "Restart nginx", Input: "systemctl restart nginx"},
+	})
+}
+
+func TestQueue(t *testing.T) {
+	var notified []string
+	slack := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		notified = append(notified, body["text"])
+	}))
+	defer slack.Close()
+
+	backend, err := approval.NewFileBackend(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create backend: %v", err)
+	}
+	queue := approval.NewQueue(backend, approval.WithNotifiers(
+		approval.NewSlackNotifier(slack.URL, approval.WithReviewURL("https://ops.example.com/approvals")),
+	))
+
+	var handled *approval.Request
+	var handledOrg string
+	queue.Handle("ops", func(ctx context.Context, req *approval.Request) (string, error) {
+		handled = req
+		handledOrg = runctx.OrgID(ctx)
+		return "done", nil
+	})
+
+	ctx := runctx.WithOrgID(context.Background(), "org-1")
+	plan := newPlan()
+	if _, err := queue.Submit(ctx, "ops", plan); err != nil {
+		t.Fatalf("failed to submit plan: %v", err)
+	}
+	if len(notified) != 1 || !strings.Contains(notified[0], "Restart the web servers") || !strings.Contains(notified[0], "https://ops.example.com/approvals/"+plan.TaskID) {
+		t.Fatalf("unexpected notifications: %v", notified)
+	}
+
+	// A new queue on the same backend sees the pending plan
+	queue = approval.NewQueue(backend)
+	queue.Handle("ops", func(ctx context.Context, req *approval.Request) (string, error) {
+		handled = req
+		handledOrg = runctx.OrgID(ctx)
+		return "done", nil
+	})
+	server := httptest.NewServer(http.StripPrefix("/approvals", queue.Handler()))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/approvals/?status=pending")
+	if err != nil {
+		t.Fatalf("failed to list approvals: %v", err)
+	}
+	var pending []approval.Request
+	_ = json.NewDecoder(resp.Body).Decode(&pending)
+	resp.Body.Close()
+	if len(pending) != 1 || pending[0].ID != plan.TaskID || pending[0].Plan.Steps[0].ToolName != "ssh" {
+		t.Fatalf("unexpected pending approvals: %+v", pending)
+	}
+
+	resp, err = http.Post(server.URL+"/approvals/"+plan.TaskID+"/approve", "application/json", strings.NewReader(`{"decided_by":"alice"}`))
+	if err != nil {
+		t.Fatalf("failed to approve: %v", err)
+	}
+	var decided approval.Request
+	_ = json.NewDecoder(resp.Body).Decode(&decided)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || decided.Status != approval.StatusApproved || decided.DecidedBy != "alice" || decided.Result != "done" {
+		t.Fatalf("unexpected decision (%d): %+v", resp.StatusCode, decided)
+	}
+	if handled == nil || handledOrg != "org-1" {
+		t.Fatalf("expected the handler to run in org-1, got %q", handledOrg)
+	}
+
+	// A decided request cannot be decided again
+	resp, err = http.Post(server.URL+"/approvals/"+plan.TaskID+"/reject", "application/json", nil)
+	if err != nil {
+		t.Fatalf("failed to reject: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusConflict {
+		t.Errorf("expected 409, got %d", resp.StatusCode)
+	}
+
+	resp, err = http.Get(server.URL + "/approvals/unknown")
+	if err != nil {
+		t.Fatalf("failed to get approval: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected 404, got %d", resp.StatusCode)
+	}
+}
+
+func TestExpireStale(t *testing.T) {
+	queue := approval.NewQueue(approval.NewMemoryBackend(), approval.WithExpiry(time.Millisecond, approval.ExpireReject))
+
+	called := false
+	queue.Handle("ops", func(ctx context.Context, req *approval.Request) (string, error) {
+		called = true
+		return "", nil
+	})
+
+	plan := newPlan()
+	if _, err := queue.Submit(context.Background(), "ops", plan); err != nil {
+		t.Fatalf("failed to submit plan: %v", err)
+	}
+	time.Sleep(5 * time.Millisecond)
+
+	expired, err := queue.ExpireStale(context.Background())
+	if err != nil || expired != 1 {
+		t.Fatalf("expected 1 expired request, got %d (%v)", expired, err)
+	}
+	req, err := queue.Get(context.Background(), plan.TaskID)
+	if err != nil || req.Status != approval.StatusExpired {
+		t.Fatalf("expected an expired request, got %+v (%v)", req, err)
+	}
+	if !called {
+		t.Error("expected the handler to be told about the expiry")
+	}
+}
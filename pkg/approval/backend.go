@@ -0,0 +1,161 @@
+package approval
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Backend persists approval requests
+type Backend interface {
+	// Save creates or replaces a request
+	Save(ctx context.Context, req *Request) error
+
+	// Get returns the request with the given ID, or ErrNotFound
+	Get(ctx context.Context, id string) (*Request, error)
+
+	// List returns all requests
+	List(ctx context.Context) ([]*Request, error)
+}
+
+// MemoryBackend keeps requests in memory. Requests are lost when the process exits.
+type MemoryBackend struct {
+	requests map[string]Request
+	mu       sync.RWMutex
+}
+
+// NewMemoryBackend creates a new in-memory backend
+func NewMemoryBackend() *MemoryBackend {
+	return &MemoryBackend{requests: make(map[string]Request)}
+}
+
+// Save creates or replaces a request
+func (b *MemoryBackend) Save(ctx context.Context, req *Request) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.requests[req.ID] = *req
+	return nil
+}
+
+// Get returns the request with the given ID
+func (b *MemoryBackend) Get(ctx context.Context, id string) (*Request, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	req, ok := b.requests[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &req, nil
+}
+
+// List returns all requests
+func (b *MemoryBackend) List(ctx context.Context) ([]*Request, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	requests := make([]*Request, 0, len(b.requests))
+	for _, req := range b.requests {
+		req := req
+		requests = append(requests, &req)
+	}
+	return requests, nil
+}
+
+// FileBackend stores each request as a JSON file in a directory, so pending
+// plans survive restarts
+type FileBackend struct {
+	dir string
+	mu  sync.RWMutex
+}
+
+// NewFileBackend creates a backend that stores requests in dir, creating it if needed
+func NewFileBackend(dir string) (*FileBackend, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create approval directory: %w", err)
+	}
+	return &FileBackend{dir: dir}, nil
+}
+
+// Save creates or replaces a request
+func (b *FileBackend) Save(ctx context.Context, req *Request) error {
+	path, err := b.path(req.ID)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(req, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal approval request: %w", err)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	// Write to a temporary file first so a crash cannot leave a partial request
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write approval request: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to write approval request: %w", err)
+	}
+	return nil
+}
+
+// Get returns the request with the given ID
+func (b *FileBackend) Get(ctx context.Context, id string) (*Request, error) {
+	path, err := b.path(id)
+	if err != nil {
+		return nil, err
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return readRequest(path)
+}
+
+// List returns all requests
+func (b *FileBackend) List(ctx context.Context) ([]*Request, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	paths, err := filepath.Glob(filepath.Join(b.dir, "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list approval requests: %w", err)
+	}
+	requests := make([]*Request, 0, len(paths))
+	for _, path := range paths {
+		req, err := readRequest(path)
+		if err != nil {
+			return nil, err
+		}
+		requests = append(requests, req)
+	}
+	return requests, nil
+}
+
+// path returns the file of the request, rejecting IDs that would escape the directory
+func (b *FileBackend) path(id string) (string, error) {
+	if id == "" || strings.ContainsAny(id, `/\`) || strings.Contains(id, "..") {
+		return "", fmt.Errorf("invalid approval request ID %q", id)
+	}
+	return filepath.Join(b.dir, id+".json"), nil
+}
+
+func readRequest(path string) (*Request, error) {
+	data, err := os.ReadFile(path) // #nosec G304 - Path is built from a validated ID
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to read approval request: %w", err)
+	}
+	var req Request
+	if err := json.Unmarshal(data, &req); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal approval request: %w", err)
+	}
+	return &req, nil
+}
@@ -0,0 +1,109 @@
+package approval
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/run-bigpig/llm-agent/pkg/runctx"
+)
+
+// decision is the optional body of approve and reject requests
+type decision struct {
+	DecidedBy string `json:"decided_by"`
+	Reason    string `json:"reason"`
+}
+
+// Handler returns an http.Handler with the approval API:
+//
+//	GET  /                 lists requests, filtered by ?status=pending
+//	GET  /{id}             returns a request
+//	POST /{id}/approve     approves a request and runs its plan
+//	POST /{id}/reject      rejects a request
+//
+// Approve and reject accept {"decided_by": "...", "reason": "..."}; the
+// decider defaults to the user ID in the request context. Mount it under a
+// prefix with http.StripPrefix.
+func (q *Queue) Handler() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {
+		requests, err := q.List(r.Context(), Status(r.URL.Query().Get("status")))
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, requests)
+	})
+
+	mux.HandleFunc("GET /{id}", func(w http.ResponseWriter, r *http.Request) {
+		req, err := q.Get(r.Context(), r.PathValue("id"))
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, req)
+	})
+
+	mux.HandleFunc("POST /{id}/approve", func(w http.ResponseWriter, r *http.Request) {
+		d, err := readDecision(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		req, err := q.Approve(r.Context(), r.PathValue("id"), d.DecidedBy)
+		writeDecision(w, req, err)
+	})
+
+	mux.HandleFunc("POST /{id}/reject", func(w http.ResponseWriter, r *http.Request) {
+		d, err := readDecision(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		req, err := q.Reject(r.Context(), r.PathValue("id"), d.DecidedBy, d.Reason)
+		writeDecision(w, req, err)
+	})
+
+	return mux
+}
+
+// readDecision reads the optional decision body
+func readDecision(r *http.Request) (decision, error) {
+	var d decision
+	if err := json.NewDecoder(io.LimitReader(r.Body, 64*1024)).Decode(&d); err != nil && !errors.Is(err, io.EOF) {
+		return d, err
+	}
+	if d.DecidedBy == "" {
+		d.DecidedBy = runctx.UserID(r.Context())
+	}
+	return d, nil
+}
+
+// writeDecision writes the decided request. A failure to run an approved
+// plan is reported in the request's error field rather than as an HTTP error.
+func writeDecision(w http.ResponseWriter, req *Request, err error) {
+	if err != nil && (req == nil || errors.Is(err, ErrDecided)) {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, req)
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, ErrNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, ErrDecided):
+		status = http.StatusConflict
+	}
+	http.Error(w, err.Error(), status)
+}
+
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(value)
+}
@@ -0,0 +1,180 @@
+package approval
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Notifier tells reviewers about a new approval request
+type Notifier interface {
+	Notify(ctx context.Context, req *Request) error
+}
+
+// NotifierFunc adapts a function to the Notifier interface
+type NotifierFunc func(ctx context.Context, req *Request) error
+
+// Notify calls f(ctx, req)
+func (f NotifierFunc) Notify(ctx context.Context, req *Request) error {
+	return f(ctx, req)
+}
+
+// notifierConfig holds the options shared by the notifiers
+type notifierConfig struct {
+	httpClient *http.Client
+	reviewURL  string
+	secret     string
+}
+
+// NotifierOption represents an option for configuring a notifier
+type NotifierOption func(*notifierConfig)
+
+// WithHTTPClient sets the HTTP client used to send notifications
+func WithHTTPClient(client *http.Client) NotifierOption {
+	return func(c *notifierConfig) {
+		c.httpClient = client
+	}
+}
+
+// WithReviewURL links notifications to baseURL + "/" + request ID, e.g. the
+// approval API or a review page
+func WithReviewURL(baseURL string) NotifierOption {
+	return func(c *notifierConfig) {
+		c.reviewURL = strings.TrimSuffix(baseURL, "/")
+	}
+}
+
+// WithSigningSecret signs webhook bodies with HMAC-SHA256 in the
+// X-Signature-256 header ("sha256=<hex>")
+func WithSigningSecret(secret string) NotifierOption {
+	return func(c *notifierConfig) {
+		c.secret = secret
+	}
+}
+
+func newNotifierConfig(options []NotifierOption) notifierConfig {
+	config := notifierConfig{httpClient: &http.Client{Timeout: 10 * time.Second}}
+	for _, option := range options {
+		option(&config)
+	}
+	return config
+}
+
+// link returns the review link of the request, or "" if none is configured
+func (c notifierConfig) link(req *Request) string {
+	if c.reviewURL == "" {
+		return ""
+	}
+	return c.reviewURL + "/" + req.ID
+}
+
+// post sends body as JSON to url
+func (c notifierConfig) post(ctx context.Context, url string, body []byte) error {
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create notification request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if c.secret != "" {
+		mac := hmac.New(sha256.New, []byte(c.secret))
+		mac.Write(body)
+		httpReq.Header.Set("X-Signature-256", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("failed to send notification: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("notification failed with status %d: %s", resp.StatusCode, string(respBody))
+	}
+	return nil
+}
+
+// WebhookNotifier posts new requests as JSON to a URL
+type WebhookNotifier struct {
+	url    string
+	config notifierConfig
+}
+
+// NewWebhookNotifier creates a notifier that posts
+// {"event": "approval.requested", "request": ..., "review_url": ...} to url
+func NewWebhookNotifier(url string, options ...NotifierOption) *WebhookNotifier {
+	return &WebhookNotifier{url: url, config: newNotifierConfig(options)}
+}
+
+// Notify posts the request to the webhook
+func (n *WebhookNotifier) Notify(ctx context.Context, req *Request) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"event":      "approval.requested",
+		"request":    req,
+		"review_url": n.config.link(req),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal notification: %w", err)
+	}
+	return n.config.post(ctx, n.url, body)
+}
+
+// SlackNotifier posts new requests to a Slack incoming webhook
+type SlackNotifier struct {
+	webhookURL string
+	config     notifierConfig
+}
+
+// NewSlackNotifier creates a notifier for a Slack incoming webhook URL
+func NewSlackNotifier(webhookURL string, options ...NotifierOption) *SlackNotifier {
+	return &SlackNotifier{webhookURL: webhookURL, config: newNotifierConfig(options)}
+}
+
+// Notify posts a summary of the plan to Slack
+func (n *SlackNotifier) Notify(ctx context.Context, req *Request) error {
+	body, err := json.Marshal(map[string]string{"text": n.message(req)})
+	if err != nil {
+		return fmt.Errorf("failed to marshal Slack message: %w", err)
+	}
+	return n.config.post(ctx, n.webhookURL, body)
+}
+
+// message formats the request for Slack
+func (n *SlackNotifier) message(req *Request) string {
+	var sb strings.Builder
+	agent := req.Agent
+	if agent == "" {
+		agent = "An agent"
+	}
+	sb.WriteString(fmt.Sprintf("*%s is waiting for approval of a plan*", agent))
+	if req.Plan != nil && req.Plan.Description != "" {
+		sb.WriteString(": " + req.Plan.Description)
+	}
+	sb.WriteString("\n")
+
+	if req.Plan != nil {
+		for i, step := range req.Plan.Steps {
+			sb.WriteString(fmt.Sprintf("%d. `%s` %s\n", i+1, step.ToolName, step.Description))
+		}
+	}
+	if req.UserID != "" {
+		sb.WriteString(fmt.Sprintf("Requested by: %s\n", req.UserID))
+	}
+	if !req.ExpiresAt.IsZero() {
+		sb.WriteString(fmt.Sprintf("Expires: %s\n", req.ExpiresAt.Format(time.RFC3339)))
+	}
+	if link := n.config.link(req); link != "" {
+		sb.WriteString(fmt.Sprintf("<%s|Review request %s>\n", link, req.ID))
+	} else {
+		sb.WriteString(fmt.Sprintf("Request ID: %s\n", req.ID))
+	}
+	return sb.String()
+}
@@ -0,0 +1,47 @@
+package artifact
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+)
+
+// ErrNotFound is returned when no object exists under a key
+var ErrNotFound = errors.New("artifact not found")
+
+// Store persists task outputs and other files so they can be retrieved after
+// the run, for example through a signed URL
+type Store interface {
+	// Put stores the content under the key, replacing any existing object
+	Put(ctx context.Context, key string, r io.Reader, contentType string) error
+
+	// Get opens the object stored under the key
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+
+	// Delete removes the object stored under the key
+	Delete(ctx context.Context, key string) error
+
+	// SignedURL returns a URL that grants read access to the object until it
+	// expires
+	SignedURL(ctx context.Context, key string, expiry time.Duration) (string, error)
+}
+
+// CleanKey normalizes a key to a relative, slash-separated path and rejects
+// keys that would escape the store root
+func CleanKey(key string) (string, error) {
+	key = strings.ReplaceAll(key, "\\", "/")
+	cleaned := path.Clean("/" + key)
+	if cleaned == "/" {
+		return "", fmt.Errorf("invalid artifact key %q", key)
+	}
+	for _, segment := range strings.Split(key, "/") {
+		if segment == ".." {
+			return "", fmt.Errorf("invalid artifact key %q", key)
+		}
+	}
+	return strings.TrimPrefix(cleaned, "/"), nil
+}
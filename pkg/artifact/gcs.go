@@ -0,0 +1,254 @@
+package artifact
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/option"
+	"google.golang.org/api/storage/v1"
+)
+
+// GCSStore stores artifacts in a Google Cloud Storage bucket
+type GCSStore struct {
+	service     *storage.Service
+	bucket      string
+	prefix      string
+	accessID    string
+	privateKey  *rsa.PrivateKey
+	credentials []byte
+	clientOpts  []option.ClientOption
+	now         func() time.Time
+}
+
+// GCSOption represents an option for configuring the GCS store
+type GCSOption func(*GCSStore)
+
+// WithGCSCredentialsFile authenticates with a service account key file, which
+// is also used to sign URLs. Without it, application default credentials are
+// used and signed URLs require WithGCSSigner.
+func WithGCSCredentialsFile(path string) GCSOption {
+	return func(s *GCSStore) {
+		s.clientOpts = append(s.clientOpts, option.WithCredentialsFile(path))
+		s.credentials, _ = os.ReadFile(path)
+	}
+}
+
+// WithGCSSigner sets the service account email and PEM-encoded private key
+// used to sign URLs
+func WithGCSSigner(accessID string, privateKeyPEM []byte) GCSOption {
+	return func(s *GCSStore) {
+		s.accessID = accessID
+		s.privateKey, _ = parseRSAPrivateKey(privateKeyPEM)
+	}
+}
+
+// WithGCSPrefix stores every object under the key prefix
+func WithGCSPrefix(prefix string) GCSOption {
+	return func(s *GCSStore) {
+		s.prefix = strings.Trim(prefix, "/")
+	}
+}
+
+// WithGCSClientOptions passes additional options to the storage client
+func WithGCSClientOptions(opts ...option.ClientOption) GCSOption {
+	return func(s *GCSStore) {
+		s.clientOpts = append(s.clientOpts, opts...)
+	}
+}
+
+// NewGCSStore creates a store for the bucket
+func NewGCSStore(ctx context.Context, bucket string, options ...GCSOption) (*GCSStore, error) {
+	store := &GCSStore{
+		bucket: bucket,
+		now:    time.Now,
+	}
+
+	for _, option := range options {
+		option(store)
+	}
+
+	// Use the service account key for signing unless a signer was given
+	if store.privateKey == nil && len(store.credentials) > 0 {
+		var key struct {
+			ClientEmail string `json:"client_email"`
+			PrivateKey  string `json:"private_key"`
+		}
+		if err := json.Unmarshal(store.credentials, &key); err == nil && key.PrivateKey != "" {
+			privateKey, err := parseRSAPrivateKey([]byte(key.PrivateKey))
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse service account key: %w", err)
+			}
+			store.accessID = key.ClientEmail
+			store.privateKey = privateKey
+		}
+	}
+
+	service, err := storage.NewService(ctx, store.clientOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create storage client: %w", err)
+	}
+	store.service = service
+
+	return store, nil
+}
+
+// Put stores the content under the key, replacing any existing object
+func (s *GCSStore) Put(ctx context.Context, key string, r io.Reader, contentType string) error {
+	name, err := s.objectName(key)
+	if err != nil {
+		return err
+	}
+
+	var mediaOptions []googleapi.MediaOption
+	if contentType != "" {
+		mediaOptions = append(mediaOptions, googleapi.ContentType(contentType))
+	}
+
+	object := &storage.Object{Name: name, ContentType: contentType}
+	if _, err := s.service.Objects.Insert(s.bucket, object).Media(r, mediaOptions...).Context(ctx).Do(); err != nil {
+		return fmt.Errorf("failed to put artifact %s: %w", key, err)
+	}
+	return nil
+}
+
+// Get opens the object stored under the key
+func (s *GCSStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	name, err := s.objectName(key)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.service.Objects.Get(s.bucket, name).Context(ctx).Download()
+	if err != nil {
+		if isNotFound(err) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get artifact %s: %w", key, err)
+	}
+	return resp.Body, nil
+}
+
+// Delete removes the object stored under the key
+func (s *GCSStore) Delete(ctx context.Context, key string) error {
+	name, err := s.objectName(key)
+	if err != nil {
+		return err
+	}
+
+	if err := s.service.Objects.Delete(s.bucket, name).Context(ctx).Do(); err != nil {
+		if isNotFound(err) {
+			return ErrNotFound
+		}
+		return fmt.Errorf("failed to delete artifact %s: %w", key, err)
+	}
+	return nil
+}
+
+// SignedURL returns a V4 signed GET URL for the object. GCS limits the expiry
+// to seven days.
+func (s *GCSStore) SignedURL(ctx context.Context, key string, expiry time.Duration) (string, error) {
+	if s.privateKey == nil || s.accessID == "" {
+		return "", fmt.Errorf("signed URLs require a service account key")
+	}
+
+	name, err := s.objectName(key)
+	if err != nil {
+		return "", err
+	}
+
+	now := s.now().UTC()
+	date := now.Format("20060102T150405Z")
+	scope := now.Format("20060102") + "/auto/storage/goog4_request"
+	host := "storage.googleapis.com"
+	path := s3EscapePath("/" + s.bucket + "/" + name)
+
+	query := url.Values{}
+	query.Set("X-Goog-Algorithm", "GOOG4-RSA-SHA256")
+	query.Set("X-Goog-Credential", s.accessID+"/"+scope)
+	query.Set("X-Goog-Date", date)
+	query.Set("X-Goog-Expires", strconv.Itoa(int(expiry.Seconds())))
+	query.Set("X-Goog-SignedHeaders", "host")
+
+	canonicalRequest := strings.Join([]string{
+		http.MethodGet,
+		path,
+		canonicalQuery(query),
+		"host:" + host + "\n",
+		"host",
+		"UNSIGNED-PAYLOAD",
+	}, "\n")
+
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{
+		"GOOG4-RSA-SHA256",
+		date,
+		scope,
+		hex.EncodeToString(requestHash[:]),
+	}, "\n")
+
+	digest := sha256.Sum256([]byte(stringToSign))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, s.privateKey, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("failed to sign URL: %w", err)
+	}
+	query.Set("X-Goog-Signature", hex.EncodeToString(signature))
+
+	return "https://" + host + path + "?" + canonicalQuery(query), nil
+}
+
+// objectName returns the object name for the key
+func (s *GCSStore) objectName(key string) (string, error) {
+	key, err := CleanKey(key)
+	if err != nil {
+		return "", err
+	}
+	if s.prefix != "" {
+		key = s.prefix + "/" + key
+	}
+	return key, nil
+}
+
+// isNotFound reports whether the error is a 404 from the API
+func isNotFound(err error) bool {
+	var apiErr *googleapi.Error
+	return errors.As(err, &apiErr) && apiErr.Code == http.StatusNotFound
+}
+
+// parseRSAPrivateKey parses a PEM-encoded PKCS#1 or PKCS#8 RSA private key
+func parseRSAPrivateKey(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data found")
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("private key is not an RSA key")
+	}
+	return rsaKey, nil
+}
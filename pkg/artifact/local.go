@@ -0,0 +1,196 @@
+package artifact
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LocalStore stores artifacts on the local filesystem
+type LocalStore struct {
+	dir        string
+	baseURL    string
+	signingKey []byte
+	now        func() time.Time
+}
+
+// LocalOption represents an option for configuring the local store
+type LocalOption func(*LocalStore)
+
+// WithBaseURL sets the URL at which the store's Handler is served, used to
+// build signed URLs
+func WithBaseURL(baseURL string) LocalOption {
+	return func(s *LocalStore) {
+		s.baseURL = strings.TrimSuffix(baseURL, "/")
+	}
+}
+
+// WithSigningKey sets the secret used to sign and verify URLs
+func WithSigningKey(key []byte) LocalOption {
+	return func(s *LocalStore) {
+		s.signingKey = key
+	}
+}
+
+// NewLocalStore creates a store that keeps artifacts under dir
+func NewLocalStore(dir string, options ...LocalOption) *LocalStore {
+	store := &LocalStore{
+		dir: dir,
+		now: time.Now,
+	}
+
+	for _, option := range options {
+		option(store)
+	}
+
+	return store
+}
+
+// Put stores the content under the key, replacing any existing object
+func (s *LocalStore) Put(ctx context.Context, key string, r io.Reader, contentType string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	// Write to a temporary file first so readers never see partial content
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".artifact-*")
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write artifact %s: %w", key, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write artifact %s: %w", key, err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write artifact %s: %w", key, err)
+	}
+	return nil
+}
+
+// Get opens the object stored under the key
+func (s *LocalStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open artifact %s: %w", key, err)
+	}
+	return f, nil
+}
+
+// Delete removes the object stored under the key
+func (s *LocalStore) Delete(ctx context.Context, key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete artifact %s: %w", key, err)
+	}
+	return nil
+}
+
+// SignedURL returns a URL served by Handler that grants read access to the
+// object until it expires
+func (s *LocalStore) SignedURL(ctx context.Context, key string, expiry time.Duration) (string, error) {
+	if s.baseURL == "" || len(s.signingKey) == 0 {
+		return "", fmt.Errorf("signed URLs require a base URL and a signing key")
+	}
+
+	key, err := CleanKey(key)
+	if err != nil {
+		return "", err
+	}
+
+	expires := strconv.FormatInt(s.now().Add(expiry).Unix(), 10)
+	query := url.Values{}
+	query.Set("expires", expires)
+	query.Set("signature", s.sign(key, expires))
+
+	return s.baseURL + "/" + (&url.URL{Path: key}).EscapedPath() + "?" + query.Encode(), nil
+}
+
+// Handler serves objects for signed URLs. Mount it at the base URL, e.g.
+// http.Handle("/artifacts/", http.StripPrefix("/artifacts", store.Handler())).
+func (s *LocalStore) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		key, err := CleanKey(r.URL.Path)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+
+		expires := r.URL.Query().Get("expires")
+		signature := r.URL.Query().Get("signature")
+		expiresAt, err := strconv.ParseInt(expires, 10, 64)
+		if err != nil || len(s.signingKey) == 0 || !hmac.Equal([]byte(signature), []byte(s.sign(key, expires))) {
+			http.Error(w, "invalid signature", http.StatusForbidden)
+			return
+		}
+		if s.now().Unix() > expiresAt {
+			http.Error(w, "URL expired", http.StatusForbidden)
+			return
+		}
+
+		path, err := s.path(key)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		http.ServeFile(w, r, path)
+	})
+}
+
+// path returns the filesystem path for the key
+func (s *LocalStore) path(key string) (string, error) {
+	key, err := CleanKey(key)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(s.dir, filepath.FromSlash(key)), nil
+}
+
+// sign computes the URL signature for the key and expiry
+func (s *LocalStore) sign(key, expires string) string {
+	mac := hmac.New(sha256.New, s.signingKey)
+	mac.Write([]byte(key + "\n" + expires))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,212 @@
+package artifact
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/run-bigpig/llm-agent/pkg/multitenancy"
+)
+
+// URIScheme is the scheme of artifact references passed between steps
+const URIScheme = "artifact://"
+
+// Artifact describes a file produced by a tool or agent, such as a CSV, image
+// or PDF. Steps pass the artifact's URI instead of its content so binary data
+// never goes through prompts.
+type Artifact struct {
+	// ID uniquely identifies the artifact
+	ID string `json:"id"`
+
+	// Name is the file name, e.g. "report.csv"
+	Name string `json:"name"`
+
+	// MimeType is the media type of the content
+	MimeType string `json:"mime_type"`
+
+	// Size is the content size in bytes
+	Size int64 `json:"size"`
+
+	// Ref is the key of the content in the store
+	Ref string `json:"ref"`
+
+	// OrgID is the organization that owns the artifact
+	OrgID string `json:"org_id,omitempty"`
+
+	// CreatedAt is when the artifact was saved
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// URI returns the reference used to pass the artifact between steps
+func (a *Artifact) URI() string {
+	return URIScheme + a.ID
+}
+
+// String returns a short description of the artifact for tool results
+func (a *Artifact) String() string {
+	return fmt.Sprintf("%s (%s, %s, %d bytes)", a.URI(), a.Name, a.MimeType, a.Size)
+}
+
+// ParseURI returns the artifact ID from an artifact URI or a bare ID
+func ParseURI(ref string) (string, error) {
+	id := strings.TrimPrefix(strings.TrimSpace(ref), URIScheme)
+	if _, err := uuid.Parse(id); err != nil {
+		return "", fmt.Errorf("invalid artifact reference %q", ref)
+	}
+	return id, nil
+}
+
+// Manager saves and loads artifacts in a store. Artifacts are scoped to the
+// organization in the context.
+type Manager struct {
+	store Store
+}
+
+// NewManager creates a manager for artifacts in the store
+func NewManager(store Store) *Manager {
+	return &Manager{store: store}
+}
+
+// Save stores the content as a new artifact. When mimeType is empty, it is
+// detected from the content.
+func (m *Manager) Save(ctx context.Context, name, mimeType string, r io.Reader) (*Artifact, error) {
+	if mimeType == "" {
+		br := bufio.NewReader(r)
+		head, _ := br.Peek(512)
+		mimeType = http.DetectContentType(head)
+		r = br
+	}
+
+	orgID, _ := multitenancy.GetOrgID(ctx)
+	artifact := &Artifact{
+		ID:        uuid.New().String(),
+		Name:      path.Base("/" + strings.ReplaceAll(name, "\\", "/")),
+		MimeType:  mimeType,
+		OrgID:     orgID,
+		CreatedAt: time.Now(),
+	}
+	if artifact.Name == "/" {
+		artifact.Name = "artifact"
+	}
+	artifact.Ref = m.prefix(ctx, artifact.ID) + "/" + artifact.Name
+
+	counter := &countingReader{r: r}
+	if err := m.store.Put(ctx, artifact.Ref, counter, mimeType); err != nil {
+		return nil, fmt.Errorf("failed to save artifact: %w", err)
+	}
+	artifact.Size = counter.n
+
+	meta, err := json.Marshal(artifact)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal artifact: %w", err)
+	}
+	if err := m.store.Put(ctx, m.metaKey(ctx, artifact.ID), bytes.NewReader(meta), "application/json"); err != nil {
+		return nil, fmt.Errorf("failed to save artifact metadata: %w", err)
+	}
+
+	return artifact, nil
+}
+
+// Get returns the metadata of the artifact with the ID or URI
+func (m *Manager) Get(ctx context.Context, ref string) (*Artifact, error) {
+	id, err := ParseURI(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := m.store.Get(ctx, m.metaKey(ctx, id))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	var artifact Artifact
+	if err := json.NewDecoder(r).Decode(&artifact); err != nil {
+		return nil, fmt.Errorf("failed to parse artifact metadata: %w", err)
+	}
+	return &artifact, nil
+}
+
+// Open returns the metadata and content of the artifact with the ID or URI
+func (m *Manager) Open(ctx context.Context, ref string) (*Artifact, io.ReadCloser, error) {
+	artifact, err := m.Get(ctx, ref)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	r, err := m.store.Get(ctx, artifact.Ref)
+	if err != nil {
+		return nil, nil, err
+	}
+	return artifact, r, nil
+}
+
+// Delete removes the artifact with the ID or URI
+func (m *Manager) Delete(ctx context.Context, ref string) error {
+	artifact, err := m.Get(ctx, ref)
+	if err != nil {
+		return err
+	}
+
+	if err := m.store.Delete(ctx, artifact.Ref); err != nil && err != ErrNotFound {
+		return err
+	}
+	return m.store.Delete(ctx, m.metaKey(ctx, artifact.ID))
+}
+
+// SignedURL returns a download URL for the artifact with the ID or URI
+func (m *Manager) SignedURL(ctx context.Context, ref string, expiry time.Duration) (string, error) {
+	artifact, err := m.Get(ctx, ref)
+	if err != nil {
+		return "", err
+	}
+	return m.store.SignedURL(ctx, artifact.Ref, expiry)
+}
+
+// prefix returns the key prefix of the artifact, scoped to the organization
+func (m *Manager) prefix(ctx context.Context, id string) string {
+	if orgID, err := multitenancy.GetOrgID(ctx); err == nil && orgID != "" {
+		return orgID + "/artifacts/" + id
+	}
+	return "artifacts/" + id
+}
+
+// metaKey returns the key of the artifact metadata
+func (m *Manager) metaKey(ctx context.Context, id string) string {
+	return m.prefix(ctx, id) + ".json"
+}
+
+// countingReader counts the bytes read through it
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// managerKey is the context key for the artifact manager
+type managerKey struct{}
+
+// WithManager returns a context carrying the manager, which agents use to make
+// it available to their tools
+func WithManager(ctx context.Context, m *Manager) context.Context {
+	return context.WithValue(ctx, managerKey{}, m)
+}
+
+// ManagerFromContext returns the manager in the context, if any
+func ManagerFromContext(ctx context.Context) (*Manager, bool) {
+	m, ok := ctx.Value(managerKey{}).(*Manager)
+	return m, ok && m != nil
+}
@@ -0,0 +1,263 @@
+// Package bench measures the latency and throughput of LLM providers under
+// configurable workloads, to help choose between providers, models and
+// regions. The llmbench command runs it against configured providers.
+package bench
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+)
+
+// Target is an LLM to benchmark
+type Target struct {
+	// Name identifies the target in reports, e.g. "openai:gpt-4o-mini"
+	Name string
+
+	// LLM is the client to call
+	LLM interfaces.LLM
+}
+
+// Workload describes the requests sent to each target
+type Workload struct {
+	// PromptTokens is the approximate size of each prompt in tokens
+	PromptTokens int
+
+	// OutputWords is the approximate length of the requested answer in words
+	OutputWords int
+
+	// Concurrency is the number of requests in flight at once
+	Concurrency int
+
+	// Requests is the total number of requests
+	Requests int
+
+	// Timeout limits each request; zero means no limit
+	Timeout time.Duration
+}
+
+// Name describes the workload in reports
+func (w Workload) Name() string {
+	return fmt.Sprintf("prompt=%d concurrency=%d", w.PromptTokens, w.Concurrency)
+}
+
+// Result is the outcome of running a workload against a target
+type Result struct {
+	Target   string `json:"target"`
+	Workload string `json:"workload"`
+
+	PromptTokens int `json:"prompt_tokens"`
+	Concurrency  int `json:"concurrency"`
+
+	Requests  int     `json:"requests"`
+	Errors    int     `json:"errors"`
+	ErrorRate float64 `json:"error_rate"`
+
+	// Latencies of successful requests
+	P50  time.Duration `json:"p50"`
+	P95  time.Duration `json:"p95"`
+	P99  time.Duration `json:"p99"`
+	Mean time.Duration `json:"mean"`
+
+	// TokensPerSecond is the output tokens of successful requests per second
+	// of wall time, across all concurrent requests
+	TokensPerSecond float64 `json:"tokens_per_second"`
+
+	// RequestsPerSecond is the successful requests per second of wall time
+	RequestsPerSecond float64 `json:"requests_per_second"`
+
+	// Duration is the wall time of the workload
+	Duration time.Duration `json:"duration"`
+
+	// SampleError is one of the errors, to tell failures apart
+	SampleError string `json:"sample_error,omitempty"`
+}
+
+// Runner runs workloads against targets
+type Runner struct {
+	countTokens func(text string) int
+	progress    func(target Target, workload Workload, done int)
+}
+
+// Option configures a Runner
+type Option func(*Runner)
+
+// WithTokenCounter sets how output tokens are counted. Providers don't
+// report usage through interfaces.LLM, so by default a token is estimated as
+// four characters.
+func WithTokenCounter(counter func(text string) int) Option {
+	return func(r *Runner) {
+		r.countTokens = counter
+	}
+}
+
+// WithProgress is called after each finished request
+func WithProgress(progress func(target Target, workload Workload, done int)) Option {
+	return func(r *Runner) {
+		r.progress = progress
+	}
+}
+
+// NewRunner creates a runner
+func NewRunner(options ...Option) *Runner {
+	r := &Runner{
+		countTokens: func(text string) int { return (len(text) + 3) / 4 },
+	}
+	for _, option := range options {
+		option(r)
+	}
+	return r
+}
+
+// Run runs every workload against every target, one after the other, and
+// returns a result per pair in that order
+func (r *Runner) Run(ctx context.Context, targets []Target, workloads []Workload) ([]Result, error) {
+	results := make([]Result, 0, len(targets)*len(workloads))
+	for _, target := range targets {
+		for _, workload := range workloads {
+			if err := ctx.Err(); err != nil {
+				return results, err
+			}
+			results = append(results, r.RunWorkload(ctx, target, workload))
+		}
+	}
+	return results, nil
+}
+
+// sample is the outcome of one request
+type sample struct {
+	latency time.Duration
+	tokens  int
+	err     error
+}
+
+// RunWorkload runs a workload against a target
+func (r *Runner) RunWorkload(ctx context.Context, target Target, workload Workload) Result {
+	concurrency := max(workload.Concurrency, 1)
+	requests := max(workload.Requests, 1)
+	prompt := Prompt(workload.PromptTokens, workload.OutputWords)
+
+	jobs := make(chan int)
+	samples := make([]sample, requests)
+	var done int
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	startedAt := time.Now()
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				samples[i] = r.call(ctx, target.LLM, prompt, workload.Timeout)
+				if r.progress != nil {
+					mu.Lock()
+					done++
+					r.progress(target, workload, done)
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	for i := 0; i < requests; i++ {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	return summarize(target, workload, samples, time.Since(startedAt))
+}
+
+// call sends one request and measures it
+func (r *Runner) call(ctx context.Context, llm interfaces.LLM, prompt string, timeout time.Duration) sample {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	startedAt := time.Now()
+	response, err := llm.Generate(ctx, prompt)
+	latency := time.Since(startedAt)
+	if err != nil {
+		return sample{latency: latency, err: err}
+	}
+	return sample{latency: latency, tokens: r.countTokens(response)}
+}
+
+// summarize computes the result of a workload from its samples
+func summarize(target Target, workload Workload, samples []sample, wall time.Duration) Result {
+	result := Result{
+		Target:       target.Name,
+		Workload:     workload.Name(),
+		PromptTokens: workload.PromptTokens,
+		Concurrency:  max(workload.Concurrency, 1),
+		Requests:     len(samples),
+		Duration:     wall,
+	}
+
+	var latencies []time.Duration
+	var total time.Duration
+	tokens := 0
+	for _, s := range samples {
+		if s.err != nil {
+			result.Errors++
+			if result.SampleError == "" {
+				result.SampleError = s.err.Error()
+			}
+			continue
+		}
+		latencies = append(latencies, s.latency)
+		total += s.latency
+		tokens += s.tokens
+	}
+	result.ErrorRate = float64(result.Errors) / float64(len(samples))
+	if len(latencies) == 0 {
+		return result
+	}
+
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	result.P50 = Percentile(latencies, 50)
+	result.P95 = Percentile(latencies, 95)
+	result.P99 = Percentile(latencies, 99)
+	result.Mean = total / time.Duration(len(latencies))
+	if wall > 0 {
+		result.TokensPerSecond = float64(tokens) / wall.Seconds()
+		result.RequestsPerSecond = float64(len(latencies)) / wall.Seconds()
+	}
+	return result
+}
+
+// Percentile returns the p-th percentile of sorted durations, using the
+// nearest-rank method
+func Percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	return sorted[min(max(rank, 1), len(sorted))-1]
+}
+
+// filler is repeated to pad prompts to the requested size
+const filler = "The quarterly report covers revenue, costs, hiring and the product roadmap across all regions. "
+
+// Prompt builds a prompt of about promptTokens tokens that asks for an answer
+// of about outputWords words
+func Prompt(promptTokens, outputWords int) string {
+	if outputWords <= 0 {
+		outputWords = 100
+	}
+	instruction := fmt.Sprintf("Summarize the following text in about %d words.\n\n", outputWords)
+
+	var sb strings.Builder
+	sb.WriteString(instruction)
+	for sb.Len() < promptTokens*4 {
+		sb.WriteString(filler)
+	}
+	return sb.String()
+}
@@ -0,0 +1,137 @@
+package bench_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/run-bigpig/llm-agent/pkg/bench"
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeLLM answers after a delay and fails every failEvery-th call
+type fakeLLM struct {
+	delay     time.Duration
+	failEvery int64
+	calls     atomic.Int64
+	inFlight  atomic.Int64
+	peak      atomic.Int64
+}
+
+func (m *fakeLLM) Generate(ctx context.Context, prompt string, options ...interfaces.GenerateOption) (string, error) {
+	n := m.inFlight.Add(1)
+	defer m.inFlight.Add(-1)
+	for {
+		peak := m.peak.Load()
+		if n <= peak || m.peak.CompareAndSwap(peak, n) {
+			break
+		}
+	}
+
+	call := m.calls.Add(1)
+	select {
+	case <-time.After(m.delay):
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+	if m.failEvery > 0 && call%m.failEvery == 0 {
+		return "", errors.New("rate limited")
+	}
+	return strings.Repeat("abcd", 10), nil
+}
+
+func (m *fakeLLM) GenerateWithTools(ctx context.Context, prompt string, tools []interfaces.Tool, options ...interfaces.GenerateOption) (string, error) {
+	return m.Generate(ctx, prompt, options...)
+}
+
+func (m *fakeLLM) Name() string { return "fake" }
+
+func TestRunWorkload(t *testing.T) {
+	llm := &fakeLLM{delay: 10 * time.Millisecond, failEvery: 4}
+	runner := bench.NewRunner()
+
+	result := runner.RunWorkload(context.Background(), bench.Target{Name: "fake", LLM: llm}, bench.Workload{
+		PromptTokens: 100,
+		Concurrency:  4,
+		Requests:     20,
+	})
+
+	assert.Equal(t, "fake", result.Target)
+	assert.Equal(t, "prompt=100 concurrency=4", result.Workload)
+	assert.Equal(t, int64(20), llm.calls.Load())
+	assert.Equal(t, int64(4), llm.peak.Load())
+	assert.Equal(t, 20, result.Requests)
+	assert.Equal(t, 5, result.Errors)
+	assert.InDelta(t, 0.25, result.ErrorRate, 0.001)
+	assert.Equal(t, "rate limited", result.SampleError)
+	assert.GreaterOrEqual(t, result.P50, 10*time.Millisecond)
+	assert.GreaterOrEqual(t, result.P95, result.P50)
+	// 15 successful answers of 10 tokens each
+	assert.InDelta(t, 150/result.Duration.Seconds(), result.TokensPerSecond, 0.001)
+}
+
+func TestRunWorkloadTimeout(t *testing.T) {
+	llm := &fakeLLM{delay: time.Second}
+	runner := bench.NewRunner()
+
+	result := runner.RunWorkload(context.Background(), bench.Target{Name: "slow", LLM: llm}, bench.Workload{
+		Concurrency: 2,
+		Requests:    2,
+		Timeout:     10 * time.Millisecond,
+	})
+
+	assert.Equal(t, 2, result.Errors)
+	assert.Equal(t, 1.0, result.ErrorRate)
+	assert.Zero(t, result.P50)
+	assert.Contains(t, result.SampleError, "deadline exceeded")
+}
+
+func TestRunReportsEveryPair(t *testing.T) {
+	var done atomic.Int64
+	runner := bench.NewRunner(
+		bench.WithTokenCounter(func(text string) int { return 1 }),
+		bench.WithProgress(func(target bench.Target, workload bench.Workload, n int) { done.Add(1) }),
+	)
+	targets := []bench.Target{{Name: "a", LLM: &fakeLLM{}}, {Name: "b", LLM: &fakeLLM{}}}
+	workloads := []bench.Workload{{PromptTokens: 10, Requests: 2}, {PromptTokens: 50, Requests: 3}}
+
+	results, err := runner.Run(context.Background(), targets, workloads)
+	require.NoError(t, err)
+	require.Len(t, results, 4)
+	assert.Equal(t, "a", results[0].Target)
+	assert.Equal(t, 50, results[1].PromptTokens)
+	assert.Equal(t, "b", results[2].Target)
+	assert.Equal(t, int64(10), done.Load())
+
+	var table bytes.Buffer
+	require.NoError(t, bench.WriteTable(&table, results))
+	assert.Contains(t, table.String(), "p95")
+	assert.Equal(t, 5, strings.Count(table.String(), "\n"))
+}
+
+func TestPercentile(t *testing.T) {
+	latencies := make([]time.Duration, 100)
+	for i := range latencies {
+		latencies[i] = time.Duration(i+1) * time.Millisecond
+	}
+
+	assert.Equal(t, 50*time.Millisecond, bench.Percentile(latencies, 50))
+	assert.Equal(t, 95*time.Millisecond, bench.Percentile(latencies, 95))
+	assert.Equal(t, 100*time.Millisecond, bench.Percentile(latencies, 100))
+	assert.Equal(t, time.Millisecond, bench.Percentile(latencies, 0))
+	assert.Zero(t, bench.Percentile(nil, 50))
+}
+
+func TestPrompt(t *testing.T) {
+	prompt := bench.Prompt(1000, 50)
+
+	assert.True(t, strings.HasPrefix(prompt, "Summarize the following text in about 50 words."))
+	assert.GreaterOrEqual(t, len(prompt), 4000)
+	assert.Less(t, len(prompt), 4200)
+}
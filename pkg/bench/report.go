@@ -0,0 +1,49 @@
+package bench
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+)
+
+// WriteTable writes results as an aligned table
+func WriteTable(w io.Writer, results []Result) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "target\tprompt\tconc\treqs\terrors\tp50\tp95\tp99\ttok/s\treq/s\t")
+	for _, r := range results {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%.1f%%\t%s\t%s\t%s\t%.1f\t%.2f\t\n",
+			r.Target, r.PromptTokens, r.Concurrency, r.Requests, r.ErrorRate*100,
+			formatLatency(r.P50), formatLatency(r.P95), formatLatency(r.P99),
+			r.TokensPerSecond, r.RequestsPerSecond)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	for _, r := range results {
+		if r.SampleError != "" {
+			if _, err := fmt.Fprintf(w, "\n%s (%s): %d errors, e.g. %s\n", r.Target, r.Workload, r.Errors, r.SampleError); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// WriteJSON writes results as indented JSON, with latencies in nanoseconds
+func WriteJSON(w io.Writer, results []Result) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(results)
+}
+
+// formatLatency formats a latency with millisecond precision, or "-" if no
+// request succeeded
+func formatLatency(d time.Duration) string {
+	if d == 0 {
+		return "-"
+	}
+	return d.Round(time.Millisecond).String()
+}
@@ -0,0 +1,363 @@
+package circuitbreaker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrOpen is returned when a call is rejected because the circuit is open
+var ErrOpen = errors.New("circuit breaker is open")
+
+// errPanicked is recorded for calls that panic
+var errPanicked = errors.New("call panicked")
+
+// State is the state of a circuit breaker
+type State string
+
+const (
+	// StateClosed lets all calls through
+	StateClosed State = "closed"
+
+	// StateOpen rejects all calls until the open timeout expires
+	StateOpen State = "open"
+
+	// StateHalfOpen lets a limited number of probe calls through
+	StateHalfOpen State = "half_open"
+)
+
+// Stats is a snapshot of a circuit breaker's state and counters
+type Stats struct {
+	Name                string    `json:"name"`
+	State               State     `json:"state"`
+	ConsecutiveFailures int       `json:"consecutive_failures"`
+	Requests            int64     `json:"requests"`
+	Successes           int64     `json:"successes"`
+	Failures            int64     `json:"failures"`
+	Rejected            int64     `json:"rejected"`
+	OpenedAt            time.Time `json:"opened_at,omitempty"`
+}
+
+// StateChangeFunc is called when a circuit breaker changes state. It is
+// called without the breaker's lock held, so it may call State or Stats.
+type StateChangeFunc func(name string, from, to State)
+
+// stateChange is a state change waiting to be reported to onStateChange
+type stateChange struct {
+	from, to State
+}
+
+// Breaker is a circuit breaker that opens after a number of consecutive
+// failures, fails fast while open, and probes with a limited number of calls
+// once the open timeout expires
+type Breaker struct {
+	name             string
+	failureThreshold int
+	successThreshold int
+	openTimeout      time.Duration
+	halfOpenMaxCalls int
+	isFailure        func(error) bool
+	onStateChange    StateChangeFunc
+	now              func() time.Time
+
+	mu                   sync.Mutex
+	state                State
+	generation           uint64 // incremented on every state change
+	changes              []stateChange
+	consecutiveFailures  int
+	consecutiveSuccesses int
+	halfOpenInFlight     int
+	openedAt             time.Time
+	requests             int64
+	successes            int64
+	failures             int64
+	rejected             int64
+}
+
+// Option represents an option for configuring a circuit breaker
+type Option func(*Breaker)
+
+// WithFailureThreshold sets the number of consecutive failures that open the circuit
+func WithFailureThreshold(threshold int) Option {
+	return func(b *Breaker) {
+		b.failureThreshold = threshold
+	}
+}
+
+// WithSuccessThreshold sets the number of successful probes needed to close the circuit
+func WithSuccessThreshold(threshold int) Option {
+	return func(b *Breaker) {
+		b.successThreshold = threshold
+	}
+}
+
+// WithOpenTimeout sets how long the circuit stays open before probing
+func WithOpenTimeout(timeout time.Duration) Option {
+	return func(b *Breaker) {
+		b.openTimeout = timeout
+	}
+}
+
+// WithHalfOpenMaxCalls sets the number of concurrent probe calls allowed while half-open
+func WithHalfOpenMaxCalls(calls int) Option {
+	return func(b *Breaker) {
+		b.halfOpenMaxCalls = calls
+	}
+}
+
+// WithIsFailure sets the function that decides whether an error counts as a
+// failure. By default every error except context cancellation counts.
+func WithIsFailure(isFailure func(error) bool) Option {
+	return func(b *Breaker) {
+		b.isFailure = isFailure
+	}
+}
+
+// WithOnStateChange sets a callback invoked on every state change
+func WithOnStateChange(fn StateChangeFunc) Option {
+	return func(b *Breaker) {
+		b.onStateChange = fn
+	}
+}
+
+// New creates a new circuit breaker
+func New(name string, options ...Option) *Breaker {
+	b := &Breaker{
+		name:             name,
+		failureThreshold: 5,
+		successThreshold: 1,
+		openTimeout:      30 * time.Second,
+		halfOpenMaxCalls: 1,
+		isFailure:        defaultIsFailure,
+		now:              time.Now,
+		state:            StateClosed,
+	}
+
+	for _, option := range options {
+		option(b)
+	}
+
+	return b
+}
+
+// defaultIsFailure counts every error except context cancellation
+func defaultIsFailure(err error) bool {
+	return !errors.Is(err, context.Canceled)
+}
+
+// Name returns the name of the circuit breaker
+func (b *Breaker) Name() string {
+	return b.name
+}
+
+// State returns the current state of the circuit breaker
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.unlock()
+	return b.currentState()
+}
+
+// Stats returns a snapshot of the circuit breaker's state and counters
+func (b *Breaker) Stats() Stats {
+	b.mu.Lock()
+	defer b.unlock()
+
+	return Stats{
+		Name:                b.name,
+		State:               b.currentState(),
+		ConsecutiveFailures: b.consecutiveFailures,
+		Requests:            b.requests,
+		Successes:           b.successes,
+		Failures:            b.failures,
+		Rejected:            b.rejected,
+		OpenedAt:            b.openedAt,
+	}
+}
+
+// Reset closes the circuit and clears the consecutive failure count
+func (b *Breaker) Reset() {
+	b.mu.Lock()
+	defer b.unlock()
+	b.setState(StateClosed)
+}
+
+// Execute calls fn if the circuit allows it and records the result. It
+// returns an error wrapping ErrOpen without calling fn if the circuit is open.
+// A panic in fn is recorded as a failure before it propagates.
+func (b *Breaker) Execute(ctx context.Context, fn func(ctx context.Context) error) error {
+	generation, err := b.allow()
+	if err != nil {
+		return err
+	}
+
+	err = errPanicked
+	defer func() {
+		b.record(generation, err)
+	}()
+
+	err = fn(ctx)
+	return err
+}
+
+// allow reports whether a call may proceed and reserves a probe slot when
+// half-open. It returns the generation of the state the call was admitted in.
+func (b *Breaker) allow() (uint64, error) {
+	b.mu.Lock()
+	defer b.unlock()
+
+	switch b.currentState() {
+	case StateOpen:
+		b.rejected++
+		return 0, fmt.Errorf("%s: %w", b.name, ErrOpen)
+	case StateHalfOpen:
+		if b.halfOpenInFlight >= b.halfOpenMaxCalls {
+			b.rejected++
+			return 0, fmt.Errorf("%s: %w", b.name, ErrOpen)
+		}
+		b.halfOpenInFlight++
+	}
+
+	b.requests++
+	return b.generation, nil
+}
+
+// record updates the circuit with the result of a call admitted in the given
+// generation. Calls that finish after the state has changed since they were
+// admitted are counted, but don't change the state: a slow call admitted
+// while closed must not close a circuit that has since opened.
+func (b *Breaker) record(generation uint64, err error) {
+	b.mu.Lock()
+	defer b.unlock()
+
+	failed := err != nil && b.isFailure(err)
+	if failed {
+		b.failures++
+	} else {
+		b.successes++
+	}
+
+	state := b.currentState()
+	if generation != b.generation {
+		return
+	}
+	if state == StateHalfOpen && b.halfOpenInFlight > 0 {
+		b.halfOpenInFlight--
+	}
+
+	if failed {
+		b.consecutiveFailures++
+		b.consecutiveSuccesses = 0
+
+		if state == StateHalfOpen || b.consecutiveFailures >= b.failureThreshold {
+			b.setState(StateOpen)
+		}
+		return
+	}
+
+	b.consecutiveFailures = 0
+	if state == StateHalfOpen {
+		b.consecutiveSuccesses++
+		if b.consecutiveSuccesses >= b.successThreshold {
+			b.setState(StateClosed)
+		}
+	}
+}
+
+// currentState returns the state, moving from open to half-open once the open
+// timeout has expired. It must be called with the lock held.
+func (b *Breaker) currentState() State {
+	if b.state == StateOpen && b.now().Sub(b.openedAt) >= b.openTimeout {
+		b.setState(StateHalfOpen)
+	}
+	return b.state
+}
+
+// setState changes the state and resets the per-state counters. It must be
+// called with the lock held; the change is reported by unlock.
+func (b *Breaker) setState(state State) {
+	if b.state == state {
+		return
+	}
+
+	from := b.state
+	b.state = state
+	b.generation++
+	b.consecutiveSuccesses = 0
+	b.halfOpenInFlight = 0
+
+	switch state {
+	case StateOpen:
+		b.openedAt = b.now()
+	case StateClosed:
+		b.consecutiveFailures = 0
+		b.openedAt = time.Time{}
+	}
+
+	if b.onStateChange != nil {
+		b.changes = append(b.changes, stateChange{from: from, to: state})
+	}
+}
+
+// unlock releases the lock and then reports the state changes made while it
+// was held, so that the callback can call back into the breaker
+func (b *Breaker) unlock() {
+	changes := b.changes
+	b.changes = nil
+	b.mu.Unlock()
+
+	for _, change := range changes {
+		b.onStateChange(b.name, change.from, change.to)
+	}
+}
+
+// Registry holds one circuit breaker per name, e.g. per provider or tool
+type Registry struct {
+	options []Option
+
+	mu       sync.Mutex
+	breakers map[string]*Breaker
+}
+
+// NewRegistry creates a registry whose breakers are created with the given options
+func NewRegistry(options ...Option) *Registry {
+	return &Registry{
+		options:  options,
+		breakers: make(map[string]*Breaker),
+	}
+}
+
+// Get returns the breaker for the name, creating it if needed
+func (r *Registry) Get(name string) *Breaker {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	b, ok := r.breakers[name]
+	if !ok {
+		b = New(name, r.options...)
+		r.breakers[name] = b
+	}
+	return b
+}
+
+// Stats returns a snapshot of every breaker in the registry, sorted by name,
+// for exporting as metrics
+func (r *Registry) Stats() []Stats {
+	r.mu.Lock()
+	breakers := make([]*Breaker, 0, len(r.breakers))
+	for _, b := range r.breakers {
+		breakers = append(breakers, b)
+	}
+	r.mu.Unlock()
+
+	stats := make([]Stats, 0, len(breakers))
+	for _, b := range breakers {
+		stats = append(stats, b.Stats())
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].Name < stats[j].Name
+	})
+	return stats
+}
@@ -0,0 +1,194 @@
+package circuitbreaker
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestBreakerTransitions(t *testing.T) {
+	now := time.Now()
+	var transitions []State
+	b := New("openai",
+		WithFailureThreshold(2),
+		WithOpenTimeout(time.Minute),
+		WithOnStateChange(func(name string, from, to State) {
+			transitions = append(transitions, to)
+		}),
+	)
+	b.now = func() time.Time { return now }
+
+	fail := func(ctx context.Context) error { return errors.New("503") }
+	succeed := func(ctx context.Context) error { return nil }
+	ctx := context.Background()
+
+	_ = b.Execute(ctx, fail)
+	if b.State() != StateClosed {
+		t.Fatalf("Expected closed after 1 failure, got %s", b.State())
+	}
+	_ = b.Execute(ctx, fail)
+	if b.State() != StateOpen {
+		t.Fatalf("Expected open after 2 failures, got %s", b.State())
+	}
+
+	called := false
+	err := b.Execute(ctx, func(ctx context.Context) error { called = true; return nil })
+	if !errors.Is(err, ErrOpen) || called {
+		t.Fatalf("Expected call to be rejected while open, got err=%v called=%v", err, called)
+	}
+
+	// After the timeout a failed probe reopens the circuit
+	now = now.Add(time.Minute)
+	if b.State() != StateHalfOpen {
+		t.Fatalf("Expected half-open after timeout, got %s", b.State())
+	}
+	_ = b.Execute(ctx, fail)
+	if b.State() != StateOpen {
+		t.Fatalf("Expected open after failed probe, got %s", b.State())
+	}
+
+	// A successful probe closes it
+	now = now.Add(time.Minute)
+	if err := b.Execute(ctx, succeed); err != nil {
+		t.Fatalf("Expected probe to run, got %v", err)
+	}
+	if b.State() != StateClosed {
+		t.Fatalf("Expected closed after successful probe, got %s", b.State())
+	}
+
+	expected := []State{StateOpen, StateHalfOpen, StateOpen, StateHalfOpen, StateClosed}
+	if len(transitions) != len(expected) {
+		t.Fatalf("Expected transitions %v, got %v", expected, transitions)
+	}
+	for i := range expected {
+		if transitions[i] != expected[i] {
+			t.Errorf("Expected transitions %v, got %v", expected, transitions)
+			break
+		}
+	}
+
+	stats := b.Stats()
+	if stats.Rejected != 1 || stats.Failures != 3 || stats.Successes != 1 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+}
+
+func TestBreakerIgnoresCancellation(t *testing.T) {
+	b := New("tool", WithFailureThreshold(1))
+	_ = b.Execute(context.Background(), func(ctx context.Context) error { return context.Canceled })
+	if b.State() != StateClosed {
+		t.Errorf("Expected cancellation not to count as a failure, got %s", b.State())
+	}
+}
+
+func TestBreakerIgnoresStaleResults(t *testing.T) {
+	now := time.Now()
+	b := New("openai", WithFailureThreshold(1), WithOpenTimeout(time.Minute))
+	b.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	// A slow call is admitted while closed
+	started := make(chan struct{})
+	release := make(chan struct{})
+	slow := make(chan error, 1)
+	go func() {
+		slow <- b.Execute(ctx, func(ctx context.Context) error {
+			close(started)
+			<-release
+			return nil
+		})
+	}()
+	<-started
+
+	// The circuit opens and moves to half-open, and a probe is in flight
+	_ = b.Execute(ctx, func(ctx context.Context) error { return errors.New("503") })
+	now = now.Add(time.Minute)
+	probeStarted := make(chan struct{})
+	probeRelease := make(chan struct{})
+	probe := make(chan error, 1)
+	go func() {
+		probe <- b.Execute(ctx, func(ctx context.Context) error {
+			close(probeStarted)
+			<-probeRelease
+			return errors.New("503")
+		})
+	}()
+	<-probeStarted
+
+	// The slow success must neither close the circuit nor free the probe slot
+	close(release)
+	<-slow
+	if b.State() != StateHalfOpen {
+		t.Fatalf("Expected the stale result to be ignored, got %s", b.State())
+	}
+	if err := b.Execute(ctx, func(ctx context.Context) error { return nil }); !errors.Is(err, ErrOpen) {
+		t.Errorf("Expected the probe slot to stay taken, got %v", err)
+	}
+
+	close(probeRelease)
+	<-probe
+	if b.State() != StateOpen {
+		t.Errorf("Expected the failed probe to reopen the circuit, got %s", b.State())
+	}
+	if stats := b.Stats(); stats.Successes != 1 || stats.Failures != 2 {
+		t.Errorf("Expected stale results to be counted, got %+v", stats)
+	}
+}
+
+func TestBreakerRecordsPanics(t *testing.T) {
+	now := time.Now()
+	b := New("tool", WithFailureThreshold(1), WithOpenTimeout(time.Minute))
+	b.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	_ = b.Execute(ctx, func(ctx context.Context) error { return errors.New("503") })
+	now = now.Add(time.Minute)
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("Expected the panic to propagate")
+			}
+		}()
+		_ = b.Execute(ctx, func(ctx context.Context) error { panic("boom") })
+	}()
+
+	// The panicking probe counts as a failure and releases its slot
+	if b.State() != StateOpen {
+		t.Fatalf("Expected the panic to reopen the circuit, got %s", b.State())
+	}
+	now = now.Add(time.Minute)
+	if err := b.Execute(ctx, func(ctx context.Context) error { return nil }); err != nil {
+		t.Errorf("Expected a new probe to be allowed, got %v", err)
+	}
+}
+
+func TestBreakerStateChangeCallback(t *testing.T) {
+	var b *Breaker
+	var states []State
+	b = New("tool",
+		WithFailureThreshold(1),
+		WithOnStateChange(func(name string, from, to State) {
+			// Reading the breaker from the callback must not deadlock
+			states = append(states, b.State())
+			_ = b.Stats()
+		}),
+	)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = b.Execute(context.Background(), func(ctx context.Context) error { return errors.New("503") })
+		b.Reset()
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Expected the callback not to deadlock")
+	}
+	if len(states) != 2 || states[0] != StateOpen || states[1] != StateClosed {
+		t.Errorf("Unexpected states seen by the callback: %v", states)
+	}
+}
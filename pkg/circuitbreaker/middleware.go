@@ -0,0 +1,196 @@
+package circuitbreaker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+)
+
+// LLM wraps an LLM with a circuit breaker
+type LLM struct {
+	llm     interfaces.LLM
+	breaker *Breaker
+}
+
+// NewLLM wraps the LLM so that calls fail fast while the breaker is open
+func NewLLM(llm interfaces.LLM, breaker *Breaker) *LLM {
+	return &LLM{
+		llm:     llm,
+		breaker: breaker,
+	}
+}
+
+// Generate generates text based on the provided prompt
+func (l *LLM) Generate(ctx context.Context, prompt string, options ...interfaces.GenerateOption) (string, error) {
+	var response string
+	err := l.breaker.Execute(ctx, func(ctx context.Context) error {
+		var err error
+		response, err = l.llm.Generate(ctx, prompt, options...)
+		return err
+	})
+	return response, err
+}
+
+// GenerateWithTools generates text and can use tools
+func (l *LLM) GenerateWithTools(ctx context.Context, prompt string, tools []interfaces.Tool, options ...interfaces.GenerateOption) (string, error) {
+	var response string
+	err := l.breaker.Execute(ctx, func(ctx context.Context) error {
+		var err error
+		response, err = l.llm.GenerateWithTools(ctx, prompt, tools, options...)
+		return err
+	})
+	return response, err
+}
+
+// Name returns the name of the LLM provider
+func (l *LLM) Name() string {
+	return l.llm.Name()
+}
+
+// Unwrap returns the wrapped LLM
+func (l *LLM) Unwrap() interfaces.LLM {
+	return l.llm
+}
+
+// Breaker returns the circuit breaker
+func (l *LLM) Breaker() *Breaker {
+	return l.breaker
+}
+
+// Tool wraps a tool with a circuit breaker
+type Tool struct {
+	tool    interfaces.Tool
+	breaker *Breaker
+}
+
+// NewTool wraps the tool so that calls fail fast while the breaker is open
+func NewTool(tool interfaces.Tool, breaker *Breaker) *Tool {
+	return &Tool{
+		tool:    tool,
+		breaker: breaker,
+	}
+}
+
+// Name returns the name of the tool
+func (t *Tool) Name() string {
+	return t.tool.Name()
+}
+
+// Description returns a description of what the tool does
+func (t *Tool) Description() string {
+	return t.tool.Description()
+}
+
+// Parameters returns the parameters that the tool accepts
+func (t *Tool) Parameters() map[string]interfaces.ParameterSpec {
+	return t.tool.Parameters()
+}
+
+// Run executes the tool with the given input
+func (t *Tool) Run(ctx context.Context, input string) (string, error) {
+	var output string
+	err := t.breaker.Execute(ctx, func(ctx context.Context) error {
+		var err error
+		output, err = t.tool.Run(ctx, input)
+		return err
+	})
+	return output, err
+}
+
+// Execute executes the tool with the given arguments
+func (t *Tool) Execute(ctx context.Context, args string) (string, error) {
+	var output string
+	err := t.breaker.Execute(ctx, func(ctx context.Context) error {
+		var err error
+		output, err = t.tool.Execute(ctx, args)
+		return err
+	})
+	return output, err
+}
+
+// FallbackLLM tries a chain of LLMs in order, moving to the next one when a
+// call fails. Combined with NewLLM, a provider whose circuit is open is
+// skipped immediately instead of waiting for its timeout.
+type FallbackLLM struct {
+	llms []interfaces.LLM
+}
+
+// NewFallbackLLM creates an LLM that falls back through the given LLMs in order
+func NewFallbackLLM(llms ...interfaces.LLM) *FallbackLLM {
+	return &FallbackLLM{llms: llms}
+}
+
+// Generate generates text with the first LLM that succeeds
+func (f *FallbackLLM) Generate(ctx context.Context, prompt string, options ...interfaces.GenerateOption) (string, error) {
+	return f.try(ctx, func(llm interfaces.LLM) (string, error) {
+		return llm.Generate(ctx, prompt, options...)
+	})
+}
+
+// GenerateWithTools generates text with the first LLM that succeeds
+func (f *FallbackLLM) GenerateWithTools(ctx context.Context, prompt string, tools []interfaces.Tool, options ...interfaces.GenerateOption) (string, error) {
+	return f.try(ctx, func(llm interfaces.LLM) (string, error) {
+		return llm.GenerateWithTools(ctx, prompt, tools, options...)
+	})
+}
+
+// Name returns the name of the primary LLM provider
+func (f *FallbackLLM) Name() string {
+	if len(f.llms) == 0 {
+		return "fallback"
+	}
+	return f.llms[0].Name()
+}
+
+// Capabilities returns the capabilities supported by every LLM that reports
+// them, so that a fallback never lacks a feature the agent relies on. If none
+// report them, every capability is assumed.
+func (f *FallbackLLM) Capabilities() interfaces.Capabilities {
+	var caps interfaces.Capabilities
+	first := true
+	for _, llm := range f.llms {
+		c, ok := interfaces.GetCapabilities(llm)
+		if !ok {
+			continue
+		}
+		if first {
+			caps, first = c, false
+			continue
+		}
+		caps.Tools = caps.Tools && c.Tools
+		caps.Vision = caps.Vision && c.Vision
+		caps.JSONMode = caps.JSONMode && c.JSONMode
+		caps.Streaming = caps.Streaming && c.Streaming
+		caps.ParallelTools = caps.ParallelTools && c.ParallelTools
+		caps.AssistantPrefill = caps.AssistantPrefill && c.AssistantPrefill
+	}
+
+	if first {
+		return interfaces.Capabilities{Tools: true, Vision: true, JSONMode: true, Streaming: true, ParallelTools: true, AssistantPrefill: true}
+	}
+	return caps
+}
+
+// try calls fn with each LLM until one succeeds
+func (f *FallbackLLM) try(ctx context.Context, fn func(llm interfaces.LLM) (string, error)) (string, error) {
+	if len(f.llms) == 0 {
+		return "", fmt.Errorf("no LLMs configured")
+	}
+
+	var errs []error
+	for _, llm := range f.llms {
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
+
+		response, err := fn(llm)
+		if err == nil {
+			return response, nil
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", llm.Name(), err))
+	}
+
+	return "", fmt.Errorf("all LLMs failed: %w", errors.Join(errs...))
+}
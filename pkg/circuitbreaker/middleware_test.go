@@ -0,0 +1,46 @@
+package circuitbreaker
+
+import (
+	"testing"
+
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+)
+
+// capableLLM reports fixed capabilities
+type capableLLM struct {
+	interfaces.LLM
+	caps interfaces.Capabilities
+}
+
+func (l capableLLM) Capabilities() interfaces.Capabilities { return l.caps }
+
+// plainLLM does not report capabilities
+type plainLLM struct {
+	interfaces.LLM
+}
+
+func TestLLMCapabilities(t *testing.T) {
+	caps := interfaces.Capabilities{Tools: true, JSONMode: true}
+	llm := NewLLM(capableLLM{caps: caps}, New("openai"))
+
+	if got, ok := interfaces.GetCapabilities(llm); !ok || got != caps {
+		t.Errorf("Expected the capabilities of the wrapped LLM, got %+v, %v", got, ok)
+	}
+}
+
+func TestFallbackLLMCapabilities(t *testing.T) {
+	primary := capableLLM{caps: interfaces.Capabilities{Tools: true, Vision: true, JSONMode: true, ParallelTools: true, AssistantPrefill: true}}
+	secondary := capableLLM{caps: interfaces.Capabilities{Tools: true, JSONMode: true, Streaming: true}}
+
+	// Only capabilities every reporting LLM supports are reported
+	fallback := NewFallbackLLM(NewLLM(primary, New("primary")), plainLLM{}, secondary)
+	expected := interfaces.Capabilities{Tools: true, JSONMode: true}
+	if got := fallback.Capabilities(); got != expected {
+		t.Errorf("Expected %+v, got %+v", expected, got)
+	}
+
+	all := interfaces.Capabilities{Tools: true, Vision: true, JSONMode: true, Streaming: true, ParallelTools: true, AssistantPrefill: true}
+	if got := NewFallbackLLM(plainLLM{}).Capabilities(); got != all {
+		t.Errorf("Expected every capability when no LLM reports them, got %+v", got)
+	}
+}
@@ -0,0 +1,125 @@
+// Package classification classifies user input by intent, topic and
+// sentiment, so that routing logic and analytics can act on what users ask
+// for. RuleClassifier is a lightweight keyword classifier that needs no model;
+// LLMClassifier asks an LLM.
+package classification
+
+import (
+	"context"
+)
+
+// Sentiments of a classified text
+const (
+	SentimentPositive = "positive"
+	SentimentNeutral  = "neutral"
+	SentimentNegative = "negative"
+)
+
+// MetadataKey is the message metadata key the agent stores a classification
+// under
+const MetadataKey = "classification"
+
+// Classification is the result of classifying a text. Fields are empty when
+// the classifier could not tell.
+type Classification struct {
+	// Intent is what the user wants, e.g. "question" or "refund_request"
+	Intent string `json:"intent,omitempty"`
+
+	// Topic is what the text is about, e.g. "billing"
+	Topic string `json:"topic,omitempty"`
+
+	// Sentiment is SentimentPositive, SentimentNeutral or SentimentNegative
+	Sentiment string `json:"sentiment,omitempty"`
+
+	// Confidence is how sure the classifier is about the intent, from 0 to 1
+	Confidence float64 `json:"confidence,omitempty"`
+
+	// Labels holds additional labels from custom classifiers
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// Metadata returns the classification as message metadata
+func (c *Classification) Metadata() map[string]interface{} {
+	metadata := map[string]interface{}{
+		"intent":     c.Intent,
+		"topic":      c.Topic,
+		"sentiment":  c.Sentiment,
+		"confidence": c.Confidence,
+	}
+	if len(c.Labels) > 0 {
+		labels := make(map[string]string, len(c.Labels))
+		for k, v := range c.Labels {
+			labels[k] = v
+		}
+		metadata["labels"] = labels
+	}
+	return metadata
+}
+
+// FromMetadata reads a classification from message metadata, as stored by
+// the agent under MetadataKey
+func FromMetadata(metadata map[string]interface{}) (*Classification, bool) {
+	switch value := metadata[MetadataKey].(type) {
+	case *Classification:
+		return value, value != nil
+	case Classification:
+		return &value, true
+	case map[string]interface{}:
+		c := &Classification{}
+		c.Intent, _ = value["intent"].(string)
+		c.Topic, _ = value["topic"].(string)
+		c.Sentiment, _ = value["sentiment"].(string)
+		c.Confidence, _ = value["confidence"].(float64)
+		switch labels := value["labels"].(type) {
+		case map[string]string:
+			c.Labels = labels
+		case map[string]interface{}:
+			c.Labels = make(map[string]string, len(labels))
+			for k, v := range labels {
+				if s, ok := v.(string); ok {
+					c.Labels[k] = s
+				}
+			}
+		}
+		return c, true
+	default:
+		return nil, false
+	}
+}
+
+// Classifier classifies text
+type Classifier interface {
+	// Classify classifies a text
+	Classify(ctx context.Context, text string) (*Classification, error)
+}
+
+// Sink receives the classification of every classified input, e.g. to count
+// intents for analytics
+type Sink interface {
+	// Record records the classification of an input
+	Record(ctx context.Context, input string, classification *Classification) error
+}
+
+// SinkFunc adapts a function to a Sink
+type SinkFunc func(ctx context.Context, input string, classification *Classification) error
+
+// Record calls the function
+func (f SinkFunc) Record(ctx context.Context, input string, classification *Classification) error {
+	return f(ctx, input, classification)
+}
+
+// contextKey is the key of the classification in a context
+type contextKey struct{}
+
+// WithClassification returns a context carrying the classification of the
+// current input
+func WithClassification(ctx context.Context, classification *Classification) context.Context {
+	return context.WithValue(ctx, contextKey{}, classification)
+}
+
+// FromContext returns the classification of the current input, if the input
+// was classified
+func FromContext(ctx context.Context) (*Classification, bool) {
+	classification, ok := ctx.Value(contextKey{}).(*Classification)
+	return classification, ok && classification != nil
+}
@@ -0,0 +1,84 @@
+package classification_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/run-bigpig/llm-agent/pkg/classification"
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+)
+
+func TestRuleClassifier(t *testing.T) {
+	classifier := classification.NewRuleClassifier(
+		classification.WithIntentKeywords("refund_request", "refund", "money back"),
+		classification.WithTopicKeywords("billing", "invoice", "charge", "refund"),
+		classification.WithTopicKeywords("shipping", "delivery", "package", "tracking"),
+	)
+
+	tests := []struct {
+		text      string
+		intent    string
+		topic     string
+		sentiment string
+	}{
+		{"I want my money back, the product is broken", "refund_request", "", classification.SentimentNegative},
+		{"Where is my package? The tracking page shows no delivery date.", classification.IntentQuestion, "shipping", classification.SentimentNeutral},
+		{"Hello there!", classification.IntentGreeting, "", classification.SentimentNeutral},
+		{"Please send me a copy of my invoice, thanks", classification.IntentRequest, "billing", classification.SentimentPositive},
+		{"The new dashboard is not good", "", "", classification.SentimentNegative},
+	}
+	for _, tt := range tests {
+		result, err := classifier.Classify(context.Background(), tt.text)
+		require.NoError(t, err)
+		assert.Equal(t, tt.intent, result.Intent, tt.text)
+		assert.Equal(t, tt.topic, result.Topic, tt.text)
+		assert.Equal(t, tt.sentiment, result.Sentiment, tt.text)
+	}
+}
+
+// scriptedLLM returns a fixed response
+type scriptedLLM struct {
+	interfaces.LLM
+	response string
+}
+
+func (l *scriptedLLM) Generate(ctx context.Context, prompt string, options ...interfaces.GenerateOption) (string, error) {
+	return l.response, nil
+}
+
+func TestLLMClassifier(t *testing.T) {
+	llm := &scriptedLLM{response: "```json\n{\"intent\": \"Cancel\", \"topic\": \"weather\", \"sentiment\": \"NEGATIVE\", \"confidence\": 1.5}\n```"}
+	classifier := classification.NewLLMClassifier(llm,
+		classification.WithIntents("cancel", "upgrade"),
+		classification.WithTopics("billing", "account"),
+	)
+
+	result, err := classifier.Classify(context.Background(), "Cancel my subscription")
+	require.NoError(t, err)
+	assert.Equal(t, "cancel", result.Intent)
+	assert.Empty(t, result.Topic, "topics outside the allowed list are dropped")
+	assert.Equal(t, classification.SentimentNegative, result.Sentiment)
+	assert.Equal(t, 1.0, result.Confidence)
+
+	llm.response = "not json"
+	_, err = classifier.Classify(context.Background(), "Cancel my subscription")
+	assert.Error(t, err)
+}
+
+func TestClassificationContextAndMetadata(t *testing.T) {
+	c := &classification.Classification{Intent: "cancel", Topic: "billing", Sentiment: classification.SentimentNeutral, Confidence: 0.8, Labels: map[string]string{"tier": "gold"}}
+
+	ctx := classification.WithClassification(context.Background(), c)
+	fromContext, ok := classification.FromContext(ctx)
+	require.True(t, ok)
+	assert.Same(t, c, fromContext)
+	_, ok = classification.FromContext(context.Background())
+	assert.False(t, ok)
+
+	restored, ok := classification.FromMetadata(map[string]interface{}{classification.MetadataKey: c.Metadata()})
+	require.True(t, ok)
+	assert.Equal(t, c, restored)
+}
@@ -0,0 +1,114 @@
+package classification
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+)
+
+// LLMClassifier asks an LLM to classify text, ideally a small and fast one
+type LLMClassifier struct {
+	llm     interfaces.LLM
+	intents []string
+	topics  []string
+}
+
+// LLMOption configures an LLMClassifier
+type LLMOption func(*LLMClassifier)
+
+// WithIntents limits the intents the LLM may choose from. By default it
+// names the intent freely.
+func WithIntents(intents ...string) LLMOption {
+	return func(c *LLMClassifier) {
+		c.intents = intents
+	}
+}
+
+// WithTopics limits the topics the LLM may choose from. By default it names
+// the topic freely.
+func WithTopics(topics ...string) LLMOption {
+	return func(c *LLMClassifier) {
+		c.topics = topics
+	}
+}
+
+// NewLLMClassifier creates a classifier backed by an LLM
+func NewLLMClassifier(llm interfaces.LLM, options ...LLMOption) *LLMClassifier {
+	c := &LLMClassifier{llm: llm}
+	for _, option := range options {
+		option(c)
+	}
+	return c
+}
+
+// Classify classifies a text
+func (c *LLMClassifier) Classify(ctx context.Context, text string) (*Classification, error) {
+	intents := "a short snake_case label of what the user wants"
+	if len(c.intents) > 0 {
+		intents = "one of: " + strings.Join(c.intents, ", ")
+	}
+	topics := "a short snake_case label of what the message is about"
+	if len(c.topics) > 0 {
+		topics = "one of: " + strings.Join(c.topics, ", ")
+	}
+
+	prompt := fmt.Sprintf(`Classify the user message between the markers.
+Answer with a JSON object and nothing else, with these fields:
+- "intent": %s
+- "topic": %s
+- "sentiment": one of: positive, neutral, negative
+- "confidence": how sure you are about the intent, between 0 and 1
+
+<<<BEGIN MESSAGE>>>
+%s
+<<<END MESSAGE>>>`, intents, topics, text)
+
+	response, err := c.llm.Generate(ctx, prompt, func(o *interfaces.GenerateOptions) {
+		if o.LLMConfig == nil {
+			o.LLMConfig = &interfaces.LLMConfig{}
+		}
+		o.LLMConfig.Temperature = 0
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to classify text: %w", err)
+	}
+
+	// Models sometimes wrap the object in a code block
+	response = strings.TrimSpace(response)
+	if start, end := strings.Index(response, "{"), strings.LastIndex(response, "}"); start >= 0 && end > start {
+		response = response[start : end+1]
+	}
+	var result Classification
+	if err := json.Unmarshal([]byte(response), &result); err != nil {
+		return nil, fmt.Errorf("unexpected classifier response %q: %w", response, err)
+	}
+
+	result.Intent = constrain(result.Intent, c.intents)
+	result.Topic = constrain(result.Topic, c.topics)
+	result.Sentiment = constrain(result.Sentiment, []string{SentimentPositive, SentimentNeutral, SentimentNegative})
+	if result.Confidence < 0 {
+		result.Confidence = 0
+	}
+	if result.Confidence > 1 {
+		result.Confidence = 1
+	}
+	return &result, nil
+}
+
+// constrain normalizes a label and drops it if it is not one of the allowed
+// labels, when there are any
+func constrain(label string, allowed []string) string {
+	label = strings.TrimSpace(label)
+	if len(allowed) == 0 {
+		return label
+	}
+	for _, a := range allowed {
+		if strings.EqualFold(label, a) {
+			return a
+		}
+	}
+	return ""
+}
@@ -0,0 +1,203 @@
+package classification
+
+import (
+	"context"
+	"strings"
+	"unicode"
+)
+
+// Default intents of a RuleClassifier
+const (
+	IntentQuestion = "question"
+	IntentRequest  = "request"
+	IntentGreeting = "greeting"
+)
+
+// questionWords start questions
+var questionWords = strings.Fields("what why how when where who which whose whom is are can could should would do does did will")
+
+// requestWords mark requests
+var requestWords = strings.Fields("please need want create make add remove delete update change send book schedule show give help")
+
+// greetingWords are greetings
+var greetingWords = strings.Fields("hi hello hey greetings morning evening thanks thank")
+
+// positiveWords and negativeWords are the sentiment lexicon
+var (
+	positiveWords = strings.Fields("good great excellent awesome amazing love like thanks thank perfect happy helpful nice wonderful appreciate works fantastic glad")
+	negativeWords = strings.Fields("bad terrible awful hate broken wrong worst angry annoyed frustrated useless disappointed problem issue fails failed failing error slow unhappy poor refund complaint")
+)
+
+// negations flip the sentiment of the word after them
+var negations = map[string]bool{"not": true, "no": true, "never": true, "don't": true, "doesn't": true, "isn't": true, "wasn't": true, "can't": true}
+
+// rule assigns a label when any of its keywords occurs
+type rule struct {
+	label    string
+	keywords []string
+}
+
+// RuleClassifier is a lightweight classifier that matches keywords. It
+// recognizes questions, requests and greetings out of the box, more intents
+// and topics can be added as keyword rules, and sentiment comes from a small
+// word list.
+type RuleClassifier struct {
+	intents []rule
+	topics  []rule
+}
+
+// RuleOption configures a RuleClassifier
+type RuleOption func(*RuleClassifier)
+
+// WithIntentKeywords classifies texts containing any of the keywords as an
+// intent. Custom intents take precedence over the default ones; among custom
+// intents, the one with the most matching keywords wins.
+func WithIntentKeywords(intent string, keywords ...string) RuleOption {
+	return func(c *RuleClassifier) {
+		c.intents = append(c.intents, rule{label: intent, keywords: lowerAll(keywords)})
+	}
+}
+
+// WithTopicKeywords classifies texts containing any of the keywords as being
+// about a topic. The topic with the most matching keywords wins.
+func WithTopicKeywords(topic string, keywords ...string) RuleOption {
+	return func(c *RuleClassifier) {
+		c.topics = append(c.topics, rule{label: topic, keywords: lowerAll(keywords)})
+	}
+}
+
+// NewRuleClassifier creates a keyword classifier
+func NewRuleClassifier(options ...RuleOption) *RuleClassifier {
+	c := &RuleClassifier{}
+	for _, option := range options {
+		option(c)
+	}
+	return c
+}
+
+// Classify classifies a text
+func (c *RuleClassifier) Classify(ctx context.Context, text string) (*Classification, error) {
+	lower := strings.ToLower(strings.TrimSpace(text))
+	words := strings.FieldsFunc(lower, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '\''
+	})
+
+	result := &Classification{Sentiment: sentiment(words)}
+	if intent, hits := bestRule(c.intents, lower, words); intent != "" {
+		result.Intent = intent
+		result.Confidence = confidence(hits)
+	} else if intent, score := defaultIntent(lower, words); intent != "" {
+		result.Intent = intent
+		result.Confidence = score
+	}
+	if topic, _ := bestRule(c.topics, lower, words); topic != "" {
+		result.Topic = topic
+	}
+	return result, nil
+}
+
+// bestRule returns the rule with the most keywords in the text; ties go to
+// the rule added first
+func bestRule(rules []rule, text string, words []string) (string, int) {
+	wordSet := make(map[string]bool, len(words))
+	for _, word := range words {
+		wordSet[word] = true
+	}
+
+	best, bestHits := "", 0
+	for _, r := range rules {
+		hits := 0
+		for _, keyword := range r.keywords {
+			// Phrases match anywhere, single words only as whole words
+			if strings.Contains(keyword, " ") {
+				if strings.Contains(text, keyword) {
+					hits++
+				}
+			} else if wordSet[keyword] {
+				hits++
+			}
+		}
+		if hits > bestHits {
+			best, bestHits = r.label, hits
+		}
+	}
+	return best, bestHits
+}
+
+// defaultIntent recognizes questions, requests and greetings
+func defaultIntent(text string, words []string) (string, float64) {
+	if len(words) == 0 {
+		return "", 0
+	}
+	switch {
+	case strings.HasSuffix(text, "?"):
+		return IntentQuestion, 0.9
+	case contains(greetingWords, words[0]) && len(words) <= 4:
+		return IntentGreeting, 0.8
+	case contains(questionWords, words[0]):
+		return IntentQuestion, 0.6
+	}
+	for _, word := range words {
+		if contains(requestWords, word) {
+			return IntentRequest, 0.5
+		}
+	}
+	return "", 0
+}
+
+// sentiment scores the words against the lexicon
+func sentiment(words []string) string {
+	score := 0
+	for i, word := range words {
+		polarity := 0
+		switch {
+		case contains(positiveWords, word):
+			polarity = 1
+		case contains(negativeWords, word):
+			polarity = -1
+		}
+		if polarity != 0 && i > 0 && negations[words[i-1]] {
+			polarity = -polarity
+		}
+		score += polarity
+	}
+	switch {
+	case score > 0:
+		return SentimentPositive
+	case score < 0:
+		return SentimentNegative
+	default:
+		return SentimentNeutral
+	}
+}
+
+// confidence grows with the number of matching keywords
+func confidence(hits int) float64 {
+	switch {
+	case hits >= 3:
+		return 0.9
+	case hits == 2:
+		return 0.8
+	default:
+		return 0.6
+	}
+}
+
+// contains reports whether a word list holds a word
+func contains(words []string, word string) bool {
+	for _, w := range words {
+		if w == word {
+			return true
+		}
+	}
+	return false
+}
+
+// lowerAll lowercases keywords
+func lowerAll(keywords []string) []string {
+	lowered := make([]string, len(keywords))
+	for i, keyword := range keywords {
+		lowered[i] = strings.ToLower(strings.TrimSpace(keyword))
+	}
+	return lowered
+}
@@ -0,0 +1,249 @@
+// Package compression shortens prompt text, such as retrieved documents and
+// old conversation turns, to a target share of its tokens, so that more
+// knowledge fits into a fixed context window. Text can be compressed
+// extractively, by keeping the sentences most relevant to the query, or by an
+// external compressor such as an LLMLingua service.
+package compression
+
+import (
+	"context"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Compressor shortens text for a prompt
+type Compressor interface {
+	// Compress shortens text to about ratio of its tokens, keeping what is
+	// most relevant to the query. The ratio is between 0 and 1; the query
+	// may be empty.
+	Compress(ctx context.Context, text, query string, ratio float64) (string, error)
+}
+
+// CompressorFunc adapts a function to a Compressor
+type CompressorFunc func(ctx context.Context, text, query string, ratio float64) (string, error)
+
+// Compress calls f(ctx, text, query, ratio)
+func (f CompressorFunc) Compress(ctx context.Context, text, query string, ratio float64) (string, error) {
+	return f(ctx, text, query, ratio)
+}
+
+// leadBonus favors the first sentence of a paragraph, which often states
+// what the paragraph is about
+const leadBonus = 0.1
+
+// Extractive compresses text by sentence pruning: it scores each sentence by
+// its overlap with the query and with the rest of the text, and keeps the
+// best sentences that fit, in their original order
+type Extractive struct {
+	countTokens func(text string) int
+}
+
+// ExtractiveOption configures an Extractive compressor
+type ExtractiveOption func(*Extractive)
+
+// WithTokenCounter sets how sentences are measured against the target. By
+// default a token is estimated as four characters.
+func WithTokenCounter(counter func(text string) int) ExtractiveOption {
+	return func(e *Extractive) {
+		e.countTokens = counter
+	}
+}
+
+// NewExtractive creates an extractive compressor
+func NewExtractive(options ...ExtractiveOption) *Extractive {
+	e := &Extractive{
+		countTokens: func(text string) int { return (len(text) + 3) / 4 },
+	}
+	for _, option := range options {
+		option(e)
+	}
+	return e
+}
+
+// sentence is a sentence of the text being compressed
+type sentence struct {
+	text      string
+	paragraph int
+	line      int
+	lead      bool
+	tokens    int
+	terms     map[string]bool
+	score     float64
+}
+
+// Compress keeps the sentences most relevant to the query that fit in ratio
+// of the text's tokens. At least one sentence is always kept.
+func (e *Extractive) Compress(ctx context.Context, text, query string, ratio float64) (string, error) {
+	if ratio >= 1 {
+		return text, nil
+	}
+
+	sentences := e.split(text)
+	total := 0
+	for _, s := range sentences {
+		total += s.tokens
+	}
+	budget := int(math.Ceil(float64(total) * math.Max(ratio, 0)))
+	if len(sentences) <= 1 || total <= budget {
+		return text, nil
+	}
+
+	e.score(sentences, query)
+
+	order := make([]int, len(sentences))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return sentences[order[a]].score > sentences[order[b]].score
+	})
+
+	// Fill the budget with the best sentences, skipping those that don't
+	// fit in favor of shorter ones further down
+	keep := make([]bool, len(sentences))
+	used := 0
+	for i, index := range order {
+		if i > 0 && used+sentences[index].tokens > budget {
+			continue
+		}
+		keep[index] = true
+		used += sentences[index].tokens
+	}
+
+	return join(sentences, keep), nil
+}
+
+// split splits text into sentences, remembering the paragraphs and lines
+// they came from so that the layout can be restored
+func (e *Extractive) split(text string) []*sentence {
+	var sentences []*sentence
+	line := 0
+	for p, paragraph := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n\n") {
+		lead := true
+		for _, l := range strings.Split(paragraph, "\n") {
+			line++
+			for _, s := range splitSentences(l) {
+				sentences = append(sentences, &sentence{
+					text:      s,
+					paragraph: p,
+					line:      line,
+					lead:      lead,
+					tokens:    e.countTokens(s),
+					terms:     terms(s),
+				})
+				lead = false
+			}
+		}
+	}
+	return sentences
+}
+
+// score scores each sentence by the share of query terms it contains and by
+// how central its terms are to the whole text
+func (e *Extractive) score(sentences []*sentence, query string) {
+	frequency := make(map[string]int)
+	for _, s := range sentences {
+		for term := range s.terms {
+			frequency[term]++
+		}
+	}
+
+	queryTerms := terms(query)
+	for _, s := range sentences {
+		if len(s.terms) > 0 {
+			centrality := 0.0
+			for term := range s.terms {
+				centrality += float64(frequency[term]-1) / float64(len(sentences))
+			}
+			s.score = centrality / math.Sqrt(float64(len(s.terms)))
+		}
+		if len(queryTerms) > 0 {
+			matched := 0
+			for term := range queryTerms {
+				if s.terms[term] {
+					matched++
+				}
+			}
+			s.score += 2 * float64(matched) / float64(len(queryTerms))
+		}
+		if s.lead {
+			s.score += leadBonus
+		}
+	}
+}
+
+// join joins the kept sentences in their original order and layout
+func join(sentences []*sentence, keep []bool) string {
+	var sb strings.Builder
+	var last *sentence
+	for i, s := range sentences {
+		if !keep[i] {
+			continue
+		}
+		if last != nil {
+			switch {
+			case s.paragraph != last.paragraph:
+				sb.WriteString("\n\n")
+			case s.line != last.line:
+				sb.WriteString("\n")
+			default:
+				sb.WriteString(" ")
+			}
+		}
+		sb.WriteString(s.text)
+		last = s
+	}
+	return sb.String()
+}
+
+// splitSentences splits a line after sentence-ending punctuation followed by
+// a space
+func splitSentences(line string) []string {
+	var sentences []string
+	runes := []rune(line)
+	start := 0
+	for i, r := range runes {
+		if r != '.' && r != '!' && r != '?' && r != '。' {
+			continue
+		}
+		if i+1 < len(runes) && !unicode.IsSpace(runes[i+1]) && r != '。' {
+			continue
+		}
+		if s := strings.TrimSpace(string(runes[start : i+1])); s != "" {
+			sentences = append(sentences, s)
+		}
+		start = i + 1
+	}
+	if s := strings.TrimSpace(string(runes[start:])); s != "" {
+		sentences = append(sentences, s)
+	}
+	return sentences
+}
+
+// stopWords are common English words that say nothing about relevance
+var stopWords = map[string]bool{
+	"the": true, "and": true, "for": true, "are": true, "was": true, "were": true,
+	"but": true, "not": true, "you": true, "your": true, "with": true, "this": true,
+	"that": true, "from": true, "have": true, "has": true, "had": true, "what": true,
+	"which": true, "who": true, "how": true, "when": true, "where": true, "why": true,
+	"can": true, "does": true, "did": true, "its": true, "they": true, "them": true,
+	"their": true, "there": true, "will": true, "would": true, "about": true,
+	"into": true, "than": true, "then": true, "also": true, "been": true,
+}
+
+// terms returns the distinct lower-case words of text, without stop words
+// and words shorter than three letters
+func terms(text string) map[string]bool {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	result := make(map[string]bool, len(words))
+	for _, word := range words {
+		if len([]rune(word)) >= 3 && !stopWords[word] {
+			result[word] = true
+		}
+	}
+	return result
+}
@@ -0,0 +1,77 @@
+package compression_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/run-bigpig/llm-agent/pkg/compression"
+)
+
+const policy = `Our store opened in 1998 in Lyon. The founders were two brothers who loved bicycles.
+
+Refunds are issued within 14 days of the return. Returned items must be unused and in their original packaging.
+Shipping costs are not refunded.
+
+We also sell gift cards. Gift cards never expire.`
+
+func TestExtractiveKeepsRelevantSentences(t *testing.T) {
+	compressed, err := compression.NewExtractive().Compress(context.Background(), policy, "When are refunds issued after a return?", 0.3)
+	require.NoError(t, err)
+
+	assert.Contains(t, compressed, "Refunds are issued within 14 days of the return.")
+	assert.NotContains(t, compressed, "bicycles")
+	assert.LessOrEqual(t, len(compressed), len(policy)*3/10+4)
+}
+
+func TestExtractiveKeepsLayout(t *testing.T) {
+	words := func(text string) int { return len(strings.Fields(text)) }
+	compressed, err := compression.NewExtractive(compression.WithTokenCounter(words)).Compress(context.Background(), policy, "refunds shipping gift cards", 0.6)
+	require.NoError(t, err)
+
+	// Kept sentences stay in order, with their line and paragraph breaks
+	assert.Equal(t, "Refunds are issued within 14 days of the return.\nShipping costs are not refunded.\n\nWe also sell gift cards. Gift cards never expire.", compressed)
+}
+
+func TestExtractiveLeavesShortText(t *testing.T) {
+	e := compression.NewExtractive()
+	compressed, err := e.Compress(context.Background(), "Gift cards never expire.", "gift cards", 0.1)
+	require.NoError(t, err)
+	assert.Equal(t, "Gift cards never expire.", compressed)
+
+	compressed, err = e.Compress(context.Background(), policy, "", 1)
+	require.NoError(t, err)
+	assert.Equal(t, policy, compressed)
+}
+
+func TestHTTPCompressor(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+		var body map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, map[string]interface{}{"text": policy, "query": "refunds", "ratio": 0.4}, body)
+		_, _ = w.Write([]byte(`{"compressed_text": "Refunds issued within 14 days."}`))
+	}))
+	defer server.Close()
+
+	compressor := compression.NewHTTPCompressor(server.URL, compression.WithHeader("Authorization", "Bearer secret"))
+	compressed, err := compressor.Compress(context.Background(), policy, "refunds", 0.4)
+	require.NoError(t, err)
+	assert.Equal(t, "Refunds issued within 14 days.", compressed)
+}
+
+func TestHTTPCompressorError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "model not loaded", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	_, err := compression.NewHTTPCompressor(server.URL).Compress(context.Background(), policy, "", 0.5)
+	assert.ErrorContains(t, err, "status 503: model not loaded")
+}
@@ -0,0 +1,102 @@
+package compression
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// HTTPCompressor compresses text with an external service, e.g. a small
+// server around LLMLingua's PromptCompressor. The service receives a POST
+// request with the JSON body {"text": ..., "query": ..., "ratio": ...} and
+// answers with {"compressed_text": ...}.
+type HTTPCompressor struct {
+	endpoint string
+	client   *http.Client
+	headers  map[string]string
+}
+
+// HTTPOption configures an HTTPCompressor
+type HTTPOption func(*HTTPCompressor)
+
+// WithHTTPClient sets the HTTP client used for requests
+func WithHTTPClient(client *http.Client) HTTPOption {
+	return func(c *HTTPCompressor) {
+		c.client = client
+	}
+}
+
+// WithHeader sets a header sent with every request, e.g. for authentication
+func WithHeader(key, value string) HTTPOption {
+	return func(c *HTTPCompressor) {
+		c.headers[key] = value
+	}
+}
+
+// NewHTTPCompressor creates a compressor that calls the service at endpoint
+func NewHTTPCompressor(endpoint string, options ...HTTPOption) *HTTPCompressor {
+	c := &HTTPCompressor{
+		endpoint: endpoint,
+		client:   http.DefaultClient,
+		headers:  make(map[string]string),
+	}
+	for _, option := range options {
+		option(c)
+	}
+	return c
+}
+
+// compressRequest is the body of a request to the service
+type compressRequest struct {
+	Text  string  `json:"text"`
+	Query string  `json:"query,omitempty"`
+	Ratio float64 `json:"ratio"`
+}
+
+// compressResponse is the body of the service's response
+type compressResponse struct {
+	CompressedText string `json:"compressed_text"`
+}
+
+// Compress sends the text to the service and returns its compressed text
+func (c *HTTPCompressor) Compress(ctx context.Context, text, query string, ratio float64) (string, error) {
+	payload, err := json.Marshal(compressRequest{Text: text, Query: query, Ratio: ratio})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode compression request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return "", fmt.Errorf("failed to create compression request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	for key, value := range c.headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("compression request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return "", fmt.Errorf("compressor returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(message)))
+	}
+
+	var out compressResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return "", fmt.Errorf("failed to decode compression response: %w", err)
+	}
+	if strings.TrimSpace(out.CompressedText) == "" {
+		return "", errors.New("compressor returned no text")
+	}
+	return out.CompressedText, nil
+}
@@ -10,6 +10,10 @@ import (
 type Config struct {
 	// LLM configuration
 	LLM struct {
+		// Provider selects the LLM provider (openai, anthropic or vertex).
+		// When empty, the first provider with credentials is used.
+		Provider string
+
 		// OpenAI configuration
 		OpenAI struct {
 			APIKey         string
@@ -28,10 +32,25 @@ type Config struct {
 			BaseURL     string
 			Timeout     time.Duration
 		}
+
+		// Vertex AI configuration
+		Vertex struct {
+			ProjectID       string
+			Location        string
+			Model           string
+			CredentialsFile string
+			Timeout         time.Duration
+		}
 	}
 
 	// Memory configuration
 	Memory struct {
+		// Type selects the memory implementation (buffer or redis)
+		Type string
+
+		// MaxSize is the maximum number of messages kept by the buffer memory
+		MaxSize int
+
 		// Redis configuration
 		Redis struct {
 			URL      string
@@ -44,11 +63,13 @@ type Config struct {
 	VectorStore struct {
 		// Weaviate configuration
 		Weaviate struct {
-			URL       string
-			APIKey    string
-			Scheme    string
-			Host      string
-			ClassName string
+			URL           string
+			APIKey        string
+			Scheme        string
+			Host          string
+			ClassName     string
+			GRPCHost      string
+			NativeTenancy bool
 		}
 	}
 
@@ -91,6 +112,15 @@ type Config struct {
 			Enabled           bool
 			ServiceName       string
 			CollectorEndpoint string
+			OpenInference     bool
+		}
+
+		// LangSmith configuration
+		LangSmith struct {
+			Enabled  bool
+			APIKey   string
+			Endpoint string
+			Project  string
 		}
 	}
 
@@ -133,6 +163,8 @@ func LoadFromEnv() *Config {
 	initLLMConfig(config)
 
 	// Memory configuration
+	config.Memory.Type = getEnv("MEMORY_TYPE", "buffer")
+	config.Memory.MaxSize = getEnvInt("MEMORY_MAX_SIZE", 100)
 	config.Memory.Redis.URL = getEnv("REDIS_URL", "localhost:6379")
 	config.Memory.Redis.Password = getEnv("REDIS_PASSWORD", "")
 	config.Memory.Redis.DB = getEnvInt("REDIS_DB", 0)
@@ -143,6 +175,8 @@ func LoadFromEnv() *Config {
 	config.VectorStore.Weaviate.Scheme = getEnv("WEAVIATE_SCHEME", "https")
 	config.VectorStore.Weaviate.Host = getEnv("WEAVIATE_HOST", "localhost:8080")
 	config.VectorStore.Weaviate.ClassName = getEnv("WEAVIATE_CLASS_NAME", "Document")
+	config.VectorStore.Weaviate.GRPCHost = getEnv("WEAVIATE_GRPC_HOST", "")
+	config.VectorStore.Weaviate.NativeTenancy = getEnvBool("WEAVIATE_NATIVE_TENANCY", false)
 
 	// DataStore configuration
 	config.DataStore.Supabase.URL = getEnv("SUPABASE_URL", "")
@@ -165,6 +199,12 @@ func LoadFromEnv() *Config {
 	config.Tracing.OpenTelemetry.Enabled = getEnvBool("OTEL_ENABLED", false)
 	config.Tracing.OpenTelemetry.ServiceName = getEnv("OTEL_SERVICE_NAME", "agent-sdk")
 	config.Tracing.OpenTelemetry.CollectorEndpoint = getEnv("OTEL_COLLECTOR_ENDPOINT", "localhost:4317")
+	config.Tracing.OpenTelemetry.OpenInference = getEnvBool("OTEL_OPENINFERENCE", false)
+
+	config.Tracing.LangSmith.Enabled = getEnvBool("LANGSMITH_ENABLED", false)
+	config.Tracing.LangSmith.APIKey = getEnv("LANGSMITH_API_KEY", "")
+	config.Tracing.LangSmith.Endpoint = getEnv("LANGSMITH_ENDPOINT", "https://api.smith.langchain.com")
+	config.Tracing.LangSmith.Project = getEnv("LANGSMITH_PROJECT", "default")
 
 	// Multitenancy configuration
 	config.Multitenancy.Enabled = getEnvBool("MULTITENANCY_ENABLED", false)
@@ -179,19 +219,29 @@ func LoadFromEnv() *Config {
 
 // initLLMConfig initializes LLM configuration with defaults
 func initLLMConfig(config *Config) {
+	config.LLM.Provider = getEnvString("LLM_PROVIDER", "")
+
 	// OpenAI defaults
 	config.LLM.OpenAI.APIKey = getEnvString("OPENAI_API_KEY", "")
 	config.LLM.OpenAI.Model = getEnvString("OPENAI_MODEL", "gpt-4o-mini")
 	config.LLM.OpenAI.Temperature = getEnvFloat("OPENAI_TEMPERATURE", 0.7)
 	config.LLM.OpenAI.BaseURL = getEnvString("OPENAI_BASE_URL", "")
-	config.LLM.OpenAI.Timeout = time.Duration(getEnvInt("OPENAI_TIMEOUT", 60)) * time.Second
+	config.LLM.OpenAI.Timeout = time.Duration(getEnvInt("OPENAI_TIMEOUT", 0)) * time.Second
+	config.LLM.OpenAI.EmbeddingModel = getEnvString("OPENAI_EMBEDDING_MODEL", "text-embedding-3-small")
 
 	// Anthropic defaults
 	config.LLM.Anthropic.APIKey = getEnvString("ANTHROPIC_API_KEY", "")
 	config.LLM.Anthropic.Model = getEnvString("ANTHROPIC_MODEL", "claude-3-7-sonnet-20240307")
 	config.LLM.Anthropic.Temperature = getEnvFloat("ANTHROPIC_TEMPERATURE", 0.7)
 	config.LLM.Anthropic.BaseURL = getEnvString("ANTHROPIC_BASE_URL", "")
-	config.LLM.Anthropic.Timeout = time.Duration(getEnvInt("ANTHROPIC_TIMEOUT", 60)) * time.Second
+	config.LLM.Anthropic.Timeout = time.Duration(getEnvInt("ANTHROPIC_TIMEOUT", 0)) * time.Second
+
+	// Vertex AI defaults
+	config.LLM.Vertex.ProjectID = getEnvString("VERTEX_PROJECT_ID", "")
+	config.LLM.Vertex.Location = getEnvString("VERTEX_LOCATION", "us-central1")
+	config.LLM.Vertex.Model = getEnvString("VERTEX_MODEL", "gemini-1.5-pro")
+	config.LLM.Vertex.CredentialsFile = getEnvString("GOOGLE_APPLICATION_CREDENTIALS", "")
+	config.LLM.Vertex.Timeout = time.Duration(getEnvInt("VERTEX_TIMEOUT", 0)) * time.Second
 }
 
 // getEnv gets an environment variable or returns a default value
@@ -0,0 +1,54 @@
+package config_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/run-bigpig/llm-agent/pkg/config"
+)
+
+func TestLoadFromEnvDefaults(t *testing.T) {
+	for _, name := range []string{"LLM_PROVIDER", "MEMORY_TYPE", "MEMORY_MAX_SIZE", "VERTEX_PROJECT_ID", "VERTEX_LOCATION", "VERTEX_MODEL", "OPENAI_EMBEDDING_MODEL"} {
+		t.Setenv(name, "")
+	}
+
+	cfg := config.LoadFromEnv()
+	if cfg.LLM.Provider != "" {
+		t.Errorf("Expected no provider, got %q", cfg.LLM.Provider)
+	}
+	if cfg.Memory.Type != "buffer" || cfg.Memory.MaxSize != 100 {
+		t.Errorf("Expected a buffer memory of 100 messages, got %q and %d", cfg.Memory.Type, cfg.Memory.MaxSize)
+	}
+	if cfg.LLM.Vertex.Location != "us-central1" || cfg.LLM.Vertex.Model != "gemini-1.5-pro" {
+		t.Errorf("Unexpected Vertex defaults: %+v", cfg.LLM.Vertex)
+	}
+	if cfg.LLM.OpenAI.EmbeddingModel != "text-embedding-3-small" {
+		t.Errorf("Unexpected embedding model: %q", cfg.LLM.OpenAI.EmbeddingModel)
+	}
+}
+
+func TestLoadFromEnv(t *testing.T) {
+	t.Setenv("LLM_PROVIDER", "vertex")
+	t.Setenv("MEMORY_TYPE", "redis")
+	t.Setenv("MEMORY_MAX_SIZE", "20")
+	t.Setenv("VERTEX_PROJECT_ID", "my-project")
+	t.Setenv("VERTEX_LOCATION", "europe-west4")
+	t.Setenv("VERTEX_MODEL", "gemini-2.0-flash")
+	t.Setenv("GOOGLE_APPLICATION_CREDENTIALS", "/secrets/sa.json")
+	t.Setenv("OPENAI_TIMEOUT", "15")
+
+	cfg := config.LoadFromEnv()
+	if cfg.LLM.Provider != "vertex" {
+		t.Errorf("Expected the vertex provider, got %q", cfg.LLM.Provider)
+	}
+	if cfg.Memory.Type != "redis" || cfg.Memory.MaxSize != 20 {
+		t.Errorf("Expected a redis memory of 20 messages, got %q and %d", cfg.Memory.Type, cfg.Memory.MaxSize)
+	}
+	vertex := cfg.LLM.Vertex
+	if vertex.ProjectID != "my-project" || vertex.Location != "europe-west4" || vertex.Model != "gemini-2.0-flash" || vertex.CredentialsFile != "/secrets/sa.json" {
+		t.Errorf("Unexpected Vertex config: %+v", vertex)
+	}
+	if cfg.LLM.OpenAI.Timeout != 15*time.Second {
+		t.Errorf("Expected a 15s OpenAI timeout, got %v", cfg.LLM.OpenAI.Timeout)
+	}
+}
@@ -0,0 +1,302 @@
+// Package cost attributes LLM and embedding token usage and spend to the
+// org and tags in the run context (see runctx.WithTags), for chargeback
+// reporting per org, team, feature or experiment.
+package cost
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/run-bigpig/llm-agent/pkg/logging"
+	"github.com/run-bigpig/llm-agent/pkg/runctx"
+)
+
+// OrgTag is the tag usage is attributed to when the context has an org ID
+// (see runctx.WithOrgID) and no tag of that name
+const OrgTag = "org_id"
+
+// DefaultBudget is the key of the budget that applies to orgs without one of
+// their own
+const DefaultBudget = "*"
+
+// ErrBudgetExceeded is returned when an org has spent its budget
+var ErrBudgetExceeded = errors.New("budget exceeded")
+
+// Price is the price of a model in dollars per million tokens
+type Price struct {
+	InputPerMillion  float64 `json:"input_per_million" yaml:"input_per_million"`
+	OutputPerMillion float64 `json:"output_per_million" yaml:"output_per_million"`
+}
+
+// Usage is the token usage of a single LLM call or embedding request
+type Usage struct {
+	Model        string
+	InputTokens  int
+	OutputTokens int
+
+	// Embedding marks the usage of an embedding request, whose tokens are
+	// the input tokens
+	Embedding bool
+}
+
+// Total aggregates the usage and cost of the calls with the same tags
+type Total struct {
+	Tags         map[string]string `json:"tags"`
+	Requests     int               `json:"requests"`
+	InputTokens  int               `json:"input_tokens"`
+	OutputTokens int               `json:"output_tokens"`
+
+	// Cost is the spend on LLM calls and embeddings
+	Cost float64 `json:"cost"`
+
+	// EmbeddingRequests, EmbeddingTokens and EmbeddingCost are the usage and
+	// spend of embedding requests; they are not counted in Requests and
+	// InputTokens
+	EmbeddingRequests int     `json:"embedding_requests"`
+	EmbeddingTokens   int     `json:"embedding_tokens"`
+	EmbeddingCost     float64 `json:"embedding_cost"`
+}
+
+// add adds the usage and cost of another total
+func (t *Total) add(other Total) {
+	t.Requests += other.Requests
+	t.InputTokens += other.InputTokens
+	t.OutputTokens += other.OutputTokens
+	t.Cost += other.Cost
+	t.EmbeddingRequests += other.EmbeddingRequests
+	t.EmbeddingTokens += other.EmbeddingTokens
+	t.EmbeddingCost += other.EmbeddingCost
+}
+
+// Option configures a Tracker
+type Option func(*Tracker)
+
+// WithPrices sets the price of each model. Calls to models without a price
+// are counted but cost nothing.
+func WithPrices(prices map[string]Price) Option {
+	return func(t *Tracker) {
+		for model, price := range prices {
+			t.prices[model] = price
+		}
+	}
+}
+
+// WithLabels sets the tags that are exported as Prometheus labels. Tags not
+// listed are still recorded and can be grouped by in Totals.
+func WithLabels(names ...string) Option {
+	return func(t *Tracker) {
+		t.labels = names
+	}
+}
+
+// WithTokenCounter sets how the LLM middleware counts the tokens in a text.
+// By default a token is estimated as four characters.
+func WithTokenCounter(counter func(text string) int) Option {
+	return func(t *Tracker) {
+		t.countTokens = counter
+	}
+}
+
+// WithEmbeddingBudgets limits the embedding spend of orgs in dollars, keyed
+// by org ID. The budget under DefaultBudget applies to orgs not listed; orgs
+// without a budget are not limited. Embedders wrapped with NewEmbedder fail
+// with ErrBudgetExceeded once an org has spent its budget.
+func WithEmbeddingBudgets(budgets map[string]float64) Option {
+	return func(t *Tracker) {
+		for orgID, budget := range budgets {
+			t.embeddingBudgets[orgID] = budget
+		}
+	}
+}
+
+// WithLogger sets the logger for sinks that fail
+func WithLogger(logger logging.Logger) Option {
+	return func(t *Tracker) {
+		t.logger = logger
+	}
+}
+
+// Tracker records the usage and cost of LLM calls and embeddings by model,
+// org and tags
+type Tracker struct {
+	prices           map[string]Price
+	labels           []string
+	countTokens      func(text string) int
+	embeddingBudgets map[string]float64
+	sinks            []Sink
+	featureTag       string
+	logger           logging.Logger
+
+	mu             sync.Mutex
+	entries        map[string]*entry
+	embeddingSpend map[string]float64
+}
+
+// entry is the running total for one model and set of tags
+type entry struct {
+	model string
+	total Total
+}
+
+// NewTracker creates a new cost tracker
+func NewTracker(options ...Option) *Tracker {
+	t := &Tracker{
+		prices:           make(map[string]Price),
+		countTokens:      estimateTokens,
+		embeddingBudgets: make(map[string]float64),
+		featureTag:       DefaultFeatureTag,
+		logger:           logging.New(),
+		entries:          make(map[string]*entry),
+		embeddingSpend:   make(map[string]float64),
+	}
+	for _, option := range options {
+		option(t)
+	}
+	return t
+}
+
+// Record records the usage of a call, attributed to the org and tags in the
+// context, sends its billing record to the sinks and returns its cost
+func (t *Tracker) Record(ctx context.Context, usage Usage) float64 {
+	price := t.prices[usage.Model]
+	cost := (float64(usage.InputTokens)*price.InputPerMillion + float64(usage.OutputTokens)*price.OutputPerMillion) / 1e6
+
+	tags := runctx.Tags(ctx)
+	orgID := runctx.OrgID(ctx)
+	if _, ok := tags[OrgTag]; !ok && orgID != "" {
+		if tags == nil {
+			tags = make(map[string]string, 1)
+		}
+		tags[OrgTag] = orgID
+	}
+	key := entryKey(usage.Model, tags)
+
+	t.mu.Lock()
+	e, ok := t.entries[key]
+	if !ok {
+		e = &entry{model: usage.Model, total: Total{Tags: tags}}
+		t.entries[key] = e
+	}
+	if usage.Embedding {
+		e.total.EmbeddingRequests++
+		e.total.EmbeddingTokens += usage.InputTokens
+		e.total.EmbeddingCost += cost
+		t.embeddingSpend[orgID] += cost
+	} else {
+		e.total.Requests++
+		e.total.InputTokens += usage.InputTokens
+		e.total.OutputTokens += usage.OutputTokens
+	}
+	e.total.Cost += cost
+	t.mu.Unlock()
+
+	if len(t.sinks) > 0 {
+		record := newBillingRecord(ctx, usage, cost, tags, t.featureTag)
+		for _, sink := range t.sinks {
+			if err := sink.Write(ctx, record); err != nil {
+				t.logger.Error(ctx, "Failed to write billing record", map[string]interface{}{"error": err.Error()})
+			}
+		}
+	}
+	return cost
+}
+
+// EmbeddingSpend returns the embedding spend of an org that counts against
+// its budget
+func (t *Tracker) EmbeddingSpend(orgID string) float64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.embeddingSpend[orgID]
+}
+
+// ResetEmbeddingSpend starts a new budget period for an org. The totals and
+// metrics are not reset.
+func (t *Tracker) ResetEmbeddingSpend(orgID string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.embeddingSpend, orgID)
+}
+
+// CheckEmbeddingBudget returns ErrBudgetExceeded if the org in the context
+// has spent its embedding budget
+func (t *Tracker) CheckEmbeddingBudget(ctx context.Context) error {
+	orgID := runctx.OrgID(ctx)
+	budget, ok := t.embeddingBudgets[orgID]
+	if !ok {
+		budget, ok = t.embeddingBudgets[DefaultBudget]
+	}
+	if !ok {
+		return nil
+	}
+
+	spend := t.EmbeddingSpend(orgID)
+	if spend >= budget {
+		return fmt.Errorf("%w: org %q has spent $%.4f of its $%.4f embedding budget", ErrBudgetExceeded, orgID, spend, budget)
+	}
+	return nil
+}
+
+// Totals returns the usage and cost grouped by the given tags, most expensive
+// first. Group by OrgTag for a report per org. Calls without one of the tags are grouped under an empty value.
+// Without tags, a single total of all calls is returned.
+func (t *Tracker) Totals(groupBy ...string) []Total {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	groups := make(map[string]*Total)
+	for _, e := range t.entries {
+		tags := make(map[string]string, len(groupBy))
+		for _, name := range groupBy {
+			tags[name] = e.total.Tags[name]
+		}
+
+		key := entryKey("", tags)
+		group, ok := groups[key]
+		if !ok {
+			group = &Total{Tags: tags}
+			groups[key] = group
+		}
+		group.add(e.total)
+	}
+
+	totals := make([]Total, 0, len(groups))
+	for _, group := range groups {
+		totals = append(totals, *group)
+	}
+	sort.Slice(totals, func(i, j int) bool {
+		if totals[i].Cost != totals[j].Cost {
+			return totals[i].Cost > totals[j].Cost
+		}
+		return totals[i].Requests > totals[j].Requests
+	})
+	return totals
+}
+
+// entryKey returns a key that is unique for the model and tags
+func entryKey(model string, tags map[string]string) string {
+	names := make([]string, 0, len(tags))
+	for name := range tags {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	b.WriteString(model)
+	for _, name := range names {
+		b.WriteString("\x00")
+		b.WriteString(name)
+		b.WriteString("=")
+		b.WriteString(tags[name])
+	}
+	return b.String()
+}
+
+// estimateTokens estimates the number of tokens in a text as four
+// characters per token
+func estimateTokens(text string) int {
+	return (len(text) + 3) / 4
+}
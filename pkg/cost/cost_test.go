@@ -0,0 +1,87 @@
+package cost_test
+
+import (
+	"context"
+	"math"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/run-bigpig/llm-agent/pkg/cost"
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+	"github.com/run-bigpig/llm-agent/pkg/runctx"
+)
+
+type echoLLM struct{}
+
+func (l *echoLLM) Generate(ctx context.Context, prompt string, options ...interfaces.GenerateOption) (string, error) {
+	return prompt, nil
+}
+
+func (l *echoLLM) GenerateWithTools(ctx context.Context, prompt string, tools []interfaces.Tool, options ...interfaces.GenerateOption) (string, error) {
+	return prompt, nil
+}
+
+func (l *echoLLM) Name() string { return "echo" }
+
+func TestTracker(t *testing.T) {
+	tracker := cost.NewTracker(
+		cost.WithPrices(map[string]cost.Price{"gpt-4o": {InputPerMillion: 2.5, OutputPerMillion: 10}}),
+		cost.WithLabels("team"),
+	)
+
+	search := runctx.WithTags(context.Background(), map[string]string{"team": "search", "feature": "autocomplete"})
+	support := runctx.WithTag(context.Background(), "team", "support")
+
+	if c := tracker.Record(search, cost.Usage{Model: "gpt-4o", InputTokens: 1000000, OutputTokens: 100000}); math.Abs(c-3.5) > 1e-9 {
+		t.Errorf("expected a cost of 3.5, got %v", c)
+	}
+	tracker.Record(search, cost.Usage{Model: "gpt-4o", InputTokens: 1000000})
+	tracker.Record(support, cost.Usage{Model: "unpriced", InputTokens: 10, OutputTokens: 10})
+
+	totals := tracker.Totals("team")
+	if len(totals) != 2 {
+		t.Fatalf("expected 2 teams, got %+v", totals)
+	}
+	if totals[0].Tags["team"] != "search" || totals[0].Requests != 2 || math.Abs(totals[0].Cost-6) > 1e-9 {
+		t.Errorf("unexpected search total: %+v", totals[0])
+	}
+	if totals[1].Tags["team"] != "support" || totals[1].Cost != 0 || totals[1].InputTokens != 10 {
+		t.Errorf("unexpected support total: %+v", totals[1])
+	}
+	if all := tracker.Totals(); len(all) != 1 || all[0].Requests != 3 {
+		t.Errorf("expected a single total of 3 requests, got %+v", all)
+	}
+
+	recorder := httptest.NewRecorder()
+	tracker.Handler().ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	metrics := recorder.Body.String()
+	for _, line := range []string{
+		`agent_llm_requests_total{model="gpt-4o",team="search"} 2`,
+		`agent_llm_tokens_total{model="gpt-4o",team="search",direction="input"} 2000000`,
+		`agent_llm_cost_dollars_total{model="gpt-4o",team="search"} 6`,
+		`agent_llm_requests_total{model="unpriced",team="support"} 1`,
+	} {
+		if !strings.Contains(metrics, line+"\n") {
+			t.Errorf("expected metrics to contain %q, got:\n%s", line, metrics)
+		}
+	}
+	if strings.Contains(metrics, "feature") {
+		t.Errorf("expected tags that are not labels to be left out, got:\n%s", metrics)
+	}
+}
+
+func TestLLM(t *testing.T) {
+	tracker := cost.NewTracker(cost.WithTokenCounter(func(text string) int { return len(strings.Fields(text)) }))
+	llm := cost.NewLLM(&echoLLM{}, tracker, "")
+
+	ctx := runctx.WithTag(context.Background(), "experiment", "b")
+	if _, err := llm.Generate(ctx, "one two three", func(o *interfaces.GenerateOptions) { o.SystemMessage = "be brief" }); err != nil {
+		t.Fatalf("failed to generate: %v", err)
+	}
+
+	totals := tracker.Totals("experiment")
+	if len(totals) != 1 || totals[0].Tags["experiment"] != "b" || totals[0].InputTokens != 5 || totals[0].OutputTokens != 3 {
+		t.Errorf("unexpected totals: %+v", totals)
+	}
+}
@@ -0,0 +1,109 @@
+package cost
+
+import (
+	"context"
+
+	"github.com/run-bigpig/llm-agent/pkg/embedding"
+)
+
+// Embedder wraps an embedding client so that the usage and cost of every
+// request is recorded in a tracker and the embedding budgets of orgs are
+// enforced. The token counts the provider reports are used when there are
+// any; otherwise tokens are counted with the tracker's token counter.
+type Embedder struct {
+	client  embedding.Client
+	tracker *Tracker
+	model   string
+}
+
+// NewEmbedder wraps the embedding client with cost tracking. The model names
+// the price to use; if it is empty, the model the provider reports is used.
+func NewEmbedder(client embedding.Client, tracker *Tracker, model string) *Embedder {
+	return &Embedder{
+		client:  client,
+		tracker: tracker,
+		model:   model,
+	}
+}
+
+// Embed generates an embedding for the given text
+func (e *Embedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	var vector []float32
+	err := e.embed(ctx, []string{text}, func(ctx context.Context) (err error) {
+		vector, err = e.client.Embed(ctx, text)
+		return err
+	})
+	return vector, err
+}
+
+// EmbedWithConfig generates an embedding with custom configuration
+func (e *Embedder) EmbedWithConfig(ctx context.Context, text string, config embedding.EmbeddingConfig) ([]float32, error) {
+	var vector []float32
+	err := e.embed(ctx, []string{text}, func(ctx context.Context) (err error) {
+		vector, err = e.client.EmbedWithConfig(ctx, text, config)
+		return err
+	})
+	return vector, err
+}
+
+// EmbedBatch generates embeddings for multiple texts
+func (e *Embedder) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	var vectors [][]float32
+	err := e.embed(ctx, texts, func(ctx context.Context) (err error) {
+		vectors, err = e.client.EmbedBatch(ctx, texts)
+		return err
+	})
+	return vectors, err
+}
+
+// EmbedBatchWithConfig generates embeddings for multiple texts with custom
+// configuration
+func (e *Embedder) EmbedBatchWithConfig(ctx context.Context, texts []string, config embedding.EmbeddingConfig) ([][]float32, error) {
+	var vectors [][]float32
+	err := e.embed(ctx, texts, func(ctx context.Context) (err error) {
+		vectors, err = e.client.EmbedBatchWithConfig(ctx, texts, config)
+		return err
+	})
+	return vectors, err
+}
+
+// CalculateSimilarity calculates the similarity between two embeddings
+func (e *Embedder) CalculateSimilarity(vec1, vec2 []float32, metric string) (float32, error) {
+	return e.client.CalculateSimilarity(vec1, vec2, metric)
+}
+
+// Unwrap returns the wrapped embedding client
+func (e *Embedder) Unwrap() embedding.Client {
+	return e.client
+}
+
+// embed checks the org's budget, makes the request and records its usage.
+// Failed requests are only recorded if the provider reported usage for them.
+func (e *Embedder) embed(ctx context.Context, texts []string, request func(ctx context.Context) error) error {
+	if err := e.tracker.CheckEmbeddingBudget(ctx); err != nil {
+		return err
+	}
+
+	var reported []embedding.Usage
+	err := request(embedding.WithUsageCallback(ctx, func(usage embedding.Usage) {
+		reported = append(reported, usage)
+	}))
+
+	usage := Usage{Model: e.model, Embedding: true}
+	if len(reported) == 0 {
+		if err != nil {
+			return err
+		}
+		for _, text := range texts {
+			usage.InputTokens += e.tracker.countTokens(text)
+		}
+	}
+	for _, r := range reported {
+		usage.InputTokens += r.Tokens
+		if usage.Model == "" {
+			usage.Model = r.Model
+		}
+	}
+	e.tracker.Record(ctx, usage)
+	return err
+}
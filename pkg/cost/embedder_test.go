@@ -0,0 +1,106 @@
+package cost_test
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/run-bigpig/llm-agent/pkg/cost"
+	"github.com/run-bigpig/llm-agent/pkg/embedding"
+	"github.com/run-bigpig/llm-agent/pkg/runctx"
+)
+
+// fakeEmbedder returns a vector per text without reporting usage
+type fakeEmbedder struct {
+	embedding.Client
+	calls int
+}
+
+func (e *fakeEmbedder) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	e.calls++
+	return make([][]float32, len(texts)), nil
+}
+
+func TestEmbedderUsesReportedUsage(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"data":[{"index":0,"embedding":[1,0]}],"usage":{"total_tokens":1000000}}`))
+	}))
+	defer server.Close()
+
+	tracker := cost.NewTracker(cost.WithPrices(map[string]cost.Price{"jina-embeddings-v3": {InputPerMillion: 0.02}}))
+	embedder := cost.NewEmbedder(embedding.NewJinaEmbedder("key", "", embedding.WithBaseURL(server.URL)), tracker, "")
+
+	ctx := runctx.WithOrgID(context.Background(), "acme")
+	if _, err := embedder.Embed(ctx, "hello"); err != nil {
+		t.Fatalf("failed to embed: %v", err)
+	}
+
+	totals := tracker.Totals(cost.OrgTag)
+	if len(totals) != 1 || totals[0].Tags[cost.OrgTag] != "acme" {
+		t.Fatalf("expected a total for org acme, got %+v", totals)
+	}
+	total := totals[0]
+	if total.EmbeddingRequests != 1 || total.EmbeddingTokens != 1000000 || total.Requests != 0 {
+		t.Errorf("expected the reported usage of one embedding request, got %+v", total)
+	}
+	if math.Abs(total.EmbeddingCost-0.02) > 1e-9 || math.Abs(total.Cost-0.02) > 1e-9 {
+		t.Errorf("expected the embedding spend in the cost, got %+v", total)
+	}
+
+	var metrics strings.Builder
+	tracker.WritePrometheus(&metrics)
+	for _, line := range []string{
+		`agent_embedding_requests_total{model="jina-embeddings-v3"} 1`,
+		`agent_embedding_tokens_total{model="jina-embeddings-v3"} 1000000`,
+		`agent_embedding_cost_dollars_total{model="jina-embeddings-v3"} 0.02`,
+	} {
+		if !strings.Contains(metrics.String(), line+"\n") {
+			t.Errorf("expected metrics to contain %q, got:\n%s", line, metrics.String())
+		}
+	}
+	if strings.Contains(metrics.String(), "agent_llm_requests_total{") {
+		t.Errorf("expected no LLM series for an embedding model, got:\n%s", metrics.String())
+	}
+}
+
+func TestEmbedderBudget(t *testing.T) {
+	tracker := cost.NewTracker(
+		cost.WithPrices(map[string]cost.Price{"small": {InputPerMillion: 1e6}}),
+		cost.WithEmbeddingBudgets(map[string]float64{"acme": 2, cost.DefaultBudget: 100}),
+	)
+	client := &fakeEmbedder{}
+	embedder := cost.NewEmbedder(client, tracker, "small")
+
+	acme := runctx.WithOrgID(context.Background(), "acme")
+	if _, err := embedder.EmbedBatch(acme, []string{"abcd", "abcd"}); err != nil {
+		t.Fatalf("failed to embed within the budget: %v", err)
+	}
+	if spend := tracker.EmbeddingSpend("acme"); math.Abs(spend-2) > 1e-9 {
+		t.Fatalf("expected estimated tokens to cost $2, got %v", spend)
+	}
+
+	if _, err := embedder.EmbedBatch(acme, []string{"abcd"}); !errors.Is(err, cost.ErrBudgetExceeded) {
+		t.Fatalf("expected ErrBudgetExceeded, got %v", err)
+	}
+	if client.calls != 1 {
+		t.Fatalf("expected the request over budget not to be sent, got %d calls", client.calls)
+	}
+
+	other := runctx.WithOrgID(context.Background(), "globex")
+	if _, err := embedder.EmbedBatch(other, []string{"abcd"}); err != nil {
+		t.Fatalf("expected other orgs to use the default budget, got %v", err)
+	}
+
+	tracker.ResetEmbeddingSpend("acme")
+	if _, err := embedder.EmbedBatch(acme, []string{"abcd"}); err != nil {
+		t.Fatalf("expected a reset budget to allow requests, got %v", err)
+	}
+	if totals := tracker.Totals(cost.OrgTag); totals[0].Tags[cost.OrgTag] != "acme" || totals[0].EmbeddingRequests != 2 {
+		t.Errorf("expected the reset not to change the totals, got %+v", totals)
+	}
+}
@@ -0,0 +1,222 @@
+package cost
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/run-bigpig/llm-agent/pkg/logging"
+)
+
+// Defaults of HTTPSink
+const (
+	defaultSinkBatchSize     = 100
+	defaultSinkFlushInterval = 10 * time.Second
+)
+
+// HTTPSink posts billing records in batches, as a JSON array, to an HTTP
+// endpoint in the background. A batch is sent when it is full or when the
+// flush interval passes. Records of failed batches are sent again with the
+// next batch; beyond ten batches the oldest records are dropped.
+type HTTPSink struct {
+	url       string
+	client    *http.Client
+	headers   map[string]string
+	batchSize int
+	interval  time.Duration
+	logger    logging.Logger
+
+	mu      sync.Mutex
+	buffer  []BillingRecord
+	dropped atomic.Int64
+
+	flush     chan struct{}
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// HTTPSinkOption configures an HTTPSink
+type HTTPSinkOption func(*HTTPSink)
+
+// WithSinkHTTPClient sets the HTTP client used for requests
+func WithSinkHTTPClient(client *http.Client) HTTPSinkOption {
+	return func(s *HTTPSink) {
+		s.client = client
+	}
+}
+
+// WithSinkHeader sets a header sent with every request, e.g. for
+// authentication
+func WithSinkHeader(key, value string) HTTPSinkOption {
+	return func(s *HTTPSink) {
+		s.headers[key] = value
+	}
+}
+
+// WithSinkBatchSize sets the most records sent in one request. Defaults to
+// 100.
+func WithSinkBatchSize(size int) HTTPSinkOption {
+	return func(s *HTTPSink) {
+		s.batchSize = size
+	}
+}
+
+// WithSinkFlushInterval sets how often records are sent when no batch fills
+// up. Defaults to 10 seconds.
+func WithSinkFlushInterval(interval time.Duration) HTTPSinkOption {
+	return func(s *HTTPSink) {
+		s.interval = interval
+	}
+}
+
+// WithSinkLogger sets the logger for failed requests and dropped records
+func WithSinkLogger(logger logging.Logger) HTTPSinkOption {
+	return func(s *HTTPSink) {
+		s.logger = logger
+	}
+}
+
+// NewHTTPSink starts a sink that posts records to the URL. Call Close to send
+// the records still buffered and stop.
+func NewHTTPSink(url string, options ...HTTPSinkOption) *HTTPSink {
+	s := &HTTPSink{
+		url:       url,
+		client:    http.DefaultClient,
+		headers:   make(map[string]string),
+		batchSize: defaultSinkBatchSize,
+		interval:  defaultSinkFlushInterval,
+		logger:    logging.New(),
+		flush:     make(chan struct{}, 1),
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+	for _, option := range options {
+		option(s)
+	}
+	if s.batchSize < 1 {
+		s.batchSize = defaultSinkBatchSize
+	}
+	go s.run()
+	return s
+}
+
+// Write buffers the records. It returns immediately.
+func (s *HTTPSink) Write(ctx context.Context, records ...BillingRecord) error {
+	s.mu.Lock()
+	s.buffer = append(s.buffer, records...)
+	if excess := len(s.buffer) - 10*s.batchSize; excess > 0 {
+		s.buffer = s.buffer[excess:]
+		s.dropped.Add(int64(excess))
+		s.logger.Warn(ctx, "Billing record buffer is full, dropping records", map[string]interface{}{"records": excess})
+	}
+	full := len(s.buffer) >= s.batchSize
+	s.mu.Unlock()
+
+	if full {
+		select {
+		case s.flush <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+// Dropped returns the number of records dropped because the buffer was full
+func (s *HTTPSink) Dropped() int64 {
+	return s.dropped.Load()
+}
+
+// Close sends the buffered records and stops. It returns early with the
+// context's error if the context ends first. Records must not be written
+// after Close.
+func (s *HTTPSink) Close(ctx context.Context) error {
+	s.closeOnce.Do(func() {
+		close(s.stop)
+	})
+	select {
+	case <-s.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// run sends batches until the sink is closed
+func (s *HTTPSink) run() {
+	defer close(s.done)
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-s.flush:
+		case <-s.stop:
+			s.send()
+			return
+		}
+		s.send()
+	}
+}
+
+// send posts the buffered records in batches. The records of a failed batch
+// and the batches after it go back into the buffer.
+func (s *HTTPSink) send() {
+	s.mu.Lock()
+	records := s.buffer
+	s.buffer = nil
+	s.mu.Unlock()
+
+	for len(records) > 0 {
+		n := min(len(records), s.batchSize)
+		if err := s.post(records[:n]); err != nil {
+			s.logger.Error(context.Background(), "Failed to send billing records", map[string]interface{}{
+				"records": len(records),
+				"error":   err.Error(),
+			})
+			s.mu.Lock()
+			s.buffer = append(records, s.buffer...)
+			s.mu.Unlock()
+			return
+		}
+		records = records[n:]
+	}
+}
+
+// post sends a batch of records
+func (s *HTTPSink) post(records []BillingRecord) error {
+	payload, err := json.Marshal(records)
+	if err != nil {
+		return fmt.Errorf("failed to encode billing records: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range s.headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("endpoint returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(message)))
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package cost
+
+import (
+	"context"
+	"sync"
+
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+)
+
+// LLM wraps an LLM so that the usage and cost of every call is recorded in a
+// tracker. The token counts the provider reports are used when there are any;
+// otherwise tokens are counted with the tracker's token counter. The usage is
+// passed on to the usage callback in the context with its estimated cost, see
+// interfaces.WithUsageCallback.
+type LLM struct {
+	llm     interfaces.LLM
+	tracker *Tracker
+	model   string
+}
+
+// NewLLM wraps the LLM with cost tracking. The model names the price to use;
+// if it is empty, the LLM's name is used.
+func NewLLM(llm interfaces.LLM, tracker *Tracker, model string) *LLM {
+	if model == "" {
+		model = llm.Name()
+	}
+	return &LLM{
+		llm:     llm,
+		tracker: tracker,
+		model:   model,
+	}
+}
+
+// Generate generates text based on the provided prompt
+func (l *LLM) Generate(ctx context.Context, prompt string, options ...interfaces.GenerateOption) (string, error) {
+	var reported []interfaces.Usage
+	response, err := l.llm.Generate(l.observe(ctx, &reported), prompt, options...)
+	l.record(ctx, prompt, options, response, reported)
+	return response, err
+}
+
+// GenerateWithTools generates text and can use tools
+func (l *LLM) GenerateWithTools(ctx context.Context, prompt string, tools []interfaces.Tool, options ...interfaces.GenerateOption) (string, error) {
+	var reported []interfaces.Usage
+	response, err := l.llm.GenerateWithTools(l.observe(ctx, &reported), prompt, tools, options...)
+	l.record(ctx, prompt, options, response, reported)
+	return response, err
+}
+
+// Name returns the name of the LLM provider
+func (l *LLM) Name() string {
+	return l.llm.Name()
+}
+
+// Unwrap returns the wrapped LLM
+func (l *LLM) Unwrap() interfaces.LLM {
+	return l.llm
+}
+
+// observe returns a context that collects the usage the provider reports
+func (l *LLM) observe(ctx context.Context, reported *[]interfaces.Usage) context.Context {
+	var mu sync.Mutex
+	return interfaces.WithUsageCallback(ctx, func(usage interfaces.Usage) {
+		mu.Lock()
+		defer mu.Unlock()
+		*reported = append(*reported, usage)
+	})
+}
+
+// record records the usage of a call and passes it on with its cost. Failed
+// calls are recorded too, since providers may bill for the prompt.
+func (l *LLM) record(ctx context.Context, prompt string, options []interfaces.GenerateOption, response string, reported []interfaces.Usage) {
+	if len(reported) == 0 {
+		generateOptions := &interfaces.GenerateOptions{}
+		for _, option := range options {
+			option(generateOptions)
+		}
+		reported = []interfaces.Usage{{
+			InputTokens:  l.tracker.countTokens(generateOptions.SystemMessage) + l.tracker.countTokens(prompt),
+			OutputTokens: l.tracker.countTokens(response),
+		}}
+	}
+
+	for _, usage := range reported {
+		usage.Cost = l.tracker.Record(ctx, Usage{
+			Model:        l.model,
+			InputTokens:  usage.InputTokens,
+			OutputTokens: usage.OutputTokens,
+		})
+		if usage.Model == "" {
+			usage.Model = l.model
+		}
+		interfaces.ReportUsage(ctx, usage)
+	}
+}
@@ -0,0 +1,125 @@
+package cost
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Handler returns an HTTP handler that serves the usage and cost in the
+// Prometheus text format, labelled by model and the tags set with WithLabels
+func (t *Tracker) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		t.WritePrometheus(w)
+	})
+}
+
+// series is the value of a metric for one set of labels
+type series struct {
+	labels string
+	total  Total
+}
+
+// WritePrometheus writes the usage and cost in the Prometheus text format
+func (t *Tracker) WritePrometheus(w io.Writer) {
+	// Aggregate the entries by their labels, dropping tags that are not labels
+	t.mu.Lock()
+	byLabels := make(map[string]*series)
+	for _, e := range t.entries {
+		labels := t.formatLabels(e.model, e.total.Tags)
+		s, ok := byLabels[labels]
+		if !ok {
+			s = &series{labels: labels}
+			byLabels[labels] = s
+		}
+		s.total.add(e.total)
+	}
+	t.mu.Unlock()
+
+	all := make([]*series, 0, len(byLabels))
+	for _, s := range byLabels {
+		all = append(all, s)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].labels < all[j].labels })
+
+	// Models are used either for LLM calls or for embeddings
+	var llm, embeddings []*series
+	for _, s := range all {
+		if s.total.Requests > 0 {
+			llm = append(llm, s)
+		}
+		if s.total.EmbeddingRequests > 0 {
+			embeddings = append(embeddings, s)
+		}
+	}
+
+	fmt.Fprintln(w, "# HELP agent_llm_requests_total LLM calls by model and attribution tags.")
+	fmt.Fprintln(w, "# TYPE agent_llm_requests_total counter")
+	for _, s := range llm {
+		fmt.Fprintf(w, "agent_llm_requests_total{%s} %d\n", s.labels, s.total.Requests)
+	}
+
+	fmt.Fprintln(w, "# HELP agent_llm_tokens_total LLM tokens by model, direction and attribution tags.")
+	fmt.Fprintln(w, "# TYPE agent_llm_tokens_total counter")
+	for _, s := range llm {
+		fmt.Fprintf(w, "agent_llm_tokens_total{%s,direction=\"input\"} %d\n", s.labels, s.total.InputTokens)
+		fmt.Fprintf(w, "agent_llm_tokens_total{%s,direction=\"output\"} %d\n", s.labels, s.total.OutputTokens)
+	}
+
+	fmt.Fprintln(w, "# HELP agent_llm_cost_dollars_total LLM cost in dollars by model and attribution tags.")
+	fmt.Fprintln(w, "# TYPE agent_llm_cost_dollars_total counter")
+	for _, s := range llm {
+		fmt.Fprintf(w, "agent_llm_cost_dollars_total{%s} %s\n", s.labels, strconv.FormatFloat(s.total.Cost-s.total.EmbeddingCost, 'g', -1, 64))
+	}
+
+	fmt.Fprintln(w, "# HELP agent_embedding_requests_total Embedding requests by model and attribution tags.")
+	fmt.Fprintln(w, "# TYPE agent_embedding_requests_total counter")
+	for _, s := range embeddings {
+		fmt.Fprintf(w, "agent_embedding_requests_total{%s} %d\n", s.labels, s.total.EmbeddingRequests)
+	}
+
+	fmt.Fprintln(w, "# HELP agent_embedding_tokens_total Embedding tokens by model and attribution tags.")
+	fmt.Fprintln(w, "# TYPE agent_embedding_tokens_total counter")
+	for _, s := range embeddings {
+		fmt.Fprintf(w, "agent_embedding_tokens_total{%s} %d\n", s.labels, s.total.EmbeddingTokens)
+	}
+
+	fmt.Fprintln(w, "# HELP agent_embedding_cost_dollars_total Embedding cost in dollars by model and attribution tags.")
+	fmt.Fprintln(w, "# TYPE agent_embedding_cost_dollars_total counter")
+	for _, s := range embeddings {
+		fmt.Fprintf(w, "agent_embedding_cost_dollars_total{%s} %s\n", s.labels, strconv.FormatFloat(s.total.EmbeddingCost, 'g', -1, 64))
+	}
+}
+
+// formatLabels formats the model and label tags as Prometheus labels
+func (t *Tracker) formatLabels(model string, tags map[string]string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, `model="%s"`, escapeLabelValue(model))
+	for _, name := range t.labels {
+		fmt.Fprintf(&b, `,%s="%s"`, labelName(name), escapeLabelValue(tags[name]))
+	}
+	return b.String()
+}
+
+// labelName replaces the characters that are not allowed in a Prometheus
+// label name with underscores
+func labelName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+// labelValueEscaper escapes backslashes, quotes and newlines in label values
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// escapeLabelValue escapes a label value for the Prometheus text format
+func escapeLabelValue(value string) string {
+	return labelValueEscaper.Replace(value)
+}
@@ -0,0 +1,161 @@
+package cost
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/run-bigpig/llm-agent/pkg/events"
+	"github.com/run-bigpig/llm-agent/pkg/runctx"
+)
+
+// DefaultFeatureTag is the tag whose value is the feature of billing records
+const DefaultFeatureTag = "feature"
+
+// TypeCostRecorded is the type of the events published by an EventSink
+const TypeCostRecorded = "cost.recorded"
+
+// BillingRecord is the usage and cost of a single LLM call or embedding
+// request, for billing and finance pipelines
+type BillingRecord struct {
+	Time      time.Time `json:"time"`
+	OrgID     string    `json:"org_id,omitempty"`
+	UserID    string    `json:"user_id,omitempty"`
+	RequestID string    `json:"request_id,omitempty"`
+
+	Model        string `json:"model"`
+	InputTokens  int    `json:"input_tokens"`
+	OutputTokens int    `json:"output_tokens"`
+	Embedding    bool   `json:"embedding,omitempty"`
+
+	// CostUSD is the cost in dollars at the tracker's prices
+	CostUSD float64 `json:"cost_usd"`
+
+	// Feature is the value of the feature tag, see WithFeatureTag
+	Feature string `json:"feature,omitempty"`
+
+	// Tags are all attribution tags of the call
+	Tags map[string]string `json:"tags,omitempty"`
+}
+
+// newBillingRecord creates the billing record of a call with the IDs in the
+// context
+func newBillingRecord(ctx context.Context, usage Usage, cost float64, tags map[string]string, featureTag string) BillingRecord {
+	rc := runctx.From(ctx)
+	return BillingRecord{
+		Time:         time.Now(),
+		OrgID:        rc.OrgID,
+		UserID:       rc.UserID,
+		RequestID:    rc.RequestID,
+		Model:        usage.Model,
+		InputTokens:  usage.InputTokens,
+		OutputTokens: usage.OutputTokens,
+		Embedding:    usage.Embedding,
+		CostUSD:      cost,
+		Feature:      tags[featureTag],
+		Tags:         tags,
+	}
+}
+
+// Sink receives a billing record for every call a tracker records. Sinks are
+// called in the path of the call, so sinks that talk to remote systems
+// should queue records and deliver them in the background.
+type Sink interface {
+	Write(ctx context.Context, records ...BillingRecord) error
+}
+
+// SinkFunc adapts a function to a Sink
+type SinkFunc func(ctx context.Context, records ...BillingRecord) error
+
+// Write calls f
+func (f SinkFunc) Write(ctx context.Context, records ...BillingRecord) error {
+	return f(ctx, records...)
+}
+
+// WithSinks sends a billing record of every recorded call to the sinks
+func WithSinks(sinks ...Sink) Option {
+	return func(t *Tracker) {
+		t.sinks = append(t.sinks, sinks...)
+	}
+}
+
+// WithFeatureTag sets the tag that names the feature in billing records.
+// Defaults to DefaultFeatureTag.
+func WithFeatureTag(name string) Option {
+	return func(t *Tracker) {
+		t.featureTag = name
+	}
+}
+
+// JSONSink writes billing records as JSON lines, e.g. to stdout for a log
+// shipper
+type JSONSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewJSONSink creates a sink that writes one JSON object per record to w
+func NewJSONSink(w io.Writer) *JSONSink {
+	return &JSONSink{w: w}
+}
+
+// Write writes the records
+func (s *JSONSink) Write(ctx context.Context, records ...BillingRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	encoder := json.NewEncoder(s.w)
+	for _, record := range records {
+		if err := encoder.Encode(record); err != nil {
+			return fmt.Errorf("failed to write billing record: %w", err)
+		}
+	}
+	return nil
+}
+
+// EventSink publishes billing records as events of type TypeCostRecorded,
+// e.g. to Kafka with an events.KafkaPublisher. Wrap the publisher with
+// events.NewAsyncPublisher so that calls don't wait for the broker.
+type EventSink struct {
+	publisher events.Publisher
+	source    string
+}
+
+// NewEventSink creates a sink that publishes records with the publisher. The
+// source names the service in the events.
+func NewEventSink(publisher events.Publisher, source string) *EventSink {
+	return &EventSink{publisher: publisher, source: source}
+}
+
+// Write publishes the records. The record is the data of each event.
+func (s *EventSink) Write(ctx context.Context, records ...BillingRecord) error {
+	published := make([]events.Event, len(records))
+	for i, record := range records {
+		data, err := recordData(record)
+		if err != nil {
+			return err
+		}
+		event := events.New(ctx, TypeCostRecorded, s.source, record.Model, data)
+		event.Time = record.Time
+		published[i] = event
+	}
+	if err := s.publisher.Publish(ctx, published...); err != nil {
+		return fmt.Errorf("failed to publish billing records: %w", err)
+	}
+	return nil
+}
+
+// recordData returns the fields of a record as event data
+func recordData(record BillingRecord) (map[string]interface{}, error) {
+	encoded, err := json.Marshal(record)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode billing record: %w", err)
+	}
+	var data map[string]interface{}
+	if err := json.Unmarshal(encoded, &data); err != nil {
+		return nil, fmt.Errorf("failed to encode billing record: %w", err)
+	}
+	return data, nil
+}
@@ -0,0 +1,117 @@
+package cost_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/run-bigpig/llm-agent/pkg/cost"
+	"github.com/run-bigpig/llm-agent/pkg/events"
+	"github.com/run-bigpig/llm-agent/pkg/runctx"
+)
+
+func TestBillingRecords(t *testing.T) {
+	var out bytes.Buffer
+	publisher := events.NewMemoryPublisher()
+	tracker := cost.NewTracker(
+		cost.WithPrices(map[string]cost.Price{"gpt-4o": {InputPerMillion: 2.5, OutputPerMillion: 10}}),
+		cost.WithSinks(cost.NewJSONSink(&out), cost.NewEventSink(publisher, "billing-test")),
+	)
+
+	ctx := runctx.WithOrgID(context.Background(), "acme")
+	ctx = runctx.WithUserID(ctx, "user-1")
+	ctx = runctx.WithTags(ctx, map[string]string{"feature": "summaries", "team": "search"})
+	tracker.Record(ctx, cost.Usage{Model: "gpt-4o", InputTokens: 1000, OutputTokens: 100})
+
+	var record cost.BillingRecord
+	if err := json.Unmarshal(out.Bytes(), &record); err != nil {
+		t.Fatalf("expected a JSON line, got %q: %v", out.String(), err)
+	}
+	if record.OrgID != "acme" || record.UserID != "user-1" || record.Model != "gpt-4o" || record.Feature != "summaries" {
+		t.Errorf("unexpected record %+v", record)
+	}
+	if record.InputTokens != 1000 || record.OutputTokens != 100 || math.Abs(record.CostUSD-0.0035) > 1e-9 {
+		t.Errorf("unexpected usage in record %+v", record)
+	}
+	if record.Tags["team"] != "search" || record.Tags[cost.OrgTag] != "acme" {
+		t.Errorf("expected the attribution tags, got %v", record.Tags)
+	}
+
+	published := publisher.Events()
+	if len(published) != 1 || published[0].Type != cost.TypeCostRecorded || published[0].Source != "billing-test" {
+		t.Fatalf("expected a cost event, got %+v", published)
+	}
+	if published[0].Data["model"] != "gpt-4o" || published[0].Data["feature"] != "summaries" {
+		t.Errorf("expected the record as event data, got %v", published[0].Data)
+	}
+}
+
+func TestHTTPSink(t *testing.T) {
+	var mu sync.Mutex
+	var batches [][]cost.BillingRecord
+	fail := true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			t.Errorf("expected the authorization header, got %q", r.Header.Get("Authorization"))
+		}
+		if fail {
+			fail = false
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		var batch []cost.BillingRecord
+		if err := json.NewDecoder(r.Body).Decode(&batch); err != nil {
+			t.Errorf("expected a JSON array: %v", err)
+		}
+		batches = append(batches, batch)
+	}))
+	defer server.Close()
+
+	sink := cost.NewHTTPSink(server.URL,
+		cost.WithSinkHeader("Authorization", "Bearer secret"),
+		cost.WithSinkBatchSize(2),
+		cost.WithSinkFlushInterval(time.Hour),
+	)
+	ctx := context.Background()
+	write := func(model string) {
+		if err := sink.Write(ctx, cost.BillingRecord{Model: model}); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+	}
+
+	// The first full batch fails and is kept for the next one
+	write("a")
+	write("b")
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(time.Millisecond) {
+		mu.Lock()
+		failed := !fail
+		mu.Unlock()
+		if failed || time.Now().After(deadline) {
+			break
+		}
+	}
+	write("c")
+	if err := sink.Close(ctx); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	var models []string
+	for _, batch := range batches {
+		for _, record := range batch {
+			models = append(models, record.Model)
+		}
+	}
+	if len(models) != 3 || models[0] != "a" || models[2] != "c" {
+		t.Errorf("expected all records in order, got %v in %d batches", models, len(batches))
+	}
+}
@@ -0,0 +1,357 @@
+package crypto
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/run-bigpig/llm-agent/pkg/multitenancy"
+)
+
+// encryptedPrefix marks strings produced by EncryptString
+const encryptedPrefix = "enc:v1:"
+
+// ErrNotEncrypted is returned when decrypting data that was not produced by the Encryptor
+var ErrNotEncrypted = errors.New("data is not encrypted")
+
+// ErrKeyMismatch is returned when decrypting data that was encrypted with a
+// key other than the one selected for the context, e.g. another tenant's
+var ErrKeyMismatch = errors.New("data was encrypted with another key")
+
+// KeyManager generates and unwraps data encryption keys. Implementations
+// typically delegate to a key management service such as AWS KMS, Google
+// Cloud KMS or HashiCorp Vault Transit.
+type KeyManager interface {
+	// GenerateDataKey returns a new data key in plaintext and wrapped with the key identified by keyID
+	GenerateDataKey(ctx context.Context, keyID string) (plaintext []byte, wrapped []byte, err error)
+
+	// DecryptDataKey unwraps a data key that was wrapped with the key identified by keyID
+	DecryptDataKey(ctx context.Context, keyID string, wrapped []byte) ([]byte, error)
+}
+
+// LocalKeyManager implements KeyManager with a master key held in process.
+// A distinct key-encryption key is derived from the master key for every key
+// ID, so tenants using different key IDs never share a key.
+type LocalKeyManager struct {
+	masterKey []byte
+}
+
+// NewLocalKeyManager creates a new local key manager from a master key of at least 32 bytes
+func NewLocalKeyManager(masterKey []byte) (*LocalKeyManager, error) {
+	if len(masterKey) < 32 {
+		return nil, fmt.Errorf("master key must be at least 32 bytes, got %d", len(masterKey))
+	}
+
+	return &LocalKeyManager{
+		masterKey: masterKey,
+	}, nil
+}
+
+// GenerateDataKey returns a new data key in plaintext and wrapped with the derived key for keyID
+func (m *LocalKeyManager) GenerateDataKey(ctx context.Context, keyID string) ([]byte, []byte, error) {
+	plaintext := make([]byte, 32)
+	if _, err := rand.Read(plaintext); err != nil {
+		return nil, nil, fmt.Errorf("failed to generate data key: %w", err)
+	}
+
+	wrapped, err := seal(m.deriveKey(keyID), plaintext, []byte(keyID))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to wrap data key: %w", err)
+	}
+
+	return plaintext, wrapped, nil
+}
+
+// DecryptDataKey unwraps a data key that was wrapped with the derived key for keyID
+func (m *LocalKeyManager) DecryptDataKey(ctx context.Context, keyID string, wrapped []byte) ([]byte, error) {
+	plaintext, err := open(m.deriveKey(keyID), wrapped, []byte(keyID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap data key: %w", err)
+	}
+	return plaintext, nil
+}
+
+// deriveKey derives the key-encryption key for a key ID
+func (m *LocalKeyManager) deriveKey(keyID string) []byte {
+	mac := hmac.New(sha256.New, m.masterKey)
+	mac.Write([]byte(keyID))
+	return mac.Sum(nil)
+}
+
+// envelope is the serialized form of encrypted data
+type envelope struct {
+	KeyID      string `json:"kid"`
+	WrappedKey []byte `json:"wk"`
+	Ciphertext []byte `json:"ct"`
+}
+
+// cachedKey is a data key kept in memory to avoid a KMS round trip per operation
+type cachedKey struct {
+	plaintext []byte
+	wrapped   []byte
+	expiresAt time.Time
+}
+
+// Encryptor encrypts data with envelope encryption: each payload is encrypted
+// with a data key, and the data key is stored alongside it wrapped by the KMS
+type Encryptor struct {
+	keyManager  KeyManager
+	keyResolver func(ctx context.Context) string
+	dataKeyTTL  time.Duration
+
+	mu          sync.Mutex
+	encryptKeys map[string]cachedKey
+	decryptKeys map[string]cachedKey
+}
+
+// Option represents an option for configuring the Encryptor
+type Option func(*Encryptor)
+
+// WithKeyID encrypts everything with a single key ID
+func WithKeyID(keyID string) Option {
+	return func(e *Encryptor) {
+		e.keyResolver = func(ctx context.Context) string {
+			return keyID
+		}
+	}
+}
+
+// WithTenantKeys encrypts data for each organization with its own key ID,
+// formed by appending the organization ID from the context to prefix.
+// Contexts without an organization use prefix + "default".
+func WithTenantKeys(prefix string) Option {
+	return func(e *Encryptor) {
+		e.keyResolver = func(ctx context.Context) string {
+			orgID, err := multitenancy.GetOrgID(ctx)
+			if err != nil {
+				orgID = "default"
+			}
+			return prefix + orgID
+		}
+	}
+}
+
+// WithKeyResolver sets a custom function that selects the key ID for a context
+func WithKeyResolver(resolver func(ctx context.Context) string) Option {
+	return func(e *Encryptor) {
+		e.keyResolver = resolver
+	}
+}
+
+// WithDataKeyTTL sets how long a data key is reused before a new one is generated.
+// A zero TTL generates a new data key for every encryption.
+func WithDataKeyTTL(ttl time.Duration) Option {
+	return func(e *Encryptor) {
+		e.dataKeyTTL = ttl
+	}
+}
+
+// NewEncryptor creates a new envelope Encryptor. By default every organization
+// gets its own key ID ("tenant/<orgID>") and data keys are reused for 5 minutes.
+func NewEncryptor(keyManager KeyManager, options ...Option) *Encryptor {
+	encryptor := &Encryptor{
+		keyManager:  keyManager,
+		dataKeyTTL:  5 * time.Minute,
+		encryptKeys: make(map[string]cachedKey),
+		decryptKeys: make(map[string]cachedKey),
+	}
+	WithTenantKeys("tenant/")(encryptor)
+
+	for _, option := range options {
+		option(encryptor)
+	}
+
+	return encryptor
+}
+
+// Encrypt encrypts plaintext with the key for the context
+func (e *Encryptor) Encrypt(ctx context.Context, plaintext []byte) ([]byte, error) {
+	keyID := e.keyResolver(ctx)
+
+	dataKey, wrapped, err := e.dataKey(ctx, keyID)
+	if err != nil {
+		return nil, err
+	}
+
+	ciphertext, err := seal(dataKey, plaintext, []byte(keyID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt data: %w", err)
+	}
+
+	data, err := json.Marshal(envelope{
+		KeyID:      keyID,
+		WrappedKey: wrapped,
+		Ciphertext: ciphertext,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal envelope: %w", err)
+	}
+
+	return data, nil
+}
+
+// Decrypt decrypts data produced by Encrypt. Data encrypted with another key
+// than the one selected for the context is rejected with ErrKeyMismatch, so
+// that one tenant can't decrypt another tenant's data.
+func (e *Encryptor) Decrypt(ctx context.Context, data []byte) ([]byte, error) {
+	var env envelope
+	if err := json.Unmarshal(data, &env); err != nil || env.KeyID == "" {
+		return nil, ErrNotEncrypted
+	}
+	if env.KeyID != e.keyResolver(ctx) {
+		return nil, ErrKeyMismatch
+	}
+
+	dataKey, err := e.unwrapKey(ctx, env.KeyID, env.WrappedKey)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := open(dataKey, env.Ciphertext, []byte(env.KeyID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt data: %w", err)
+	}
+
+	return plaintext, nil
+}
+
+// EncryptString encrypts a string and returns a printable representation
+// that can be stored in place of the original value
+func (e *Encryptor) EncryptString(ctx context.Context, plaintext string) (string, error) {
+	data, err := e.Encrypt(ctx, []byte(plaintext))
+	if err != nil {
+		return "", err
+	}
+	return encryptedPrefix + base64.StdEncoding.EncodeToString(data), nil
+}
+
+// DecryptString decrypts a string produced by EncryptString
+func (e *Encryptor) DecryptString(ctx context.Context, value string) (string, error) {
+	if !IsEncrypted(value) {
+		return "", ErrNotEncrypted
+	}
+
+	data, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, encryptedPrefix))
+	if err != nil {
+		return "", fmt.Errorf("failed to decode encrypted value: %w", err)
+	}
+
+	plaintext, err := e.Decrypt(ctx, data)
+	if err != nil {
+		return "", err
+	}
+
+	return string(plaintext), nil
+}
+
+// IsEncrypted reports whether the string was produced by EncryptString
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, encryptedPrefix)
+}
+
+// dataKey returns a data key for encryption, reusing a cached key while it is valid
+func (e *Encryptor) dataKey(ctx context.Context, keyID string) ([]byte, []byte, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if cached, ok := e.encryptKeys[keyID]; ok && time.Now().Before(cached.expiresAt) {
+		return cached.plaintext, cached.wrapped, nil
+	}
+
+	plaintext, wrapped, err := e.keyManager.GenerateDataKey(ctx, keyID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate data key: %w", err)
+	}
+
+	if e.dataKeyTTL > 0 {
+		e.encryptKeys[keyID] = cachedKey{
+			plaintext: plaintext,
+			wrapped:   wrapped,
+			expiresAt: time.Now().Add(e.dataKeyTTL),
+		}
+	}
+
+	return plaintext, wrapped, nil
+}
+
+// unwrapKey returns the plaintext of a wrapped data key, caching unwrapped keys
+func (e *Encryptor) unwrapKey(ctx context.Context, keyID string, wrapped []byte) ([]byte, error) {
+	cacheKey := keyID + ":" + string(wrapped)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if cached, ok := e.decryptKeys[cacheKey]; ok && time.Now().Before(cached.expiresAt) {
+		return cached.plaintext, nil
+	}
+
+	plaintext, err := e.keyManager.DecryptDataKey(ctx, keyID, wrapped)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt data key: %w", err)
+	}
+
+	if e.dataKeyTTL > 0 {
+		// Drop expired entries so the cache does not grow without bound
+		now := time.Now()
+		for k, cached := range e.decryptKeys {
+			if now.After(cached.expiresAt) {
+				delete(e.decryptKeys, k)
+			}
+		}
+		e.decryptKeys[cacheKey] = cachedKey{
+			plaintext: plaintext,
+			expiresAt: now.Add(e.dataKeyTTL),
+		}
+	}
+
+	return plaintext, nil
+}
+
+// seal encrypts plaintext with AES-GCM, prepending the nonce to the ciphertext
+func seal(key, plaintext, additionalData []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	return gcm.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+// open decrypts data produced by seal
+func open(key, data, additionalData []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) < gcm.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+
+	nonce, ciphertext := data[:gcm.NonceSize()], data[gcm.NonceSize():]
+	return gcm.Open(nil, nonce, ciphertext, additionalData)
+}
+
+// newGCM creates an AES-GCM cipher for the key
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,73 @@
+package crypto_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/run-bigpig/llm-agent/pkg/crypto"
+	"github.com/run-bigpig/llm-agent/pkg/multitenancy"
+)
+
+func TestEncryptorRoundTrip(t *testing.T) {
+	keyManager, err := crypto.NewLocalKeyManager(bytes.Repeat([]byte("k"), 32))
+	if err != nil {
+		t.Fatalf("Failed to create key manager: %v", err)
+	}
+	encryptor := crypto.NewEncryptor(keyManager)
+
+	ctx := multitenancy.WithOrgID(context.Background(), "org1")
+
+	encrypted, err := encryptor.EncryptString(ctx, "secret message")
+	if err != nil {
+		t.Fatalf("Failed to encrypt: %v", err)
+	}
+	if !crypto.IsEncrypted(encrypted) {
+		t.Errorf("Expected encrypted value to carry the encryption prefix, got %s", encrypted)
+	}
+
+	decrypted, err := encryptor.DecryptString(ctx, encrypted)
+	if err != nil {
+		t.Fatalf("Failed to decrypt: %v", err)
+	}
+	if decrypted != "secret message" {
+		t.Errorf("Expected 'secret message', got '%s'", decrypted)
+	}
+
+	if _, err := encryptor.DecryptString(ctx, "plain text"); err != crypto.ErrNotEncrypted {
+		t.Errorf("Expected ErrNotEncrypted, got %v", err)
+	}
+}
+
+func TestEncryptorTenantIsolation(t *testing.T) {
+	keyManager, err := crypto.NewLocalKeyManager(bytes.Repeat([]byte("k"), 32))
+	if err != nil {
+		t.Fatalf("Failed to create key manager: %v", err)
+	}
+	encryptor := crypto.NewEncryptor(keyManager)
+
+	data, err := encryptor.Encrypt(multitenancy.WithOrgID(context.Background(), "org1"), []byte("org1 data"))
+	if err != nil {
+		t.Fatalf("Failed to encrypt: %v", err)
+	}
+
+	// Another tenant can't decrypt the data
+	org2 := multitenancy.WithOrgID(context.Background(), "org2")
+	if _, err := encryptor.Decrypt(org2, data); !errors.Is(err, crypto.ErrKeyMismatch) {
+		t.Errorf("Expected ErrKeyMismatch for another tenant, got %v", err)
+	}
+	if _, err := encryptor.Decrypt(context.Background(), data); !errors.Is(err, crypto.ErrKeyMismatch) {
+		t.Errorf("Expected ErrKeyMismatch without a tenant, got %v", err)
+	}
+
+	// Tampering with the key ID must not let another tenant's key decrypt the data
+	tampered := bytes.Replace(data, []byte(`"tenant/org1"`), []byte(`"tenant/org2"`), 1)
+	if _, err := encryptor.Decrypt(org2, tampered); err == nil {
+		t.Error("Expected decryption with another tenant's key to fail")
+	}
+
+	if _, err := crypto.NewLocalKeyManager([]byte("short")); err == nil {
+		t.Error("Expected error for short master key")
+	}
+}
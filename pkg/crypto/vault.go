@@ -0,0 +1,153 @@
+package crypto
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// VaultKeyManager implements KeyManager with the transit secrets engine of
+// HashiCorp Vault, so that key-encryption keys never leave Vault. Key IDs map
+// to transit key names with "/" replaced by "-", e.g. "tenant/org-1" uses the
+// key "tenant-org-1", which must exist.
+type VaultKeyManager struct {
+	address    string
+	token      string
+	mount      string
+	namespace  string
+	httpClient *http.Client
+}
+
+// VaultOption represents an option for configuring the VaultKeyManager
+type VaultOption func(*VaultKeyManager)
+
+// WithVaultMount sets the path the transit engine is mounted at. The default is "transit".
+func WithVaultMount(mount string) VaultOption {
+	return func(m *VaultKeyManager) {
+		m.mount = strings.Trim(mount, "/")
+	}
+}
+
+// WithVaultNamespace sets the Vault Enterprise namespace of the requests
+func WithVaultNamespace(namespace string) VaultOption {
+	return func(m *VaultKeyManager) {
+		m.namespace = namespace
+	}
+}
+
+// WithVaultHTTPClient sets the HTTP client used to call Vault
+func WithVaultHTTPClient(client *http.Client) VaultOption {
+	return func(m *VaultKeyManager) {
+		m.httpClient = client
+	}
+}
+
+// NewVaultKeyManager creates a key manager for the Vault server at address,
+// e.g. "https://vault.example.com:8200", authenticating with token
+func NewVaultKeyManager(address, token string, options ...VaultOption) *VaultKeyManager {
+	manager := &VaultKeyManager{
+		address:    strings.TrimRight(address, "/"),
+		token:      token,
+		mount:      "transit",
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+
+	for _, option := range options {
+		option(manager)
+	}
+
+	return manager
+}
+
+// vaultKeyData is the data of transit datakey and decrypt responses
+type vaultKeyData struct {
+	Plaintext  string `json:"plaintext"`
+	Ciphertext string `json:"ciphertext"`
+}
+
+// GenerateDataKey asks Vault for a new 256-bit data key wrapped with the transit key for keyID
+func (m *VaultKeyManager) GenerateDataKey(ctx context.Context, keyID string) ([]byte, []byte, error) {
+	data, err := m.call(ctx, "datakey/plaintext/"+vaultKeyName(keyID), map[string]interface{}{"bits": 256})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate data key: %w", err)
+	}
+
+	plaintext, err := base64.StdEncoding.DecodeString(data.Plaintext)
+	if err != nil || data.Ciphertext == "" {
+		return nil, nil, fmt.Errorf("invalid data key in Vault response")
+	}
+
+	return plaintext, []byte(data.Ciphertext), nil
+}
+
+// DecryptDataKey asks Vault to unwrap a data key with the transit key for keyID
+func (m *VaultKeyManager) DecryptDataKey(ctx context.Context, keyID string, wrapped []byte) ([]byte, error) {
+	data, err := m.call(ctx, "decrypt/"+vaultKeyName(keyID), map[string]interface{}{"ciphertext": string(wrapped)})
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap data key: %w", err)
+	}
+
+	plaintext, err := base64.StdEncoding.DecodeString(data.Plaintext)
+	if err != nil {
+		return nil, fmt.Errorf("invalid data key in Vault response: %w", err)
+	}
+	return plaintext, nil
+}
+
+// call sends a request to an endpoint of the transit engine
+func (m *VaultKeyManager) call(ctx context.Context, endpoint string, body map[string]interface{}) (*vaultKeyData, error) {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	url := fmt.Sprintf("%s/v1/%s/%s", m.address, m.mount, endpoint)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Vault-Token", m.token)
+	if m.namespace != "" {
+		req.Header.Set("X-Vault-Namespace", m.namespace)
+	}
+
+	resp, err := m.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call Vault: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Vault response: %w", err)
+	}
+
+	var result struct {
+		Data   vaultKeyData `json:"data"`
+		Errors []string     `json:"errors"`
+	}
+	if err := json.Unmarshal(respBody, &result); err != nil && resp.StatusCode == http.StatusOK {
+		return nil, fmt.Errorf("failed to unmarshal Vault response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		if len(result.Errors) > 0 {
+			return nil, fmt.Errorf("vault returned %d: %s", resp.StatusCode, strings.Join(result.Errors, "; "))
+		}
+		return nil, fmt.Errorf("vault returned %d", resp.StatusCode)
+	}
+
+	return &result.Data, nil
+}
+
+// vaultKeyName returns the transit key name for a key ID; transit key names
+// can't contain "/"
+func vaultKeyName(keyID string) string {
+	return strings.ReplaceAll(keyID, "/", "-")
+}
@@ -0,0 +1,94 @@
+package crypto_test
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/run-bigpig/llm-agent/pkg/crypto"
+	"github.com/run-bigpig/llm-agent/pkg/multitenancy"
+)
+
+// fakeVault implements the datakey and decrypt endpoints of the transit engine
+type fakeVault struct {
+	mu   sync.Mutex
+	keys map[string]string // ciphertext -> key name + plaintext
+}
+
+func (v *fakeVault) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("X-Vault-Token") != "vault-token" {
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(`{"errors": ["permission denied"]}`))
+		return
+	}
+
+	var body map[string]interface{}
+	_ = json.NewDecoder(r.Body).Decode(&body)
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	switch {
+	case strings.HasPrefix(r.URL.Path, "/v1/transit/datakey/plaintext/"):
+		name := strings.TrimPrefix(r.URL.Path, "/v1/transit/datakey/plaintext/")
+		key := make([]byte, 32)
+		_, _ = rand.Read(key)
+		plaintext := base64.StdEncoding.EncodeToString(key)
+		ciphertext := fmt.Sprintf("vault:v1:%d", len(v.keys))
+		v.keys[ciphertext] = name + ":" + plaintext
+		_, _ = fmt.Fprintf(w, `{"data": {"plaintext": %q, "ciphertext": %q}}`, plaintext, ciphertext)
+	case strings.HasPrefix(r.URL.Path, "/v1/transit/decrypt/"):
+		name := strings.TrimPrefix(r.URL.Path, "/v1/transit/decrypt/")
+		stored, ok := v.keys[body["ciphertext"].(string)]
+		if !ok || !strings.HasPrefix(stored, name+":") {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"errors": ["cipher: message authentication failed"]}`))
+			return
+		}
+		_, _ = fmt.Fprintf(w, `{"data": {"plaintext": %q}}`, strings.TrimPrefix(stored, name+":"))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestVaultKeyManager(t *testing.T) {
+	vault := &fakeVault{keys: make(map[string]string)}
+	server := httptest.NewServer(vault)
+	defer server.Close()
+
+	encryptor := crypto.NewEncryptor(crypto.NewVaultKeyManager(server.URL, "vault-token"))
+	ctx := multitenancy.WithOrgID(context.Background(), "org1")
+
+	encrypted, err := encryptor.EncryptString(ctx, "secret message")
+	if err != nil {
+		t.Fatalf("Failed to encrypt: %v", err)
+	}
+	if _, ok := vault.keys["vault:v1:0"]; !ok || !strings.HasPrefix(vault.keys["vault:v1:0"], "tenant-org1:") {
+		t.Errorf("Expected a data key from the transit key tenant-org1, got %v", vault.keys)
+	}
+
+	// A new encryptor has no cached keys, so the data key is unwrapped by Vault
+	decrypted, err := crypto.NewEncryptor(crypto.NewVaultKeyManager(server.URL, "vault-token")).DecryptString(ctx, encrypted)
+	if err != nil {
+		t.Fatalf("Failed to decrypt: %v", err)
+	}
+	if decrypted != "secret message" {
+		t.Errorf("Expected 'secret message', got '%s'", decrypted)
+	}
+
+	// Vault errors are returned
+	denied := crypto.NewEncryptor(crypto.NewVaultKeyManager(server.URL, "wrong-token"))
+	if _, err := denied.EncryptString(ctx, "secret message"); err == nil || !strings.Contains(err.Error(), "permission denied") {
+		t.Errorf("Expected a permission error, got %v", err)
+	}
+	if _, err := denied.DecryptString(ctx, encrypted); err == nil {
+		t.Error("Expected decryption without access to the transit key to fail")
+	}
+}
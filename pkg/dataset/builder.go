@@ -0,0 +1,172 @@
+package dataset
+
+import (
+	"sort"
+	"time"
+
+	"github.com/run-bigpig/llm-agent/pkg/feedback"
+)
+
+// Builder joins runs with their feedback and selects the examples for a
+// dataset
+type Builder struct {
+	minScore        *float64
+	maxScore        *float64
+	requireFeedback bool
+	tools           []string
+	noTools         bool
+	since           time.Time
+	until           time.Time
+	includeFailed   bool
+}
+
+// Option configures a Builder
+type Option func(*Builder)
+
+// WithMinScore keeps runs whose average rating is at least score, e.g. 1
+// for runs only rated thumbs up. Unrated runs are dropped.
+func WithMinScore(score float64) Option {
+	return func(b *Builder) {
+		b.minScore = &score
+	}
+}
+
+// WithMaxScore keeps runs whose average rating is at most score, e.g. -1
+// for runs only rated thumbs down. Unrated runs are dropped.
+func WithMaxScore(score float64) Option {
+	return func(b *Builder) {
+		b.maxScore = &score
+	}
+}
+
+// WithFeedbackOnly drops runs without any feedback
+func WithFeedbackOnly() Option {
+	return func(b *Builder) {
+		b.requireFeedback = true
+	}
+}
+
+// WithTools keeps runs that called at least one of the tools
+func WithTools(names ...string) Option {
+	return func(b *Builder) {
+		b.tools = append(b.tools, names...)
+	}
+}
+
+// WithoutToolCalls keeps runs that called no tools
+func WithoutToolCalls() Option {
+	return func(b *Builder) {
+		b.noTools = true
+	}
+}
+
+// WithDateRange keeps runs started in [since, until). A zero time leaves that
+// end of the range open.
+func WithDateRange(since, until time.Time) Option {
+	return func(b *Builder) {
+		b.since = since
+		b.until = until
+	}
+}
+
+// WithFailedRuns keeps runs that ended with an error, which are dropped by
+// default
+func WithFailedRuns() Option {
+	return func(b *Builder) {
+		b.includeFailed = true
+	}
+}
+
+// NewBuilder creates a dataset builder
+func NewBuilder(options ...Option) *Builder {
+	b := &Builder{}
+	for _, option := range options {
+		option(b)
+	}
+	return b
+}
+
+// Build matches feedback to runs and returns the examples that pass the
+// filters, in the order of the runs. Feedback matches a run by request ID,
+// or by trace ID when one of them has no request ID.
+func (b *Builder) Build(runs []Run, feedbacks []feedback.Feedback) []Example {
+	byRequest := make(map[string][]feedback.Feedback)
+	byTrace := make(map[string][]feedback.Feedback)
+	for _, f := range feedbacks {
+		if f.RequestID != "" {
+			byRequest[f.RequestID] = append(byRequest[f.RequestID], f)
+		}
+		if f.TraceID != "" {
+			byTrace[f.TraceID] = append(byTrace[f.TraceID], f)
+		}
+	}
+
+	var examples []Example
+	for _, run := range runs {
+		example := Example{Run: run}
+		if run.RequestID != "" {
+			example.Feedback = append(example.Feedback, byRequest[run.RequestID]...)
+		}
+		if run.TraceID != "" {
+			for _, f := range byTrace[run.TraceID] {
+				if f.RequestID == "" || run.RequestID == "" {
+					example.Feedback = append(example.Feedback, f)
+				}
+			}
+		}
+		sort.SliceStable(example.Feedback, func(i, j int) bool {
+			return example.Feedback[i].CreatedAt.Before(example.Feedback[j].CreatedAt)
+		})
+		if b.keep(example) {
+			examples = append(examples, example)
+		}
+	}
+	return examples
+}
+
+// keep reports whether an example passes the filters
+func (b *Builder) keep(e Example) bool {
+	if e.Error != "" && !b.includeFailed {
+		return false
+	}
+	if !b.since.IsZero() && e.StartedAt.Before(b.since) {
+		return false
+	}
+	if !b.until.IsZero() && !e.StartedAt.Before(b.until) {
+		return false
+	}
+	if b.requireFeedback && len(e.Feedback) == 0 {
+		return false
+	}
+	if b.noTools && len(e.Tools) > 0 {
+		return false
+	}
+	if len(b.tools) > 0 && !usesAny(e.Tools, b.tools) {
+		return false
+	}
+	if b.minScore != nil || b.maxScore != nil {
+		score, ok := e.Score()
+		if !ok {
+			return false
+		}
+		if b.minScore != nil && score < *b.minScore {
+			return false
+		}
+		if b.maxScore != nil && score > *b.maxScore {
+			return false
+		}
+	}
+	return true
+}
+
+// usesAny reports whether any of the wanted tools was used
+func usesAny(used, wanted []string) bool {
+	for _, u := range used {
+		for _, w := range wanted {
+			if u == w {
+				return true
+			}
+		}
+	}
+	return false
+}
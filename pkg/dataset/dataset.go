@@ -0,0 +1,123 @@
+// Package dataset turns recorded agent runs and the feedback on them into
+// JSONL datasets, either for evaluating agents or for fine-tuning models in
+// the OpenAI chat format.
+package dataset
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/run-bigpig/llm-agent/pkg/debug"
+	"github.com/run-bigpig/llm-agent/pkg/feedback"
+)
+
+// Run is a recorded agent run
+type Run struct {
+	RequestID    string    `json:"request_id,omitempty"`
+	TraceID      string    `json:"trace_id,omitempty"`
+	Agent        string    `json:"agent,omitempty"`
+	SystemPrompt string    `json:"system_prompt,omitempty"`
+	Input        string    `json:"input"`
+	Output       string    `json:"output"`
+	Error        string    `json:"error,omitempty"`
+	Tools        []string  `json:"tools,omitempty"`
+	StartedAt    time.Time `json:"started_at"`
+}
+
+// FromTranscript converts a debug transcript into a run. The system prompt
+// is the one sent with the first LLM call.
+func FromTranscript(t *debug.Transcript) Run {
+	run := Run{
+		RequestID: t.RequestID,
+		TraceID:   t.TraceID,
+		Agent:     t.Agent,
+		Input:     t.Input,
+		Output:    t.Output,
+		Error:     t.Error,
+		StartedAt: t.StartedAt,
+	}
+	for _, entry := range t.Entries {
+		switch entry.Type {
+		case debug.EntryToolCall:
+			run.Tools = append(run.Tools, entry.Name)
+		case debug.EntryLLMCall:
+			if prompt, ok := entry.Metadata["system_prompt"].(string); ok && run.SystemPrompt == "" {
+				run.SystemPrompt = prompt
+			}
+		}
+	}
+	return run
+}
+
+// FromTranscripts converts debug transcripts into runs
+func FromTranscripts(transcripts []*debug.Transcript) []Run {
+	runs := make([]Run, len(transcripts))
+	for i, t := range transcripts {
+		runs[i] = FromTranscript(t)
+	}
+	return runs
+}
+
+// ReadTranscripts reads the transcripts a debug.Recorder wrote with
+// debug.FormatJSON
+func ReadTranscripts(r io.Reader) ([]*debug.Transcript, error) {
+	var transcripts []*debug.Transcript
+	decoder := json.NewDecoder(r)
+	for {
+		t := &debug.Transcript{}
+		err := decoder.Decode(t)
+		if errors.Is(err, io.EOF) {
+			return transcripts, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read transcript %d: %w", len(transcripts)+1, err)
+		}
+		transcripts = append(transcripts, t)
+	}
+}
+
+// Example is a run with the feedback given on it
+type Example struct {
+	Run
+
+	// Feedback is the feedback on the run, oldest first
+	Feedback []feedback.Feedback `json:"feedback,omitempty"`
+}
+
+// Score is the average rating of the run: 1 if all feedback was thumbs up
+// and -1 if all was thumbs down. It returns false for runs without ratings.
+func (e Example) Score() (float64, bool) {
+	total, count := 0.0, 0
+	for _, f := range e.Feedback {
+		if f.Rating != "" {
+			total += f.Rating.Value()
+			count++
+		}
+	}
+	if count == 0 {
+		return 0, false
+	}
+	return total / float64(count), true
+}
+
+// Correction returns the most recent correction of the run's output
+func (e Example) Correction() string {
+	for i := len(e.Feedback) - 1; i >= 0; i-- {
+		if e.Feedback[i].Correction != "" {
+			return e.Feedback[i].Correction
+		}
+	}
+	return ""
+}
+
+// Target returns the output the model should have given: the correction if
+// there is one, and otherwise the recorded output
+func (e Example) Target() string {
+	if correction := e.Correction(); correction != "" {
+		return correction
+	}
+	return e.Output
+}
@@ -0,0 +1,105 @@
+package dataset_test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/run-bigpig/llm-agent/pkg/dataset"
+	"github.com/run-bigpig/llm-agent/pkg/debug"
+	"github.com/run-bigpig/llm-agent/pkg/feedback"
+)
+
+// recordRun records a transcript the way the agent does
+func recordRun(recorder *debug.Recorder, requestID, input, output string, tools ...string) {
+	t := recorder.Start("support", input)
+	t.Add(debug.Entry{Type: debug.EntryLLMCall, Name: "mock", Metadata: map[string]interface{}{"system_prompt": "You are helpful."}})
+	for _, tool := range tools {
+		t.Add(debug.Entry{Type: debug.EntryToolCall, Name: tool})
+	}
+	t.SetIDs(requestID, "trace-"+requestID)
+	recorder.Finish(t, output, nil)
+}
+
+func TestBuildAndExport(t *testing.T) {
+	var log bytes.Buffer
+	recorder := debug.NewRecorder(debug.WithOutput(&log, debug.FormatJSON))
+	recordRun(recorder, "req-1", "What's the weather in Paris?", "Sunny", "weather")
+	recordRun(recorder, "req-2", "What's 2+2?", "5", "calculator")
+	recordRun(recorder, "req-3", "Hello", "Hi!")
+
+	transcripts, err := dataset.ReadTranscripts(&log)
+	require.NoError(t, err)
+	runs := dataset.FromTranscripts(transcripts)
+	require.Len(t, runs, 3)
+	assert.Equal(t, "You are helpful.", runs[0].SystemPrompt)
+	assert.Equal(t, []string{"weather"}, runs[0].Tools)
+
+	now := time.Now()
+	feedbacks := []feedback.Feedback{
+		{RequestID: "req-1", Rating: feedback.RatingUp, CreatedAt: now},
+		{TraceID: "trace-req-2", Rating: feedback.RatingDown, CreatedAt: now},
+		{RequestID: "req-2", Correction: "4", Rating: feedback.RatingDown, CreatedAt: now.Add(time.Second)},
+		{RequestID: "req-9", Rating: feedback.RatingUp, CreatedAt: now},
+	}
+
+	// Feedback is matched by request ID, or by trace ID without one
+	all := dataset.NewBuilder().Build(runs, feedbacks)
+	require.Len(t, all, 3)
+	assert.Len(t, all[1].Feedback, 2)
+	assert.Equal(t, "4", all[1].Target())
+	assert.Empty(t, all[2].Feedback)
+
+	good := dataset.NewBuilder(dataset.WithMinScore(1)).Build(runs, feedbacks)
+	require.Len(t, good, 1)
+	assert.Equal(t, "req-1", good[0].RequestID)
+
+	bad := dataset.NewBuilder(dataset.WithMaxScore(-1), dataset.WithTools("calculator")).Build(runs, feedbacks)
+	require.Len(t, bad, 1)
+	assert.Equal(t, "req-2", bad[0].RequestID)
+
+	assert.Len(t, dataset.NewBuilder(dataset.WithoutToolCalls()).Build(runs, feedbacks), 1)
+	assert.Len(t, dataset.NewBuilder(dataset.WithFeedbackOnly()).Build(runs, feedbacks), 2)
+	assert.Empty(t, dataset.NewBuilder(dataset.WithDateRange(now.Add(time.Hour), time.Time{})).Build(runs, feedbacks))
+
+	// Fine-tuning data uses the correction as the answer
+	var chat bytes.Buffer
+	require.NoError(t, dataset.Export(&chat, all, dataset.FormatOpenAIChat))
+	lines := readLines(t, &chat)
+	require.Len(t, lines, 3)
+	var example dataset.ChatExample
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &example))
+	assert.Equal(t, []dataset.ChatMessage{
+		{Role: "system", Content: "You are helpful."},
+		{Role: "user", Content: "What's 2+2?"},
+		{Role: "assistant", Content: "4"},
+	}, example.Messages)
+
+	var eval bytes.Buffer
+	require.NoError(t, dataset.Export(&eval, all, dataset.FormatEval))
+	lines = readLines(t, &eval)
+	var evalCase dataset.EvalCase
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &evalCase))
+	assert.Equal(t, "4", evalCase.ExpectedOutput)
+	assert.Equal(t, "5", evalCase.Output)
+	require.NotNil(t, evalCase.Score)
+	assert.Equal(t, -1.0, *evalCase.Score)
+	assert.Equal(t, "req-2", evalCase.Metadata["request_id"])
+
+	assert.Error(t, dataset.Export(&eval, all, "csv"))
+}
+
+func readLines(t *testing.T, buf *bytes.Buffer) []string {
+	var lines []string
+	scanner := bufio.NewScanner(buf)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	require.NoError(t, scanner.Err())
+	return lines
+}
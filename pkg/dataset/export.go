@@ -0,0 +1,98 @@
+package dataset
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// Format is the format of an exported dataset
+type Format string
+
+const (
+	// FormatEval writes one evaluation case per line, with the input, the
+	// expected output and the recorded output, score and tools
+	FormatEval Format = "eval"
+
+	// FormatOpenAIChat writes one chat conversation per line in the OpenAI
+	// fine-tuning format, with the correction as the assistant message when
+	// there is one
+	FormatOpenAIChat Format = "openai_chat"
+)
+
+// EvalCase is a line of a FormatEval dataset
+type EvalCase struct {
+	Input          string                 `json:"input"`
+	ExpectedOutput string                 `json:"expected_output"`
+	Output         string                 `json:"output"`
+	Score          *float64               `json:"score,omitempty"`
+	Tools          []string               `json:"tools,omitempty"`
+	Metadata       map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// ChatMessage is a message of a FormatOpenAIChat line
+type ChatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// ChatExample is a line of a FormatOpenAIChat dataset
+type ChatExample struct {
+	Messages []ChatMessage `json:"messages"`
+}
+
+// Export writes the examples as JSONL in the given format
+func Export(w io.Writer, examples []Example, format Format) error {
+	encoder := json.NewEncoder(w)
+	for i, example := range examples {
+		var line interface{}
+		switch format {
+		case FormatEval:
+			line = evalCase(example)
+		case FormatOpenAIChat:
+			line = chatExample(example)
+		default:
+			return fmt.Errorf("unknown dataset format %q", format)
+		}
+		if err := encoder.Encode(line); err != nil {
+			return fmt.Errorf("failed to write example %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+// evalCase converts an example into an evaluation case
+func evalCase(e Example) EvalCase {
+	c := EvalCase{
+		Input:          e.Input,
+		ExpectedOutput: e.Target(),
+		Output:         e.Output,
+		Tools:          e.Tools,
+		Metadata:       map[string]interface{}{},
+	}
+	if score, ok := e.Score(); ok {
+		c.Score = &score
+	}
+	for key, value := range map[string]string{"agent": e.Agent, "request_id": e.RequestID, "trace_id": e.TraceID} {
+		if value != "" {
+			c.Metadata[key] = value
+		}
+	}
+	if !e.StartedAt.IsZero() {
+		c.Metadata["started_at"] = e.StartedAt
+	}
+	return c
+}
+
+// chatExample converts an example into a fine-tuning conversation
+func chatExample(e Example) ChatExample {
+	var messages []ChatMessage
+	if e.SystemPrompt != "" {
+		messages = append(messages, ChatMessage{Role: "system", Content: e.SystemPrompt})
+	}
+	messages = append(messages,
+		ChatMessage{Role: "user", Content: e.Input},
+		ChatMessage{Role: "assistant", Content: e.Target()},
+	)
+	return ChatExample{Messages: messages}
+}
@@ -0,0 +1,282 @@
+package debug
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// EntryType is the type of a transcript entry
+type EntryType string
+
+const (
+	// EntryLLMCall records a prompt sent to the LLM and its raw response
+	EntryLLMCall EntryType = "llm_call"
+
+	// EntryToolCall records a tool invocation
+	EntryToolCall EntryType = "tool_call"
+
+	// EntryNote records free-form information about the run
+	EntryNote EntryType = "note"
+)
+
+// Entry is a single step of an agent run
+type Entry struct {
+	Type      EntryType              `json:"type"`
+	Name      string                 `json:"name"`
+	StartedAt time.Time              `json:"started_at"`
+	Duration  time.Duration          `json:"duration"`
+	Input     string                 `json:"input,omitempty"`
+	Output    string                 `json:"output,omitempty"`
+	Error     string                 `json:"error,omitempty"`
+	Metadata  map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// Transcript is the complete record of an agent run
+type Transcript struct {
+	Agent     string        `json:"agent"`
+	RequestID string        `json:"request_id,omitempty"`
+	TraceID   string        `json:"trace_id,omitempty"`
+	Input     string        `json:"input"`
+	Output    string        `json:"output"`
+	Error     string        `json:"error,omitempty"`
+	StartedAt time.Time     `json:"started_at"`
+	Duration  time.Duration `json:"duration"`
+	Entries   []Entry       `json:"entries"`
+
+	mu sync.Mutex
+}
+
+// Add appends an entry to the transcript
+func (t *Transcript) Add(entry Entry) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.Entries = append(t.Entries, entry)
+}
+
+// SetIDs records the request and trace IDs of the run, so that the
+// transcript can be matched with feedback on it
+func (t *Transcript) SetIDs(requestID, traceID string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.RequestID = requestID
+	t.TraceID = traceID
+}
+
+// JSON renders the transcript as indented JSON
+func (t *Transcript) JSON() ([]byte, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return json.MarshalIndent(t, "", "  ")
+}
+
+// Markdown renders the transcript as human-readable markdown
+func (t *Transcript) Markdown() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var sb strings.Builder
+	name := t.Agent
+	if name == "" {
+		name = "Agent"
+	}
+	sb.WriteString(fmt.Sprintf("# %s run\n\n", name))
+	sb.WriteString(fmt.Sprintf("- **Started:** %s\n", t.StartedAt.Format(time.RFC3339)))
+	sb.WriteString(fmt.Sprintf("- **Duration:** %s\n", t.Duration))
+	sb.WriteString(fmt.Sprintf("- **Steps:** %d\n", len(t.Entries)))
+	if t.Error != "" {
+		sb.WriteString(fmt.Sprintf("- **Error:** %s\n", t.Error))
+	}
+	sb.WriteString("\n## Input\n\n")
+	writeBlock(&sb, t.Input)
+
+	for i, entry := range t.Entries {
+		sb.WriteString(fmt.Sprintf("## %d. %s: %s (%s)\n\n", i+1, entry.Type, entry.Name, entry.Duration))
+		keys := make([]string, 0, len(entry.Metadata))
+		for key := range entry.Metadata {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			sb.WriteString(fmt.Sprintf("- **%s:** %v\n", key, entry.Metadata[key]))
+		}
+		if len(entry.Metadata) > 0 {
+			sb.WriteString("\n")
+		}
+		if entry.Input != "" {
+			sb.WriteString("**Input**\n\n")
+			writeBlock(&sb, entry.Input)
+		}
+		if entry.Output != "" {
+			sb.WriteString("**Output**\n\n")
+			writeBlock(&sb, entry.Output)
+		}
+		if entry.Error != "" {
+			sb.WriteString(fmt.Sprintf("**Error:** %s\n\n", entry.Error))
+		}
+	}
+
+	sb.WriteString("## Output\n\n")
+	writeBlock(&sb, t.Output)
+
+	return sb.String()
+}
+
+// writeBlock writes text as a fenced code block
+func writeBlock(sb *strings.Builder, text string) {
+	sb.WriteString("```\n")
+	sb.WriteString(text)
+	if !strings.HasSuffix(text, "\n") {
+		sb.WriteString("\n")
+	}
+	sb.WriteString("```\n\n")
+}
+
+// Format is the output format of a recorder
+type Format string
+
+const (
+	// FormatMarkdown renders transcripts as markdown
+	FormatMarkdown Format = "markdown"
+
+	// FormatJSON renders transcripts as JSON
+	FormatJSON Format = "json"
+)
+
+// Recorder records transcripts of agent runs
+type Recorder struct {
+	output     io.Writer
+	format     Format
+	maxHistory int
+
+	mu          sync.Mutex
+	transcripts []*Transcript
+}
+
+// Option represents an option for configuring a recorder
+type Option func(*Recorder)
+
+// WithOutput writes every finished transcript to w in the given format
+func WithOutput(w io.Writer, format Format) Option {
+	return func(r *Recorder) {
+		r.output = w
+		r.format = format
+	}
+}
+
+// WithMaxHistory sets how many finished transcripts are kept in memory
+func WithMaxHistory(n int) Option {
+	return func(r *Recorder) {
+		r.maxHistory = n
+	}
+}
+
+// NewRecorder creates a new debug recorder
+func NewRecorder(options ...Option) *Recorder {
+	recorder := &Recorder{
+		format:     FormatMarkdown,
+		maxHistory: 10,
+	}
+
+	for _, option := range options {
+		option(recorder)
+	}
+
+	return recorder
+}
+
+// Start begins a transcript for a run
+func (r *Recorder) Start(agentName, input string) *Transcript {
+	return &Transcript{
+		Agent:     agentName,
+		Input:     input,
+		StartedAt: time.Now(),
+		Entries:   []Entry{},
+	}
+}
+
+// Finish completes a transcript, stores it and writes it to the configured output
+func (r *Recorder) Finish(t *Transcript, output string, err error) {
+	t.mu.Lock()
+	t.Output = output
+	t.Duration = time.Since(t.StartedAt)
+	if err != nil {
+		t.Error = err.Error()
+	}
+	t.mu.Unlock()
+
+	r.mu.Lock()
+	r.transcripts = append(r.transcripts, t)
+	if r.maxHistory > 0 && len(r.transcripts) > r.maxHistory {
+		r.transcripts = r.transcripts[len(r.transcripts)-r.maxHistory:]
+	}
+	r.mu.Unlock()
+
+	if r.output != nil {
+		r.write(t)
+	}
+}
+
+// Last returns the most recently finished transcript, or nil if there is none
+func (r *Recorder) Last() *Transcript {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.transcripts) == 0 {
+		return nil
+	}
+	return r.transcripts[len(r.transcripts)-1]
+}
+
+// Transcripts returns the finished transcripts kept in memory, oldest first
+func (r *Recorder) Transcripts() []*Transcript {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	transcripts := make([]*Transcript, len(r.transcripts))
+	copy(transcripts, r.transcripts)
+	return transcripts
+}
+
+// write renders the transcript to the configured output
+func (r *Recorder) write(t *Transcript) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	switch r.format {
+	case FormatJSON:
+		data, err := t.JSON()
+		if err != nil {
+			fmt.Fprintf(r.output, "failed to render transcript: %v\n", err)
+			return
+		}
+		_, _ = r.output.Write(append(data, '\n'))
+	default:
+		_, _ = io.WriteString(r.output, t.Markdown())
+	}
+}
+
+// transcriptKey is the context key for the transcript of the current run
+type transcriptKey struct{}
+
+// WithTranscript returns a context that records entries into the transcript
+func WithTranscript(ctx context.Context, t *Transcript) context.Context {
+	return context.WithValue(ctx, transcriptKey{}, t)
+}
+
+// TranscriptFromContext returns the transcript of the current run, if one is being recorded
+func TranscriptFromContext(ctx context.Context) (*Transcript, bool) {
+	t, ok := ctx.Value(transcriptKey{}).(*Transcript)
+	return t, ok
+}
+
+// Record adds an entry to the transcript in the context. It is a no-op when
+// the run is not being recorded.
+func Record(ctx context.Context, entry Entry) {
+	if t, ok := TranscriptFromContext(ctx); ok {
+		t.Add(entry)
+	}
+}
@@ -0,0 +1,109 @@
+package debug_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/run-bigpig/llm-agent/pkg/debug"
+)
+
+func TestRecorderTranscript(t *testing.T) {
+	recorder := debug.NewRecorder()
+	transcript := recorder.Start("support", "where is my order?")
+	ctx := debug.WithTranscript(context.Background(), transcript)
+
+	debug.Record(ctx, debug.Entry{Type: debug.EntryLLMCall, Name: "gpt-4o", Input: "prompt", Output: "call lookup"})
+	debug.Record(ctx, debug.Entry{Type: debug.EntryToolCall, Name: "lookup", Input: `{"id":1}`, Error: "not found"})
+	transcript.SetIDs("req-1", "trace-1")
+	recorder.Finish(transcript, "I couldn't find it", errors.New("lookup failed"))
+
+	if recorder.Last() != transcript {
+		t.Fatal("expected the finished transcript to be the last one")
+	}
+	if len(transcript.Entries) != 2 || transcript.Entries[1].Name != "lookup" {
+		t.Fatalf("unexpected entries: %+v", transcript.Entries)
+	}
+	if transcript.Output != "I couldn't find it" || transcript.Error != "lookup failed" || transcript.Duration <= 0 {
+		t.Errorf("unexpected transcript: %+v", transcript)
+	}
+	if transcript.RequestID != "req-1" || transcript.TraceID != "trace-1" {
+		t.Errorf("expected the IDs to be set, got %q and %q", transcript.RequestID, transcript.TraceID)
+	}
+}
+
+func TestRecordWithoutTranscript(t *testing.T) {
+	// Recording outside of a recorded run is a no-op
+	debug.Record(context.Background(), debug.Entry{Type: debug.EntryNote, Name: "ignored"})
+	if _, ok := debug.TranscriptFromContext(context.Background()); ok {
+		t.Error("expected no transcript in a plain context")
+	}
+}
+
+func TestRecorderMaxHistory(t *testing.T) {
+	recorder := debug.NewRecorder(debug.WithMaxHistory(2))
+	for _, input := range []string{"one", "two", "three"} {
+		recorder.Finish(recorder.Start("agent", input), input, nil)
+	}
+
+	transcripts := recorder.Transcripts()
+	if len(transcripts) != 2 || transcripts[0].Input != "two" || transcripts[1].Input != "three" {
+		t.Errorf("expected the 2 newest transcripts, got %+v", transcripts)
+	}
+	if debug.NewRecorder().Last() != nil {
+		t.Error("expected no last transcript before a run finished")
+	}
+}
+
+func TestRecorderMarkdownOutput(t *testing.T) {
+	var out bytes.Buffer
+	recorder := debug.NewRecorder(debug.WithOutput(&out, debug.FormatMarkdown))
+	transcript := recorder.Start("support", "hi")
+	transcript.Add(debug.Entry{
+		Type:     debug.EntryToolCall,
+		Name:     "lookup",
+		Duration: time.Second,
+		Input:    `{"id":1}`,
+		Output:   "shipped",
+		Metadata: map[string]interface{}{"b": 2, "a": 1},
+	})
+	recorder.Finish(transcript, "hello", nil)
+
+	markdown := out.String()
+	for _, want := range []string{
+		"# support run\n",
+		"- **Steps:** 1\n",
+		"## Input\n\n```\nhi\n```\n",
+		"## 1. tool_call: lookup (1s)\n\n- **a:** 1\n- **b:** 2\n",
+		"**Input**\n\n```\n{\"id\":1}\n```\n",
+		"**Output**\n\n```\nshipped\n```\n",
+		"## Output\n\n```\nhello\n```\n",
+	} {
+		if !strings.Contains(markdown, want) {
+			t.Errorf("expected %q in markdown:\n%s", want, markdown)
+		}
+	}
+	if strings.Contains(markdown, "**Error:**") {
+		t.Errorf("expected no error in markdown:\n%s", markdown)
+	}
+}
+
+func TestRecorderJSONOutput(t *testing.T) {
+	var out bytes.Buffer
+	recorder := debug.NewRecorder(debug.WithOutput(&out, debug.FormatJSON))
+	transcript := recorder.Start("support", "hi")
+	transcript.Add(debug.Entry{Type: debug.EntryNote, Name: "cache miss"})
+	recorder.Finish(transcript, "hello", nil)
+
+	var decoded debug.Transcript
+	if err := json.Unmarshal(out.Bytes(), &decoded); err != nil {
+		t.Fatalf("failed to unmarshal output: %v\n%s", err, out.String())
+	}
+	if decoded.Agent != "support" || decoded.Output != "hello" || len(decoded.Entries) != 1 || decoded.Entries[0].Type != debug.EntryNote {
+		t.Errorf("unexpected transcript: %+v", &decoded)
+	}
+}
@@ -0,0 +1,138 @@
+package embedding
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// cohereMaxBatch is the maximum number of texts per Cohere embed request
+const cohereMaxBatch = 96
+
+// CohereEmbedder implements embedding generation using the Cohere API
+type CohereEmbedder struct {
+	http   httpClient
+	config EmbeddingConfig
+}
+
+// NewCohereEmbedder creates a new CohereEmbedder with default configuration
+func NewCohereEmbedder(apiKey, model string, options ...Option) *CohereEmbedder {
+	if model == "" {
+		model = "embed-english-v3.0"
+	}
+	return NewCohereEmbedderWithConfig(apiKey, DefaultEmbeddingConfig(model), options...)
+}
+
+// NewCohereEmbedderWithConfig creates a new CohereEmbedder with custom configuration
+func NewCohereEmbedderWithConfig(apiKey string, config EmbeddingConfig, options ...Option) *CohereEmbedder {
+	if config.Model == "" {
+		config.Model = "embed-english-v3.0"
+	}
+
+	return &CohereEmbedder{
+		http:   newHTTPClient("cohere", apiKey, "https://api.cohere.com", options...),
+		config: config,
+	}
+}
+
+// cohereEmbedRequest is the body of a Cohere v2 embed request
+type cohereEmbedRequest struct {
+	Model           string   `json:"model"`
+	Texts           []string `json:"texts"`
+	InputType       string   `json:"input_type"`
+	EmbeddingTypes  []string `json:"embedding_types"`
+	Truncate        string   `json:"truncate,omitempty"`
+	OutputDimension int      `json:"output_dimension,omitempty"`
+}
+
+// cohereEmbedResponse is the body of a Cohere v2 embed response
+type cohereEmbedResponse struct {
+	Embeddings struct {
+		Float [][]float32 `json:"float"`
+	} `json:"embeddings"`
+	Meta struct {
+		BilledUnits struct {
+			InputTokens int `json:"input_tokens"`
+		} `json:"billed_units"`
+	} `json:"meta"`
+}
+
+// Embed generates an embedding using the Cohere API with default configuration
+func (e *CohereEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	return e.EmbedWithConfig(ctx, text, e.config)
+}
+
+// EmbedWithConfig generates an embedding using the Cohere API with custom configuration
+func (e *CohereEmbedder) EmbedWithConfig(ctx context.Context, text string, config EmbeddingConfig) ([]float32, error) {
+	embeddings, err := e.EmbedBatchWithConfig(ctx, []string{text}, config)
+	if err != nil {
+		return nil, err
+	}
+	return embeddings[0], nil
+}
+
+// EmbedBatch generates embeddings for multiple texts using default configuration
+func (e *CohereEmbedder) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	return e.EmbedBatchWithConfig(ctx, texts, e.config)
+}
+
+// EmbedBatchWithConfig generates embeddings for multiple texts with custom
+// configuration. Cohere truncates texts over the model's limit itself, by
+// tokens, unless Truncation is "none".
+func (e *CohereEmbedder) EmbedBatchWithConfig(ctx context.Context, texts []string, config EmbeddingConfig) ([][]float32, error) {
+	if len(texts) == 0 {
+		return [][]float32{}, nil
+	}
+
+	truncate := "END"
+	switch config.Truncation {
+	case TruncationNone:
+		truncate = "NONE"
+	case TruncationStart:
+		truncate = "START"
+	}
+
+	embeddings := make([][]float32, 0, len(texts))
+	for start := 0; start < len(texts); start += cohereMaxBatch {
+		end := start + cohereMaxBatch
+		if end > len(texts) {
+			end = len(texts)
+		}
+
+		req := cohereEmbedRequest{
+			Model:           config.Model,
+			Texts:           texts[start:end],
+			InputType:       config.inputType(ctx),
+			EmbeddingTypes:  []string{"float"},
+			Truncate:        truncate,
+			OutputDimension: config.Dimensions,
+		}
+		var resp cohereEmbedResponse
+		if err := e.http.post(ctx, "/v2/embed", req, &resp); err != nil {
+			return nil, err
+		}
+		if len(resp.Embeddings.Float) != end-start {
+			return nil, fmt.Errorf("cohere returned %d embeddings for %d texts", len(resp.Embeddings.Float), end-start)
+		}
+		reportUsage(ctx, config.Model, resp.Meta.BilledUnits.InputTokens)
+		embeddings = append(embeddings, resp.Embeddings.Float...)
+	}
+
+	return config.finishVectors(embeddings), nil
+}
+
+// CalculateSimilarity calculates the similarity between two embeddings
+func (e *CohereEmbedder) CalculateSimilarity(vec1, vec2 []float32, metric string) (float32, error) {
+	if len(vec1) != len(vec2) {
+		return 0, errors.New("embedding vectors must have the same dimensions")
+	}
+	if metric == "" {
+		metric = e.config.SimilarityMetric
+	}
+	return calculateSimilarity(vec1, vec2, metric)
+}
+
+// GetConfig returns the current embedding configuration
+func (e *CohereEmbedder) GetConfig() EmbeddingConfig {
+	return e.config
+}
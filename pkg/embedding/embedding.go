@@ -22,9 +22,21 @@ type EmbeddingConfig struct {
 	EncodingFormat string
 
 	// Truncation controls how the input text is handled if it exceeds the model's token limit
-	// Options: "none" (error on overflow), "truncate" (truncate to limit)
+	// Options: "none" (error on overflow), "truncate" (drop the end), "truncate_start" (drop the start)
 	Truncation string
 
+	// MaxTokens is the input token limit used for truncation
+	// Defaults to the model's limit (see ModelMaxTokens)
+	MaxTokens int
+
+	// Normalize scales embeddings to unit length (L2 normalization)
+	Normalize bool
+
+	// InputType tells providers that support it what the text is used for
+	// Options: "search_document", "search_query", "classification", "clustering"
+	// If empty, the input type from the context is used (see WithInputType)
+	InputType string
+
 	// SimilarityMetric specifies the similarity metric to use when comparing embeddings
 	// Options: "cosine" (default), "euclidean", "dot_product"
 	SimilarityMetric string
@@ -111,7 +123,7 @@ func (e *OpenAIEmbedder) Embed(ctx context.Context, text string) ([]float32, err
 // EmbedWithConfig generates an embedding using OpenAI API with custom configuration
 func (e *OpenAIEmbedder) EmbedWithConfig(ctx context.Context, text string, config EmbeddingConfig) ([]float32, error) {
 	req := openai.EmbeddingRequest{
-		Input: []string{text},
+		Input: config.prepareTexts([]string{text}),
 		Model: openai.EmbeddingModel(config.Model),
 	}
 
@@ -136,8 +148,9 @@ func (e *OpenAIEmbedder) EmbedWithConfig(ctx context.Context, text string, confi
 	if len(resp.Data) == 0 {
 		return nil, errors.New("no embedding data returned from API")
 	}
+	reportUsage(ctx, config.Model, resp.Usage.PromptTokens)
 
-	return resp.Data[0].Embedding, nil
+	return config.finishVectors([][]float32{resp.Data[0].Embedding})[0], nil
 }
 
 // EmbedBatch generates embeddings for multiple texts using default configuration
@@ -152,7 +165,7 @@ func (e *OpenAIEmbedder) EmbedBatchWithConfig(ctx context.Context, texts []strin
 	}
 
 	req := openai.EmbeddingRequest{
-		Input: texts,
+		Input: config.prepareTexts(texts),
 		Model: openai.EmbeddingModel(config.Model),
 	}
 
@@ -177,6 +190,7 @@ func (e *OpenAIEmbedder) EmbedBatchWithConfig(ctx context.Context, texts []strin
 	if len(resp.Data) == 0 {
 		return nil, errors.New("no embedding data returned from API")
 	}
+	reportUsage(ctx, config.Model, resp.Usage.PromptTokens)
 
 	// Sort embeddings by index to ensure correct order
 	embeddings := make([][]float32, len(texts))
@@ -187,7 +201,7 @@ func (e *OpenAIEmbedder) EmbedBatchWithConfig(ctx context.Context, texts []strin
 		embeddings[data.Index] = data.Embedding
 	}
 
-	return embeddings, nil
+	return config.finishVectors(embeddings), nil
 }
 
 // CalculateSimilarity calculates the similarity between two embeddings
@@ -200,6 +214,12 @@ func (e *OpenAIEmbedder) CalculateSimilarity(vec1, vec2 []float32, metric string
 		metric = e.config.SimilarityMetric
 	}
 
+	return calculateSimilarity(vec1, vec2, metric)
+}
+
+// calculateSimilarity calculates the similarity between two embeddings of the
+// same dimensions with the given metric
+func calculateSimilarity(vec1, vec2 []float32, metric string) (float32, error) {
 	switch metric {
 	case "cosine":
 		return cosineSimilarity(vec1, vec2), nil
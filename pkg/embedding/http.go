@@ -0,0 +1,83 @@
+package embedding
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Option configures an embedder that calls a provider's HTTP API
+type Option func(*httpClient)
+
+// WithBaseURL sets the provider's API base URL, e.g. for a proxy
+func WithBaseURL(baseURL string) Option {
+	return func(c *httpClient) {
+		c.baseURL = strings.TrimRight(baseURL, "/")
+	}
+}
+
+// WithHTTPClient sets the HTTP client used for requests
+func WithHTTPClient(client *http.Client) Option {
+	return func(c *httpClient) {
+		c.client = client
+	}
+}
+
+// httpClient sends JSON requests to a provider's embedding API
+type httpClient struct {
+	provider string
+	apiKey   string
+	baseURL  string
+	client   *http.Client
+}
+
+// newHTTPClient creates an HTTP client for a provider with the given default
+// base URL
+func newHTTPClient(provider, apiKey, baseURL string, options ...Option) httpClient {
+	c := httpClient{
+		provider: provider,
+		apiKey:   apiKey,
+		baseURL:  baseURL,
+		client:   http.DefaultClient,
+	}
+	for _, option := range options {
+		option(&c)
+	}
+	return c
+}
+
+// post sends body to the path and decodes the response into out
+func (c httpClient) post(ctx context.Context, path string, body, out interface{}) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s request: %w", c.provider, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+path, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to create %s request: %w", c.provider, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Authorization", "Bearer "+c.apiKey)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s request failed: %w", c.provider, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("%s API returned status %d: %s", c.provider, resp.StatusCode, strings.TrimSpace(string(message)))
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode %s response: %w", c.provider, err)
+	}
+	return nil
+}
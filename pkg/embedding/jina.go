@@ -0,0 +1,144 @@
+package embedding
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// JinaEmbedder implements embedding generation using the Jina AI API
+type JinaEmbedder struct {
+	http   httpClient
+	config EmbeddingConfig
+}
+
+// NewJinaEmbedder creates a new JinaEmbedder with default configuration
+func NewJinaEmbedder(apiKey, model string, options ...Option) *JinaEmbedder {
+	if model == "" {
+		model = "jina-embeddings-v3"
+	}
+	return NewJinaEmbedderWithConfig(apiKey, DefaultEmbeddingConfig(model), options...)
+}
+
+// NewJinaEmbedderWithConfig creates a new JinaEmbedder with custom configuration
+func NewJinaEmbedderWithConfig(apiKey string, config EmbeddingConfig, options ...Option) *JinaEmbedder {
+	if config.Model == "" {
+		config.Model = "jina-embeddings-v3"
+	}
+
+	return &JinaEmbedder{
+		http:   newHTTPClient("jina", apiKey, "https://api.jina.ai", options...),
+		config: config,
+	}
+}
+
+// jinaTasks maps input types to jina-embeddings-v3 tasks
+var jinaTasks = map[string]string{
+	InputTypeDocument:       "retrieval.passage",
+	InputTypeQuery:          "retrieval.query",
+	InputTypeClassification: "classification",
+	InputTypeClustering:     "separation",
+}
+
+// jinaEmbedRequest is the body of a Jina embeddings request
+type jinaEmbedRequest struct {
+	Model      string   `json:"model"`
+	Input      []string `json:"input"`
+	Task       string   `json:"task,omitempty"`
+	Truncate   bool     `json:"truncate"`
+	Normalized bool     `json:"normalized,omitempty"`
+	Dimensions int      `json:"dimensions,omitempty"`
+}
+
+// jinaEmbedResponse is the body of a Jina embeddings response
+type jinaEmbedResponse struct {
+	Data []struct {
+		Index     int       `json:"index"`
+		Embedding []float32 `json:"embedding"`
+	} `json:"data"`
+	Usage struct {
+		TotalTokens int `json:"total_tokens"`
+	} `json:"usage"`
+}
+
+// Embed generates an embedding using the Jina API with default configuration
+func (e *JinaEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	return e.EmbedWithConfig(ctx, text, e.config)
+}
+
+// EmbedWithConfig generates an embedding using the Jina API with custom configuration
+func (e *JinaEmbedder) EmbedWithConfig(ctx context.Context, text string, config EmbeddingConfig) ([]float32, error) {
+	embeddings, err := e.EmbedBatchWithConfig(ctx, []string{text}, config)
+	if err != nil {
+		return nil, err
+	}
+	return embeddings[0], nil
+}
+
+// EmbedBatch generates embeddings for multiple texts using default configuration
+func (e *JinaEmbedder) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	return e.EmbedBatchWithConfig(ctx, texts, e.config)
+}
+
+// EmbedBatchWithConfig generates embeddings for multiple texts with custom
+// configuration. Jina drops the end of texts over the model's limit itself;
+// with "truncate_start" the start is dropped before sending.
+func (e *JinaEmbedder) EmbedBatchWithConfig(ctx context.Context, texts []string, config EmbeddingConfig) ([][]float32, error) {
+	if len(texts) == 0 {
+		return [][]float32{}, nil
+	}
+
+	if config.Truncation == TruncationStart {
+		texts = config.prepareTexts(texts)
+	}
+
+	req := jinaEmbedRequest{
+		Model:      config.Model,
+		Input:      texts,
+		Truncate:   config.Truncation != TruncationNone,
+		Normalized: config.Normalize,
+		Dimensions: config.Dimensions,
+	}
+	// Only v3 models support tasks
+	if strings.HasPrefix(config.Model, "jina-embeddings-v3") {
+		req.Task = jinaTasks[config.inputType(ctx)]
+	}
+
+	var resp jinaEmbedResponse
+	if err := e.http.post(ctx, "/v1/embeddings", req, &resp); err != nil {
+		return nil, err
+	}
+	reportUsage(ctx, config.Model, resp.Usage.TotalTokens)
+
+	embeddings := make([][]float32, len(texts))
+	for _, data := range resp.Data {
+		if data.Index < 0 || data.Index >= len(embeddings) {
+			return nil, fmt.Errorf("invalid embedding index: %d", data.Index)
+		}
+		embeddings[data.Index] = data.Embedding
+	}
+	for i, embedding := range embeddings {
+		if embedding == nil {
+			return nil, fmt.Errorf("jina returned no embedding for text %d", i)
+		}
+	}
+
+	return config.finishVectors(embeddings), nil
+}
+
+// CalculateSimilarity calculates the similarity between two embeddings
+func (e *JinaEmbedder) CalculateSimilarity(vec1, vec2 []float32, metric string) (float32, error) {
+	if len(vec1) != len(vec2) {
+		return 0, errors.New("embedding vectors must have the same dimensions")
+	}
+	if metric == "" {
+		metric = e.config.SimilarityMetric
+	}
+	return calculateSimilarity(vec1, vec2, metric)
+}
+
+// GetConfig returns the current embedding configuration
+func (e *JinaEmbedder) GetConfig() EmbeddingConfig {
+	return e.config
+}
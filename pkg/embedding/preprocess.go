@@ -0,0 +1,225 @@
+package embedding
+
+import (
+	"context"
+	"math"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Truncation modes for EmbeddingConfig.Truncation
+const (
+	// TruncationNone sends texts as they are; the provider rejects texts over
+	// the model's limit
+	TruncationNone = "none"
+
+	// TruncationEnd drops the end of texts over the limit
+	TruncationEnd = "truncate"
+
+	// TruncationStart drops the start of texts over the limit
+	TruncationStart = "truncate_start"
+)
+
+// Input types for EmbeddingConfig.InputType. Providers that support them embed
+// documents and queries differently, which improves retrieval.
+const (
+	// InputTypeDocument marks texts that are stored and searched
+	InputTypeDocument = "search_document"
+
+	// InputTypeQuery marks search queries
+	InputTypeQuery = "search_query"
+
+	// InputTypeClassification marks texts embedded for a classifier
+	InputTypeClassification = "classification"
+
+	// InputTypeClustering marks texts embedded for clustering
+	InputTypeClustering = "clustering"
+)
+
+// modelMaxTokens are the input limits of known embedding models
+var modelMaxTokens = map[string]int{
+	"text-embedding-3-small":        8191,
+	"text-embedding-3-large":        8191,
+	"text-embedding-ada-002":        8191,
+	"embed-english-v3.0":            512,
+	"embed-multilingual-v3.0":       512,
+	"embed-english-light-v3.0":      512,
+	"embed-multilingual-light-v3.0": 512,
+	"embed-v4.0":                    128000,
+	"jina-embeddings-v3":            8192,
+	"jina-embeddings-v2-base-en":    8192,
+	"jina-embeddings-v2-small-en":   8192,
+	"jina-embeddings-v2-base-de":    8192,
+	"jina-embeddings-v2-base-es":    8192,
+	"jina-embeddings-v2-base-zh":    8192,
+	"jina-embeddings-v2-base-code":  8192,
+}
+
+// ModelMaxTokens returns the input token limit of a known embedding model, or
+// 0 if the model is unknown
+func ModelMaxTokens(model string) int {
+	return modelMaxTokens[model]
+}
+
+// maxTokens returns the token limit for the config: MaxTokens if set,
+// otherwise the model's limit
+func (c EmbeddingConfig) maxTokens() int {
+	if c.MaxTokens > 0 {
+		return c.MaxTokens
+	}
+	return ModelMaxTokens(c.Model)
+}
+
+// EstimateTokens estimates the number of tokens in text. It errs on the high
+// side: ASCII text is counted as a token per 3 characters and every other
+// character as a token, which covers CJK scripts.
+func EstimateTokens(text string) int {
+	ascii, other := 0, 0
+	for _, r := range text {
+		if r < utf8.RuneSelf {
+			ascii++
+		} else {
+			other++
+		}
+	}
+	return (ascii+2)/3 + other
+}
+
+// runeTokens is the estimated token cost of a rune, in thirds of a token
+func runeTokens(r rune) int {
+	if r < utf8.RuneSelf {
+		return 1
+	}
+	return 3
+}
+
+// TruncateText clips text to about maxTokens tokens, estimated with
+// EstimateTokens. TruncationEnd keeps the start of the text and
+// TruncationStart keeps the end; the cut is moved to a word boundary when one
+// is close. Texts within the limit, a maxTokens of 0 and TruncationNone leave
+// the text unchanged.
+func TruncateText(text string, maxTokens int, truncation string) string {
+	if maxTokens <= 0 || truncation == TruncationNone || EstimateTokens(text) <= maxTokens {
+		return text
+	}
+
+	budget := maxTokens * 3
+	runes := []rune(text)
+	if truncation == TruncationStart {
+		start := len(runes)
+		for start > 0 && budget >= runeTokens(runes[start-1]) {
+			budget -= runeTokens(runes[start-1])
+			start--
+		}
+		kept := runes[start:]
+		if i := indexFunc(kept, unicode.IsSpace); i >= 0 && i < len(kept)/10 {
+			kept = kept[i+1:]
+		}
+		return string(kept)
+	}
+
+	end := 0
+	for end < len(runes) && budget >= runeTokens(runes[end]) {
+		budget -= runeTokens(runes[end])
+		end++
+	}
+	kept := runes[:end]
+	if i := lastIndexFunc(kept, unicode.IsSpace); i >= 0 && i > len(kept)-len(kept)/10 {
+		kept = kept[:i]
+	}
+	return string(kept)
+}
+
+// indexFunc returns the index of the first rune that satisfies f, or -1
+func indexFunc(runes []rune, f func(rune) bool) int {
+	for i, r := range runes {
+		if f(r) {
+			return i
+		}
+	}
+	return -1
+}
+
+// lastIndexFunc returns the index of the last rune that satisfies f, or -1
+func lastIndexFunc(runes []rune, f func(rune) bool) int {
+	for i := len(runes) - 1; i >= 0; i-- {
+		if f(runes[i]) {
+			return i
+		}
+	}
+	return -1
+}
+
+// NormalizeL2 scales a vector to unit length, so that the dot product of two
+// normalized vectors is their cosine similarity. A zero vector is returned
+// unchanged.
+func NormalizeL2(vector []float32) []float32 {
+	var sum float64
+	for _, v := range vector {
+		sum += float64(v) * float64(v)
+	}
+	if sum == 0 {
+		return vector
+	}
+
+	norm := math.Sqrt(sum)
+	normalized := make([]float32, len(vector))
+	for i, v := range vector {
+		normalized[i] = float32(float64(v) / norm)
+	}
+	return normalized
+}
+
+// inputTypeKey is the context key for the input type
+type inputTypeKey struct{}
+
+// WithInputType returns a context whose embeddings use the input type, e.g.
+// InputTypeQuery, unless the embedder's config sets one. Vector stores use it
+// to embed documents and queries differently.
+func WithInputType(ctx context.Context, inputType string) context.Context {
+	return context.WithValue(ctx, inputTypeKey{}, inputType)
+}
+
+// InputType returns the input type from the context, if there is one
+func InputType(ctx context.Context) string {
+	inputType, _ := ctx.Value(inputTypeKey{}).(string)
+	return inputType
+}
+
+// inputType returns the input type for a request: the config's, then the
+// context's, then InputTypeDocument
+func (c EmbeddingConfig) inputType(ctx context.Context) string {
+	if c.InputType != "" {
+		return c.InputType
+	}
+	if inputType := InputType(ctx); inputType != "" {
+		return inputType
+	}
+	return InputTypeDocument
+}
+
+// prepareTexts truncates texts that exceed the model's limit according to the
+// config. Providers that truncate on their side don't need it.
+func (c EmbeddingConfig) prepareTexts(texts []string) []string {
+	maxTokens := c.maxTokens()
+	if maxTokens == 0 || c.Truncation == TruncationNone {
+		return texts
+	}
+
+	prepared := make([]string, len(texts))
+	for i, text := range texts {
+		prepared[i] = TruncateText(text, maxTokens, c.Truncation)
+	}
+	return prepared
+}
+
+// finishVectors normalizes vectors if the config asks for it
+func (c EmbeddingConfig) finishVectors(vectors [][]float32) [][]float32 {
+	if !c.Normalize {
+		return vectors
+	}
+	for i, vector := range vectors {
+		vectors[i] = NormalizeL2(vector)
+	}
+	return vectors
+}
@@ -0,0 +1,86 @@
+package embedding_test
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/run-bigpig/llm-agent/pkg/embedding"
+)
+
+func TestTruncateText(t *testing.T) {
+	text := strings.Repeat("word ", 100) // 500 characters, about 167 tokens
+
+	end := embedding.TruncateText(text, 50, embedding.TruncationEnd)
+	if embedding.EstimateTokens(end) > 50 || !strings.HasPrefix(text, end) || strings.HasSuffix(end, " ") {
+		t.Errorf("expected the start of the text cut at a word boundary, got %q", end)
+	}
+
+	start := embedding.TruncateText(text, 50, embedding.TruncationStart)
+	if embedding.EstimateTokens(start) > 50 || !strings.HasSuffix(text, start) || !strings.HasPrefix(start, "word") {
+		t.Errorf("expected the end of the text starting at a word, got %q", start)
+	}
+
+	if got := embedding.TruncateText(text, 50, embedding.TruncationNone); got != text {
+		t.Error("expected no truncation with TruncationNone")
+	}
+
+	cjk := strings.Repeat("漢", 100)
+	if got := embedding.TruncateText(cjk, 10, embedding.TruncationEnd); got != strings.Repeat("漢", 10) {
+		t.Errorf("expected 10 characters of CJK text, got %q", got)
+	}
+}
+
+func TestCohereEmbedder(t *testing.T) {
+	var requests []map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v2/embed" || r.Header.Get("Authorization") != "Bearer key" {
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+			return
+		}
+		var req map[string]interface{}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		requests = append(requests, req)
+
+		vectors := make([][]float32, len(req["texts"].([]interface{})))
+		for i := range vectors {
+			vectors[i] = []float32{3, 4}
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"embeddings": map[string]interface{}{"float": vectors}})
+	}))
+	defer server.Close()
+
+	config := embedding.DefaultEmbeddingConfig("embed-english-v3.0")
+	config.Normalize = true
+	config.Truncation = embedding.TruncationStart
+	embedder := embedding.NewCohereEmbedderWithConfig("key", config, embedding.WithBaseURL(server.URL))
+
+	ctx := embedding.WithInputType(context.Background(), embedding.InputTypeQuery)
+	vector, err := embedder.Embed(ctx, "what is a vector database?")
+	if err != nil {
+		t.Fatalf("failed to embed: %v", err)
+	}
+	if math.Abs(float64(vector[0])-0.6) > 1e-6 || math.Abs(float64(vector[1])-0.8) > 1e-6 {
+		t.Errorf("expected a normalized vector, got %v", vector)
+	}
+	if requests[0]["input_type"] != "search_query" || requests[0]["truncate"] != "START" {
+		t.Errorf("unexpected request %v", requests[0])
+	}
+
+	// Large batches are split into requests of at most 96 texts
+	texts := make([]string, 100)
+	for i := range texts {
+		texts[i] = "document"
+	}
+	vectors, err := embedder.EmbedBatch(context.Background(), texts)
+	if err != nil {
+		t.Fatalf("failed to embed batch: %v", err)
+	}
+	if len(vectors) != 100 || len(requests) != 3 || requests[1]["input_type"] != "search_document" {
+		t.Errorf("expected 100 vectors from 2 document requests, got %d vectors and %d requests", len(vectors), len(requests)-1)
+	}
+}
@@ -0,0 +1,28 @@
+package embedding
+
+import "context"
+
+// Usage is the token usage of an embedding request as reported by the
+// provider
+type Usage struct {
+	Model  string
+	Tokens int
+}
+
+// usageKey is the context key for the usage callback
+type usageKey struct{}
+
+// WithUsageCallback returns a context in which embedders call fn with the
+// usage the provider reports for each request, e.g. to meter spend. Batches
+// that are split into several requests report each request.
+func WithUsageCallback(ctx context.Context, fn func(Usage)) context.Context {
+	return context.WithValue(ctx, usageKey{}, fn)
+}
+
+// reportUsage passes the usage of a request to the callback in the context,
+// if there is one
+func reportUsage(ctx context.Context, model string, tokens int) {
+	if fn, ok := ctx.Value(usageKey{}).(func(Usage)); ok && fn != nil {
+		fn(Usage{Model: model, Tokens: tokens})
+	}
+}
@@ -0,0 +1,116 @@
+package events
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+
+	"github.com/run-bigpig/llm-agent/pkg/logging"
+)
+
+// AsyncPublisher queues events and publishes them in the background, in
+// order, so that publishing never blocks an agent run on a slow broker.
+// Events are dropped when the queue is full, and failures are logged.
+type AsyncPublisher struct {
+	publisher Publisher
+	queue     chan []Event
+	logger    logging.Logger
+	dropped   atomic.Int64
+
+	closeOnce sync.Once
+	done      chan struct{}
+}
+
+// AsyncOption configures an AsyncPublisher
+type AsyncOption func(*asyncConfig)
+
+// asyncConfig holds the options of an AsyncPublisher
+type asyncConfig struct {
+	bufferSize int
+	logger     logging.Logger
+}
+
+// WithBufferSize sets how many Publish calls can be queued. Defaults to
+// 1000.
+func WithBufferSize(size int) AsyncOption {
+	return func(c *asyncConfig) {
+		c.bufferSize = size
+	}
+}
+
+// WithLogger sets the logger for failed and dropped events
+func WithLogger(logger logging.Logger) AsyncOption {
+	return func(c *asyncConfig) {
+		c.logger = logger
+	}
+}
+
+// NewAsyncPublisher starts publishing queued events to the publisher. Call
+// Close to publish the events still queued and stop.
+func NewAsyncPublisher(publisher Publisher, options ...AsyncOption) *AsyncPublisher {
+	config := asyncConfig{bufferSize: 1000, logger: logging.New()}
+	for _, option := range options {
+		option(&config)
+	}
+
+	p := &AsyncPublisher{
+		publisher: publisher,
+		queue:     make(chan []Event, config.bufferSize),
+		logger:    config.logger,
+		done:      make(chan struct{}),
+	}
+	go p.run()
+	return p
+}
+
+// Publish queues the events. It returns immediately; events that don't fit
+// in the queue are dropped.
+func (p *AsyncPublisher) Publish(ctx context.Context, events ...Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+	select {
+	case p.queue <- events:
+	default:
+		p.dropped.Add(int64(len(events)))
+		p.logger.Warn(ctx, "Event queue is full, dropping events", map[string]interface{}{
+			"events": len(events),
+			"type":   events[0].Type,
+		})
+	}
+	return nil
+}
+
+// Dropped returns the number of events dropped because the queue was full
+func (p *AsyncPublisher) Dropped() int64 {
+	return p.dropped.Load()
+}
+
+// Close publishes the queued events and stops. It returns early with the
+// context's error if the context ends first. Events must not be published
+// after Close.
+func (p *AsyncPublisher) Close(ctx context.Context) error {
+	p.closeOnce.Do(func() {
+		close(p.queue)
+	})
+	select {
+	case <-p.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// run publishes queued events until the queue is closed
+func (p *AsyncPublisher) run() {
+	defer close(p.done)
+	for events := range p.queue {
+		if err := p.publisher.Publish(context.Background(), events...); err != nil {
+			p.logger.Error(context.Background(), "Failed to publish events", map[string]interface{}{
+				"events": len(events),
+				"type":   events[0].Type,
+				"error":  err.Error(),
+			})
+		}
+	}
+}
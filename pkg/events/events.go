@@ -0,0 +1,130 @@
+// Package events publishes agent activity, such as tool calls, finished
+// runs, task status changes and audit records, to message brokers like Redis
+// Streams or Kafka, so that other services can react to it asynchronously.
+package events
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/run-bigpig/llm-agent/pkg/runctx"
+)
+
+// Types of events
+const (
+	// TypeToolCallStarted is published when an agent starts a tool call
+	TypeToolCallStarted = "agent.tool_call.started"
+
+	// TypeToolCallFinished is published when a tool call returns
+	TypeToolCallFinished = "agent.tool_call.finished"
+
+	// TypeRunFinished is published when an agent run finishes
+	TypeRunFinished = "agent.run.finished"
+
+	// TypeTaskStatusChanged is published when a task changes status
+	TypeTaskStatusChanged = "task.status_changed"
+
+	// TypeAudit is an audit record of an action taken by or on behalf of a
+	// user
+	TypeAudit = "audit"
+)
+
+// Event is a record of agent activity
+type Event struct {
+	// ID uniquely identifies the event, so consumers can deduplicate
+	ID string `json:"id"`
+
+	// Type is the type of the event, e.g. TypeRunFinished
+	Type string `json:"type"`
+
+	// Source is the agent or service that published the event
+	Source string `json:"source,omitempty"`
+
+	// Subject is what the event is about, e.g. a tool name or task ID
+	Subject string `json:"subject,omitempty"`
+
+	// Time is when the event happened
+	Time time.Time `json:"time"`
+
+	// The IDs of the run the event happened in
+	OrgID          string `json:"org_id,omitempty"`
+	UserID         string `json:"user_id,omitempty"`
+	ConversationID string `json:"conversation_id,omitempty"`
+	RequestID      string `json:"request_id,omitempty"`
+	TraceID        string `json:"trace_id,omitempty"`
+
+	// Tags are the attribution tags of the run, e.g. the agent version
+	Tags map[string]string `json:"tags,omitempty"`
+
+	// Data holds the details of the event
+	Data map[string]interface{} `json:"data,omitempty"`
+}
+
+// New creates an event that happened now, with the run IDs from the context
+func New(ctx context.Context, eventType, source, subject string, data map[string]interface{}) Event {
+	rc := runctx.From(ctx)
+	return Event{
+		ID:             uuid.New().String(),
+		Type:           eventType,
+		Source:         source,
+		Subject:        subject,
+		Time:           time.Now(),
+		OrgID:          rc.OrgID,
+		UserID:         rc.UserID,
+		ConversationID: rc.ConversationID,
+		RequestID:      rc.RequestID,
+		TraceID:        rc.TraceID,
+		Tags:           rc.Tags,
+		Data:           data,
+	}
+}
+
+// TaskStatusChanged creates an event for a task that changed status
+func TaskStatusChanged(ctx context.Context, source, taskID, from, to string) Event {
+	return New(ctx, TypeTaskStatusChanged, source, taskID, map[string]interface{}{
+		"from": from,
+		"to":   to,
+	})
+}
+
+// Audit creates an audit record of an action on a resource and its outcome,
+// e.g. "allowed" or "denied"
+func Audit(ctx context.Context, source, action, resource, outcome string, details map[string]interface{}) Event {
+	data := map[string]interface{}{
+		"action":  action,
+		"outcome": outcome,
+	}
+	for key, value := range details {
+		data[key] = value
+	}
+	return New(ctx, TypeAudit, source, resource, data)
+}
+
+// Key returns the key that keeps related events in order on partitioned
+// brokers: the conversation ID, the organization ID or the subject
+func (e Event) Key() string {
+	switch {
+	case e.ConversationID != "":
+		return e.ConversationID
+	case e.OrgID != "":
+		return e.OrgID
+	default:
+		return e.Subject
+	}
+}
+
+// Publisher publishes events
+type Publisher interface {
+	// Publish publishes the events in order
+	Publish(ctx context.Context, events ...Event) error
+}
+
+// PublisherFunc adapts a function to a Publisher
+type PublisherFunc func(ctx context.Context, events ...Event) error
+
+// Publish calls f
+func (f PublisherFunc) Publish(ctx context.Context, events ...Event) error {
+	return f(ctx, events...)
+}
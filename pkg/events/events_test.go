@@ -0,0 +1,123 @@
+package events_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/run-bigpig/llm-agent/pkg/events"
+	"github.com/run-bigpig/llm-agent/pkg/runctx"
+)
+
+func TestNew(t *testing.T) {
+	ctx := runctx.WithConversationID(runctx.WithOrgID(context.Background(), "acme"), "conv-1")
+	event := events.TaskStatusChanged(ctx, "tasks", "task-1", "planning", "executing")
+
+	assert.NotEmpty(t, event.ID)
+	assert.Equal(t, events.TypeTaskStatusChanged, event.Type)
+	assert.Equal(t, "acme", event.OrgID)
+	assert.Equal(t, "task-1", event.Subject)
+	assert.Equal(t, "executing", event.Data["to"])
+	assert.Equal(t, "conv-1", event.Key())
+
+	audit := events.Audit(context.Background(), "api", "delete", "doc-1", "denied", map[string]interface{}{"reason": "not owner"})
+	assert.Equal(t, "doc-1", audit.Key())
+	assert.Equal(t, "denied", audit.Data["outcome"])
+	assert.Equal(t, "not owner", audit.Data["reason"])
+}
+
+// streamClient records XADD calls
+type streamClient struct {
+	redis.Cmdable
+	adds []*redis.XAddArgs
+}
+
+func (c *streamClient) XAdd(ctx context.Context, a *redis.XAddArgs) *redis.StringCmd {
+	c.adds = append(c.adds, a)
+	return redis.NewStringResult(fmt.Sprintf("%d-0", len(c.adds)), nil)
+}
+
+func TestRedisStreamPublisher(t *testing.T) {
+	client := &streamClient{}
+	publisher := events.NewRedisStreamPublisher(client, "agent:events", events.WithStreamMaxLen(1000))
+
+	event := events.New(context.Background(), events.TypeRunFinished, "support", "support", nil)
+	require.NoError(t, publisher.Publish(context.Background(), event))
+
+	require.Len(t, client.adds, 1)
+	args := client.adds[0]
+	assert.Equal(t, "agent:events", args.Stream)
+	assert.Equal(t, int64(1000), args.MaxLen)
+	assert.True(t, args.Approx)
+	values := args.Values.(map[string]interface{})
+	assert.Equal(t, events.TypeRunFinished, values["type"])
+	var decoded events.Event
+	require.NoError(t, json.Unmarshal([]byte(values["event"].(string)), &decoded))
+	assert.Equal(t, event.ID, decoded.ID)
+}
+
+// producer records produced messages
+type producer struct {
+	messages []events.KafkaMessage
+}
+
+func (p *producer) Produce(ctx context.Context, messages ...events.KafkaMessage) error {
+	p.messages = append(p.messages, messages...)
+	return nil
+}
+
+func TestKafkaPublisher(t *testing.T) {
+	p := &producer{}
+	publisher := events.NewKafkaPublisher(p, "agent-events", events.WithTopicFunc(func(e events.Event) string {
+		if e.Type == events.TypeAudit {
+			return "audit"
+		}
+		return "agent-events"
+	}))
+
+	ctx := runctx.WithConversationID(context.Background(), "conv-1")
+	require.NoError(t, publisher.Publish(ctx,
+		events.New(ctx, events.TypeToolCallStarted, "support", "search", nil),
+		events.Audit(ctx, "support", "run_agent", "support", "denied", nil),
+	))
+
+	require.Len(t, p.messages, 2)
+	assert.Equal(t, "agent-events", p.messages[0].Topic)
+	assert.Equal(t, "audit", p.messages[1].Topic)
+	assert.Equal(t, []byte("conv-1"), p.messages[0].Key)
+	assert.Equal(t, events.TypeToolCallStarted, p.messages[0].Headers["event_type"])
+}
+
+func TestAsyncPublisher(t *testing.T) {
+	memory := events.NewMemoryPublisher()
+	publisher := events.NewAsyncPublisher(memory)
+
+	for i := 0; i < 10; i++ {
+		require.NoError(t, publisher.Publish(context.Background(), events.New(context.Background(), events.TypeAudit, "test", fmt.Sprint(i), nil)))
+	}
+	require.NoError(t, publisher.Close(context.Background()))
+
+	published := memory.Events()
+	require.Len(t, published, 10)
+	for i, event := range published {
+		assert.Equal(t, fmt.Sprint(i), event.Subject)
+	}
+	assert.Zero(t, publisher.Dropped())
+}
+
+func TestMulti(t *testing.T) {
+	memory := events.NewMemoryPublisher()
+	failing := events.PublisherFunc(func(ctx context.Context, e ...events.Event) error {
+		return errors.New("broker down")
+	})
+
+	err := events.Multi(failing, memory).Publish(context.Background(), events.New(context.Background(), events.TypeAudit, "test", "x", nil))
+	assert.ErrorContains(t, err, "broker down")
+	assert.Len(t, memory.Events(), 1)
+}
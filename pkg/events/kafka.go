@@ -0,0 +1,77 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// KafkaMessage is a message to produce to a Kafka topic
+type KafkaMessage struct {
+	Topic   string
+	Key     []byte
+	Value   []byte
+	Headers map[string]string
+}
+
+// KafkaProducer produces messages to Kafka. Implement it with the client
+// library of your choice, e.g. a kafka-go Writer or a sarama SyncProducer.
+type KafkaProducer interface {
+	// Produce writes the messages in order
+	Produce(ctx context.Context, messages ...KafkaMessage) error
+}
+
+// KafkaPublisher produces events to a Kafka topic. Messages are keyed by
+// Event.Key, so that the events of a conversation stay in order, carry the
+// event as JSON and have "event_id" and "event_type" headers.
+type KafkaPublisher struct {
+	producer KafkaProducer
+	topic    func(Event) string
+}
+
+// KafkaOption configures a KafkaPublisher
+type KafkaOption func(*KafkaPublisher)
+
+// WithTopicFunc chooses the topic of each event, e.g. to send audit records
+// to their own topic
+func WithTopicFunc(topic func(Event) string) KafkaOption {
+	return func(p *KafkaPublisher) {
+		p.topic = topic
+	}
+}
+
+// NewKafkaPublisher creates a publisher that produces events to the topic
+func NewKafkaPublisher(producer KafkaProducer, topic string, options ...KafkaOption) *KafkaPublisher {
+	p := &KafkaPublisher{
+		producer: producer,
+		topic:    func(Event) string { return topic },
+	}
+	for _, option := range options {
+		option(p)
+	}
+	return p
+}
+
+// Publish produces the events
+func (p *KafkaPublisher) Publish(ctx context.Context, events ...Event) error {
+	messages := make([]KafkaMessage, len(events))
+	for i, event := range events {
+		data, err := json.Marshal(event)
+		if err != nil {
+			return fmt.Errorf("failed to encode event %s: %w", event.ID, err)
+		}
+		messages[i] = KafkaMessage{
+			Topic: p.topic(event),
+			Key:   []byte(event.Key()),
+			Value: data,
+			Headers: map[string]string{
+				"event_id":   event.ID,
+				"event_type": event.Type,
+			},
+		}
+	}
+	if err := p.producer.Produce(ctx, messages...); err != nil {
+		return fmt.Errorf("failed to produce events: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// Multi returns a publisher that publishes events to all publishers. Every
+// publisher is tried; their errors are joined.
+func Multi(publishers ...Publisher) Publisher {
+	return PublisherFunc(func(ctx context.Context, events ...Event) error {
+		var errs []error
+		for _, publisher := range publishers {
+			if err := publisher.Publish(ctx, events...); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		return errors.Join(errs...)
+	})
+}
+
+// MemoryPublisher keeps published events in memory, e.g. for tests
+type MemoryPublisher struct {
+	mu     sync.Mutex
+	events []Event
+}
+
+// NewMemoryPublisher creates an empty in-memory publisher
+func NewMemoryPublisher() *MemoryPublisher {
+	return &MemoryPublisher{}
+}
+
+// Publish stores the events
+func (p *MemoryPublisher) Publish(ctx context.Context, events ...Event) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.events = append(p.events, events...)
+	return nil
+}
+
+// Events returns the published events, oldest first
+func (p *MemoryPublisher) Events() []Event {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]Event(nil), p.events...)
+}
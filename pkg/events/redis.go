@@ -0,0 +1,68 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// RedisStreamPublisher appends events to a Redis stream with XADD. Each
+// entry has the fields "id", "type" and "event", the event as JSON, so that
+// consumer groups can filter on the type without decoding the event.
+type RedisStreamPublisher struct {
+	client redis.Cmdable
+	stream string
+	maxLen int64
+}
+
+// RedisStreamOption configures a RedisStreamPublisher
+type RedisStreamOption func(*RedisStreamPublisher)
+
+// WithStreamMaxLen caps the stream at about maxLen entries, trimming the
+// oldest. Defaults to 0, no cap.
+func WithStreamMaxLen(maxLen int64) RedisStreamOption {
+	return func(p *RedisStreamPublisher) {
+		p.maxLen = maxLen
+	}
+}
+
+// NewRedisStreamPublisher creates a publisher that appends events to the
+// stream, e.g. "agent:events"
+func NewRedisStreamPublisher(client redis.Cmdable, stream string, options ...RedisStreamOption) *RedisStreamPublisher {
+	p := &RedisStreamPublisher{
+		client: client,
+		stream: stream,
+	}
+	for _, option := range options {
+		option(p)
+	}
+	return p
+}
+
+// Publish appends the events to the stream
+func (p *RedisStreamPublisher) Publish(ctx context.Context, events ...Event) error {
+	for _, event := range events {
+		data, err := json.Marshal(event)
+		if err != nil {
+			return fmt.Errorf("failed to encode event %s: %w", event.ID, err)
+		}
+		args := &redis.XAddArgs{
+			Stream: p.stream,
+			Values: map[string]interface{}{
+				"id":    event.ID,
+				"type":  event.Type,
+				"event": string(data),
+			},
+		}
+		if p.maxLen > 0 {
+			args.MaxLen = p.maxLen
+			args.Approx = true
+		}
+		if err := p.client.XAdd(ctx, args).Err(); err != nil {
+			return fmt.Errorf("failed to add event %s to stream %s: %w", event.ID, p.stream, err)
+		}
+	}
+	return nil
+}
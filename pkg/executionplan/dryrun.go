@@ -0,0 +1,32 @@
+package executionplan
+
+import (
+	"context"
+
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+	"github.com/run-bigpig/llm-agent/pkg/runctx"
+)
+
+// WithSideEffects names tools that change external systems but don't declare
+// it through interfaces.ToolWithSideEffects. In a dry run (see
+// runctx.WithDryRun) their steps are simulated; tools recorded in an outbox
+// are treated the same way.
+func WithSideEffects(toolNames ...string) ExecutorOption {
+	return func(e *Executor) {
+		if e.sideEffects == nil {
+			e.sideEffects = make(map[string]bool, len(toolNames))
+		}
+		for _, name := range toolNames {
+			e.sideEffects[name] = true
+		}
+	}
+}
+
+// simulates reports whether the step is simulated instead of executed,
+// which is the case for steps with side effects in a dry run
+func (e *Executor) simulates(ctx context.Context, tool interfaces.Tool, step ExecutionStep) bool {
+	if !runctx.DryRun(ctx) {
+		return false
+	}
+	return e.sideEffects[step.ToolName] || e.outboxTools[step.ToolName] || interfaces.HasSideEffects(tool, step.Input)
+}
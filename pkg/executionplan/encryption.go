@@ -0,0 +1,160 @@
+package executionplan
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/run-bigpig/llm-agent/pkg/crypto"
+)
+
+// EncryptHistory returns a HistoryStore that encrypts the descriptions,
+// results, errors and step inputs and outputs of runs before saving them to
+// store, and decrypts them when runs are listed. The fields the filter
+// matches on, like the IDs and the status, are stored as they are.
+func EncryptHistory(store HistoryStore, encryptor *crypto.Encryptor) HistoryStore {
+	return &encryptedHistory{store: store, encryptor: encryptor}
+}
+
+type encryptedHistory struct {
+	store     HistoryStore
+	encryptor *crypto.Encryptor
+}
+
+// SaveRun encrypts a copy of the run and saves it
+func (h *encryptedHistory) SaveRun(ctx context.Context, run *RunRecord) error {
+	encrypted := *run
+	encrypted.Steps = append([]StepRecord(nil), run.Steps...)
+
+	fields := []*string{&encrypted.Description, &encrypted.Result, &encrypted.Error}
+	for i := range encrypted.Steps {
+		fields = append(fields, &encrypted.Steps[i].Input, &encrypted.Steps[i].Output, &encrypted.Steps[i].Error)
+	}
+	if err := encryptFields(ctx, h.encryptor, fields...); err != nil {
+		return fmt.Errorf("failed to encrypt run: %w", err)
+	}
+
+	return h.store.SaveRun(ctx, &encrypted)
+}
+
+// ListRuns lists the runs and decrypts them. Runs encrypted with another
+// key than the one of the context, e.g. another organization's, are left out.
+func (h *encryptedHistory) ListRuns(ctx context.Context, filter HistoryFilter) ([]*RunRecord, error) {
+	runs, err := h.store.ListRuns(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	decrypted := runs[:0]
+	for _, run := range runs {
+		fields := []*string{&run.Description, &run.Result, &run.Error}
+		for i := range run.Steps {
+			fields = append(fields, &run.Steps[i].Input, &run.Steps[i].Output, &run.Steps[i].Error)
+		}
+		if err := decryptFields(ctx, h.encryptor, fields...); err != nil {
+			if errors.Is(err, crypto.ErrKeyMismatch) {
+				continue
+			}
+			return nil, fmt.Errorf("failed to decrypt run %s: %w", run.TaskID, err)
+		}
+		decrypted = append(decrypted, run)
+	}
+	return decrypted, nil
+}
+
+// DeleteRunsBefore deletes old runs from the underlying store
+func (h *encryptedHistory) DeleteRunsBefore(ctx context.Context, before time.Time) (int, error) {
+	return h.store.DeleteRunsBefore(ctx, before)
+}
+
+// EncryptOutbox returns an Outbox that encrypts the inputs, outputs and
+// errors of tool calls before saving them to outbox, and decrypts them when
+// entries are read
+func EncryptOutbox(outbox Outbox, encryptor *crypto.Encryptor) Outbox {
+	return &encryptedOutbox{outbox: outbox, encryptor: encryptor}
+}
+
+type encryptedOutbox struct {
+	outbox    Outbox
+	encryptor *crypto.Encryptor
+}
+
+// Save encrypts the entry and saves it
+func (o *encryptedOutbox) Save(ctx context.Context, entry OutboxEntry) error {
+	if err := encryptFields(ctx, o.encryptor, &entry.Input, &entry.Output, &entry.Error); err != nil {
+		return fmt.Errorf("failed to encrypt outbox entry: %w", err)
+	}
+	return o.outbox.Save(ctx, entry)
+}
+
+// Get returns the decrypted entry of a step
+func (o *encryptedOutbox) Get(ctx context.Context, taskID string, stepIndex int) (OutboxEntry, bool, error) {
+	entry, found, err := o.outbox.Get(ctx, taskID, stepIndex)
+	if err != nil || !found {
+		return entry, found, err
+	}
+	if err := decryptFields(ctx, o.encryptor, &entry.Input, &entry.Output, &entry.Error); err != nil {
+		return OutboxEntry{}, false, fmt.Errorf("failed to decrypt outbox entry: %w", err)
+	}
+	return entry, true, nil
+}
+
+// List returns the decrypted entries of a plan
+func (o *encryptedOutbox) List(ctx context.Context, taskID string) ([]OutboxEntry, error) {
+	entries, err := o.outbox.List(ctx, taskID)
+	if err != nil {
+		return nil, err
+	}
+	return o.decryptEntries(ctx, entries)
+}
+
+// ListPending returns the decrypted entries that were never confirmed or failed
+func (o *encryptedOutbox) ListPending(ctx context.Context) ([]OutboxEntry, error) {
+	entries, err := o.outbox.ListPending(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return o.decryptEntries(ctx, entries)
+}
+
+func (o *encryptedOutbox) decryptEntries(ctx context.Context, entries []OutboxEntry) ([]OutboxEntry, error) {
+	for i := range entries {
+		entry := &entries[i]
+		if err := decryptFields(ctx, o.encryptor, &entry.Input, &entry.Output, &entry.Error); err != nil {
+			return nil, fmt.Errorf("failed to decrypt outbox entry: %w", err)
+		}
+	}
+	return entries, nil
+}
+
+// encryptFields encrypts the non-empty fields in place
+func encryptFields(ctx context.Context, encryptor *crypto.Encryptor, fields ...*string) error {
+	for _, field := range fields {
+		if *field == "" {
+			continue
+		}
+		encrypted, err := encryptor.EncryptString(ctx, *field)
+		if err != nil {
+			return err
+		}
+		*field = encrypted
+	}
+	return nil
+}
+
+// decryptFields decrypts the encrypted fields in place. Fields saved before
+// encryption was enabled are left as they are.
+func decryptFields(ctx context.Context, encryptor *crypto.Encryptor, fields ...*string) error {
+	for _, field := range fields {
+		if !crypto.IsEncrypted(*field) {
+			continue
+		}
+		decrypted, err := encryptor.DecryptString(ctx, *field)
+		if err != nil {
+			return err
+		}
+		*field = decrypted
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package executionplan
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/run-bigpig/llm-agent/pkg/crypto"
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+	"github.com/run-bigpig/llm-agent/pkg/multitenancy"
+)
+
+func newTestEncryptor(t *testing.T) *crypto.Encryptor {
+	keyManager, err := crypto.NewLocalKeyManager(bytes.Repeat([]byte("k"), 32))
+	if err != nil {
+		t.Fatalf("failed to create key manager: %v", err)
+	}
+	return crypto.NewEncryptor(keyManager)
+}
+
+func TestEncryptHistory(t *testing.T) {
+	stored := NewMemoryHistory()
+	history := EncryptHistory(stored, newTestEncryptor(t))
+	executor := NewExecutor([]interfaces.Tool{&upperTool{}}, WithHistory(history, "ops"))
+
+	ctx := multitenancy.WithOrgID(context.Background(), "org-1")
+	plan := NewExecutionPlan("Upper card number", []ExecutionStep{{ToolName: "upper", Input: "4111"}})
+	plan.UserApproved = true
+	if _, err := executor.ExecutePlan(ctx, plan); err != nil {
+		t.Fatalf("failed to execute plan: %v", err)
+	}
+
+	raw, err := stored.ListRuns(ctx, HistoryFilter{})
+	if err != nil || len(raw) != 1 {
+		t.Fatalf("expected 1 stored run, got %d (%v)", len(raw), err)
+	}
+	for _, value := range []string{raw[0].Description, raw[0].Result, raw[0].Steps[0].Input, raw[0].Steps[0].Output} {
+		if !crypto.IsEncrypted(value) {
+			t.Errorf("expected the stored run to be encrypted, got %q", value)
+		}
+	}
+
+	runs, err := history.ListRuns(ctx, HistoryFilter{TaskID: plan.TaskID})
+	if err != nil || len(runs) != 1 {
+		t.Fatalf("expected 1 run, got %d (%v)", len(runs), err)
+	}
+	if runs[0].Description != "Upper card number" || runs[0].Steps[0].Input != "4111" || !strings.HasPrefix(runs[0].Steps[0].Output, "4111") {
+		t.Errorf("expected the run to be decrypted, got %+v", runs[0])
+	}
+	// Another organization can't read the run
+	runs, err = history.ListRuns(multitenancy.WithOrgID(context.Background(), "org-2"), HistoryFilter{})
+	if err != nil || len(runs) != 0 {
+		t.Errorf("expected the runs of other organizations to be left out, got %d (%v)", len(runs), err)
+	}
+}
+
+func TestEncryptOutbox(t *testing.T) {
+	stored := NewMemoryOutbox()
+	outbox := EncryptOutbox(stored, newTestEncryptor(t))
+	tool := &chargeTool{}
+	executor := NewExecutor([]interfaces.Tool{tool}, WithOutbox(outbox, "charge"))
+
+	ctx := multitenancy.WithOrgID(context.Background(), "org-1")
+	plan := newChargePlan("10")
+	if _, err := executor.ExecutePlan(ctx, plan); err != nil {
+		t.Fatalf("failed to execute plan: %v", err)
+	}
+
+	raw, _, err := stored.Get(ctx, plan.TaskID, 0)
+	if err != nil || !crypto.IsEncrypted(raw.Input) || !crypto.IsEncrypted(raw.Output) {
+		t.Errorf("expected the stored entry to be encrypted, got %+v (%v)", raw, err)
+	}
+
+	entries, err := outbox.List(ctx, plan.TaskID)
+	if err != nil || len(entries) != 1 || entries[0].Input != "10" || entries[0].Output != "charged 10" {
+		t.Errorf("expected the entry to be decrypted, got %+v (%v)", entries, err)
+	}
+
+	// Confirmed calls are read back through the encryption and not repeated
+	if _, err := executor.ExecutePlan(ctx, plan); err != nil || len(tool.keys) != 1 {
+		t.Errorf("expected the confirmed call not to be repeated, got %d calls (%v)", len(tool.keys), err)
+	}
+
+	// Entries saved before encryption was enabled are read as they are
+	_ = stored.Save(ctx, OutboxEntry{TaskID: "old", Input: "plain", Status: OutboxPending})
+	pending, err := outbox.ListPending(ctx)
+	if err != nil || len(pending) != 1 || pending[0].Input != "plain" {
+		t.Errorf("expected the plaintext entry, got %+v (%v)", pending, err)
+	}
+
+	// Another organization can't decrypt the entry
+	if _, _, err := outbox.Get(multitenancy.WithOrgID(context.Background(), "org-2"), plan.TaskID, 0); !errors.Is(err, crypto.ErrKeyMismatch) {
+		t.Errorf("expected ErrKeyMismatch for another organization, got %v", err)
+	}
+}
@@ -4,25 +4,66 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+	"github.com/run-bigpig/llm-agent/pkg/runctx"
 )
 
+// Observer is told when plans and their steps start. The returned function
+// is called with the result when they finish, and the returned context is
+// used for the plan's steps or the step's tool call.
+type Observer interface {
+	StartPlan(ctx context.Context, plan *ExecutionPlan) (context.Context, func(result string, err error))
+	StartStep(ctx context.Context, plan *ExecutionPlan, index int, step ExecutionStep) (context.Context, func(result string, err error))
+}
+
 // Executor handles execution of execution plans
 type Executor struct {
-	tools map[string]interfaces.Tool
+	tools    map[string]interfaces.Tool
+	observer Observer
+	history  HistoryStore
+	agent    string
+
+	outbox      Outbox
+	outboxTools map[string]bool
+	reconciler  Reconciler
+	sideEffects map[string]bool
+}
+
+// ExecutorOption represents an option for configuring the executor
+type ExecutorOption func(*Executor)
+
+// WithObserver reports plan and step execution to the observer, e.g. a tracer
+func WithObserver(observer Observer) ExecutorOption {
+	return func(e *Executor) {
+		e.observer = observer
+	}
+}
+
+// WithHistory records every executed plan in the history store, attributed
+// to the named agent
+func WithHistory(store HistoryStore, agent string) ExecutorOption {
+	return func(e *Executor) {
+		e.history = store
+		e.agent = agent
+	}
 }
 
 // NewExecutor creates a new execution plan executor
-func NewExecutor(tools []interfaces.Tool) *Executor {
+func NewExecutor(tools []interfaces.Tool, options ...ExecutorOption) *Executor {
 	toolMap := make(map[string]interfaces.Tool)
 	for _, tool := range tools {
 		toolMap[tool.Name()] = tool
 	}
 
-	return &Executor{
+	executor := &Executor{
 		tools: toolMap,
 	}
+	for _, option := range options {
+		option(executor)
+	}
+	return executor
 }
 
 // ExecutePlan executes an approved execution plan
@@ -31,6 +72,33 @@ func (e *Executor) ExecutePlan(ctx context.Context, plan *ExecutionPlan) (string
 		return "", fmt.Errorf("execution plan has not been approved by the user")
 	}
 
+	var record *RunRecord
+	if e.history != nil {
+		record = NewRunRecord(ctx, e.agent, plan)
+	}
+
+	var result string
+	var err error
+	if e.observer == nil {
+		result, err = e.executePlan(ctx, plan, record)
+	} else {
+		observedCtx, finish := e.observer.StartPlan(ctx, plan)
+		result, err = e.executePlan(observedCtx, plan, record)
+		finish(result, err)
+	}
+
+	if record != nil {
+		record.finish(plan, result, err)
+		if saveErr := e.history.SaveRun(ctx, record); saveErr != nil {
+			// Log the error but don't fail the plan
+			fmt.Printf("Failed to record execution plan history: %v\n", saveErr)
+		}
+	}
+	return result, err
+}
+
+// executePlan runs the steps of the plan in order
+func (e *Executor) executePlan(ctx context.Context, plan *ExecutionPlan, record *RunRecord) (string, error) {
 	// Update status to executing
 	plan.Status = StatusExecuting
 
@@ -46,14 +114,24 @@ func (e *Executor) ExecutePlan(ctx context.Context, plan *ExecutionPlan) (string
 
 		fmt.Println("step.Input", step.Input)
 		// Execute the tool
-		result, err := tool.Execute(ctx, step.Input)
+		result, err := e.executeStep(ctx, plan, i, step, tool, record)
 		if err != nil {
 			plan.Status = StatusFailed
 			return "", fmt.Errorf("failed to execute step %d: %w", i+1, err)
 		}
 
-		// Add the result to the list of results
-		results = append(results, fmt.Sprintf("Step %d (%s): %s", i+1, step.Description, result))
+		// Add the result to the list of results, noting simulated steps
+		if e.simulates(ctx, tool, step) {
+			results = append(results, fmt.Sprintf("Step %d (%s) [dry run: %s was not called]: %s", i+1, step.Description, step.ToolName, result))
+		} else {
+			results = append(results, fmt.Sprintf("Step %d (%s): %s", i+1, step.Description, result))
+		}
+	}
+
+	// A dry run leaves the plan approved so that it can be executed for real
+	if runctx.DryRun(ctx) {
+		plan.Status = StatusApproved
+		return fmt.Sprintf("Dry run of execution plan completed; no side effects were applied.\n\n%s", strings.Join(results, "\n\n")), nil
 	}
 
 	// Update status to completed
@@ -72,3 +150,52 @@ func (e *Executor) CancelPlan(plan *ExecutionPlan) {
 func (e *Executor) GetPlanStatus(plan *ExecutionPlan) ExecutionPlanStatus {
 	return plan.Status
 }
+
+// executeStep runs the tool of a single step, adding it to the record if
+// there is one
+func (e *Executor) executeStep(ctx context.Context, plan *ExecutionPlan, index int, step ExecutionStep, tool interfaces.Tool, record *RunRecord) (string, error) {
+	startedAt := time.Now()
+
+	execute := func(ctx context.Context) (string, error) {
+		return tool.Execute(ctx, step.Input)
+	}
+	if e.simulates(ctx, tool, step) {
+		// Side effects of a dry run are simulated and not recorded in the outbox
+		execute = func(ctx context.Context) (string, error) {
+			return interfaces.SimulateToolCall(ctx, tool, step.Input)
+		}
+	} else if e.outbox != nil && e.outboxTools[step.ToolName] {
+		call := execute
+		execute = func(ctx context.Context) (string, error) {
+			return e.executeWithOutbox(ctx, plan, index, step, call)
+		}
+	}
+
+	var result string
+	var err error
+	if e.observer == nil {
+		result, err = execute(ctx)
+	} else {
+		observedCtx, finish := e.observer.StartStep(ctx, plan, index, step)
+		result, err = execute(observedCtx)
+		finish(result, err)
+	}
+
+	if record != nil {
+		stepRecord := StepRecord{
+			Index:       index + 1,
+			ToolName:    step.ToolName,
+			Description: step.Description,
+			Input:       step.Input,
+			Output:      result,
+			Simulated:   e.simulates(ctx, tool, step),
+			StartedAt:   startedAt,
+			Duration:    time.Since(startedAt),
+		}
+		if err != nil {
+			stepRecord.Error = err.Error()
+		}
+		record.Steps = append(record.Steps, stepRecord)
+	}
+	return result, err
+}
@@ -0,0 +1,122 @@
+package executionplan
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+	"github.com/run-bigpig/llm-agent/pkg/runctx"
+)
+
+type upperTool struct{}
+
+func (t *upperTool) Name() string                                    { return "upper" }
+func (t *upperTool) Description() string                             { return "Upper-cases its input" }
+func (t *upperTool) Parameters() map[string]interfaces.ParameterSpec { return nil }
+func (t *upperTool) Run(ctx context.Context, input string) (string, error) {
+	return t.Execute(ctx, input)
+}
+func (t *upperTool) Execute(ctx context.Context, args string) (string, error) {
+	if args == "fail" {
+		return "", errors.New("tool failed")
+	}
+	return fmt.Sprintf("%s:%v", args, ctx.Value(observerKey{})), nil
+}
+
+type observerKey struct{}
+
+// recordingObserver records the events it observes
+type recordingObserver struct {
+	events []string
+}
+
+func (o *recordingObserver) StartPlan(ctx context.Context, plan *ExecutionPlan) (context.Context, func(string, error)) {
+	o.events = append(o.events, "plan started")
+	return context.WithValue(ctx, observerKey{}, "plan"), func(result string, err error) {
+		o.events = append(o.events, fmt.Sprintf("plan finished: %v", err))
+	}
+}
+
+func (o *recordingObserver) StartStep(ctx context.Context, plan *ExecutionPlan, index int, step ExecutionStep) (context.Context, func(string, error)) {
+	o.events = append(o.events, fmt.Sprintf("step %d started", index+1))
+	return ctx, func(result string, err error) {
+		o.events = append(o.events, fmt.Sprintf("step %d finished: %s %v", index+1, result, err))
+	}
+}
+
+func TestExecutorObserver(t *testing.T) {
+	observer := &recordingObserver{}
+	executor := NewExecutor([]interfaces.Tool{&upperTool{}}, WithObserver(observer))
+
+	plan := NewExecutionPlan("Test plan", []ExecutionStep{
+		{ToolName: "upper", Input: "a"},
+		{ToolName: "upper", Input: "fail"},
+	})
+	plan.UserApproved = true
+
+	if _, err := executor.ExecutePlan(context.Background(), plan); err == nil {
+		t.Fatal("expected the second step to fail")
+	}
+
+	expected := []string{
+		"plan started",
+		"step 1 started",
+		"step 1 finished: a:plan <nil>",
+		"step 2 started",
+		"step 2 finished:  tool failed",
+		"plan finished: failed to execute step 2: tool failed",
+	}
+	if len(observer.events) != len(expected) {
+		t.Fatalf("expected events %v, got %v", expected, observer.events)
+	}
+	for i := range expected {
+		if observer.events[i] != expected[i] {
+			t.Errorf("event %d: expected %q, got %q", i, expected[i], observer.events[i])
+		}
+	}
+}
+
+func TestExecutorHistory(t *testing.T) {
+	history := NewMemoryHistory(WithMaxRuns(2))
+	executor := NewExecutor([]interfaces.Tool{&upperTool{}}, WithHistory(history, "ops"))
+
+	ctx := runctx.WithUserID(context.Background(), "user-1")
+	ctx = WithApproval(ctx, Approval{Approved: true, DecidedBy: "alice", Source: "approval_queue"})
+
+	var plans []*ExecutionPlan
+	for _, input := range []string{"a", "fail", "b"} {
+		plan := NewExecutionPlan("Plan "+input, []ExecutionStep{{ToolName: "upper", Input: input}})
+		plan.UserApproved = true
+		_, _ = executor.ExecutePlan(ctx, plan)
+		plans = append(plans, plan)
+	}
+
+	runs, err := history.ListRuns(context.Background(), HistoryFilter{})
+	if err != nil {
+		t.Fatalf("failed to list runs: %v", err)
+	}
+	if len(runs) != 2 || runs[0].TaskID != plans[2].TaskID || runs[1].TaskID != plans[1].TaskID {
+		t.Fatalf("expected the 2 most recent runs, got %+v", runs)
+	}
+
+	failed := runs[1]
+	if failed.Status != StatusFailed || failed.Error == "" || failed.Agent != "ops" || failed.UserID != "user-1" || failed.Approval.DecidedBy != "alice" {
+		t.Errorf("unexpected failed run: %+v", failed)
+	}
+	if len(failed.Steps) != 1 || failed.Steps[0].Error != "tool failed" || failed.Steps[0].Index != 1 {
+		t.Errorf("unexpected failed steps: %+v", failed.Steps)
+	}
+
+	completed, err := history.ListRuns(context.Background(), HistoryFilter{Status: StatusCompleted})
+	if err != nil || len(completed) != 1 || completed[0].Steps[0].Output != "b:<nil>" {
+		t.Errorf("expected 1 completed run, got %+v (%v)", completed, err)
+	}
+
+	deleted, err := history.DeleteRunsBefore(context.Background(), time.Now())
+	if err != nil || deleted != 2 {
+		t.Errorf("expected 2 deleted runs, got %d (%v)", deleted, err)
+	}
+}
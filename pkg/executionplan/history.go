@@ -0,0 +1,297 @@
+package executionplan
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/run-bigpig/llm-agent/pkg/runctx"
+)
+
+// RunRecord records the execution of a plan
+type RunRecord struct {
+	// TaskID is the task ID of the plan
+	TaskID string `json:"task_id"`
+
+	// Agent is the name of the agent that executed the plan
+	Agent string `json:"agent,omitempty"`
+
+	// OrgID, UserID, ConversationID and RequestID identify the run
+	OrgID          string `json:"org_id,omitempty"`
+	UserID         string `json:"user_id,omitempty"`
+	ConversationID string `json:"conversation_id,omitempty"`
+	RequestID      string `json:"request_id,omitempty"`
+
+	// Description is the description of the plan
+	Description string `json:"description"`
+
+	// Status is the status of the plan when the run finished
+	Status ExecutionPlanStatus `json:"status"`
+
+	// Steps lists the steps that were executed, in order
+	Steps []StepRecord `json:"steps"`
+
+	// Result is the result of the plan if it completed
+	Result string `json:"result,omitempty"`
+
+	// Error is the error message if the plan failed
+	Error string `json:"error,omitempty"`
+
+	// Approval records who approved or rejected the plan
+	Approval Approval `json:"approval"`
+
+	// DryRun is whether the run was a dry run
+	DryRun bool `json:"dry_run,omitempty"`
+
+	// StartedAt is when execution started
+	StartedAt time.Time `json:"started_at"`
+
+	// FinishedAt is when execution finished
+	FinishedAt time.Time `json:"finished_at"`
+
+	// Duration is how long execution took
+	Duration time.Duration `json:"duration"`
+}
+
+// StepRecord records the execution of a single step
+type StepRecord struct {
+	// Index is the position of the step in the plan, starting at 1
+	Index int `json:"index"`
+
+	ToolName    string `json:"tool_name"`
+	Description string `json:"description"`
+	Input       string `json:"input"`
+
+	// Output is the output of the tool
+	Output string `json:"output,omitempty"`
+
+	// Error is the error message if the step failed
+	Error string `json:"error,omitempty"`
+
+	// Simulated is whether the step had side effects that a dry run
+	// simulated instead of applying
+	Simulated bool `json:"simulated,omitempty"`
+
+	StartedAt time.Time     `json:"started_at"`
+	Duration  time.Duration `json:"duration"`
+}
+
+// Approval records the decision on a plan
+type Approval struct {
+	// Approved is whether the plan was approved
+	Approved bool `json:"approved"`
+
+	// DecidedBy is who made the decision, e.g. a user ID or reviewer name
+	DecidedBy string `json:"decided_by,omitempty"`
+
+	// DecidedAt is when the decision was made
+	DecidedAt time.Time `json:"decided_at"`
+
+	// Source is where the decision was made, e.g. "user" or "approval_queue"
+	Source string `json:"source,omitempty"`
+
+	// Reason is the reason given for the decision
+	Reason string `json:"reason,omitempty"`
+}
+
+// HistoryFilter selects plan runs. Empty fields match every run.
+type HistoryFilter struct {
+	Agent          string
+	OrgID          string
+	UserID         string
+	ConversationID string
+	TaskID         string
+	Status         ExecutionPlanStatus
+
+	// Since and Until bound the time the run started
+	Since time.Time
+	Until time.Time
+
+	// Limit is the maximum number of runs to return; zero means no limit
+	Limit int
+}
+
+// Matches reports whether the run matches the filter, ignoring the limit
+func (f HistoryFilter) Matches(run *RunRecord) bool {
+	switch {
+	case f.Agent != "" && run.Agent != f.Agent,
+		f.OrgID != "" && run.OrgID != f.OrgID,
+		f.UserID != "" && run.UserID != f.UserID,
+		f.ConversationID != "" && run.ConversationID != f.ConversationID,
+		f.TaskID != "" && run.TaskID != f.TaskID,
+		f.Status != "" && run.Status != f.Status,
+		!f.Since.IsZero() && run.StartedAt.Before(f.Since),
+		!f.Until.IsZero() && !run.StartedAt.Before(f.Until):
+		return false
+	}
+	return true
+}
+
+// HistoryStore stores the history of plan runs
+type HistoryStore interface {
+	// SaveRun saves a run, replacing any run with the same task ID
+	SaveRun(ctx context.Context, run *RunRecord) error
+
+	// ListRuns returns the runs that match the filter, most recent first
+	ListRuns(ctx context.Context, filter HistoryFilter) ([]*RunRecord, error)
+
+	// DeleteRunsBefore deletes the runs that started before the given time
+	// and returns how many were deleted
+	DeleteRunsBefore(ctx context.Context, before time.Time) (int, error)
+}
+
+// approvalKey is the context key for the approval of the plan being executed
+type approvalKey struct{}
+
+// WithApproval returns a context carrying the approval of the plan that is
+// executed with it, so that it is recorded in the plan's history
+func WithApproval(ctx context.Context, approval Approval) context.Context {
+	return context.WithValue(ctx, approvalKey{}, approval)
+}
+
+// ApprovalFromContext returns the approval in the context, if there is one
+func ApprovalFromContext(ctx context.Context) (Approval, bool) {
+	approval, ok := ctx.Value(approvalKey{}).(Approval)
+	return approval, ok
+}
+
+// NewRunRecord creates a record for a plan that starts running now, with the
+// run identifiers and approval from the context
+func NewRunRecord(ctx context.Context, agent string, plan *ExecutionPlan) *RunRecord {
+	rc := runctx.From(ctx)
+	approval, _ := ApprovalFromContext(ctx)
+	return &RunRecord{
+		TaskID:         plan.TaskID,
+		Agent:          agent,
+		OrgID:          rc.OrgID,
+		UserID:         rc.UserID,
+		ConversationID: rc.ConversationID,
+		RequestID:      rc.RequestID,
+		Description:    plan.Description,
+		Status:         plan.Status,
+		Steps:          []StepRecord{},
+		Approval:       approval,
+		DryRun:         rc.DryRun,
+		StartedAt:      time.Now(),
+	}
+}
+
+// finish records the outcome of the run
+func (r *RunRecord) finish(plan *ExecutionPlan, result string, err error) {
+	r.FinishedAt = time.Now()
+	r.Duration = r.FinishedAt.Sub(r.StartedAt)
+	r.Status = plan.Status
+	r.Result = result
+	if err != nil {
+		r.Error = err.Error()
+	}
+}
+
+// HistoryOption represents an option for configuring the in-memory history
+type HistoryOption func(*MemoryHistory)
+
+// WithMaxRuns keeps only the most recent runs
+func WithMaxRuns(maxRuns int) HistoryOption {
+	return func(h *MemoryHistory) {
+		h.maxRuns = maxRuns
+	}
+}
+
+// WithMaxAge deletes runs that started longer ago than maxAge
+func WithMaxAge(maxAge time.Duration) HistoryOption {
+	return func(h *MemoryHistory) {
+		h.maxAge = maxAge
+	}
+}
+
+// MemoryHistory is a HistoryStore that keeps runs in memory
+type MemoryHistory struct {
+	mu      sync.RWMutex
+	runs    map[string]*RunRecord
+	maxRuns int
+	maxAge  time.Duration
+}
+
+// NewMemoryHistory creates a new in-memory plan history
+func NewMemoryHistory(options ...HistoryOption) *MemoryHistory {
+	h := &MemoryHistory{
+		runs: make(map[string]*RunRecord),
+	}
+	for _, option := range options {
+		option(h)
+	}
+	return h
+}
+
+// SaveRun saves a run, applying the retention policy
+func (h *MemoryHistory) SaveRun(ctx context.Context, run *RunRecord) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	saved := *run
+	saved.Steps = append([]StepRecord(nil), run.Steps...)
+	h.runs[run.TaskID] = &saved
+
+	if h.maxAge > 0 {
+		h.deleteBefore(time.Now().Add(-h.maxAge))
+	}
+	if h.maxRuns > 0 && len(h.runs) > h.maxRuns {
+		runs := h.sorted()
+		for _, old := range runs[h.maxRuns:] {
+			delete(h.runs, old.TaskID)
+		}
+	}
+	return nil
+}
+
+// ListRuns returns the runs that match the filter, most recent first
+func (h *MemoryHistory) ListRuns(ctx context.Context, filter HistoryFilter) ([]*RunRecord, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	var runs []*RunRecord
+	for _, run := range h.sorted() {
+		if !filter.Matches(run) {
+			continue
+		}
+		copied := *run
+		copied.Steps = append([]StepRecord(nil), run.Steps...)
+		runs = append(runs, &copied)
+		if filter.Limit > 0 && len(runs) == filter.Limit {
+			break
+		}
+	}
+	return runs, nil
+}
+
+// DeleteRunsBefore deletes the runs that started before the given time
+func (h *MemoryHistory) DeleteRunsBefore(ctx context.Context, before time.Time) (int, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.deleteBefore(before), nil
+}
+
+// deleteBefore deletes old runs; the caller must hold the lock
+func (h *MemoryHistory) deleteBefore(before time.Time) int {
+	deleted := 0
+	for taskID, run := range h.runs {
+		if run.StartedAt.Before(before) {
+			delete(h.runs, taskID)
+			deleted++
+		}
+	}
+	return deleted
+}
+
+// sorted returns the runs, most recent first; the caller must hold the lock
+func (h *MemoryHistory) sorted() []*RunRecord {
+	runs := make([]*RunRecord, 0, len(h.runs))
+	for _, run := range h.runs {
+		runs = append(runs, run)
+	}
+	sort.Slice(runs, func(i, j int) bool {
+		return runs[i].StartedAt.After(runs[j].StartedAt)
+	})
+	return runs
+}
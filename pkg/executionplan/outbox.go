@@ -0,0 +1,288 @@
+package executionplan
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrUnreconciled is returned when a plan is resumed with a side effect that
+// may have been half applied and no reconciler decided what to do about it
+var ErrUnreconciled = errors.New("side effect may have been applied but was not confirmed")
+
+// OutboxStatus is the status of a side-effecting step in the outbox
+type OutboxStatus string
+
+const (
+	// OutboxPending means the intent to call the tool was recorded but the
+	// call was not confirmed; the effect may or may not have been applied
+	OutboxPending OutboxStatus = "pending"
+
+	// OutboxConfirmed means the tool call returned successfully
+	OutboxConfirmed OutboxStatus = "confirmed"
+
+	// OutboxFailed means the tool call returned an error
+	OutboxFailed OutboxStatus = "failed"
+)
+
+// OutboxEntry records a call of a side-effecting tool in a plan
+type OutboxEntry struct {
+	// TaskID and StepIndex identify the plan and its step, starting at 0
+	TaskID    string `json:"task_id"`
+	StepIndex int    `json:"step_index"`
+
+	// ToolName and Input are the call that was or will be made
+	ToolName string `json:"tool_name"`
+	Input    string `json:"input"`
+
+	// IdempotencyKey is passed to the tool, see IdempotencyKey
+	IdempotencyKey string `json:"idempotency_key"`
+
+	// Status is the status of the call
+	Status OutboxStatus `json:"status"`
+
+	// Output and Error are the result of a confirmed or failed call
+	Output string `json:"output,omitempty"`
+	Error  string `json:"error,omitempty"`
+
+	// Attempts counts how often the call was started
+	Attempts int `json:"attempts"`
+
+	// CreatedAt and UpdatedAt are when the entry was recorded and last changed
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Outbox persists the calls of side-effecting tools: the intent is recorded
+// before the call and confirmed after it, so that a plan resumed after a
+// crash can tell which effects were applied, which were not, and which may
+// have been half applied.
+type Outbox interface {
+	// Save creates or replaces the entry of a step
+	Save(ctx context.Context, entry OutboxEntry) error
+
+	// Get returns the entry of a step
+	Get(ctx context.Context, taskID string, stepIndex int) (OutboxEntry, bool, error)
+
+	// List returns the entries of a plan, in step order
+	List(ctx context.Context, taskID string) ([]OutboxEntry, error)
+
+	// ListPending returns the entries of all plans that were never confirmed
+	// or failed, oldest first, e.g. to find half-applied effects at startup
+	ListPending(ctx context.Context) ([]OutboxEntry, error)
+}
+
+// ReconcileAction is what to do with an unconfirmed side effect on resume
+type ReconcileAction string
+
+const (
+	// ReconcileRetry calls the tool again, e.g. when it is idempotent or the
+	// effect was found not to be applied
+	ReconcileRetry ReconcileAction = "retry"
+
+	// ReconcileApplied treats the effect as applied and continues with the
+	// output the reconciler gives
+	ReconcileApplied ReconcileAction = "applied"
+
+	// ReconcileAbort fails the plan so that a person can look into it
+	ReconcileAbort ReconcileAction = "abort"
+)
+
+// Reconciler decides what to do with a step whose intent was recorded but
+// never confirmed, typically by checking the external system. For
+// ReconcileApplied it also returns the output to continue with.
+type Reconciler interface {
+	Reconcile(ctx context.Context, entry OutboxEntry) (ReconcileAction, string, error)
+}
+
+// ReconcilerFunc adapts a function to a Reconciler
+type ReconcilerFunc func(ctx context.Context, entry OutboxEntry) (ReconcileAction, string, error)
+
+// Reconcile calls f
+func (f ReconcilerFunc) Reconcile(ctx context.Context, entry OutboxEntry) (ReconcileAction, string, error) {
+	return f(ctx, entry)
+}
+
+type idempotencyKey struct{}
+
+// IdempotencyKey returns the idempotency key of the step being executed. It
+// is the same every time the step is retried, so tools can pass it to APIs
+// that deduplicate requests.
+func IdempotencyKey(ctx context.Context) (string, bool) {
+	key, ok := ctx.Value(idempotencyKey{}).(string)
+	return key, ok
+}
+
+// WithOutbox records the calls of the named side-effecting tools in the
+// outbox. When a plan is executed again with the same task ID, e.g. after a
+// crash, confirmed steps are not repeated and unconfirmed ones are passed to
+// the reconciler.
+func WithOutbox(outbox Outbox, toolNames ...string) ExecutorOption {
+	return func(e *Executor) {
+		e.outbox = outbox
+		e.outboxTools = make(map[string]bool, len(toolNames))
+		for _, name := range toolNames {
+			e.outboxTools[name] = true
+		}
+	}
+}
+
+// WithReconciler sets how unconfirmed side effects are handled when a plan is
+// resumed. Without one such plans fail with ErrUnreconciled.
+func WithReconciler(reconciler Reconciler) ExecutorOption {
+	return func(e *Executor) {
+		e.reconciler = reconciler
+	}
+}
+
+// executeWithOutbox runs a side-effecting step: a confirmed step returns its
+// recorded output, an unconfirmed one is reconciled, and otherwise the intent
+// is recorded, the tool called and the result confirmed
+func (e *Executor) executeWithOutbox(ctx context.Context, plan *ExecutionPlan, index int, step ExecutionStep, execute func(context.Context) (string, error)) (string, error) {
+	entry, found, err := e.outbox.Get(ctx, plan.TaskID, index)
+	if err != nil {
+		return "", fmt.Errorf("failed to read outbox: %w", err)
+	}
+
+	if found {
+		switch entry.Status {
+		case OutboxConfirmed:
+			return entry.Output, nil
+		case OutboxPending:
+			action, output, err := e.reconcile(ctx, entry)
+			if err != nil {
+				return "", err
+			}
+			if action == ReconcileApplied {
+				entry.Status = OutboxConfirmed
+				entry.Output = output
+				entry.UpdatedAt = time.Now()
+				if err := e.outbox.Save(ctx, entry); err != nil {
+					return "", fmt.Errorf("failed to confirm reconciled step: %w", err)
+				}
+				return output, nil
+			}
+		}
+	} else {
+		entry = OutboxEntry{
+			TaskID:         plan.TaskID,
+			StepIndex:      index,
+			IdempotencyKey: fmt.Sprintf("%s:%d", plan.TaskID, index),
+			CreatedAt:      time.Now(),
+		}
+	}
+
+	// Record the intent before calling the tool
+	entry.ToolName = step.ToolName
+	entry.Input = step.Input
+	entry.Status = OutboxPending
+	entry.Output = ""
+	entry.Error = ""
+	entry.Attempts++
+	entry.UpdatedAt = time.Now()
+	if err := e.outbox.Save(ctx, entry); err != nil {
+		return "", fmt.Errorf("failed to record intent in outbox: %w", err)
+	}
+
+	result, callErr := execute(context.WithValue(ctx, idempotencyKey{}, entry.IdempotencyKey))
+
+	// Confirm the call; if this fails the entry stays pending and the step
+	// is reconciled on resume
+	entry.UpdatedAt = time.Now()
+	if callErr != nil {
+		entry.Status = OutboxFailed
+		entry.Error = callErr.Error()
+	} else {
+		entry.Status = OutboxConfirmed
+		entry.Output = result
+	}
+	if err := e.outbox.Save(ctx, entry); err != nil {
+		fmt.Printf("Failed to confirm step %d of plan %s in outbox: %v\n", index+1, plan.TaskID, err)
+	}
+	return result, callErr
+}
+
+// reconcile asks the reconciler what to do with an unconfirmed step
+func (e *Executor) reconcile(ctx context.Context, entry OutboxEntry) (ReconcileAction, string, error) {
+	if e.reconciler == nil {
+		return "", "", fmt.Errorf("%w: step %d (%s)", ErrUnreconciled, entry.StepIndex+1, entry.ToolName)
+	}
+	action, output, err := e.reconciler.Reconcile(ctx, entry)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to reconcile step %d (%s): %w", entry.StepIndex+1, entry.ToolName, err)
+	}
+	switch action {
+	case ReconcileRetry, ReconcileApplied:
+		return action, output, nil
+	default:
+		return "", "", fmt.Errorf("%w: step %d (%s) was aborted by the reconciler", ErrUnreconciled, entry.StepIndex+1, entry.ToolName)
+	}
+}
+
+// MemoryOutbox is an Outbox that keeps entries in memory
+type MemoryOutbox struct {
+	mu      sync.RWMutex
+	entries map[string]map[int]OutboxEntry
+}
+
+// NewMemoryOutbox creates an empty in-memory outbox
+func NewMemoryOutbox() *MemoryOutbox {
+	return &MemoryOutbox{entries: make(map[string]map[int]OutboxEntry)}
+}
+
+// Save creates or replaces the entry of a step
+func (o *MemoryOutbox) Save(ctx context.Context, entry OutboxEntry) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	steps, ok := o.entries[entry.TaskID]
+	if !ok {
+		steps = make(map[int]OutboxEntry)
+		o.entries[entry.TaskID] = steps
+	}
+	steps[entry.StepIndex] = entry
+	return nil
+}
+
+// Get returns the entry of a step
+func (o *MemoryOutbox) Get(ctx context.Context, taskID string, stepIndex int) (OutboxEntry, bool, error) {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	entry, ok := o.entries[taskID][stepIndex]
+	return entry, ok, nil
+}
+
+// List returns the entries of a plan, in step order
+func (o *MemoryOutbox) List(ctx context.Context, taskID string) ([]OutboxEntry, error) {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	entries := make([]OutboxEntry, 0, len(o.entries[taskID]))
+	for _, entry := range o.entries[taskID] {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].StepIndex < entries[j].StepIndex
+	})
+	return entries, nil
+}
+
+// ListPending returns the entries of all plans that were never confirmed or
+// failed, oldest first
+func (o *MemoryOutbox) ListPending(ctx context.Context) ([]OutboxEntry, error) {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	var entries []OutboxEntry
+	for _, steps := range o.entries {
+		for _, entry := range steps {
+			if entry.Status == OutboxPending {
+				entries = append(entries, entry)
+			}
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].CreatedAt.Before(entries[j].CreatedAt)
+	})
+	return entries, nil
+}
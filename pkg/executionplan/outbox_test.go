@@ -0,0 +1,218 @@
+package executionplan
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+	"github.com/run-bigpig/llm-agent/pkg/runctx"
+)
+
+// chargeTool records the idempotency keys it is called with
+type chargeTool struct {
+	keys []string
+}
+
+func (t *chargeTool) Name() string                                    { return "charge" }
+func (t *chargeTool) Description() string                             { return "Charges a card" }
+func (t *chargeTool) Parameters() map[string]interfaces.ParameterSpec { return nil }
+func (t *chargeTool) Run(ctx context.Context, input string) (string, error) {
+	return t.Execute(ctx, input)
+}
+func (t *chargeTool) Execute(ctx context.Context, args string) (string, error) {
+	key, _ := IdempotencyKey(ctx)
+	t.keys = append(t.keys, key)
+	if args == "fail" {
+		return "", errors.New("card declined")
+	}
+	return "charged " + args, nil
+}
+
+func newChargePlan(inputs ...string) *ExecutionPlan {
+	steps := make([]ExecutionStep, len(inputs))
+	for i, input := range inputs {
+		steps[i] = ExecutionStep{ToolName: "charge", Input: input}
+	}
+	plan := NewExecutionPlan("Charge", steps)
+	plan.UserApproved = true
+	return plan
+}
+
+func TestOutboxConfirmsSideEffects(t *testing.T) {
+	outbox := NewMemoryOutbox()
+	tool := &chargeTool{}
+	executor := NewExecutor([]interfaces.Tool{tool}, WithOutbox(outbox, "charge"))
+
+	plan := newChargePlan("10", "20")
+	if _, err := executor.ExecutePlan(context.Background(), plan); err != nil {
+		t.Fatalf("failed to execute plan: %v", err)
+	}
+
+	entries, err := outbox.List(context.Background(), plan.TaskID)
+	if err != nil {
+		t.Fatalf("failed to list outbox: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 outbox entries, got %d", len(entries))
+	}
+	for i, entry := range entries {
+		if entry.Status != OutboxConfirmed || entry.Attempts != 1 {
+			t.Errorf("entry %d: expected a confirmed first attempt, got %+v", i, entry)
+		}
+		if entry.IdempotencyKey != tool.keys[i] {
+			t.Errorf("entry %d: expected the tool to get key %q, got %q", i, entry.IdempotencyKey, tool.keys[i])
+		}
+	}
+
+	// Executing the plan again does not repeat confirmed calls
+	if _, err := executor.ExecutePlan(context.Background(), plan); err != nil {
+		t.Fatalf("failed to execute plan again: %v", err)
+	}
+	if len(tool.keys) != 2 {
+		t.Fatalf("expected confirmed calls not to be repeated, got %d calls", len(tool.keys))
+	}
+}
+
+func TestOutboxRetriesFailedSteps(t *testing.T) {
+	outbox := NewMemoryOutbox()
+	tool := &chargeTool{}
+	executor := NewExecutor([]interfaces.Tool{tool}, WithOutbox(outbox, "charge"))
+
+	plan := newChargePlan("fail")
+	if _, err := executor.ExecutePlan(context.Background(), plan); err == nil {
+		t.Fatal("expected the plan to fail")
+	}
+	entry, ok, _ := outbox.Get(context.Background(), plan.TaskID, 0)
+	if !ok || entry.Status != OutboxFailed || entry.Error != "card declined" {
+		t.Fatalf("expected a failed entry, got %+v", entry)
+	}
+
+	plan.Steps[0].Input = "10"
+	if _, err := executor.ExecutePlan(context.Background(), plan); err != nil {
+		t.Fatalf("failed to execute plan again: %v", err)
+	}
+	entry, _, _ = outbox.Get(context.Background(), plan.TaskID, 0)
+	if entry.Status != OutboxConfirmed || entry.Attempts != 2 {
+		t.Fatalf("expected a confirmed second attempt, got %+v", entry)
+	}
+	if tool.keys[0] != tool.keys[1] {
+		t.Fatalf("expected retries to reuse the idempotency key, got %v", tool.keys)
+	}
+}
+
+func TestOutboxReconcilesPendingSteps(t *testing.T) {
+	ctx := context.Background()
+	tool := &chargeTool{}
+	plan := newChargePlan("10", "20")
+
+	// Simulate a crash after the intent of step 1 was recorded
+	pendingOutbox := func() *MemoryOutbox {
+		outbox := NewMemoryOutbox()
+		_ = outbox.Save(ctx, OutboxEntry{TaskID: plan.TaskID, StepIndex: 0, ToolName: "charge", Input: "10", IdempotencyKey: plan.TaskID + ":0", Status: OutboxPending, Attempts: 1})
+		return outbox
+	}
+
+	t.Run("without reconciler", func(t *testing.T) {
+		outbox := pendingOutbox()
+		executor := NewExecutor([]interfaces.Tool{tool}, WithOutbox(outbox, "charge"))
+		if _, err := executor.ExecutePlan(ctx, plan); !errors.Is(err, ErrUnreconciled) {
+			t.Fatalf("expected ErrUnreconciled, got %v", err)
+		}
+		pending, _ := outbox.ListPending(ctx)
+		if len(pending) != 1 {
+			t.Fatalf("expected the step to stay pending, got %+v", pending)
+		}
+	})
+
+	t.Run("applied", func(t *testing.T) {
+		tool.keys = nil
+		outbox := pendingOutbox()
+		reconciler := ReconcilerFunc(func(ctx context.Context, entry OutboxEntry) (ReconcileAction, string, error) {
+			return ReconcileApplied, "charged 10 (reconciled)", nil
+		})
+		executor := NewExecutor([]interfaces.Tool{tool}, WithOutbox(outbox, "charge"), WithReconciler(reconciler))
+		if _, err := executor.ExecutePlan(ctx, plan); err != nil {
+			t.Fatalf("failed to resume plan: %v", err)
+		}
+		if len(tool.keys) != 1 {
+			t.Fatalf("expected only step 2 to call the tool, got %d calls", len(tool.keys))
+		}
+		entry, _, _ := outbox.Get(ctx, plan.TaskID, 0)
+		if entry.Status != OutboxConfirmed || entry.Output != "charged 10 (reconciled)" {
+			t.Fatalf("expected the reconciled step to be confirmed, got %+v", entry)
+		}
+	})
+
+	t.Run("retry", func(t *testing.T) {
+		tool.keys = nil
+		outbox := pendingOutbox()
+		reconciler := ReconcilerFunc(func(ctx context.Context, entry OutboxEntry) (ReconcileAction, string, error) {
+			return ReconcileRetry, "", nil
+		})
+		executor := NewExecutor([]interfaces.Tool{tool}, WithOutbox(outbox, "charge"), WithReconciler(reconciler))
+		if _, err := executor.ExecutePlan(ctx, plan); err != nil {
+			t.Fatalf("failed to resume plan: %v", err)
+		}
+		entry, _, _ := outbox.Get(ctx, plan.TaskID, 0)
+		if len(tool.keys) != 2 || entry.Attempts != 2 {
+			t.Fatalf("expected step 1 to be retried, got %d calls and %+v", len(tool.keys), entry)
+		}
+	})
+
+	t.Run("abort", func(t *testing.T) {
+		reconciler := ReconcilerFunc(func(ctx context.Context, entry OutboxEntry) (ReconcileAction, string, error) {
+			return ReconcileAbort, "", nil
+		})
+		executor := NewExecutor([]interfaces.Tool{tool}, WithOutbox(pendingOutbox(), "charge"), WithReconciler(reconciler))
+		if _, err := executor.ExecutePlan(ctx, plan); !errors.Is(err, ErrUnreconciled) {
+			t.Fatalf("expected ErrUnreconciled, got %v", err)
+		}
+	})
+}
+
+func TestOutboxIgnoresOtherTools(t *testing.T) {
+	outbox := NewMemoryOutbox()
+	executor := NewExecutor([]interfaces.Tool{&upperTool{}}, WithOutbox(outbox, "charge"))
+
+	plan := NewExecutionPlan("Upper", []ExecutionStep{{ToolName: "upper", Input: "a"}})
+	plan.UserApproved = true
+	if _, err := executor.ExecutePlan(context.Background(), plan); err != nil {
+		t.Fatalf("failed to execute plan: %v", err)
+	}
+	if entries, _ := outbox.List(context.Background(), plan.TaskID); len(entries) != 0 {
+		t.Fatalf("expected no outbox entries for other tools, got %+v", entries)
+	}
+}
+
+func TestDryRunSkipsOutbox(t *testing.T) {
+	outbox := NewMemoryOutbox()
+	history := NewMemoryHistory()
+	tool := &chargeTool{}
+	executor := NewExecutor([]interfaces.Tool{tool, &upperTool{}}, WithOutbox(outbox, "charge"), WithHistory(history, "billing"))
+
+	plan := newChargePlan("10")
+	plan.Steps = append(plan.Steps, ExecutionStep{ToolName: "upper", Input: "a"})
+	result, err := executor.ExecutePlan(runctx.WithDryRun(context.Background(), true), plan)
+	if err != nil {
+		t.Fatalf("failed to dry-run plan: %v", err)
+	}
+	if len(tool.keys) != 0 {
+		t.Fatalf("expected the side effect to be simulated, got %d calls", len(tool.keys))
+	}
+	if !strings.Contains(result, "[dry run: charge was not called]") || !strings.Contains(result, "Step 2 (): a:") {
+		t.Fatalf("unexpected dry-run result: %s", result)
+	}
+	if entries, _ := outbox.List(context.Background(), plan.TaskID); len(entries) != 0 {
+		t.Fatalf("expected a dry run not to touch the outbox, got %+v", entries)
+	}
+	if plan.Status != StatusApproved {
+		t.Fatalf("expected the plan to stay approved, got %s", plan.Status)
+	}
+
+	runs, _ := history.ListRuns(context.Background(), HistoryFilter{})
+	if len(runs) != 1 || !runs[0].DryRun || !runs[0].Steps[0].Simulated || runs[0].Steps[1].Simulated {
+		t.Fatalf("expected a dry run with a simulated first step in the history, got %+v", runs)
+	}
+}
@@ -0,0 +1,208 @@
+// Package feedback records what users think of agent responses: thumbs up
+// or down, comments and corrections. Feedback is stored with the
+// conversation, request and trace it is about, and can be forwarded as
+// scores to a tracing backend such as Langfuse.
+package feedback
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/run-bigpig/llm-agent/pkg/logging"
+	"github.com/run-bigpig/llm-agent/pkg/runctx"
+)
+
+// Rating is a thumbs up or down
+type Rating string
+
+const (
+	// RatingUp means the response was helpful
+	RatingUp Rating = "up"
+
+	// RatingDown means the response was not helpful
+	RatingDown Rating = "down"
+)
+
+// Value returns the rating as a score: 1 for up and -1 for down
+func (r Rating) Value() float64 {
+	if r == RatingUp {
+		return 1
+	}
+	return -1
+}
+
+// ErrInvalid is returned for feedback that can't be recorded
+var ErrInvalid = errors.New("invalid feedback")
+
+// Feedback is a user's feedback on one agent response
+type Feedback struct {
+	// ID identifies the feedback; generated if empty
+	ID string `json:"id"`
+
+	// OrgID, UserID and ConversationID are taken from the context if empty
+	OrgID          string `json:"org_id,omitempty"`
+	UserID         string `json:"user_id,omitempty"`
+	ConversationID string `json:"conversation_id,omitempty"`
+
+	// RequestID and TraceID identify the run that produced the response, as
+	// reported in the agent's RunReport. At least one is required.
+	RequestID string `json:"request_id,omitempty"`
+	TraceID   string `json:"trace_id,omitempty"`
+
+	// Agent is the name of the agent that responded
+	Agent string `json:"agent,omitempty"`
+
+	// Rating is the thumbs up or down. A correction without a rating counts
+	// as a thumbs down.
+	Rating Rating `json:"rating,omitempty"`
+
+	// Correction is what the response should have been
+	Correction string `json:"correction,omitempty"`
+
+	// Comment is free-form text from the user
+	Comment string `json:"comment,omitempty"`
+
+	// Input and Response are the rated exchange, if the caller has them
+	Input    string `json:"input,omitempty"`
+	Response string `json:"response,omitempty"`
+
+	// CreatedAt is when the feedback was given; set when it is recorded
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Validate checks that the feedback can be recorded
+func (f *Feedback) Validate() error {
+	if f.RequestID == "" && f.TraceID == "" {
+		return fmt.Errorf("%w: a request ID or trace ID is required", ErrInvalid)
+	}
+	switch f.Rating {
+	case RatingUp, RatingDown:
+	case "":
+		if f.Correction == "" && f.Comment == "" {
+			return fmt.Errorf("%w: a rating, correction or comment is required", ErrInvalid)
+		}
+	default:
+		return fmt.Errorf("%w: unknown rating %q", ErrInvalid, f.Rating)
+	}
+	return nil
+}
+
+// Scorer attaches scores to traces, e.g. tracing.LangfuseTracer
+type Scorer interface {
+	// Score attaches a named score with a comment to a trace
+	Score(ctx context.Context, traceID, name string, value float64, comment string) error
+}
+
+// Recorder validates, stores and forwards feedback
+type Recorder struct {
+	store     Store
+	scorer    Scorer
+	scoreName string
+	logger    logging.Logger
+	now       func() time.Time
+}
+
+// Option configures a Recorder
+type Option func(*Recorder)
+
+// WithScorer forwards rated feedback on traced runs as scores. Thumbs up is
+// sent as 1 and thumbs down as -1, with the correction and comment as the
+// score's comment.
+func WithScorer(scorer Scorer) Option {
+	return func(r *Recorder) {
+		r.scorer = scorer
+	}
+}
+
+// WithScoreName sets the name of forwarded scores. Defaults to
+// "user_feedback".
+func WithScoreName(name string) Option {
+	return func(r *Recorder) {
+		r.scoreName = name
+	}
+}
+
+// WithLogger sets the logger
+func WithLogger(logger logging.Logger) Option {
+	return func(r *Recorder) {
+		r.logger = logger
+	}
+}
+
+// NewRecorder creates a recorder that saves feedback in a store
+func NewRecorder(store Store, options ...Option) *Recorder {
+	r := &Recorder{
+		store:     store,
+		scoreName: "user_feedback",
+		logger:    logging.New(),
+		now:       time.Now,
+	}
+	for _, option := range options {
+		option(r)
+	}
+	return r
+}
+
+// Record stores feedback and forwards it to the scorer. IDs missing from the
+// feedback are taken from the context. Forwarding failures are logged; the
+// feedback is stored either way.
+func (r *Recorder) Record(ctx context.Context, f Feedback) (Feedback, error) {
+	rc := runctx.From(ctx)
+	if f.OrgID == "" {
+		f.OrgID = rc.OrgID
+	}
+	if f.UserID == "" {
+		f.UserID = rc.UserID
+	}
+	if f.ConversationID == "" {
+		f.ConversationID = rc.ConversationID
+	}
+	if f.Rating == "" && f.Correction != "" {
+		f.Rating = RatingDown
+	}
+	if err := f.Validate(); err != nil {
+		return Feedback{}, err
+	}
+	if f.ID == "" {
+		f.ID = uuid.New().String()
+	}
+	if f.CreatedAt.IsZero() {
+		f.CreatedAt = r.now()
+	}
+
+	if err := r.store.Save(ctx, f); err != nil {
+		return Feedback{}, fmt.Errorf("failed to save feedback: %w", err)
+	}
+
+	if r.scorer != nil && f.TraceID != "" && f.Rating != "" {
+		if err := r.scorer.Score(ctx, f.TraceID, r.scoreName, f.Rating.Value(), scoreComment(f)); err != nil {
+			r.logger.Warn(ctx, "Failed to forward feedback score", map[string]interface{}{
+				"feedback_id": f.ID,
+				"trace_id":    f.TraceID,
+				"error":       err.Error(),
+			})
+		}
+	}
+	return f, nil
+}
+
+// List returns the stored feedback matching the filter
+func (r *Recorder) List(ctx context.Context, filter Filter) ([]Feedback, error) {
+	return r.store.List(ctx, filter)
+}
+
+// scoreComment combines the correction and comment for a score
+func scoreComment(f Feedback) string {
+	switch {
+	case f.Correction != "" && f.Comment != "":
+		return f.Comment + "\n\nCorrection: " + f.Correction
+	case f.Correction != "":
+		return "Correction: " + f.Correction
+	default:
+		return f.Comment
+	}
+}
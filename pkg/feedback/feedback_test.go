@@ -0,0 +1,114 @@
+package feedback_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/run-bigpig/llm-agent/pkg/feedback"
+	"github.com/run-bigpig/llm-agent/pkg/runctx"
+)
+
+// score is a score sent to the fake scorer
+type score struct {
+	traceID, name, comment string
+	value                  float64
+}
+
+type fakeScorer struct {
+	scores []score
+	err    error
+}
+
+func (s *fakeScorer) Score(ctx context.Context, traceID, name string, value float64, comment string) error {
+	s.scores = append(s.scores, score{traceID: traceID, name: name, value: value, comment: comment})
+	return s.err
+}
+
+func TestRecorder(t *testing.T) {
+	store := feedback.NewMemoryStore()
+	scorer := &fakeScorer{}
+	recorder := feedback.NewRecorder(store, feedback.WithScorer(scorer))
+
+	ctx, cancel := runctx.With(context.Background(), runctx.RunContext{OrgID: "org-1", UserID: "user-1", ConversationID: "conv-1"})
+	defer cancel()
+
+	up, err := recorder.Record(ctx, feedback.Feedback{RequestID: "req-1", TraceID: "trace-1", Rating: feedback.RatingUp})
+	require.NoError(t, err)
+	assert.NotEmpty(t, up.ID)
+	assert.False(t, up.CreatedAt.IsZero())
+	assert.Equal(t, "org-1", up.OrgID)
+	assert.Equal(t, "user-1", up.UserID)
+	assert.Equal(t, "conv-1", up.ConversationID)
+
+	// A correction counts as a thumbs down
+	corrected, err := recorder.Record(ctx, feedback.Feedback{TraceID: "trace-2", Correction: "Paris is in France."})
+	require.NoError(t, err)
+	assert.Equal(t, feedback.RatingDown, corrected.Rating)
+
+	// Feedback without a trace is stored but not scored
+	_, err = recorder.Record(ctx, feedback.Feedback{RequestID: "req-3", Comment: "Too long"})
+	require.NoError(t, err)
+
+	assert.Equal(t, []score{
+		{traceID: "trace-1", name: "user_feedback", value: 1},
+		{traceID: "trace-2", name: "user_feedback", value: -1, comment: "Correction: Paris is in France."},
+	}, scorer.scores)
+
+	down, err := recorder.List(ctx, feedback.Filter{OrgID: "org-1", Rating: feedback.RatingDown})
+	require.NoError(t, err)
+	require.Len(t, down, 1)
+	assert.Equal(t, "trace-2", down[0].TraceID)
+	all, err := recorder.List(ctx, feedback.Filter{ConversationID: "conv-1"})
+	require.NoError(t, err)
+	assert.Len(t, all, 3)
+
+	// Scoring failures don't lose the feedback
+	scorer.err = errors.New("langfuse down")
+	_, err = recorder.Record(ctx, feedback.Feedback{TraceID: "trace-4", Rating: feedback.RatingUp})
+	require.NoError(t, err)
+
+	for _, invalid := range []feedback.Feedback{
+		{Rating: feedback.RatingUp},
+		{RequestID: "req-5"},
+		{RequestID: "req-5", Rating: "meh"},
+	} {
+		_, err := recorder.Record(ctx, invalid)
+		assert.ErrorIs(t, err, feedback.ErrInvalid)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	store := feedback.NewMemoryStore()
+	handler := feedback.NewRecorder(store).Handler()
+
+	post := func(ctx context.Context, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/feedback", bytes.NewBufferString(body)).WithContext(ctx)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// The organization from authentication wins over the body
+	ctx := runctx.WithOrgID(context.Background(), "org-1")
+	rec := post(ctx, `{"org_id": "org-2", "request_id": "req-1", "rating": "down", "comment": "wrong answer"}`)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	var recorded feedback.Feedback
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &recorded))
+	assert.Equal(t, "org-1", recorded.OrgID)
+	assert.Equal(t, feedback.RatingDown, recorded.Rating)
+
+	assert.Equal(t, http.StatusBadRequest, post(ctx, `{"rating": "up"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, post(ctx, `not json`).Code)
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/feedback", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
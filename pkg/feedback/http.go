@@ -0,0 +1,60 @@
+package feedback
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/run-bigpig/llm-agent/pkg/runctx"
+)
+
+// maxRequestSize limits the size of a feedback request body
+const maxRequestSize = 1 << 20
+
+// Handler returns an http.Handler that records feedback POSTed as JSON and
+// responds 201 with the recorded feedback. The organization and user set on
+// the request context by authentication middleware take precedence over the
+// ones in the body.
+func (r *Recorder) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+
+		var f Feedback
+		if err := json.NewDecoder(http.MaxBytesReader(w, req.Body, maxRequestSize)).Decode(&f); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid JSON: "+err.Error())
+			return
+		}
+		ctx := req.Context()
+		if orgID := runctx.OrgID(ctx); orgID != "" {
+			f.OrgID = orgID
+		}
+		if userID := runctx.UserID(ctx); userID != "" {
+			f.UserID = userID
+		}
+
+		recorded, err := r.Record(ctx, f)
+		switch {
+		case errors.Is(err, ErrInvalid):
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		case err != nil:
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(recorded)
+	})
+}
+
+// writeError writes an error as JSON
+func writeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
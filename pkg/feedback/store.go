@@ -0,0 +1,84 @@
+package feedback
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Filter selects stored feedback. Empty fields match everything.
+type Filter struct {
+	OrgID          string
+	ConversationID string
+	RequestID      string
+	TraceID        string
+	Rating         Rating
+
+	// Since and Until limit when the feedback was given; Until is exclusive
+	Since time.Time
+	Until time.Time
+}
+
+// Matches reports whether feedback matches the filter
+func (f Filter) Matches(fb Feedback) bool {
+	switch {
+	case f.OrgID != "" && fb.OrgID != f.OrgID,
+		f.ConversationID != "" && fb.ConversationID != f.ConversationID,
+		f.RequestID != "" && fb.RequestID != f.RequestID,
+		f.TraceID != "" && fb.TraceID != f.TraceID,
+		f.Rating != "" && fb.Rating != f.Rating,
+		!f.Since.IsZero() && fb.CreatedAt.Before(f.Since),
+		!f.Until.IsZero() && !fb.CreatedAt.Before(f.Until):
+		return false
+	}
+	return true
+}
+
+// Store persists feedback
+type Store interface {
+	// Save saves feedback, replacing feedback with the same ID
+	Save(ctx context.Context, feedback Feedback) error
+
+	// List returns the feedback matching the filter, oldest first
+	List(ctx context.Context, filter Filter) ([]Feedback, error)
+}
+
+// MemoryStore keeps feedback in memory, for tests and single-process use
+type MemoryStore struct {
+	mu       sync.RWMutex
+	feedback map[string]Feedback
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{feedback: make(map[string]Feedback)}
+}
+
+// Save saves feedback, replacing feedback with the same ID
+func (s *MemoryStore) Save(ctx context.Context, feedback Feedback) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.feedback[feedback.ID] = feedback
+	return nil
+}
+
+// List returns the feedback matching the filter, oldest first
+func (s *MemoryStore) List(ctx context.Context, filter Filter) ([]Feedback, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var result []Feedback
+	for _, fb := range s.feedback {
+		if filter.Matches(fb) {
+			result = append(result, fb)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if !result[i].CreatedAt.Equal(result[j].CreatedAt) {
+			return result[i].CreatedAt.Before(result[j].CreatedAt)
+		}
+		return result[i].ID < result[j].ID
+	})
+	return result, nil
+}
@@ -0,0 +1,286 @@
+// Package finetune runs OpenAI fine-tuning jobs: it uploads a dataset,
+// creates a job, waits for it to finish and registers the resulting model,
+// so that data logged by agents (see the dataset package) can be turned into
+// better models.
+package finetune
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+
+	"github.com/run-bigpig/llm-agent/pkg/logging"
+)
+
+// Status is the status of a fine-tuning job
+type Status string
+
+// Statuses of a fine-tuning job
+const (
+	StatusValidatingFiles Status = "validating_files"
+	StatusQueued          Status = "queued"
+	StatusRunning         Status = "running"
+	StatusSucceeded       Status = "succeeded"
+	StatusFailed          Status = "failed"
+	StatusCancelled       Status = "cancelled"
+)
+
+// Done reports whether a job with the status has finished
+func (s Status) Done() bool {
+	return s == StatusSucceeded || s == StatusFailed || s == StatusCancelled
+}
+
+// JobRequest describes a fine-tuning job
+type JobRequest struct {
+	// BaseModel is the model to fine-tune, e.g. "gpt-4o-mini-2024-07-18"
+	BaseModel string
+
+	// TrainingFile and ValidationFile are the IDs of uploaded datasets
+	TrainingFile   string
+	ValidationFile string
+
+	// Suffix is added to the name of the fine-tuned model
+	Suffix string
+
+	// Epochs is the number of passes over the training data; zero lets the
+	// provider choose
+	Epochs int
+}
+
+// Job is a fine-tuning job
+type Job struct {
+	ID             string    `json:"id"`
+	Status         Status    `json:"status"`
+	BaseModel      string    `json:"base_model"`
+	FineTunedModel string    `json:"fine_tuned_model,omitempty"`
+	TrainingFile   string    `json:"training_file"`
+	ValidationFile string    `json:"validation_file,omitempty"`
+	TrainedTokens  int       `json:"trained_tokens,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+	FinishedAt     time.Time `json:"finished_at"`
+}
+
+// Client runs fine-tuning jobs
+type Client struct {
+	client       *openai.Client
+	httpClient   *http.Client
+	pollInterval time.Duration
+	registry     Registry
+	logger       logging.Logger
+}
+
+// Option configures a Client
+type Option func(*Client)
+
+// WithHTTPClient sets the HTTP client
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithPollInterval sets how often Wait checks on a job. Defaults to 30
+// seconds.
+func WithPollInterval(interval time.Duration) Option {
+	return func(c *Client) {
+		c.pollInterval = interval
+	}
+}
+
+// WithRegistry sets the registry that Train registers models in. Defaults
+// to an in-memory registry.
+func WithRegistry(registry Registry) Option {
+	return func(c *Client) {
+		c.registry = registry
+	}
+}
+
+// WithLogger sets the logger
+func WithLogger(logger logging.Logger) Option {
+	return func(c *Client) {
+		c.logger = logger
+	}
+}
+
+// NewClient creates a fine-tuning client. An empty baseURL uses the OpenAI
+// API.
+func NewClient(baseURL, apiKey string, options ...Option) *Client {
+	if baseURL == "" {
+		baseURL = "https://api.openai.com/v1"
+	}
+	c := &Client{
+		pollInterval: 30 * time.Second,
+		logger:       logging.New(),
+	}
+	for _, option := range options {
+		option(c)
+	}
+	if c.registry == nil {
+		c.registry = NewMemoryRegistry()
+	}
+
+	config := openai.DefaultConfig(apiKey)
+	config.BaseURL = baseURL
+	if c.httpClient != nil {
+		config.HTTPClient = c.httpClient
+	}
+	c.client = openai.NewClientWithConfig(config)
+	return c
+}
+
+// Registry returns the registry models are registered in
+func (c *Client) Registry() Registry {
+	return c.registry
+}
+
+// UploadDataset uploads a JSONL dataset in the OpenAI chat format, e.g. one
+// written by dataset.Export with dataset.FormatOpenAIChat, and returns its
+// file ID
+func (c *Client) UploadDataset(ctx context.Context, name string, data io.Reader) (string, error) {
+	content, err := io.ReadAll(data)
+	if err != nil {
+		return "", fmt.Errorf("failed to read dataset: %w", err)
+	}
+	file, err := c.client.CreateFileBytes(ctx, openai.FileBytesRequest{
+		Name:    name,
+		Bytes:   content,
+		Purpose: openai.PurposeFineTune,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to upload dataset: %w", err)
+	}
+	return file.ID, nil
+}
+
+// CreateJob starts a fine-tuning job
+func (c *Client) CreateJob(ctx context.Context, request JobRequest) (*Job, error) {
+	jobRequest := openai.FineTuningJobRequest{
+		TrainingFile:   request.TrainingFile,
+		ValidationFile: request.ValidationFile,
+		Model:          request.BaseModel,
+		Suffix:         request.Suffix,
+	}
+	if request.Epochs > 0 {
+		jobRequest.Hyperparameters = &openai.Hyperparameters{Epochs: request.Epochs}
+	}
+	job, err := c.client.CreateFineTuningJob(ctx, jobRequest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create fine-tuning job: %w", err)
+	}
+	return fromOpenAI(job), nil
+}
+
+// GetJob returns the current state of a job
+func (c *Client) GetJob(ctx context.Context, jobID string) (*Job, error) {
+	job, err := c.client.RetrieveFineTuningJob(ctx, jobID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get fine-tuning job %s: %w", jobID, err)
+	}
+	return fromOpenAI(job), nil
+}
+
+// CancelJob cancels a job
+func (c *Client) CancelJob(ctx context.Context, jobID string) (*Job, error) {
+	job, err := c.client.CancelFineTuningJob(ctx, jobID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to cancel fine-tuning job %s: %w", jobID, err)
+	}
+	return fromOpenAI(job), nil
+}
+
+// Wait polls a job until it finishes and returns its final state. A job
+// that failed or was cancelled is returned with an error.
+func (c *Client) Wait(ctx context.Context, jobID string) (*Job, error) {
+	ticker := time.NewTicker(c.pollInterval)
+	defer ticker.Stop()
+
+	lastStatus := Status("")
+	for {
+		job, err := c.GetJob(ctx, jobID)
+		if err != nil {
+			return nil, err
+		}
+		if job.Status != lastStatus {
+			c.logger.Info(ctx, "Fine-tuning job status", map[string]interface{}{
+				"job_id": jobID,
+				"status": job.Status,
+			})
+			lastStatus = job.Status
+		}
+		if job.Status.Done() {
+			if job.Status != StatusSucceeded {
+				return job, fmt.Errorf("fine-tuning job %s %s", jobID, job.Status)
+			}
+			return job, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return job, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Register registers the model of a succeeded job under a name
+func (c *Client) Register(ctx context.Context, name string, job *Job) (Model, error) {
+	if job.Status != StatusSucceeded || job.FineTunedModel == "" {
+		return Model{}, fmt.Errorf("fine-tuning job %s has no model to register (status %s)", job.ID, job.Status)
+	}
+	model, err := c.registry.Register(ctx, Model{
+		Name:         name,
+		ID:           job.FineTunedModel,
+		BaseModel:    job.BaseModel,
+		JobID:        job.ID,
+		TrainingFile: job.TrainingFile,
+	})
+	if err != nil {
+		return Model{}, fmt.Errorf("failed to register model %s: %w", name, err)
+	}
+	return model, nil
+}
+
+// Train uploads a dataset, fine-tunes a model on it, waits for the job and
+// registers the model under name. TrainingFile in the request is replaced by
+// the uploaded dataset.
+func (c *Client) Train(ctx context.Context, name string, data io.Reader, request JobRequest) (Model, error) {
+	fileID, err := c.UploadDataset(ctx, name+".jsonl", data)
+	if err != nil {
+		return Model{}, err
+	}
+	request.TrainingFile = fileID
+
+	job, err := c.CreateJob(ctx, request)
+	if err != nil {
+		return Model{}, err
+	}
+	job, err = c.Wait(ctx, job.ID)
+	if err != nil {
+		return Model{}, err
+	}
+	return c.Register(ctx, name, job)
+}
+
+// fromOpenAI converts a job returned by the API
+func fromOpenAI(job openai.FineTuningJob) *Job {
+	result := &Job{
+		ID:             job.ID,
+		Status:         Status(job.Status),
+		BaseModel:      job.Model,
+		FineTunedModel: job.FineTunedModel,
+		TrainingFile:   job.TrainingFile,
+		ValidationFile: job.ValidationFile,
+		TrainedTokens:  job.TrainedTokens,
+	}
+	if job.CreatedAt > 0 {
+		result.CreatedAt = time.Unix(job.CreatedAt, 0)
+	}
+	if job.FinishedAt > 0 {
+		result.FinishedAt = time.Unix(job.FinishedAt, 0)
+	}
+	return result
+}
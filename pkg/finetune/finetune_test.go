@@ -0,0 +1,134 @@
+package finetune_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/run-bigpig/llm-agent/pkg/finetune"
+)
+
+// fakeAPI serves the OpenAI files and fine-tuning endpoints. The job
+// reaches status on the second status check.
+type fakeAPI struct {
+	mu       sync.Mutex
+	uploaded string
+	request  map[string]interface{}
+	polls    int
+	status   string
+}
+
+func (f *fakeAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == "/files":
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		data, _ := io.ReadAll(file)
+		f.uploaded = string(data)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"id": "file-1", "purpose": r.FormValue("purpose")})
+	case r.Method == http.MethodPost && r.URL.Path == "/fine_tuning/jobs":
+		_ = json.NewDecoder(r.Body).Decode(&f.request)
+		_ = json.NewEncoder(w).Encode(f.job("queued"))
+	case r.Method == http.MethodGet && r.URL.Path == "/fine_tuning/jobs/ftjob-1":
+		f.polls++
+		status := "running"
+		if f.polls >= 2 {
+			status = f.status
+		}
+		_ = json.NewEncoder(w).Encode(f.job(status))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (f *fakeAPI) job(status string) map[string]interface{} {
+	job := map[string]interface{}{
+		"id":            "ftjob-1",
+		"status":        status,
+		"model":         "gpt-4o-mini",
+		"training_file": "file-1",
+		"created_at":    1700000000,
+	}
+	if status == "succeeded" {
+		job["fine_tuned_model"] = "ft:gpt-4o-mini:acme::abc123"
+	}
+	return job
+}
+
+func TestTrain(t *testing.T) {
+	api := &fakeAPI{status: "succeeded"}
+	server := httptest.NewServer(api)
+	defer server.Close()
+
+	client := finetune.NewClient(server.URL, "key", finetune.WithPollInterval(time.Millisecond))
+	dataset := `{"messages":[{"role":"user","content":"hi"},{"role":"assistant","content":"hello"}]}` + "\n"
+
+	model, err := client.Train(context.Background(), "support-agent", strings.NewReader(dataset), finetune.JobRequest{
+		BaseModel: "gpt-4o-mini",
+		Suffix:    "support",
+		Epochs:    3,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, dataset, api.uploaded)
+	assert.Equal(t, "file-1", api.request["training_file"])
+	assert.Equal(t, "support", api.request["suffix"])
+	assert.Equal(t, map[string]interface{}{"n_epochs": float64(3)}, api.request["hyperparameters"])
+
+	assert.Equal(t, "ft:gpt-4o-mini:acme::abc123", model.ID)
+	assert.Equal(t, 1, model.Version)
+	assert.Equal(t, "ftjob-1", model.JobID)
+
+	latest, err := client.Registry().Get(context.Background(), "support-agent")
+	require.NoError(t, err)
+	assert.Equal(t, model, latest)
+}
+
+func TestWaitReportsFailedJobs(t *testing.T) {
+	server := httptest.NewServer(&fakeAPI{status: "failed"})
+	defer server.Close()
+
+	client := finetune.NewClient(server.URL, "key", finetune.WithPollInterval(time.Millisecond))
+	job, err := client.Wait(context.Background(), "ftjob-1")
+	require.Error(t, err)
+	assert.Equal(t, finetune.StatusFailed, job.Status)
+
+	_, err = client.Register(context.Background(), "support-agent", job)
+	assert.Error(t, err)
+}
+
+func TestMemoryRegistryVersions(t *testing.T) {
+	registry := finetune.NewMemoryRegistry()
+	ctx := context.Background()
+
+	_, err := registry.Get(ctx, "support-agent")
+	assert.ErrorIs(t, err, finetune.ErrModelNotFound)
+
+	_, err = registry.Register(ctx, finetune.Model{Name: "support-agent", ID: "ft:v1"})
+	require.NoError(t, err)
+	second, err := registry.Register(ctx, finetune.Model{Name: "support-agent", ID: "ft:v2"})
+	require.NoError(t, err)
+	assert.Equal(t, 2, second.Version)
+
+	latest, err := registry.Get(ctx, "support-agent")
+	require.NoError(t, err)
+	assert.Equal(t, "ft:v2", latest.ID)
+	versions, err := registry.Versions(ctx, "support-agent")
+	require.NoError(t, err)
+	assert.Len(t, versions, 2)
+}
@@ -0,0 +1,112 @@
+package finetune
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrModelNotFound is returned for a model name that isn't registered
+var ErrModelNotFound = errors.New("model not found")
+
+// Model is a fine-tuned model registered under a name
+type Model struct {
+	// Name is the name teams refer to the model by, e.g. "support-agent"
+	Name string `json:"name"`
+
+	// ID is the provider's model ID, e.g. "ft:gpt-4o-mini:acme::abc123", to
+	// pass to openai.WithModel
+	ID string `json:"id"`
+
+	// BaseModel is the model that was fine-tuned
+	BaseModel string `json:"base_model"`
+
+	// JobID is the fine-tuning job that produced the model
+	JobID string `json:"job_id,omitempty"`
+
+	// TrainingFile is the ID of the dataset the model was trained on
+	TrainingFile string `json:"training_file,omitempty"`
+
+	// Version counts the models registered under the name, starting at 1
+	Version int `json:"version"`
+
+	// RegisteredAt is when the model was registered
+	RegisteredAt time.Time `json:"registered_at"`
+}
+
+// Registry keeps track of fine-tuned models by name. Registering a model
+// under a name that is taken adds a new version, and the latest version is
+// the one in use.
+type Registry interface {
+	// Register adds a model as the latest version of its name and returns it
+	// with its version set
+	Register(ctx context.Context, model Model) (Model, error)
+
+	// Get returns the latest version of a model
+	Get(ctx context.Context, name string) (Model, error)
+
+	// Versions returns every version of a model, oldest first
+	Versions(ctx context.Context, name string) ([]Model, error)
+}
+
+// MemoryRegistry is a Registry that keeps models in memory
+type MemoryRegistry struct {
+	mu     sync.RWMutex
+	models map[string][]Model
+	now    func() time.Time
+}
+
+// NewMemoryRegistry creates an empty in-memory registry
+func NewMemoryRegistry() *MemoryRegistry {
+	return &MemoryRegistry{
+		models: make(map[string][]Model),
+		now:    time.Now,
+	}
+}
+
+// Register adds a model as the latest version of its name and returns it
+// with its version set
+func (r *MemoryRegistry) Register(ctx context.Context, model Model) (Model, error) {
+	if model.Name == "" || model.ID == "" {
+		return Model{}, fmt.Errorf("a model needs a name and an ID")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	model.Version = len(r.models[model.Name]) + 1
+	if model.RegisteredAt.IsZero() {
+		model.RegisteredAt = r.now()
+	}
+	r.models[model.Name] = append(r.models[model.Name], model)
+	return model, nil
+}
+
+// Get returns the latest version of a model
+func (r *MemoryRegistry) Get(ctx context.Context, name string) (Model, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	versions := r.models[name]
+	if len(versions) == 0 {
+		return Model{}, fmt.Errorf("%w: %s", ErrModelNotFound, name)
+	}
+	return versions[len(versions)-1], nil
+}
+
+// Versions returns every version of a model, oldest first
+func (r *MemoryRegistry) Versions(ctx context.Context, name string) ([]Model, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	versions := r.models[name]
+	if len(versions) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrModelNotFound, name)
+	}
+	result := make([]Model, len(versions))
+	copy(result, versions)
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Version < result[j].Version
+	})
+	return result, nil
+}
@@ -25,6 +25,17 @@ const (
 
 	// RateLimitGuardrail limits the rate of requests
 	RateLimitGuardrail GuardrailType = "rate_limit"
+
+	// OutputLimitGuardrail limits the length of responses and catches
+	// repetition loops
+	OutputLimitGuardrail GuardrailType = "output_limit"
+
+	// PromptInjectionGuardrail detects instructions injected into untrusted
+	// content
+	PromptInjectionGuardrail GuardrailType = "prompt_injection"
+
+	// LanguageGuardrail checks the language of responses
+	LanguageGuardrail GuardrailType = "language"
 )
 
 // Action represents the action to take when a guardrail is triggered
@@ -39,8 +50,33 @@ const (
 
 	// WarnAction allows the content but logs a warning
 	WarnAction Action = "warn"
+
+	// RegenerateAction asks the LLM for a new response. Where that isn't
+	// possible it acts like RedactAction.
+	RegenerateAction Action = "regenerate"
 )
 
+// RegenerateError is returned by Pipeline.ProcessResponse when a guardrail
+// with RegenerateAction is triggered
+type RegenerateError struct {
+	// Guardrail is the type of the triggered guardrail
+	Guardrail GuardrailType
+
+	// Feedback tells the LLM what was wrong with the response
+	Feedback string
+}
+
+// Error returns the error message
+func (e *RegenerateError) Error() string {
+	return fmt.Sprintf("response rejected by %s guardrail", e.Guardrail)
+}
+
+// FeedbackProvider is implemented by guardrails that can explain why they
+// rejected a response, for asking the LLM to answer again
+type FeedbackProvider interface {
+	Feedback(response string) string
+}
+
 // Guardrail represents a guardrail that can be applied to requests and responses
 type Guardrail interface {
 	// Type returns the type of guardrail
@@ -109,8 +145,15 @@ func (p *Pipeline) ProcessRequest(ctx context.Context, request string) (string,
 	return processedRequest, nil
 }
 
-// ProcessResponse processes a response through the guardrails pipeline
+// ProcessResponse processes a response through the guardrails pipeline. It
+// returns a *RegenerateError if a guardrail asks for a new response.
 func (p *Pipeline) ProcessResponse(ctx context.Context, response string) (string, error) {
+	return p.processResponse(ctx, response, true)
+}
+
+// processResponse processes a response through the guardrails pipeline.
+// Without regenerate, RegenerateAction is applied like RedactAction.
+func (p *Pipeline) processResponse(ctx context.Context, response string, regenerate bool) (string, error) {
 	processedResponse := response
 
 	for _, guardrail := range p.guardrails {
@@ -132,6 +175,11 @@ func (p *Pipeline) ProcessResponse(ctx context.Context, response string) (string
 			switch guardrail.Action() {
 			case BlockAction:
 				return "", fmt.Errorf("response blocked by %s guardrail", guardrail.Type())
+			case RegenerateAction:
+				if regenerate {
+					return "", &RegenerateError{Guardrail: guardrail.Type(), Feedback: feedback(guardrail, processedResponse)}
+				}
+				processedResponse = modified
 			case RedactAction:
 				processedResponse = modified
 			case WarnAction:
@@ -148,6 +196,27 @@ func (p *Pipeline) ProcessResponse(ctx context.Context, response string) (string
 	return processedResponse, nil
 }
 
+// feedback explains why a guardrail rejected a response
+func feedback(guardrail Guardrail, response string) string {
+	if provider, ok := guardrail.(FeedbackProvider); ok {
+		return provider.Feedback(response)
+	}
+	return fmt.Sprintf("The previous response was rejected by the %s guardrail. Answer again.", guardrail.Type())
+}
+
+// ProcessInput processes user input through the guardrails pipeline, so that
+// a Pipeline can be used as the guardrails of an agent
+func (p *Pipeline) ProcessInput(ctx context.Context, input string) (string, error) {
+	return p.ProcessRequest(ctx, input)
+}
+
+// ProcessOutput processes an agent's output through the guardrails pipeline.
+// The agent can't regenerate its output, so RegenerateAction is applied like
+// RedactAction.
+func (p *Pipeline) ProcessOutput(ctx context.Context, output string) (string, error) {
+	return p.processResponse(ctx, output, false)
+}
+
 // AddGuardrail adds a guardrail to the pipeline
 func (p *Pipeline) AddGuardrail(guardrail Guardrail) {
 	p.guardrails = append(p.guardrails, guardrail)
@@ -0,0 +1,59 @@
+package guardrails
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/run-bigpig/llm-agent/pkg/language"
+)
+
+// LanguageCheck implements a guardrail that checks that responses are in an
+// expected language. It can't translate, so its modified response is the
+// response itself; use it with RegenerateAction to ask for a new answer, or
+// with BlockAction.
+type LanguageCheck struct {
+	code          string
+	minConfidence float64
+	action        Action
+}
+
+// NewLanguageCheck creates a guardrail that expects responses in the language
+// with the ISO 639-1 code
+func NewLanguageCheck(code string, action Action) *LanguageCheck {
+	return &LanguageCheck{
+		code:          code,
+		minConfidence: 0.5,
+		action:        action,
+	}
+}
+
+// Type returns the type of guardrail
+func (l *LanguageCheck) Type() GuardrailType {
+	return LanguageGuardrail
+}
+
+// CheckRequest checks if a request violates the guardrail. Requests may be in
+// any language.
+func (l *LanguageCheck) CheckRequest(ctx context.Context, request string) (bool, string, error) {
+	return false, request, nil
+}
+
+// CheckResponse checks if a response is detected to be in another language
+func (l *LanguageCheck) CheckResponse(ctx context.Context, response string) (bool, string, error) {
+	detection := language.Detect(response)
+	if detection.Confidence < l.minConfidence || strings.EqualFold(detection.Code, l.code) {
+		return false, response, nil
+	}
+	return true, response, nil
+}
+
+// Action returns the action to take when the guardrail is triggered
+func (l *LanguageCheck) Action() Action {
+	return l.action
+}
+
+// Feedback asks for the response in the expected language
+func (l *LanguageCheck) Feedback(response string) string {
+	return fmt.Sprintf("The previous response was not in %s. Answer again in %s.", language.Name(l.code), language.Name(l.code))
+}
@@ -0,0 +1,27 @@
+package guardrails_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/run-bigpig/llm-agent/pkg/guardrails"
+	"github.com/run-bigpig/llm-agent/pkg/logging"
+)
+
+func TestLanguageCheckRegenerates(t *testing.T) {
+	pipeline := guardrails.NewPipeline([]guardrails.Guardrail{
+		guardrails.NewLanguageCheck("es", guardrails.RegenerateAction),
+	}, logging.New())
+
+	llm := &scriptedLLM{responses: []string{
+		"Your invoice was paid on Monday and the receipt is in your inbox.",
+		"Su factura fue pagada el lunes y el recibo está en su correo.",
+	}}
+	response, err := guardrails.NewLLMMiddleware(llm, pipeline).Generate(context.Background(), "¿Cuándo se pagó mi factura?", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "Su factura fue pagada el lunes y el recibo está en su correo.", response)
+	assert.Contains(t, llm.prompts[1], "Answer again in Spanish")
+}
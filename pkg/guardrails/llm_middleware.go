@@ -2,22 +2,41 @@ package guardrails
 
 import (
 	"context"
+	"errors"
 
 	"github.com/run-bigpig/llm-agent/pkg/interfaces"
 )
 
 // LLMMiddleware implements middleware for LLM calls
 type LLMMiddleware struct {
-	llm      interfaces.LLM
-	pipeline *Pipeline
+	llm              interfaces.LLM
+	pipeline         *Pipeline
+	maxRegenerations int
+}
+
+// LLMMiddlewareOption configures an LLMMiddleware
+type LLMMiddlewareOption func(*LLMMiddleware)
+
+// WithMaxRegenerations sets how many times the LLM is asked for a new
+// response when a guardrail with RegenerateAction rejects one. After that the
+// guardrail's modified response is used. Defaults to 1.
+func WithMaxRegenerations(n int) LLMMiddlewareOption {
+	return func(m *LLMMiddleware) {
+		m.maxRegenerations = n
+	}
 }
 
 // NewLLMMiddleware creates a new LLM middleware
-func NewLLMMiddleware(llm interfaces.LLM, pipeline *Pipeline) *LLMMiddleware {
-	return &LLMMiddleware{
-		llm:      llm,
-		pipeline: pipeline,
+func NewLLMMiddleware(llm interfaces.LLM, pipeline *Pipeline, options ...LLMMiddlewareOption) *LLMMiddleware {
+	m := &LLMMiddleware{
+		llm:              llm,
+		pipeline:         pipeline,
+		maxRegenerations: 1,
+	}
+	for _, option := range options {
+		option(m)
 	}
+	return m
 }
 
 // Generate generates text from a prompt
@@ -28,18 +47,27 @@ func (m *LLMMiddleware) Generate(ctx context.Context, prompt string, options map
 		return "", err
 	}
 
-	// Call the underlying LLM
-	// Pass an empty slice of options instead of nil to avoid nil pointer dereference
-	response, err := m.llm.Generate(ctx, processedPrompt, []interfaces.GenerateOption{}...)
-	if err != nil {
-		return "", err
-	}
+	currentPrompt := processedPrompt
+	for attempt := 0; ; attempt++ {
+		// Call the underlying LLM
+		// Pass an empty slice of options instead of nil to avoid nil pointer dereference
+		response, err := m.llm.Generate(ctx, currentPrompt, []interfaces.GenerateOption{}...)
+		if err != nil {
+			return "", err
+		}
 
-	// Process response through guardrails
-	processedResponse, err := m.pipeline.ProcessResponse(ctx, response)
-	if err != nil {
-		return "", err
-	}
+		// Process response through guardrails, asking for a new response
+		// while regenerations are left
+		processedResponse, err := m.pipeline.processResponse(ctx, response, attempt < m.maxRegenerations)
+		var regenerateErr *RegenerateError
+		if errors.As(err, &regenerateErr) {
+			currentPrompt = processedPrompt + "\n\n" + regenerateErr.Feedback
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
 
-	return processedResponse, nil
+		return processedResponse, nil
+	}
 }
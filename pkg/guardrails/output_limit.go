@@ -0,0 +1,147 @@
+package guardrails
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// wordPattern matches the words of a response for repetition detection
+var wordPattern = regexp.MustCompile(`\S+`)
+
+// OutputLimit implements a guardrail that catches responses that are too
+// long or stuck in a repetition loop. Its modified response is cut off at the
+// limit, or after the first occurrence of the repeated text.
+type OutputLimit struct {
+	action      Action
+	maxLength   int
+	minRepeats  int
+	minWords    int
+	maxUnitSize int
+}
+
+// OutputLimitOption configures an OutputLimit
+type OutputLimitOption func(*OutputLimit)
+
+// WithMaxLength sets the maximum length of a response in characters. By
+// default the length is not limited.
+func WithMaxLength(characters int) OutputLimitOption {
+	return func(o *OutputLimit) {
+		o.maxLength = characters
+	}
+}
+
+// WithRepetitionThreshold sets when text repeating itself counts as a loop: a
+// run of words repeated at least repeats times in a row that spans at least
+// minWords words. Defaults to 3 repeats spanning 30 words; zero repeats turns
+// repetition detection off.
+func WithRepetitionThreshold(repeats, minWords int) OutputLimitOption {
+	return func(o *OutputLimit) {
+		o.minRepeats = repeats
+		o.minWords = minWords
+	}
+}
+
+// NewOutputLimit creates a new output limit guardrail. RedactAction returns
+// the truncated response and RegenerateAction asks the LLM for a new one.
+func NewOutputLimit(action Action, options ...OutputLimitOption) *OutputLimit {
+	o := &OutputLimit{
+		action:      action,
+		minRepeats:  3,
+		minWords:    30,
+		maxUnitSize: 50,
+	}
+	for _, option := range options {
+		option(o)
+	}
+	return o
+}
+
+// Type returns the type of guardrail
+func (o *OutputLimit) Type() GuardrailType {
+	return OutputLimitGuardrail
+}
+
+// CheckRequest checks if a request violates the guardrail. Requests are not
+// limited.
+func (o *OutputLimit) CheckRequest(ctx context.Context, request string) (bool, string, error) {
+	return false, request, nil
+}
+
+// CheckResponse checks if a response is too long or repeats itself
+func (o *OutputLimit) CheckResponse(ctx context.Context, response string) (bool, string, error) {
+	modified := response
+	if end, ok := o.findRepetition(response); ok {
+		modified = strings.TrimSpace(response[:end])
+	}
+	if o.maxLength > 0 && utf8.RuneCountInString(modified) > o.maxLength {
+		modified = truncateText(modified, o.maxLength)
+	}
+	return modified != response, modified, nil
+}
+
+// Action returns the action to take when the guardrail is triggered
+func (o *OutputLimit) Action() Action {
+	return o.action
+}
+
+// Feedback explains what was wrong with a response, for asking the LLM to
+// answer again
+func (o *OutputLimit) Feedback(response string) string {
+	if _, ok := o.findRepetition(response); ok {
+		return "The previous response got stuck repeating the same text. Answer again without repeating yourself."
+	}
+	return fmt.Sprintf("The previous response was too long. Answer again in at most %d characters.", o.maxLength)
+}
+
+// findRepetition looks for a run of words repeated at least minRepeats times
+// in a row, and returns the offset at which the first occurrence ends
+func (o *OutputLimit) findRepetition(text string) (int, bool) {
+	if o.minRepeats < 2 {
+		return 0, false
+	}
+	spans := wordPattern.FindAllStringIndex(text, -1)
+	words := make([]string, len(spans))
+	for i, span := range spans {
+		words[i] = text[span[0]:span[1]]
+	}
+
+	for size := 1; size <= o.maxUnitSize && size*o.minRepeats <= len(words); size++ {
+		// run counts the words equal to the word size positions earlier
+		run := 0
+		for i := size; i < len(words); i++ {
+			if words[i] != words[i-size] {
+				run = 0
+				continue
+			}
+			run++
+			if run >= size*(o.minRepeats-1) && run+size >= o.minWords {
+				start := i - run - size + 1
+				return spans[start+size-1][1], true
+			}
+		}
+	}
+	return 0, false
+}
+
+// truncateText cuts text to at most maxLength characters including an
+// ellipsis, at a word boundary if there is one
+func truncateText(text string, maxLength int) string {
+	const ellipsis = " ..."
+	runes := []rune(text)
+	if maxLength <= len(ellipsis) {
+		return string(runes[:maxLength])
+	}
+	limit := maxLength - len(ellipsis)
+	cut := string(runes[:limit])
+	if !unicode.IsSpace(runes[limit]) {
+		// Don't end on part of a word
+		if i := strings.LastIndexFunc(cut, unicode.IsSpace); i > 0 {
+			cut = cut[:i]
+		}
+	}
+	return strings.TrimSpace(cut) + ellipsis
+}
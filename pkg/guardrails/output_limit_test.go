@@ -0,0 +1,85 @@
+package guardrails_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/run-bigpig/llm-agent/pkg/guardrails"
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+	"github.com/run-bigpig/llm-agent/pkg/logging"
+)
+
+// scriptedLLM returns its responses in order and records the prompts
+type scriptedLLM struct {
+	interfaces.LLM
+	responses []string
+	prompts   []string
+}
+
+func (l *scriptedLLM) Generate(ctx context.Context, prompt string, options ...interfaces.GenerateOption) (string, error) {
+	l.prompts = append(l.prompts, prompt)
+	response := l.responses[0]
+	l.responses = l.responses[1:]
+	return response, nil
+}
+
+func TestOutputLimitTruncatesLongResponses(t *testing.T) {
+	limit := guardrails.NewOutputLimit(guardrails.RedactAction, guardrails.WithMaxLength(20))
+
+	triggered, modified, err := limit.CheckResponse(context.Background(), "The invoice was paid on the first of May.")
+	require.NoError(t, err)
+	assert.True(t, triggered)
+	assert.Equal(t, "The invoice was ...", modified)
+	assert.LessOrEqual(t, len(modified), 20)
+
+	triggered, _, err = limit.CheckResponse(context.Background(), "It was paid.")
+	require.NoError(t, err)
+	assert.False(t, triggered)
+}
+
+func TestOutputLimitDetectsRepetition(t *testing.T) {
+	limit := guardrails.NewOutputLimit(guardrails.RedactAction, guardrails.WithRepetitionThreshold(3, 12))
+	loop := "The answer is 42. " + strings.Repeat("Let me check that again. ", 10)
+
+	triggered, modified, err := limit.CheckResponse(context.Background(), loop)
+	require.NoError(t, err)
+	assert.True(t, triggered)
+	assert.Equal(t, "The answer is 42. Let me check that again.", modified)
+
+	// Natural text with a few repeated words is left alone
+	triggered, _, err = limit.CheckResponse(context.Background(), "Yes, yes, yes. I agree that the plan works and the plan is cheap.")
+	require.NoError(t, err)
+	assert.False(t, triggered)
+}
+
+func TestLLMMiddlewareRegeneratesRejectedResponses(t *testing.T) {
+	pipeline := guardrails.NewPipeline([]guardrails.Guardrail{
+		guardrails.NewOutputLimit(guardrails.RegenerateAction, guardrails.WithMaxLength(20)),
+	}, logging.New())
+
+	llm := &scriptedLLM{responses: []string{"This answer is far too long to be accepted.", "Short answer."}}
+	response, err := guardrails.NewLLMMiddleware(llm, pipeline).Generate(context.Background(), "Question?", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "Short answer.", response)
+	require.Len(t, llm.prompts, 2)
+	assert.Contains(t, llm.prompts[1], "at most 20 characters")
+
+	// Once the regenerations are used up, the truncated response is returned
+	llm = &scriptedLLM{responses: []string{"This answer is far too long.", "This one is also far too long."}}
+	response, err = guardrails.NewLLMMiddleware(llm, pipeline).Generate(context.Background(), "Question?", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "This one is also ...", response)
+
+	// ProcessResponse reports the rejection, ProcessOutput truncates
+	_, err = pipeline.ProcessResponse(context.Background(), "This answer is far too long.")
+	var regenerateErr *guardrails.RegenerateError
+	assert.True(t, errors.As(err, &regenerateErr))
+	output, err := pipeline.ProcessOutput(context.Background(), "This answer is far too long.")
+	require.NoError(t, err)
+	assert.Equal(t, "This answer is ...", output)
+}
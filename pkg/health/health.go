@@ -0,0 +1,249 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Pinger is implemented by components that can check connectivity to their
+// external dependency (LLM providers, vector stores, memory backends, MCP servers)
+type Pinger interface {
+	// Ping returns an error if the dependency is unreachable or unhealthy
+	Ping(ctx context.Context) error
+}
+
+// PingFunc adapts a function to the Pinger interface
+type PingFunc func(ctx context.Context) error
+
+// Ping calls f(ctx)
+func (f PingFunc) Ping(ctx context.Context) error {
+	return f(ctx)
+}
+
+// Status is the outcome of a health check
+type Status string
+
+const (
+	// StatusUp means the dependency is healthy
+	StatusUp Status = "up"
+
+	// StatusDown means the dependency is unhealthy
+	StatusDown Status = "down"
+)
+
+// Result is the result of checking a single dependency
+type Result struct {
+	Name     string        `json:"name"`
+	Status   Status        `json:"status"`
+	Critical bool          `json:"critical"`
+	Latency  time.Duration `json:"latency"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// Report is the aggregated result of checking every registered dependency
+type Report struct {
+	Status    Status    `json:"status"`
+	CheckedAt time.Time `json:"checked_at"`
+	Results   []Result  `json:"results"`
+}
+
+// Ready reports whether every critical dependency is up
+func (r Report) Ready() bool {
+	return r.Status == StatusUp
+}
+
+// check is a registered dependency
+type check struct {
+	name     string
+	pinger   Pinger
+	critical bool
+}
+
+// Checker aggregates health checks for external dependencies
+type Checker struct {
+	timeout time.Duration
+
+	mu     sync.RWMutex
+	checks []check
+}
+
+// Option represents an option for configuring the checker
+type Option func(*Checker)
+
+// WithTimeout sets the timeout applied to each individual check
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *Checker) {
+		c.timeout = timeout
+	}
+}
+
+// CheckOption represents an option for a registered check
+type CheckOption func(*check)
+
+// NonCritical marks a dependency whose failure does not make the service unready
+func NonCritical() CheckOption {
+	return func(c *check) {
+		c.critical = false
+	}
+}
+
+// NewChecker creates a new health checker
+func NewChecker(options ...Option) *Checker {
+	checker := &Checker{
+		timeout: 5 * time.Second,
+	}
+
+	for _, option := range options {
+		option(checker)
+	}
+
+	return checker
+}
+
+// Register adds a dependency to check. Dependencies are critical by default.
+func (c *Checker) Register(name string, pinger Pinger, options ...CheckOption) {
+	chk := check{
+		name:     name,
+		pinger:   pinger,
+		critical: true,
+	}
+	for _, option := range options {
+		option(&chk)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.checks = append(c.checks, chk)
+}
+
+// Check pings every registered dependency concurrently
+func (c *Checker) Check(ctx context.Context) Report {
+	c.mu.RLock()
+	checks := make([]check, len(c.checks))
+	copy(checks, c.checks)
+	c.mu.RUnlock()
+
+	results := make([]Result, len(checks))
+	var wg sync.WaitGroup
+	for i, chk := range checks {
+		wg.Add(1)
+		go func(i int, chk check) {
+			defer wg.Done()
+			results[i] = c.run(ctx, chk)
+		}(i, chk)
+	}
+	wg.Wait()
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Name < results[j].Name
+	})
+
+	report := Report{
+		Status:    StatusUp,
+		CheckedAt: time.Now(),
+		Results:   results,
+	}
+	for _, result := range results {
+		if result.Critical && result.Status == StatusDown {
+			report.Status = StatusDown
+			break
+		}
+	}
+
+	return report
+}
+
+// run executes a single check with the configured timeout
+func (c *Checker) run(ctx context.Context, chk check) (result Result) {
+	result = Result{
+		Name:     chk.name,
+		Status:   StatusUp,
+		Critical: chk.critical,
+	}
+
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	defer func() {
+		if r := recover(); r != nil {
+			result.Status = StatusDown
+			result.Error = fmt.Sprintf("panic: %v", r)
+		}
+		result.Latency = time.Since(start)
+	}()
+
+	if err := chk.pinger.Ping(ctx); err != nil {
+		result.Status = StatusDown
+		result.Error = err.Error()
+	}
+
+	return result
+}
+
+// LivenessHandler returns an http.Handler for /healthz. It reports the status
+// of every dependency but always responds 200 while the process is serving.
+func (c *Checker) LivenessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeReport(w, http.StatusOK, c.Check(r.Context()))
+	})
+}
+
+// ReadinessHandler returns an http.Handler for /readyz. It responds 503 when
+// any critical dependency is down.
+func (c *Checker) ReadinessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		report := c.Check(r.Context())
+		status := http.StatusOK
+		if !report.Ready() {
+			status = http.StatusServiceUnavailable
+		}
+		writeReport(w, status, report)
+	})
+}
+
+// writeReport writes the report as JSON
+func writeReport(w http.ResponseWriter, status int, report Report) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(report)
+}
+
+// Doctor checks every dependency and prints a human-readable summary to w.
+// It returns an error if any critical dependency is down, making it suitable
+// for a CLI "doctor" command.
+func (c *Checker) Doctor(ctx context.Context, w io.Writer) error {
+	report := c.Check(ctx)
+
+	for _, result := range report.Results {
+		mark := "ok"
+		if result.Status == StatusDown {
+			mark = "FAIL"
+			if !result.Critical {
+				mark = "warn"
+			}
+		}
+		fmt.Fprintf(w, "[%-4s] %-30s %8s", mark, result.Name, result.Latency.Round(time.Millisecond))
+		if result.Error != "" {
+			fmt.Fprintf(w, "  %s", result.Error)
+		}
+		fmt.Fprintln(w)
+	}
+
+	if !report.Ready() {
+		return fmt.Errorf("one or more critical dependencies are down")
+	}
+
+	fmt.Fprintf(w, "All %d checks passed\n", len(report.Results))
+	return nil
+}
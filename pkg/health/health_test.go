@@ -0,0 +1,77 @@
+package health_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/run-bigpig/llm-agent/pkg/health"
+)
+
+func TestReadinessHandler(t *testing.T) {
+	checker := health.NewChecker(health.WithTimeout(50 * time.Millisecond))
+	checker.Register("llm", health.PingFunc(func(ctx context.Context) error { return nil }))
+	checker.Register("cache", health.PingFunc(func(ctx context.Context) error {
+		return errors.New("connection refused")
+	}), health.NonCritical())
+
+	rec := httptest.NewRecorder()
+	checker.ReadinessHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/readyz", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200 when only non-critical checks fail, got %d", rec.Code)
+	}
+
+	var report health.Report
+	if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+		t.Fatalf("Failed to decode report: %v", err)
+	}
+	if len(report.Results) != 2 || report.Results[0].Name != "cache" || report.Results[0].Status != health.StatusDown {
+		t.Errorf("Unexpected results: %+v", report.Results)
+	}
+
+	// A critical check that exceeds the timeout makes the service unready
+	checker.Register("vectorstore", health.PingFunc(func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}))
+
+	rec = httptest.NewRecorder()
+	checker.ReadinessHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/readyz", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 when a critical check fails, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	checker.LivenessHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/healthz", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected liveness to respond 200, got %d", rec.Code)
+	}
+}
+
+func TestDoctor(t *testing.T) {
+	checker := health.NewChecker()
+	checker.Register("redis", health.PingFunc(func(ctx context.Context) error { return nil }))
+
+	var out bytes.Buffer
+	if err := checker.Doctor(context.Background(), &out); err != nil {
+		t.Fatalf("Expected doctor to pass, got %v", err)
+	}
+	if !strings.Contains(out.String(), "redis") || !strings.Contains(out.String(), "All 1 checks passed") {
+		t.Errorf("Unexpected doctor output: %s", out.String())
+	}
+
+	checker.Register("mcp", health.PingFunc(func(ctx context.Context) error { return errors.New("no route to host") }))
+	out.Reset()
+	if err := checker.Doctor(context.Background(), &out); err == nil {
+		t.Error("Expected doctor to fail when a critical dependency is down")
+	}
+	if !strings.Contains(out.String(), "no route to host") {
+		t.Errorf("Expected the error in doctor output, got %s", out.String())
+	}
+}
@@ -0,0 +1,109 @@
+package ingest
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Checkpoint records which source documents have been fully ingested, so
+// that an interrupted run can resume without re-embedding them
+type Checkpoint interface {
+	// IsDone reports whether the document was ingested by an earlier run
+	IsDone(ctx context.Context, docID string) (bool, error)
+
+	// MarkDone records that all chunks of the documents have been stored
+	MarkDone(ctx context.Context, docIDs ...string) error
+}
+
+// MemoryCheckpoint keeps the checkpoint in memory, e.g. to resume a failed
+// run within the same process
+type MemoryCheckpoint struct {
+	mu   sync.RWMutex
+	done map[string]bool
+}
+
+// NewMemoryCheckpoint creates an empty in-memory checkpoint
+func NewMemoryCheckpoint() *MemoryCheckpoint {
+	return &MemoryCheckpoint{done: make(map[string]bool)}
+}
+
+// IsDone reports whether the document was marked done
+func (c *MemoryCheckpoint) IsDone(ctx context.Context, docID string) (bool, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.done[docID], nil
+}
+
+// MarkDone marks the documents done
+func (c *MemoryCheckpoint) MarkDone(ctx context.Context, docIDs ...string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, id := range docIDs {
+		c.done[id] = true
+	}
+	return nil
+}
+
+// FileCheckpoint keeps the checkpoint in a file with one document ID per
+// line. IDs are appended as documents finish, so the file survives crashes.
+type FileCheckpoint struct {
+	MemoryCheckpoint
+	file *os.File
+}
+
+// OpenFileCheckpoint opens or creates a checkpoint file and loads the
+// documents it records
+func OpenFileCheckpoint(path string) (*FileCheckpoint, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open checkpoint: %w", err)
+	}
+
+	c := &FileCheckpoint{
+		MemoryCheckpoint: MemoryCheckpoint{done: make(map[string]bool)},
+		file:             file,
+	}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if id := strings.TrimSpace(scanner.Text()); id != "" {
+			c.done[id] = true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to read checkpoint: %w", err)
+	}
+	return c, nil
+}
+
+// MarkDone marks the documents done and appends them to the file
+func (c *FileCheckpoint) MarkDone(ctx context.Context, docIDs ...string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var lines strings.Builder
+	for _, id := range docIDs {
+		if strings.ContainsAny(id, "\r\n") {
+			return fmt.Errorf("document ID %q contains a line break", id)
+		}
+		lines.WriteString(id)
+		lines.WriteByte('\n')
+	}
+	if _, err := c.file.WriteString(lines.String()); err != nil {
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+	for _, id := range docIDs {
+		c.done[id] = true
+	}
+	return nil
+}
+
+// Close closes the checkpoint file
+func (c *FileCheckpoint) Close() error {
+	return c.file.Close()
+}
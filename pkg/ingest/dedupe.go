@@ -0,0 +1,207 @@
+package ingest
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+)
+
+// Duplicate describes a chunk that was skipped as a duplicate
+type Duplicate struct {
+	// ID is the ID of the skipped chunk
+	ID string
+
+	// SourceID is the ID of the document the chunk was split from
+	SourceID string
+
+	// DuplicateOf is the ID of the chunk it duplicates
+	DuplicateOf string
+
+	// Similarity is the similarity to that chunk; 1 for identical content
+	Similarity float64
+
+	// Exact reports whether the content is identical after normalizing case
+	// and whitespace
+	Exact bool
+}
+
+// Deduper skips chunks whose content was already ingested, so that re-crawled
+// or copy-pasted documents don't fill the store with copies. Chunks with
+// identical content are detected by hash; chunks with vectors are also
+// compared with the vectors of earlier chunks by cosine similarity. A Deduper
+// can be shared across runs to skip duplicates of earlier runs.
+type Deduper struct {
+	threshold   float64
+	maxVectors  int
+	searchStore bool
+
+	mu         sync.Mutex
+	hashes     map[[sha256.Size]byte]string
+	vectors    []seenVector
+	next       int
+	duplicates []Duplicate
+}
+
+// seenVector is the vector of an accepted chunk
+type seenVector struct {
+	id     string
+	vector []float32
+}
+
+// DedupeOption configures a Deduper
+type DedupeOption func(*Deduper)
+
+// WithSimilarityThreshold sets the cosine similarity at or above which a chunk
+// is a near-duplicate. Defaults to 0.95; 0 disables similarity checks.
+func WithSimilarityThreshold(threshold float64) DedupeOption {
+	return func(d *Deduper) {
+		d.threshold = threshold
+	}
+}
+
+// WithMaxVectors sets how many vectors of accepted chunks are kept for
+// similarity checks. The oldest are dropped first. Defaults to 10000.
+func WithMaxVectors(n int) DedupeOption {
+	return func(d *Deduper) {
+		if n > 0 {
+			d.maxVectors = n
+		}
+	}
+}
+
+// WithStoreSearch also searches the vector store for each chunk's nearest
+// neighbor, which catches duplicates stored by other processes or before the
+// Deduper was created. The store's score is compared with the similarity
+// threshold, so it must be on the same 0-1 scale.
+func WithStoreSearch() DedupeOption {
+	return func(d *Deduper) {
+		d.searchStore = true
+	}
+}
+
+// NewDeduper creates a Deduper
+func NewDeduper(options ...DedupeOption) *Deduper {
+	d := &Deduper{
+		threshold:  0.95,
+		maxVectors: 10000,
+		hashes:     make(map[[sha256.Size]byte]string),
+	}
+	for _, option := range options {
+		option(d)
+	}
+	return d
+}
+
+// Duplicates returns the chunks skipped so far
+func (d *Deduper) Duplicates() []Duplicate {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]Duplicate(nil), d.duplicates...)
+}
+
+// filter returns the chunks of a batch that aren't duplicates. A match with
+// the chunk's own ID isn't a duplicate: storing it again overwrites it.
+func (d *Deduper) filter(ctx context.Context, store interfaces.VectorStore, batch []chunk, options []interfaces.SearchOption) ([]chunk, error) {
+	// Search the store first, without holding the lock
+	storeMatches := make([]*Duplicate, len(batch))
+	if d.searchStore && d.threshold > 0 {
+		for i, c := range batch {
+			if len(c.doc.Vector) == 0 {
+				continue
+			}
+			results, err := store.SearchByVector(ctx, c.doc.Vector, 1, options...)
+			if err != nil {
+				return nil, fmt.Errorf("failed to search for duplicates: %w", err)
+			}
+			if len(results) > 0 && results[0].Document.ID != c.doc.ID && float64(results[0].Score) >= d.threshold {
+				storeMatches[i] = &Duplicate{DuplicateOf: results[0].Document.ID, Similarity: float64(results[0].Score)}
+			}
+		}
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	kept := make([]chunk, 0, len(batch))
+	for i, c := range batch {
+		duplicate := d.match(c.doc)
+		if duplicate == nil {
+			duplicate = storeMatches[i]
+		}
+		if duplicate != nil {
+			duplicate.ID = c.doc.ID
+			duplicate.SourceID = c.source
+			d.duplicates = append(d.duplicates, *duplicate)
+			continue
+		}
+
+		d.hashes[contentHash(c.doc.Content)] = c.doc.ID
+		if len(c.doc.Vector) > 0 && d.threshold > 0 {
+			d.remember(c.doc.ID, c.doc.Vector)
+		}
+		kept = append(kept, c)
+	}
+	return kept, nil
+}
+
+// match returns the duplicate a chunk matches among the accepted chunks, or
+// nil. The caller holds the lock.
+func (d *Deduper) match(doc interfaces.Document) *Duplicate {
+	if id, ok := d.hashes[contentHash(doc.Content)]; ok && id != doc.ID {
+		return &Duplicate{DuplicateOf: id, Similarity: 1, Exact: true}
+	}
+	if len(doc.Vector) == 0 || d.threshold <= 0 {
+		return nil
+	}
+
+	var best *Duplicate
+	for _, seen := range d.vectors {
+		if seen.id == doc.ID {
+			continue
+		}
+		similarity := cosineSimilarity(doc.Vector, seen.vector)
+		if similarity >= d.threshold && (best == nil || similarity > best.Similarity) {
+			best = &Duplicate{DuplicateOf: seen.id, Similarity: similarity}
+		}
+	}
+	return best
+}
+
+// remember keeps the vector of an accepted chunk, replacing the oldest once
+// maxVectors are kept. The caller holds the lock.
+func (d *Deduper) remember(id string, vector []float32) {
+	if len(d.vectors) < d.maxVectors {
+		d.vectors = append(d.vectors, seenVector{id: id, vector: vector})
+		return
+	}
+	d.vectors[d.next] = seenVector{id: id, vector: vector}
+	d.next = (d.next + 1) % d.maxVectors
+}
+
+// contentHash hashes text with case and whitespace normalized
+func contentHash(text string) [sha256.Size]byte {
+	return sha256.Sum256([]byte(strings.ToLower(strings.Join(strings.Fields(text), " "))))
+}
+
+// cosineSimilarity returns the cosine similarity of two vectors, or 0 if their
+// lengths differ or either is zero
+func cosineSimilarity(a, b []float32) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
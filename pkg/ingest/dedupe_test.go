@@ -0,0 +1,130 @@
+package ingest_test
+
+import (
+	"context"
+	"hash/fnv"
+	"math"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/run-bigpig/llm-agent/pkg/embedding"
+	"github.com/run-bigpig/llm-agent/pkg/ingest"
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+)
+
+// wordEmbedder embeds texts as bags of hashed words
+type wordEmbedder struct {
+	embedding.Client
+}
+
+func (wordEmbedder) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vectors[i] = make([]float32, 64)
+		for _, word := range strings.Fields(strings.ToLower(text)) {
+			h := fnv.New32a()
+			h.Write([]byte(word))
+			vectors[i][h.Sum32()%64]++
+		}
+	}
+	return vectors, nil
+}
+
+// SearchByVector returns the stored documents most similar to the vector
+func (s *memoryStore) SearchByVector(ctx context.Context, vector []float32, limit int, options ...interfaces.SearchOption) ([]interfaces.SearchResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var results []interfaces.SearchResult
+	for _, doc := range s.docs {
+		var dot, a, b float64
+		for i := range vector {
+			dot += float64(vector[i]) * float64(doc.Vector[i])
+			a += float64(vector[i]) * float64(vector[i])
+			b += float64(doc.Vector[i]) * float64(doc.Vector[i])
+		}
+		results = append(results, interfaces.SearchResult{Document: doc, Score: float32(dot / math.Sqrt(a*b))})
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].Score > results[j].Score })
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
+
+const article = "The quarterly report shows revenue growth across all regions with strong demand for cloud services and a steady increase in subscription renewals from enterprise customers in Europe and Asia"
+
+func dedupeDocuments() []interfaces.Document {
+	return []interfaces.Document{
+		{ID: "original", Content: article},
+		{ID: "copy", Content: "  " + strings.ToUpper(article) + "\n"},
+		{ID: "near", Content: strings.Replace(article, "steady", "modest", 1)},
+		{ID: "other", Content: "Installation requires Go 1.21 or later and a running Weaviate instance for the examples"},
+	}
+}
+
+func TestPipelineDeduper(t *testing.T) {
+	store := newMemoryStore()
+	deduper := ingest.NewDeduper(ingest.WithSimilarityThreshold(0.9))
+	pipeline := ingest.NewPipeline(store,
+		ingest.WithEmbedder(wordEmbedder{}),
+		ingest.WithDeduper(deduper),
+		ingest.WithBatchSize(3),
+		ingest.WithWorkers(1),
+	)
+
+	progress, err := pipeline.Run(context.Background(), ingest.SliceLoader(dedupeDocuments()))
+	if err != nil {
+		t.Fatalf("failed to run pipeline: %v", err)
+	}
+	if progress.ChunksStored != 2 || progress.ChunksSkipped != 2 || progress.DocumentsDone != 4 {
+		t.Errorf("expected 2 chunks stored, 2 skipped and 4 documents done, got %+v", progress)
+	}
+
+	duplicates := deduper.Duplicates()
+	if len(duplicates) != 2 {
+		t.Fatalf("expected 2 duplicates, got %+v", duplicates)
+	}
+	original := ingest.ChunkID("original", 0)
+	if d := duplicates[0]; d.SourceID != "copy" || d.DuplicateOf != original || !d.Exact || d.Similarity != 1 {
+		t.Errorf("expected an exact duplicate of the original, got %+v", d)
+	}
+	if d := duplicates[1]; d.SourceID != "near" || d.DuplicateOf != original || d.Exact || d.Similarity < 0.9 || d.Similarity >= 1 {
+		t.Errorf("expected a near-duplicate of the original, got %+v", d)
+	}
+
+	// A second run with the same Deduper skips everything except re-stored chunks
+	progress, err = pipeline.Run(context.Background(), ingest.SliceLoader([]interfaces.Document{{ID: "again", Content: article}}))
+	if err != nil {
+		t.Fatalf("failed to run pipeline: %v", err)
+	}
+	if progress.ChunksStored != 0 || progress.ChunksSkipped != 1 {
+		t.Errorf("expected the copy to be skipped, got %+v", progress)
+	}
+}
+
+func TestDeduperStoreSearch(t *testing.T) {
+	store := newMemoryStore()
+	first := ingest.NewPipeline(store, ingest.WithEmbedder(wordEmbedder{}))
+	if _, err := first.Run(context.Background(), ingest.SliceLoader(dedupeDocuments()[:1])); err != nil {
+		t.Fatalf("failed to run pipeline: %v", err)
+	}
+
+	// A new Deduper only finds the earlier copy in the store
+	deduper := ingest.NewDeduper(ingest.WithSimilarityThreshold(0.9), ingest.WithStoreSearch())
+	second := ingest.NewPipeline(store, ingest.WithEmbedder(wordEmbedder{}), ingest.WithDeduper(deduper))
+	progress, err := second.Run(context.Background(), ingest.SliceLoader(dedupeDocuments()))
+	if err != nil {
+		t.Fatalf("failed to run pipeline: %v", err)
+	}
+
+	// The original is stored again under its own ID, the copies are skipped
+	if progress.ChunksStored != 2 || progress.ChunksSkipped != 2 {
+		t.Errorf("expected 2 chunks stored and 2 skipped, got %+v", progress)
+	}
+	for _, d := range deduper.Duplicates() {
+		if d.DuplicateOf != ingest.ChunkID("original", 0) {
+			t.Errorf("expected a duplicate of the original, got %+v", d)
+		}
+	}
+}
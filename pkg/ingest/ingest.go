@@ -0,0 +1,377 @@
+// Package ingest loads, splits, embeds and stores large document collections
+// with bounded memory.
+package ingest
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/run-bigpig/llm-agent/pkg/embedding"
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+)
+
+// Progress reports how far a run has got
+type Progress struct {
+	// DocumentsLoaded is the number of source documents read from the loader
+	DocumentsLoaded int
+
+	// DocumentsSkipped is the number of documents skipped because the
+	// checkpoint records them as done
+	DocumentsSkipped int
+
+	// DocumentsDone is the number of documents whose chunks are all stored
+	DocumentsDone int
+
+	// ChunksStored is the number of chunks stored
+	ChunksStored int
+
+	// ChunksSkipped is the number of chunks skipped as duplicates
+	ChunksSkipped int
+
+	// Batches is the number of batches stored
+	Batches int
+
+	// Elapsed is the time since the run started
+	Elapsed time.Duration
+}
+
+// Pipeline streams documents from a loader through a splitter and an
+// embedder into a vector store. Each stage is connected by a bounded channel,
+// so a slow store slows down loading instead of filling memory.
+type Pipeline struct {
+	store        interfaces.VectorStore
+	splitter     Splitter
+	embedder     embedding.Client
+	batchSize    int
+	workers      int
+	bufferSize   int
+	checkpoint   Checkpoint
+	deduper      *Deduper
+	onProgress   func(Progress)
+	storeOptions []interfaces.StoreOption
+}
+
+// Option configures a Pipeline
+type Option func(*Pipeline)
+
+// WithSplitter sets how documents are split into chunks. Defaults to a
+// TextSplitter with 1000-character chunks and 100 characters of overlap.
+func WithSplitter(splitter Splitter) Option {
+	return func(p *Pipeline) {
+		p.splitter = splitter
+	}
+}
+
+// WithEmbedder embeds each batch with one EmbedBatch call before storing it.
+// Without an embedder, the vector store embeds the chunks itself.
+func WithEmbedder(embedder embedding.Client) Option {
+	return func(p *Pipeline) {
+		p.embedder = embedder
+	}
+}
+
+// WithBatchSize sets the number of chunks embedded and stored together
+func WithBatchSize(size int) Option {
+	return func(p *Pipeline) {
+		if size > 0 {
+			p.batchSize = size
+		}
+	}
+}
+
+// WithWorkers sets the number of batches embedded and stored at the same time
+func WithWorkers(n int) Option {
+	return func(p *Pipeline) {
+		if n > 0 {
+			p.workers = n
+		}
+	}
+}
+
+// WithBufferSize sets the capacity of the channels between stages. Together
+// with the batch size and workers it bounds the number of documents in memory.
+func WithBufferSize(n int) Option {
+	return func(p *Pipeline) {
+		if n > 0 {
+			p.bufferSize = n
+		}
+	}
+}
+
+// WithCheckpoint records finished documents and skips documents an earlier
+// run has finished
+func WithCheckpoint(checkpoint Checkpoint) Option {
+	return func(p *Pipeline) {
+		p.checkpoint = checkpoint
+	}
+}
+
+// WithDeduper skips chunks the Deduper detects as duplicates. Similarity
+// checks need vectors, so they only apply with WithEmbedder or when the loader
+// sets them; otherwise only identical content is skipped.
+func WithDeduper(deduper *Deduper) Option {
+	return func(p *Pipeline) {
+		p.deduper = deduper
+	}
+}
+
+// WithProgress sets a callback that is called whenever a document is loaded
+// or a batch is stored. Calls are serialized; keep the callback fast.
+func WithProgress(onProgress func(Progress)) Option {
+	return func(p *Pipeline) {
+		p.onProgress = onProgress
+	}
+}
+
+// WithStoreOptions sets the options passed to the vector store, e.g. the class
+func WithStoreOptions(options ...interfaces.StoreOption) Option {
+	return func(p *Pipeline) {
+		p.storeOptions = options
+	}
+}
+
+// NewPipeline creates a pipeline that stores into the vector store
+func NewPipeline(store interfaces.VectorStore, options ...Option) *Pipeline {
+	p := &Pipeline{
+		store:     store,
+		splitter:  NewTextSplitter(1000, 100),
+		batchSize: 100,
+		workers:   4,
+	}
+	for _, option := range options {
+		option(p)
+	}
+	if p.bufferSize == 0 {
+		p.bufferSize = p.batchSize
+	}
+	return p
+}
+
+// chunk is a chunk on its way through the pipeline
+type chunk struct {
+	doc    interfaces.Document
+	source string
+}
+
+// run is the state of one Run call
+type run struct {
+	*Pipeline
+	started time.Time
+
+	mu       sync.Mutex
+	progress Progress
+	pending  map[string]int // remaining chunks per source document
+}
+
+// Run ingests all documents of the loader and returns the final progress. It
+// stops at the first error; documents finished before that are recorded in
+// the checkpoint, so running again with the same checkpoint resumes.
+func (p *Pipeline) Run(ctx context.Context, loader Loader) (Progress, error) {
+	r := &run{
+		Pipeline: p,
+		started:  time.Now(),
+		pending:  make(map[string]int),
+	}
+
+	group, ctx := errgroup.WithContext(ctx)
+	docs := make(chan interfaces.Document, p.bufferSize)
+	chunks := make(chan chunk, p.bufferSize)
+	batches := make(chan []chunk, p.workers)
+
+	group.Go(func() error {
+		defer close(docs)
+		return loader.Load(ctx, docs)
+	})
+	group.Go(func() error {
+		defer close(chunks)
+		return r.split(ctx, docs, chunks)
+	})
+	group.Go(func() error {
+		defer close(batches)
+		return r.batch(ctx, chunks, batches)
+	})
+	for i := 0; i < p.workers; i++ {
+		group.Go(func() error {
+			for batch := range batches {
+				if err := r.store(ctx, batch); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+
+	err := group.Wait()
+	return r.snapshot(), err
+}
+
+// split skips finished documents and splits the others into chunks
+func (r *run) split(ctx context.Context, docs <-chan interfaces.Document, out chan<- chunk) error {
+	for doc := range docs {
+		if r.checkpoint != nil {
+			done, err := r.checkpoint.IsDone(ctx, doc.ID)
+			if err != nil {
+				return fmt.Errorf("failed to read checkpoint: %w", err)
+			}
+			if done {
+				r.update(func(p *Progress) {
+					p.DocumentsLoaded++
+					p.DocumentsSkipped++
+				})
+				continue
+			}
+		}
+
+		parts := r.splitter.Split(doc)
+		r.update(func(p *Progress) { p.DocumentsLoaded++ })
+		if len(parts) == 0 {
+			// Nothing to store, e.g. an empty document
+			if err := r.finish(ctx, []string{doc.ID}); err != nil {
+				return err
+			}
+			continue
+		}
+
+		r.mu.Lock()
+		r.pending[doc.ID] += len(parts)
+		r.mu.Unlock()
+		for _, part := range parts {
+			if err := send(ctx, out, chunk{doc: part, source: doc.ID}); err != nil {
+				return err
+			}
+		}
+	}
+	return ctx.Err()
+}
+
+// batch groups chunks into batches
+func (r *run) batch(ctx context.Context, chunks <-chan chunk, out chan<- []chunk) error {
+	batch := make([]chunk, 0, r.batchSize)
+	for c := range chunks {
+		batch = append(batch, c)
+		if len(batch) == r.batchSize {
+			if err := send(ctx, out, batch); err != nil {
+				return err
+			}
+			batch = make([]chunk, 0, r.batchSize)
+		}
+	}
+	if len(batch) > 0 {
+		return send(ctx, out, batch)
+	}
+	return nil
+}
+
+// store embeds and stores a batch, then records the documents it completed
+func (r *run) store(ctx context.Context, batch []chunk) error {
+	docs := make([]interfaces.Document, len(batch))
+	for i, c := range batch {
+		docs[i] = c.doc
+	}
+
+	if r.embedder != nil {
+		texts := make([]string, len(docs))
+		for i, doc := range docs {
+			texts[i] = doc.Content
+		}
+		vectors, err := r.embedder.EmbedBatch(embedding.WithInputType(ctx, embedding.InputTypeDocument), texts)
+		if err != nil {
+			return fmt.Errorf("failed to embed batch: %w", err)
+		}
+		if len(vectors) != len(docs) {
+			return fmt.Errorf("embedder returned %d vectors for %d chunks", len(vectors), len(docs))
+		}
+		for i := range docs {
+			docs[i].Vector = vectors[i]
+		}
+	}
+
+	stored := batch
+	if r.deduper != nil {
+		for i := range batch {
+			batch[i].doc = docs[i]
+		}
+		var err error
+		if stored, err = r.deduper.filter(ctx, r.Pipeline.store, batch, r.searchOptions()); err != nil {
+			return err
+		}
+		docs = docs[:0]
+		for _, c := range stored {
+			docs = append(docs, c.doc)
+		}
+	}
+
+	if len(docs) > 0 {
+		if err := r.Pipeline.store.Store(ctx, docs, r.storeOptions...); err != nil {
+			return fmt.Errorf("failed to store batch: %w", err)
+		}
+	}
+
+	// Documents whose last chunk was in this batch are done
+	var finished []string
+	r.mu.Lock()
+	for _, c := range batch {
+		r.pending[c.source]--
+		if r.pending[c.source] == 0 {
+			delete(r.pending, c.source)
+			finished = append(finished, c.source)
+		}
+	}
+	r.progress.ChunksStored += len(stored)
+	r.progress.ChunksSkipped += len(batch) - len(stored)
+	r.progress.Batches++
+	r.mu.Unlock()
+
+	return r.finish(ctx, finished)
+}
+
+// searchOptions returns the options for searching the class the pipeline
+// stores into
+func (p *Pipeline) searchOptions() []interfaces.SearchOption {
+	var storeOptions interfaces.StoreOptions
+	for _, option := range p.storeOptions {
+		option(&storeOptions)
+	}
+	if storeOptions.Class == "" {
+		return nil
+	}
+	return []interfaces.SearchOption{func(o *interfaces.SearchOptions) {
+		o.Class = storeOptions.Class
+	}}
+}
+
+// finish records finished documents in the checkpoint and reports progress
+func (r *run) finish(ctx context.Context, docIDs []string) error {
+	if len(docIDs) > 0 && r.checkpoint != nil {
+		if err := r.checkpoint.MarkDone(ctx, docIDs...); err != nil {
+			return fmt.Errorf("failed to write checkpoint: %w", err)
+		}
+	}
+	r.update(func(p *Progress) { p.DocumentsDone += len(docIDs) })
+	return nil
+}
+
+// update changes the progress and reports it
+func (r *run) update(change func(*Progress)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	change(&r.progress)
+	if r.onProgress != nil {
+		progress := r.progress
+		progress.Elapsed = time.Since(r.started)
+		r.onProgress(progress)
+	}
+}
+
+// snapshot returns the current progress
+func (r *run) snapshot() Progress {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	progress := r.progress
+	progress.Elapsed = time.Since(r.started)
+	return progress
+}
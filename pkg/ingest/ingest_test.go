@@ -0,0 +1,153 @@
+package ingest_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/run-bigpig/llm-agent/pkg/ingest"
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+)
+
+// memoryStore is a vector store that records stored documents and can fail
+// after a number of batches
+type memoryStore struct {
+	interfaces.VectorStore
+
+	mu        sync.Mutex
+	docs      map[string]interfaces.Document
+	batches   int
+	failAfter int
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{docs: make(map[string]interfaces.Document), failAfter: -1}
+}
+
+func (s *memoryStore) Store(ctx context.Context, docs []interfaces.Document, options ...interfaces.StoreOption) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.failAfter >= 0 && s.batches >= s.failAfter {
+		return errors.New("store unavailable")
+	}
+	s.batches++
+	for _, doc := range docs {
+		s.docs[doc.ID] = doc
+	}
+	return nil
+}
+
+func testDocuments(n int) []interfaces.Document {
+	docs := make([]interfaces.Document, n)
+	for i := range docs {
+		docs[i] = interfaces.Document{
+			ID:      fmt.Sprintf("doc-%d", i),
+			Content: strings.Repeat(fmt.Sprintf("Sentence %d of a document. ", i), 10),
+		}
+	}
+	return docs
+}
+
+func TestPipelineRun(t *testing.T) {
+	store := newMemoryStore()
+	var calls int
+	pipeline := ingest.NewPipeline(store,
+		ingest.WithSplitter(ingest.NewTextSplitter(100, 20)),
+		ingest.WithBatchSize(7),
+		ingest.WithWorkers(3),
+		ingest.WithBufferSize(2),
+		ingest.WithProgress(func(ingest.Progress) { calls++ }),
+	)
+
+	progress, err := pipeline.Run(context.Background(), ingest.SliceLoader(testDocuments(50)))
+	if err != nil {
+		t.Fatalf("failed to run pipeline: %v", err)
+	}
+	if progress.DocumentsLoaded != 50 || progress.DocumentsDone != 50 {
+		t.Errorf("expected 50 documents loaded and done, got %+v", progress)
+	}
+	if progress.ChunksStored != len(store.docs) || len(store.docs) <= 50 {
+		t.Errorf("expected every document to be split into several chunks, got %d chunks stored and %d in the store", progress.ChunksStored, len(store.docs))
+	}
+	if calls == 0 {
+		t.Error("expected progress callbacks")
+	}
+
+	for _, doc := range store.docs {
+		if len([]rune(doc.Content)) > 100 {
+			t.Errorf("chunk longer than 100 characters: %q", doc.Content)
+		}
+		if doc.Metadata[ingest.MetadataSourceID] == nil {
+			t.Errorf("chunk without source ID: %+v", doc)
+		}
+	}
+}
+
+func TestPipelineResume(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "checkpoint")
+	checkpoint, err := ingest.OpenFileCheckpoint(path)
+	if err != nil {
+		t.Fatalf("failed to open checkpoint: %v", err)
+	}
+	defer checkpoint.Close()
+
+	// The first run fails after a few batches
+	store := newMemoryStore()
+	store.failAfter = 3
+	pipeline := ingest.NewPipeline(store,
+		ingest.WithSplitter(ingest.NewTextSplitter(100, 0)),
+		ingest.WithBatchSize(5),
+		ingest.WithWorkers(1),
+		ingest.WithCheckpoint(checkpoint),
+	)
+	first, err := pipeline.Run(context.Background(), ingest.SliceLoader(testDocuments(20)))
+	if err == nil {
+		t.Fatal("expected the first run to fail")
+	}
+	if first.DocumentsDone == 0 || first.DocumentsDone == 20 {
+		t.Fatalf("expected some documents to be done, got %+v", first)
+	}
+
+	// Only finished documents are skipped when resuming
+	store.failAfter = -1
+	second, err := pipeline.Run(context.Background(), ingest.SliceLoader(testDocuments(20)))
+	if err != nil {
+		t.Fatalf("failed to resume: %v", err)
+	}
+	if second.DocumentsSkipped != first.DocumentsDone || second.DocumentsDone != 20-first.DocumentsDone {
+		t.Errorf("expected %d documents skipped and %d done, got %+v", first.DocumentsDone, 20-first.DocumentsDone, second)
+	}
+
+	// The checkpoint survives reopening
+	reopened, err := ingest.OpenFileCheckpoint(path)
+	if err != nil {
+		t.Fatalf("failed to reopen checkpoint: %v", err)
+	}
+	defer reopened.Close()
+	for _, doc := range testDocuments(20) {
+		if done, _ := reopened.IsDone(context.Background(), doc.ID); !done {
+			t.Errorf("expected %s to be recorded as done", doc.ID)
+		}
+	}
+}
+
+func TestTextSplitter(t *testing.T) {
+	text := "First paragraph is here.\n\nSecond paragraph has two sentences. This is the second one.\n\n" + strings.Repeat("x", 250)
+	chunks := ingest.NewTextSplitter(60, 0).Split(interfaces.Document{ID: "doc", Content: text})
+
+	if chunks[0].Content != "First paragraph is here." {
+		t.Errorf("expected the first paragraph as the first chunk, got %q", chunks[0].Content)
+	}
+	for _, chunk := range chunks {
+		if len(chunk.Content) > 60 {
+			t.Errorf("chunk longer than 60 characters: %q", chunk.Content)
+		}
+	}
+	if chunks[1].ID != ingest.ChunkID("doc", 1) || chunks[1].Metadata[ingest.MetadataChunkIndex] != 1 {
+		t.Errorf("unexpected chunk ID or index: %+v", chunks[1])
+	}
+}
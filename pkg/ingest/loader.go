@@ -0,0 +1,93 @@
+package ingest
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+)
+
+// Loader streams source documents into out. It must stop when the context is
+// cancelled; sends block while the pipeline is busy, which bounds memory.
+type Loader interface {
+	Load(ctx context.Context, out chan<- interfaces.Document) error
+}
+
+// LoaderFunc adapts a function to a Loader
+type LoaderFunc func(ctx context.Context, out chan<- interfaces.Document) error
+
+// Load calls f(ctx, out)
+func (f LoaderFunc) Load(ctx context.Context, out chan<- interfaces.Document) error {
+	return f(ctx, out)
+}
+
+// SliceLoader loads documents from a slice
+func SliceLoader(docs []interfaces.Document) Loader {
+	return LoaderFunc(func(ctx context.Context, out chan<- interfaces.Document) error {
+		for _, doc := range docs {
+			if err := send(ctx, out, doc); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// DirectoryLoader loads the files under root with one of the given extensions,
+// e.g. ".md", or all files if none are given. A document's ID is its path
+// relative to root, and its metadata has the "path".
+func DirectoryLoader(root string, extensions ...string) Loader {
+	return LoaderFunc(func(ctx context.Context, out chan<- interfaces.Document) error {
+		return filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if entry.IsDir() || !hasExtension(path, extensions) {
+				return nil
+			}
+
+			content, err := os.ReadFile(path)
+			if err != nil {
+				return fmt.Errorf("failed to read %s: %w", path, err)
+			}
+			rel, err := filepath.Rel(root, path)
+			if err != nil {
+				return err
+			}
+			return send(ctx, out, interfaces.Document{
+				ID:       filepath.ToSlash(rel),
+				Content:  string(content),
+				Metadata: map[string]interface{}{"path": filepath.ToSlash(rel)},
+			})
+		})
+	})
+}
+
+// hasExtension reports whether the path has one of the extensions. Any path
+// matches an empty list.
+func hasExtension(path string, extensions []string) bool {
+	if len(extensions) == 0 {
+		return true
+	}
+	ext := filepath.Ext(path)
+	for _, e := range extensions {
+		if strings.EqualFold(ext, e) {
+			return true
+		}
+	}
+	return false
+}
+
+// send sends v on out unless the context is cancelled first
+func send[T any](ctx context.Context, out chan<- T, v T) error {
+	select {
+	case out <- v:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,161 @@
+package ingest
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+)
+
+// Metadata keys set on every chunk
+const (
+	// MetadataSourceID is the ID of the document a chunk was split from
+	MetadataSourceID = "source_id"
+
+	// MetadataChunkIndex is the position of a chunk in its document
+	MetadataChunkIndex = "chunk_index"
+)
+
+// Splitter splits a document into chunks
+type Splitter interface {
+	Split(doc interfaces.Document) []interfaces.Document
+}
+
+// SplitterFunc adapts a function to a Splitter
+type SplitterFunc func(doc interfaces.Document) []interfaces.Document
+
+// Split calls f(doc)
+func (f SplitterFunc) Split(doc interfaces.Document) []interfaces.Document {
+	return f(doc)
+}
+
+// TextSplitter splits text into chunks of at most a number of characters,
+// preferring paragraph, line, sentence and word boundaries, in that order
+type TextSplitter struct {
+	chunkSize  int
+	overlap    int
+	separators []string
+}
+
+// NewTextSplitter creates a splitter for chunks of at most chunkSize
+// characters. Each chunk repeats up to overlap characters from the end of the
+// previous one, so that context isn't lost at the cut.
+func NewTextSplitter(chunkSize, overlap int) *TextSplitter {
+	if chunkSize <= 0 {
+		chunkSize = 1000
+	}
+	if overlap < 0 || overlap >= chunkSize {
+		overlap = 0
+	}
+	return &TextSplitter{
+		chunkSize:  chunkSize,
+		overlap:    overlap,
+		separators: []string{"\n\n", "\n", ". ", " "},
+	}
+}
+
+// Split splits the document. Chunks get IDs derived from the document ID and
+// their position, so re-ingesting a document overwrites its chunks, and carry
+// the document's metadata plus MetadataSourceID and MetadataChunkIndex.
+func (s *TextSplitter) Split(doc interfaces.Document) []interfaces.Document {
+	texts := s.splitText(doc.Content)
+	chunks := make([]interfaces.Document, len(texts))
+	for i, text := range texts {
+		metadata := make(map[string]interface{}, len(doc.Metadata)+2)
+		for k, v := range doc.Metadata {
+			metadata[k] = v
+		}
+		metadata[MetadataSourceID] = doc.ID
+		metadata[MetadataChunkIndex] = i
+
+		chunks[i] = interfaces.Document{
+			ID:       ChunkID(doc.ID, i),
+			Content:  text,
+			Metadata: metadata,
+		}
+	}
+	return chunks
+}
+
+// ChunkID returns the ID of a document's chunk: a UUID derived from the
+// document ID and the chunk index
+func ChunkID(docID string, index int) string {
+	return uuid.NewSHA1(uuid.NameSpaceURL, []byte(fmt.Sprintf("%s#%d", docID, index))).String()
+}
+
+// splitText splits text into chunks with overlap
+func (s *TextSplitter) splitText(text string) []string {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil
+	}
+
+	var chunks []string
+	var current strings.Builder
+	for _, piece := range s.pieces(text, 0) {
+		if current.Len() > 0 && len([]rune(current.String()))+len([]rune(piece)) > s.chunkSize {
+			chunk := strings.TrimSpace(current.String())
+			chunks = append(chunks, chunk)
+			current.Reset()
+			if tail := s.tail(chunk); len([]rune(tail))+len([]rune(piece)) <= s.chunkSize {
+				current.WriteString(tail)
+			}
+		}
+		current.WriteString(piece)
+	}
+	if chunk := strings.TrimSpace(current.String()); chunk != "" {
+		chunks = append(chunks, chunk)
+	}
+	return chunks
+}
+
+// pieces splits text into pieces no longer than the chunk size, keeping the
+// separators so that joining the pieces restores the text
+func (s *TextSplitter) pieces(text string, level int) []string {
+	if len([]rune(text)) <= s.chunkSize {
+		return []string{text}
+	}
+	if level >= len(s.separators) {
+		// No boundary left, cut by characters
+		runes := []rune(text)
+		var pieces []string
+		for len(runes) > s.chunkSize {
+			pieces = append(pieces, string(runes[:s.chunkSize]))
+			runes = runes[s.chunkSize:]
+		}
+		return append(pieces, string(runes))
+	}
+
+	var pieces []string
+	sep := s.separators[level]
+	parts := strings.SplitAfter(text, sep)
+	for _, part := range parts {
+		if part == "" {
+			continue
+		}
+		pieces = append(pieces, s.pieces(part, level+1)...)
+	}
+	return pieces
+}
+
+// tail returns the overlap from the end of a chunk, starting at a word
+func (s *TextSplitter) tail(chunk string) string {
+	if s.overlap == 0 {
+		return ""
+	}
+	runes := []rune(chunk)
+	if len(runes) <= s.overlap {
+		// Don't repeat a whole chunk
+		return ""
+	}
+	tail := string(runes[len(runes)-s.overlap:])
+	if i := strings.IndexAny(tail, " \n"); i >= 0 {
+		tail = tail[i+1:]
+	}
+	if tail == "" {
+		return ""
+	}
+	return tail + " "
+}
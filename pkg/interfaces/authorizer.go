@@ -0,0 +1,30 @@
+package interfaces
+
+import "context"
+
+// Authorizer decides whether the identity in the context may run agents and
+// use tools
+type Authorizer interface {
+	// CanRunAgent reports whether the caller may run the named agent
+	CanRunAgent(ctx context.Context, agentName string) (bool, error)
+
+	// CanUseTool reports whether the caller may use the named tool
+	CanUseTool(ctx context.Context, toolName string) (bool, error)
+}
+
+// ToolCallAuthorizer is implemented by authorizers that also decide on each
+// tool call given its arguments, e.g. to allow a SQL tool only on some
+// tables. When an authorizer implements it, CanCallTool replaces CanUseTool
+// for the calls themselves.
+type ToolCallAuthorizer interface {
+	// CanCallTool reports whether the caller may call the named tool with
+	// the arguments
+	CanCallTool(ctx context.Context, toolName, args string) (bool, error)
+}
+
+// DocumentAuthorizer is implemented by authorizers that decide which
+// documents the caller may read
+type DocumentAuthorizer interface {
+	// CanReadDocument reports whether the caller may read the document
+	CanReadDocument(ctx context.Context, document Document) (bool, error)
+}
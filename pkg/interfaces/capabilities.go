@@ -0,0 +1,73 @@
+package interfaces
+
+// Capabilities describes the features an LLM provider supports
+type Capabilities struct {
+	// Tools is true if the provider supports tool (function) calling
+	Tools bool
+
+	// Vision is true if the model accepts images
+	Vision bool
+
+	// JSONMode is true if the provider can enforce a response format natively
+	JSONMode bool
+
+	// Streaming is true if responses can be streamed
+	Streaming bool
+
+	// ParallelTools is true if the model can request several tool calls at once
+	ParallelTools bool
+
+	// AssistantPrefill is true if the model can continue a response from a
+	// prefix, see WithAssistantPrefix
+	AssistantPrefill bool
+}
+
+// Missing returns the names of the required capabilities that are not supported
+func (c Capabilities) Missing(required Capabilities) []string {
+	var missing []string
+	if required.Tools && !c.Tools {
+		missing = append(missing, "tools")
+	}
+	if required.Vision && !c.Vision {
+		missing = append(missing, "vision")
+	}
+	if required.JSONMode && !c.JSONMode {
+		missing = append(missing, "json_mode")
+	}
+	if required.Streaming && !c.Streaming {
+		missing = append(missing, "streaming")
+	}
+	if required.ParallelTools && !c.ParallelTools {
+		missing = append(missing, "parallel_tools")
+	}
+	if required.AssistantPrefill && !c.AssistantPrefill {
+		missing = append(missing, "assistant_prefill")
+	}
+	return missing
+}
+
+// CapabilityProvider is implemented by LLMs that report their capabilities
+type CapabilityProvider interface {
+	Capabilities() Capabilities
+}
+
+// LLMWrapper is implemented by LLM middleware that wraps another LLM
+type LLMWrapper interface {
+	Unwrap() LLM
+}
+
+// GetCapabilities returns the capabilities of the LLM, looking through
+// wrapping middleware. It returns false if the LLM does not report them.
+func GetCapabilities(llm LLM) (Capabilities, bool) {
+	for llm != nil {
+		if provider, ok := llm.(CapabilityProvider); ok {
+			return provider.Capabilities(), true
+		}
+		wrapper, ok := llm.(LLMWrapper)
+		if !ok {
+			break
+		}
+		llm = wrapper.Unwrap()
+	}
+	return Capabilities{}, false
+}
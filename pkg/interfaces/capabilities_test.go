@@ -0,0 +1,75 @@
+package interfaces_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+)
+
+// capableLLM reports fixed capabilities
+type capableLLM struct {
+	interfaces.LLM
+	caps interfaces.Capabilities
+}
+
+func (l capableLLM) Capabilities() interfaces.Capabilities { return l.caps }
+
+// wrappingLLM is middleware around another LLM
+type wrappingLLM struct {
+	interfaces.LLM
+	inner interfaces.LLM
+}
+
+func (l wrappingLLM) Unwrap() interfaces.LLM { return l.inner }
+
+// plainLLM neither reports capabilities nor wraps another LLM
+type plainLLM struct {
+	interfaces.LLM
+}
+
+func TestCapabilitiesMissing(t *testing.T) {
+	caps := interfaces.Capabilities{Tools: true, Streaming: true}
+
+	missing := caps.Missing(interfaces.Capabilities{
+		Tools:            true,
+		Vision:           true,
+		JSONMode:         true,
+		Streaming:        true,
+		ParallelTools:    true,
+		AssistantPrefill: true,
+	})
+	expected := []string{"vision", "json_mode", "parallel_tools", "assistant_prefill"}
+	if !reflect.DeepEqual(missing, expected) {
+		t.Errorf("Expected %v, got %v", expected, missing)
+	}
+
+	if missing := caps.Missing(interfaces.Capabilities{Tools: true}); missing != nil {
+		t.Errorf("Expected nothing missing, got %v", missing)
+	}
+}
+
+func TestGetCapabilities(t *testing.T) {
+	caps := interfaces.Capabilities{Tools: true, Vision: true}
+
+	if got, ok := interfaces.GetCapabilities(capableLLM{caps: caps}); !ok || got != caps {
+		t.Errorf("Expected the reported capabilities, got %+v, %v", got, ok)
+	}
+
+	// Middleware is looked through until an LLM reports its capabilities
+	wrapped := wrappingLLM{inner: wrappingLLM{inner: capableLLM{caps: caps}}}
+	if got, ok := interfaces.GetCapabilities(wrapped); !ok || got != caps {
+		t.Errorf("Expected the capabilities of the wrapped LLM, got %+v, %v", got, ok)
+	}
+
+	for name, llm := range map[string]interfaces.LLM{
+		"plain":         plainLLM{},
+		"wrapped plain": wrappingLLM{inner: plainLLM{}},
+		"wrapped nil":   wrappingLLM{},
+		"nil":           nil,
+	} {
+		if got, ok := interfaces.GetCapabilities(llm); ok || got != (interfaces.Capabilities{}) {
+			t.Errorf("%s: expected no capabilities, got %+v, %v", name, got, ok)
+		}
+	}
+}
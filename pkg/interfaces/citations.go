@@ -0,0 +1,39 @@
+package interfaces
+
+import "context"
+
+// Citation is a source a response is based on, such as a web page found by
+// a model with search grounding
+type Citation struct {
+	// URL is the address of the source
+	URL string `json:"url"`
+
+	// Title is the title of the source, if known
+	Title string `json:"title,omitempty"`
+
+	// Text is the part of the response the source supports. It's empty if
+	// the source was used for the response as a whole.
+	Text string `json:"text,omitempty"`
+
+	// StartIndex and EndIndex are the byte offsets of Text in the response
+	StartIndex int `json:"start_index,omitempty"`
+	EndIndex   int `json:"end_index,omitempty"`
+}
+
+// citationsKey is the context key for the citations callback
+type citationsKey struct{}
+
+// WithCitationsCallback returns a context in which LLM clients call fn with
+// the sources of responses that cite them, e.g. Gemini with Google Search
+// grounding
+func WithCitationsCallback(ctx context.Context, fn func(citations []Citation)) context.Context {
+	return context.WithValue(ctx, citationsKey{}, fn)
+}
+
+// ReportCitations passes the sources of a response to the callback in the
+// context, if there is one
+func ReportCitations(ctx context.Context, citations []Citation) {
+	if fn, ok := ctx.Value(citationsKey{}).(func([]Citation)); ok && fn != nil && len(citations) > 0 {
+		fn(citations)
+	}
+}
@@ -0,0 +1,41 @@
+package interfaces
+
+// Constraint restricts decoding so that the response always matches a
+// grammar, a regular expression or a JSON schema. Unlike a ResponseFormat,
+// which asks for a format, a constraint is enforced token by token by
+// servers that support constrained decoding. Only one field is set.
+type Constraint struct {
+	// Grammar is a grammar in the notation of the server, e.g. GBNF for
+	// llama.cpp or EBNF for vLLM
+	Grammar string
+
+	// Regex is a regular expression the whole response matches
+	Regex string
+
+	// Schema is a JSON schema the response conforms to
+	Schema JSONSchema
+}
+
+// WithGrammar constrains the response to the grammar. Providers that can't
+// enforce a grammar fail the call.
+func WithGrammar(grammar string) GenerateOption {
+	return func(options *GenerateOptions) {
+		options.Constraint = &Constraint{Grammar: grammar}
+	}
+}
+
+// WithRegex constrains the response to the regular expression. Providers
+// that can't enforce a regular expression fail the call.
+func WithRegex(pattern string) GenerateOption {
+	return func(options *GenerateOptions) {
+		options.Constraint = &Constraint{Regex: pattern}
+	}
+}
+
+// WithSchemaConstraint constrains the response to JSON that conforms to the
+// schema. Providers that can't enforce a schema fail the call.
+func WithSchemaConstraint(schema JSONSchema) GenerateOption {
+	return func(options *GenerateOptions) {
+		options.Constraint = &Constraint{Schema: schema}
+	}
+}
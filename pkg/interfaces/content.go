@@ -0,0 +1,122 @@
+package interfaces
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// ContentType is the kind of a part of a multimodal prompt
+type ContentType string
+
+const (
+	// ContentTypeText is a text part
+	ContentTypeText ContentType = "text"
+
+	// ContentTypeImageURL is an image the provider fetches from a URL
+	ContentTypeImageURL ContentType = "image_url"
+
+	// ContentTypeImage is an image sent with the prompt
+	ContentTypeImage ContentType = "image"
+
+	// ContentTypeAudio is a recording sent with the prompt, or speech
+	// synthesized from a response
+	ContentTypeAudio ContentType = "audio"
+)
+
+// Content is a part of a multimodal prompt, such as a question about a
+// screenshot followed by the screenshot
+type Content struct {
+	Type ContentType `json:"type"`
+
+	// Text is the text of text parts
+	Text string `json:"text,omitempty"`
+
+	// URL is the address of image URL parts
+	URL string `json:"url,omitempty"`
+
+	// Data and MediaType are the bytes and media type, e.g. "image/png" or
+	// "audio/wav", of image and audio parts
+	Data      []byte `json:"data,omitempty"`
+	MediaType string `json:"media_type,omitempty"`
+}
+
+// TextContent returns a text part
+func TextContent(text string) Content {
+	return Content{Type: ContentTypeText, Text: text}
+}
+
+// ImageURLContent returns an image part the provider fetches from the URL
+func ImageURLContent(url string) Content {
+	return Content{Type: ContentTypeImageURL, URL: url}
+}
+
+// ImageContent returns an image part with the image's bytes. The media type
+// is detected from the bytes if it's empty.
+func ImageContent(data []byte, mediaType string) Content {
+	if mediaType == "" {
+		mediaType = http.DetectContentType(data)
+	}
+	return Content{Type: ContentTypeImage, Data: data, MediaType: mediaType}
+}
+
+// AudioContent returns an audio part with the recording's bytes. The media
+// type is detected from the bytes if it's empty.
+func AudioContent(data []byte, mediaType string) Content {
+	if mediaType == "" {
+		mediaType = http.DetectContentType(data)
+	}
+	return Content{Type: ContentTypeAudio, Data: data, MediaType: mediaType}
+}
+
+// Validate reports whether the part has the fields its type needs
+func (c Content) Validate() error {
+	switch c.Type {
+	case ContentTypeText:
+		return nil
+	case ContentTypeImageURL:
+		if c.URL == "" {
+			return fmt.Errorf("image URL content has no URL")
+		}
+	case ContentTypeImage:
+		if len(c.Data) == 0 {
+			return fmt.Errorf("image content has no data")
+		}
+		if !strings.HasPrefix(c.MediaType, "image/") {
+			return fmt.Errorf("image content has media type %q", c.MediaType)
+		}
+	case ContentTypeAudio:
+		if len(c.Data) == 0 {
+			return fmt.Errorf("audio content has no data")
+		}
+		if !strings.HasPrefix(c.MediaType, "audio/") {
+			return fmt.Errorf("audio content has media type %q", c.MediaType)
+		}
+	default:
+		return fmt.Errorf("unknown content type %q", c.Type)
+	}
+	return nil
+}
+
+// ContentText returns the text parts of the contents, e.g. to log a
+// multimodal prompt or to send it to a model without vision
+func ContentText(contents []Content) string {
+	var texts []string
+	for _, content := range contents {
+		if content.Type == ContentTypeText && content.Text != "" {
+			texts = append(texts, content.Text)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
+
+// MultimodalLLM is implemented by LLMs that accept prompts with images, see
+// Capabilities.Vision, and possibly audio
+type MultimodalLLM interface {
+	LLM
+
+	// GenerateMultimodal generates text like Generate for a prompt made of
+	// the contents, in order
+	GenerateMultimodal(ctx context.Context, contents []Content, options ...GenerateOption) (string, error)
+}
@@ -0,0 +1,33 @@
+package interfaces_test
+
+import (
+	"testing"
+
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+)
+
+func TestImageContentDetectsMediaType(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	content := interfaces.ImageContent(png, "")
+	if content.MediaType != "image/png" {
+		t.Errorf("Expected image/png, got %s", content.MediaType)
+	}
+	if err := content.Validate(); err != nil {
+		t.Errorf("Expected a valid image, got %v", err)
+	}
+
+	if err := interfaces.ImageContent([]byte("plain text"), "").Validate(); err == nil {
+		t.Error("Expected an error for data that isn't an image")
+	}
+}
+
+func TestContentText(t *testing.T) {
+	contents := []interfaces.Content{
+		interfaces.TextContent("What is this?"),
+		interfaces.ImageURLContent("https://example.com/a.png"),
+		interfaces.TextContent("Answer briefly."),
+	}
+	if text := interfaces.ContentText(contents); text != "What is this?\nAnswer briefly." {
+		t.Errorf("Unexpected text %q", text)
+	}
+}
@@ -0,0 +1,301 @@
+package interfaces
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// FilterOperator is the operator of a metadata filter
+type FilterOperator string
+
+const (
+	// FilterEq matches documents whose field equals the value
+	FilterEq FilterOperator = "eq"
+	// FilterNeq matches documents whose field doesn't equal the value
+	FilterNeq FilterOperator = "neq"
+	// FilterGt matches documents whose field is greater than the value
+	FilterGt FilterOperator = "gt"
+	// FilterGte matches documents whose field is greater than or equal to the value
+	FilterGte FilterOperator = "gte"
+	// FilterLt matches documents whose field is less than the value
+	FilterLt FilterOperator = "lt"
+	// FilterLte matches documents whose field is less than or equal to the value
+	FilterLte FilterOperator = "lte"
+	// FilterIn matches documents whose field equals one of the values
+	FilterIn FilterOperator = "in"
+	// FilterAnd matches documents that match all operands
+	FilterAnd FilterOperator = "and"
+	// FilterOr matches documents that match any operand
+	FilterOr FilterOperator = "or"
+)
+
+// Filter is a metadata filter that works the same on every vector store. Build
+// it with Eq, Neq, Gt, Gte, Lt, Lte, In, And and Or, and pass it to a search
+// with WithFilter; each store translates it into its own query language.
+//
+// Values are strings, bools, numbers or time.Time. Numbers are compared by
+// value, so Eq("pages", 3) matches a document stored with 3.0.
+type Filter struct {
+	// Operator is the comparison or logical operator
+	Operator FilterOperator
+
+	// Field is the metadata field of a comparison
+	Field string
+
+	// Value is the value of a comparison. For FilterIn it is a []interface{}.
+	Value interface{}
+
+	// Operands are the filters combined by FilterAnd and FilterOr
+	Operands []Filter
+}
+
+// Eq matches documents whose field equals value
+func Eq(field string, value interface{}) Filter {
+	return Filter{Operator: FilterEq, Field: field, Value: value}
+}
+
+// Neq matches documents whose field doesn't equal value
+func Neq(field string, value interface{}) Filter {
+	return Filter{Operator: FilterNeq, Field: field, Value: value}
+}
+
+// Gt matches documents whose field is greater than value
+func Gt(field string, value interface{}) Filter {
+	return Filter{Operator: FilterGt, Field: field, Value: value}
+}
+
+// Gte matches documents whose field is greater than or equal to value
+func Gte(field string, value interface{}) Filter {
+	return Filter{Operator: FilterGte, Field: field, Value: value}
+}
+
+// Lt matches documents whose field is less than value
+func Lt(field string, value interface{}) Filter {
+	return Filter{Operator: FilterLt, Field: field, Value: value}
+}
+
+// Lte matches documents whose field is less than or equal to value
+func Lte(field string, value interface{}) Filter {
+	return Filter{Operator: FilterLte, Field: field, Value: value}
+}
+
+// In matches documents whose field equals one of values
+func In(field string, values ...interface{}) Filter {
+	return Filter{Operator: FilterIn, Field: field, Value: values}
+}
+
+// And matches documents that match all filters. An empty And matches every
+// document.
+func And(filters ...Filter) Filter {
+	return Filter{Operator: FilterAnd, Operands: filters}
+}
+
+// Or matches documents that match any of the filters
+func Or(filters ...Filter) Filter {
+	return Filter{Operator: FilterOr, Operands: filters}
+}
+
+// Validate checks that the filter is well formed, so that stores can reject
+// a bad filter instead of silently ignoring it
+func (f Filter) Validate() error {
+	switch f.Operator {
+	case FilterAnd, FilterOr:
+		if f.Operator == FilterOr && len(f.Operands) == 0 {
+			return fmt.Errorf("or filter needs at least one operand")
+		}
+		for _, operand := range f.Operands {
+			if err := operand.Validate(); err != nil {
+				return err
+			}
+		}
+		return nil
+	case FilterEq, FilterNeq, FilterGt, FilterGte, FilterLt, FilterLte, FilterIn:
+	default:
+		return fmt.Errorf("unsupported filter operator %q", f.Operator)
+	}
+
+	if f.Field == "" {
+		return fmt.Errorf("%s filter without a field", f.Operator)
+	}
+
+	values := []interface{}{f.Value}
+	if f.Operator == FilterIn {
+		var ok bool
+		if values, ok = f.Value.([]interface{}); !ok || len(values) == 0 {
+			return fmt.Errorf("in filter on %s needs at least one value", f.Field)
+		}
+	}
+	for _, value := range values {
+		kind := filterValueKind(value)
+		if kind == "" {
+			return fmt.Errorf("unsupported value %v (%T) in filter on %s", value, value, f.Field)
+		}
+		if kind == "bool" && f.Operator != FilterEq && f.Operator != FilterNeq && f.Operator != FilterIn {
+			return fmt.Errorf("%s filter on %s cannot compare booleans", f.Operator, f.Field)
+		}
+	}
+	return nil
+}
+
+// Matches reports whether document metadata matches the filter. It defines the
+// semantics every store's translation must follow, and serves stores that
+// filter in memory. A comparison on a missing field never matches.
+func (f Filter) Matches(metadata map[string]interface{}) bool {
+	switch f.Operator {
+	case FilterAnd:
+		for _, operand := range f.Operands {
+			if !operand.Matches(metadata) {
+				return false
+			}
+		}
+		return true
+	case FilterOr:
+		for _, operand := range f.Operands {
+			if operand.Matches(metadata) {
+				return true
+			}
+		}
+		return false
+	}
+
+	value, ok := metadata[f.Field]
+	if !ok || value == nil {
+		return false
+	}
+
+	switch f.Operator {
+	case FilterIn:
+		values, _ := f.Value.([]interface{})
+		for _, v := range values {
+			if cmp, ok := compareFilterValues(value, v); ok && cmp == 0 {
+				return true
+			}
+		}
+		return false
+	case FilterNeq:
+		cmp, ok := compareFilterValues(value, f.Value)
+		return !ok || cmp != 0
+	}
+
+	cmp, ok := compareFilterValues(value, f.Value)
+	if !ok {
+		return false
+	}
+	switch f.Operator {
+	case FilterEq:
+		return cmp == 0
+	case FilterGt:
+		return cmp > 0
+	case FilterGte:
+		return cmp >= 0
+	case FilterLt:
+		return cmp < 0
+	case FilterLte:
+		return cmp <= 0
+	}
+	return false
+}
+
+// String returns a readable form of the filter, e.g. for logs
+func (f Filter) String() string {
+	switch f.Operator {
+	case FilterAnd, FilterOr:
+		operands := make([]string, len(f.Operands))
+		for i, operand := range f.Operands {
+			operands[i] = operand.String()
+		}
+		return fmt.Sprintf("%s(%s)", f.Operator, strings.Join(operands, ", "))
+	}
+	return fmt.Sprintf("%s %s %v", f.Field, f.Operator, f.Value)
+}
+
+// filterValueKind returns the kind of a filter value: "string", "bool",
+// "number" or "time", or "" if the value isn't supported
+func filterValueKind(value interface{}) string {
+	switch value.(type) {
+	case string:
+		return "string"
+	case bool:
+		return "bool"
+	case time.Time:
+		return "time"
+	}
+	switch reflect.ValueOf(value).Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	}
+	return ""
+}
+
+// FilterNumber converts a numeric filter value to float64
+func FilterNumber(value interface{}) (float64, bool) {
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	}
+	return 0, false
+}
+
+// compareFilterValues compares a stored value with a filter value. It returns
+// false if the values can't be compared. Times compare with RFC 3339 strings,
+// which is how they come back from stores that keep metadata as JSON.
+func compareFilterValues(stored, value interface{}) (int, bool) {
+	switch v := value.(type) {
+	case string:
+		s, ok := stored.(string)
+		if !ok {
+			return 0, false
+		}
+		return strings.Compare(s, v), true
+	case bool:
+		b, ok := stored.(bool)
+		if !ok {
+			return 0, false
+		}
+		if b == v {
+			return 0, true
+		}
+		if !b {
+			return -1, true
+		}
+		return 1, true
+	case time.Time:
+		t, ok := stored.(time.Time)
+		if !ok {
+			s, isString := stored.(string)
+			if !isString {
+				return 0, false
+			}
+			var err error
+			if t, err = time.Parse(time.RFC3339Nano, s); err != nil {
+				return 0, false
+			}
+		}
+		return t.Compare(v), true
+	}
+
+	n, ok := FilterNumber(value)
+	if !ok {
+		return 0, false
+	}
+	s, ok := FilterNumber(stored)
+	if !ok {
+		return 0, false
+	}
+	switch {
+	case s < n:
+		return -1, true
+	case s > n:
+		return 1, true
+	}
+	return 0, true
+}
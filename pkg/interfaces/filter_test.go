@@ -0,0 +1,66 @@
+package interfaces_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+)
+
+func TestFilterMatches(t *testing.T) {
+	published := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	metadata := map[string]interface{}{
+		"type":      "article",
+		"pages":     12.0, // numbers decoded from JSON
+		"draft":     false,
+		"published": published.Format(time.RFC3339),
+	}
+
+	tests := []struct {
+		name   string
+		filter interfaces.Filter
+		want   bool
+	}{
+		{"eq string", interfaces.Eq("type", "article"), true},
+		{"eq int matches float", interfaces.Eq("pages", 12), true},
+		{"neq", interfaces.Neq("type", "news"), true},
+		{"gt", interfaces.Gt("pages", 10), true},
+		{"lte", interfaces.Lte("pages", 11), false},
+		{"in", interfaces.In("type", "news", "article"), true},
+		{"not in", interfaces.In("type", "news", "blog"), false},
+		{"bool", interfaces.Eq("draft", false), true},
+		{"time", interfaces.Gte("published", published), true},
+		{"missing field", interfaces.Neq("author", "bob"), false},
+		{"type mismatch", interfaces.Eq("pages", "12"), false},
+		{"and", interfaces.And(interfaces.Eq("type", "article"), interfaces.Lt("pages", 5)), false},
+		{"or", interfaces.Or(interfaces.Eq("type", "news"), interfaces.Gt("pages", 5)), true},
+		{"empty and", interfaces.And(), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.filter.Validate(); err != nil {
+				t.Fatalf("unexpected validation error: %v", err)
+			}
+			if got := tt.filter.Matches(metadata); got != tt.want {
+				t.Errorf("%s: expected %v, got %v", tt.filter, tt.want, got)
+			}
+		})
+	}
+}
+
+func TestFilterValidate(t *testing.T) {
+	invalid := []interfaces.Filter{
+		{Operator: "like", Field: "type", Value: "a"},
+		interfaces.Eq("", "article"),
+		interfaces.Eq("tags", []string{"a"}),
+		interfaces.Gt("draft", true),
+		interfaces.In("type"),
+		interfaces.Or(),
+		interfaces.And(interfaces.Eq("type", "article"), interfaces.Eq("", 1)),
+	}
+	for _, filter := range invalid {
+		if err := filter.Validate(); err == nil {
+			t.Errorf("expected %s to be invalid", filter)
+		}
+	}
+}
@@ -10,3 +10,10 @@ type Guardrails interface {
 	// ProcessOutput processes LLM output before returning to the user
 	ProcessOutput(ctx context.Context, output string) (string, error)
 }
+
+// ContentSanitizer neutralizes instructions hidden in untrusted content, such
+// as tool results and retrieved documents, before it is put in a prompt
+type ContentSanitizer interface {
+	// Sanitize returns the content with instruction-like text neutralized
+	Sanitize(ctx context.Context, content string) (string, error)
+}
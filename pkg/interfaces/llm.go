@@ -1,6 +1,9 @@
 package interfaces
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
 // LLM represents a large language model provider
 type LLM interface {
@@ -19,10 +22,48 @@ type GenerateOption func(options *GenerateOptions)
 
 // GenerateOptions contains configuration for text generation
 type GenerateOptions struct {
-	LLMConfig      *LLMConfig      // LLM config for the generation
-	OrgID          string          // For multi-tenancy
-	SystemMessage  string          // System message for chat models
-	ResponseFormat *ResponseFormat // Optional expected response format
+	LLMConfig       *LLMConfig      // LLM config for the generation
+	OrgID           string          // For multi-tenancy
+	SystemMessage   string          // System message for chat models
+	ResponseFormat  *ResponseFormat // Optional expected response format
+	Timeout         time.Duration   // Timeout of the call, see WithRequestTimeout
+	AssistantPrefix string          // Start of the response to continue, see WithAssistantPrefix
+	Constraint      *Constraint     // Constrained decoding, see WithGrammar
+	CachedContent   string          // Name of content cached by the provider, e.g. with vertex.WithCachedContent
+}
+
+// WithAssistantPrefix has the model continue a response that starts with
+// prefix: it generates only the text that follows. Only providers whose
+// Capabilities report AssistantPrefill support it; others ignore it.
+func WithAssistantPrefix(prefix string) GenerateOption {
+	return func(options *GenerateOptions) {
+		options.AssistantPrefix = prefix
+	}
+}
+
+// WithRequestTimeout limits how long a call may take, overriding the default
+// request timeout of the provider. The call's context is cancelled when the
+// timeout expires; an earlier deadline of the caller's context still applies.
+// For GenerateWithTools the timeout covers the whole call, including the tool
+// calls made in between.
+func WithRequestTimeout(timeout time.Duration) GenerateOption {
+	return func(options *GenerateOptions) {
+		options.Timeout = timeout
+	}
+}
+
+// RequestContext derives the context of a provider call from the caller's
+// context: the call's timeout applies if set and the provider's default
+// timeout otherwise. With neither, the context is only cancelled with its
+// parent.
+func RequestContext(ctx context.Context, timeout, defaultTimeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
 }
 
 type LLMConfig struct {
@@ -32,4 +73,16 @@ type LLMConfig struct {
 	PresencePenalty  float64  // Presence penalty for the generation
 	StopSequences    []string // Stop sequences for the generation
 	Reasoning        string   // Reasoning mode (none, minimal, comprehensive) to control explanation detail
+	MaxTokens        int      // Maximum number of tokens to generate; zero uses the provider's default
+}
+
+// WithMaxTokens limits the number of tokens the model generates. Zero uses
+// the provider's default.
+func WithMaxTokens(maxTokens int) GenerateOption {
+	return func(options *GenerateOptions) {
+		if options.LLMConfig == nil {
+			options.LLMConfig = &LLMConfig{}
+		}
+		options.LLMConfig.MaxTokens = maxTokens
+	}
 }
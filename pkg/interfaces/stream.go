@@ -0,0 +1,79 @@
+package interfaces
+
+import (
+	"context"
+	"strings"
+)
+
+// StreamEventType is the type of a stream event
+type StreamEventType string
+
+const (
+	// StreamEventText carries the next piece of the response in Content
+	StreamEventText StreamEventType = "text"
+
+	// StreamEventDone is the last event of a complete response
+	StreamEventDone StreamEventType = "done"
+
+	// StreamEventError is the last event of a failed response, with the
+	// error in Err
+	StreamEventError StreamEventType = "error"
+
+	// StreamEventThinking carries the next piece of the model's reasoning in
+	// Content, for models with extended thinking. It is not part of the
+	// response.
+	StreamEventThinking StreamEventType = "thinking"
+)
+
+// StreamEvent is an event of a streamed response
+type StreamEvent struct {
+	Type    StreamEventType
+	Content string
+	Err     error
+}
+
+// StreamingLLM is implemented by LLMs that can stream their responses
+type StreamingLLM interface {
+	LLM
+
+	// GenerateStream generates text like Generate, but returns the response
+	// as it is generated. The channel ends with a done or an error event and
+	// is then closed. Cancel the context to stop the generation early.
+	GenerateStream(ctx context.Context, prompt string, options ...GenerateOption) (<-chan StreamEvent, error)
+}
+
+// GenerateStream streams the response of the LLM if it implements
+// StreamingLLM. Otherwise the response is generated in full and sent as a
+// single text event.
+func GenerateStream(ctx context.Context, llm LLM, prompt string, options ...GenerateOption) (<-chan StreamEvent, error) {
+	if streaming, ok := llm.(StreamingLLM); ok {
+		return streaming.GenerateStream(ctx, prompt, options...)
+	}
+
+	events := make(chan StreamEvent, 2)
+	go func() {
+		defer close(events)
+		response, err := llm.Generate(ctx, prompt, options...)
+		if err != nil {
+			events <- StreamEvent{Type: StreamEventError, Err: err}
+			return
+		}
+		events <- StreamEvent{Type: StreamEventText, Content: response}
+		events <- StreamEvent{Type: StreamEventDone}
+	}()
+	return events, nil
+}
+
+// CollectStream reads a stream to its end and returns the full response
+func CollectStream(events <-chan StreamEvent) (string, error) {
+	var sb strings.Builder
+	for event := range events {
+		switch event.Type {
+		case StreamEventText:
+			sb.WriteString(event.Content)
+		case StreamEventError:
+			return sb.String(), event.Err
+		}
+	}
+	return sb.String(), nil
+}
@@ -0,0 +1,48 @@
+package interfaces_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+)
+
+// staticLLM returns a fixed response and cannot stream
+type staticLLM struct {
+	response string
+	err      error
+}
+
+func (l staticLLM) Generate(ctx context.Context, prompt string, options ...interfaces.GenerateOption) (string, error) {
+	return l.response, l.err
+}
+
+func (l staticLLM) GenerateWithTools(ctx context.Context, prompt string, tools []interfaces.Tool, options ...interfaces.GenerateOption) (string, error) {
+	return l.response, l.err
+}
+
+func (l staticLLM) Name() string { return "static" }
+
+func TestGenerateStreamFallsBackToGenerate(t *testing.T) {
+	events, err := interfaces.GenerateStream(context.Background(), staticLLM{response: "hello"}, "hi")
+	if err != nil {
+		t.Fatalf("GenerateStream failed: %v", err)
+	}
+	var types []interfaces.StreamEventType
+	for event := range events {
+		types = append(types, event.Type)
+		if event.Type == interfaces.StreamEventText && event.Content != "hello" {
+			t.Errorf("expected the full response in one event, got %q", event.Content)
+		}
+	}
+	if len(types) != 2 || types[0] != interfaces.StreamEventText || types[1] != interfaces.StreamEventDone {
+		t.Errorf("expected a text and a done event, got %v", types)
+	}
+
+	failure := errors.New("unavailable")
+	events, _ = interfaces.GenerateStream(context.Background(), staticLLM{err: failure}, "hi")
+	if _, err := interfaces.CollectStream(events); !errors.Is(err, failure) {
+		t.Errorf("expected the error of Generate, got %v", err)
+	}
+}
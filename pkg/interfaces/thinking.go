@@ -0,0 +1,21 @@
+package interfaces
+
+import "context"
+
+// thinkingKey is the context key for the thinking callback
+type thinkingKey struct{}
+
+// WithThinkingCallback returns a context in which LLM clients call fn with
+// the reasoning of models with extended thinking, see LLMConfig.Reasoning.
+// Streams send the reasoning as StreamEventThinking events instead.
+func WithThinkingCallback(ctx context.Context, fn func(thinking string)) context.Context {
+	return context.WithValue(ctx, thinkingKey{}, fn)
+}
+
+// ReportThinking passes the reasoning of a response to the callback in the
+// context, if there is one
+func ReportThinking(ctx context.Context, thinking string) {
+	if fn, ok := ctx.Value(thinkingKey{}).(func(string)); ok && fn != nil && thinking != "" {
+		fn(thinking)
+	}
+}
@@ -1,6 +1,10 @@
 package interfaces
 
-import "context"
+import (
+	"context"
+	"fmt"
+	"io"
+)
 
 // Tool represents a tool that can be used by an agent
 type Tool interface {
@@ -41,6 +45,116 @@ type ParameterSpec struct {
 	Items *ParameterSpec
 }
 
+// ToolExample is an example invocation of a tool
+type ToolExample struct {
+	// Description says what the example does or when it applies
+	Description string
+
+	// Arguments are the arguments the tool is called with
+	Arguments map[string]interface{}
+
+	// Outcome describes the expected result
+	Outcome string
+}
+
+// ToolWithExamples is implemented by tools that declare example invocations.
+// The examples are shown to the LLM to help it call complex tools correctly.
+type ToolWithExamples interface {
+	Tool
+
+	// Examples returns the example invocations of the tool
+	Examples() []ToolExample
+}
+
+// ToolExamples returns the examples of a tool, or nil if it declares none.
+// Tool wrappers use it to pass on the examples of the tools they wrap.
+func ToolExamples(tool Tool) []ToolExample {
+	if withExamples, ok := tool.(ToolWithExamples); ok {
+		return withExamples.Examples()
+	}
+	return nil
+}
+
+// ToolWithSideEffects is implemented by tools that change external systems,
+// such as sending messages or creating tickets. In a dry run (see
+// runctx.WithDryRun) their calls are simulated instead of made.
+type ToolWithSideEffects interface {
+	Tool
+
+	// HasSideEffects reports whether a call with the arguments would change
+	// an external system
+	HasSideEffects(args string) bool
+}
+
+// ToolWithDryRun is implemented by tools with side effects that can simulate
+// a call themselves, e.g. by validating and rendering a message without
+// sending it
+type ToolWithDryRun interface {
+	Tool
+
+	// DryRun returns what a call with the arguments would do, without doing it
+	DryRun(ctx context.Context, args string) (string, error)
+}
+
+// HasSideEffects reports whether the tool declares that a call with the
+// arguments changes an external system
+func HasSideEffects(tool Tool, args string) bool {
+	if withSideEffects, ok := tool.(ToolWithSideEffects); ok {
+		return withSideEffects.HasSideEffects(args)
+	}
+	return false
+}
+
+// SimulateToolCall returns what a call of the tool with the arguments would
+// do without making it, using the tool's own DryRun if it has one
+func SimulateToolCall(ctx context.Context, tool Tool, args string) (string, error) {
+	if withDryRun, ok := tool.(ToolWithDryRun); ok {
+		return withDryRun.DryRun(ctx, args)
+	}
+	return fmt.Sprintf("[dry run] %s was not called; it would have been called with: %s", tool.Name(), args), nil
+}
+
+// ToolWithReader is implemented by tools that can return large results, such
+// as file reads or scrapes, as a stream instead of a string. Agents that spill
+// large results to disk read the stream without holding it in memory.
+type ToolWithReader interface {
+	Tool
+
+	// ExecuteReader executes the tool and returns its result as a stream,
+	// which the caller must close
+	ExecuteReader(ctx context.Context, args string) (io.ReadCloser, error)
+}
+
+// ToolProgress is a progress update of a running tool call
+type ToolProgress struct {
+	// Message describes the current step, e.g. "Fetched 3 of 10 pages"
+	Message string `json:"message"`
+
+	// Completed and Total count the units of work done and to do; Total is
+	// zero if it is not known
+	Completed int `json:"completed,omitempty"`
+	Total     int `json:"total,omitempty"`
+}
+
+// toolProgressKey is the context key for the tool progress callback
+type toolProgressKey struct{}
+
+// WithToolProgress returns a context in which long-running tools, like
+// scrapes or code execution, report their progress to fn. Agents set it for
+// every tool call.
+func WithToolProgress(ctx context.Context, fn func(ToolProgress)) context.Context {
+	return context.WithValue(ctx, toolProgressKey{}, fn)
+}
+
+// ReportToolProgress passes a progress update of the running tool call to
+// the callback in the context, if there is one. Tools call it as often as
+// they like; it does nothing when nobody listens.
+func ReportToolProgress(ctx context.Context, progress ToolProgress) {
+	if fn, ok := ctx.Value(toolProgressKey{}).(func(ToolProgress)); ok && fn != nil {
+		fn(progress)
+	}
+}
+
 // ToolRegistry is a registry of available tools
 type ToolRegistry interface {
 	// Register registers a tool with the registry
@@ -52,3 +166,9 @@ type ToolRegistry interface {
 	// List returns all registered tools
 	List() []Tool
 }
+
+// ToolSelector selects the subset of tools relevant to a query
+type ToolSelector interface {
+	// Select returns the tools from the given set that are most relevant to the query
+	Select(ctx context.Context, query string, tools []Tool) ([]Tool, error)
+}
@@ -0,0 +1,52 @@
+package interfaces
+
+import "context"
+
+// Usage is the token usage of LLM requests as reported by the provider
+type Usage struct {
+	// Model is the model that served the request, as the provider names it
+	Model string `json:"model,omitempty"`
+
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+
+	// Cost is the estimated cost in dollars. Providers don't report it; it
+	// is set by cost tracking wrappers like cost.LLM.
+	Cost float64 `json:"cost,omitempty"`
+}
+
+// TotalTokens returns the input and output tokens
+func (u Usage) TotalTokens() int {
+	return u.InputTokens + u.OutputTokens
+}
+
+// Add adds the tokens and cost of other to the usage. The model is taken
+// from other if the usage has none.
+func (u *Usage) Add(other Usage) {
+	if u.Model == "" {
+		u.Model = other.Model
+	}
+	u.InputTokens += other.InputTokens
+	u.OutputTokens += other.OutputTokens
+	u.Cost += other.Cost
+}
+
+// usageKey is the context key for the usage callback
+type usageKey struct{}
+
+// WithUsageCallback returns a context in which LLM clients call fn with the
+// usage the provider reports for each request. Calls that make several
+// requests, like GenerateWithTools, report each request. The callback
+// replaces any callback already in the context; wrappers that observe usage
+// pass it on with ReportUsage on their own context.
+func WithUsageCallback(ctx context.Context, fn func(Usage)) context.Context {
+	return context.WithValue(ctx, usageKey{}, fn)
+}
+
+// ReportUsage passes the usage of a request to the callback in the context,
+// if there is one. LLM clients call it after each request.
+func ReportUsage(ctx context.Context, usage Usage) {
+	if fn, ok := ctx.Value(usageKey{}).(func(Usage)); ok && fn != nil {
+		fn(usage)
+	}
+}
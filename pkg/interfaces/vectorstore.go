@@ -65,6 +65,21 @@ type VectorStore interface {
 	Get(ctx context.Context, ids []string) ([]Document, error)
 }
 
+// VectorStoreWithFilters is implemented by vector stores that can get and
+// delete documents by metadata filter, e.g. to purge all documents of a
+// source without listing their IDs
+type VectorStoreWithFilters interface {
+	VectorStore
+
+	// GetByFilter returns up to limit documents whose metadata matches the
+	// filter
+	GetByFilter(ctx context.Context, filter Filter, limit int, options ...SearchOption) ([]Document, error)
+
+	// DeleteByFilter deletes the documents whose metadata matches the filter
+	// and returns how many were deleted
+	DeleteByFilter(ctx context.Context, filter Filter, options ...DeleteOption) (int, error)
+}
+
 // StoreOption represents an option for storing documents
 type StoreOption func(*StoreOptions)
 
@@ -91,9 +106,14 @@ type SearchOptions struct {
 	// MinScore is the minimum similarity score (0-1)
 	MinScore float32
 
-	// Filters are metadata filters to apply to the search
+	// Filters are metadata filters to apply to the search, in a
+	// store-specific format
 	Filters map[string]interface{}
 
+	// Filter is a metadata filter that works the same on every store. It is
+	// combined with Filters if both are set.
+	Filter *Filter
+
 	// Class is the class/collection name to search in
 	Class string
 
@@ -151,6 +171,13 @@ func WithFilters(filters map[string]interface{}) SearchOption {
 	}
 }
 
+// WithFilter sets a metadata filter built with Eq, Gt, In, And, Or, etc.
+func WithFilter(filter Filter) SearchOption {
+	return func(o *SearchOptions) {
+		o.Filter = &filter
+	}
+}
+
 // WithEmbedding sets whether to use embedding for the search
 func WithEmbedding(useEmbedding bool) SearchOption {
 	return func(o *SearchOptions) {
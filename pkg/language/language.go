@@ -0,0 +1,176 @@
+// Package language detects the language of a text. It recognizes languages
+// by their script, and Latin-script languages by their most common words, so
+// it needs no model and works best on sentences rather than single words.
+package language
+
+import (
+	"strings"
+	"unicode"
+)
+
+// names are the English names of the languages Detect recognizes
+var names = map[string]string{
+	"ar": "Arabic",
+	"de": "German",
+	"el": "Greek",
+	"en": "English",
+	"es": "Spanish",
+	"fr": "French",
+	"he": "Hebrew",
+	"hi": "Hindi",
+	"it": "Italian",
+	"ja": "Japanese",
+	"ko": "Korean",
+	"nl": "Dutch",
+	"pt": "Portuguese",
+	"ru": "Russian",
+	"th": "Thai",
+	"uk": "Ukrainian",
+	"zh": "Chinese",
+}
+
+// commonWords are frequent words of Latin-script languages
+var commonWords = map[string][]string{
+	"en": strings.Fields("the and is are was were of to in that it for on with as you this be have not but what how can do i my we your please thanks hello"),
+	"es": strings.Fields("el la los las y es son de que en un una por para con no se lo como más pero su al del está estás qué cómo hola gracias yo tengo puedes puedo mi mis tu muy este esta hay ayuda"),
+	"fr": strings.Fields("le la les et est sont de des que en un une pour avec ne pas se du au qui dans ce il je vous nous mais comment bonjour merci suis"),
+	"de": strings.Fields("der die das und ist sind nicht ein eine zu mit den dem von für auf ich sie es wir wie was bitte danke hallo auch noch kann"),
+	"it": strings.Fields("il lo la gli le e è sono di che un una per con non si del della in mi ti come cosa ciao grazie sei ho anche ma"),
+	"pt": strings.Fields("o a os as e é são de que em um uma por para com não se do da no na como mais mas você olá obrigado está eu tenho"),
+	"nl": strings.Fields("de het een en is zijn van dat in op te met niet voor ik je jij we wat hoe maar ook hallo dank bedankt kan"),
+}
+
+// wordLanguages maps each common word to the languages it belongs to
+var wordLanguages = func() map[string][]string {
+	index := make(map[string][]string)
+	for code, words := range commonWords {
+		for _, word := range words {
+			index[word] = append(index[word], code)
+		}
+	}
+	return index
+}()
+
+// scripts are the non-Latin scripts that identify a language
+var scripts = []struct {
+	code  string
+	table *unicode.RangeTable
+}{
+	{"ja", unicode.Hiragana},
+	{"ja", unicode.Katakana},
+	{"ko", unicode.Hangul},
+	{"zh", unicode.Han},
+	{"ru", unicode.Cyrillic},
+	{"ar", unicode.Arabic},
+	{"el", unicode.Greek},
+	{"he", unicode.Hebrew},
+	{"hi", unicode.Devanagari},
+	{"th", unicode.Thai},
+}
+
+// Detection is the detected language of a text
+type Detection struct {
+	// Code is the ISO 639-1 code of the language, or empty if it is unknown
+	Code string
+
+	// Confidence is how sure the detection is, from 0 to 1
+	Confidence float64
+}
+
+// Detect detects the language of a text
+func Detect(text string) Detection {
+	if detection, ok := detectScript(text); ok {
+		return detection
+	}
+	return detectWords(text)
+}
+
+// Name returns the English name of a language, or the code if it is unknown
+func Name(code string) string {
+	if name, ok := names[strings.ToLower(code)]; ok {
+		return name
+	}
+	return code
+}
+
+// detectScript detects languages written in a non-Latin script
+func detectScript(text string) (Detection, bool) {
+	counts := make(map[string]int)
+	letters := 0
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		for _, script := range scripts {
+			if unicode.Is(script.table, r) {
+				counts[script.code]++
+				break
+			}
+		}
+	}
+	if letters == 0 {
+		return Detection{}, false
+	}
+
+	// Japanese mixes kana with Han characters
+	if counts["ja"] > 0 {
+		counts["ja"] += counts["zh"]
+		delete(counts, "zh")
+	}
+	best := ""
+	for code, count := range counts {
+		if best == "" || count > counts[best] || (count == counts[best] && code < best) {
+			best = code
+		}
+	}
+	if best == "" || counts[best]*2 < letters {
+		return Detection{}, false
+	}
+	detection := Detection{Code: best, Confidence: float64(counts[best]) / float64(letters)}
+	if best == "ru" && strings.ContainsAny(strings.ToLower(text), "іїєґ") {
+		detection.Code = "uk"
+	}
+	return detection, true
+}
+
+// detectWords detects Latin-script languages by their common words
+func detectWords(text string) Detection {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && r != '\''
+	})
+	if len(words) == 0 {
+		return Detection{}
+	}
+
+	hits := make(map[string]int)
+	for _, word := range words {
+		for _, code := range wordLanguages[word] {
+			hits[code]++
+		}
+	}
+	best, second := "", 0
+	for code, count := range hits {
+		switch {
+		case best == "" || count > hits[best] || (count == hits[best] && code < best):
+			if best != "" {
+				second = hits[best]
+			}
+			best = code
+		case count > second:
+			second = count
+		}
+	}
+	if best == "" {
+		return Detection{}
+	}
+
+	// Confident when the language stands out from the others and enough of
+	// the words are common words
+	margin := float64(hits[best]-second) / float64(hits[best])
+	coverage := float64(hits[best]) / float64(len(words)) * 3
+	if coverage > 1 {
+		coverage = 1
+	}
+	return Detection{Code: best, Confidence: margin * coverage}
+}
@@ -0,0 +1,40 @@
+package language_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/run-bigpig/llm-agent/pkg/language"
+)
+
+func TestDetect(t *testing.T) {
+	for text, code := range map[string]string{
+		"Hello, how can I change the address on my invoice?":          "en",
+		"Hola, ¿cómo puedo cambiar la dirección de mi factura?":       "es",
+		"Bonjour, comment est-ce que je peux changer mon adresse ?":   "fr",
+		"Hallo, wie kann ich die Adresse auf meiner Rechnung ändern?": "de",
+		"Ciao, come posso cambiare l'indirizzo della fattura?":        "it",
+		"Olá, como posso mudar o endereço da minha fatura? Obrigado!": "pt",
+		"Hallo, hoe kan ik het adres op mijn factuur wijzigen?":       "nl",
+		"请问如何修改发票上的地址？":                                               "zh",
+		"請求書の住所を変更するにはどうすればいいですか？":                                    "ja",
+		"청구서의 주소를 어떻게 변경하나요?":                                         "ko",
+		"Как изменить адрес в моем счете?":                            "ru",
+		"Як змінити адресу в моєму рахунку? Дякую, і до побачення":    "uk",
+		"كيف يمكنني تغيير العنوان في فاتورتي؟":                        "ar",
+	} {
+		detection := language.Detect(text)
+		assert.Equal(t, code, detection.Code, text)
+		assert.Greater(t, detection.Confidence, 0.5, text)
+	}
+
+	assert.Equal(t, "", language.Detect("12345 !!!").Code)
+	assert.Less(t, language.Detect("OK").Confidence, 0.5)
+}
+
+func TestName(t *testing.T) {
+	assert.Equal(t, "Spanish", language.Name("es"))
+	assert.Equal(t, "Spanish", language.Name("ES"))
+	assert.Equal(t, "tlh", language.Name("tlh"))
+}
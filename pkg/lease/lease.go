@@ -0,0 +1,218 @@
+// Package lease provides leases that give one replica at a time exclusive use
+// of a resource, such as a conversation. A lease expires unless its holder
+// renews it, so a crashed replica's lease is taken over once it runs out.
+package lease
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrHeld is returned when another owner holds the lease
+	ErrHeld = errors.New("lease is held by another owner")
+
+	// ErrLost is the cause of a lease context's cancellation when the lease
+	// could not be renewed, e.g. because it expired and was taken over
+	ErrLost = errors.New("lease lost")
+)
+
+// Locker stores leases. Implementations must make Acquire, Renew and Release
+// atomic, and let an owner acquire a lease it already holds.
+type Locker interface {
+	// Acquire takes the lease on key for owner for ttl. It returns false if
+	// another owner holds the lease.
+	Acquire(ctx context.Context, key, owner string, ttl time.Duration) (bool, error)
+
+	// Renew extends owner's lease on key to ttl from now. It returns false if
+	// owner no longer holds the lease.
+	Renew(ctx context.Context, key, owner string, ttl time.Duration) (bool, error)
+
+	// Release gives up owner's lease on key. Releasing a lease owner doesn't
+	// hold does nothing.
+	Release(ctx context.Context, key, owner string) error
+}
+
+// Manager acquires leases and keeps them renewed while they are held
+type Manager struct {
+	locker        Locker
+	owner         string
+	ttl           time.Duration
+	renewInterval time.Duration
+	wait          time.Duration
+	retryInterval time.Duration
+}
+
+// Option configures a Manager
+type Option func(*Manager)
+
+// WithTTL sets how long a lease lasts without renewal, which is how long a
+// crashed holder blocks others. Defaults to 30 seconds.
+func WithTTL(ttl time.Duration) Option {
+	return func(m *Manager) {
+		if ttl > 0 {
+			m.ttl = ttl
+		}
+	}
+}
+
+// WithRenewInterval sets how often held leases are renewed. Defaults to a
+// third of the TTL.
+func WithRenewInterval(interval time.Duration) Option {
+	return func(m *Manager) {
+		if interval > 0 {
+			m.renewInterval = interval
+		}
+	}
+}
+
+// WithWait makes Acquire wait up to d for a held lease to be released,
+// retrying every retryInterval. By default Acquire fails right away with
+// ErrHeld.
+func WithWait(d, retryInterval time.Duration) Option {
+	return func(m *Manager) {
+		m.wait = d
+		if retryInterval > 0 {
+			m.retryInterval = retryInterval
+		}
+	}
+}
+
+// WithOwner sets the owner ID of the leases. Defaults to the host name plus a
+// random ID, which is unique per Manager.
+func WithOwner(owner string) Option {
+	return func(m *Manager) {
+		m.owner = owner
+	}
+}
+
+// NewManager creates a Manager that stores leases in locker
+func NewManager(locker Locker, options ...Option) *Manager {
+	host, _ := os.Hostname()
+	m := &Manager{
+		locker:        locker,
+		owner:         fmt.Sprintf("%s-%s", host, uuid.New().String()),
+		ttl:           30 * time.Second,
+		retryInterval: 100 * time.Millisecond,
+	}
+	for _, option := range options {
+		option(m)
+	}
+	if m.renewInterval == 0 || m.renewInterval >= m.ttl {
+		m.renewInterval = m.ttl / 3
+	}
+	return m
+}
+
+// Owner returns the owner ID of the Manager's leases
+func (m *Manager) Owner() string {
+	return m.owner
+}
+
+// Acquire takes the lease on key and renews it in the background until it is
+// released. It fails with ErrHeld if another owner holds the lease.
+func (m *Manager) Acquire(ctx context.Context, key string) (*Lease, error) {
+	deadline := time.Now().Add(m.wait)
+	for {
+		acquired, err := m.locker.Acquire(ctx, key, m.owner, m.ttl)
+		if err != nil {
+			return nil, fmt.Errorf("failed to acquire lease on %s: %w", key, err)
+		}
+		if acquired {
+			break
+		}
+		if !time.Now().Before(deadline) {
+			return nil, fmt.Errorf("%w: %s", ErrHeld, key)
+		}
+		select {
+		case <-time.After(m.retryInterval):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	leaseCtx, cancel := context.WithCancelCause(ctx)
+	l := &Lease{
+		manager: m,
+		key:     key,
+		ctx:     leaseCtx,
+		cancel:  cancel,
+		done:    make(chan struct{}),
+	}
+	go l.renew()
+	return l, nil
+}
+
+// Lease is a held lease
+type Lease struct {
+	manager *Manager
+	key     string
+	ctx     context.Context
+	cancel  context.CancelCauseFunc
+	done    chan struct{}
+	once    sync.Once
+}
+
+// Key returns the leased key
+func (l *Lease) Key() string {
+	return l.key
+}
+
+// Context returns a context that is cancelled when the lease is released or
+// lost. Work done under the lease should use it, so that it stops when another
+// owner may have taken over; context.Cause returns ErrLost in that case.
+func (l *Lease) Context() context.Context {
+	return l.ctx
+}
+
+// Release stops renewing the lease and gives it up
+func (l *Lease) Release(ctx context.Context) error {
+	var err error
+	l.once.Do(func() {
+		l.cancel(context.Canceled)
+		<-l.done
+		if releaseErr := l.manager.locker.Release(ctx, l.key, l.manager.owner); releaseErr != nil {
+			err = fmt.Errorf("failed to release lease on %s: %w", l.key, releaseErr)
+		}
+	})
+	return err
+}
+
+// renew renews the lease until it is released. It cancels the lease context
+// when another owner has taken the lease, or when renewals have failed for so
+// long that the lease may have expired.
+func (l *Lease) renew() {
+	defer close(l.done)
+	ticker := time.NewTicker(l.manager.renewInterval)
+	defer ticker.Stop()
+
+	renewedAt := time.Now()
+	for {
+		select {
+		case <-l.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		renewed, err := l.manager.locker.Renew(context.WithoutCancel(l.ctx), l.key, l.manager.owner, l.manager.ttl)
+		switch {
+		case err != nil:
+			// Keep trying while the lease may still be valid
+			if time.Since(renewedAt) >= l.manager.ttl {
+				l.cancel(fmt.Errorf("%w: %s: %v", ErrLost, l.key, err))
+				return
+			}
+		case !renewed:
+			l.cancel(fmt.Errorf("%w: %s", ErrLost, l.key))
+			return
+		default:
+			renewedAt = time.Now()
+		}
+	}
+}
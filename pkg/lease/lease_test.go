@@ -0,0 +1,105 @@
+package lease_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/run-bigpig/llm-agent/pkg/lease"
+)
+
+func TestLeaseExclusion(t *testing.T) {
+	locker := lease.NewMemoryLocker()
+	first := lease.NewManager(locker, lease.WithTTL(time.Second))
+	second := lease.NewManager(locker, lease.WithTTL(time.Second))
+	ctx := context.Background()
+
+	held, err := first.Acquire(ctx, "conv")
+	if err != nil {
+		t.Fatalf("failed to acquire lease: %v", err)
+	}
+	if _, err := second.Acquire(ctx, "conv"); !errors.Is(err, lease.ErrHeld) {
+		t.Fatalf("expected ErrHeld, got %v", err)
+	}
+
+	if err := held.Release(ctx); err != nil {
+		t.Fatalf("failed to release lease: %v", err)
+	}
+	if held.Context().Err() == nil {
+		t.Error("expected the lease context to be cancelled on release")
+	}
+	if _, err := second.Acquire(ctx, "conv"); err != nil {
+		t.Fatalf("expected the released lease to be free, got %v", err)
+	}
+}
+
+func TestLeaseRenewalAndTakeover(t *testing.T) {
+	locker := lease.NewMemoryLocker()
+	ctx := context.Background()
+
+	// A holder that keeps renewing keeps the lease past its TTL
+	holder := lease.NewManager(locker, lease.WithTTL(60*time.Millisecond))
+	held, err := holder.Acquire(ctx, "conv")
+	if err != nil {
+		t.Fatalf("failed to acquire lease: %v", err)
+	}
+	time.Sleep(150 * time.Millisecond)
+	if ok, _ := locker.Acquire(ctx, "conv", "other", time.Second); ok {
+		t.Fatal("expected the renewed lease to still be held")
+	}
+	if err := held.Release(ctx); err != nil {
+		t.Fatalf("failed to release lease: %v", err)
+	}
+
+	// A crashed holder stops renewing, and the lease is taken over once it expires
+	if ok, _ := locker.Acquire(ctx, "conv", "crashed", 100*time.Millisecond); !ok {
+		t.Fatal("failed to acquire lease for the crashed holder")
+	}
+	waiter := lease.NewManager(locker, lease.WithWait(time.Second, 10*time.Millisecond))
+	started := time.Now()
+	taken, err := waiter.Acquire(ctx, "conv")
+	if err != nil {
+		t.Fatalf("expected the expired lease to be taken over, got %v", err)
+	}
+	defer taken.Release(ctx)
+	if time.Since(started) < 50*time.Millisecond {
+		t.Error("expected the lease to be taken over only after it expired")
+	}
+}
+
+func TestLeaseLost(t *testing.T) {
+	locker := lease.NewMemoryLocker()
+	ctx := context.Background()
+	manager := lease.NewManager(locker, lease.WithTTL(300*time.Millisecond), lease.WithRenewInterval(10*time.Millisecond))
+
+	held, err := manager.Acquire(ctx, "conv")
+	if err != nil {
+		t.Fatalf("failed to acquire lease: %v", err)
+	}
+
+	// Another owner takes the lease, e.g. after a network partition
+	if err := locker.Release(ctx, "conv", manager.Owner()); err != nil {
+		t.Fatalf("failed to release lease: %v", err)
+	}
+	if ok, _ := locker.Acquire(ctx, "conv", "other", time.Second); !ok {
+		t.Fatal("failed to take the lease over")
+	}
+
+	select {
+	case <-held.Context().Done():
+	case <-time.After(time.Second):
+		t.Fatal("expected the lease context to be cancelled")
+	}
+	if cause := context.Cause(held.Context()); !errors.Is(cause, lease.ErrLost) {
+		t.Errorf("expected ErrLost, got %v", cause)
+	}
+
+	// Releasing a lost lease leaves the new owner's lease alone
+	if err := held.Release(ctx); err != nil {
+		t.Fatalf("failed to release lease: %v", err)
+	}
+	if ok, _ := locker.Renew(ctx, "conv", "other", time.Second); !ok {
+		t.Error("expected the new owner to keep the lease")
+	}
+}
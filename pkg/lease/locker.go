@@ -0,0 +1,134 @@
+package lease
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// renewScript extends the lease only if owner still holds it
+var renewScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+// releaseScript deletes the lease only if owner still holds it
+var releaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// RedisLocker stores leases in Redis, so that replicas sharing the Redis
+// server exclude each other
+type RedisLocker struct {
+	client    *redis.Client
+	keyPrefix string
+}
+
+// RedisOption configures a RedisLocker
+type RedisOption func(*RedisLocker)
+
+// WithKeyPrefix sets the prefix of lease keys. Defaults to "agent:lease:".
+func WithKeyPrefix(prefix string) RedisOption {
+	return func(r *RedisLocker) {
+		r.keyPrefix = prefix
+	}
+}
+
+// NewRedisLocker creates a locker backed by a Redis client
+func NewRedisLocker(client *redis.Client, options ...RedisOption) *RedisLocker {
+	r := &RedisLocker{
+		client:    client,
+		keyPrefix: "agent:lease:",
+	}
+	for _, option := range options {
+		option(r)
+	}
+	return r
+}
+
+// Acquire takes the lease with SET NX, or renews it if owner already holds it
+func (r *RedisLocker) Acquire(ctx context.Context, key, owner string, ttl time.Duration) (bool, error) {
+	acquired, err := r.client.SetNX(ctx, r.keyPrefix+key, owner, ttl).Result()
+	if err != nil {
+		return false, err
+	}
+	if acquired {
+		return true, nil
+	}
+	return r.Renew(ctx, key, owner, ttl)
+}
+
+// Renew extends the lease if owner still holds it
+func (r *RedisLocker) Renew(ctx context.Context, key, owner string, ttl time.Duration) (bool, error) {
+	renewed, err := renewScript.Run(ctx, r.client, []string{r.keyPrefix + key}, owner, ttl.Milliseconds()).Int()
+	if err != nil {
+		return false, err
+	}
+	return renewed == 1, nil
+}
+
+// Release deletes the lease if owner still holds it
+func (r *RedisLocker) Release(ctx context.Context, key, owner string) error {
+	return releaseScript.Run(ctx, r.client, []string{r.keyPrefix + key}, owner).Err()
+}
+
+// MemoryLocker stores leases in memory. It only excludes owners within one
+// process, e.g. for tests and single-replica deployments.
+type MemoryLocker struct {
+	mu     sync.Mutex
+	leases map[string]memoryLease
+	now    func() time.Time
+}
+
+// memoryLease is a lease held in memory
+type memoryLease struct {
+	owner   string
+	expires time.Time
+}
+
+// NewMemoryLocker creates an empty in-memory locker
+func NewMemoryLocker() *MemoryLocker {
+	return &MemoryLocker{leases: make(map[string]memoryLease), now: time.Now}
+}
+
+// Acquire takes the lease if it is free, expired or already owner's
+func (m *MemoryLocker) Acquire(ctx context.Context, key, owner string, ttl time.Duration) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.now()
+	if l, ok := m.leases[key]; ok && l.owner != owner && now.Before(l.expires) {
+		return false, nil
+	}
+	m.leases[key] = memoryLease{owner: owner, expires: now.Add(ttl)}
+	return true, nil
+}
+
+// Renew extends the lease if owner still holds it
+func (m *MemoryLocker) Renew(ctx context.Context, key, owner string, ttl time.Duration) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.now()
+	l, ok := m.leases[key]
+	if !ok || l.owner != owner || !now.Before(l.expires) {
+		return false, nil
+	}
+	m.leases[key] = memoryLease{owner: owner, expires: now.Add(ttl)}
+	return true, nil
+}
+
+// Release deletes the lease if owner still holds it
+func (m *MemoryLocker) Release(ctx context.Context, key, owner string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if l, ok := m.leases[key]; ok && l.owner == owner {
+		delete(m.leases, key)
+	}
+	return nil
+}
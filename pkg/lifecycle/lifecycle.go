@@ -0,0 +1,324 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/run-bigpig/llm-agent/pkg/logging"
+)
+
+// ErrShuttingDown is returned when new work is started after shutdown has begun
+var ErrShuttingDown = errors.New("lifecycle: shutting down, not accepting new work")
+
+// Flusher is implemented by components that buffer data, such as tracers
+type Flusher interface {
+	Flush() error
+}
+
+// FlusherFunc adapts a function to the Flusher interface
+type FlusherFunc func() error
+
+// Flush calls f()
+func (f FlusherFunc) Flush() error {
+	return f()
+}
+
+// CloserFunc adapts a function to the io.Closer interface
+type CloserFunc func() error
+
+// Close calls f()
+func (f CloserFunc) Close() error {
+	return f()
+}
+
+// namedFlusher pairs a registered flusher with its name for logging
+type namedFlusher struct {
+	name    string
+	flusher Flusher
+}
+
+// namedCloser pairs a registered closer with its name for logging
+type namedCloser struct {
+	name   string
+	closer io.Closer
+}
+
+// Manager coordinates graceful shutdown. It tracks in-flight work, and on
+// shutdown stops accepting new work, waits for in-flight work with a deadline,
+// flushes registered flushers and closes registered closers.
+type Manager struct {
+	timeout time.Duration
+	logger  logging.Logger
+	signals []os.Signal
+
+	mu           sync.Mutex
+	shuttingDown bool
+	inFlight     map[uint64]string
+	nextID       uint64
+	wg           sync.WaitGroup
+	flushers     []namedFlusher
+	closers      []namedCloser
+
+	// ctx is cancelled when the shutdown deadline is reached so that
+	// in-flight work can abort
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	shutdownOnce sync.Once
+	shutdownErr  error
+	done         chan struct{}
+}
+
+// Option represents an option for configuring the manager
+type Option func(*Manager)
+
+// WithTimeout sets how long shutdown waits for in-flight work before giving up
+func WithTimeout(timeout time.Duration) Option {
+	return func(m *Manager) {
+		m.timeout = timeout
+	}
+}
+
+// WithLogger sets the logger for the manager
+func WithLogger(logger logging.Logger) Option {
+	return func(m *Manager) {
+		m.logger = logger
+	}
+}
+
+// WithSignals sets the signals that trigger shutdown in ListenForSignals
+func WithSignals(signals ...os.Signal) Option {
+	return func(m *Manager) {
+		m.signals = signals
+	}
+}
+
+// NewManager creates a new lifecycle manager
+func NewManager(options ...Option) *Manager {
+	ctx, cancel := context.WithCancel(context.Background())
+	m := &Manager{
+		timeout:  30 * time.Second,
+		logger:   logging.New(),
+		signals:  []os.Signal{syscall.SIGTERM, os.Interrupt},
+		inFlight: make(map[uint64]string),
+		ctx:      ctx,
+		cancel:   cancel,
+		done:     make(chan struct{}),
+	}
+
+	for _, option := range options {
+		option(m)
+	}
+
+	return m
+}
+
+// Begin registers a unit of in-flight work, such as an agent run or a
+// workflow. The returned function must be called when the work is finished.
+// Begin returns ErrShuttingDown once shutdown has started.
+func (m *Manager) Begin(name string) (func(), error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.shuttingDown {
+		return nil, ErrShuttingDown
+	}
+
+	id := m.nextID
+	m.nextID++
+	m.inFlight[id] = name
+	m.wg.Add(1)
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			m.mu.Lock()
+			delete(m.inFlight, id)
+			m.mu.Unlock()
+			m.wg.Done()
+		})
+	}, nil
+}
+
+// Go runs fn in a new goroutine tracked as in-flight work. The context passed
+// to fn is cancelled if the shutdown deadline is reached.
+func (m *Manager) Go(name string, fn func(ctx context.Context)) error {
+	done, err := m.Begin(name)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		defer done()
+		fn(m.ctx)
+	}()
+
+	return nil
+}
+
+// Context returns a context that is cancelled when the shutdown deadline is
+// reached, for long-running work that should abort rather than be abandoned
+func (m *Manager) Context() context.Context {
+	return m.ctx
+}
+
+// Bind returns a copy of ctx that is also cancelled when the shutdown
+// deadline is reached, so that work started with a caller's context, such as
+// an agent run, aborts instead of outliving shutdown. The returned cancel
+// function must be called when the work is finished.
+func (m *Manager) Bind(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	stop := context.AfterFunc(m.ctx, func() {
+		cancel(ErrShuttingDown)
+	})
+	return ctx, func() {
+		stop()
+		cancel(context.Canceled)
+	}
+}
+
+// InFlight returns the names of the work currently in flight
+func (m *Manager) InFlight() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	names := make([]string, 0, len(m.inFlight))
+	for _, name := range m.inFlight {
+		names = append(names, name)
+	}
+	return names
+}
+
+// RegisterFlusher registers a component to flush on shutdown, such as a Langfuse tracer
+func (m *Manager) RegisterFlusher(name string, flusher Flusher) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.flushers = append(m.flushers, namedFlusher{name: name, flusher: flusher})
+}
+
+// RegisterCloser registers a component to close on shutdown, such as an LLM
+// client, memory backend or MCP server. Closers are closed in reverse order
+// of registration.
+func (m *Manager) RegisterCloser(name string, closer io.Closer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.closers = append(m.closers, namedCloser{name: name, closer: closer})
+}
+
+// ShuttingDown reports whether shutdown has started
+func (m *Manager) ShuttingDown() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.shuttingDown
+}
+
+// Done returns a channel that is closed when shutdown has completed
+func (m *Manager) Done() <-chan struct{} {
+	return m.done
+}
+
+// Shutdown stops accepting new work, waits for in-flight work until the
+// configured timeout or ctx is done, then flushes and closes registered
+// components. Flushing and closing happen even if the wait times out.
+// Calling Shutdown more than once returns the result of the first call.
+func (m *Manager) Shutdown(ctx context.Context) error {
+	m.shutdownOnce.Do(func() {
+		m.shutdownErr = m.shutdown(ctx)
+		close(m.done)
+	})
+	return m.shutdownErr
+}
+
+// shutdown performs the shutdown sequence
+func (m *Manager) shutdown(ctx context.Context) error {
+	m.mu.Lock()
+	m.shuttingDown = true
+	pending := len(m.inFlight)
+	m.mu.Unlock()
+
+	m.logger.Info(ctx, "Shutting down", map[string]interface{}{"in_flight": pending})
+
+	var errs []error
+
+	if err := m.wait(ctx); err != nil {
+		remaining := m.InFlight()
+		m.logger.Warn(ctx, "Shutdown deadline reached with work still in flight", map[string]interface{}{
+			"in_flight": remaining,
+		})
+		errs = append(errs, fmt.Errorf("%w: %d still in flight", err, len(remaining)))
+	}
+
+	// Signal any remaining work to abort
+	m.cancel()
+
+	m.mu.Lock()
+	flushers := append([]namedFlusher(nil), m.flushers...)
+	closers := append([]namedCloser(nil), m.closers...)
+	m.mu.Unlock()
+
+	for _, f := range flushers {
+		if err := f.flusher.Flush(); err != nil {
+			m.logger.Error(ctx, "Failed to flush", map[string]interface{}{"name": f.name, "error": err.Error()})
+			errs = append(errs, fmt.Errorf("failed to flush %s: %w", f.name, err))
+		}
+	}
+
+	for i := len(closers) - 1; i >= 0; i-- {
+		c := closers[i]
+		if err := c.closer.Close(); err != nil {
+			m.logger.Error(ctx, "Failed to close", map[string]interface{}{"name": c.name, "error": err.Error()})
+			errs = append(errs, fmt.Errorf("failed to close %s: %w", c.name, err))
+		}
+	}
+
+	m.logger.Info(ctx, "Shutdown complete", nil)
+
+	return errors.Join(errs...)
+}
+
+// wait blocks until in-flight work finishes, the timeout expires or ctx is done
+func (m *Manager) wait(ctx context.Context) error {
+	if m.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, m.timeout)
+		defer cancel()
+	}
+
+	finished := make(chan struct{})
+	go func() {
+		m.wg.Wait()
+		close(finished)
+	}()
+
+	select {
+	case <-finished:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// ListenForSignals starts shutdown when one of the configured signals is
+// received (SIGTERM and interrupt by default). It returns immediately; use
+// Done to wait for shutdown to complete.
+func (m *Manager) ListenForSignals() {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, m.signals...)
+
+	go func() {
+		select {
+		case sig := <-ch:
+			m.logger.Info(context.Background(), "Received signal", map[string]interface{}{"signal": sig.String()})
+			_ = m.Shutdown(context.Background())
+		case <-m.done:
+		}
+		signal.Stop(ch)
+	}()
+}
@@ -0,0 +1,133 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestBeginAfterShutdown(t *testing.T) {
+	m := NewManager()
+	if err := m.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown failed: %v", err)
+	}
+
+	if _, err := m.Begin("run"); !errors.Is(err, ErrShuttingDown) {
+		t.Errorf("Expected ErrShuttingDown, got %v", err)
+	}
+	if err := m.Go("worker", func(ctx context.Context) {}); !errors.Is(err, ErrShuttingDown) {
+		t.Errorf("Expected ErrShuttingDown from Go, got %v", err)
+	}
+	select {
+	case <-m.Done():
+	default:
+		t.Error("Expected Done to be closed after shutdown")
+	}
+}
+
+func TestShutdownWaitsForInFlightWork(t *testing.T) {
+	m := NewManager()
+	done, err := m.Begin("run")
+	if err != nil {
+		t.Fatalf("Begin failed: %v", err)
+	}
+
+	finished := make(chan struct{})
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		close(finished)
+		done()
+	}()
+
+	if err := m.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown failed: %v", err)
+	}
+	select {
+	case <-finished:
+	default:
+		t.Error("Expected shutdown to wait for in-flight work")
+	}
+}
+
+func TestShutdownTimeout(t *testing.T) {
+	m := NewManager(WithTimeout(20 * time.Millisecond))
+	if _, err := m.Begin("stuck"); err != nil {
+		t.Fatalf("Begin failed: %v", err)
+	}
+
+	ctx, cancel := m.Bind(context.Background())
+	defer cancel()
+
+	flushed := false
+	m.RegisterFlusher("tracer", FlusherFunc(func() error {
+		flushed = true
+		return nil
+	}))
+
+	err := m.Shutdown(context.Background())
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected a deadline error, got %v", err)
+	}
+	if !flushed {
+		t.Error("Expected flushers to run after the deadline")
+	}
+	if !reflect.DeepEqual(m.InFlight(), []string{"stuck"}) {
+		t.Errorf("Expected the stuck work to be in flight, got %v", m.InFlight())
+	}
+
+	// Bound contexts are cancelled at the deadline
+	select {
+	case <-ctx.Done():
+		if !errors.Is(context.Cause(ctx), ErrShuttingDown) {
+			t.Errorf("Expected ErrShuttingDown as the cause, got %v", context.Cause(ctx))
+		}
+	case <-time.After(time.Second):
+		t.Error("Expected the bound context to be cancelled")
+	}
+	if m.Context().Err() == nil {
+		t.Error("Expected the manager context to be cancelled")
+	}
+}
+
+func TestBindKeepsCallerContext(t *testing.T) {
+	m := NewManager()
+	parent, cancelParent := context.WithCancel(context.Background())
+	ctx, cancel := m.Bind(parent)
+	defer cancel()
+
+	cancelParent()
+	if !errors.Is(ctx.Err(), context.Canceled) || errors.Is(context.Cause(ctx), ErrShuttingDown) {
+		t.Errorf("Expected the caller's cancellation, got %v", context.Cause(ctx))
+	}
+}
+
+func TestClosersRunInReverseOrder(t *testing.T) {
+	m := NewManager()
+
+	var order []string
+	for _, name := range []string{"llm", "memory", "mcp"} {
+		name := name
+		m.RegisterCloser(name, CloserFunc(func() error {
+			order = append(order, name)
+			if name == "memory" {
+				return errors.New("connection reset")
+			}
+			return nil
+		}))
+	}
+
+	err := m.Shutdown(context.Background())
+	if err == nil {
+		t.Error("Expected the close error to be returned")
+	}
+	if !reflect.DeepEqual(order, []string{"mcp", "memory", "llm"}) {
+		t.Errorf("Expected closers in reverse order, got %v", order)
+	}
+
+	// Shutdown runs once
+	if again := m.Shutdown(context.Background()); again != err || len(order) != 3 {
+		t.Errorf("Expected the first result and no second shutdown, got %v after %d closes", again, len(order))
+	}
+}
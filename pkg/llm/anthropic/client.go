@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -15,16 +16,21 @@ import (
 	"github.com/run-bigpig/llm-agent/pkg/logging"
 	"github.com/run-bigpig/llm-agent/pkg/multitenancy"
 	"github.com/run-bigpig/llm-agent/pkg/retry"
+	"github.com/run-bigpig/llm-agent/pkg/runctx"
 )
 
 // AnthropicClient implements the LLM interface for Anthropic
 type AnthropicClient struct {
-	APIKey        string
-	Model         string
-	BaseURL       string
-	HTTPClient    *http.Client
-	logger        logging.Logger
-	retryExecutor *retry.Executor
+	APIKey         string
+	Model          string
+	BaseURL        string
+	HTTPClient     *http.Client
+	logger         logging.Logger
+	retryExecutor  *retry.Executor
+	inputExamples  bool // Send tool examples in the input_examples field
+	requestTimeout time.Duration
+
+	maxToolIterations int
 }
 
 // Option represents an option for configuring the Anthropic client
@@ -58,6 +64,52 @@ func WithBaseURL(baseURL string) Option {
 	}
 }
 
+// WithToolInputExamples sends the examples of tools implementing
+// interfaces.ToolWithExamples in the input_examples field of the tool
+// definitions, which is a beta feature of the Anthropic API
+func WithToolInputExamples() Option {
+	return func(c *AnthropicClient) {
+		c.inputExamples = true
+	}
+}
+
+// WithMaxToolIterations sets the maximum number of tool calling rounds in
+// GenerateWithTools before the model is asked for a final answer
+func WithMaxToolIterations(maxIterations int) Option {
+	return func(c *AnthropicClient) {
+		c.maxToolIterations = maxIterations
+	}
+}
+
+// defaultMaxTokens is the number of tokens a response may have unless
+// LLMConfig.MaxTokens sets another limit
+const defaultMaxTokens = 2048
+
+// maxTokens returns the number of tokens a response may have
+func maxTokens(config *interfaces.LLMConfig) int {
+	if config != nil && config.MaxTokens > 0 {
+		return config.MaxTokens
+	}
+	return defaultMaxTokens
+}
+
+// errConstraintUnsupported is returned for calls with a constraint, see
+// interfaces.WithGrammar
+var errConstraintUnsupported = errors.New("constrained decoding is not supported by the Anthropic API")
+
+// DefaultRequestTimeout is how long a call may take unless the client or the
+// call sets another timeout. Long answers from large models can take minutes.
+const DefaultRequestTimeout = 5 * time.Minute
+
+// WithDefaultRequestTimeout sets how long a call may take unless it sets its
+// own timeout with interfaces.WithRequestTimeout. Zero means no limit other
+// than the deadline of the caller's context.
+func WithDefaultRequestTimeout(timeout time.Duration) Option {
+	return func(c *AnthropicClient) {
+		c.requestTimeout = timeout
+	}
+}
+
 // WithHTTPClient sets the HTTP client for the Anthropic client
 func WithHTTPClient(httpClient *http.Client) Option {
 	return func(c *AnthropicClient) {
@@ -69,11 +121,13 @@ func WithHTTPClient(httpClient *http.Client) Option {
 func NewClient(apiKey string, options ...Option) *AnthropicClient {
 	// Create client with default options
 	client := &AnthropicClient{
-		APIKey:     apiKey,
-		Model:      Claude37Sonnet,
-		BaseURL:    "https://api.anthropic.com",
-		HTTPClient: &http.Client{Timeout: 60 * time.Second},
-		logger:     logging.New(),
+		APIKey:            apiKey,
+		Model:             Claude37Sonnet,
+		BaseURL:           "https://api.anthropic.com",
+		HTTPClient:        &http.Client{},
+		logger:            logging.New(),
+		requestTimeout:    DefaultRequestTimeout,
+		maxToolIterations: 10,
 	}
 
 	// Apply options
@@ -98,26 +152,50 @@ const (
 	Claude37Sonnet = "claude-3-7-sonnet-latest"
 )
 
-// Message represents a message for Anthropic API
+// Message represents a message for Anthropic API. Its content is either
+// plain text or, for tool use and images, a list of content blocks.
 type Message struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+	Role    string
+	Content string
+	Blocks  []ContentBlock
 }
 
-// ToolUse represents a tool call for Anthropic API
-type ToolUse struct {
-	RecipientName string                 `json:"recipient_name"`
-	Name          string                 `json:"name"`
-	ID            string                 `json:"id"`
-	Input         map[string]interface{} `json:"input"`
-	Parameters    map[string]interface{} `json:"parameters"`
+// messageJSON is the wire format of a Message
+type messageJSON struct {
+	Role    string          `json:"role"`
+	Content json.RawMessage `json:"content"`
 }
 
-// ToolResult represents a tool result for Anthropic API
-type ToolResult struct {
-	Type     string `json:"type"`
-	Content  string `json:"content"`
-	ToolName string `json:"tool_name"`
+// MarshalJSON encodes the content as blocks if there are any, and as text
+// otherwise
+func (m Message) MarshalJSON() ([]byte, error) {
+	var content interface{} = m.Content
+	if len(m.Blocks) > 0 {
+		content = m.Blocks
+	}
+	raw, err := json.Marshal(content)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(messageJSON{Role: m.Role, Content: raw})
+}
+
+// UnmarshalJSON decodes text content into Content and block content into
+// Blocks
+func (m *Message) UnmarshalJSON(data []byte) error {
+	var msg messageJSON
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return err
+	}
+	*m = Message{Role: msg.Role}
+	content := bytes.TrimSpace(msg.Content)
+	if len(content) == 0 || content[0] != '[' {
+		if len(content) == 0 || string(content) == "null" {
+			return nil
+		}
+		return json.Unmarshal(content, &m.Content)
+	}
+	return json.Unmarshal(content, &m.Blocks)
 }
 
 // CompletionRequest represents a request for Anthropic API
@@ -133,21 +211,57 @@ type CompletionRequest struct {
 	Tools         []Tool      `json:"tools,omitempty"`
 	ToolChoice    interface{} `json:"tool_choice,omitempty"`
 	Stream        bool        `json:"stream,omitempty"`
-	MetadataKey   string      `json:"metadata,omitempty"`
+	Metadata      *Metadata   `json:"metadata,omitempty"`
+	Thinking      *Thinking   `json:"thinking,omitempty"`
+}
+
+// Metadata describes the request to Anthropic
+type Metadata struct {
+	// UserID is an opaque identifier of the end user, used for abuse detection
+	UserID string `json:"user_id,omitempty"`
+}
+
+// requestMetadata returns the request metadata for the user in the context,
+// or nil if there is no user
+func requestMetadata(ctx context.Context) *Metadata {
+	userID := runctx.UserID(ctx)
+	if userID == "" {
+		return nil
+	}
+	return &Metadata{UserID: userID}
 }
 
 // Tool represents a tool definition for Anthropic API
 type Tool struct {
-	Name        string                 `json:"name"`
-	Description string                 `json:"description"`
-	InputSchema map[string]interface{} `json:"input_schema"`
+	Name          string                   `json:"name"`
+	Description   string                   `json:"description"`
+	InputSchema   map[string]interface{}   `json:"input_schema"`
+	InputExamples []map[string]interface{} `json:"input_examples,omitempty"`
 }
 
 // ContentBlock represents a content block in Anthropic API response
 type ContentBlock struct {
-	Type    string   `json:"type"`
-	Text    string   `json:"text,omitempty"`
-	ToolUse *ToolUse `json:"tool_use,omitempty"`
+	Type string `json:"type"`
+	Text string `json:"text,omitempty"`
+
+	// ID, Name and Input are set on tool_use blocks
+	ID    string          `json:"id,omitempty"`
+	Name  string          `json:"name,omitempty"`
+	Input json.RawMessage `json:"input,omitempty"`
+
+	// ToolUseID, Content and IsError are set on tool_result blocks
+	ToolUseID string `json:"tool_use_id,omitempty"`
+	Content   string `json:"content,omitempty"`
+	IsError   bool   `json:"is_error,omitempty"`
+
+	// Thinking and Signature are set on thinking blocks, Data on
+	// redacted_thinking blocks
+	Thinking  string `json:"thinking,omitempty"`
+	Signature string `json:"signature,omitempty"`
+	Data      string `json:"data,omitempty"`
+
+	// Source is set on image blocks
+	Source *ImageSource `json:"source,omitempty"`
 }
 
 // CompletionResponse represents a response from Anthropic API
@@ -167,16 +281,27 @@ type Usage struct {
 	OutputTokens int `json:"output_tokens"`
 }
 
-// WithReasoning creates a GenerateOption to set the reasoning mode
-// Note: Reasoning parameter is not supported in the current Anthropic API version.
-// This option is kept for compatibility but will have no effect.
+// reportUsage passes the token usage of the response to the usage callback
+// in the context
+func (r CompletionResponse) reportUsage(ctx context.Context) {
+	interfaces.ReportUsage(ctx, interfaces.Usage{
+		Model:        r.Model,
+		InputTokens:  r.Usage.InputTokens,
+		OutputTokens: r.Usage.OutputTokens,
+	})
+}
+
+// WithReasoning creates a GenerateOption that enables extended thinking:
+// "minimal" and "comprehensive" set a thinking budget of 1024 and 16000
+// tokens, a number sets the budget in tokens and "none" disables thinking.
+// The reasoning is passed to the callback set with
+// interfaces.WithThinkingCallback, or streamed as thinking events.
 func WithReasoning(reasoning string) interfaces.GenerateOption {
 	return func(options *interfaces.GenerateOptions) {
 		if options.LLMConfig == nil {
 			options.LLMConfig = &interfaces.LLMConfig{}
 		}
 		options.LLMConfig.Reasoning = reasoning
-		// No actual functionality since reasoning is not supported
 	}
 }
 
@@ -199,49 +324,16 @@ func (c *AnthropicClient) Generate(ctx context.Context, prompt string, options .
 		option(params)
 	}
 
-	// Check for organization ID in context, and add a default one if missing
-	defaultOrgID := "default"
-	if id, err := multitenancy.GetOrgID(ctx); err == nil {
-		// Organization ID found in context, use it
-		ctx = multitenancy.WithOrgID(ctx, id) // Ensure consistency in context
-	} else {
-		// Add default organization ID to context to prevent errors in tool execution
-		ctx = multitenancy.WithOrgID(ctx, defaultOrgID)
+	// Constraints can't be enforced, so they fail the call
+	if params.Constraint != nil {
+		return "", errConstraintUnsupported
 	}
 
-	// Create request with messages
-	messages := []Message{
-		{
-			Role:    "user",
-			Content: prompt,
-		},
-	}
+	// Limit the call to its timeout
+	ctx, cancel := interfaces.RequestContext(ctx, params.Timeout, c.requestTimeout)
+	defer cancel()
 
-	// Create request
-	req := CompletionRequest{
-		Model:       c.Model,
-		Messages:    messages,
-		MaxTokens:   2048,
-		Temperature: params.LLMConfig.Temperature,
-		TopP:        params.LLMConfig.TopP,
-	}
-
-	// Add system message if available
-	if params.SystemMessage != "" {
-		req.System = params.SystemMessage
-		c.logger.Debug(ctx, "Using system message", map[string]interface{}{"system_message": params.SystemMessage})
-	}
-
-	// Add reasoning parameter if available
-	if params.LLMConfig != nil && params.LLMConfig.Reasoning != "" {
-		c.logger.Debug(ctx, "Reasoning mode not supported in current API version", map[string]interface{}{"reasoning": params.LLMConfig.Reasoning})
-	}
-
-	if params.LLMConfig != nil {
-		if len(params.LLMConfig.StopSequences) > 0 {
-			req.StopSequences = params.LLMConfig.StopSequences
-		}
-	}
+	ctx, req := c.messageRequest(ctx, prompt, params)
 
 	var resp CompletionResponse
 	var err error
@@ -307,7 +399,7 @@ func (c *AnthropicClient) Generate(ctx context.Context, prompt string, options .
 				"response":    string(respBody),
 				"model":       c.Model,
 			})
-			return fmt.Errorf("error from Anthropic API: %s", string(respBody))
+			return newAPIError(httpResp, respBody)
 		}
 
 		// Unmarshal response
@@ -323,18 +415,26 @@ func (c *AnthropicClient) Generate(ctx context.Context, prompt string, options .
 		c.logger.Debug(ctx, "Using retry mechanism for Anthropic request", map[string]interface{}{
 			"model": c.Model,
 		})
-		err = c.retryExecutor.Execute(ctx, operation)
-	} else {
-		err = operation()
 	}
+	err = c.execute(ctx, operation)
 
 	if err != nil {
 		return "", err
 	}
+	resp.reportUsage(ctx)
+	resp.reportThinking(ctx)
 
-	// Extract text from content blocks
+	c.logger.Debug(ctx, "Successfully received response from Anthropic", map[string]interface{}{
+		"model": c.Model,
+	})
+
+	return resp.text()
+}
+
+// text returns the text content blocks of the response
+func (r CompletionResponse) text() (string, error) {
 	var contentText []string
-	for _, block := range resp.Content {
+	for _, block := range r.Content {
 		if block.Type == "text" {
 			contentText = append(contentText, block.Text)
 		}
@@ -343,12 +443,68 @@ func (c *AnthropicClient) Generate(ctx context.Context, prompt string, options .
 	if len(contentText) == 0 {
 		return "", fmt.Errorf("no text content in response")
 	}
+	return strings.Join(contentText, "\n"), nil
+}
 
-	c.logger.Debug(ctx, "Successfully received response from Anthropic", map[string]interface{}{
-		"model": c.Model,
-	})
+// messageRequest builds the request of a Generate call. The returned context
+// carries an organization ID.
+func (c *AnthropicClient) messageRequest(ctx context.Context, prompt string, params *interfaces.GenerateOptions) (context.Context, CompletionRequest) {
+	// Check for organization ID in context, and add a default one if missing
+	defaultOrgID := "default"
+	if id, err := multitenancy.GetOrgID(ctx); err == nil {
+		// Organization ID found in context, use it
+		ctx = multitenancy.WithOrgID(ctx, id) // Ensure consistency in context
+	} else {
+		// Add default organization ID to context to prevent errors in tool execution
+		ctx = multitenancy.WithOrgID(ctx, defaultOrgID)
+	}
 
-	return strings.Join(contentText, "\n"), nil
+	// Create request with messages
+	messages := []Message{
+		{
+			Role:    "user",
+			Content: prompt,
+		},
+	}
+
+	// Create request
+	req := CompletionRequest{
+		Model:       c.Model,
+		Messages:    messages,
+		MaxTokens:   maxTokens(params.LLMConfig),
+		Temperature: params.LLMConfig.Temperature,
+		TopP:        params.LLMConfig.TopP,
+		Metadata:    requestMetadata(ctx),
+	}
+
+	// Add system message if available
+	if params.SystemMessage != "" {
+		req.System = params.SystemMessage
+		c.logger.Debug(ctx, "Using system message", map[string]interface{}{"system_message": params.SystemMessage})
+	}
+
+	// Enable extended thinking for the reasoning mode
+	if params.LLMConfig != nil {
+		c.applyThinking(ctx, &req, params.LLMConfig.Reasoning)
+	}
+
+	if params.LLMConfig != nil {
+		if len(params.LLMConfig.StopSequences) > 0 {
+			req.StopSequences = params.LLMConfig.StopSequences
+		}
+	}
+
+	// Continue the response from the prefix. The API rejects trailing
+	// whitespace in it, and doesn't allow it with extended thinking.
+	if prefix := strings.TrimRight(params.AssistantPrefix, " \t\r\n"); prefix != "" {
+		req.Messages = append(req.Messages, Message{Role: "assistant", Content: prefix})
+		if req.Thinking != nil {
+			c.logger.Debug(ctx, "Extended thinking disabled to continue a response", nil)
+			req.Thinking = nil
+		}
+	}
+
+	return ctx, req
 }
 
 // Chat uses the messages API to have a conversation with a model
@@ -362,6 +518,9 @@ func (c *AnthropicClient) Chat(ctx context.Context, messages []llm.Message, para
 		params = llm.DefaultGenerateParams()
 	}
 
+	ctx, cancel := interfaces.RequestContext(ctx, 0, c.requestTimeout)
+	defer cancel()
+
 	// Convert messages to the Anthropic Chat format
 	anthropicMessages := make([]Message, len(messages))
 	var systemMessage string
@@ -403,10 +562,15 @@ func (c *AnthropicClient) Chat(ctx context.Context, messages []llm.Message, para
 	req := CompletionRequest{
 		Model:         c.Model,
 		Messages:      filteredMessages,
-		MaxTokens:     2048,
+		MaxTokens:     defaultMaxTokens,
 		Temperature:   params.Temperature,
 		TopP:          params.TopP,
 		StopSequences: params.StopSequences,
+		Metadata:      requestMetadata(ctx),
+	}
+
+	if params.MaxTokens > 0 {
+		req.MaxTokens = params.MaxTokens
 	}
 
 	// Add system message if available
@@ -414,10 +578,8 @@ func (c *AnthropicClient) Chat(ctx context.Context, messages []llm.Message, para
 		req.System = systemMessage
 	}
 
-	// Add reasoning parameter if available
-	if params.Reasoning != "" {
-		c.logger.Debug(ctx, "Reasoning mode not supported in current API version", map[string]interface{}{"reasoning": params.Reasoning})
-	}
+	// Enable extended thinking for the reasoning mode
+	c.applyThinking(ctx, &req, params.Reasoning)
 
 	var resp CompletionResponse
 	var err error
@@ -483,7 +645,7 @@ func (c *AnthropicClient) Chat(ctx context.Context, messages []llm.Message, para
 				"response":    string(respBody),
 				"model":       c.Model,
 			})
-			return fmt.Errorf("error from Anthropic API: %s", string(respBody))
+			return newAPIError(httpResp, respBody)
 		}
 
 		// Unmarshal response
@@ -499,14 +661,14 @@ func (c *AnthropicClient) Chat(ctx context.Context, messages []llm.Message, para
 		c.logger.Debug(ctx, "Using retry mechanism for Anthropic Chat request", map[string]interface{}{
 			"model": c.Model,
 		})
-		err = c.retryExecutor.Execute(ctx, operation)
-	} else {
-		err = operation()
 	}
+	err = c.execute(ctx, operation)
 
 	if err != nil {
 		return "", err
 	}
+	resp.reportUsage(ctx)
+	resp.reportThinking(ctx)
 
 	// Extract text from content blocks
 	var contentText []string
@@ -548,6 +710,15 @@ func (c *AnthropicClient) GenerateWithTools(ctx context.Context, prompt string,
 		}
 	}
 
+	// Constraints can't be enforced, so they fail the call
+	if params.Constraint != nil {
+		return "", errConstraintUnsupported
+	}
+
+	// Limit the call to its timeout
+	ctx, cancel := interfaces.RequestContext(ctx, params.Timeout, c.requestTimeout)
+	defer cancel()
+
 	// Check for organization ID in context, and add a default one if missing
 	defaultOrgID := "default"
 	if id, err := multitenancy.GetOrgID(ctx); err == nil {
@@ -601,6 +772,11 @@ func (c *AnthropicClient) GenerateWithTools(ctx context.Context, prompt string,
 			Description: tool.Description(),
 			InputSchema: inputSchema,
 		}
+		if c.inputExamples {
+			for _, example := range interfaces.ToolExamples(tool) {
+				anthropicTools[i].InputExamples = append(anthropicTools[i].InputExamples, example.Arguments)
+			}
+		}
 	}
 
 	// Create messages array with user message
@@ -615,10 +791,11 @@ func (c *AnthropicClient) GenerateWithTools(ctx context.Context, prompt string,
 	req := CompletionRequest{
 		Model:       c.Model,
 		Messages:    messages,
-		MaxTokens:   2048,
+		MaxTokens:   maxTokens(params.LLMConfig),
 		Temperature: params.LLMConfig.Temperature,
 		TopP:        params.LLMConfig.TopP,
 		Tools:       anthropicTools,
+		Metadata:    requestMetadata(ctx),
 		// Auto use tools when needed
 		ToolChoice: map[string]string{
 			"type": "auto",
@@ -631,9 +808,9 @@ func (c *AnthropicClient) GenerateWithTools(ctx context.Context, prompt string,
 		c.logger.Debug(ctx, "Using system message", map[string]interface{}{"system_message": params.SystemMessage})
 	}
 
-	// Add reasoning parameter if available
-	if params.LLMConfig != nil && params.LLMConfig.Reasoning != "" {
-		c.logger.Debug(ctx, "Reasoning mode not supported in current API version", map[string]interface{}{"reasoning": params.LLMConfig.Reasoning})
+	// Enable extended thinking for the reasoning mode
+	if params.LLMConfig != nil {
+		c.applyThinking(ctx, &req, params.LLMConfig.Reasoning)
 	}
 
 	// Send request
@@ -646,251 +823,153 @@ func (c *AnthropicClient) GenerateWithTools(ctx context.Context, prompt string,
 		"system":      req.System != "",
 	})
 
-	// Convert request to JSON
-	reqBody, err := json.Marshal(req)
-	if err != nil {
-		return "", fmt.Errorf("failed to marshal request: %w", err)
-	}
-
-	// Create HTTP request
-	httpReq, err := http.NewRequestWithContext(
-		ctx,
-		"POST",
-		c.BaseURL+"/v1/messages",
-		bytes.NewBuffer(reqBody),
-	)
-	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
-	}
-
-	// Set headers
-	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("X-API-Key", c.APIKey)
-	httpReq.Header.Set("Anthropic-Version", "2023-06-01")
+	// Re-submit the tool results until the model answers without calling
+	// tools
+	for iteration := 0; ; iteration++ {
+		// Convert request to JSON
+		reqBody, err := json.Marshal(req)
+		if err != nil {
+			return "", fmt.Errorf("failed to marshal request: %w", err)
+		}
 
-	// Send request
-	httpResp, err := c.HTTPClient.Do(httpReq)
-	if err != nil {
-		c.logger.Error(ctx, "Error from Anthropic API", map[string]interface{}{
-			"error": err.Error(),
-			"model": c.Model,
-		})
-		return "", fmt.Errorf("failed to send request: %w", err)
-	}
-	defer func() {
-		if closeErr := httpResp.Body.Close(); closeErr != nil {
-			c.logger.Warn(ctx, "Failed to close response body", map[string]interface{}{
-				"error": closeErr.Error(),
-			})
+		// Send request
+		respBody, err := c.sendMessages(ctx, reqBody)
+		if err != nil {
+			return "", err
 		}
-	}()
 
-	// Read response body
-	respBody, err := io.ReadAll(httpResp.Body)
-	if err != nil {
-		return "", fmt.Errorf("failed to read response body: %w", err)
-	}
+		// Unmarshal response
+		var resp CompletionResponse
+		err = json.Unmarshal(respBody, &resp)
+		if err != nil {
+			return "", fmt.Errorf("failed to unmarshal response: %w", err)
+		}
+		resp.reportUsage(ctx)
+		resp.reportThinking(ctx)
 
-	// Check for error response
-	if httpResp.StatusCode != http.StatusOK {
-		c.logger.Error(ctx, "Error from Anthropic API", map[string]interface{}{
-			"status_code": httpResp.StatusCode,
-			"response":    string(respBody),
-			"model":       c.Model,
+		// Log the raw response for debugging
+		c.logger.Debug(ctx, "Raw response from Anthropic", map[string]interface{}{
+			"response": string(respBody),
 		})
-		return "", fmt.Errorf("error from Anthropic API: %s", string(respBody))
-	}
 
-	// Unmarshal response
-	var resp CompletionResponse
-	err = json.Unmarshal(respBody, &resp)
-	if err != nil {
-		return "", fmt.Errorf("failed to unmarshal response: %w", err)
-	}
-
-	// Log the raw response for debugging
-	c.logger.Debug(ctx, "Raw response from Anthropic", map[string]interface{}{
-		"response": string(respBody),
-	})
+		// Make sure content is not nil
+		if resp.Content == nil {
+			c.logger.Error(ctx, "No content in response", nil)
+			return "", fmt.Errorf("no content in response")
+		}
 
-	// Make sure content is not nil
-	if resp.Content == nil {
-		c.logger.Error(ctx, "No content in response", nil)
-		return "", fmt.Errorf("no content in response")
-	}
-
-	// Check if the model wants to use tools
-	var hasToolUse bool
-	var toolCalls []ToolUse
-
-	c.logger.Debug(ctx, "Response content blocks", map[string]interface{}{
-		"numBlocks": len(resp.Content),
-		"blockTypes": func() []string {
-			types := make([]string, len(resp.Content))
-			for i, block := range resp.Content {
-				types[i] = block.Type
-				if block.Type == "tool_use" && block.ToolUse != nil {
-					toolName := ""
-					if block.ToolUse.Name != "" {
-						toolName = block.ToolUse.Name
-					} else if block.ToolUse.RecipientName != "" {
-						toolName = block.ToolUse.RecipientName
-					}
-					c.logger.Debug(ctx, "Found tool use block", map[string]interface{}{
-						"toolName": toolName,
-						"toolID":   block.ToolUse.ID,
-					})
-				}
+		// Check if the model wants to use tools
+		var toolCalls []ContentBlock
+		for _, block := range resp.Content {
+			if block.Type == "tool_use" {
+				toolCalls = append(toolCalls, block)
 			}
-			return types
-		}(),
-	})
-
-	for _, contentBlock := range resp.Content {
-		if contentBlock.Type == "tool_use" && contentBlock.ToolUse != nil {
-			hasToolUse = true
-			toolCalls = append(toolCalls, *contentBlock.ToolUse)
 		}
-	}
 
-	c.logger.Debug(ctx, "Tool use detection results", map[string]interface{}{
-		"hasToolUse": hasToolUse,
-		"toolCalls":  len(toolCalls),
-	})
-
-	if hasToolUse {
-		// The model wants to use tools
-		c.logger.Info(ctx, "Processing tool calls", map[string]interface{}{"count": len(toolCalls)})
-
-		// Create a new conversation with the initial messages and the assistant response
-		messages := append(req.Messages, Message{
-			Role: "assistant",
-			// We don't need the content here as we'll be adding tool results
+		c.logger.Debug(ctx, "Tool use detection results", map[string]interface{}{
+			"numBlocks": len(resp.Content),
+			"toolCalls": len(toolCalls),
+			"iteration": iteration,
 		})
 
-		// Process each tool call
-		var toolResults []ToolResult
-		for _, toolCall := range toolCalls {
-			// Get tool name - it could be in either Name or RecipientName field
-			toolName := ""
-			if toolCall.Name != "" {
-				toolName = toolCall.Name
-			} else if toolCall.RecipientName != "" {
-				toolName = toolCall.RecipientName
-			} else {
-				c.logger.Error(ctx, "Tool call missing both Name and RecipientName", nil)
-				continue
-			}
-
-			// Find the requested tool
-			var selectedTool interfaces.Tool
-			for _, tool := range tools {
-				if tool.Name() == toolName {
-					selectedTool = tool
-					break
+		if len(toolCalls) == 0 {
+			// No tool was used, return the response
+			var contentText []string
+			for _, block := range resp.Content {
+				if block.Type == "text" {
+					contentText = append(contentText, block.Text)
 				}
 			}
-
-			if selectedTool == nil {
-				c.logger.Error(ctx, "Tool not found", map[string]interface{}{
-					"toolName": toolName,
-					"availableTools": func() []string {
-						names := make([]string, len(tools))
-						for i, t := range tools {
-							names[i] = t.Name()
-						}
-						return names
-					}(),
-				})
-				return "", fmt.Errorf("tool not found: %s", toolName)
-			}
-
-			// Get parameters - could be in either Input or Parameters field
-			var parameters map[string]interface{}
-			if len(toolCall.Input) > 0 {
-				parameters = toolCall.Input
-			} else if len(toolCall.Parameters) > 0 {
-				parameters = toolCall.Parameters
-			}
-
-			// Convert parameters to JSON string
-			toolCallJSON, err := json.Marshal(parameters)
-			if err != nil {
-				c.logger.Error(ctx, "Error marshalling parameters", map[string]interface{}{
-					"error":      err.Error(),
-					"parameters": parameters,
-				})
-				return "", fmt.Errorf("error marshalling parameters: %w", err)
+			if len(contentText) == 0 {
+				return "", fmt.Errorf("no text content in response")
 			}
+			return strings.Join(contentText, "\n"), nil
+		}
+		if iteration >= c.maxToolIterations {
+			return "", fmt.Errorf("model kept calling tools after %d iterations", c.maxToolIterations)
+		}
 
-			// Log parameters for debugging
-			c.logger.Debug(ctx, "Tool parameters", map[string]interface{}{
-				"toolName":   toolName,
-				"parameters": string(toolCallJSON),
-			})
-
-			// Execute the tool
-			c.logger.Info(ctx, "Executing tool", map[string]interface{}{"toolName": selectedTool.Name()})
-			toolResult, err := selectedTool.Execute(ctx, string(toolCallJSON))
-			if err != nil {
-				c.logger.Error(ctx, "Error executing tool", map[string]interface{}{"toolName": selectedTool.Name(), "error": err.Error()})
-				// Return error as tool result
-				toolResults = append(toolResults, ToolResult{
-					Type:     "tool_result",
-					Content:  fmt.Sprintf("Error: %v", err),
-					ToolName: toolName,
-				})
-				continue
-			}
+		// The model wants to use tools
+		c.logger.Info(ctx, "Processing tool calls", map[string]interface{}{"count": len(toolCalls), "iteration": iteration + 1})
 
-			// Add tool result
-			toolResults = append(toolResults, ToolResult{
-				Type:     "tool_result",
-				Content:  toolResult,
-				ToolName: toolName,
-			})
+		// Continue the conversation with the assistant's content blocks as
+		// they were, since thinking blocks must be passed back unchanged,
+		// and a user message with a tool_result block for every tool_use
+		// block
+		toolResults := make([]ContentBlock, 0, len(toolCalls))
+		for _, toolCall := range toolCalls {
+			toolResults = append(toolResults, c.executeToolUse(ctx, toolCall, tools))
 		}
+		req.Messages = append(req.Messages,
+			Message{Role: "assistant", Blocks: resp.Content},
+			Message{Role: "user", Blocks: toolResults},
+		)
 
-		// Create a new message from the user with the tool results
-		toolResultsJSON, err := json.Marshal(toolResults)
-		if err != nil {
-			return "", fmt.Errorf("failed to marshal tool results: %w", err)
+		// Once the iteration limit is reached, ask for an answer without
+		// further tool calls
+		if iteration+1 >= c.maxToolIterations {
+			req.ToolChoice = map[string]string{
+				"type": "none",
+			}
 		}
 
-		// Add a user message with the tool results
-		messages = append(messages, Message{
-			Role:    "user",
-			Content: fmt.Sprintf("Here are the tool results: %s", string(toolResultsJSON)),
+		c.logger.Info(ctx, "Sending request with tool results", map[string]interface{}{
+			"messages":  len(req.Messages),
+			"iteration": iteration + 1,
 		})
+	}
+}
 
-		// Get the final response
-		c.logger.Info(ctx, "Sending final request with tool results", nil)
-
-		finalReq := CompletionRequest{
-			Model:       c.Model,
-			Messages:    messages,
-			MaxTokens:   2048,
-			Temperature: params.LLMConfig.Temperature,
-			TopP:        params.LLMConfig.TopP,
+// executeToolUse executes the tool a tool_use block calls and returns the
+// tool_result block that answers it. Failures are reported to the model as
+// error results, so that it can react to them.
+func (c *AnthropicClient) executeToolUse(ctx context.Context, toolCall ContentBlock, tools []interfaces.Tool) ContentBlock {
+	result := ContentBlock{Type: "tool_result", ToolUseID: toolCall.ID}
+
+	var selectedTool interfaces.Tool
+	for _, tool := range tools {
+		if tool.Name() == toolCall.Name {
+			selectedTool = tool
+			break
 		}
+	}
+	if selectedTool == nil {
+		c.logger.Error(ctx, "Tool not found", map[string]interface{}{"toolName": toolCall.Name})
+		result.Content = fmt.Sprintf("Error: tool not found: %s", toolCall.Name)
+		result.IsError = true
+		return result
+	}
 
-		// Add system message if available
-		if params.SystemMessage != "" {
-			finalReq.System = params.SystemMessage
-		}
+	args := "{}"
+	if len(toolCall.Input) > 0 && string(toolCall.Input) != "null" {
+		args = string(toolCall.Input)
+	}
 
-		// Add reasoning parameter if available
-		if params.LLMConfig != nil && params.LLMConfig.Reasoning != "" {
-			c.logger.Debug(ctx, "Reasoning mode not supported in current API version", map[string]interface{}{"reasoning": params.LLMConfig.Reasoning})
-		}
+	c.logger.Info(ctx, "Executing tool", map[string]interface{}{
+		"toolName":  toolCall.Name,
+		"toolUseID": toolCall.ID,
+	})
+	c.logger.Debug(ctx, "Tool parameters", map[string]interface{}{
+		"toolName":   toolCall.Name,
+		"parameters": args,
+	})
 
-		// Convert request to JSON
-		reqBody, err := json.Marshal(finalReq)
-		if err != nil {
-			return "", fmt.Errorf("failed to marshal final request: %w", err)
-		}
+	output, err := selectedTool.Execute(ctx, args)
+	if err != nil {
+		c.logger.Error(ctx, "Error executing tool", map[string]interface{}{"toolName": toolCall.Name, "error": err.Error()})
+		result.Content = fmt.Sprintf("Error: %v", err)
+		result.IsError = true
+		return result
+	}
+	result.Content = output
+	return result
+}
 
-		// Create HTTP request
+// sendMessages posts the request body to the messages endpoint, retrying
+// transient errors with the retry executor, and returns the response body
+func (c *AnthropicClient) sendMessages(ctx context.Context, reqBody []byte) ([]byte, error) {
+	var respBody []byte
+	operation := func() error {
 		httpReq, err := http.NewRequestWithContext(
 			ctx,
 			"POST",
@@ -898,22 +977,24 @@ func (c *AnthropicClient) GenerateWithTools(ctx context.Context, prompt string,
 			bytes.NewBuffer(reqBody),
 		)
 		if err != nil {
-			return "", fmt.Errorf("failed to create final request: %w", err)
+			return retry.Permanent(fmt.Errorf("failed to create request: %w", err))
 		}
 
 		// Set headers
 		httpReq.Header.Set("Content-Type", "application/json")
 		httpReq.Header.Set("X-API-Key", c.APIKey)
 		httpReq.Header.Set("Anthropic-Version", "2023-06-01")
+		if c.inputExamples {
+			httpReq.Header.Set("Anthropic-Beta", "advanced-tool-use-2025-11-20")
+		}
 
-		// Send request
 		httpResp, err := c.HTTPClient.Do(httpReq)
 		if err != nil {
-			c.logger.Error(ctx, "Error from final Anthropic API call", map[string]interface{}{
+			c.logger.Error(ctx, "Error from Anthropic API", map[string]interface{}{
 				"error": err.Error(),
 				"model": c.Model,
 			})
-			return "", fmt.Errorf("failed to send final request: %w", err)
+			return fmt.Errorf("failed to send request: %w", err)
 		}
 		defer func() {
 			if closeErr := httpResp.Body.Close(); closeErr != nil {
@@ -923,62 +1004,55 @@ func (c *AnthropicClient) GenerateWithTools(ctx context.Context, prompt string,
 			}
 		}()
 
-		// Read response body
-		respBody, err := io.ReadAll(httpResp.Body)
+		body, err := io.ReadAll(httpResp.Body)
 		if err != nil {
-			return "", fmt.Errorf("failed to read final response body: %w", err)
+			return fmt.Errorf("failed to read response body: %w", err)
 		}
 
-		// Check for error response
 		if httpResp.StatusCode != http.StatusOK {
-			c.logger.Error(ctx, "Error from final Anthropic API call", map[string]interface{}{
+			c.logger.Error(ctx, "Error from Anthropic API", map[string]interface{}{
 				"status_code": httpResp.StatusCode,
-				"response":    string(respBody),
+				"response":    string(body),
 				"model":       c.Model,
 			})
-			return "", fmt.Errorf("error from final Anthropic API call: %s", string(respBody))
+			return newAPIError(httpResp, body)
 		}
 
-		// Unmarshal response
-		var finalResp CompletionResponse
-		err = json.Unmarshal(respBody, &finalResp)
-		if err != nil {
-			return "", fmt.Errorf("failed to unmarshal final response: %w", err)
-		}
-
-		// Extract text from content blocks
-		var contentText []string
-		for _, block := range finalResp.Content {
-			if block.Type == "text" {
-				contentText = append(contentText, block.Text)
-			}
-		}
-
-		if len(contentText) == 0 {
-			return "", fmt.Errorf("no text content in final response")
-		}
-
-		return strings.Join(contentText, "\n"), nil
+		respBody = body
+		return nil
 	}
 
-	// No tool was used, return the direct response
-	var contentText []string
-	for _, block := range resp.Content {
-		if block.Type == "text" {
-			contentText = append(contentText, block.Text)
-		}
+	if err := c.execute(ctx, operation); err != nil {
+		return nil, err
 	}
+	return respBody, nil
+}
 
-	c.logger.Debug(ctx, "Direct response without tool use", map[string]interface{}{
-		"contentBlocks": len(resp.Content),
-		"textBlocks":    len(contentText),
-	})
+// execute runs the operation with the retry executor if one is configured
+func (c *AnthropicClient) execute(ctx context.Context, operation func() error) error {
+	if c.retryExecutor != nil {
+		return c.retryExecutor.Execute(ctx, operation)
+	}
 
-	if len(contentText) == 0 {
-		return "", fmt.Errorf("no text content in response")
+	err := operation()
+	var permanent *retry.PermanentError
+	if errors.As(err, &permanent) {
+		return permanent.Err
 	}
+	return err
+}
 
-	return strings.Join(contentText, "\n"), nil
+// Capabilities reports the features supported by the configured model. The
+// messages API has no native JSON mode, so response formats are prompt-based,
+// and continues responses from a prefilled assistant message.
+func (c *AnthropicClient) Capabilities() interfaces.Capabilities {
+	return interfaces.Capabilities{
+		Tools:            true,
+		Vision:           !strings.HasPrefix(c.Model, "claude-2") && !strings.HasPrefix(c.Model, "claude-instant"),
+		Streaming:        true,
+		ParallelTools:    true,
+		AssistantPrefill: true,
+	}
 }
 
 // Name implements interfaces.LLM.Name
@@ -986,6 +1060,29 @@ func (c *AnthropicClient) Name() string {
 	return "anthropic"
 }
 
+// Ping checks that the Anthropic API is reachable and the API key is valid
+func (c *AnthropicClient) Ping(ctx context.Context) error {
+	httpReq, err := http.NewRequestWithContext(ctx, "GET", c.BaseURL+"/v1/models?limit=1", nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("X-API-Key", c.APIKey)
+	httpReq.Header.Set("Anthropic-Version", "2023-06-01")
+
+	resp, err := c.HTTPClient.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("failed to reach Anthropic API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("anthropic API returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	return nil
+}
+
 // WithTemperature creates a GenerateOption to set the temperature
 func WithTemperature(temperature float64) interfaces.GenerateOption {
 	return func(options *interfaces.GenerateOptions) {
@@ -0,0 +1,379 @@
+package anthropic
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+	"github.com/run-bigpig/llm-agent/pkg/retry"
+)
+
+func TestGenerateWithToolsRetriesTransientErrors(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.Header().Set("Anthropic-Ratelimit-Requests-Remaining", "0")
+			w.WriteHeader(529)
+			_, _ = w.Write([]byte(`{"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"content":[{"type":"text","text":"hello"}]}`))
+	}))
+	defer server.Close()
+
+	client := NewClient("key",
+		WithBaseURL(server.URL),
+		WithRetry(retry.WithInitialInterval(time.Millisecond), retry.WithMaxAttempts(3)),
+	)
+
+	response, err := client.GenerateWithTools(context.Background(), "hi", nil)
+	if err != nil {
+		t.Fatalf("GenerateWithTools failed: %v", err)
+	}
+	if response != "hello" || calls.Load() != 2 {
+		t.Errorf("Expected a retried response, got %q after %d calls", response, calls.Load())
+	}
+}
+
+func TestGenerateWithToolsDoesNotRetryInvalidRequests(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"type":"error","error":{"type":"invalid_request_error","message":"bad"}}`))
+	}))
+	defer server.Close()
+
+	client := NewClient("key",
+		WithBaseURL(server.URL),
+		WithRetry(retry.WithInitialInterval(time.Millisecond), retry.WithMaxAttempts(3)),
+	)
+
+	_, err := client.GenerateWithTools(context.Background(), "hi", nil)
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Type != "invalid_request_error" {
+		t.Fatalf("Expected an APIError, got %v", err)
+	}
+	if calls.Load() != 1 {
+		t.Errorf("Expected no retries, got %d calls", calls.Load())
+	}
+}
+
+// exampleTool is a tool with an example invocation
+type exampleTool struct{}
+
+func (exampleTool) Name() string        { return "search_invoices" }
+func (exampleTool) Description() string { return "Searches invoices" }
+func (exampleTool) Parameters() map[string]interfaces.ParameterSpec {
+	return map[string]interfaces.ParameterSpec{"customer": {Type: "string", Required: true}}
+}
+func (exampleTool) Run(ctx context.Context, input string) (string, error)    { return "", nil }
+func (exampleTool) Execute(ctx context.Context, args string) (string, error) { return "", nil }
+func (exampleTool) Examples() []interfaces.ToolExample {
+	return []interfaces.ToolExample{{Arguments: map[string]interface{}{"customer": "acme"}}}
+}
+
+func TestGenerateWithToolsSendsInputExamples(t *testing.T) {
+	var req CompletionRequest
+	var beta string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		beta = r.Header.Get("Anthropic-Beta")
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		_, _ = w.Write([]byte(`{"content":[{"type":"text","text":"hello"}]}`))
+	}))
+	defer server.Close()
+
+	client := NewClient("key", WithBaseURL(server.URL), WithToolInputExamples())
+	if _, err := client.GenerateWithTools(context.Background(), "hi", []interfaces.Tool{exampleTool{}}); err != nil {
+		t.Fatalf("GenerateWithTools failed: %v", err)
+	}
+	if beta == "" {
+		t.Error("Expected a beta header")
+	}
+	if len(req.Tools) != 1 || len(req.Tools[0].InputExamples) != 1 || req.Tools[0].InputExamples[0]["customer"] != "acme" {
+		t.Errorf("Expected the tool's example in the request, got %+v", req.Tools)
+	}
+}
+
+func TestGenerateRequestTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+			return
+		}
+		_, _ = w.Write([]byte(`{"content":[{"type":"text","text":"hello"}]}`))
+	}))
+	defer server.Close()
+	defer close(release)
+
+	client := NewClient("key",
+		WithBaseURL(server.URL),
+		WithDefaultRequestTimeout(time.Hour),
+		WithRetry(retry.WithMaxAttempts(1)),
+	)
+
+	startedAt := time.Now()
+	_, err := client.Generate(context.Background(), "hi", interfaces.WithRequestTimeout(20*time.Millisecond))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected the call to time out, got %v", err)
+	}
+	if elapsed := time.Since(startedAt); elapsed > 5*time.Second {
+		t.Errorf("Expected the call timeout to override the default, took %s", elapsed)
+	}
+
+	// The default applies to calls without a timeout
+	client = NewClient("key",
+		WithBaseURL(server.URL),
+		WithDefaultRequestTimeout(20*time.Millisecond),
+		WithRetry(retry.WithMaxAttempts(1)),
+	)
+	if _, err := client.GenerateWithTools(context.Background(), "hi", nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected the default timeout to apply, got %v", err)
+	}
+}
+
+func TestGenerateWithThinking(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("Failed to decode request: %v", err)
+		}
+		thinking, _ := req["thinking"].(map[string]interface{})
+		if thinking["type"] != "enabled" || thinking["budget_tokens"] != float64(4000) {
+			t.Errorf("Expected extended thinking with a budget of 4000 tokens, got %v", req["thinking"])
+		}
+		if _, ok := req["temperature"]; ok {
+			t.Errorf("Expected no temperature with extended thinking, got %v", req["temperature"])
+		}
+		_, _ = w.Write([]byte(`{"content":[{"type":"thinking","thinking":"2 + 2 is 4.","signature":"sig"},{"type":"text","text":"4"}]}`))
+	}))
+	defer server.Close()
+
+	var thinking string
+	ctx := interfaces.WithThinkingCallback(context.Background(), func(t string) { thinking = t })
+
+	response, err := NewClient("key", WithBaseURL(server.URL)).Generate(ctx, "What is 2 + 2?", WithReasoning("4000"))
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if response != "4" || thinking != "2 + 2 is 4." {
+		t.Errorf("Expected the response and the thinking apart, got %q and %q", response, thinking)
+	}
+}
+
+func TestThinkingBudget(t *testing.T) {
+	for reasoning, want := range map[string]int{"minimal": 1024, "comprehensive": 16000, "8000": 8000, "100": 1024, "deep": 0} {
+		if budget, _ := thinkingBudget(reasoning); budget != want {
+			t.Errorf("thinkingBudget(%q) = %d, want %d", reasoning, budget, want)
+		}
+	}
+}
+
+func TestGenerateWithAssistantPrefix(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req CompletionRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("Failed to decode request: %v", err)
+		}
+		if len(req.Messages) != 2 || req.Messages[1].Role != "assistant" || req.Messages[1].Content != "Paris is the" {
+			t.Errorf("Expected the prefix as a trimmed assistant message, got %+v", req.Messages)
+		}
+		if req.Thinking != nil {
+			t.Errorf("Expected no extended thinking with a prefix, got %+v", req.Thinking)
+		}
+		_, _ = w.Write([]byte(`{"content":[{"type":"text","text":" capital of France."}]}`))
+	}))
+	defer server.Close()
+
+	response, err := NewClient("key", WithBaseURL(server.URL)).Generate(context.Background(), "Tell me about Paris",
+		interfaces.WithAssistantPrefix("Paris is the "), WithReasoning("minimal"))
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if response != " capital of France." {
+		t.Errorf("Expected the continuation, got %q", response)
+	}
+}
+
+// invoiceTool returns the invoices of a customer
+type invoiceTool struct {
+	exampleTool
+	args string
+}
+
+func (t *invoiceTool) Execute(ctx context.Context, args string) (string, error) {
+	t.args = args
+	return "2 open invoices", nil
+}
+
+func TestGenerateWithToolsSendsToolResults(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req CompletionRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("Failed to decode request: %v", err)
+		}
+		if calls.Add(1) == 1 {
+			_, _ = w.Write([]byte(`{"content":[
+				{"type":"thinking","thinking":"Look it up","signature":"sig"},
+				{"type":"tool_use","id":"toolu_1","name":"search_invoices","input":{"customer":"acme"}},
+				{"type":"tool_use","id":"toolu_2","name":"missing","input":{}}
+			],"stop_reason":"tool_use"}`))
+			return
+		}
+
+		if len(req.Messages) != 3 || len(req.Tools) != 1 {
+			t.Fatalf("Expected the tool round in the conversation, got %+v", req)
+		}
+		assistant, user := req.Messages[1], req.Messages[2]
+		if assistant.Role != "assistant" || len(assistant.Blocks) != 3 || assistant.Blocks[0].Signature != "sig" || assistant.Blocks[1].ID != "toolu_1" {
+			t.Errorf("Expected the assistant's blocks echoed, got %+v", assistant)
+		}
+		if user.Role != "user" || len(user.Blocks) != 2 {
+			t.Fatalf("Expected a tool result per tool call, got %+v", user)
+		}
+		if result := user.Blocks[0]; result.Type != "tool_result" || result.ToolUseID != "toolu_1" || result.Content != "2 open invoices" || result.IsError {
+			t.Errorf("Unexpected tool result %+v", result)
+		}
+		if result := user.Blocks[1]; result.ToolUseID != "toolu_2" || !result.IsError {
+			t.Errorf("Expected an error result for the unknown tool, got %+v", result)
+		}
+		_, _ = w.Write([]byte(`{"content":[{"type":"text","text":"Acme has 2 open invoices."}]}`))
+	}))
+	defer server.Close()
+
+	tool := &invoiceTool{}
+	response, err := NewClient("key", WithBaseURL(server.URL)).GenerateWithTools(context.Background(), "What does acme owe?", []interfaces.Tool{tool})
+	if err != nil {
+		t.Fatalf("GenerateWithTools failed: %v", err)
+	}
+	if response != "Acme has 2 open invoices." || tool.args != `{"customer":"acme"}` {
+		t.Errorf("Unexpected response %q for tool arguments %q", response, tool.args)
+	}
+}
+
+func TestMessageJSON(t *testing.T) {
+	data, err := json.Marshal([]Message{
+		{Role: "user", Content: "hi"},
+		{Role: "user", Blocks: []ContentBlock{{Type: "tool_result", ToolUseID: "toolu_1", Content: "ok"}}},
+	})
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	expected := `[{"role":"user","content":"hi"},{"role":"user","content":[{"type":"tool_result","tool_use_id":"toolu_1","content":"ok"}]}]`
+	if string(data) != expected {
+		t.Errorf("Expected %s, got %s", expected, data)
+	}
+}
+
+func TestGenerateWithToolsLoopsUntilAnswer(t *testing.T) {
+	var calls atomic.Int32
+	var choices []interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req CompletionRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("Failed to decode request: %v", err)
+		}
+		choices = append(choices, req.ToolChoice)
+		call := calls.Add(1)
+		if len(req.Messages) != int(2*call-1) {
+			t.Errorf("Expected the earlier rounds in request %d, got %d messages", call, len(req.Messages))
+		}
+		if call == 3 {
+			_, _ = w.Write([]byte(`{"content":[{"type":"text","text":"done"}]}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"content":[{"type":"tool_use","id":"toolu_` + string(rune('0'+call)) + `","name":"search_invoices","input":{"customer":"acme"}}]}`))
+	}))
+	defer server.Close()
+
+	tool := &invoiceTool{}
+	response, err := NewClient("key", WithBaseURL(server.URL)).GenerateWithTools(context.Background(), "hi", []interfaces.Tool{tool})
+	if err != nil {
+		t.Fatalf("GenerateWithTools failed: %v", err)
+	}
+	if response != "done" || calls.Load() != 3 {
+		t.Errorf("Expected an answer after two tool rounds, got %q after %d calls", response, calls.Load())
+	}
+
+	// At the iteration limit, the model is asked to answer without tools
+	calls.Store(0)
+	choices = nil
+	_, err = NewClient("key", WithBaseURL(server.URL), WithMaxToolIterations(2)).GenerateWithTools(context.Background(), "hi", []interfaces.Tool{tool})
+	if err != nil {
+		t.Fatalf("GenerateWithTools failed: %v", err)
+	}
+	if none, _ := choices[2].(map[string]interface{}); none["type"] != "none" {
+		t.Errorf("Expected tool_choice none in the last request, got %v", choices)
+	}
+
+	calls.Store(0)
+	_, err = NewClient("key", WithBaseURL(server.URL), WithMaxToolIterations(1)).GenerateWithTools(context.Background(), "hi", []interfaces.Tool{tool})
+	if err == nil {
+		t.Error("Expected an error when the model keeps calling tools")
+	}
+}
+
+func TestConstraintsAreRejected(t *testing.T) {
+	client := NewClient("key", WithBaseURL("http://127.0.0.1:0"))
+	if _, err := client.Generate(context.Background(), "hi", interfaces.WithGrammar(`root ::= "yes"`)); !errors.Is(err, errConstraintUnsupported) {
+		t.Errorf("Expected constraints to be rejected, got %v", err)
+	}
+	if _, err := client.GenerateStream(context.Background(), "hi", interfaces.WithRegex(`\d+`)); !errors.Is(err, errConstraintUnsupported) {
+		t.Errorf("Expected constraints to be rejected in streams, got %v", err)
+	}
+}
+
+func TestGenerateWithMaxTokens(t *testing.T) {
+	var maxTokens []int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req CompletionRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("Failed to decode request: %v", err)
+		}
+		maxTokens = append(maxTokens, req.MaxTokens)
+		_, _ = w.Write([]byte(`{"content":[{"type":"text","text":"ok"}]}`))
+	}))
+	defer server.Close()
+
+	client := NewClient("key", WithBaseURL(server.URL))
+	for _, options := range [][]interfaces.GenerateOption{
+		nil,
+		{interfaces.WithMaxTokens(500)},
+		{interfaces.WithMaxTokens(500), WithReasoning("minimal")},
+	} {
+		if _, err := client.Generate(context.Background(), "hi", options...); err != nil {
+			t.Fatalf("Generate failed: %v", err)
+		}
+	}
+	if _, err := client.GenerateWithTools(context.Background(), "hi", nil, interfaces.WithMaxTokens(300)); err != nil {
+		t.Fatalf("GenerateWithTools failed: %v", err)
+	}
+
+	// Thinking adds its budget to the limit of the response
+	expected := []int{defaultMaxTokens, 500, 500 + 1024, 300}
+	for i := range expected {
+		if i >= len(maxTokens) || maxTokens[i] != expected[i] {
+			t.Fatalf("Expected max tokens %v, got %v", expected, maxTokens)
+		}
+	}
+}
+
+func TestCapabilities(t *testing.T) {
+	caps := NewClient("key", WithModel("claude-sonnet-4-20250514")).Capabilities()
+	if !caps.Tools || !caps.Vision || !caps.ParallelTools || !caps.AssistantPrefill || caps.JSONMode {
+		t.Errorf("Unexpected capabilities: %+v", caps)
+	}
+
+	if caps := NewClient("key", WithModel("claude-2.1")).Capabilities(); caps.Vision {
+		t.Errorf("Expected no vision for Claude 2, got %+v", caps)
+	}
+}
@@ -0,0 +1,140 @@
+package anthropic
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/run-bigpig/llm-agent/pkg/retry"
+)
+
+// APIError is an error response from the Anthropic API
+type APIError struct {
+	// StatusCode is the HTTP status code
+	StatusCode int
+
+	// Type is the error type, e.g. "overloaded_error" or "rate_limit_error"
+	Type string
+
+	// Message is the error message
+	Message string
+
+	// RequestID identifies the request for Anthropic support
+	RequestID string
+
+	// RetryAfterDuration is the delay requested by the Retry-After header
+	RetryAfterDuration time.Duration
+
+	// RateLimit holds the rate limit headers of the response
+	RateLimit RateLimit
+}
+
+// RateLimit holds the rate limit state reported in response headers. Missing
+// values are -1.
+type RateLimit struct {
+	RequestsRemaining int
+	TokensRemaining   int
+	RequestsReset     string
+	TokensReset       string
+}
+
+// Error returns the error message with the status and rate limit details
+func (e *APIError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "error from Anthropic API: status %d", e.StatusCode)
+	if e.Type != "" {
+		fmt.Fprintf(&b, " %s", e.Type)
+	}
+	if e.Message != "" {
+		fmt.Fprintf(&b, ": %s", e.Message)
+	}
+
+	var details []string
+	if e.RetryAfterDuration > 0 {
+		details = append(details, fmt.Sprintf("retry after %s", e.RetryAfterDuration))
+	}
+	if e.RateLimit.RequestsRemaining >= 0 {
+		details = append(details, fmt.Sprintf("requests remaining %d", e.RateLimit.RequestsRemaining))
+	}
+	if e.RateLimit.TokensRemaining >= 0 {
+		details = append(details, fmt.Sprintf("tokens remaining %d", e.RateLimit.TokensRemaining))
+	}
+	if e.RateLimit.RequestsReset != "" {
+		details = append(details, "requests reset at "+e.RateLimit.RequestsReset)
+	}
+	if e.RateLimit.TokensReset != "" {
+		details = append(details, "tokens reset at "+e.RateLimit.TokensReset)
+	}
+	if e.RequestID != "" {
+		details = append(details, "request ID "+e.RequestID)
+	}
+	if len(details) > 0 {
+		fmt.Fprintf(&b, " (%s)", strings.Join(details, ", "))
+	}
+
+	return b.String()
+}
+
+// RetryAfter returns the delay requested by the server
+func (e *APIError) RetryAfter() time.Duration {
+	return e.RetryAfterDuration
+}
+
+// Retryable reports whether the request can be retried: rate limits,
+// overloaded errors and server errors are transient
+func (e *APIError) Retryable() bool {
+	switch e.Type {
+	case "overloaded_error", "rate_limit_error", "api_error":
+		return true
+	}
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
+}
+
+// newAPIError builds an APIError from an error response. Errors that cannot
+// be retried are marked permanent so the retry executor stops.
+func newAPIError(resp *http.Response, body []byte) error {
+	apiErr := &APIError{
+		StatusCode: resp.StatusCode,
+		RequestID:  resp.Header.Get("Request-Id"),
+		RateLimit: RateLimit{
+			RequestsRemaining: headerInt(resp.Header, "Anthropic-Ratelimit-Requests-Remaining"),
+			TokensRemaining:   headerInt(resp.Header, "Anthropic-Ratelimit-Tokens-Remaining"),
+			RequestsReset:     resp.Header.Get("Anthropic-Ratelimit-Requests-Reset"),
+			TokensReset:       resp.Header.Get("Anthropic-Ratelimit-Tokens-Reset"),
+		},
+	}
+
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+		apiErr.RetryAfterDuration = time.Duration(seconds) * time.Second
+	}
+
+	var errResp struct {
+		Error struct {
+			Type    string `json:"type"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(body, &errResp); err == nil && errResp.Error.Type != "" {
+		apiErr.Type = errResp.Error.Type
+		apiErr.Message = errResp.Error.Message
+	} else {
+		apiErr.Message = strings.TrimSpace(string(body))
+	}
+
+	if !apiErr.Retryable() {
+		return retry.Permanent(apiErr)
+	}
+	return apiErr
+}
+
+// headerInt parses an integer header, returning -1 if it is missing
+func headerInt(header http.Header, name string) int {
+	n, err := strconv.Atoi(header.Get(name))
+	if err != nil {
+		return -1
+	}
+	return n
+}
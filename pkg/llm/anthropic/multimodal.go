@@ -0,0 +1,100 @@
+package anthropic
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+)
+
+// ImageSource is the image of an image content block, either base64 data or
+// a URL
+type ImageSource struct {
+	Type      string `json:"type"`
+	MediaType string `json:"media_type,omitempty"`
+	Data      string `json:"data,omitempty"`
+	URL       string `json:"url,omitempty"`
+}
+
+// GenerateMultimodal implements interfaces.MultimodalLLM. The prompt is sent
+// as text and image content blocks, which Claude 3 and later models accept.
+func (c *AnthropicClient) GenerateMultimodal(ctx context.Context, contents []interfaces.Content, options ...interfaces.GenerateOption) (string, error) {
+	if c.Model == "" {
+		return "", fmt.Errorf("model not specified: use WithModel option when creating the client")
+	}
+
+	blocks, err := contentBlocks(contents)
+	if err != nil {
+		return "", err
+	}
+
+	params := &interfaces.GenerateOptions{
+		LLMConfig: &interfaces.LLMConfig{
+			Temperature: 0.7,
+		},
+	}
+	for _, option := range options {
+		option(params)
+	}
+
+	if params.Constraint != nil {
+		return "", errConstraintUnsupported
+	}
+
+	ctx, cancel := interfaces.RequestContext(ctx, params.Timeout, c.requestTimeout)
+	defer cancel()
+
+	ctx, req := c.messageRequest(ctx, "", params)
+	req.Messages[0].Blocks = blocks
+
+	reqBody, err := json.Marshal(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal request: %w", err)
+	}
+	respBody, err := c.sendMessages(ctx, reqBody)
+	if err != nil {
+		return "", err
+	}
+
+	var resp CompletionResponse
+	if err := json.Unmarshal(respBody, &resp); err != nil {
+		return "", fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	resp.reportUsage(ctx)
+	resp.reportThinking(ctx)
+
+	return resp.text()
+}
+
+// contentBlocks converts the contents to the content blocks of a user
+// message
+func contentBlocks(contents []interfaces.Content) ([]ContentBlock, error) {
+	blocks := make([]ContentBlock, 0, len(contents))
+	for _, content := range contents {
+		if err := content.Validate(); err != nil {
+			return nil, err
+		}
+		switch content.Type {
+		case interfaces.ContentTypeText:
+			if content.Text != "" {
+				blocks = append(blocks, ContentBlock{Type: "text", Text: content.Text})
+			}
+		case interfaces.ContentTypeImageURL:
+			blocks = append(blocks, ContentBlock{Type: "image", Source: &ImageSource{Type: "url", URL: content.URL}})
+		case interfaces.ContentTypeImage:
+			blocks = append(blocks, ContentBlock{Type: "image", Source: &ImageSource{
+				Type:      "base64",
+				MediaType: content.MediaType,
+				Data:      base64.StdEncoding.EncodeToString(content.Data),
+			}})
+		default:
+			return nil, fmt.Errorf("%s content is not supported by the Anthropic API", content.Type)
+		}
+	}
+	if len(blocks) == 0 {
+		return nil, fmt.Errorf("no content to send")
+	}
+	return blocks, nil
+}
@@ -0,0 +1,44 @@
+package anthropic
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+)
+
+func TestGenerateMultimodal(t *testing.T) {
+	var request map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Errorf("Failed to decode request: %v", err)
+		}
+		_, _ = w.Write([]byte(`{"content":[{"type":"text","text":"A cat."}],"usage":{"input_tokens":1500,"output_tokens":3}}`))
+	}))
+	defer server.Close()
+
+	client := NewClient("key", WithBaseURL(server.URL))
+	response, err := client.GenerateMultimodal(context.Background(), []interfaces.Content{
+		interfaces.TextContent("What animal is this?"),
+		interfaces.ImageContent([]byte("jpeg"), "image/jpeg"),
+		interfaces.ImageURLContent("https://example.com/cat.png"),
+	})
+	if err != nil {
+		t.Fatalf("GenerateMultimodal failed: %v", err)
+	}
+	if response != "A cat." {
+		t.Errorf("Unexpected response %q", response)
+	}
+
+	messages := request["messages"].([]interface{})
+	content, _ := json.Marshal(messages[0].(map[string]interface{})["content"])
+	expected := `[{"text":"What animal is this?","type":"text"},` +
+		`{"source":{"data":"anBlZw==","media_type":"image/jpeg","type":"base64"},"type":"image"},` +
+		`{"source":{"type":"url","url":"https://example.com/cat.png"},"type":"image"}]`
+	if string(content) != expected {
+		t.Errorf("Expected content %s, got %s", expected, content)
+	}
+}
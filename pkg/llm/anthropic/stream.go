@@ -0,0 +1,195 @@
+package anthropic
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+)
+
+// streamEvent is the data of a server-sent event of a streamed message
+type streamEvent struct {
+	Type  string `json:"type"`
+	Delta struct {
+		Type     string `json:"type"`
+		Text     string `json:"text"`
+		Thinking string `json:"thinking"`
+	} `json:"delta"`
+	Error struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+
+	// Message is set on message_start events, with the input tokens
+	Message struct {
+		Model string `json:"model"`
+		Usage Usage  `json:"usage"`
+	} `json:"message"`
+
+	// Usage is set on message_delta events, with the output tokens so far
+	Usage Usage `json:"usage"`
+}
+
+// GenerateStream generates text from a prompt and streams the response as it
+// is generated, preceded by thinking events if extended thinking is enabled.
+// Opening the stream is retried with the client's retry policy; a stream
+// that fails midway is not.
+func (c *AnthropicClient) GenerateStream(ctx context.Context, prompt string, options ...interfaces.GenerateOption) (<-chan interfaces.StreamEvent, error) {
+	if c.Model == "" {
+		return nil, fmt.Errorf("model not specified: use WithModel option when creating the client")
+	}
+
+	params := &interfaces.GenerateOptions{
+		LLMConfig: &interfaces.LLMConfig{
+			Temperature: 0.7, // Default temperature
+		},
+	}
+	for _, option := range options {
+		option(params)
+	}
+
+	// Constraints can't be enforced, so they fail the call
+	if params.Constraint != nil {
+		return nil, errConstraintUnsupported
+	}
+
+	// The timeout covers the whole stream
+	ctx, cancel := interfaces.RequestContext(ctx, params.Timeout, c.requestTimeout)
+
+	ctx, req := c.messageRequest(ctx, prompt, params)
+	req.Stream = true
+
+	var body io.ReadCloser
+	operation := func() error {
+		reqBody, err := json.Marshal(req)
+		if err != nil {
+			return fmt.Errorf("failed to marshal request: %w", err)
+		}
+
+		httpReq, err := http.NewRequestWithContext(ctx, "POST", c.BaseURL+"/v1/messages", bytes.NewBuffer(reqBody))
+		if err != nil {
+			return fmt.Errorf("failed to create request: %w", err)
+		}
+		httpReq.Header.Set("Content-Type", "application/json")
+		httpReq.Header.Set("Accept", "text/event-stream")
+		httpReq.Header.Set("X-API-Key", c.APIKey)
+		httpReq.Header.Set("Anthropic-Version", "2023-06-01")
+
+		httpResp, err := c.HTTPClient.Do(httpReq)
+		if err != nil {
+			c.logger.Error(ctx, "Error from Anthropic API", map[string]interface{}{
+				"error": err.Error(),
+				"model": c.Model,
+			})
+			return fmt.Errorf("failed to send request: %w", err)
+		}
+		if httpResp.StatusCode != http.StatusOK {
+			respBody, _ := io.ReadAll(httpResp.Body)
+			_ = httpResp.Body.Close()
+			c.logger.Error(ctx, "Error from Anthropic API", map[string]interface{}{
+				"status_code": httpResp.StatusCode,
+				"response":    string(respBody),
+				"model":       c.Model,
+			})
+			return newAPIError(httpResp, respBody)
+		}
+		body = httpResp.Body
+		return nil
+	}
+
+	if err := c.execute(ctx, operation); err != nil {
+		cancel()
+		return nil, err
+	}
+
+	events := make(chan interfaces.StreamEvent)
+	go func() {
+		defer cancel()
+		defer close(events)
+		defer func() {
+			if closeErr := body.Close(); closeErr != nil {
+				c.logger.Warn(ctx, "Failed to close response body", map[string]interface{}{
+					"error": closeErr.Error(),
+				})
+			}
+		}()
+
+		send := func(event interfaces.StreamEvent) bool {
+			select {
+			case events <- event:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		var usage interfaces.Usage
+		err := readStream(body, func(event streamEvent) (bool, error) {
+			switch event.Type {
+			case "message_start":
+				usage.Model = event.Message.Model
+				usage.InputTokens = event.Message.Usage.InputTokens
+				usage.OutputTokens = event.Message.Usage.OutputTokens
+			case "message_delta":
+				usage.OutputTokens = event.Usage.OutputTokens
+			case "content_block_delta":
+				if event.Delta.Type == "text_delta" && event.Delta.Text != "" {
+					if !send(interfaces.StreamEvent{Type: interfaces.StreamEventText, Content: event.Delta.Text}) {
+						return false, ctx.Err()
+					}
+				}
+				if event.Delta.Type == "thinking_delta" && event.Delta.Thinking != "" {
+					if !send(interfaces.StreamEvent{Type: interfaces.StreamEventThinking, Content: event.Delta.Thinking}) {
+						return false, ctx.Err()
+					}
+				}
+			case "message_stop":
+				interfaces.ReportUsage(ctx, usage)
+				return false, nil
+			case "error":
+				return false, fmt.Errorf("error from Anthropic API: %s: %s", event.Error.Type, event.Error.Message)
+			}
+			return true, nil
+		})
+		if err != nil {
+			send(interfaces.StreamEvent{Type: interfaces.StreamEventError, Err: err})
+			return
+		}
+		send(interfaces.StreamEvent{Type: interfaces.StreamEventDone})
+	}()
+	return events, nil
+}
+
+// readStream reads server-sent events and passes their data to handle until
+// it returns false or an error. A stream that ends without a message_stop
+// event is an error.
+func readStream(body io.Reader, handle func(event streamEvent) (bool, error)) error {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		data, ok := strings.CutPrefix(line, "data:")
+		if !ok {
+			continue
+		}
+
+		var event streamEvent
+		if err := json.Unmarshal([]byte(strings.TrimSpace(data)), &event); err != nil {
+			return fmt.Errorf("failed to unmarshal stream event: %w", err)
+		}
+		more, err := handle(event)
+		if err != nil || !more {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read stream: %w", err)
+	}
+	return fmt.Errorf("stream ended before the message was complete")
+}
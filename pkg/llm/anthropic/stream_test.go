@@ -0,0 +1,122 @@
+package anthropic
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+)
+
+func TestGenerateStream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req CompletionRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || !req.Stream {
+			t.Errorf("Expected a streaming request, got %+v (%v)", req, err)
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, data := range []string{
+			`{"type":"message_start","message":{"id":"msg_1","model":"claude-test","usage":{"input_tokens":12,"output_tokens":1}}}`,
+			`{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+			`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Hello"}}`,
+			`{"type":"ping"}`,
+			`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":", world"}}`,
+			`{"type":"content_block_stop","index":0}`,
+			`{"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":4}}`,
+			`{"type":"message_stop"}`,
+		} {
+			var event struct{ Type string }
+			_ = json.Unmarshal([]byte(data), &event)
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data)
+		}
+	}))
+	defer server.Close()
+
+	var usage interfaces.Usage
+	ctx := interfaces.WithUsageCallback(context.Background(), func(u interfaces.Usage) { usage = u })
+
+	client := NewClient("key", WithBaseURL(server.URL))
+	events, err := client.GenerateStream(ctx, "hi")
+	if err != nil {
+		t.Fatalf("GenerateStream failed: %v", err)
+	}
+
+	var deltas []string
+	var last interfaces.StreamEvent
+	for event := range events {
+		if event.Type == interfaces.StreamEventText {
+			deltas = append(deltas, event.Content)
+		}
+		last = event
+	}
+	if strings.Join(deltas, "|") != "Hello|, world" {
+		t.Errorf("Expected two deltas, got %q", deltas)
+	}
+	if last.Type != interfaces.StreamEventDone {
+		t.Errorf("Expected the stream to end with a done event, got %+v", last)
+	}
+	if want := (interfaces.Usage{Model: "claude-test", InputTokens: 12, OutputTokens: 4}); usage != want {
+		t.Errorf("Expected usage %+v, got %+v", want, usage)
+	}
+}
+
+func TestGenerateStreamErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "data: {\"type\":\"content_block_delta\",\"delta\":{\"type\":\"text_delta\",\"text\":\"Hel\"}}\n\n")
+		fmt.Fprint(w, "data: {\"type\":\"error\",\"error\":{\"type\":\"overloaded_error\",\"message\":\"Overloaded\"}}\n\n")
+	}))
+	defer server.Close()
+
+	events, err := NewClient("key", WithBaseURL(server.URL)).GenerateStream(context.Background(), "hi")
+	if err != nil {
+		t.Fatalf("GenerateStream failed: %v", err)
+	}
+	text, err := interfaces.CollectStream(events)
+	if text != "Hel" || err == nil || !strings.Contains(err.Error(), "overloaded_error") {
+		t.Errorf("Expected the partial text and the stream error, got %q and %v", text, err)
+	}
+}
+
+func TestGenerateStreamThinking(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req CompletionRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("Failed to decode request: %v", err)
+		}
+		if req.Thinking == nil || req.Thinking.BudgetTokens != 1024 || req.MaxTokens != 1024+defaultMaxTokens {
+			t.Errorf("Expected extended thinking with a budget of 1024 tokens, got %+v and max tokens %d", req.Thinking, req.MaxTokens)
+		}
+		for _, data := range []string{
+			`{"type":"message_start","message":{"model":"claude-test","usage":{"input_tokens":12}}}`,
+			`{"type":"content_block_delta","index":0,"delta":{"type":"thinking_delta","thinking":"The user greets me."}}`,
+			`{"type":"content_block_delta","index":0,"delta":{"type":"signature_delta","signature":"sig"}}`,
+			`{"type":"content_block_delta","index":1,"delta":{"type":"text_delta","text":"Hello"}}`,
+			`{"type":"message_stop"}`,
+		} {
+			fmt.Fprintf(w, "data: %s\n\n", data)
+		}
+	}))
+	defer server.Close()
+
+	events, err := NewClient("key", WithBaseURL(server.URL)).GenerateStream(context.Background(), "hi", WithReasoning("minimal"))
+	if err != nil {
+		t.Fatalf("GenerateStream failed: %v", err)
+	}
+
+	var types []interfaces.StreamEventType
+	var contents []string
+	for event := range events {
+		types = append(types, event.Type)
+		contents = append(contents, event.Content)
+	}
+	if len(types) != 3 || types[0] != interfaces.StreamEventThinking || types[1] != interfaces.StreamEventText {
+		t.Fatalf("Expected a thinking, a text and a done event, got %v", types)
+	}
+	if contents[0] != "The user greets me." || contents[1] != "Hello" {
+		t.Errorf("Unexpected event contents %q", contents)
+	}
+}
@@ -0,0 +1,79 @@
+package anthropic
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+)
+
+// Thinking budgets of the reasoning modes, in tokens
+const (
+	// minimalThinkingBudget is the smallest budget the API accepts
+	minimalThinkingBudget       = 1024
+	comprehensiveThinkingBudget = 16000
+)
+
+// Thinking enables extended thinking for a request
+type Thinking struct {
+	Type         string `json:"type"`
+	BudgetTokens int    `json:"budget_tokens"`
+}
+
+// thinkingBudget returns the thinking budget of a reasoning mode: "minimal",
+// "comprehensive" or a number of tokens. It reports false for unknown modes.
+func thinkingBudget(reasoning string) (int, bool) {
+	switch reasoning {
+	case "minimal":
+		return minimalThinkingBudget, true
+	case "comprehensive":
+		return comprehensiveThinkingBudget, true
+	}
+	budget, err := strconv.Atoi(reasoning)
+	if err != nil || budget <= 0 {
+		return 0, false
+	}
+	if budget < minimalThinkingBudget {
+		budget = minimalThinkingBudget
+	}
+	return budget, true
+}
+
+// applyThinking enables extended thinking on the request for the reasoning
+// mode. Modes other than "none" need a model with extended thinking, such as
+// Claude 3.7 Sonnet.
+func (c *AnthropicClient) applyThinking(ctx context.Context, req *CompletionRequest, reasoning string) {
+	if reasoning == "" || reasoning == "none" {
+		return
+	}
+	budget, ok := thinkingBudget(reasoning)
+	if !ok {
+		c.logger.Warn(ctx, "Unknown reasoning mode, thinking disabled", map[string]interface{}{"reasoning": reasoning})
+		return
+	}
+
+	req.Thinking = &Thinking{Type: "enabled", BudgetTokens: budget}
+	// The thinking counts toward max_tokens, so the response keeps its room
+	req.MaxTokens += budget
+
+	// Thinking is incompatible with changes to the sampling, so the API
+	// defaults are used
+	req.Temperature = 0
+	req.TopP = 0
+	req.TopK = 0
+
+	c.logger.Debug(ctx, "Using extended thinking", map[string]interface{}{"budget_tokens": budget})
+}
+
+// reportThinking passes the thinking blocks of the response to the thinking
+// callback in the context
+func (r CompletionResponse) reportThinking(ctx context.Context) {
+	var thinking []string
+	for _, block := range r.Content {
+		if block.Type == "thinking" && block.Thinking != "" {
+			thinking = append(thinking, block.Thinking)
+		}
+	}
+	interfaces.ReportThinking(ctx, strings.Join(thinking, "\n"))
+}
@@ -0,0 +1,22 @@
+// Package audio adds voice input and output to agents: transcribers turn
+// recordings into text, synthesizers turn responses into speech, and Voice
+// runs an agent between them.
+package audio
+
+import (
+	"context"
+
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+)
+
+// Transcriber turns a recording into text
+type Transcriber interface {
+	// Transcribe returns the text spoken in the audio content
+	Transcribe(ctx context.Context, audio interfaces.Content) (string, error)
+}
+
+// Synthesizer turns text into speech
+type Synthesizer interface {
+	// Synthesize returns the text spoken as audio content
+	Synthesize(ctx context.Context, text string) (interfaces.Content, error)
+}
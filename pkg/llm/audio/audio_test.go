@@ -0,0 +1,124 @@
+package audio_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+	"github.com/run-bigpig/llm-agent/pkg/llm/audio"
+)
+
+func TestOpenAI(t *testing.T) {
+	var fileName, language, speechRequest string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/audio/transcriptions":
+			file, header, err := r.FormFile("file")
+			require.NoError(t, err)
+			_ = file.Close()
+			fileName = header.Filename
+			language = r.FormValue("language")
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"text":"Wie spät ist es?"}`))
+		case "/audio/speech":
+			body, _ := io.ReadAll(r.Body)
+			speechRequest = string(body)
+			w.Header().Set("Content-Type", "audio/mpeg")
+			_, _ = w.Write([]byte("mp3"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	client := audio.NewOpenAI("key", audio.WithBaseURL(server.URL), audio.WithLanguage("de"), audio.WithVoice("nova"))
+
+	transcript, err := client.Transcribe(context.Background(), interfaces.AudioContent([]byte("wav"), "audio/x-wav"))
+	require.NoError(t, err)
+	assert.Equal(t, "Wie spät ist es?", transcript)
+	assert.Equal(t, "audio.wav", fileName)
+	assert.Equal(t, "de", language)
+
+	speech, err := client.Synthesize(context.Background(), "Es ist drei Uhr.")
+	require.NoError(t, err)
+	assert.Equal(t, interfaces.AudioContent([]byte("mp3"), "audio/mpeg"), speech)
+	assert.JSONEq(t, `{"model":"tts-1","input":"Es ist drei Uhr.","voice":"nova","response_format":"mp3"}`, speechRequest)
+
+	_, err = client.Transcribe(context.Background(), interfaces.TextContent("hi"))
+	assert.Error(t, err)
+}
+
+// audioLLM answers multimodal prompts like Gemini with a transcript
+type audioLLM struct {
+	contents []interfaces.Content
+}
+
+func (m *audioLLM) Generate(ctx context.Context, prompt string, options ...interfaces.GenerateOption) (string, error) {
+	return "", errors.New("not implemented")
+}
+
+func (m *audioLLM) GenerateWithTools(ctx context.Context, prompt string, tools []interfaces.Tool, options ...interfaces.GenerateOption) (string, error) {
+	return "", errors.New("not implemented")
+}
+
+func (m *audioLLM) GenerateMultimodal(ctx context.Context, contents []interfaces.Content, options ...interfaces.GenerateOption) (string, error) {
+	m.contents = contents
+	return " What time is it?\n", nil
+}
+
+func (m *audioLLM) Name() string { return "audio" }
+
+func TestLLMTranscriber(t *testing.T) {
+	llm := &audioLLM{}
+	recording := interfaces.AudioContent([]byte("wav"), "audio/wav")
+
+	transcript, err := audio.NewLLMTranscriber(llm, "").Transcribe(context.Background(), recording)
+	require.NoError(t, err)
+	assert.Equal(t, "What time is it?", transcript)
+	assert.Equal(t, []interfaces.Content{interfaces.TextContent(audio.DefaultTranscriptionPrompt), recording}, llm.contents)
+}
+
+type fixedTranscriber string
+
+func (f fixedTranscriber) Transcribe(ctx context.Context, recording interfaces.Content) (string, error) {
+	return string(f), nil
+}
+
+type echoSynthesizer struct{}
+
+func (echoSynthesizer) Synthesize(ctx context.Context, text string) (interfaces.Content, error) {
+	return interfaces.AudioContent([]byte(text), "audio/mpeg"), nil
+}
+
+type runnerFunc func(ctx context.Context, input string) (string, error)
+
+func (f runnerFunc) Run(ctx context.Context, input string) (string, error) { return f(ctx, input) }
+
+func TestVoice(t *testing.T) {
+	agent := runnerFunc(func(ctx context.Context, input string) (string, error) {
+		return "You asked: " + input, nil
+	})
+	recording := interfaces.AudioContent([]byte("wav"), "audio/wav")
+
+	reply, err := audio.NewVoice(agent, fixedTranscriber("What time is it?"), echoSynthesizer{}).Respond(context.Background(), recording)
+	require.NoError(t, err)
+	assert.Equal(t, "What time is it?", reply.Transcript)
+	assert.Equal(t, "You asked: What time is it?", reply.Text)
+	require.NotNil(t, reply.Audio)
+	assert.Equal(t, "You asked: What time is it?", string(reply.Audio.Data))
+
+	// Without a synthesizer the reply is text only
+	reply, err = audio.NewVoice(agent, fixedTranscriber("hi"), nil).Respond(context.Background(), recording)
+	require.NoError(t, err)
+	assert.Nil(t, reply.Audio)
+
+	_, err = audio.NewVoice(agent, fixedTranscriber(" "), nil).Respond(context.Background(), recording)
+	assert.Error(t, err)
+}
@@ -0,0 +1,40 @@
+package audio
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+)
+
+// DefaultTranscriptionPrompt asks a multimodal LLM for a transcript
+const DefaultTranscriptionPrompt = "Transcribe the speech in this recording. Respond with the transcript only."
+
+// LLMTranscriber transcribes with a multimodal LLM that accepts audio, such
+// as Gemini on Vertex AI
+type LLMTranscriber struct {
+	llm    interfaces.MultimodalLLM
+	prompt string
+}
+
+// NewLLMTranscriber creates a transcriber that asks the LLM for a
+// transcript. An empty prompt uses DefaultTranscriptionPrompt.
+func NewLLMTranscriber(llm interfaces.MultimodalLLM, prompt string) *LLMTranscriber {
+	if prompt == "" {
+		prompt = DefaultTranscriptionPrompt
+	}
+	return &LLMTranscriber{llm: llm, prompt: prompt}
+}
+
+// Transcribe implements Transcriber
+func (t *LLMTranscriber) Transcribe(ctx context.Context, audio interfaces.Content) (string, error) {
+	if audio.Type != interfaces.ContentTypeAudio {
+		return "", fmt.Errorf("can't transcribe %s content", audio.Type)
+	}
+	transcript, err := t.llm.GenerateMultimodal(ctx, []interfaces.Content{interfaces.TextContent(t.prompt), audio})
+	if err != nil {
+		return "", fmt.Errorf("failed to transcribe audio: %w", err)
+	}
+	return strings.TrimSpace(transcript), nil
+}
@@ -0,0 +1,162 @@
+package audio
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/sashabaranov/go-openai"
+
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+)
+
+// OpenAI transcribes with Whisper and synthesizes speech with the OpenAI
+// text-to-speech models
+type OpenAI struct {
+	client             *openai.Client
+	baseURL            string
+	httpClient         *http.Client
+	transcriptionModel string
+	speechModel        openai.SpeechModel
+	voice              openai.SpeechVoice
+	language           string
+}
+
+// Option configures the OpenAI transcriber and synthesizer
+type Option func(*OpenAI)
+
+// WithBaseURL sets the API base URL, e.g. for an OpenAI-compatible server
+func WithBaseURL(baseURL string) Option {
+	return func(o *OpenAI) {
+		o.baseURL = baseURL
+	}
+}
+
+// WithHTTPClient sets the HTTP client used for requests
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(o *OpenAI) {
+		o.httpClient = httpClient
+	}
+}
+
+// WithTranscriptionModel sets the transcription model. Defaults to
+// "whisper-1".
+func WithTranscriptionModel(model string) Option {
+	return func(o *OpenAI) {
+		o.transcriptionModel = model
+	}
+}
+
+// WithSpeechModel sets the text-to-speech model. Defaults to "tts-1".
+func WithSpeechModel(model string) Option {
+	return func(o *OpenAI) {
+		o.speechModel = openai.SpeechModel(model)
+	}
+}
+
+// WithVoice sets the voice of synthesized speech, e.g. "nova". Defaults to
+// "alloy".
+func WithVoice(voice string) Option {
+	return func(o *OpenAI) {
+		o.voice = openai.SpeechVoice(voice)
+	}
+}
+
+// WithLanguage sets the ISO-639-1 language of recordings, e.g. "de", which
+// improves transcription. By default the language is detected.
+func WithLanguage(language string) Option {
+	return func(o *OpenAI) {
+		o.language = language
+	}
+}
+
+// NewOpenAI creates a transcriber and synthesizer for the OpenAI audio API
+func NewOpenAI(apiKey string, options ...Option) *OpenAI {
+	o := &OpenAI{
+		transcriptionModel: openai.Whisper1,
+		speechModel:        openai.TTSModel1,
+		voice:              openai.VoiceAlloy,
+	}
+	for _, option := range options {
+		option(o)
+	}
+
+	config := openai.DefaultConfig(apiKey)
+	if o.baseURL != "" {
+		config.BaseURL = o.baseURL
+	}
+	if o.httpClient != nil {
+		config.HTTPClient = o.httpClient
+	}
+	o.client = openai.NewClientWithConfig(config)
+	return o
+}
+
+// Transcribe implements Transcriber
+func (o *OpenAI) Transcribe(ctx context.Context, audio interfaces.Content) (string, error) {
+	if err := audio.Validate(); err != nil {
+		return "", err
+	}
+	if audio.Type != interfaces.ContentTypeAudio {
+		return "", fmt.Errorf("can't transcribe %s content", audio.Type)
+	}
+
+	// The API tells the format from the file name
+	resp, err := o.client.CreateTranscription(ctx, openai.AudioRequest{
+		Model:    o.transcriptionModel,
+		FilePath: "audio" + audioExtension(audio.MediaType),
+		Reader:   bytes.NewReader(audio.Data),
+		Language: o.language,
+		Format:   openai.AudioResponseFormatJSON,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to transcribe audio: %w", err)
+	}
+	return resp.Text, nil
+}
+
+// Synthesize implements Synthesizer. The speech is MP3 audio.
+func (o *OpenAI) Synthesize(ctx context.Context, text string) (interfaces.Content, error) {
+	resp, err := o.client.CreateSpeech(ctx, openai.CreateSpeechRequest{
+		Model:          o.speechModel,
+		Input:          text,
+		Voice:          o.voice,
+		ResponseFormat: openai.SpeechResponseFormatMp3,
+	})
+	if err != nil {
+		return interfaces.Content{}, fmt.Errorf("failed to synthesize speech: %w", err)
+	}
+	defer resp.Close()
+
+	data, err := io.ReadAll(resp)
+	if err != nil {
+		return interfaces.Content{}, fmt.Errorf("failed to read speech: %w", err)
+	}
+	return interfaces.AudioContent(data, "audio/mpeg"), nil
+}
+
+// audioExtensions are the file extensions of the media types the
+// transcription API accepts, where they differ from the subtype
+var audioExtensions = map[string]string{
+	"audio/mpeg":   ".mp3",
+	"audio/x-wav":  ".wav",
+	"audio/wave":   ".wav",
+	"audio/x-m4a":  ".m4a",
+	"audio/mp4":    ".m4a",
+	"audio/x-flac": ".flac",
+}
+
+// audioExtension returns the file extension of the media type
+func audioExtension(mediaType string) string {
+	if parsed, _, err := mime.ParseMediaType(mediaType); err == nil {
+		mediaType = parsed
+	}
+	if extension, ok := audioExtensions[mediaType]; ok {
+		return extension
+	}
+	return "." + strings.TrimPrefix(mediaType, "audio/")
+}
@@ -0,0 +1,67 @@
+package audio
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+)
+
+// Runner runs an agent on text input, e.g. *agent.Agent
+type Runner interface {
+	Run(ctx context.Context, input string) (string, error)
+}
+
+// Reply is the response of an agent to voice input
+type Reply struct {
+	// Transcript is the text of the input
+	Transcript string
+
+	// Text is the agent's response
+	Text string
+
+	// Audio is the response as speech, if the voice has a synthesizer
+	Audio *interfaces.Content
+}
+
+// Voice lets an agent take voice input and respond with speech
+type Voice struct {
+	runner      Runner
+	transcriber Transcriber
+	synthesizer Synthesizer
+}
+
+// NewVoice creates a voice for the agent. Without a synthesizer, replies are
+// text only.
+func NewVoice(runner Runner, transcriber Transcriber, synthesizer Synthesizer) *Voice {
+	return &Voice{runner: runner, transcriber: transcriber, synthesizer: synthesizer}
+}
+
+// Respond transcribes the recording, runs the agent on the transcript and
+// synthesizes its response
+func (v *Voice) Respond(ctx context.Context, recording interfaces.Content) (*Reply, error) {
+	transcript, err := v.transcriber.Transcribe(ctx, recording)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(transcript) == "" {
+		return nil, errors.New("no speech in the recording")
+	}
+
+	response, err := v.runner.Run(ctx, transcript)
+	if err != nil {
+		return nil, fmt.Errorf("failed to run agent: %w", err)
+	}
+
+	reply := &Reply{Transcript: transcript, Text: response}
+	if v.synthesizer != nil {
+		speech, err := v.synthesizer.Synthesize(ctx, response)
+		if err != nil {
+			return nil, err
+		}
+		reply.Audio = &speech
+	}
+	return reply, nil
+}
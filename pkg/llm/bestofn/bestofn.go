@@ -0,0 +1,280 @@
+// Package bestofn samples several responses to a prompt in parallel and
+// returns the best one, picked by a scoring function or a judge model. It
+// trades latency and cost for quality, for high-stakes generation.
+package bestofn
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+)
+
+// defaultSamples is the number of responses sampled by default
+const defaultSamples = 3
+
+// Candidate is one sampled response
+type Candidate struct {
+	// Index is the position of the sample, from 0
+	Index int `json:"index"`
+
+	Response string  `json:"response,omitempty"`
+	Score    float64 `json:"score"`
+
+	// Error is the error of a failed sample, which is not scored
+	Error string `json:"error,omitempty"`
+}
+
+// Result is the outcome of sampling
+type Result struct {
+	// Best is the candidate with the highest score; ties go to the earlier
+	// sample
+	Best Candidate `json:"best"`
+
+	// Candidates are all samples, best first, with failed samples last
+	Candidates []Candidate `json:"candidates"`
+}
+
+// Alternatives returns the successful candidates other than the best one,
+// best first
+func (r *Result) Alternatives() []Candidate {
+	var alternatives []Candidate
+	for _, candidate := range r.Candidates {
+		if candidate.Index != r.Best.Index && candidate.Error == "" {
+			alternatives = append(alternatives, candidate)
+		}
+	}
+	return alternatives
+}
+
+// Scorer scores the responses to a prompt; higher is better
+type Scorer interface {
+	// Score returns a score per response, in the same order
+	Score(ctx context.Context, prompt string, responses []string) ([]float64, error)
+}
+
+// ScorerFunc scores each response on its own, e.g. by validating it or
+// checking its length
+type ScorerFunc func(ctx context.Context, prompt, response string) (float64, error)
+
+// Score returns a score per response, in the same order
+func (f ScorerFunc) Score(ctx context.Context, prompt string, responses []string) ([]float64, error) {
+	scores := make([]float64, len(responses))
+	for i, response := range responses {
+		score, err := f(ctx, prompt, response)
+		if err != nil {
+			return nil, err
+		}
+		scores[i] = score
+	}
+	return scores, nil
+}
+
+// Judge scores responses by asking an LLM to rate them side by side
+type Judge struct {
+	llm      interfaces.LLM
+	criteria string
+}
+
+// NewJudge creates a judge backed by an LLM. The criteria describe what a
+// good response is, e.g. "factually correct and cites the policy"; empty
+// criteria ask for the most helpful, correct and complete response.
+func NewJudge(llm interfaces.LLM, criteria string) *Judge {
+	if criteria == "" {
+		criteria = "helpful, correct and complete"
+	}
+	return &Judge{llm: llm, criteria: criteria}
+}
+
+// Score rates each response from 0 to 10
+func (j *Judge) Score(ctx context.Context, prompt string, responses []string) ([]float64, error) {
+	var sb strings.Builder
+	for i, response := range responses {
+		fmt.Fprintf(&sb, "<<<RESPONSE %d>>>\n%s\n<<<END RESPONSE %d>>>\n\n", i+1, response, i+1)
+	}
+
+	judgePrompt := fmt.Sprintf(`Below is a request and %d candidate responses to it.
+Rate how well each response meets these criteria: %s.
+Rate each response from 0 (worst) to 10 (best). Answer with a JSON array of the %d ratings in the order of the responses and nothing else.
+
+<<<BEGIN REQUEST>>>
+%s
+<<<END REQUEST>>>
+
+%s`, len(responses), j.criteria, len(responses), prompt, sb.String())
+
+	response, err := j.llm.Generate(ctx, judgePrompt, func(o *interfaces.GenerateOptions) {
+		if o.LLMConfig == nil {
+			o.LLMConfig = &interfaces.LLMConfig{}
+		}
+		o.LLMConfig.Temperature = 0
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to judge responses: %w", err)
+	}
+
+	// Models sometimes wrap the array in a code block
+	response = strings.TrimSpace(response)
+	if start, end := strings.Index(response, "["), strings.LastIndex(response, "]"); start >= 0 && end > start {
+		response = response[start : end+1]
+	}
+	var scores []float64
+	if err := json.Unmarshal([]byte(response), &scores); err != nil {
+		return nil, fmt.Errorf("unexpected judge response %q: %w", response, err)
+	}
+	if len(scores) != len(responses) {
+		return nil, fmt.Errorf("judge rated %d of %d responses", len(scores), len(responses))
+	}
+	return scores, nil
+}
+
+// LLM wraps an LLM so that Generate samples several responses in parallel
+// and returns the best one
+type LLM struct {
+	llm         interfaces.LLM
+	scorer      Scorer
+	samples     int
+	temperature *float64
+}
+
+// Option configures an LLM
+type Option func(*LLM)
+
+// WithSamples sets the number of responses sampled per call. Defaults to 3.
+func WithSamples(n int) Option {
+	return func(l *LLM) {
+		l.samples = n
+	}
+}
+
+// WithTemperature sets the sampling temperature, overriding the options of
+// the call, so that the samples differ
+func WithTemperature(temperature float64) Option {
+	return func(l *LLM) {
+		l.temperature = &temperature
+	}
+}
+
+// New wraps the LLM with best-of-N sampling, scoring the samples with the
+// scorer
+func New(llm interfaces.LLM, scorer Scorer, options ...Option) *LLM {
+	l := &LLM{
+		llm:     llm,
+		scorer:  scorer,
+		samples: defaultSamples,
+	}
+	for _, option := range options {
+		option(l)
+	}
+	if l.samples < 1 {
+		l.samples = 1
+	}
+	return l
+}
+
+// Generate samples responses and returns the best one. The full result,
+// with the alternatives, is passed to the callback set with
+// WithResultCallback.
+func (l *LLM) Generate(ctx context.Context, prompt string, options ...interfaces.GenerateOption) (string, error) {
+	result, err := l.GenerateCandidates(ctx, prompt, options...)
+	if err != nil {
+		return "", err
+	}
+	if fn, ok := ctx.Value(resultKey{}).(func(*Result)); ok && fn != nil {
+		fn(result)
+	}
+	return result.Best.Response, nil
+}
+
+// GenerateCandidates samples responses in parallel, scores the successful
+// ones and returns them all, best first. It fails if every sample fails or
+// the scorer fails.
+func (l *LLM) GenerateCandidates(ctx context.Context, prompt string, options ...interfaces.GenerateOption) (*Result, error) {
+	if l.temperature != nil {
+		options = append(options, func(o *interfaces.GenerateOptions) {
+			if o.LLMConfig == nil {
+				o.LLMConfig = &interfaces.LLMConfig{}
+			}
+			o.LLMConfig.Temperature = *l.temperature
+		})
+	}
+
+	candidates := make([]Candidate, l.samples)
+	errs := make([]error, l.samples)
+	var wg sync.WaitGroup
+	for i := range candidates {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			response, err := l.llm.Generate(ctx, prompt, options...)
+			candidates[i] = Candidate{Index: i, Response: response}
+			if err != nil {
+				candidates[i] = Candidate{Index: i, Error: err.Error()}
+				errs[i] = err
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	var responses []string
+	var scored []int
+	for i, candidate := range candidates {
+		if errs[i] == nil {
+			responses = append(responses, candidate.Response)
+			scored = append(scored, i)
+		}
+	}
+	if len(responses) == 0 {
+		return nil, fmt.Errorf("all %d samples failed: %w", l.samples, errors.Join(errs...))
+	}
+
+	scores, err := l.scorer.Score(ctx, prompt, responses)
+	if err != nil {
+		return nil, fmt.Errorf("failed to score samples: %w", err)
+	}
+	if len(scores) != len(responses) {
+		return nil, fmt.Errorf("scorer returned %d scores for %d samples", len(scores), len(responses))
+	}
+	for j, i := range scored {
+		candidates[i].Score = scores[j]
+	}
+
+	sort.SliceStable(candidates, func(a, b int) bool {
+		failedA, failedB := candidates[a].Error != "", candidates[b].Error != ""
+		if failedA != failedB {
+			return failedB
+		}
+		return candidates[a].Score > candidates[b].Score
+	})
+	return &Result{Best: candidates[0], Candidates: candidates}, nil
+}
+
+// GenerateWithTools is passed through with a single sample, since sampling
+// would call the tools once per sample
+func (l *LLM) GenerateWithTools(ctx context.Context, prompt string, tools []interfaces.Tool, options ...interfaces.GenerateOption) (string, error) {
+	return l.llm.GenerateWithTools(ctx, prompt, tools, options...)
+}
+
+// Name returns the name of the wrapped LLM
+func (l *LLM) Name() string {
+	return l.llm.Name()
+}
+
+// Unwrap returns the wrapped LLM
+func (l *LLM) Unwrap() interfaces.LLM {
+	return l.llm
+}
+
+type resultKey struct{}
+
+// WithResultCallback returns a context in which Generate passes its full
+// result to fn, e.g. to show the alternatives when the LLM is used by an
+// agent
+func WithResultCallback(ctx context.Context, fn func(*Result)) context.Context {
+	return context.WithValue(ctx, resultKey{}, fn)
+}
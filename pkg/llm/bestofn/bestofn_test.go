@@ -0,0 +1,104 @@
+package bestofn_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+	"github.com/run-bigpig/llm-agent/pkg/llm/bestofn"
+)
+
+// sampleLLM returns a different response on every call, failing the calls
+// listed in fail
+type sampleLLM struct {
+	calls     atomic.Int64
+	responses []string
+	fail      map[int]bool
+}
+
+func (m *sampleLLM) Generate(ctx context.Context, prompt string, options ...interfaces.GenerateOption) (string, error) {
+	i := int(m.calls.Add(1)) - 1
+	if m.fail[i] {
+		return "", errors.New("overloaded")
+	}
+	return m.responses[i%len(m.responses)], nil
+}
+
+func (m *sampleLLM) GenerateWithTools(ctx context.Context, prompt string, tools []interfaces.Tool, options ...interfaces.GenerateOption) (string, error) {
+	return m.Generate(ctx, prompt, options...)
+}
+
+func (m *sampleLLM) Name() string { return "sample" }
+
+// judgeLLM answers with fixed ratings and records the prompt
+type judgeLLM struct {
+	sampleLLM
+	prompt string
+}
+
+func (m *judgeLLM) Generate(ctx context.Context, prompt string, options ...interfaces.GenerateOption) (string, error) {
+	m.prompt = prompt
+	return "```json\n[3, 9, 5]\n```", nil
+}
+
+var byLength = bestofn.ScorerFunc(func(ctx context.Context, prompt, response string) (float64, error) {
+	return float64(len(response)), nil
+})
+
+func TestGenerateCandidates(t *testing.T) {
+	llm := &sampleLLM{responses: []string{"short", "the longest one", "medium one"}}
+	result, err := bestofn.New(llm, byLength).GenerateCandidates(context.Background(), "hi")
+	require.NoError(t, err)
+
+	assert.Equal(t, int64(3), llm.calls.Load())
+	assert.Equal(t, "the longest one", result.Best.Response)
+	require.Len(t, result.Candidates, 3)
+	assert.Equal(t, result.Best, result.Candidates[0])
+
+	alternatives := result.Alternatives()
+	require.Len(t, alternatives, 2)
+	assert.Equal(t, "medium one", alternatives[0].Response)
+	assert.Equal(t, "short", alternatives[1].Response)
+}
+
+func TestGenerateSkipsFailedSamples(t *testing.T) {
+	llm := &sampleLLM{responses: []string{"a", "bb", "ccc", "dddd"}, fail: map[int]bool{0: true, 1: true}}
+
+	var result *bestofn.Result
+	ctx := bestofn.WithResultCallback(context.Background(), func(r *bestofn.Result) { result = r })
+	response, err := bestofn.New(llm, byLength, bestofn.WithSamples(4)).Generate(ctx, "hi")
+	require.NoError(t, err)
+
+	assert.Len(t, response, 4)
+	require.NotNil(t, result)
+	require.Len(t, result.Candidates, 4)
+	assert.Equal(t, "overloaded", result.Candidates[3].Error)
+	assert.Len(t, result.Alternatives(), 1)
+}
+
+func TestGenerateFailsWhenAllSamplesFail(t *testing.T) {
+	llm := &sampleLLM{responses: []string{"a"}, fail: map[int]bool{0: true, 1: true}}
+	_, err := bestofn.New(llm, byLength, bestofn.WithSamples(2)).Generate(context.Background(), "hi")
+	assert.ErrorContains(t, err, "all 2 samples failed")
+}
+
+func TestJudge(t *testing.T) {
+	llm := &sampleLLM{responses: []string{"first", "second", "third"}}
+	judge := &judgeLLM{}
+	response, err := bestofn.New(llm, bestofn.NewJudge(judge, "cites the refund policy")).Generate(context.Background(), "Can I get a refund?")
+	require.NoError(t, err)
+
+	// The samples finish in any order; the judge rated the second one best
+	_, second, found := strings.Cut(judge.prompt, "<<<RESPONSE 2>>>\n")
+	require.True(t, found)
+	second, _, _ = strings.Cut(second, "\n")
+	assert.Equal(t, second, response)
+	assert.Contains(t, judge.prompt, "cites the refund policy")
+	assert.Contains(t, judge.prompt, "<<<BEGIN REQUEST>>>\nCan I get a refund?\n<<<END REQUEST>>>")
+}
@@ -0,0 +1,244 @@
+package dedupe
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+	"github.com/run-bigpig/llm-agent/pkg/multitenancy"
+	"github.com/run-bigpig/llm-agent/pkg/runctx"
+)
+
+// Stats counts deduplicated calls
+type Stats struct {
+	// Calls is the number of Generate calls received
+	Calls int64 `json:"calls"`
+
+	// Upstream is the number of calls sent to the underlying LLM
+	Upstream int64 `json:"upstream"`
+
+	// Shared is the number of calls that shared another call's result
+	Shared int64 `json:"shared"`
+}
+
+// LLM wraps an LLM so that concurrent identical Generate calls share a single
+// upstream request. Calls are identical when they have the same prompt,
+// generation options and organization, so different users of an
+// organization share results; don't wrap LLMs whose responses depend on
+// per-user state that isn't in the prompt or options. Streaming and
+// multimodal calls are passed through without deduplication.
+type LLM struct {
+	llm     interfaces.LLM
+	group   singleflight.Group
+	timeout time.Duration
+
+	calls    atomic.Int64
+	upstream atomic.Int64
+	shared   atomic.Int64
+}
+
+// Option represents an option for configuring the deduplicating LLM
+type Option func(*LLM)
+
+// WithTimeout bounds how long a shared upstream request may run. The
+// default is 2 minutes.
+func WithTimeout(timeout time.Duration) Option {
+	return func(l *LLM) {
+		l.timeout = timeout
+	}
+}
+
+// New wraps the LLM with request deduplication
+func New(llm interfaces.LLM, options ...Option) *LLM {
+	l := &LLM{
+		llm:     llm,
+		timeout: 2 * time.Minute,
+	}
+	for _, option := range options {
+		option(l)
+	}
+	return l
+}
+
+// Generate generates text based on the provided prompt, sharing the upstream
+// request with any identical call already in flight. The upstream request is
+// not cancelled when a single caller's context is cancelled, since other
+// callers may still be waiting for it; it is bounded by WithTimeout instead.
+//
+// The upstream request doesn't carry the user, conversation, request, trace
+// or tags of any one caller. The usage, thinking and citations it reports
+// are passed to the callbacks in every caller's context instead.
+func (l *LLM) Generate(ctx context.Context, prompt string, options ...interfaces.GenerateOption) (string, error) {
+	l.calls.Add(1)
+
+	key, err := requestKey(ctx, prompt, options)
+	if err != nil {
+		// Options that cannot be keyed are not deduplicated
+		l.upstream.Add(1)
+		return l.llm.Generate(ctx, prompt, options...)
+	}
+
+	ch := l.group.DoChan(key, func() (interface{}, error) {
+		reports := &reports{}
+		upstreamCtx, cancel := context.WithTimeout(reports.context(sharedContext{context.WithoutCancel(ctx)}), l.timeout)
+		defer cancel()
+
+		l.upstream.Add(1)
+		response, err := l.llm.Generate(upstreamCtx, prompt, options...)
+		return &sharedResponse{response: response, reports: reports}, err
+	})
+
+	select {
+	case result := <-ch:
+		if result.Shared {
+			l.shared.Add(1)
+		}
+		shared := result.Val.(*sharedResponse)
+		shared.reports.replay(ctx)
+		if result.Err != nil {
+			return "", result.Err
+		}
+		return shared.response, nil
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// GenerateStream streams the response of the wrapped LLM without deduplication
+func (l *LLM) GenerateStream(ctx context.Context, prompt string, options ...interfaces.GenerateOption) (<-chan interfaces.StreamEvent, error) {
+	return interfaces.GenerateStream(ctx, l.llm, prompt, options...)
+}
+
+// GenerateMultimodal passes the multimodal prompt to the wrapped LLM without
+// deduplication
+func (l *LLM) GenerateMultimodal(ctx context.Context, contents []interfaces.Content, options ...interfaces.GenerateOption) (string, error) {
+	multimodal, ok := l.llm.(interfaces.MultimodalLLM)
+	if !ok {
+		return "", fmt.Errorf("%s does not accept multimodal prompts", l.llm.Name())
+	}
+	return multimodal.GenerateMultimodal(ctx, contents, options...)
+}
+
+// GenerateWithTools is passed through without deduplication, since tool calls
+// may have side effects
+func (l *LLM) GenerateWithTools(ctx context.Context, prompt string, tools []interfaces.Tool, options ...interfaces.GenerateOption) (string, error) {
+	return l.llm.GenerateWithTools(ctx, prompt, tools, options...)
+}
+
+// Name returns the name of the LLM provider
+func (l *LLM) Name() string {
+	return l.llm.Name()
+}
+
+// Unwrap returns the wrapped LLM
+func (l *LLM) Unwrap() interfaces.LLM {
+	return l.llm
+}
+
+// Stats returns the deduplication counters
+func (l *LLM) Stats() Stats {
+	return Stats{
+		Calls:    l.calls.Load(),
+		Upstream: l.upstream.Load(),
+		Shared:   l.shared.Load(),
+	}
+}
+
+// sharedResponse is the result of an upstream request with what it reported
+type sharedResponse struct {
+	response string
+	reports  *reports
+}
+
+// reports collects what an upstream request reports to the context
+// callbacks, to pass it on to every caller sharing the request
+type reports struct {
+	mu        sync.Mutex
+	usage     []interfaces.Usage
+	thinking  []string
+	citations [][]interfaces.Citation
+}
+
+// context returns ctx with callbacks that collect the reports
+func (r *reports) context(ctx context.Context) context.Context {
+	ctx = interfaces.WithUsageCallback(ctx, func(usage interfaces.Usage) {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.usage = append(r.usage, usage)
+	})
+	ctx = interfaces.WithThinkingCallback(ctx, func(thinking string) {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.thinking = append(r.thinking, thinking)
+	})
+	return interfaces.WithCitationsCallback(ctx, func(citations []interfaces.Citation) {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.citations = append(r.citations, citations)
+	})
+}
+
+// replay passes the reports to the callbacks in ctx
+func (r *reports) replay(ctx context.Context) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, usage := range r.usage {
+		interfaces.ReportUsage(ctx, usage)
+	}
+	for _, thinking := range r.thinking {
+		interfaces.ReportThinking(ctx, thinking)
+	}
+	for _, citations := range r.citations {
+		interfaces.ReportCitations(ctx, citations)
+	}
+}
+
+// sharedContext hides the identity of the caller that started a shared
+// request, so that it isn't attributed to that caller alone. The
+// organization is kept, since it is part of the request key.
+type sharedContext struct {
+	context.Context
+}
+
+// Value returns the value of the wrapped context for all but the caller's identity
+func (c sharedContext) Value(key interface{}) interface{} {
+	switch key {
+	case runctx.UserIDKey, runctx.ConversationIDKey, runctx.RequestIDKey, runctx.TraceIDKey, runctx.TagsKey:
+		return nil
+	}
+	return c.Context.Value(key)
+}
+
+// requestKey builds a key from the provider-independent parts of a request
+func requestKey(ctx context.Context, prompt string, options []interfaces.GenerateOption) (string, error) {
+	opts := &interfaces.GenerateOptions{
+		LLMConfig: &interfaces.LLMConfig{},
+	}
+	for _, option := range options {
+		option(opts)
+	}
+
+	if opts.OrgID == "" {
+		opts.OrgID, _ = multitenancy.GetOrgID(ctx)
+	}
+
+	data, err := json.Marshal(struct {
+		Prompt  string                      `json:"prompt"`
+		Options *interfaces.GenerateOptions `json:"options"`
+	}{prompt, opts})
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
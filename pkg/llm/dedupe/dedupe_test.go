@@ -0,0 +1,249 @@
+package dedupe_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+	"github.com/run-bigpig/llm-agent/pkg/llm/dedupe"
+	"github.com/run-bigpig/llm-agent/pkg/multitenancy"
+	"github.com/run-bigpig/llm-agent/pkg/runctx"
+)
+
+// blockingLLM answers once release is closed, or fails when its context is done
+type blockingLLM struct {
+	calls   atomic.Int64
+	release chan struct{}
+}
+
+func (m *blockingLLM) Generate(ctx context.Context, prompt string, options ...interfaces.GenerateOption) (string, error) {
+	m.calls.Add(1)
+	select {
+	case <-m.release:
+		return "answer to " + prompt, nil
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+func (m *blockingLLM) GenerateWithTools(ctx context.Context, prompt string, tools []interfaces.Tool, options ...interfaces.GenerateOption) (string, error) {
+	return m.Generate(ctx, prompt, options...)
+}
+
+func (m *blockingLLM) Name() string { return "blocking" }
+
+// waitForCalls waits until the wrapper has received n calls and they had
+// time to join the upstream request
+func waitForCalls(t *testing.T, llm *dedupe.LLM, n int64) {
+	deadline := time.Now().Add(5 * time.Second)
+	for llm.Stats().Calls < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for %d calls", n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(20 * time.Millisecond)
+}
+
+func TestConcurrentIdenticalCallsShareRequest(t *testing.T) {
+	upstream := &blockingLLM{release: make(chan struct{})}
+	llm := dedupe.New(upstream)
+
+	const callers = 5
+	ctx := multitenancy.WithOrgID(context.Background(), "org-1")
+	responses := make([]string, callers)
+	var wg sync.WaitGroup
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			response, err := llm.Generate(ctx, "What is Go?")
+			if err != nil {
+				t.Errorf("Generate failed: %v", err)
+			}
+			responses[i] = response
+		}(i)
+	}
+
+	// Another organization doesn't share the request
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if _, err := llm.Generate(multitenancy.WithOrgID(context.Background(), "org-2"), "What is Go?"); err != nil {
+			t.Errorf("Generate failed: %v", err)
+		}
+	}()
+
+	waitForCalls(t, llm, callers+1)
+	close(upstream.release)
+	wg.Wait()
+
+	for _, response := range responses {
+		if response != "answer to What is Go?" {
+			t.Errorf("Expected the shared answer, got %q", response)
+		}
+	}
+	if calls := upstream.calls.Load(); calls != 2 {
+		t.Errorf("Expected one upstream request per organization, got %d", calls)
+	}
+	if stats := llm.Stats(); stats.Calls != callers+1 || stats.Upstream != 2 || stats.Shared != callers {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+}
+
+func TestCancelledCallerDoesNotCancelOthers(t *testing.T) {
+	upstream := &blockingLLM{release: make(chan struct{})}
+	llm := dedupe.New(upstream)
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancelledErr := make(chan error, 1)
+	go func() {
+		_, err := llm.Generate(cancelled, "Summarize")
+		cancelledErr <- err
+	}()
+
+	waiting := make(chan string, 1)
+	go func() {
+		response, err := llm.Generate(context.Background(), "Summarize")
+		if err != nil {
+			t.Errorf("Generate failed: %v", err)
+		}
+		waiting <- response
+	}()
+
+	waitForCalls(t, llm, 2)
+	cancel()
+	if err := <-cancelledErr; !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected the cancelled caller to stop waiting, got %v", err)
+	}
+
+	close(upstream.release)
+	if response := <-waiting; response != "answer to Summarize" {
+		t.Errorf("Expected the other caller to get the answer, got %q", response)
+	}
+	if calls := upstream.calls.Load(); calls != 1 {
+		t.Errorf("Expected a single upstream request, got %d", calls)
+	}
+}
+
+func TestSharedRequestTimeout(t *testing.T) {
+	upstream := &blockingLLM{release: make(chan struct{})}
+	llm := dedupe.New(upstream, dedupe.WithTimeout(50*time.Millisecond))
+
+	// The caller has no deadline, but the shared request does
+	_, err := llm.Generate(context.Background(), "Never answered")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the shared request to time out, got %v", err)
+	}
+}
+
+// reportingLLM reports usage and records the user of the request
+type reportingLLM struct {
+	blockingLLM
+	users chan string
+}
+
+func (m *reportingLLM) Generate(ctx context.Context, prompt string, options ...interfaces.GenerateOption) (string, error) {
+	m.users <- runctx.UserID(ctx)
+	response, err := m.blockingLLM.Generate(ctx, prompt, options...)
+	interfaces.ReportUsage(ctx, interfaces.Usage{Model: "gpt-4o", InputTokens: 10, OutputTokens: 5})
+	interfaces.ReportThinking(ctx, "thinking about "+prompt)
+	return response, err
+}
+
+func TestSharedRequestReportsToEveryCaller(t *testing.T) {
+	upstream := &reportingLLM{blockingLLM: blockingLLM{release: make(chan struct{})}, users: make(chan string, 2)}
+	llm := dedupe.New(upstream)
+
+	var mu sync.Mutex
+	usage := map[string]interfaces.Usage{}
+	thinking := map[string]string{}
+	var wg sync.WaitGroup
+	for _, user := range []string{"alice", "bob"} {
+		ctx := runctx.WithUserID(multitenancy.WithOrgID(context.Background(), "org-1"), user)
+		ctx = interfaces.WithUsageCallback(ctx, func(u interfaces.Usage) {
+			mu.Lock()
+			defer mu.Unlock()
+			usage[user] = u
+		})
+		ctx = interfaces.WithThinkingCallback(ctx, func(text string) {
+			mu.Lock()
+			defer mu.Unlock()
+			thinking[user] = text
+		})
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := llm.Generate(ctx, "What is Go?"); err != nil {
+				t.Errorf("Generate failed: %v", err)
+			}
+		}()
+	}
+
+	waitForCalls(t, llm, 2)
+	close(upstream.release)
+	wg.Wait()
+
+	if user := <-upstream.users; user != "" {
+		t.Errorf("Expected the shared request not to carry a caller's user, got %q", user)
+	}
+	if calls := upstream.calls.Load(); calls != 1 {
+		t.Fatalf("Expected a single upstream request, got %d", calls)
+	}
+	for _, user := range []string{"alice", "bob"} {
+		if usage[user].InputTokens != 10 || usage[user].Model != "gpt-4o" {
+			t.Errorf("Expected %s to get the usage of the shared request, got %+v", user, usage[user])
+		}
+		if thinking[user] != "thinking about What is Go?" {
+			t.Errorf("Expected %s to get the thinking of the shared request, got %q", user, thinking[user])
+		}
+	}
+}
+
+// streamingLLM streams a fixed response and reports its capabilities
+type streamingLLM struct {
+	blockingLLM
+}
+
+func (m *streamingLLM) GenerateStream(ctx context.Context, prompt string, options ...interfaces.GenerateOption) (<-chan interfaces.StreamEvent, error) {
+	events := make(chan interfaces.StreamEvent, 2)
+	events <- interfaces.StreamEvent{Type: interfaces.StreamEventText, Content: "streamed"}
+	events <- interfaces.StreamEvent{Type: interfaces.StreamEventDone}
+	close(events)
+	return events, nil
+}
+
+func (m *streamingLLM) Capabilities() interfaces.Capabilities {
+	return interfaces.Capabilities{Streaming: true, Vision: true}
+}
+
+func TestWrappingKeepsInterfaces(t *testing.T) {
+	var wrapped interfaces.LLM = dedupe.New(&streamingLLM{})
+
+	streaming, ok := wrapped.(interfaces.StreamingLLM)
+	if !ok {
+		t.Fatal("Expected the wrapper to implement StreamingLLM")
+	}
+	events, err := streaming.GenerateStream(context.Background(), "Hello")
+	if err != nil {
+		t.Fatalf("GenerateStream failed: %v", err)
+	}
+	if response, err := interfaces.CollectStream(events); err != nil || response != "streamed" {
+		t.Errorf("Expected the wrapped LLM's stream, got %q (%v)", response, err)
+	}
+
+	if capabilities, ok := interfaces.GetCapabilities(wrapped); !ok || !capabilities.Streaming || !capabilities.Vision {
+		t.Errorf("Expected the wrapped LLM's capabilities, got %+v (%v)", capabilities, ok)
+	}
+
+	// LLMs that don't accept multimodal prompts report an error
+	multimodal := wrapped.(interfaces.MultimodalLLM)
+	if _, err := multimodal.GenerateMultimodal(context.Background(), nil); err == nil {
+		t.Error("Expected an error for an LLM without multimodal support")
+	}
+}
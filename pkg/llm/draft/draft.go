@@ -0,0 +1,183 @@
+// Package draft speeds up long generations with two models: a small model
+// writes a draft and a large model verifies it, keeping the paragraphs it
+// accepts and rewriting the rest. When the draft is good, the large model
+// writes only a few tokens, which cuts latency and cost for long outputs on
+// providers without speculative decoding.
+package draft
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+)
+
+// acceptAll is the verifier's answer for a draft that needs no changes
+const acceptAll = "ACCEPT"
+
+// Stats counts how drafts were verified
+type Stats struct {
+	// Calls is the number of Generate calls
+	Calls int64 `json:"calls"`
+
+	// Accepted is the number of drafts accepted without changes
+	Accepted int64 `json:"accepted"`
+
+	// Edited is the number of drafts whose first paragraphs were kept and
+	// the rest rewritten
+	Edited int64 `json:"edited"`
+
+	// Rewritten is the number of drafts replaced entirely, including
+	// verifier answers that could not be parsed
+	Rewritten int64 `json:"rewritten"`
+
+	// DraftFailures is the number of calls answered by the target model
+	// alone because the draft failed
+	DraftFailures int64 `json:"draft_failures"`
+
+	// KeptParagraphs is the number of draft paragraphs in final responses
+	KeptParagraphs int64 `json:"kept_paragraphs"`
+}
+
+// LLM generates with a draft model and verifies with a target model
+type LLM struct {
+	draft  interfaces.LLM
+	target interfaces.LLM
+
+	calls         atomic.Int64
+	accepted      atomic.Int64
+	edited        atomic.Int64
+	rewritten     atomic.Int64
+	draftFailures atomic.Int64
+	kept          atomic.Int64
+}
+
+// New creates an LLM that drafts with the draft model and verifies with the
+// target model. Responses have the quality of the target model as long as
+// it verifies faithfully.
+func New(draft, target interfaces.LLM) *LLM {
+	return &LLM{draft: draft, target: target}
+}
+
+// Generate drafts a response and has the target model verify it. If the
+// draft fails, the target model answers on its own.
+func (l *LLM) Generate(ctx context.Context, prompt string, options ...interfaces.GenerateOption) (string, error) {
+	l.calls.Add(1)
+
+	draft, err := l.draft.Generate(ctx, prompt, options...)
+	if err != nil || strings.TrimSpace(draft) == "" {
+		l.draftFailures.Add(1)
+		return l.target.Generate(ctx, prompt, options...)
+	}
+
+	paragraphs := splitParagraphs(draft)
+	verdict, err := l.target.Generate(ctx, verifyPrompt(prompt, paragraphs), options...)
+	if err != nil {
+		return "", fmt.Errorf("failed to verify draft: %w", err)
+	}
+
+	keep, continuation, ok := parseVerdict(verdict, len(paragraphs))
+	switch {
+	case !ok:
+		// The verifier answered with a response of its own
+		l.rewritten.Add(1)
+		return strings.TrimSpace(verdict), nil
+	case keep == len(paragraphs):
+		l.accepted.Add(1)
+	case keep == 0:
+		l.rewritten.Add(1)
+	default:
+		l.edited.Add(1)
+	}
+	l.kept.Add(int64(keep))
+
+	parts := append([]string{}, paragraphs[:keep]...)
+	if continuation != "" {
+		parts = append(parts, continuation)
+	}
+	return strings.Join(parts, "\n\n"), nil
+}
+
+// GenerateWithTools is passed to the target model, since drafting would call
+// the tools twice
+func (l *LLM) GenerateWithTools(ctx context.Context, prompt string, tools []interfaces.Tool, options ...interfaces.GenerateOption) (string, error) {
+	return l.target.GenerateWithTools(ctx, prompt, tools, options...)
+}
+
+// Name returns the name of the target model's provider
+func (l *LLM) Name() string {
+	return l.target.Name()
+}
+
+// Unwrap returns the target model
+func (l *LLM) Unwrap() interfaces.LLM {
+	return l.target
+}
+
+// Stats returns the verification counters
+func (l *LLM) Stats() Stats {
+	return Stats{
+		Calls:          l.calls.Load(),
+		Accepted:       l.accepted.Load(),
+		Edited:         l.edited.Load(),
+		Rewritten:      l.rewritten.Load(),
+		DraftFailures:  l.draftFailures.Load(),
+		KeptParagraphs: l.kept.Load(),
+	}
+}
+
+// splitParagraphs splits a response at blank lines
+func splitParagraphs(text string) []string {
+	var paragraphs []string
+	for _, paragraph := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n\n") {
+		if paragraph = strings.TrimSpace(paragraph); paragraph != "" {
+			paragraphs = append(paragraphs, paragraph)
+		}
+	}
+	return paragraphs
+}
+
+// verifyPrompt asks the target model to check a draft paragraph by paragraph
+func verifyPrompt(prompt string, paragraphs []string) string {
+	var sb strings.Builder
+	for i, paragraph := range paragraphs {
+		fmt.Fprintf(&sb, "[%d]\n%s\n\n", i+1, paragraph)
+	}
+
+	return fmt.Sprintf(`A draft answer to the request below was written by a smaller model. Check it paragraph by paragraph as if you had written the answer yourself.
+
+If the whole draft is correct and as good as your own answer, reply with %s and nothing else.
+Otherwise reply with the number of the first paragraph that needs changes on the first line, followed by your answer from that paragraph to the end, without paragraph numbers. The paragraphs before it are kept as they are.
+
+<<<BEGIN REQUEST>>>
+%s
+<<<END REQUEST>>>
+
+<<<BEGIN DRAFT>>>
+%s<<<END DRAFT>>>`, acceptAll, prompt, sb.String())
+}
+
+// parseVerdict returns the number of draft paragraphs to keep and the text
+// that replaces the rest. It reports false if the verdict doesn't follow the
+// requested format.
+func parseVerdict(verdict string, paragraphs int) (int, string, bool) {
+	verdict = strings.TrimSpace(verdict)
+	if strings.EqualFold(strings.Trim(verdict, ".`*\" "), acceptAll) {
+		return paragraphs, "", true
+	}
+
+	first, rest, _ := strings.Cut(verdict, "\n")
+	first = strings.Trim(strings.TrimSpace(first), "[]().:")
+	n, err := strconv.Atoi(first)
+	if err != nil || n < 1 || n > paragraphs {
+		return 0, "", false
+	}
+	rest = strings.TrimSpace(rest)
+	if rest == "" {
+		return 0, "", false
+	}
+	return n - 1, rest, true
+}
@@ -0,0 +1,79 @@
+package draft_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+	"github.com/run-bigpig/llm-agent/pkg/llm/draft"
+)
+
+// fixedLLM answers every prompt with the same response and records the
+// prompts
+type fixedLLM struct {
+	response string
+	err      error
+	prompts  []string
+}
+
+func (m *fixedLLM) Generate(ctx context.Context, prompt string, options ...interfaces.GenerateOption) (string, error) {
+	m.prompts = append(m.prompts, prompt)
+	return m.response, m.err
+}
+
+func (m *fixedLLM) GenerateWithTools(ctx context.Context, prompt string, tools []interfaces.Tool, options ...interfaces.GenerateOption) (string, error) {
+	return m.Generate(ctx, prompt, options...)
+}
+
+func (m *fixedLLM) Name() string { return "fixed" }
+
+const drafted = "Paris is the capital of France.\n\nIt has 20 million inhabitants.\n\nThe Eiffel Tower is there."
+
+func TestAcceptedDraft(t *testing.T) {
+	target := &fixedLLM{response: "ACCEPT"}
+	llm := draft.New(&fixedLLM{response: drafted}, target)
+
+	response, err := llm.Generate(context.Background(), "Tell me about Paris")
+	require.NoError(t, err)
+	assert.Equal(t, drafted, response)
+
+	require.Len(t, target.prompts, 1)
+	assert.Contains(t, target.prompts[0], "<<<BEGIN REQUEST>>>\nTell me about Paris\n<<<END REQUEST>>>")
+	assert.Contains(t, target.prompts[0], "[2]\nIt has 20 million inhabitants.\n")
+	assert.Equal(t, draft.Stats{Calls: 1, Accepted: 1, KeptParagraphs: 3}, llm.Stats())
+}
+
+func TestEditedDraft(t *testing.T) {
+	target := &fixedLLM{response: "2\nIt has about 2 million inhabitants.\n\nThe Eiffel Tower is there."}
+	llm := draft.New(&fixedLLM{response: drafted}, target)
+
+	response, err := llm.Generate(context.Background(), "Tell me about Paris")
+	require.NoError(t, err)
+	assert.Equal(t, "Paris is the capital of France.\n\nIt has about 2 million inhabitants.\n\nThe Eiffel Tower is there.", response)
+	assert.Equal(t, draft.Stats{Calls: 1, Edited: 1, KeptParagraphs: 1}, llm.Stats())
+}
+
+func TestUnparsableVerdictIsTheResponse(t *testing.T) {
+	llm := draft.New(&fixedLLM{response: drafted}, &fixedLLM{response: "Paris, the capital of France, has about 2 million inhabitants."})
+
+	response, err := llm.Generate(context.Background(), "Tell me about Paris")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(response, "Paris, the capital"))
+	assert.Equal(t, int64(1), llm.Stats().Rewritten)
+}
+
+func TestFailedDraftFallsBackToTarget(t *testing.T) {
+	target := &fixedLLM{response: "Paris is the capital of France."}
+	llm := draft.New(&fixedLLM{err: errors.New("overloaded")}, target)
+
+	response, err := llm.Generate(context.Background(), "Tell me about Paris")
+	require.NoError(t, err)
+	assert.Equal(t, "Paris is the capital of France.", response)
+	assert.Equal(t, []string{"Tell me about Paris"}, target.prompts)
+	assert.Equal(t, int64(1), llm.Stats().DraftFailures)
+}
@@ -4,14 +4,17 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net/http"
 	"strings"
-	"sync"
+	"time"
 
 	"github.com/run-bigpig/llm-agent/pkg/interfaces"
 	"github.com/run-bigpig/llm-agent/pkg/llm"
 	"github.com/run-bigpig/llm-agent/pkg/logging"
 	"github.com/run-bigpig/llm-agent/pkg/multitenancy"
+	"github.com/run-bigpig/llm-agent/pkg/parallel"
 	"github.com/run-bigpig/llm-agent/pkg/retry"
+	"github.com/run-bigpig/llm-agent/pkg/runctx"
 	"github.com/sashabaranov/go-openai"
 )
 
@@ -23,10 +26,19 @@ const organizationKey contextKey = "organization"
 
 // OpenAIClient implements the LLM interface for OpenAI
 type OpenAIClient struct {
-	Client        *openai.Client
-	Model         string
-	logger        logging.Logger
-	retryExecutor *retry.Executor
+	Client         *openai.Client
+	Model          string
+	logger         logging.Logger
+	retryExecutor  *retry.Executor
+	httpClient     *http.Client
+	requestTimeout time.Duration
+
+	maxToolIterations int
+
+	strictTools     bool
+	strictToolNames map[string]bool
+
+	constraintDialect ConstraintDialect
 }
 
 // Option represents an option for configuring the OpenAI client
@@ -53,6 +65,35 @@ func WithRetry(opts ...retry.Option) Option {
 	}
 }
 
+// WithHTTPClient sets the HTTP client used for API requests, e.g. to log
+// wire-level traffic with the wirelog package
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *OpenAIClient) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithMaxToolIterations sets the maximum number of tool calling rounds in
+// GenerateWithTools before the model is asked for a final answer
+func WithMaxToolIterations(maxIterations int) Option {
+	return func(c *OpenAIClient) {
+		c.maxToolIterations = maxIterations
+	}
+}
+
+// DefaultRequestTimeout is how long a call may take unless the client or the
+// call sets another timeout
+const DefaultRequestTimeout = 2 * time.Minute
+
+// WithDefaultRequestTimeout sets how long a call may take unless it sets its
+// own timeout with interfaces.WithRequestTimeout. Zero means no limit other
+// than the deadline of the caller's context.
+func WithDefaultRequestTimeout(timeout time.Duration) Option {
+	return func(c *OpenAIClient) {
+		c.requestTimeout = timeout
+	}
+}
+
 // NewClient creates a new OpenAI client
 func NewClient(baseUrl, apiKey string, options ...Option) *OpenAIClient {
 	if baseUrl == "" {
@@ -62,9 +103,10 @@ func NewClient(baseUrl, apiKey string, options ...Option) *OpenAIClient {
 	config.BaseURL = baseUrl
 	// Create client with default options
 	client := &OpenAIClient{
-		Client: openai.NewClientWithConfig(config),
-		Model:  "gpt-4o-mini",
-		logger: logging.New(),
+		Model:             "gpt-4o-mini",
+		logger:            logging.New(),
+		requestTimeout:    DefaultRequestTimeout,
+		maxToolIterations: 10,
 	}
 
 	// Apply options
@@ -72,6 +114,13 @@ func NewClient(baseUrl, apiKey string, options ...Option) *OpenAIClient {
 		option(client)
 	}
 
+	httpClient := http.DefaultClient
+	if client.httpClient != nil {
+		httpClient = client.httpClient
+	}
+	config.HTTPClient = withExtraBody(httpClient)
+	client.Client = openai.NewClientWithConfig(config)
+
 	return client
 }
 
@@ -88,6 +137,84 @@ func (c *OpenAIClient) Generate(ctx context.Context, prompt string, options ...i
 		option(params)
 	}
 
+	// Limit the call to its timeout
+	ctx, cancel := interfaces.RequestContext(ctx, params.Timeout, c.requestTimeout)
+	defer cancel()
+
+	ctx, req, err := c.chatCompletionRequest(ctx, prompt, params)
+	if err != nil {
+		return "", err
+	}
+
+	return c.createChatCompletion(ctx, req, params)
+}
+
+// createChatCompletion sends the request of a Generate call and returns the
+// text of the response
+func (c *OpenAIClient) createChatCompletion(ctx context.Context, req openai.ChatCompletionRequest, params *interfaces.GenerateOptions) (string, error) {
+	var resp openai.ChatCompletionResponse
+	var err error
+
+	operation := func() error {
+		var reasoningMode string
+		if params.LLMConfig != nil && params.LLMConfig.Reasoning != "" {
+			reasoningMode = params.LLMConfig.Reasoning
+		} else {
+			reasoningMode = "none"
+		}
+
+		c.logger.Debug(ctx, "Executing OpenAI API request", map[string]interface{}{
+			"model":             c.Model,
+			"temperature":       req.Temperature,
+			"top_p":             req.TopP,
+			"frequency_penalty": req.FrequencyPenalty,
+			"presence_penalty":  req.PresencePenalty,
+			"stop_sequences":    req.Stop,
+			"messages":          len(req.Messages),
+			"response_format":   req.ResponseFormat != nil,
+			"reasoning":         reasoningMode,
+		})
+
+		resp, err = c.Client.CreateChatCompletion(ctx, req)
+		if err != nil {
+			c.logger.Error(ctx, "Error from OpenAI API", map[string]interface{}{
+				"error": err.Error(),
+				"model": c.Model,
+			})
+			return fmt.Errorf("failed to generate text: %w", err)
+		}
+		return nil
+	}
+
+	if c.retryExecutor != nil {
+		c.logger.Debug(ctx, "Using retry mechanism for OpenAI request", map[string]interface{}{
+			"model": c.Model,
+		})
+		err = c.retryExecutor.Execute(ctx, operation)
+	} else {
+		err = operation()
+	}
+
+	if err != nil {
+		return "", err
+	}
+	reportUsage(ctx, resp.Model, resp.Usage)
+
+	// Return response
+	if len(resp.Choices) > 0 {
+		c.logger.Debug(ctx, "Successfully received response from OpenAI", map[string]interface{}{
+			"model": c.Model,
+		})
+		return resp.Choices[0].Message.Content, nil
+	}
+
+	return "", fmt.Errorf("no response from OpenAI API")
+}
+
+// chatCompletionRequest builds the request of a Generate call. The returned
+// context carries the organization ID and the fields of the constraint that
+// the request type doesn't have.
+func (c *OpenAIClient) chatCompletionRequest(ctx context.Context, prompt string, params *interfaces.GenerateOptions) (context.Context, openai.ChatCompletionRequest, error) {
 	// Get organization ID from context if available
 	orgID, _ := multitenancy.GetOrgID(ctx)
 	if orgID != "" {
@@ -165,6 +292,7 @@ func (c *OpenAIClient) Generate(ctx context.Context, prompt string, options ...i
 		req.FrequencyPenalty = float32(params.LLMConfig.FrequencyPenalty)
 		req.PresencePenalty = float32(params.LLMConfig.PresencePenalty)
 		req.Stop = params.LLMConfig.StopSequences
+		setMaxTokens(&req, params.LLMConfig.MaxTokens)
 	}
 
 	// Set response format if provided
@@ -179,67 +307,16 @@ func (c *OpenAIClient) Generate(ctx context.Context, prompt string, options ...i
 		c.logger.Debug(ctx, "Using response format", map[string]interface{}{"format": *params.ResponseFormat})
 	}
 
-	// Set organization ID if available
-	if orgID, ok := ctx.Value(organizationKey).(string); ok && orgID != "" {
+	// Identify the end user, falling back to the organization ID
+	if userID := runctx.UserID(ctx); userID != "" {
+		req.User = userID
+	} else if orgID, ok := ctx.Value(organizationKey).(string); ok && orgID != "" {
 		req.User = orgID
 	}
 
-	var resp openai.ChatCompletionResponse
-	var err error
-
-	operation := func() error {
-		var reasoningMode string
-		if params.LLMConfig != nil && params.LLMConfig.Reasoning != "" {
-			reasoningMode = params.LLMConfig.Reasoning
-		} else {
-			reasoningMode = "none"
-		}
-
-		c.logger.Debug(ctx, "Executing OpenAI API request", map[string]interface{}{
-			"model":             c.Model,
-			"temperature":       req.Temperature,
-			"top_p":             req.TopP,
-			"frequency_penalty": req.FrequencyPenalty,
-			"presence_penalty":  req.PresencePenalty,
-			"stop_sequences":    req.Stop,
-			"messages":          len(req.Messages),
-			"response_format":   req.ResponseFormat != nil,
-			"reasoning":         reasoningMode,
-		})
-
-		resp, err = c.Client.CreateChatCompletion(ctx, req)
-		if err != nil {
-			c.logger.Error(ctx, "Error from OpenAI API", map[string]interface{}{
-				"error": err.Error(),
-				"model": c.Model,
-			})
-			return fmt.Errorf("failed to generate text: %w", err)
-		}
-		return nil
-	}
-
-	if c.retryExecutor != nil {
-		c.logger.Debug(ctx, "Using retry mechanism for OpenAI request", map[string]interface{}{
-			"model": c.Model,
-		})
-		err = c.retryExecutor.Execute(ctx, operation)
-	} else {
-		err = operation()
-	}
-
-	if err != nil {
-		return "", err
-	}
-
-	// Return response
-	if len(resp.Choices) > 0 {
-		c.logger.Debug(ctx, "Successfully received response from OpenAI", map[string]interface{}{
-			"model": c.Model,
-		})
-		return resp.Choices[0].Message.Content, nil
-	}
-
-	return "", fmt.Errorf("no response from OpenAI API")
+	// Constrain decoding if requested
+	ctx, err := c.applyConstraint(ctx, &req, params.Constraint)
+	return ctx, req, err
 }
 
 // Chat uses the ChatCompletion API to have a conversation (messages) with a model
@@ -248,6 +325,9 @@ func (c *OpenAIClient) Chat(ctx context.Context, messages []llm.Message, params
 		params = llm.DefaultGenerateParams()
 	}
 
+	ctx, cancel := interfaces.RequestContext(ctx, 0, c.requestTimeout)
+	defer cancel()
+
 	// Handle reasoning if specified
 	var systemMessage string
 	var hasSystemMessage bool
@@ -321,7 +401,9 @@ func (c *OpenAIClient) Chat(ctx context.Context, messages []llm.Message, params
 		FrequencyPenalty: float32(params.FrequencyPenalty),
 		PresencePenalty:  float32(params.PresencePenalty),
 		Stop:             params.StopSequences,
+		User:             runctx.UserID(ctx),
 	}
+	setMaxTokens(&req, params.MaxTokens)
 
 	var resp openai.ChatCompletionResponse
 	var err error
@@ -361,6 +443,7 @@ func (c *OpenAIClient) Chat(ctx context.Context, messages []llm.Message, params
 	if err != nil {
 		return "", err
 	}
+	reportUsage(ctx, resp.Model, resp.Usage)
 
 	if len(resp.Choices) == 0 {
 		return "", fmt.Errorf("no completions returned")
@@ -383,6 +466,10 @@ func (c *OpenAIClient) GenerateWithTools(ctx context.Context, prompt string, too
 		}
 	}
 
+	// Limit the call to its timeout
+	ctx, cancel := interfaces.RequestContext(ctx, params.Timeout, c.requestTimeout)
+	defer cancel()
+
 	// Set default values only if they're not provided
 	if params.LLMConfig == nil {
 		params.LLMConfig = &interfaces.LLMConfig{
@@ -402,6 +489,7 @@ func (c *OpenAIClient) GenerateWithTools(ctx context.Context, prompt string, too
 
 	// Convert tools to OpenAI format
 	openaiTools := make([]openai.Tool, len(tools))
+	strict := false
 	for i, tool := range tools {
 		// Convert ParameterSpec to JSON Schema
 		properties := make(map[string]interface{})
@@ -443,6 +531,17 @@ func (c *OpenAIClient) GenerateWithTools(ctx context.Context, prompt string, too
 				},
 			},
 		}
+
+		// Tighten the schema for strict mode
+		if c.isStrict(tool.Name()) {
+			if schema, ok := strictSchema(tool.Parameters()); ok {
+				openaiTools[i].Function.Parameters = schema
+				openaiTools[i].Function.Strict = true
+				strict = true
+			} else {
+				c.logger.Warn(ctx, "Tool parameters can't be described strictly, sending tool without strict mode", map[string]interface{}{"toolName": tool.Name()})
+			}
+		}
 	}
 
 	// Create messages array with system message if provided
@@ -514,6 +613,20 @@ func (c *OpenAIClient) GenerateWithTools(ctx context.Context, prompt string, too
 		PresencePenalty:   float32(params.LLMConfig.PresencePenalty),
 		Stop:              params.LLMConfig.StopSequences,
 		ParallelToolCalls: true,
+		User:              runctx.UserID(ctx),
+	}
+
+	setMaxTokens(&req, params.LLMConfig.MaxTokens)
+
+	// Strict mode isn't enforced for parallel tool calls
+	if strict {
+		req.ParallelToolCalls = false
+	}
+
+	// Constrain decoding if requested
+	ctx, err := c.applyConstraint(ctx, &req, params.Constraint)
+	if err != nil {
+		return "", err
 	}
 
 	// Set response format if provided
@@ -549,21 +662,31 @@ func (c *OpenAIClient) GenerateWithTools(ctx context.Context, prompt string, too
 		"parallel_tools":    req.ParallelToolCalls,
 		"reasoning":         reasoningMode,
 	})
-	resp, err := c.Client.CreateChatCompletion(ctx, req)
-	if err != nil {
-		c.logger.Error(ctx, "Error from OpenAI API", map[string]interface{}{"error": err.Error()})
-		return "", fmt.Errorf("failed to create chat completion: %w", err)
-	}
 
-	if len(resp.Choices) == 0 {
-		return "", fmt.Errorf("no completions returned")
-	}
+	// Re-submit the tool results until the model answers without calling
+	// tools
+	for iteration := 0; ; iteration++ {
+		resp, err := c.Client.CreateChatCompletion(ctx, req)
+		if err != nil {
+			c.logger.Error(ctx, "Error from OpenAI API", map[string]interface{}{"error": err.Error()})
+			return "", fmt.Errorf("failed to create chat completion: %w", err)
+		}
+		reportUsage(ctx, resp.Model, resp.Usage)
+
+		if len(resp.Choices) == 0 {
+			return "", fmt.Errorf("no completions returned")
+		}
 
-	// Check if the model wants to use tools
-	if len(resp.Choices[0].Message.ToolCalls) > 0 {
-		// The model wants to use tools
 		toolCalls := resp.Choices[0].Message.ToolCalls
-		c.logger.Info(ctx, "Processing tool calls", map[string]interface{}{"count": len(toolCalls)})
+		if len(toolCalls) == 0 {
+			return strings.TrimSpace(resp.Choices[0].Message.Content), nil
+		}
+		if iteration >= c.maxToolIterations {
+			return "", fmt.Errorf("model kept calling tools after %d iterations", c.maxToolIterations)
+		}
+
+		// The model wants to use tools
+		c.logger.Info(ctx, "Processing tool calls", map[string]interface{}{"count": len(toolCalls), "iteration": iteration + 1})
 
 		// Replace multi_tool_use.parallel name if present
 		for i := range toolCalls {
@@ -574,206 +697,161 @@ func (c *OpenAIClient) GenerateWithTools(ctx context.Context, prompt string, too
 			}
 		}
 
-		// Create a new conversation with the initial messages
-		messages := []openai.ChatCompletionMessage{
-			{Role: "user", Content: prompt},
-			resp.Choices[0].Message,
+		// Continue the conversation with the tool calls and their results
+		toolMessages, err := c.executeToolCalls(ctx, toolCalls, tools)
+		if err != nil {
+			return "", err
 		}
+		req.Messages = append(req.Messages, resp.Choices[0].Message)
+		req.Messages = append(req.Messages, toolMessages...)
 
-		// Process each tool call
-		for _, toolCall := range toolCalls {
-
-			if toolCall.Function.Name == "parallel_tool_use" {
-				c.logger.Info(ctx, "Parallel tool call", map[string]interface{}{"toolName": toolCall.Function.Name})
+		// Once the iteration limit is reached, ask for an answer without
+		// further tool calls
+		if iteration+1 >= c.maxToolIterations {
+			req.ToolChoice = "none"
+		}
 
-				arguments := toolCall.Function.Arguments
-				var toolUsesWrapper struct {
-					ToolUses []map[string]interface{} `json:"tool_uses"`
-				}
-				err := json.Unmarshal([]byte(arguments), &toolUsesWrapper)
-				if err != nil {
-					c.logger.Error(ctx, "Error unmarshalling tool uses", map[string]interface{}{"error": err.Error()})
-					continue
-				}
+		c.logger.Info(ctx, "Sending request with tool results", map[string]interface{}{
+			"model":     c.Model,
+			"messages":  len(req.Messages),
+			"iteration": iteration + 1,
+		})
+	}
+}
 
-				type toolResult struct {
-					index  int
-					result string
-					err    error
-				}
+// executeToolCalls executes the tool calls of a response and returns the
+// tool messages with their results
+func (c *OpenAIClient) executeToolCalls(ctx context.Context, toolCalls []openai.ToolCall, tools []interfaces.Tool) ([]openai.ChatCompletionMessage, error) {
+	var messages []openai.ChatCompletionMessage
 
-				resultCh := make(chan toolResult, len(toolUsesWrapper.ToolUses))
-				var wg sync.WaitGroup
-
-				// Launch goroutines for concurrent tool execution
-				for i, toolUse := range toolUsesWrapper.ToolUses {
-					wg.Add(1)
-					go func(index int, toolUse map[string]interface{}) {
-						defer wg.Done()
-
-						toolName := toolUse["recipient_name"].(string)
-						parameters := toolUse["parameters"].(map[string]interface{})
-
-						c.logger.Info(ctx, "Parallel tool use", map[string]interface{}{"toolName": toolName, "parameters": parameters})
-
-						// Convert parameters to JSON string
-						paramsBytes, err := json.Marshal(parameters)
-						if err != nil {
-							c.logger.Error(ctx, "Error marshalling parameters", map[string]interface{}{"error": err.Error()})
-							resultCh <- toolResult{index: index, result: "", err: err}
-							return
-						}
-
-						// Find the correct tool for this operation
-						var tool interfaces.Tool
-						for _, t := range tools {
-							if t.Name() == toolName {
-								tool = t
-								break
-							}
-						}
-
-						if tool == nil {
-							err := fmt.Errorf("tool not found: %s", toolName)
-							c.logger.Error(ctx, "Tool not found in parallel execution", map[string]interface{}{"toolName": toolName})
-							resultCh <- toolResult{index: index, result: "", err: err}
-							return
-						}
-
-						c.logger.Info(ctx, "Executing tool", map[string]interface{}{"toolName": toolName, "parameters": string(paramsBytes)})
-
-						result, err := tool.Execute(ctx, string(paramsBytes))
-						resultCh <- toolResult{index: index, result: result, err: err}
-					}(i, toolUse)
-				}
+	// Process each tool call
+	for _, toolCall := range toolCalls {
 
-				// Close channel when all goroutines complete
-				go func() {
-					wg.Wait()
-					close(resultCh)
-				}()
-
-				// Collect results and check for errors
-				toolsResults := make([]string, len(toolUsesWrapper.ToolUses))
-				for result := range resultCh {
-					if result.err != nil {
-						c.logger.Error(ctx, "Error executing tool", map[string]interface{}{"error": result.err.Error()})
-						return "", fmt.Errorf("error executing tool: %s", result.err.Error())
-					}
-					toolsResults[result.index] = result.result
-				}
+		if toolCall.Function.Name == "parallel_tool_use" {
+			c.logger.Info(ctx, "Parallel tool call", map[string]interface{}{"toolName": toolCall.Function.Name})
 
+			arguments := toolCall.Function.Arguments
+			var toolUsesWrapper struct {
+				ToolUses []map[string]interface{} `json:"tool_uses"`
+			}
+			err := json.Unmarshal([]byte(arguments), &toolUsesWrapper)
+			if err != nil {
+				c.logger.Error(ctx, "Error unmarshalling tool uses", map[string]interface{}{"error": err.Error()})
+				// Every tool call needs a response, so report the error to the model
 				messages = append(messages, openai.ChatCompletionMessage{
 					Role:       "tool",
-					Content:    strings.Join(toolsResults, "\n"),
+					Content:    fmt.Sprintf("Error: invalid parallel_tool_use arguments: %v", err),
 					ToolCallID: toolCall.ID,
 					Name:       "parallel_tool_use",
 				})
 				continue
 			}
 
-			// Find the requested tool
-			var selectedTool interfaces.Tool
-			for _, tool := range tools {
-				if tool.Name() == toolCall.Function.Name {
-					selectedTool = tool
-					break
+			// Execute the tool uses concurrently, stopping at the first error
+			toolsResults, err := parallel.Map(ctx, toolUsesWrapper.ToolUses, func(ctx context.Context, index int, toolUse map[string]interface{}) (string, error) {
+				toolName, _ := toolUse["recipient_name"].(string)
+				parameters, _ := toolUse["parameters"].(map[string]interface{})
+
+				c.logger.Info(ctx, "Parallel tool use", map[string]interface{}{"toolName": toolName, "parameters": parameters})
+
+				// Convert parameters to JSON string
+				paramsBytes, err := json.Marshal(parameters)
+				if err != nil {
+					c.logger.Error(ctx, "Error marshalling parameters", map[string]interface{}{"error": err.Error()})
+					return "", err
 				}
-			}
 
-			if selectedTool == nil || selectedTool.Name() == "" {
-				c.logger.Error(ctx, "Tool not found", map[string]interface{}{
-					"toolName": toolCall.Function.Name,
-					"toolcall": toolCall,
-					"resp":     resp,
-				})
-				return "", fmt.Errorf("tool not found: %s", toolCall.Function.Name)
-			}
+				// Find the correct tool for this operation
+				var tool interfaces.Tool
+				for _, t := range tools {
+					if t.Name() == toolName {
+						tool = t
+						break
+					}
+				}
+
+				if tool == nil {
+					c.logger.Error(ctx, "Tool not found in parallel execution", map[string]interface{}{"toolName": toolName})
+					return "", fmt.Errorf("tool not found: %s", toolName)
+				}
+
+				c.logger.Info(ctx, "Executing tool", map[string]interface{}{"toolName": toolName, "parameters": string(paramsBytes)})
 
-			// Execute the tool
-			c.logger.Info(ctx, "Executing tool", map[string]interface{}{"toolName": selectedTool.Name()})
-			toolResult, err := selectedTool.Execute(ctx, toolCall.Function.Arguments)
+				return tool.Execute(ctx, string(paramsBytes))
+			})
+			content := strings.Join(toolsResults, "\n")
 			if err != nil {
-				c.logger.Error(ctx, "Error executing tool", map[string]interface{}{"toolName": selectedTool.Name(), "error": err.Error()})
-				// Add error message as tool response
-				messages = append(messages, openai.ChatCompletionMessage{
-					Role:       "tool",
-					Content:    fmt.Sprintf("Error: %v", err),
-					Name:       selectedTool.Name(),
-					ToolCallID: toolCall.ID,
-				})
-				continue
+				// Like a failed single tool call, the error is the tool result
+				c.logger.Error(ctx, "Error executing tool", map[string]interface{}{"error": err.Error()})
+				content = fmt.Sprintf("Error: %v", err)
 			}
 
-			// Add tool result to messages
 			messages = append(messages, openai.ChatCompletionMessage{
 				Role:       "tool",
-				Content:    toolResult,
-				Name:       selectedTool.Name(),
+				Content:    content,
 				ToolCallID: toolCall.ID,
+				Name:       "parallel_tool_use",
 			})
+			continue
 		}
 
-		// Get the final response
-		var reasoningMode string
-		if params.LLMConfig != nil && params.LLMConfig.Reasoning != "" {
-			reasoningMode = params.LLMConfig.Reasoning
-		} else {
-			reasoningMode = "none"
+		// Find the requested tool
+		var selectedTool interfaces.Tool
+		for _, tool := range tools {
+			if tool.Name() == toolCall.Function.Name {
+				selectedTool = tool
+				break
+			}
 		}
 
-		c.logger.Info(ctx, "Sending final request with tool results", map[string]interface{}{
-			"model":             c.Model,
-			"temperature":       req.Temperature,
-			"top_p":             req.TopP,
-			"frequency_penalty": req.FrequencyPenalty,
-			"presence_penalty":  req.PresencePenalty,
-			"stop_sequences":    req.Stop,
-			"messages":          len(messages),
-			"reasoning":         reasoningMode,
-		})
+		if selectedTool == nil || selectedTool.Name() == "" {
+			c.logger.Error(ctx, "Tool not found", map[string]interface{}{
+				"toolName": toolCall.Function.Name,
+				"toolcall": toolCall,
+			})
+			return nil, fmt.Errorf("tool not found: %s", toolCall.Function.Name)
+		}
 
-		req := openai.ChatCompletionRequest{
-			Model:            c.Model,
-			Messages:         messages,
-			Temperature:      float32(params.LLMConfig.Temperature),
-			TopP:             float32(params.LLMConfig.TopP),
-			FrequencyPenalty: float32(params.LLMConfig.FrequencyPenalty),
-			PresencePenalty:  float32(params.LLMConfig.PresencePenalty),
-			Stop:             params.LLMConfig.StopSequences,
-		}
-
-		// Set response format for final request if provided
-		if params.ResponseFormat != nil {
-			req.ResponseFormat = &openai.ChatCompletionResponseFormat{
-				Type: openai.ChatCompletionResponseFormatTypeJSONSchema,
-				JSONSchema: &openai.ChatCompletionResponseFormatJSONSchema{
-					Name:   params.ResponseFormat.Name,
-					Schema: params.ResponseFormat.Schema,
-				},
-			}
-			c.logger.Debug(ctx, "Using response format", map[string]interface{}{"format": *params.ResponseFormat})
+		// Strict calls set missing optional arguments to null
+		arguments := toolCall.Function.Arguments
+		if c.isStrict(selectedTool.Name()) {
+			arguments = dropNullArguments(arguments, selectedTool.Parameters())
 		}
 
-		finalCompletion, err := c.Client.CreateChatCompletion(ctx, req)
+		// Execute the tool
+		c.logger.Info(ctx, "Executing tool", map[string]interface{}{"toolName": selectedTool.Name()})
+		toolResult, err := selectedTool.Execute(ctx, arguments)
 		if err != nil {
-			c.logger.Error(ctx, "Error from final OpenAI API call", map[string]interface{}{
-				"error": err.Error(),
+			c.logger.Error(ctx, "Error executing tool", map[string]interface{}{"toolName": selectedTool.Name(), "error": err.Error()})
+			// Add error message as tool response
+			messages = append(messages, openai.ChatCompletionMessage{
+				Role:       "tool",
+				Content:    fmt.Sprintf("Error: %v", err),
+				Name:       selectedTool.Name(),
+				ToolCallID: toolCall.ID,
 			})
-			return "", fmt.Errorf("failed to create final chat completion: %w", err)
-		}
-
-		if len(finalCompletion.Choices) == 0 {
-			return "", fmt.Errorf("no completions returned")
+			continue
 		}
 
-		content := strings.TrimSpace(finalCompletion.Choices[0].Message.Content)
-		return content, nil
+		// Add tool result to messages
+		messages = append(messages, openai.ChatCompletionMessage{
+			Role:       "tool",
+			Content:    toolResult,
+			Name:       selectedTool.Name(),
+			ToolCallID: toolCall.ID,
+		})
 	}
+	return messages, nil
+}
 
-	// No tool was used, return the direct response
-	content := strings.TrimSpace(resp.Choices[0].Message.Content)
-	return content, nil
+// reportUsage passes the token usage of a response to the usage callback in
+// the context
+func reportUsage(ctx context.Context, model string, usage openai.Usage) {
+	interfaces.ReportUsage(ctx, interfaces.Usage{
+		Model:        model,
+		InputTokens:  usage.PromptTokens,
+		OutputTokens: usage.CompletionTokens,
+	})
 }
 
 // Name implements interfaces.LLM.Name
@@ -781,6 +859,59 @@ func (c *OpenAIClient) Name() string {
 	return "openai"
 }
 
+// Capabilities reports the features supported by the configured model
+func (c *OpenAIClient) Capabilities() interfaces.Capabilities {
+	return interfaces.Capabilities{
+		Tools:         true,
+		Vision:        openAIVisionModel(c.Model),
+		JSONMode:      true,
+		Streaming:     true,
+		ParallelTools: true,
+	}
+}
+
+// setMaxTokens limits the number of tokens of the response, if maxTokens is
+// set. Reasoning models only accept max_completion_tokens, which includes
+// their reasoning tokens.
+func setMaxTokens(req *openai.ChatCompletionRequest, maxTokens int) {
+	if maxTokens <= 0 {
+		return
+	}
+	if openAIReasoningModel(req.Model) {
+		req.MaxCompletionTokens = maxTokens
+	} else {
+		req.MaxTokens = maxTokens
+	}
+}
+
+// openAIReasoningModel reports whether the model is a reasoning model
+func openAIReasoningModel(model string) bool {
+	for _, prefix := range []string{"o1", "o3", "o4", "gpt-5"} {
+		if strings.HasPrefix(model, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// openAIVisionModel reports whether the model accepts images
+func openAIVisionModel(model string) bool {
+	for _, prefix := range []string{"gpt-4o", "gpt-4.1", "gpt-4-turbo", "gpt-4-vision", "gpt-5", "o1", "o3", "o4"} {
+		if strings.HasPrefix(model, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Ping checks that the OpenAI API is reachable and the API key is valid
+func (c *OpenAIClient) Ping(ctx context.Context) error {
+	if _, err := c.Client.ListModels(ctx); err != nil {
+		return fmt.Errorf("failed to reach OpenAI API: %w", err)
+	}
+	return nil
+}
+
 // WithTemperature creates a GenerateOption to set the temperature
 func WithTemperature(temperature float64) interfaces.GenerateOption {
 	return func(options *interfaces.GenerateOptions) {
@@ -5,8 +5,11 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
+	"sync/atomic"
 	"testing"
 
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
 	"github.com/run-bigpig/llm-agent/pkg/llm"
 	"github.com/run-bigpig/llm-agent/pkg/llm/openai"
 	"github.com/run-bigpig/llm-agent/pkg/logging"
@@ -64,7 +67,7 @@ func TestGenerate(t *testing.T) {
 
 	// Create our wrapper client with a logger
 	logger := logging.New()
-	client := openai.NewClient("test-key",
+	client := openai.NewClient("", "test-key",
 		openai.WithModel("gpt-4"),
 		openai.WithLogger(logger),
 	)
@@ -131,7 +134,7 @@ func TestChat(t *testing.T) {
 
 	// Create our wrapper client with a logger
 	logger := logging.New()
-	client := openai.NewClient("test-key",
+	client := openai.NewClient("", "test-key",
 		openai.WithModel("gpt-4"),
 		openai.WithLogger(logger),
 	)
@@ -156,3 +159,140 @@ func TestChat(t *testing.T) {
 		t.Errorf("Expected response 'test response', got '%s'", resp)
 	}
 }
+
+func TestGenerateWithToolsLoopsUntilAnswer(t *testing.T) {
+	var calls atomic.Int32
+	var requests []map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Errorf("Failed to decode request: %v", err)
+		}
+		requests = append(requests, request)
+		w.Header().Set("Content-Type", "application/json")
+		if calls.Add(1) == 3 {
+			_, _ = w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"done"}}]}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"role":"assistant","tool_calls":[{"id":"call_1","type":"function",
+			"function":{"name":"search","arguments":"{\"query\":\"go\"}"}}]}}]}`))
+	}))
+	defer server.Close()
+
+	search := &searchTool{name: "search", params: map[string]interfaces.ParameterSpec{"query": {Type: "string", Required: true}}}
+	client := openai.NewClient(server.URL, "key", openai.WithMaxToolIterations(2))
+	response, err := client.GenerateWithTools(context.Background(), "Search for go", []interfaces.Tool{search}, openai.WithSystemMessage("Be brief"))
+	if err != nil {
+		t.Fatalf("GenerateWithTools failed: %v", err)
+	}
+	if response != "done" || len(requests) != 3 {
+		t.Fatalf("Expected an answer after two tool rounds, got %q after %d requests", response, len(requests))
+	}
+
+	// Each round adds the tool call and its result to the conversation
+	if messages := requests[2]["messages"].([]interface{}); len(messages) != 6 {
+		t.Errorf("Expected the system message, prompt and two rounds, got %d messages", len(messages))
+	}
+	if requests[1]["tool_choice"] != nil || requests[2]["tool_choice"] != "none" {
+		t.Errorf("Expected tool_choice none only at the iteration limit, got %v and %v", requests[1]["tool_choice"], requests[2]["tool_choice"])
+	}
+}
+
+func TestParallelToolUseErrorsAreToolResults(t *testing.T) {
+	var requests []map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Errorf("Failed to decode request: %v", err)
+		}
+		requests = append(requests, request)
+		w.Header().Set("Content-Type", "application/json")
+		if len(requests) == 2 {
+			_, _ = w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"done"}}]}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"role":"assistant","tool_calls":[
+			{"id":"call_1","type":"function","function":{"name":"parallel_tool_use","arguments":"{\"tool_uses\": ["}},
+			{"id":"call_2","type":"function","function":{"name":"parallel_tool_use",
+				"arguments":"{\"tool_uses\":[{\"recipient_name\":\"search\",\"parameters\":{}},{\"recipient_name\":\"missing\",\"parameters\":{}}]}"}}]}}]}`))
+	}))
+	defer server.Close()
+
+	search := &searchTool{name: "search"}
+	client := openai.NewClient(server.URL, "key")
+	response, err := client.GenerateWithTools(context.Background(), "Search", []interfaces.Tool{search})
+	if err != nil {
+		t.Fatalf("GenerateWithTools failed: %v", err)
+	}
+	if response != "done" || len(requests) != 2 {
+		t.Fatalf("Expected an answer after the failed tool calls, got %q after %d requests", response, len(requests))
+	}
+
+	// Both tool calls are answered with their errors
+	results := map[string]string{}
+	for _, message := range requests[1]["messages"].([]interface{}) {
+		message := message.(map[string]interface{})
+		if message["role"] == "tool" {
+			results[message["tool_call_id"].(string)] = message["content"].(string)
+		}
+	}
+	if !strings.HasPrefix(results["call_1"], "Error: invalid parallel_tool_use arguments") {
+		t.Errorf("Expected the unmarshal error for call_1, got %q", results["call_1"])
+	}
+	if !strings.Contains(results["call_2"], "tool not found: missing") {
+		t.Errorf("Expected the missing tool error for call_2, got %q", results["call_2"])
+	}
+}
+
+func TestGenerateWithMaxTokens(t *testing.T) {
+	var request map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		request = nil
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Errorf("Failed to decode request: %v", err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"ok"}}]}`))
+	}))
+	defer server.Close()
+
+	client := openai.NewClient(server.URL, "key", openai.WithModel("gpt-4o"))
+	if _, err := client.Generate(context.Background(), "hi", interfaces.WithMaxTokens(500)); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if request["max_tokens"] != float64(500) {
+		t.Errorf("Expected max_tokens 500, got %v", request)
+	}
+
+	// Reasoning models take max_completion_tokens
+	client = openai.NewClient(server.URL, "key", openai.WithModel("o3-mini"))
+	if _, err := client.GenerateWithTools(context.Background(), "hi", nil, interfaces.WithMaxTokens(800)); err != nil {
+		t.Fatalf("GenerateWithTools failed: %v", err)
+	}
+	if request["max_completion_tokens"] != float64(800) || request["max_tokens"] != nil {
+		t.Errorf("Expected max_completion_tokens 800, got %v", request)
+	}
+}
+
+func TestCapabilities(t *testing.T) {
+	tests := []struct {
+		model  string
+		vision bool
+	}{
+		{model: "gpt-4o-mini", vision: true},
+		{model: "gpt-4.1", vision: true},
+		{model: "o3-mini", vision: true},
+		{model: "gpt-4", vision: false},
+		{model: "gpt-3.5-turbo", vision: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.model, func(t *testing.T) {
+			caps := openai.NewClient("", "test-key", openai.WithModel(tt.model)).Capabilities()
+			expected := interfaces.Capabilities{Tools: true, Vision: tt.vision, JSONMode: true, Streaming: true, ParallelTools: true}
+			if caps != expected {
+				t.Errorf("Expected %+v, got %+v", expected, caps)
+			}
+		})
+	}
+}
@@ -0,0 +1,153 @@
+package openai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+	"github.com/sashabaranov/go-openai"
+)
+
+// ConstraintDialect is the way an OpenAI-compatible server takes constraints
+// for constrained decoding
+type ConstraintDialect string
+
+const (
+	// ConstraintOpenAI enforces schemas with strict structured outputs in
+	// response_format, as supported by OpenAI and Ollama. Grammars and
+	// regular expressions are not supported.
+	ConstraintOpenAI ConstraintDialect = "openai"
+
+	// ConstraintVLLM uses the guided_grammar, guided_regex and guided_json
+	// fields of vLLM
+	ConstraintVLLM ConstraintDialect = "vllm"
+
+	// ConstraintLlamaCpp uses the grammar and json_schema fields of the
+	// llama.cpp server. Regular expressions are not supported.
+	ConstraintLlamaCpp ConstraintDialect = "llamacpp"
+)
+
+// WithConstraintDialect sets how constraints set with interfaces.WithGrammar,
+// interfaces.WithRegex and interfaces.WithSchemaConstraint are sent to the
+// server. Defaults to ConstraintOpenAI.
+func WithConstraintDialect(dialect ConstraintDialect) Option {
+	return func(c *OpenAIClient) {
+		c.constraintDialect = dialect
+	}
+}
+
+// applyConstraint sets the constraint on the request. Fields the request
+// type doesn't have are carried by the returned context and added to the
+// request body by the client's transport.
+func (c *OpenAIClient) applyConstraint(ctx context.Context, req *openai.ChatCompletionRequest, constraint *interfaces.Constraint) (context.Context, error) {
+	if constraint == nil {
+		return ctx, nil
+	}
+
+	set := 0
+	for _, ok := range []bool{constraint.Grammar != "", constraint.Regex != "", constraint.Schema != nil} {
+		if ok {
+			set++
+		}
+	}
+	if set != 1 {
+		return ctx, fmt.Errorf("a constraint needs exactly one of a grammar, a regex or a schema")
+	}
+
+	var fields map[string]interface{}
+	switch c.constraintDialect {
+	case ConstraintVLLM:
+		switch {
+		case constraint.Grammar != "":
+			fields = map[string]interface{}{"guided_grammar": constraint.Grammar}
+		case constraint.Regex != "":
+			fields = map[string]interface{}{"guided_regex": constraint.Regex}
+		default:
+			fields = map[string]interface{}{"guided_json": constraint.Schema}
+		}
+	case ConstraintLlamaCpp:
+		switch {
+		case constraint.Grammar != "":
+			fields = map[string]interface{}{"grammar": constraint.Grammar}
+		case constraint.Regex != "":
+			return ctx, fmt.Errorf("regex constraints are not supported by the %s dialect", c.constraintDialect)
+		default:
+			fields = map[string]interface{}{"json_schema": constraint.Schema}
+		}
+	default:
+		if constraint.Schema == nil {
+			return ctx, fmt.Errorf("only schema constraints are supported by the %s dialect", ConstraintOpenAI)
+		}
+		req.ResponseFormat = &openai.ChatCompletionResponseFormat{
+			Type: openai.ChatCompletionResponseFormatTypeJSONSchema,
+			JSONSchema: &openai.ChatCompletionResponseFormatJSONSchema{
+				Name:   "constrained_response",
+				Schema: constraint.Schema,
+				Strict: true,
+			},
+		}
+		return ctx, nil
+	}
+
+	// The server's own field replaces a requested response format
+	req.ResponseFormat = nil
+	return context.WithValue(ctx, extraBodyKey, fields), nil
+}
+
+// extraBodyKey is the context key of the fields extraBodyTransport adds
+const extraBodyKey contextKey = "extra_body"
+
+// extraBodyTransport adds the fields in the request's context to the JSON
+// body of the request
+type extraBodyTransport struct {
+	base http.RoundTripper
+}
+
+// RoundTrip adds the fields to the body and sends the request
+func (t *extraBodyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	fields, ok := req.Context().Value(extraBodyKey).(map[string]interface{})
+	if !ok || req.Body == nil {
+		return t.base.RoundTrip(req)
+	}
+
+	body, err := io.ReadAll(req.Body)
+	_ = req.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read request body: %w", err)
+	}
+	var values map[string]interface{}
+	if err := json.Unmarshal(body, &values); err != nil {
+		return nil, fmt.Errorf("failed to decode request body: %w", err)
+	}
+	for key, value := range fields {
+		values[key] = value
+	}
+	body, err = json.Marshal(values)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode request body: %w", err)
+	}
+
+	req = req.Clone(req.Context())
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(body)), nil
+	}
+	req.ContentLength = int64(len(body))
+	return t.base.RoundTrip(req)
+}
+
+// withExtraBody returns a copy of the HTTP client whose transport adds the
+// fields of extra body contexts to requests
+func withExtraBody(client *http.Client) *http.Client {
+	wrapped := *client
+	base := client.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	wrapped.Transport = &extraBodyTransport{base: base}
+	return &wrapped
+}
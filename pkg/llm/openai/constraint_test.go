@@ -0,0 +1,108 @@
+package openai_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+	"github.com/run-bigpig/llm-agent/pkg/llm/openai"
+)
+
+// recordingServer answers every request with content and records the last
+// request body
+func recordingServer(t *testing.T, request *map[string]interface{}) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*request = nil
+		if err := json.NewDecoder(r.Body).Decode(request); err != nil {
+			t.Errorf("Failed to decode request: %v", err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"2024-01-31"}}]}`))
+	}))
+}
+
+func TestConstraintDialects(t *testing.T) {
+	var request map[string]interface{}
+	server := recordingServer(t, &request)
+	defer server.Close()
+
+	schema := interfaces.JSONSchema{"type": "object"}
+	tests := []struct {
+		dialect openai.ConstraintDialect
+		option  interfaces.GenerateOption
+		field   string
+		value   interface{}
+	}{
+		{openai.ConstraintVLLM, interfaces.WithRegex(`\d{4}-\d{2}-\d{2}`), "guided_regex", `\d{4}-\d{2}-\d{2}`},
+		{openai.ConstraintVLLM, interfaces.WithGrammar(`root ::= "yes" | "no"`), "guided_grammar", `root ::= "yes" | "no"`},
+		{openai.ConstraintVLLM, interfaces.WithSchemaConstraint(schema), "guided_json", map[string]interface{}{"type": "object"}},
+		{openai.ConstraintLlamaCpp, interfaces.WithGrammar(`root ::= "yes" | "no"`), "grammar", `root ::= "yes" | "no"`},
+		{openai.ConstraintLlamaCpp, interfaces.WithSchemaConstraint(schema), "json_schema", map[string]interface{}{"type": "object"}},
+	}
+	for _, tt := range tests {
+		client := openai.NewClient(server.URL, "key", openai.WithConstraintDialect(tt.dialect))
+		response, err := client.Generate(context.Background(), "When?", tt.option)
+		if err != nil {
+			t.Fatalf("%s: Generate failed: %v", tt.field, err)
+		}
+		if response != "2024-01-31" {
+			t.Errorf("%s: unexpected response %q", tt.field, response)
+		}
+		value, _ := json.Marshal(request[tt.field])
+		expected, _ := json.Marshal(tt.value)
+		if string(value) != string(expected) {
+			t.Errorf("Expected %s to be %s, got %s", tt.field, expected, value)
+		}
+		if request["model"] == nil || request["messages"] == nil {
+			t.Errorf("%s: expected the rest of the request to be kept, got %v", tt.field, request)
+		}
+	}
+
+	// Without a constraint, nothing is added
+	client := openai.NewClient(server.URL, "key", openai.WithConstraintDialect(openai.ConstraintVLLM))
+	if _, err := client.Generate(context.Background(), "When?"); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if _, ok := request["guided_json"]; ok {
+		t.Errorf("Expected no constraint fields, got %v", request)
+	}
+}
+
+func TestSchemaConstraintUsesStructuredOutputs(t *testing.T) {
+	var request map[string]interface{}
+	server := recordingServer(t, &request)
+	defer server.Close()
+
+	client := openai.NewClient(server.URL, "key")
+	_, err := client.Generate(context.Background(), "When?", interfaces.WithSchemaConstraint(interfaces.JSONSchema{"type": "object"}))
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	format, _ := request["response_format"].(map[string]interface{})
+	jsonSchema, _ := format["json_schema"].(map[string]interface{})
+	if format["type"] != "json_schema" || jsonSchema["strict"] != true {
+		t.Errorf("Expected a strict JSON schema response format, got %v", request["response_format"])
+	}
+}
+
+func TestUnsupportedConstraints(t *testing.T) {
+	var request map[string]interface{}
+	server := recordingServer(t, &request)
+	defer server.Close()
+
+	_, err := openai.NewClient(server.URL, "key").Generate(context.Background(), "When?", interfaces.WithRegex(`\d+`))
+	if err == nil {
+		t.Error("Expected regex constraints to fail with the openai dialect")
+	}
+	_, err = openai.NewClient(server.URL, "key", openai.WithConstraintDialect(openai.ConstraintLlamaCpp)).
+		Generate(context.Background(), "When?", interfaces.WithRegex(`\d+`))
+	if err == nil {
+		t.Error("Expected regex constraints to fail with the llamacpp dialect")
+	}
+	if request != nil {
+		t.Errorf("Expected no request to be sent, got %v", request)
+	}
+}
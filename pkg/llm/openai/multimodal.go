@@ -0,0 +1,78 @@
+package openai
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+	"github.com/sashabaranov/go-openai"
+)
+
+// GenerateMultimodal implements interfaces.MultimodalLLM. Images are sent
+// as image_url parts, with image bytes as data URLs, so the model must
+// accept images, e.g. GPT-4o.
+func (c *OpenAIClient) GenerateMultimodal(ctx context.Context, contents []interfaces.Content, options ...interfaces.GenerateOption) (string, error) {
+	parts, err := contentParts(contents)
+	if err != nil {
+		return "", err
+	}
+
+	params := &interfaces.GenerateOptions{
+		LLMConfig: &interfaces.LLMConfig{
+			Temperature: 0.7,
+		},
+	}
+	for _, option := range options {
+		option(params)
+	}
+
+	ctx, cancel := interfaces.RequestContext(ctx, params.Timeout, c.requestTimeout)
+	defer cancel()
+
+	ctx, req, err := c.chatCompletionRequest(ctx, "", params)
+	if err != nil {
+		return "", err
+	}
+
+	// The prompt is the last message
+	prompt := &req.Messages[len(req.Messages)-1]
+	prompt.Content = ""
+	prompt.MultiContent = parts
+
+	return c.createChatCompletion(ctx, req, params)
+}
+
+// errAudioUnsupported is returned for prompts with audio, which chat
+// completions don't take
+var errAudioUnsupported = errors.New("audio content is not supported by chat completions: transcribe it with audio.OpenAI first")
+
+// contentParts converts the contents to the parts of a user message
+func contentParts(contents []interfaces.Content) ([]openai.ChatMessagePart, error) {
+	parts := make([]openai.ChatMessagePart, 0, len(contents))
+	for _, content := range contents {
+		if err := content.Validate(); err != nil {
+			return nil, err
+		}
+		switch content.Type {
+		case interfaces.ContentTypeText:
+			parts = append(parts, openai.ChatMessagePart{Type: openai.ChatMessagePartTypeText, Text: content.Text})
+		case interfaces.ContentTypeImageURL:
+			parts = append(parts, openai.ChatMessagePart{
+				Type:     openai.ChatMessagePartTypeImageURL,
+				ImageURL: &openai.ChatMessageImageURL{URL: content.URL},
+			})
+		case interfaces.ContentTypeImage:
+			parts = append(parts, openai.ChatMessagePart{
+				Type: openai.ChatMessagePartTypeImageURL,
+				ImageURL: &openai.ChatMessageImageURL{
+					URL: fmt.Sprintf("data:%s;base64,%s", content.MediaType, base64.StdEncoding.EncodeToString(content.Data)),
+				},
+			})
+		default:
+			return nil, errAudioUnsupported
+		}
+	}
+	return parts, nil
+}
@@ -0,0 +1,57 @@
+package openai_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+	"github.com/run-bigpig/llm-agent/pkg/llm/openai"
+)
+
+func TestGenerateMultimodal(t *testing.T) {
+	var request map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Errorf("Failed to decode request: %v", err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"A login form."}}]}`))
+	}))
+	defer server.Close()
+
+	client := openai.NewClient(server.URL, "key", openai.WithModel("gpt-4o"))
+	response, err := client.GenerateMultimodal(context.Background(), []interfaces.Content{
+		interfaces.TextContent("What is on the screen?"),
+		interfaces.ImageContent([]byte("png"), "image/png"),
+		interfaces.ImageURLContent("https://example.com/photo.jpg"),
+	}, openai.WithSystemMessage("Describe images briefly."))
+	if err != nil {
+		t.Fatalf("GenerateMultimodal failed: %v", err)
+	}
+	if response != "A login form." {
+		t.Errorf("Unexpected response %q", response)
+	}
+
+	messages := request["messages"].([]interface{})
+	if len(messages) != 2 {
+		t.Fatalf("Expected a system and a user message, got %v", messages)
+	}
+	content, _ := json.Marshal(messages[1].(map[string]interface{})["content"])
+	expected := `[{"text":"What is on the screen?","type":"text"},` +
+		`{"image_url":{"url":"data:image/png;base64,cG5n"},"type":"image_url"},` +
+		`{"image_url":{"url":"https://example.com/photo.jpg"},"type":"image_url"}]`
+	if string(content) != expected {
+		t.Errorf("Expected content %s, got %s", expected, content)
+	}
+}
+
+func TestGenerateMultimodalRejectsInvalidContent(t *testing.T) {
+	client := openai.NewClient("http://localhost", "key")
+	_, err := client.GenerateMultimodal(context.Background(), []interfaces.Content{{Type: interfaces.ContentTypeImage}})
+	if err == nil {
+		t.Fatal("Expected an error for an image without data")
+	}
+}
@@ -0,0 +1,99 @@
+package openai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+	"github.com/sashabaranov/go-openai"
+)
+
+// GenerateStream generates text from a prompt and streams the response as it
+// is generated. Opening the stream is retried with the client's retry
+// policy; a stream that fails midway is not.
+func (c *OpenAIClient) GenerateStream(ctx context.Context, prompt string, options ...interfaces.GenerateOption) (<-chan interfaces.StreamEvent, error) {
+	params := &interfaces.GenerateOptions{
+		LLMConfig: &interfaces.LLMConfig{
+			Temperature: 0.7,
+		},
+	}
+	for _, option := range options {
+		option(params)
+	}
+
+	// The timeout covers the whole stream
+	ctx, cancel := interfaces.RequestContext(ctx, params.Timeout, c.requestTimeout)
+
+	ctx, req, err := c.chatCompletionRequest(ctx, prompt, params)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	req.Stream = true
+	req.StreamOptions = &openai.StreamOptions{IncludeUsage: true}
+
+	var stream *openai.ChatCompletionStream
+	operation := func() error {
+		var err error
+		stream, err = c.Client.CreateChatCompletionStream(ctx, req)
+		if err != nil {
+			c.logger.Error(ctx, "Error from OpenAI API", map[string]interface{}{
+				"error": err.Error(),
+				"model": c.Model,
+			})
+			return fmt.Errorf("failed to generate text: %w", err)
+		}
+		return nil
+	}
+
+	if c.retryExecutor != nil {
+		err = c.retryExecutor.Execute(ctx, operation)
+	} else {
+		err = operation()
+	}
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	events := make(chan interfaces.StreamEvent)
+	go func() {
+		defer cancel()
+		defer close(events)
+		defer stream.Close()
+
+		send := func(event interfaces.StreamEvent) bool {
+			select {
+			case events <- event:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		for {
+			chunk, err := stream.Recv()
+			if errors.Is(err, io.EOF) {
+				send(interfaces.StreamEvent{Type: interfaces.StreamEventDone})
+				return
+			}
+			if err != nil {
+				send(interfaces.StreamEvent{Type: interfaces.StreamEventError, Err: fmt.Errorf("failed to read stream: %w", err)})
+				return
+			}
+			// The last chunk has the usage of the whole stream and no choices
+			if chunk.Usage != nil {
+				reportUsage(ctx, chunk.Model, *chunk.Usage)
+			}
+			if len(chunk.Choices) == 0 || chunk.Choices[0].Delta.Content == "" {
+				continue
+			}
+			if !send(interfaces.StreamEvent{Type: interfaces.StreamEventText, Content: chunk.Choices[0].Delta.Content}) {
+				return
+			}
+		}
+	}()
+	return events, nil
+}
@@ -0,0 +1,121 @@
+package openai
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+)
+
+// WithStrictTools enables OpenAI's strict mode for tools, so that the model's
+// tool-call arguments always match the tool's parameter schema. Without
+// names, all tools are strict; otherwise only the named tools are. Tools
+// whose parameters can't be described strictly, such as free-form objects,
+// are sent as they are.
+func WithStrictTools(names ...string) Option {
+	return func(c *OpenAIClient) {
+		c.strictTools = true
+		if len(names) > 0 && c.strictToolNames == nil {
+			c.strictToolNames = make(map[string]bool)
+		}
+		for _, name := range names {
+			c.strictToolNames[name] = true
+		}
+	}
+}
+
+// isStrict reports whether strict mode is enabled for the tool
+func (c *OpenAIClient) isStrict(name string) bool {
+	return c.strictTools && (c.strictToolNames == nil || c.strictToolNames[name])
+}
+
+// strictSchema tightens the parameter schema of a tool for strict mode: all
+// properties are required, with optional ones accepting null instead,
+// additional properties are rejected, and defaults, which strict mode
+// doesn't support, are moved to the description. It reports false if the
+// parameters can't be described strictly.
+func strictSchema(params map[string]interfaces.ParameterSpec) (map[string]interface{}, bool) {
+	names := make([]string, 0, len(params))
+	for name := range params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	properties := make(map[string]interface{}, len(params))
+	for _, name := range names {
+		param := params[name]
+		property, ok := strictProperty(param)
+		if !ok {
+			return nil, false
+		}
+		if param.Default != nil {
+			property["description"] = fmt.Sprintf("%s (default: %v)", param.Description, param.Default)
+		}
+		if !param.Required {
+			property["type"] = []interface{}{param.Type, "null"}
+			if enum, ok := property["enum"].([]interface{}); ok {
+				property["enum"] = append(enum[:len(enum):len(enum)], nil)
+			}
+		}
+		properties[name] = property
+	}
+
+	return map[string]interface{}{
+		"type":                 "object",
+		"properties":           properties,
+		"required":             names,
+		"additionalProperties": false,
+	}, true
+}
+
+// strictProperty returns the schema of a parameter for strict mode, or
+// false for objects, whose properties are unknown
+func strictProperty(param interfaces.ParameterSpec) (map[string]interface{}, bool) {
+	if param.Type == "" || param.Type == "object" {
+		return nil, false
+	}
+	property := map[string]interface{}{
+		"type":        param.Type,
+		"description": param.Description,
+	}
+	if param.Enum != nil {
+		property["enum"] = param.Enum
+	}
+	if param.Type == "array" {
+		if param.Items == nil {
+			return nil, false
+		}
+		items, ok := strictProperty(*param.Items)
+		if !ok {
+			return nil, false
+		}
+		delete(items, "description")
+		property["items"] = items
+	}
+	return property, true
+}
+
+// dropNullArguments removes the optional arguments that a strict tool call
+// set to null, so that tools see them as missing like in non-strict calls
+func dropNullArguments(args string, params map[string]interfaces.ParameterSpec) string {
+	var values map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(args), &values); err != nil {
+		return args
+	}
+	dropped := false
+	for name, value := range values {
+		if string(value) == "null" && !params[name].Required {
+			delete(values, name)
+			dropped = true
+		}
+	}
+	if !dropped {
+		return args
+	}
+	cleaned, err := json.Marshal(values)
+	if err != nil {
+		return args
+	}
+	return string(cleaned)
+}
@@ -0,0 +1,124 @@
+package openai_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+	"github.com/run-bigpig/llm-agent/pkg/llm/openai"
+)
+
+// searchTool records the arguments it's called with
+type searchTool struct {
+	name   string
+	params map[string]interfaces.ParameterSpec
+	args   string
+}
+
+func (t *searchTool) Name() string        { return t.name }
+func (t *searchTool) Description() string { return "Searches" }
+func (t *searchTool) Parameters() map[string]interfaces.ParameterSpec {
+	return t.params
+}
+func (t *searchTool) Run(ctx context.Context, input string) (string, error) { return "", nil }
+func (t *searchTool) Execute(ctx context.Context, args string) (string, error) {
+	t.args = args
+	return "3 results", nil
+}
+
+func TestStrictTools(t *testing.T) {
+	var calls atomic.Int32
+	var request map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if calls.Add(1) > 1 {
+			_, _ = w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"Found 3 results."}}]}`))
+			return
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Errorf("Failed to decode request: %v", err)
+		}
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"role":"assistant","tool_calls":[{"id":"call_1","type":"function",
+			"function":{"name":"search","arguments":"{\"query\":\"go\",\"limit\":null,\"sort\":null}"}}]}}]}`))
+	}))
+	defer server.Close()
+
+	search := &searchTool{name: "search", params: map[string]interfaces.ParameterSpec{
+		"query": {Type: "string", Description: "The query", Required: true},
+		"limit": {Type: "integer", Description: "Maximum results", Default: 10},
+		"sort":  {Type: "string", Description: "Sort order", Enum: []interface{}{"asc", "desc"}},
+	}}
+	lookup := &searchTool{name: "lookup", params: map[string]interfaces.ParameterSpec{
+		"filter": {Type: "object", Description: "Free-form filter"},
+	}}
+
+	client := openai.NewClient(server.URL, "key", openai.WithStrictTools())
+	response, err := client.GenerateWithTools(context.Background(), "Search for go", []interfaces.Tool{search, lookup})
+	if err != nil {
+		t.Fatalf("GenerateWithTools failed: %v", err)
+	}
+	if response != "Found 3 results." {
+		t.Errorf("Unexpected response %q", response)
+	}
+	if search.args != `{"query":"go"}` {
+		t.Errorf("Expected the null arguments to be dropped, got %s", search.args)
+	}
+
+	if request["parallel_tool_calls"] != false {
+		t.Errorf("Expected parallel tool calls to be disabled, got %v", request["parallel_tool_calls"])
+	}
+	tools := request["tools"].([]interface{})
+	function := tools[0].(map[string]interface{})["function"].(map[string]interface{})
+	if function["strict"] != true {
+		t.Fatalf("Expected a strict tool, got %v", function)
+	}
+	schema, _ := json.Marshal(function["parameters"])
+	expected := `{"additionalProperties":false,"properties":{` +
+		`"limit":{"description":"Maximum results (default: 10)","type":["integer","null"]},` +
+		`"query":{"description":"The query","type":"string"},` +
+		`"sort":{"description":"Sort order","enum":["asc","desc",null],"type":["string","null"]}},` +
+		`"required":["limit","query","sort"],"type":"object"}`
+	if string(schema) != expected {
+		t.Errorf("Expected schema %s, got %s", expected, schema)
+	}
+
+	// Free-form objects can't be described strictly
+	function = tools[1].(map[string]interface{})["function"].(map[string]interface{})
+	if _, ok := function["strict"]; ok {
+		t.Errorf("Expected the object tool not to be strict, got %v", function)
+	}
+}
+
+func TestStrictToolsByName(t *testing.T) {
+	var request map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Errorf("Failed to decode request: %v", err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"ok"}}]}`))
+	}))
+	defer server.Close()
+
+	params := map[string]interfaces.ParameterSpec{"query": {Type: "string", Required: true}}
+	client := openai.NewClient(server.URL, "key", openai.WithStrictTools("search"))
+	_, err := client.GenerateWithTools(context.Background(), "hi", []interfaces.Tool{
+		&searchTool{name: "search", params: params},
+		&searchTool{name: "browse", params: params},
+	})
+	if err != nil {
+		t.Fatalf("GenerateWithTools failed: %v", err)
+	}
+
+	tools := request["tools"].([]interface{})
+	for i, strict := range []bool{true, false} {
+		function := tools[i].(map[string]interface{})["function"].(map[string]interface{})
+		if (function["strict"] == true) != strict {
+			t.Errorf("Expected strict=%v for %v", strict, function["name"])
+		}
+	}
+}
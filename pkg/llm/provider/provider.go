@@ -0,0 +1,87 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/run-bigpig/llm-agent/pkg/config"
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+	"github.com/run-bigpig/llm-agent/pkg/llm/anthropic"
+	"github.com/run-bigpig/llm-agent/pkg/llm/openai"
+	"github.com/run-bigpig/llm-agent/pkg/llm/vertex"
+)
+
+// Provider names accepted in LLM_PROVIDER
+const (
+	OpenAI    = "openai"
+	Anthropic = "anthropic"
+	Vertex    = "vertex"
+)
+
+// FromConfig creates the LLM client selected by cfg.LLM.Provider. When no
+// provider is set, the first of OpenAI, Anthropic and Vertex AI with
+// credentials is used. A nil cfg uses config.Get().
+func FromConfig(ctx context.Context, cfg *config.Config) (interfaces.LLM, error) {
+	if cfg == nil {
+		cfg = config.Get()
+	}
+
+	name := cfg.LLM.Provider
+	if name == "" {
+		switch {
+		case cfg.LLM.OpenAI.APIKey != "":
+			name = OpenAI
+		case cfg.LLM.Anthropic.APIKey != "":
+			name = Anthropic
+		case cfg.LLM.Vertex.ProjectID != "":
+			name = Vertex
+		default:
+			return nil, fmt.Errorf("no LLM provider configured: set LLM_PROVIDER or the credentials of a provider")
+		}
+	}
+
+	switch name {
+	case OpenAI:
+		c := cfg.LLM.OpenAI
+		if c.APIKey == "" {
+			return nil, fmt.Errorf("OPENAI_API_KEY is required for the openai provider")
+		}
+		options := []openai.Option{openai.WithModel(c.Model)}
+		if c.Timeout > 0 {
+			options = append(options, openai.WithDefaultRequestTimeout(c.Timeout))
+		}
+		return openai.NewClient(c.BaseURL, c.APIKey, options...), nil
+
+	case Anthropic:
+		c := cfg.LLM.Anthropic
+		if c.APIKey == "" {
+			return nil, fmt.Errorf("ANTHROPIC_API_KEY is required for the anthropic provider")
+		}
+		options := []anthropic.Option{anthropic.WithModel(c.Model)}
+		if c.BaseURL != "" {
+			options = append(options, anthropic.WithBaseURL(c.BaseURL))
+		}
+		if c.Timeout > 0 {
+			options = append(options, anthropic.WithDefaultRequestTimeout(c.Timeout))
+		}
+		return anthropic.NewClient(c.APIKey, options...), nil
+
+	case Vertex:
+		c := cfg.LLM.Vertex
+		options := []vertex.ClientOption{vertex.WithModel(c.Model), vertex.WithLocation(c.Location)}
+		if c.Timeout > 0 {
+			options = append(options, vertex.WithDefaultRequestTimeout(c.Timeout))
+		}
+		if c.CredentialsFile != "" {
+			options = append(options, vertex.WithCredentialsFile(c.CredentialsFile))
+		}
+		client, err := vertex.NewClient(ctx, c.ProjectID, options...)
+		if err != nil {
+			return nil, err
+		}
+		return client, nil
+
+	default:
+		return nil, fmt.Errorf("unknown LLM provider %q", name)
+	}
+}
@@ -0,0 +1,98 @@
+package provider_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/run-bigpig/llm-agent/pkg/config"
+	"github.com/run-bigpig/llm-agent/pkg/llm/anthropic"
+	"github.com/run-bigpig/llm-agent/pkg/llm/openai"
+	"github.com/run-bigpig/llm-agent/pkg/llm/provider"
+)
+
+func TestFromConfigSelectsProvider(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.LLM.OpenAI.Model = "gpt-4o"
+	cfg.LLM.Anthropic.APIKey = "anthropic-key"
+	cfg.LLM.Anthropic.Model = "claude-sonnet-4-20250514"
+
+	// Without a provider, the first one with credentials is used
+	llm, err := provider.FromConfig(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("FromConfig failed: %v", err)
+	}
+	client, ok := llm.(*anthropic.AnthropicClient)
+	if !ok || client.Model != "claude-sonnet-4-20250514" {
+		t.Fatalf("Expected an Anthropic client for the configured model, got %T %+v", llm, llm)
+	}
+
+	cfg.LLM.OpenAI.APIKey = "openai-key"
+	cfg.LLM.OpenAI.Timeout = time.Minute
+	llm, err = provider.FromConfig(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("FromConfig failed: %v", err)
+	}
+	if client, ok := llm.(*openai.OpenAIClient); !ok || client.Model != "gpt-4o" {
+		t.Fatalf("Expected OpenAI to be preferred, got %T", llm)
+	}
+
+	// An explicit provider wins
+	cfg.LLM.Provider = provider.Anthropic
+	llm, err = provider.FromConfig(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("FromConfig failed: %v", err)
+	}
+	if _, ok := llm.(*anthropic.AnthropicClient); !ok {
+		t.Fatalf("Expected the configured provider, got %T", llm)
+	}
+}
+
+func TestFromConfigErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		configure func(cfg *config.Config)
+		expected  string
+	}{
+		{
+			name:      "no provider",
+			configure: func(cfg *config.Config) {},
+			expected:  "no LLM provider configured: set LLM_PROVIDER or the credentials of a provider",
+		},
+		{
+			name:      "unknown provider",
+			configure: func(cfg *config.Config) { cfg.LLM.Provider = "ollama" },
+			expected:  `unknown LLM provider "ollama"`,
+		},
+		{
+			name:      "missing OpenAI key",
+			configure: func(cfg *config.Config) { cfg.LLM.Provider = provider.OpenAI },
+			expected:  "OPENAI_API_KEY is required for the openai provider",
+		},
+		{
+			name: "missing Anthropic key",
+			configure: func(cfg *config.Config) {
+				cfg.LLM.Provider = provider.Anthropic
+				cfg.LLM.OpenAI.APIKey = "openai-key"
+			},
+			expected: "ANTHROPIC_API_KEY is required for the anthropic provider",
+		},
+		{
+			name:      "missing Vertex project",
+			configure: func(cfg *config.Config) { cfg.LLM.Provider = provider.Vertex },
+			expected:  "projectID is required",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{}
+			tt.configure(cfg)
+
+			_, err := provider.FromConfig(context.Background(), cfg)
+			if err == nil || err.Error() != tt.expected {
+				t.Errorf("Expected error %q, got %v", tt.expected, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,253 @@
+// Package resume keeps streamed responses going when a provider drops the
+// connection midway. The stream is resumed from the text received so far:
+// with the text as a prefilled assistant message where the provider supports
+// it, or with a prompt to continue it otherwise. A stream that fails before
+// any text is restarted. Callers see a single uninterrupted stream.
+package resume
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"unicode"
+
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+)
+
+// defaultMaxResumes is how often a stream is resumed by default
+const defaultMaxResumes = 2
+
+// Stats counts how streams were resumed
+type Stats struct {
+	// Streams is the number of GenerateStream calls
+	Streams int64 `json:"streams"`
+
+	// Prefilled is the number of resumes with the text as an assistant
+	// prefix
+	Prefilled int64 `json:"prefilled"`
+
+	// Continued is the number of resumes with a prompt to continue the text
+	Continued int64 `json:"continued"`
+
+	// Restarted is the number of streams restarted before any text
+	Restarted int64 `json:"restarted"`
+
+	// Failures is the number of streams that failed after all resumes
+	Failures int64 `json:"failures"`
+}
+
+// LLM wraps an LLM so that its streams are resumed when they fail
+type LLM struct {
+	llms       []interfaces.LLM
+	maxResumes int
+
+	streams   atomic.Int64
+	prefilled atomic.Int64
+	continued atomic.Int64
+	restarted atomic.Int64
+	failures  atomic.Int64
+}
+
+// Option configures an LLM
+type Option func(*LLM)
+
+// WithFallbacks resumes failed streams with the given LLMs, in order, instead
+// of the LLM whose stream failed. Without fallbacks, the same LLM is used.
+func WithFallbacks(llms ...interfaces.LLM) Option {
+	return func(l *LLM) {
+		l.llms = append(l.llms, llms...)
+	}
+}
+
+// WithMaxResumes sets how often a stream may be resumed. Defaults to 2.
+func WithMaxResumes(n int) Option {
+	return func(l *LLM) {
+		l.maxResumes = n
+	}
+}
+
+// New wraps the LLM so that its streams are resumed when they fail
+func New(llm interfaces.LLM, options ...Option) *LLM {
+	l := &LLM{
+		llms:       []interfaces.LLM{llm},
+		maxResumes: defaultMaxResumes,
+	}
+	for _, option := range options {
+		option(l)
+	}
+	return l
+}
+
+// Generate is passed to the wrapped LLM
+func (l *LLM) Generate(ctx context.Context, prompt string, options ...interfaces.GenerateOption) (string, error) {
+	return l.llms[0].Generate(ctx, prompt, options...)
+}
+
+// GenerateWithTools is passed to the wrapped LLM
+func (l *LLM) GenerateWithTools(ctx context.Context, prompt string, tools []interfaces.Tool, options ...interfaces.GenerateOption) (string, error) {
+	return l.llms[0].GenerateWithTools(ctx, prompt, tools, options...)
+}
+
+// Name returns the name of the wrapped LLM
+func (l *LLM) Name() string {
+	return l.llms[0].Name()
+}
+
+// Unwrap returns the wrapped LLM
+func (l *LLM) Unwrap() interfaces.LLM {
+	return l.llms[0]
+}
+
+// Stats returns the resume counters
+func (l *LLM) Stats() Stats {
+	return Stats{
+		Streams:   l.streams.Load(),
+		Prefilled: l.prefilled.Load(),
+		Continued: l.continued.Load(),
+		Restarted: l.restarted.Load(),
+		Failures:  l.failures.Load(),
+	}
+}
+
+// GenerateStream streams the response, resuming the stream when it fails.
+// Opening the stream fails only if every attempt to open it fails.
+func (l *LLM) GenerateStream(ctx context.Context, prompt string, options ...interfaces.GenerateOption) (<-chan interfaces.StreamEvent, error) {
+	l.streams.Add(1)
+
+	attempt := 0
+	upstream, err := l.open(ctx, &attempt, prompt, "", options)
+	if err != nil {
+		l.failures.Add(1)
+		return nil, err
+	}
+
+	events := make(chan interfaces.StreamEvent)
+	go func() {
+		defer close(events)
+		send := func(event interfaces.StreamEvent) bool {
+			select {
+			case events <- event:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		var text strings.Builder
+		trimSpace := false
+		for {
+			var failure error
+			for event := range upstream {
+				switch event.Type {
+				case interfaces.StreamEventText:
+					content := event.Content
+					if trimSpace {
+						// The resumed text repeats the whitespace the
+						// prefix ended with
+						content = strings.TrimLeftFunc(content, unicode.IsSpace)
+						if content == "" {
+							continue
+						}
+						trimSpace = false
+					}
+					text.WriteString(content)
+					event.Content = content
+				case interfaces.StreamEventError:
+					failure = event.Err
+					continue
+				}
+				if !send(event) {
+					drain(upstream)
+					return
+				}
+			}
+			if failure == nil {
+				return
+			}
+
+			if ctx.Err() != nil || attempt >= l.maxResumes || !resumable(failure, len(l.llms)) {
+				l.failures.Add(1)
+				send(interfaces.StreamEvent{Type: interfaces.StreamEventError, Err: failure})
+				return
+			}
+
+			attempt++
+			upstream, err = l.open(ctx, &attempt, prompt, text.String(), options)
+			if err != nil {
+				l.failures.Add(1)
+				send(interfaces.StreamEvent{Type: interfaces.StreamEventError, Err: fmt.Errorf("failed to resume stream after %v: %w", failure, err)})
+				return
+			}
+			trimSpace = strings.TrimRightFunc(text.String(), unicode.IsSpace) != text.String()
+		}
+	}()
+	return events, nil
+}
+
+// open opens a stream for the attempt, continuing the text received so far.
+// If the stream can't be opened, the next attempts are tried.
+func (l *LLM) open(ctx context.Context, attempt *int, prompt, text string, options []interfaces.GenerateOption) (<-chan interfaces.StreamEvent, error) {
+	for {
+		llm := l.llms[*attempt%len(l.llms)]
+		events, err := l.openWith(ctx, llm, *attempt, prompt, text, options)
+		if err == nil || ctx.Err() != nil || *attempt >= l.maxResumes || !resumable(err, len(l.llms)) {
+			return events, err
+		}
+		*attempt++
+	}
+}
+
+// openWith opens a stream with the LLM that continues the text
+func (l *LLM) openWith(ctx context.Context, llm interfaces.LLM, attempt int, prompt, text string, options []interfaces.GenerateOption) (<-chan interfaces.StreamEvent, error) {
+	switch {
+	case text == "":
+		if attempt > 0 {
+			l.restarted.Add(1)
+		}
+	case prefills(llm):
+		l.prefilled.Add(1)
+		options = append(options[:len(options):len(options)], interfaces.WithAssistantPrefix(text))
+	default:
+		l.continued.Add(1)
+		prompt = continuePrompt(prompt, text)
+	}
+	return interfaces.GenerateStream(ctx, llm, prompt, options...)
+}
+
+// prefills reports whether the LLM can continue a response from a prefix
+func prefills(llm interfaces.LLM) bool {
+	caps, ok := interfaces.GetCapabilities(llm)
+	return ok && caps.AssistantPrefill
+}
+
+// continuePrompt asks to continue a partial response to the prompt
+func continuePrompt(prompt, text string) string {
+	return fmt.Sprintf(`%s
+
+Your answer to the request above was cut off. Continue it exactly where it stops, without repeating any of it and without any preamble.
+
+<<<BEGIN PARTIAL ANSWER>>>
+%s
+<<<END PARTIAL ANSWER>>>`, prompt, text)
+}
+
+// resumable reports whether a failed stream should be resumed. Errors that
+// say retrying won't help, such as invalid requests, end the stream unless
+// a fallback can take over.
+func resumable(err error, llms int) bool {
+	var retryable interface{ Retryable() bool }
+	if errors.As(err, &retryable) && !retryable.Retryable() {
+		return llms > 1
+	}
+	return true
+}
+
+// drain reads the rest of a stream whose reader stopped
+func drain(events <-chan interfaces.StreamEvent) {
+	go func() {
+		for range events {
+		}
+	}()
+}
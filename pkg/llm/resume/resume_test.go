@@ -0,0 +1,151 @@
+package resume_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+	"github.com/run-bigpig/llm-agent/pkg/llm/resume"
+)
+
+// attempt is the scripted outcome of one stream
+type attempt struct {
+	pieces []string
+	err    error
+}
+
+// scriptedLLM streams the scripted attempts in turn and records the prompts
+// and prefixes it was called with
+type scriptedLLM struct {
+	mu       sync.Mutex
+	attempts []attempt
+	prefill  bool
+	prompts  []string
+	prefixes []string
+}
+
+func (m *scriptedLLM) Generate(ctx context.Context, prompt string, options ...interfaces.GenerateOption) (string, error) {
+	return interfaces.CollectStream(m.stream(prompt, options))
+}
+
+func (m *scriptedLLM) GenerateWithTools(ctx context.Context, prompt string, tools []interfaces.Tool, options ...interfaces.GenerateOption) (string, error) {
+	return m.Generate(ctx, prompt, options...)
+}
+
+func (m *scriptedLLM) GenerateStream(ctx context.Context, prompt string, options ...interfaces.GenerateOption) (<-chan interfaces.StreamEvent, error) {
+	return m.stream(prompt, options), nil
+}
+
+func (m *scriptedLLM) stream(prompt string, options []interfaces.GenerateOption) <-chan interfaces.StreamEvent {
+	params := &interfaces.GenerateOptions{}
+	for _, option := range options {
+		option(params)
+	}
+
+	m.mu.Lock()
+	a := m.attempts[0]
+	m.attempts = m.attempts[1:]
+	m.prompts = append(m.prompts, prompt)
+	m.prefixes = append(m.prefixes, params.AssistantPrefix)
+	m.mu.Unlock()
+
+	events := make(chan interfaces.StreamEvent, len(a.pieces)+1)
+	for _, piece := range a.pieces {
+		events <- interfaces.StreamEvent{Type: interfaces.StreamEventText, Content: piece}
+	}
+	if a.err != nil {
+		events <- interfaces.StreamEvent{Type: interfaces.StreamEventError, Err: a.err}
+	} else {
+		events <- interfaces.StreamEvent{Type: interfaces.StreamEventDone}
+	}
+	close(events)
+	return events
+}
+
+func (m *scriptedLLM) Name() string { return "scripted" }
+
+func (m *scriptedLLM) Capabilities() interfaces.Capabilities {
+	return interfaces.Capabilities{Streaming: true, AssistantPrefill: m.prefill}
+}
+
+var errDisconnected = errors.New("connection reset by peer")
+
+func collect(t *testing.T, llm *resume.LLM) (string, error) {
+	events, err := llm.GenerateStream(context.Background(), "Tell me about Paris")
+	require.NoError(t, err)
+	return interfaces.CollectStream(events)
+}
+
+func TestResumeWithPrefill(t *testing.T) {
+	llm := &scriptedLLM{prefill: true, attempts: []attempt{
+		{pieces: []string{"Paris is the ", "capital "}, err: errDisconnected},
+		{pieces: []string{" of France."}},
+	}}
+	text, err := collect(t, resume.New(llm))
+	require.NoError(t, err)
+
+	// The whitespace the prefix ended with is not repeated
+	assert.Equal(t, "Paris is the capital of France.", text)
+	assert.Equal(t, []string{"", "Paris is the capital "}, llm.prefixes)
+	assert.Equal(t, "Tell me about Paris", llm.prompts[1])
+}
+
+func TestResumeWithContinuePrompt(t *testing.T) {
+	primary := &scriptedLLM{prefill: true, attempts: []attempt{{pieces: []string{"Paris is"}, err: errDisconnected}}}
+	fallback := &scriptedLLM{attempts: []attempt{{pieces: []string{" the capital of France."}}}}
+
+	llm := resume.New(primary, resume.WithFallbacks(fallback))
+	text, err := collect(t, llm)
+	require.NoError(t, err)
+
+	assert.Equal(t, "Paris is the capital of France.", text)
+	require.Len(t, fallback.prompts, 1)
+	assert.True(t, strings.HasPrefix(fallback.prompts[0], "Tell me about Paris\n\n"))
+	assert.Contains(t, fallback.prompts[0], "<<<BEGIN PARTIAL ANSWER>>>\nParis is\n<<<END PARTIAL ANSWER>>>")
+	assert.Equal(t, []string{""}, fallback.prefixes)
+	assert.Equal(t, resume.Stats{Streams: 1, Continued: 1}, llm.Stats())
+}
+
+func TestRestartBeforeText(t *testing.T) {
+	llm := &scriptedLLM{attempts: []attempt{
+		{err: errDisconnected},
+		{pieces: []string{"Paris."}},
+	}}
+	wrapped := resume.New(llm)
+	text, err := collect(t, wrapped)
+	require.NoError(t, err)
+	assert.Equal(t, "Paris.", text)
+	assert.Equal(t, []string{"Tell me about Paris", "Tell me about Paris"}, llm.prompts)
+	assert.Equal(t, int64(1), wrapped.Stats().Restarted)
+}
+
+func TestGiveUpAfterMaxResumes(t *testing.T) {
+	llm := &scriptedLLM{prefill: true, attempts: []attempt{
+		{pieces: []string{"Paris"}, err: errDisconnected},
+		{pieces: []string{" is"}, err: errDisconnected},
+	}}
+	wrapped := resume.New(llm, resume.WithMaxResumes(1))
+	text, err := collect(t, wrapped)
+	assert.Equal(t, "Paris is", text)
+	assert.ErrorIs(t, err, errDisconnected)
+	assert.Equal(t, int64(1), wrapped.Stats().Failures)
+}
+
+// permanentError is an error that retrying won't fix
+type permanentError struct{}
+
+func (permanentError) Error() string   { return "invalid request" }
+func (permanentError) Retryable() bool { return false }
+
+func TestPermanentErrorsAreNotResumed(t *testing.T) {
+	llm := &scriptedLLM{attempts: []attempt{{pieces: []string{"Paris"}, err: permanentError{}}}}
+	_, err := collect(t, resume.New(llm))
+	assert.ErrorIs(t, err, permanentError{})
+	assert.Len(t, llm.prompts, 1)
+}
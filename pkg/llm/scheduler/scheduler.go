@@ -0,0 +1,352 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+	"github.com/run-bigpig/llm-agent/pkg/multitenancy"
+)
+
+// ErrQueueFull is returned when the queue for a priority class is full
+var ErrQueueFull = errors.New("scheduler: queue is full")
+
+// Priority is the priority class of a request. Higher priorities are always
+// dispatched before lower ones.
+type Priority int
+
+const (
+	// PriorityBackground is for batch jobs such as evaluations and workflows
+	PriorityBackground Priority = iota
+
+	// PriorityNormal is the default priority
+	PriorityNormal
+
+	// PriorityInteractive is for user-facing agent traffic
+	PriorityInteractive
+)
+
+// priorities lists the priority classes from highest to lowest
+var priorities = []Priority{PriorityInteractive, PriorityNormal, PriorityBackground}
+
+// String returns the name of the priority class
+func (p Priority) String() string {
+	switch p {
+	case PriorityBackground:
+		return "background"
+	case PriorityNormal:
+		return "normal"
+	case PriorityInteractive:
+		return "interactive"
+	default:
+		return fmt.Sprintf("priority(%d)", int(p))
+	}
+}
+
+type contextKey string
+
+const priorityKey contextKey = "scheduler_priority"
+
+// WithPriority returns a context that schedules requests with the given priority
+func WithPriority(ctx context.Context, priority Priority) context.Context {
+	return context.WithValue(ctx, priorityKey, priority)
+}
+
+// GetPriority returns the priority from the context, or PriorityNormal
+func GetPriority(ctx context.Context) Priority {
+	if priority, ok := ctx.Value(priorityKey).(Priority); ok {
+		return priority
+	}
+	return PriorityNormal
+}
+
+// Stats is a snapshot of the scheduler
+type Stats struct {
+	InFlight   int              `json:"in_flight"`
+	QueueDepth map[Priority]int `json:"queue_depth"`
+}
+
+// waiter is a queued request
+type waiter struct {
+	ready   chan struct{}
+	granted bool
+}
+
+// queue holds the waiters of a single priority class, with one FIFO per
+// organization that are served round-robin
+type queue struct {
+	orgs   []string
+	byOrg  map[string][]*waiter
+	next   int
+	length int
+}
+
+// push adds a waiter to the organization's FIFO
+func (q *queue) push(orgID string, w *waiter) {
+	if _, ok := q.byOrg[orgID]; !ok {
+		q.orgs = append(q.orgs, orgID)
+	}
+	q.byOrg[orgID] = append(q.byOrg[orgID], w)
+	q.length++
+}
+
+// pop removes the next waiter, rotating between organizations
+func (q *queue) pop() *waiter {
+	if q.length == 0 {
+		return nil
+	}
+
+	if q.next >= len(q.orgs) {
+		q.next = 0
+	}
+	orgID := q.orgs[q.next]
+	waiters := q.byOrg[orgID]
+	w := waiters[0]
+
+	if len(waiters) == 1 {
+		q.removeOrg(q.next)
+	} else {
+		q.byOrg[orgID] = waiters[1:]
+		q.next++
+	}
+	q.length--
+
+	return w
+}
+
+// remove removes a waiter that gave up before being granted
+func (q *queue) remove(orgID string, w *waiter) bool {
+	waiters := q.byOrg[orgID]
+	for i, candidate := range waiters {
+		if candidate != w {
+			continue
+		}
+		if len(waiters) == 1 {
+			for j, org := range q.orgs {
+				if org == orgID {
+					q.removeOrg(j)
+					break
+				}
+			}
+		} else {
+			q.byOrg[orgID] = append(waiters[:i:i], waiters[i+1:]...)
+		}
+		q.length--
+		return true
+	}
+	return false
+}
+
+// removeOrg removes the organization at index i from the rotation
+func (q *queue) removeOrg(i int) {
+	delete(q.byOrg, q.orgs[i])
+	q.orgs = append(q.orgs[:i], q.orgs[i+1:]...)
+	if q.next > i {
+		q.next--
+	}
+}
+
+// Scheduler limits the number of concurrent LLM requests and dispatches
+// queued requests by priority class, round-robin between organizations
+// within a class
+type Scheduler struct {
+	maxConcurrency int
+	maxQueueDepth  map[Priority]int
+
+	mu       sync.Mutex
+	inFlight int
+	queues   map[Priority]*queue
+}
+
+// Option represents an option for configuring the scheduler
+type Option func(*Scheduler)
+
+// WithMaxConcurrency sets the maximum number of requests in flight
+func WithMaxConcurrency(n int) Option {
+	return func(s *Scheduler) {
+		s.maxConcurrency = n
+	}
+}
+
+// WithMaxQueueDepth sets the maximum number of queued requests for a
+// priority class. Zero means unlimited.
+func WithMaxQueueDepth(priority Priority, depth int) Option {
+	return func(s *Scheduler) {
+		s.maxQueueDepth[priority] = depth
+	}
+}
+
+// New creates a new scheduler
+func New(options ...Option) *Scheduler {
+	s := &Scheduler{
+		maxConcurrency: 10,
+		maxQueueDepth: map[Priority]int{
+			PriorityInteractive: 0,
+			PriorityNormal:      1000,
+			PriorityBackground:  1000,
+		},
+		queues: make(map[Priority]*queue),
+	}
+
+	for _, option := range options {
+		option(s)
+	}
+
+	for _, priority := range priorities {
+		s.queues[priority] = &queue{byOrg: make(map[string][]*waiter)}
+	}
+
+	return s
+}
+
+// Acquire waits for a slot using the priority and organization in the
+// context. The returned function must be called to release the slot.
+func (s *Scheduler) Acquire(ctx context.Context) (func(), error) {
+	priority := GetPriority(ctx)
+	q, ok := s.queues[priority]
+	if !ok {
+		return nil, fmt.Errorf("unknown priority: %s", priority)
+	}
+
+	orgID, err := multitenancy.GetOrgID(ctx)
+	if err != nil {
+		orgID = "default"
+	}
+
+	s.mu.Lock()
+	if s.inFlight < s.maxConcurrency && s.queuedLocked() == 0 {
+		s.inFlight++
+		s.mu.Unlock()
+		return s.releaseFunc(), nil
+	}
+
+	if depth := s.maxQueueDepth[priority]; depth > 0 && q.length >= depth {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("%w: %s", ErrQueueFull, priority)
+	}
+
+	w := &waiter{ready: make(chan struct{})}
+	q.push(orgID, w)
+	s.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return s.releaseFunc(), nil
+	case <-ctx.Done():
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if w.granted {
+			// The slot was granted as the context was cancelled; pass it on
+			s.inFlight--
+			s.dispatchLocked()
+		} else {
+			q.remove(orgID, w)
+		}
+		return nil, ctx.Err()
+	}
+}
+
+// releaseFunc returns a function that releases a slot once
+func (s *Scheduler) releaseFunc() func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			s.inFlight--
+			s.dispatchLocked()
+		})
+	}
+}
+
+// dispatchLocked grants free slots to the highest priority waiters. It must
+// be called with the lock held.
+func (s *Scheduler) dispatchLocked() {
+	for s.inFlight < s.maxConcurrency {
+		var w *waiter
+		for _, priority := range priorities {
+			if w = s.queues[priority].pop(); w != nil {
+				break
+			}
+		}
+		if w == nil {
+			return
+		}
+
+		s.inFlight++
+		w.granted = true
+		close(w.ready)
+	}
+}
+
+// queuedLocked returns the total number of queued requests
+func (s *Scheduler) queuedLocked() int {
+	total := 0
+	for _, q := range s.queues {
+		total += q.length
+	}
+	return total
+}
+
+// Stats returns a snapshot of the scheduler
+func (s *Scheduler) Stats() Stats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats := Stats{
+		InFlight:   s.inFlight,
+		QueueDepth: make(map[Priority]int, len(s.queues)),
+	}
+	for priority, q := range s.queues {
+		stats.QueueDepth[priority] = q.length
+	}
+	return stats
+}
+
+// LLM wraps an LLM so that its requests go through the scheduler
+type LLM struct {
+	llm       interfaces.LLM
+	scheduler *Scheduler
+}
+
+// NewLLM wraps the LLM with the scheduler. Several LLMs can share a scheduler.
+func NewLLM(llm interfaces.LLM, scheduler *Scheduler) *LLM {
+	return &LLM{
+		llm:       llm,
+		scheduler: scheduler,
+	}
+}
+
+// Generate generates text based on the provided prompt
+func (l *LLM) Generate(ctx context.Context, prompt string, options ...interfaces.GenerateOption) (string, error) {
+	release, err := l.scheduler.Acquire(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer release()
+
+	return l.llm.Generate(ctx, prompt, options...)
+}
+
+// GenerateWithTools generates text and can use tools
+func (l *LLM) GenerateWithTools(ctx context.Context, prompt string, tools []interfaces.Tool, options ...interfaces.GenerateOption) (string, error) {
+	release, err := l.scheduler.Acquire(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer release()
+
+	return l.llm.GenerateWithTools(ctx, prompt, tools, options...)
+}
+
+// Name returns the name of the LLM provider
+func (l *LLM) Name() string {
+	return l.llm.Name()
+}
+
+// Unwrap returns the wrapped LLM
+func (l *LLM) Unwrap() interfaces.LLM {
+	return l.llm
+}
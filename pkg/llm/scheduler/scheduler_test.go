@@ -0,0 +1,107 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/run-bigpig/llm-agent/pkg/multitenancy"
+)
+
+func TestSchedulerPriorityAndFairness(t *testing.T) {
+	s := New(WithMaxConcurrency(1))
+
+	hold, err := s.Acquire(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to acquire: %v", err)
+	}
+
+	var mu sync.Mutex
+	var order []string
+	var wg sync.WaitGroup
+	queued := 0
+
+	enqueue := func(name, org string, priority Priority) {
+		ctx := WithPriority(multitenancy.WithOrgID(context.Background(), org), priority)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			release, err := s.Acquire(ctx)
+			if err != nil {
+				t.Errorf("Failed to acquire %s: %v", name, err)
+				return
+			}
+			mu.Lock()
+			order = append(order, name)
+			mu.Unlock()
+			release()
+		}()
+
+		// Wait until the request is queued so the queue order is deterministic
+		queued++
+		for s.queued() < queued {
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	enqueue("bg-a", "a", PriorityBackground)
+	enqueue("int-a1", "a", PriorityInteractive)
+	enqueue("int-a2", "a", PriorityInteractive)
+	enqueue("int-b1", "b", PriorityInteractive)
+
+	hold()
+	wg.Wait()
+
+	expected := []string{"int-a1", "int-b1", "int-a2", "bg-a"}
+	if len(order) != len(expected) {
+		t.Fatalf("Expected order %v, got %v", expected, order)
+	}
+	for i := range expected {
+		if order[i] != expected[i] {
+			t.Fatalf("Expected order %v, got %v", expected, order)
+		}
+	}
+}
+
+func TestSchedulerQueueLimitAndCancellation(t *testing.T) {
+	s := New(WithMaxConcurrency(1), WithMaxQueueDepth(PriorityBackground, 1))
+
+	hold, err := s.Acquire(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to acquire: %v", err)
+	}
+	defer hold()
+
+	background := WithPriority(context.Background(), PriorityBackground)
+	ctx, cancel := context.WithCancel(background)
+
+	errs := make(chan error, 1)
+	go func() {
+		_, err := s.Acquire(ctx)
+		errs <- err
+	}()
+	for s.queued() != 1 {
+		time.Sleep(time.Millisecond)
+	}
+
+	if _, err := s.Acquire(background); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("Expected ErrQueueFull, got %v", err)
+	}
+
+	cancel()
+	if err := <-errs; !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+	if depth := s.Stats().QueueDepth[PriorityBackground]; depth != 0 {
+		t.Errorf("Expected cancelled request to leave the queue, got depth %d", depth)
+	}
+}
+
+// queued returns the total number of queued requests
+func (s *Scheduler) queued() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.queuedLocked()
+}
@@ -16,6 +16,7 @@ type GenerateParams struct {
 	TopK             int      // Limit vocabulary to top K tokens
 	RepeatPenalty    float64  // Penalize token repetition
 	Reasoning        string   // Reasoning mode for Claude models (none, minimal, comprehensive)
+	MaxTokens        int      // Maximum number of tokens to generate; zero uses the provider's default
 }
 
 // DefaultGenerateParams returns default generation parameters
@@ -0,0 +1,181 @@
+package vertex
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"cloud.google.com/go/vertexai/genai"
+	"google.golang.org/api/iterator"
+
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+)
+
+// DefaultCacheTTL is how long cached content lives unless CacheConfig sets
+// another TTL
+const DefaultCacheTTL = time.Hour
+
+// CacheConfig is the content of a context cache. Vertex AI only caches
+// content above a minimum size, e.g. 32,768 tokens for Gemini 1.5 models, so
+// caches are meant for large static corpora and long system prompts.
+type CacheConfig struct {
+	// SystemInstruction is a system prompt to cache
+	SystemInstruction string
+
+	// Documents are texts to cache, in order, as user content that precedes
+	// the prompt of each call
+	Documents []string
+
+	// Tools are tools to cache. Calls with cached content can't send tools of
+	// their own, so GenerateWithTools uses the cached tools.
+	Tools []interfaces.Tool
+
+	// TTL is how long the cache lives. Defaults to DefaultCacheTTL.
+	TTL time.Duration
+}
+
+// Cache is content cached by Vertex AI
+type Cache struct {
+	// Name is the resource name, used to refer to the cache
+	Name string
+
+	// Model is the model the cache was created for
+	Model string
+
+	// ExpireTime is when the cache is deleted
+	ExpireTime time.Time
+
+	// CreateTime and UpdateTime are when the cache was created and last
+	// updated
+	CreateTime time.Time
+	UpdateTime time.Time
+}
+
+// WithCachedContent prepends the cached content with the given name to the
+// prompt of the call. The cache must have been created for the client's
+// model.
+func WithCachedContent(name string) interfaces.GenerateOption {
+	return func(options *interfaces.GenerateOptions) {
+		options.CachedContent = name
+	}
+}
+
+// CreateCache caches content for the client's model and returns the cache,
+// whose name is passed to calls with WithCachedContent
+func (c *Client) CreateCache(ctx context.Context, config CacheConfig) (*Cache, error) {
+	content, err := c.cachedContent(config)
+	if err != nil {
+		return nil, err
+	}
+
+	var cached *genai.CachedContent
+	err = c.withRetry(ctx, func() error {
+		var createErr error
+		cached, createErr = c.client.CreateCachedContent(ctx, content)
+		return createErr
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cached content: %w", err)
+	}
+
+	c.logger.Debug("Created cached content", "name", cached.Name, "expires", cached.Expiration.ExpireTime)
+	return newCache(cached), nil
+}
+
+// cachedContent converts the config to the cached content of the client's
+// model
+func (c *Client) cachedContent(config CacheConfig) (*genai.CachedContent, error) {
+	if config.SystemInstruction == "" && len(config.Documents) == 0 && len(config.Tools) == 0 {
+		return nil, errors.New("cache config has no content")
+	}
+
+	ttl := config.TTL
+	if ttl <= 0 {
+		ttl = DefaultCacheTTL
+	}
+	content := &genai.CachedContent{
+		Model:      c.model,
+		Expiration: genai.ExpireTimeOrTTL{TTL: ttl},
+	}
+	if config.SystemInstruction != "" {
+		content.SystemInstruction = genai.NewUserContent(genai.Text(config.SystemInstruction))
+	}
+	for _, document := range config.Documents {
+		content.Contents = append(content.Contents, genai.NewUserContent(genai.Text(document)))
+	}
+	if len(config.Tools) > 0 {
+		content.Tools = c.convertTools(config.Tools)
+	}
+	return content, nil
+}
+
+// GetCache returns the cache with the given name
+func (c *Client) GetCache(ctx context.Context, name string) (*Cache, error) {
+	cached, err := c.client.GetCachedContent(ctx, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cached content: %w", err)
+	}
+	return newCache(cached), nil
+}
+
+// ListCaches returns the caches of the project and location
+func (c *Client) ListCaches(ctx context.Context) ([]*Cache, error) {
+	var caches []*Cache
+	it := c.client.ListCachedContents(ctx)
+	for {
+		cached, err := it.Next()
+		if errors.Is(err, iterator.Done) {
+			return caches, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to list cached content: %w", err)
+		}
+		caches = append(caches, newCache(cached))
+	}
+}
+
+// ExtendCache sets the cache to expire ttl from now, e.g. to keep a cache
+// that is still in use
+func (c *Client) ExtendCache(ctx context.Context, name string, ttl time.Duration) (*Cache, error) {
+	if ttl <= 0 {
+		return nil, errors.New("cache TTL must be positive")
+	}
+	cached, err := c.client.UpdateCachedContent(ctx, &genai.CachedContent{Name: name}, &genai.CachedContentToUpdate{
+		Expiration: &genai.ExpireTimeOrTTL{TTL: ttl},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to update cached content: %w", err)
+	}
+	return newCache(cached), nil
+}
+
+// DeleteCache deletes the cache, e.g. when the cached content has changed.
+// Calls that still refer to it fail.
+func (c *Client) DeleteCache(ctx context.Context, name string) error {
+	if err := c.client.DeleteCachedContent(ctx, name); err != nil {
+		return fmt.Errorf("failed to delete cached content: %w", err)
+	}
+	return nil
+}
+
+// newCache describes the cached content
+func newCache(cached *genai.CachedContent) *Cache {
+	return &Cache{
+		Name:       cached.Name,
+		Model:      cached.Model,
+		ExpireTime: cached.Expiration.ExpireTime,
+		CreateTime: cached.CreateTime,
+		UpdateTime: cached.UpdateTime,
+	}
+}
+
+// useCache sets the cached content of the call on the model and reports
+// whether there is one
+func useCache(model *genai.GenerativeModel, params *interfaces.GenerateOptions) bool {
+	if params.CachedContent == "" {
+		return false
+	}
+	model.CachedContentName = params.CachedContent
+	return true
+}
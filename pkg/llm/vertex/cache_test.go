@@ -0,0 +1,72 @@
+package vertex
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/vertexai/genai"
+
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+)
+
+// lookupTool is a tool to cache
+type lookupTool struct{}
+
+func (lookupTool) Name() string        { return "lookup" }
+func (lookupTool) Description() string { return "Looks up a term" }
+func (lookupTool) Parameters() map[string]interfaces.ParameterSpec {
+	return map[string]interfaces.ParameterSpec{"term": {Type: "string", Required: true}}
+}
+func (lookupTool) Run(ctx context.Context, input string) (string, error)    { return "", nil }
+func (lookupTool) Execute(ctx context.Context, args string) (string, error) { return "", nil }
+
+func TestCachedContent(t *testing.T) {
+	client := &Client{model: ModelGemini15Pro}
+
+	content, err := client.cachedContent(CacheConfig{
+		SystemInstruction: "Answer from the handbook.",
+		Documents:         []string{"Chapter 1", "Chapter 2"},
+		Tools:             []interfaces.Tool{lookupTool{}},
+	})
+	if err != nil {
+		t.Fatalf("cachedContent failed: %v", err)
+	}
+	if content.Model != ModelGemini15Pro || content.Expiration.TTL != DefaultCacheTTL {
+		t.Errorf("Expected the client's model and the default TTL, got %q and %v", content.Model, content.Expiration.TTL)
+	}
+	if content.SystemInstruction == nil || content.SystemInstruction.Parts[0] != genai.Text("Answer from the handbook.") {
+		t.Errorf("Expected the system instruction, got %+v", content.SystemInstruction)
+	}
+	if len(content.Contents) != 2 || content.Contents[1].Parts[0] != genai.Text("Chapter 2") || content.Contents[1].Role != "user" {
+		t.Errorf("Expected the documents in order as user content, got %+v", content.Contents)
+	}
+	if len(content.Tools) != 1 || content.Tools[0].FunctionDeclarations[0].Name != "lookup" {
+		t.Errorf("Expected the tool, got %+v", content.Tools)
+	}
+
+	content, err = client.cachedContent(CacheConfig{Documents: []string{"Chapter 1"}, TTL: 10 * time.Minute})
+	if err != nil || content.Expiration.TTL != 10*time.Minute {
+		t.Errorf("Expected the TTL of the config, got %+v, %v", content, err)
+	}
+
+	if _, err := client.cachedContent(CacheConfig{TTL: time.Hour}); err == nil {
+		t.Error("Expected an error for a config without content")
+	}
+}
+
+func TestGenerateWithCachedContent(t *testing.T) {
+	client := &Client{client: &genai.Client{}, model: ModelGemini15Pro}
+
+	params := &interfaces.GenerateOptions{}
+	WithCachedContent("projects/p/locations/l/cachedContents/123")(params)
+	model, _ := client.generateRequest("What does chapter 2 say?", params)
+	if model.CachedContentName != "projects/p/locations/l/cachedContents/123" {
+		t.Errorf("Expected the cached content on the model, got %q", model.CachedContentName)
+	}
+
+	model, _ = client.generateRequest("What does chapter 2 say?", &interfaces.GenerateOptions{})
+	if model.CachedContentName != "" {
+		t.Errorf("Expected no cached content, got %q", model.CachedContentName)
+	}
+}
@@ -3,17 +3,20 @@ package vertex
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"strings"
 	"time"
 
+	aiplatform "cloud.google.com/go/aiplatform/apiv1"
 	"cloud.google.com/go/vertexai/genai"
 	"github.com/cenkalti/backoff/v4"
 	"google.golang.org/api/option"
 
 	"github.com/run-bigpig/llm-agent/pkg/interfaces"
 	"github.com/run-bigpig/llm-agent/pkg/llm"
+	"github.com/run-bigpig/llm-agent/pkg/parallel"
 )
 
 // VertexAI model constants
@@ -38,15 +41,21 @@ const (
 
 // Client represents a Vertex AI client
 type Client struct {
-	client          *genai.Client
-	model           string
-	projectID       string
-	location        string
-	maxRetries      int
-	retryDelay      time.Duration
-	reasoningMode   ReasoningMode
-	logger          *slog.Logger
-	credentialsFile string
+	client            *genai.Client
+	model             string
+	projectID         string
+	location          string
+	maxRetries        int
+	retryDelay        time.Duration
+	reasoningMode     ReasoningMode
+	logger            *slog.Logger
+	credentialsFile   string
+	maxToolIterations int
+	toolHistoryBudget int
+	stepSummarizer    interfaces.LLM
+	requestTimeout    time.Duration
+	googleSearch      bool
+	prediction        *aiplatform.PredictionClient
 }
 
 // ClientOption is a function that configures the Client
@@ -101,6 +110,31 @@ func WithCredentialsFile(credentialsFile string) ClientOption {
 	}
 }
 
+// WithMaxToolIterations sets the maximum number of function calling rounds in
+// GenerateWithTools before the model is asked for a final answer
+func WithMaxToolIterations(maxIterations int) ClientOption {
+	return func(c *Client) {
+		c.maxToolIterations = maxIterations
+	}
+}
+
+// errConstraintUnsupported is returned for calls with a constraint, see
+// interfaces.WithGrammar
+var errConstraintUnsupported = errors.New("constrained decoding is not supported by the Vertex AI client")
+
+// DefaultRequestTimeout is how long a call may take unless the client or the
+// call sets another timeout
+const DefaultRequestTimeout = 2 * time.Minute
+
+// WithDefaultRequestTimeout sets how long a call may take unless it sets its
+// own timeout with interfaces.WithRequestTimeout. Zero means no limit other
+// than the deadline of the caller's context.
+func WithDefaultRequestTimeout(timeout time.Duration) ClientOption {
+	return func(c *Client) {
+		c.requestTimeout = timeout
+	}
+}
+
 // NewClient creates a new Vertex AI client
 func NewClient(ctx context.Context, projectID string, options ...ClientOption) (*Client, error) {
 	if projectID == "" {
@@ -108,13 +142,15 @@ func NewClient(ctx context.Context, projectID string, options ...ClientOption) (
 	}
 
 	client := &Client{
-		model:         DefaultModel,
-		projectID:     projectID,
-		location:      "us-central1",
-		maxRetries:    3,
-		retryDelay:    time.Second,
-		reasoningMode: ReasoningModeNone,
-		logger:        slog.Default(),
+		model:             DefaultModel,
+		projectID:         projectID,
+		location:          "us-central1",
+		maxRetries:        3,
+		retryDelay:        time.Second,
+		reasoningMode:     ReasoningModeNone,
+		logger:            slog.Default(),
+		maxToolIterations: 10,
+		requestTimeout:    DefaultRequestTimeout,
 	}
 
 	// Apply options
@@ -136,6 +172,14 @@ func NewClient(ctx context.Context, projectID string, options ...ClientOption) (
 	}
 
 	client.client = vertexClient
+
+	if client.googleSearch {
+		client.prediction, err = newPredictionClient(ctx, client.location, clientOptions)
+		if err != nil {
+			_ = vertexClient.Close()
+			return nil, fmt.Errorf("failed to create Vertex AI prediction client: %w", err)
+		}
+	}
 	return client, nil
 }
 
@@ -144,6 +188,25 @@ func (c *Client) Name() string {
 	return fmt.Sprintf("vertex:%s", c.model)
 }
 
+// Capabilities reports the features supported by Gemini models. Response
+// formats are not passed to the API, so JSON output is prompt-based.
+func (c *Client) Capabilities() interfaces.Capabilities {
+	return interfaces.Capabilities{
+		Tools:         true,
+		Vision:        true,
+		Streaming:     true,
+		ParallelTools: true,
+	}
+}
+
+// Ping checks that Vertex AI is reachable and the configured model is available
+func (c *Client) Ping(ctx context.Context) error {
+	if _, err := c.client.GenerativeModel(c.model).CountTokens(ctx, genai.Text("ping")); err != nil {
+		return fmt.Errorf("failed to reach Vertex AI: %w", err)
+	}
+	return nil
+}
+
 // GenerateWithTools implements interfaces.LLM.GenerateWithTools
 func (c *Client) GenerateWithTools(ctx context.Context, prompt string, tools []interfaces.Tool, options ...interfaces.GenerateOption) (string, error) {
 	// Apply options
@@ -157,6 +220,15 @@ func (c *Client) GenerateWithTools(ctx context.Context, prompt string, tools []i
 		option(params)
 	}
 
+	// Constraints can't be enforced, so they fail the call
+	if params.Constraint != nil {
+		return "", errConstraintUnsupported
+	}
+
+	// Limit the call to its timeout
+	ctx, cancel := interfaces.RequestContext(ctx, params.Timeout, c.requestTimeout)
+	defer cancel()
+
 	// Create parts for the prompt
 	parts := []genai.Part{genai.Text(prompt)}
 
@@ -194,130 +266,153 @@ func (c *Client) GenerateWithTools(ctx context.Context, prompt string, tools []i
 		if len(params.LLMConfig.StopSequences) > 0 {
 			model.StopSequences = params.LLMConfig.StopSequences
 		}
+		if params.LLMConfig.MaxTokens > 0 {
+			model.SetMaxOutputTokens(int32(params.LLMConfig.MaxTokens))
+		}
 	}
 
-	// Convert tools to Vertex AI format
-	if len(tools) > 0 {
+	// Convert tools to Vertex AI format. Calls with cached content use the
+	// cached tools, since they can't send tools of their own.
+	cached := useCache(model, params)
+	if len(tools) > 0 && !cached {
 		vertexTools := c.convertTools(tools)
 		model.Tools = vertexTools
 	}
 
-	// Generate content with retry logic
-	var response *genai.GenerateContentResponse
-	err := c.withRetry(ctx, func() error {
-		var genErr error
-		response, genErr = model.GenerateContent(ctx, parts...)
-		return genErr
-	})
-
-	if err != nil {
-		return "", fmt.Errorf("failed to generate content: %w", err)
-	}
-
-	// Extract response
-	if len(response.Candidates) == 0 {
-		return "", fmt.Errorf("no candidates in response")
-	}
+	// The chat session keeps the function calls and responses of each round
+	// in the history so the model can chain tool calls
+	session := model.StartChat()
+	message := parts
 
-	candidate := response.Candidates[0]
-	if candidate.Content == nil {
-		return "", fmt.Errorf("no content in response")
-	}
+	for iteration := 0; ; iteration++ {
+		response, err := c.sendMessage(ctx, session, message)
+		if err != nil {
+			return "", fmt.Errorf("failed to generate content: %w", err)
+		}
 
-	var text strings.Builder
-	var functionCalls []genai.FunctionCall
+		if len(response.Candidates) == 0 {
+			return "", fmt.Errorf("no candidates in response")
+		}
 
-	for _, part := range candidate.Content.Parts {
-		switch p := part.(type) {
-		case genai.Text:
-			text.WriteString(string(p))
-		case genai.FunctionCall:
-			functionCalls = append(functionCalls, p)
+		candidate := response.Candidates[0]
+		if candidate.Content == nil {
+			return "", fmt.Errorf("no content in response")
 		}
-	}
 
-	// If there are function calls, execute them
-	if len(functionCalls) > 0 {
-		// Execute all function calls and collect responses
-		var functionResponses []genai.Part
+		var text strings.Builder
+		var functionCalls []genai.FunctionCall
 
-		for _, funcCall := range functionCalls {
-			// Find the corresponding tool
-			var selectedTool interfaces.Tool
-			for _, tool := range tools {
-				if tool.Name() == funcCall.Name {
-					selectedTool = tool
-					break
-				}
+		for _, part := range candidate.Content.Parts {
+			switch p := part.(type) {
+			case genai.Text:
+				text.WriteString(string(p))
+			case genai.FunctionCall:
+				functionCalls = append(functionCalls, p)
 			}
+		}
 
-			if selectedTool == nil {
-				return "", fmt.Errorf("tool not found: %s", funcCall.Name)
-			}
+		if len(functionCalls) == 0 {
+			return text.String(), nil
+		}
+		if iteration >= c.maxToolIterations {
+			return "", fmt.Errorf("model kept calling functions after %d iterations", c.maxToolIterations)
+		}
 
-			// Convert arguments to JSON string
-			argsJSON, err := json.Marshal(funcCall.Args)
-			if err != nil {
-				return "", fmt.Errorf("failed to marshal function arguments: %w", err)
-			}
+		c.logger.Debug("Executing function calls", "count", len(functionCalls), "iteration", iteration+1)
 
-			// Execute the tool
-			toolResult, err := selectedTool.Execute(ctx, string(argsJSON))
-			if err != nil {
-				return "", fmt.Errorf("tool execution failed: %w", err)
-			}
+		// Execute the calls of this round concurrently. Failures are returned
+		// to the model as error responses so it can recover.
+		message, _ = parallel.Map(ctx, functionCalls, func(ctx context.Context, index int, funcCall genai.FunctionCall) (genai.Part, error) {
+			return c.executeFunctionCall(ctx, tools, funcCall), nil
+		})
 
-			// Create function response
-			funcResponse := genai.FunctionResponse{
-				Name:     funcCall.Name,
-				Response: map[string]any{"result": toolResult},
-			}
+		// Summarize earlier rounds once they take up too much of the context
+		if c.toolHistoryBudget > 0 {
+			session.History = c.compactToolHistory(ctx, session.History)
+		}
 
-			functionResponses = append(functionResponses, funcResponse)
+		// Once the iteration limit is reached, ask for an answer without
+		// further function calls. Calls with cached content can't send a
+		// tool config, so they end with an error if the model goes on.
+		if iteration+1 >= c.maxToolIterations && !cached {
+			model.ToolConfig = &genai.ToolConfig{
+				FunctionCallingConfig: &genai.FunctionCallingConfig{Mode: genai.FunctionCallingNone},
+			}
 		}
+	}
+}
 
-		// Create a new conversation with proper turn structure
-		// Turn 1: User message
-		// Turn 2: Assistant message with function calls (from previous response)
-		// Turn 3: Function responses (as user turn)
-		// Turn 4: Final assistant response
+// sendMessage sends the parts in the chat session with retries. The history is
+// restored before each attempt since a failed send leaves the message in it.
+func (c *Client) sendMessage(ctx context.Context, session *genai.ChatSession, parts []genai.Part) (*genai.GenerateContentResponse, error) {
+	history := session.History
 
-		session := model.StartChat()
+	var response *genai.GenerateContentResponse
+	err := c.withRetry(ctx, func() error {
+		session.History = history
+		var sendErr error
+		response, sendErr = session.SendMessage(ctx, parts...)
+		return sendErr
+	})
+	if err == nil {
+		c.reportUsage(ctx, response)
+	}
+	return response, err
+}
 
-		// Add the original user message
-		userMsg := &genai.Content{
-			Parts: parts,
-			Role:  "user",
-		}
-		session.History = []*genai.Content{userMsg}
+// reportUsage passes the token usage of a response to the usage callback in
+// the context
+func (c *Client) reportUsage(ctx context.Context, response *genai.GenerateContentResponse) {
+	if response == nil || response.UsageMetadata == nil {
+		return
+	}
+	interfaces.ReportUsage(ctx, interfaces.Usage{
+		Model:        c.model,
+		InputTokens:  int(response.UsageMetadata.PromptTokenCount),
+		OutputTokens: int(response.UsageMetadata.CandidatesTokenCount),
+	})
+}
 
-		// Add the assistant response with function calls
-		assistantMsg := &genai.Content{
-			Parts: candidate.Content.Parts,
-			Role:  "model",
+// executeFunctionCall runs the tool requested by the function call and wraps
+// the result or error in a function response
+func (c *Client) executeFunctionCall(ctx context.Context, tools []interfaces.Tool, funcCall genai.FunctionCall) genai.Part {
+	errorResponse := func(err error) genai.Part {
+		c.logger.Error("Function call failed", "tool", funcCall.Name, "error", err)
+		return genai.FunctionResponse{
+			Name:     funcCall.Name,
+			Response: map[string]any{"error": err.Error()},
 		}
-		session.History = append(session.History, assistantMsg)
+	}
 
-		// Send function responses and get final response
-		finalResponse, err := session.SendMessage(ctx, functionResponses...)
-		if err != nil {
-			return "", fmt.Errorf("failed to send function responses: %w", err)
+	// Find the corresponding tool
+	var selectedTool interfaces.Tool
+	for _, tool := range tools {
+		if tool.Name() == funcCall.Name {
+			selectedTool = tool
+			break
 		}
+	}
 
-		if len(finalResponse.Candidates) > 0 && finalResponse.Candidates[0].Content != nil {
-			var finalText strings.Builder
-			for _, part := range finalResponse.Candidates[0].Content.Parts {
-				if textPart, ok := part.(genai.Text); ok {
-					finalText.WriteString(string(textPart))
-				}
-			}
-			return finalText.String(), nil
-		}
+	if selectedTool == nil {
+		return errorResponse(fmt.Errorf("tool not found: %s", funcCall.Name))
+	}
 
-		return "", fmt.Errorf("no final response received")
+	// Convert arguments to JSON string
+	argsJSON, err := json.Marshal(funcCall.Args)
+	if err != nil {
+		return errorResponse(fmt.Errorf("failed to marshal function arguments: %w", err))
+	}
+
+	// Execute the tool
+	toolResult, err := selectedTool.Execute(ctx, string(argsJSON))
+	if err != nil {
+		return errorResponse(fmt.Errorf("tool execution failed: %w", err))
 	}
 
-	return text.String(), nil
+	return genai.FunctionResponse{
+		Name:     funcCall.Name,
+		Response: map[string]any{"result": toolResult},
+	}
 }
 
 // Generate implements interfaces.LLM.Generate
@@ -333,6 +428,61 @@ func (c *Client) Generate(ctx context.Context, prompt string, options ...interfa
 		option(params)
 	}
 
+	// Constraints can't be enforced, so they fail the call
+	if params.Constraint != nil {
+		return "", errConstraintUnsupported
+	}
+
+	// Limit the call to its timeout
+	ctx, cancel := interfaces.RequestContext(ctx, params.Timeout, c.requestTimeout)
+	defer cancel()
+
+	if c.prediction != nil {
+		return c.generateGrounded(ctx, prompt, params)
+	}
+
+	model, parts := c.generateRequest(prompt, params)
+	return c.generateContent(ctx, model, parts)
+}
+
+// generateContent generates content with the model and returns the text of
+// the response
+func (c *Client) generateContent(ctx context.Context, model *genai.GenerativeModel, parts []genai.Part) (string, error) {
+	// Generate content with retry logic
+	var response *genai.GenerateContentResponse
+	err := c.withRetry(ctx, func() error {
+		var genErr error
+		response, genErr = model.GenerateContent(ctx, parts...)
+		return genErr
+	})
+
+	if err != nil {
+		return "", fmt.Errorf("failed to generate content: %w", err)
+	}
+	c.reportUsage(ctx, response)
+
+	// Extract text from response
+	if len(response.Candidates) == 0 {
+		return "", fmt.Errorf("no candidates in response")
+	}
+
+	candidate := response.Candidates[0]
+	if candidate.Content == nil || len(candidate.Content.Parts) == 0 {
+		return "", fmt.Errorf("no content in response")
+	}
+
+	var result strings.Builder
+	for _, part := range candidate.Content.Parts {
+		if textPart, ok := part.(genai.Text); ok {
+			result.WriteString(string(textPart))
+		}
+	}
+
+	return result.String(), nil
+}
+
+// generateRequest builds the model and parts of a Generate call
+func (c *Client) generateRequest(prompt string, params *interfaces.GenerateOptions) (*genai.GenerativeModel, []genai.Part) {
 	// Create parts for the prompt
 	parts := []genai.Part{genai.Text(prompt)}
 
@@ -370,38 +520,13 @@ func (c *Client) Generate(ctx context.Context, prompt string, options ...interfa
 		if len(params.LLMConfig.StopSequences) > 0 {
 			model.StopSequences = params.LLMConfig.StopSequences
 		}
-	}
-
-	// Generate content with retry logic
-	var response *genai.GenerateContentResponse
-	err := c.withRetry(ctx, func() error {
-		var genErr error
-		response, genErr = model.GenerateContent(ctx, parts...)
-		return genErr
-	})
-
-	if err != nil {
-		return "", fmt.Errorf("failed to generate content: %w", err)
-	}
-
-	// Extract text from response
-	if len(response.Candidates) == 0 {
-		return "", fmt.Errorf("no candidates in response")
-	}
-
-	candidate := response.Candidates[0]
-	if candidate.Content == nil || len(candidate.Content.Parts) == 0 {
-		return "", fmt.Errorf("no content in response")
-	}
-
-	var result strings.Builder
-	for _, part := range candidate.Content.Parts {
-		if textPart, ok := part.(genai.Text); ok {
-			result.WriteString(string(textPart))
+		if params.LLMConfig.MaxTokens > 0 {
+			model.SetMaxOutputTokens(int32(params.LLMConfig.MaxTokens))
 		}
 	}
+	useCache(model, params)
 
-	return result.String(), nil
+	return model, parts
 }
 
 // convertMessages converts llm.Message to Vertex AI parts
@@ -504,6 +629,9 @@ func (c *Client) withRetry(ctx context.Context, fn func() error) error {
 
 // Close closes the Vertex AI client
 func (c *Client) Close() error {
+	if c.prediction != nil {
+		_ = c.prediction.Close()
+	}
 	if c.client != nil {
 		return c.client.Close()
 	}
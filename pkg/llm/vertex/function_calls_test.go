@@ -0,0 +1,214 @@
+package vertex
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/aiplatform/apiv1beta1/aiplatformpb"
+	"cloud.google.com/go/vertexai/genai"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+)
+
+// fakePrediction is a Vertex AI prediction service answering each
+// GenerateContent request with the next scripted response
+type fakePrediction struct {
+	aiplatformpb.UnimplementedPredictionServiceServer
+
+	mu        sync.Mutex
+	requests  []*aiplatformpb.GenerateContentRequest
+	responses []*aiplatformpb.Content
+}
+
+func (s *fakePrediction) GenerateContent(ctx context.Context, req *aiplatformpb.GenerateContentRequest) (*aiplatformpb.GenerateContentResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.requests = append(s.requests, req)
+	if len(s.responses) == 0 {
+		return nil, fmt.Errorf("unexpected request %d", len(s.requests))
+	}
+	content := s.responses[0]
+	s.responses = s.responses[1:]
+	return &aiplatformpb.GenerateContentResponse{Candidates: []*aiplatformpb.Candidate{{Content: content}}}, nil
+}
+
+func modelText(text string) *aiplatformpb.Content {
+	return &aiplatformpb.Content{Role: "model", Parts: []*aiplatformpb.Part{{Data: &aiplatformpb.Part_Text{Text: text}}}}
+}
+
+func modelCalls(calls ...*aiplatformpb.FunctionCall) *aiplatformpb.Content {
+	content := &aiplatformpb.Content{Role: "model"}
+	for _, call := range calls {
+		content.Parts = append(content.Parts, &aiplatformpb.Part{Data: &aiplatformpb.Part_FunctionCall{FunctionCall: call}})
+	}
+	return content
+}
+
+func functionCall(name string, args map[string]interface{}) *aiplatformpb.FunctionCall {
+	s, _ := structpb.NewStruct(args)
+	return &aiplatformpb.FunctionCall{Name: name, Args: s}
+}
+
+// newFakeVertexClient starts the fake service and returns a client talking to it
+func newFakeVertexClient(t *testing.T, service *fakePrediction, maxToolIterations int) *Client {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	server := grpc.NewServer()
+	aiplatformpb.RegisterPredictionServiceServer(server, service)
+	go func() { _ = server.Serve(listener) }()
+	t.Cleanup(server.Stop)
+
+	genaiClient, err := genai.NewClient(context.Background(), "project", "us-central1",
+		option.WithEndpoint(listener.Addr().String()),
+		option.WithoutAuthentication(),
+		option.WithGRPCDialOption(grpc.WithTransportCredentials(insecure.NewCredentials())),
+	)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	t.Cleanup(func() { _ = genaiClient.Close() })
+
+	return &Client{
+		client:            genaiClient,
+		model:             ModelGemini15Pro,
+		maxRetries:        1,
+		retryDelay:        time.Millisecond,
+		logger:            slog.New(slog.NewTextHandler(io.Discard, nil)),
+		maxToolIterations: maxToolIterations,
+	}
+}
+
+// termTool echoes the term it is asked to look up
+type termTool struct {
+	lookupTool
+
+	mu    sync.Mutex
+	terms []string
+}
+
+func (t *termTool) Execute(ctx context.Context, args string) (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.terms = append(t.terms, args)
+	if strings.Contains(args, "broken") {
+		return "", fmt.Errorf("index unavailable")
+	}
+	return "definition of " + args, nil
+}
+
+// functionResponses returns the function responses in the last content of a
+// request by function name
+func functionResponses(req *aiplatformpb.GenerateContentRequest) map[string]map[string]interface{} {
+	responses := make(map[string]map[string]interface{})
+	last := req.Contents[len(req.Contents)-1]
+	for _, part := range last.Parts {
+		if response := part.GetFunctionResponse(); response != nil {
+			responses[response.Name] = response.Response.AsMap()
+		}
+	}
+	return responses
+}
+
+func TestGenerateWithToolsLoop(t *testing.T) {
+	service := &fakePrediction{responses: []*aiplatformpb.Content{
+		modelCalls(
+			functionCall("lookup", map[string]interface{}{"term": "go"}),
+			functionCall("search", map[string]interface{}{"query": "go"}),
+		),
+		modelCalls(functionCall("lookup", map[string]interface{}{"term": "broken"})),
+		modelText("Go is a language."),
+	}}
+	client := newFakeVertexClient(t, service, 10)
+	tool := &termTool{}
+
+	response, err := client.GenerateWithTools(context.Background(), "What is Go?", []interfaces.Tool{tool})
+	if err != nil {
+		t.Fatalf("GenerateWithTools failed: %v", err)
+	}
+	if response != "Go is a language." {
+		t.Errorf("Expected the final answer, got %q", response)
+	}
+	if len(tool.terms) != 2 {
+		t.Errorf("Expected the tool to be called in both rounds, got %v", tool.terms)
+	}
+
+	if len(service.requests) != 3 {
+		t.Fatalf("Expected 3 requests, got %d", len(service.requests))
+	}
+
+	// Each round sends the whole conversation with the function responses
+	second := service.requests[1]
+	if len(second.Contents) != 3 || second.Contents[1].Role != "model" || second.Contents[2].Role != "user" {
+		t.Fatalf("Expected the prompt, the function calls and the responses, got %v", second.Contents)
+	}
+	responses := functionResponses(second)
+	if responses["lookup"]["result"] != `definition of {"term":"go"}` {
+		t.Errorf("Expected the lookup result, got %v", responses["lookup"])
+	}
+	if responses["search"]["error"] != "tool not found: search" {
+		t.Errorf("Expected unknown tools to be answered with an error, got %v", responses["search"])
+	}
+
+	third := service.requests[2]
+	if len(third.Contents) != 5 {
+		t.Fatalf("Expected the history of both rounds, got %d contents", len(third.Contents))
+	}
+	if responses := functionResponses(third); responses["lookup"]["error"] != "tool execution failed: index unavailable" {
+		t.Errorf("Expected the tool error to be returned to the model, got %v", responses["lookup"])
+	}
+	for i, req := range service.requests {
+		if req.ToolConfig != nil {
+			t.Errorf("Expected no tool config in request %d, got %v", i, req.ToolConfig)
+		}
+	}
+}
+
+func TestGenerateWithToolsIterationLimit(t *testing.T) {
+	t.Run("final answer", func(t *testing.T) {
+		service := &fakePrediction{responses: []*aiplatformpb.Content{
+			modelCalls(functionCall("lookup", map[string]interface{}{"term": "go"})),
+			modelText("Go is a language."),
+		}}
+		client := newFakeVertexClient(t, service, 1)
+
+		response, err := client.GenerateWithTools(context.Background(), "What is Go?", []interfaces.Tool{&termTool{}})
+		if err != nil || response != "Go is a language." {
+			t.Fatalf("Expected the final answer, got %q, %v", response, err)
+		}
+
+		// The last request forbids further function calls
+		config := service.requests[1].GetToolConfig().GetFunctionCallingConfig()
+		if config.GetMode() != aiplatformpb.FunctionCallingConfig_NONE {
+			t.Errorf("Expected function calling to be turned off, got %v", config)
+		}
+	})
+
+	t.Run("model keeps calling", func(t *testing.T) {
+		service := &fakePrediction{responses: []*aiplatformpb.Content{
+			modelCalls(functionCall("lookup", map[string]interface{}{"term": "go"})),
+			modelCalls(functionCall("lookup", map[string]interface{}{"term": "go"})),
+		}}
+		client := newFakeVertexClient(t, service, 1)
+
+		_, err := client.GenerateWithTools(context.Background(), "What is Go?", []interfaces.Tool{&termTool{}})
+		if err == nil || err.Error() != "model kept calling functions after 1 iterations" {
+			t.Errorf("Expected the iteration limit error, got %v", err)
+		}
+	})
+}
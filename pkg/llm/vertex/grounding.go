@@ -0,0 +1,168 @@
+package vertex
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	aiplatform "cloud.google.com/go/aiplatform/apiv1"
+	"cloud.google.com/go/aiplatform/apiv1/aiplatformpb"
+	"cloud.google.com/go/vertexai/genai"
+	"google.golang.org/api/option"
+
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+)
+
+// WithGoogleSearchGrounding grounds the responses of Generate in Google
+// Search results, so that the model answers with current information from
+// the web. The sources of a response are passed to the callback set with
+// interfaces.WithCitationsCallback.
+func WithGoogleSearchGrounding() ClientOption {
+	return func(c *Client) {
+		c.googleSearch = true
+	}
+}
+
+// newPredictionClient creates the client for grounded calls. The genai
+// package has no search tools, so these go to the Vertex AI API directly.
+func newPredictionClient(ctx context.Context, location string, options []option.ClientOption) (*aiplatform.PredictionClient, error) {
+	endpoint := fmt.Sprintf("%s-aiplatform.googleapis.com:443", location)
+	if location == "global" {
+		endpoint = "aiplatform.googleapis.com:443"
+	}
+	options = append([]option.ClientOption{option.WithEndpoint(endpoint)}, options...)
+	return aiplatform.NewPredictionClient(ctx, options...)
+}
+
+// generateGrounded generates a response grounded in Google Search results
+// and reports its sources
+func (c *Client) generateGrounded(ctx context.Context, prompt string, params *interfaces.GenerateOptions) (string, error) {
+	request, err := c.groundedRequest(prompt, params)
+	if err != nil {
+		return "", err
+	}
+
+	var response *aiplatformpb.GenerateContentResponse
+	err = c.withRetry(ctx, func() error {
+		var genErr error
+		response, genErr = c.prediction.GenerateContent(ctx, request)
+		return genErr
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to generate grounded content: %w", err)
+	}
+
+	if usage := response.GetUsageMetadata(); usage != nil {
+		interfaces.ReportUsage(ctx, interfaces.Usage{
+			Model:        c.model,
+			InputTokens:  int(usage.GetPromptTokenCount()),
+			OutputTokens: int(usage.GetCandidatesTokenCount()),
+		})
+	}
+
+	if len(response.GetCandidates()) == 0 {
+		return "", fmt.Errorf("no candidates in response")
+	}
+	candidate := response.GetCandidates()[0]
+	if len(candidate.GetContent().GetParts()) == 0 {
+		return "", fmt.Errorf("no content in response")
+	}
+
+	var result strings.Builder
+	for _, part := range candidate.GetContent().GetParts() {
+		result.WriteString(part.GetText())
+	}
+
+	citations := groundingCitations(candidate.GetGroundingMetadata())
+	c.logger.Debug("Generated grounded content", "queries", candidate.GetGroundingMetadata().GetWebSearchQueries(), "sources", len(citations))
+	interfaces.ReportCitations(ctx, citations)
+
+	return result.String(), nil
+}
+
+// groundedRequest builds the request of a grounded Generate call with the
+// same prompt and configuration as an ungrounded one
+func (c *Client) groundedRequest(prompt string, params *interfaces.GenerateOptions) (*aiplatformpb.GenerateContentRequest, error) {
+	model, parts := c.generateRequest(prompt, params)
+
+	content := &aiplatformpb.Content{Role: "user"}
+	for _, part := range parts {
+		converted, err := groundedPart(part)
+		if err != nil {
+			return nil, err
+		}
+		content.Parts = append(content.Parts, converted)
+	}
+
+	// Gemini 1.5 models ground with the retrieval tool, later models with
+	// the search tool
+	tool := &aiplatformpb.Tool{GoogleSearch: &aiplatformpb.Tool_GoogleSearch{}}
+	if strings.HasPrefix(c.model, "gemini-1.") {
+		tool = &aiplatformpb.Tool{GoogleSearchRetrieval: &aiplatformpb.GoogleSearchRetrieval{}}
+	}
+
+	return &aiplatformpb.GenerateContentRequest{
+		Model:         fmt.Sprintf("projects/%s/locations/%s/publishers/google/models/%s", c.projectID, c.location, c.model),
+		Contents:      []*aiplatformpb.Content{content},
+		Tools:         []*aiplatformpb.Tool{tool},
+		CachedContent: model.CachedContentName,
+		GenerationConfig: &aiplatformpb.GenerationConfig{
+			Temperature:     model.Temperature,
+			TopP:            model.TopP,
+			StopSequences:   model.StopSequences,
+			MaxOutputTokens: model.MaxOutputTokens,
+		},
+	}, nil
+}
+
+// groundedPart converts a genai part to a part of the Vertex AI API
+func groundedPart(part genai.Part) (*aiplatformpb.Part, error) {
+	switch p := part.(type) {
+	case genai.Text:
+		return &aiplatformpb.Part{Data: &aiplatformpb.Part_Text{Text: string(p)}}, nil
+	case genai.Blob:
+		return &aiplatformpb.Part{Data: &aiplatformpb.Part_InlineData{
+			InlineData: &aiplatformpb.Blob{MimeType: p.MIMEType, Data: p.Data},
+		}}, nil
+	case genai.FileData:
+		return &aiplatformpb.Part{Data: &aiplatformpb.Part_FileData{
+			FileData: &aiplatformpb.FileData{MimeType: p.MIMEType, FileUri: p.FileURI},
+		}}, nil
+	default:
+		return nil, fmt.Errorf("grounded requests don't support %T parts", part)
+	}
+}
+
+// groundingCitations converts the grounding metadata of a response to
+// citations: one per source of each supported part of the response, and one
+// for each source that supports no part in particular
+func groundingCitations(metadata *aiplatformpb.GroundingMetadata) []interfaces.Citation {
+	chunks := metadata.GetGroundingChunks()
+	cited := make([]bool, len(chunks))
+
+	var citations []interfaces.Citation
+	for _, support := range metadata.GetGroundingSupports() {
+		segment := support.GetSegment()
+		for _, index := range support.GetGroundingChunkIndices() {
+			if int(index) >= len(chunks) || chunks[index].GetWeb() == nil {
+				continue
+			}
+			cited[index] = true
+			web := chunks[index].GetWeb()
+			citations = append(citations, interfaces.Citation{
+				URL:        web.GetUri(),
+				Title:      web.GetTitle(),
+				Text:       segment.GetText(),
+				StartIndex: int(segment.GetStartIndex()),
+				EndIndex:   int(segment.GetEndIndex()),
+			})
+		}
+	}
+
+	for i, chunk := range chunks {
+		if web := chunk.GetWeb(); web != nil && !cited[i] {
+			citations = append(citations, interfaces.Citation{URL: web.GetUri(), Title: web.GetTitle()})
+		}
+	}
+	return citations
+}
@@ -0,0 +1,126 @@
+package vertex
+
+import (
+	"reflect"
+	"testing"
+
+	"cloud.google.com/go/aiplatform/apiv1/aiplatformpb"
+	"cloud.google.com/go/vertexai/genai"
+
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+)
+
+func webChunk(uri, title string) *aiplatformpb.GroundingChunk {
+	return &aiplatformpb.GroundingChunk{ChunkType: &aiplatformpb.GroundingChunk_Web_{
+		Web: &aiplatformpb.GroundingChunk_Web{Uri: &uri, Title: &title},
+	}}
+}
+
+func TestGroundingCitations(t *testing.T) {
+	metadata := &aiplatformpb.GroundingMetadata{
+		WebSearchQueries: []string{"latest go release"},
+		GroundingChunks: []*aiplatformpb.GroundingChunk{
+			webChunk("https://go.dev/doc/go1.24", "go.dev"),
+			webChunk("https://github.com/golang/go", "github.com"),
+			webChunk("https://en.wikipedia.org/wiki/Go", "wikipedia.org"),
+		},
+		GroundingSupports: []*aiplatformpb.GroundingSupport{
+			{
+				Segment:               &aiplatformpb.Segment{StartIndex: 0, EndIndex: 22, Text: "Go 1.24 is the latest."},
+				GroundingChunkIndices: []int32{0, 1, 7},
+			},
+		},
+	}
+
+	citations := groundingCitations(metadata)
+	expected := []interfaces.Citation{
+		{URL: "https://go.dev/doc/go1.24", Title: "go.dev", Text: "Go 1.24 is the latest.", EndIndex: 22},
+		{URL: "https://github.com/golang/go", Title: "github.com", Text: "Go 1.24 is the latest.", EndIndex: 22},
+		{URL: "https://en.wikipedia.org/wiki/Go", Title: "wikipedia.org"},
+	}
+	if !reflect.DeepEqual(citations, expected) {
+		t.Errorf("Expected citations %+v, got %+v", expected, citations)
+	}
+
+	if citations := groundingCitations(nil); citations != nil {
+		t.Errorf("Expected no citations without metadata, got %+v", citations)
+	}
+}
+
+func TestGroundedRequest(t *testing.T) {
+	tests := []struct {
+		model     string
+		retrieval bool
+	}{
+		{model: ModelGemini15Pro, retrieval: true},
+		{model: ModelGemini20Flash, retrieval: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.model, func(t *testing.T) {
+			client := &Client{client: &genai.Client{}, model: tt.model, projectID: "project", location: "us-central1"}
+			params := &interfaces.GenerateOptions{
+				LLMConfig:     &interfaces.LLMConfig{Temperature: 0.2, MaxTokens: 512},
+				SystemMessage: "Be brief.",
+				CachedContent: "projects/project/locations/us-central1/cachedContents/1",
+			}
+
+			request, err := client.groundedRequest("What is the latest Go release?", params)
+			if err != nil {
+				t.Fatalf("Failed to build request: %v", err)
+			}
+			if request.Model != "projects/project/locations/us-central1/publishers/google/models/"+tt.model {
+				t.Errorf("Unexpected model %s", request.Model)
+			}
+			if parts := request.Contents[0].Parts; len(parts) != 2 || parts[1].GetText() != "What is the latest Go release?" {
+				t.Errorf("Unexpected parts %v", parts)
+			}
+			if request.GenerationConfig.GetMaxOutputTokens() != 512 || request.GenerationConfig.GetTemperature() != 0.2 {
+				t.Errorf("Unexpected generation config %v", request.GenerationConfig)
+			}
+			if request.CachedContent != params.CachedContent {
+				t.Errorf("Expected cached content %s, got %s", params.CachedContent, request.CachedContent)
+			}
+			tool := request.Tools[0]
+			if (tool.GoogleSearchRetrieval != nil) != tt.retrieval || (tool.GoogleSearch != nil) == tt.retrieval {
+				t.Errorf("Unexpected grounding tool %v", tool)
+			}
+		})
+	}
+}
+
+func TestGroundedPart(t *testing.T) {
+	tests := []struct {
+		name     string
+		part     genai.Part
+		expected *aiplatformpb.Part
+	}{
+		{"text", genai.Text("Describe this"), &aiplatformpb.Part{Data: &aiplatformpb.Part_Text{Text: "Describe this"}}},
+		{
+			"blob",
+			genai.Blob{MIMEType: "image/png", Data: []byte{0x89, 'P', 'N', 'G'}},
+			&aiplatformpb.Part{Data: &aiplatformpb.Part_InlineData{InlineData: &aiplatformpb.Blob{MimeType: "image/png", Data: []byte{0x89, 'P', 'N', 'G'}}}},
+		},
+		{
+			"file data",
+			genai.FileData{MIMEType: "image/jpeg", FileURI: "gs://bucket/photo.jpg"},
+			&aiplatformpb.Part{Data: &aiplatformpb.Part_FileData{FileData: &aiplatformpb.FileData{MimeType: "image/jpeg", FileUri: "gs://bucket/photo.jpg"}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			part, err := groundedPart(tt.part)
+			if err != nil {
+				t.Fatalf("Failed to convert part: %v", err)
+			}
+			if !reflect.DeepEqual(part, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, part)
+			}
+		})
+	}
+
+	if _, err := groundedPart(genai.FunctionResponse{Name: "lookup"}); err == nil {
+		t.Error("Expected an error for an unsupported part")
+	}
+}
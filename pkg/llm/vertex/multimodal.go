@@ -0,0 +1,75 @@
+package vertex
+
+import (
+	"context"
+	"mime"
+	"net/url"
+	"path"
+
+	"cloud.google.com/go/vertexai/genai"
+
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+)
+
+// GenerateMultimodal implements interfaces.MultimodalLLM. Gemini also
+// accepts audio, e.g. to answer a spoken question. Image URLs are
+// passed to Gemini as file data, so they must be Cloud Storage (gs://) or
+// public HTTP URLs. Calls are not grounded, see WithGoogleSearchGrounding.
+func (c *Client) GenerateMultimodal(ctx context.Context, contents []interfaces.Content, options ...interfaces.GenerateOption) (string, error) {
+	contentParts, err := convertContents(contents)
+	if err != nil {
+		return "", err
+	}
+
+	params := &interfaces.GenerateOptions{
+		LLMConfig: &interfaces.LLMConfig{
+			Temperature: 0.7,
+		},
+	}
+	for _, option := range options {
+		option(params)
+	}
+
+	if params.Constraint != nil {
+		return "", errConstraintUnsupported
+	}
+
+	ctx, cancel := interfaces.RequestContext(ctx, params.Timeout, c.requestTimeout)
+	defer cancel()
+
+	// The prompt is the last part
+	model, parts := c.generateRequest("", params)
+	parts = append(parts[:len(parts)-1], contentParts...)
+
+	return c.generateContent(ctx, model, parts)
+}
+
+// convertContents converts the contents to Vertex AI parts
+func convertContents(contents []interfaces.Content) ([]genai.Part, error) {
+	parts := make([]genai.Part, 0, len(contents))
+	for _, content := range contents {
+		if err := content.Validate(); err != nil {
+			return nil, err
+		}
+		switch content.Type {
+		case interfaces.ContentTypeText:
+			parts = append(parts, genai.Text(content.Text))
+		case interfaces.ContentTypeImageURL:
+			parts = append(parts, genai.FileData{MIMEType: imageMediaType(content.URL), FileURI: content.URL})
+		case interfaces.ContentTypeImage, interfaces.ContentTypeAudio:
+			parts = append(parts, genai.Blob{MIMEType: content.MediaType, Data: content.Data})
+		}
+	}
+	return parts, nil
+}
+
+// imageMediaType guesses the media type of an image from the extension of
+// its URL, which Gemini requires for file data
+func imageMediaType(imageURL string) string {
+	if parsed, err := url.Parse(imageURL); err == nil {
+		if mediaType := mime.TypeByExtension(path.Ext(parsed.Path)); mediaType != "" {
+			return mediaType
+		}
+	}
+	return "image/jpeg"
+}
@@ -0,0 +1,36 @@
+package vertex
+
+import (
+	"reflect"
+	"testing"
+
+	"cloud.google.com/go/vertexai/genai"
+
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+)
+
+func TestConvertContents(t *testing.T) {
+	parts, err := convertContents([]interfaces.Content{
+		interfaces.TextContent("What is in these photos?"),
+		interfaces.ImageContent([]byte("png"), "image/png"),
+		interfaces.ImageURLContent("gs://bucket/photo.webp"),
+		interfaces.ImageURLContent("https://example.com/photo?id=1"),
+	})
+	if err != nil {
+		t.Fatalf("convertContents failed: %v", err)
+	}
+
+	expected := []genai.Part{
+		genai.Text("What is in these photos?"),
+		genai.Blob{MIMEType: "image/png", Data: []byte("png")},
+		genai.FileData{MIMEType: "image/webp", FileURI: "gs://bucket/photo.webp"},
+		genai.FileData{MIMEType: "image/jpeg", FileURI: "https://example.com/photo?id=1"},
+	}
+	if !reflect.DeepEqual(parts, expected) {
+		t.Errorf("Expected parts %v, got %v", expected, parts)
+	}
+
+	if _, err := convertContents([]interfaces.Content{{Type: "audio"}}); err == nil {
+		t.Error("Expected an error for an unknown content type")
+	}
+}
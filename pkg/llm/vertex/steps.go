@@ -0,0 +1,137 @@
+package vertex
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"cloud.google.com/go/vertexai/genai"
+
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+)
+
+// stepSummaryPrefix starts the summary of earlier rounds added to the prompt
+const stepSummaryPrefix = "Summary of the function calls made so far:\n"
+
+// WithToolHistoryBudget sets how many tokens the function calls and responses
+// of earlier rounds may take up in GenerateWithTools. Once they exceed the
+// budget they are replaced with a summary that keeps the facts found so far,
+// so that long tool loops stay within the context window. The latest round is
+// always kept as is. Zero, the default, keeps the full history.
+func WithToolHistoryBudget(maxTokens int) ClientOption {
+	return func(c *Client) {
+		c.toolHistoryBudget = maxTokens
+	}
+}
+
+// WithStepSummarizer sets the LLM that summarizes earlier rounds, e.g. a
+// cheaper model. Defaults to the client itself.
+func WithStepSummarizer(llm interfaces.LLM) ClientOption {
+	return func(c *Client) {
+		c.stepSummarizer = llm
+	}
+}
+
+// compactToolHistory replaces the rounds before the latest function call with
+// a summary appended to the prompt, if they exceed the budget. The history
+// starts with the prompt and ends with the model's latest function call. If
+// summarizing fails the history is returned unchanged.
+func (c *Client) compactToolHistory(ctx context.Context, history []*genai.Content) []*genai.Content {
+	// Keep the prompt and the latest call; there must be an earlier round
+	// between them
+	if len(history) < 4 {
+		return history
+	}
+	prompt, previousSummary := splitStepSummary(history[0])
+	earlier := history[1 : len(history)-1]
+
+	transcript := renderSteps(previousSummary, earlier)
+	if estimateTokens(transcript) <= c.toolHistoryBudget {
+		return history
+	}
+
+	summary, err := c.summarizeSteps(ctx, transcript)
+	if err != nil {
+		c.logger.Warn("Failed to summarize earlier function calls", "error", err)
+		return history
+	}
+	c.logger.Debug("Summarized earlier function calls", "rounds", len(earlier)/2, "tokens", estimateTokens(transcript))
+
+	parts := append(append([]genai.Part{}, prompt...), genai.Text(stepSummaryPrefix+summary))
+	return []*genai.Content{
+		{Role: history[0].Role, Parts: parts},
+		history[len(history)-1],
+	}
+}
+
+// summarizeSteps has the summarizer condense a transcript of function calls
+func (c *Client) summarizeSteps(ctx context.Context, transcript string) (string, error) {
+	summarizer := c.stepSummarizer
+	if summarizer == nil {
+		summarizer = c
+	}
+	prompt := fmt.Sprintf(`Below are function calls an assistant made while working on a task, with their results.
+
+Summarize them so the assistant can continue the task without the full results. Keep every fact, figure, name and identifier that was found, note which calls failed, and drop repetition and boilerplate. Respond with the summary only.
+
+%s`, transcript)
+
+	summary, err := summarizer.Generate(ctx, prompt, func(o *interfaces.GenerateOptions) {
+		if o.LLMConfig == nil {
+			o.LLMConfig = &interfaces.LLMConfig{}
+		}
+		o.LLMConfig.Temperature = 0
+	})
+	if err != nil {
+		return "", err
+	}
+	summary = strings.TrimSpace(summary)
+	if summary == "" {
+		return "", fmt.Errorf("empty summary")
+	}
+	return summary, nil
+}
+
+// splitStepSummary separates an earlier summary from the prompt parts
+func splitStepSummary(content *genai.Content) ([]genai.Part, string) {
+	parts := content.Parts
+	if len(parts) > 0 {
+		if text, ok := parts[len(parts)-1].(genai.Text); ok && strings.HasPrefix(string(text), stepSummaryPrefix) {
+			return parts[:len(parts)-1], strings.TrimPrefix(string(text), stepSummaryPrefix)
+		}
+	}
+	return parts, ""
+}
+
+// renderSteps writes function calls and responses as text
+func renderSteps(previousSummary string, contents []*genai.Content) string {
+	var b strings.Builder
+	if previousSummary != "" {
+		b.WriteString("Earlier calls (summarized):\n")
+		b.WriteString(previousSummary)
+		b.WriteString("\n\n")
+	}
+	for _, content := range contents {
+		for _, part := range content.Parts {
+			switch p := part.(type) {
+			case genai.FunctionCall:
+				args, _ := json.Marshal(p.Args)
+				fmt.Fprintf(&b, "Called %s with %s\n", p.Name, args)
+			case genai.FunctionResponse:
+				response, _ := json.Marshal(p.Response)
+				fmt.Fprintf(&b, "%s returned %s\n", p.Name, response)
+			case genai.Text:
+				if text := strings.TrimSpace(string(p)); text != "" {
+					fmt.Fprintf(&b, "Assistant: %s\n", text)
+				}
+			}
+		}
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// estimateTokens estimates a token as four characters
+func estimateTokens(text string) int {
+	return (len(text) + 3) / 4
+}
@@ -0,0 +1,91 @@
+package vertex
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"cloud.google.com/go/vertexai/genai"
+
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+)
+
+// stepSummaryLLM returns a numbered summary and records the prompts
+type stepSummaryLLM struct {
+	interfaces.LLM
+	prompts []string
+	err     error
+}
+
+func (m *stepSummaryLLM) Generate(ctx context.Context, prompt string, options ...interfaces.GenerateOption) (string, error) {
+	m.prompts = append(m.prompts, prompt)
+	if m.err != nil {
+		return "", m.err
+	}
+	return fmt.Sprintf("summary %d", len(m.prompts)), nil
+}
+
+// toolRound returns the model's call and the user's response of a round
+func toolRound(name, result string) []*genai.Content {
+	return []*genai.Content{
+		{Role: "model", Parts: []genai.Part{genai.FunctionCall{Name: name, Args: map[string]any{"q": name}}}},
+		{Role: "user", Parts: []genai.Part{genai.FunctionResponse{Name: name, Response: map[string]any{"result": result}}}},
+	}
+}
+
+func TestCompactToolHistory(t *testing.T) {
+	summarizer := &stepSummaryLLM{}
+	client := &Client{logger: slog.Default(), toolHistoryBudget: 50, stepSummarizer: summarizer}
+	prompt := &genai.Content{Role: "user", Parts: []genai.Part{genai.Text("Find the invoice total")}}
+	latest := &genai.Content{Role: "model", Parts: []genai.Part{genai.FunctionCall{Name: "latest"}}}
+
+	// Earlier rounds within the budget are kept
+	history := append([]*genai.Content{prompt}, toolRound("search", "short")...)
+	history = append(history, latest)
+	if got := client.compactToolHistory(context.Background(), history); len(got) != len(history) {
+		t.Fatalf("expected history within the budget to be kept, got %d contents", len(got))
+	}
+
+	// Rounds over the budget are summarized into the prompt
+	history = append([]*genai.Content{prompt}, toolRound("search", strings.Repeat("invoice 42 ", 30))...)
+	history = append(history, toolRound("lookup", "total 99")...)
+	history = append(history, latest)
+	compacted := client.compactToolHistory(context.Background(), history)
+	if len(compacted) != 2 || compacted[1] != latest {
+		t.Fatalf("expected the prompt and the latest call, got %d contents", len(compacted))
+	}
+	parts := compacted[0].Parts
+	if len(parts) != 2 || parts[0] != genai.Text("Find the invoice total") || parts[1] != genai.Text(stepSummaryPrefix+"summary 1") {
+		t.Fatalf("unexpected prompt parts: %v", parts)
+	}
+	if !strings.Contains(summarizer.prompts[0], "lookup returned") || !strings.Contains(summarizer.prompts[0], "total 99") {
+		t.Fatalf("expected the rounds in the summary prompt, got %q", summarizer.prompts[0])
+	}
+
+	// A later compaction replaces the earlier summary instead of adding one
+	history = append(compacted[:1], toolRound("fetch", strings.Repeat("line item ", 30))...)
+	history = append(history, latest)
+	compacted = client.compactToolHistory(context.Background(), history)
+	if len(compacted[0].Parts) != 2 || compacted[0].Parts[1] != genai.Text(stepSummaryPrefix+"summary 2") {
+		t.Fatalf("expected a single updated summary, got %v", compacted[0].Parts)
+	}
+	if !strings.Contains(summarizer.prompts[1], "summary 1") {
+		t.Fatalf("expected the earlier summary in the summary prompt, got %q", summarizer.prompts[1])
+	}
+}
+
+func TestCompactToolHistoryKeepsHistoryOnFailure(t *testing.T) {
+	client := &Client{
+		logger:            slog.Default(),
+		toolHistoryBudget: 1,
+		stepSummarizer:    &stepSummaryLLM{err: fmt.Errorf("unavailable")},
+	}
+	history := append([]*genai.Content{{Role: "user", Parts: []genai.Part{genai.Text("prompt")}}}, toolRound("search", "result")...)
+	history = append(history, &genai.Content{Role: "model", Parts: []genai.Part{genai.FunctionCall{Name: "next"}}})
+
+	if got := client.compactToolHistory(context.Background(), history); len(got) != len(history) {
+		t.Fatalf("expected the history to be kept when summarizing fails, got %d contents", len(got))
+	}
+}
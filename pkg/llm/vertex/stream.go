@@ -0,0 +1,111 @@
+package vertex
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"cloud.google.com/go/vertexai/genai"
+	"google.golang.org/api/iterator"
+
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+)
+
+// GenerateStream generates text from a prompt and streams the response as it
+// is generated. Opening the stream is retried like other calls; a stream that
+// fails midway is not.
+func (c *Client) GenerateStream(ctx context.Context, prompt string, options ...interfaces.GenerateOption) (<-chan interfaces.StreamEvent, error) {
+	params := &interfaces.GenerateOptions{
+		LLMConfig: &interfaces.LLMConfig{
+			Temperature: 0.7,
+		},
+	}
+	for _, option := range options {
+		option(params)
+	}
+
+	// Constraints can't be enforced, so they fail the call
+	if params.Constraint != nil {
+		return nil, errConstraintUnsupported
+	}
+
+	// The timeout covers the whole stream
+	ctx, cancel := interfaces.RequestContext(ctx, params.Timeout, c.requestTimeout)
+
+	model, parts := c.generateRequest(prompt, params)
+
+	// The stream is opened by the first call to Next
+	var iter *genai.GenerateContentResponseIterator
+	var first *genai.GenerateContentResponse
+	err := c.withRetry(ctx, func() error {
+		iter = model.GenerateContentStream(ctx, parts...)
+		var err error
+		first, err = iter.Next()
+		if errors.Is(err, iterator.Done) {
+			first = nil
+			return nil
+		}
+		return err
+	})
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to generate content: %w", err)
+	}
+
+	events := make(chan interfaces.StreamEvent)
+	go func() {
+		defer cancel()
+		defer close(events)
+
+		send := func(event interfaces.StreamEvent) bool {
+			select {
+			case events <- event:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		// The usage is counted over the stream; the last response has the
+		// totals
+		var last *genai.GenerateContentResponse
+		for response := first; response != nil; {
+			if response.UsageMetadata != nil {
+				last = response
+			}
+			if text := responseText(response); text != "" {
+				if !send(interfaces.StreamEvent{Type: interfaces.StreamEventText, Content: text}) {
+					return
+				}
+			}
+
+			var err error
+			response, err = iter.Next()
+			if errors.Is(err, iterator.Done) {
+				break
+			}
+			if err != nil {
+				send(interfaces.StreamEvent{Type: interfaces.StreamEventError, Err: fmt.Errorf("failed to read stream: %w", err)})
+				return
+			}
+		}
+		c.reportUsage(ctx, last)
+		send(interfaces.StreamEvent{Type: interfaces.StreamEventDone})
+	}()
+	return events, nil
+}
+
+// responseText returns the text of the first candidate of a response
+func responseText(response *genai.GenerateContentResponse) string {
+	if len(response.Candidates) == 0 || response.Candidates[0].Content == nil {
+		return ""
+	}
+	var result strings.Builder
+	for _, part := range response.Candidates[0].Content.Parts {
+		if textPart, ok := part.(genai.Text); ok {
+			result.WriteString(string(textPart))
+		}
+	}
+	return result.String()
+}
@@ -0,0 +1,357 @@
+package wirelog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/run-bigpig/llm-agent/pkg/logging"
+)
+
+// redacted replaces the value of redacted headers, query parameters and fields
+const redacted = "[REDACTED]"
+
+// Record is a single logged request/response exchange with an LLM provider
+type Record struct {
+	Provider        string            `json:"provider,omitempty"`
+	Timestamp       time.Time         `json:"timestamp"`
+	Duration        time.Duration     `json:"duration"`
+	Method          string            `json:"method"`
+	URL             string            `json:"url"`
+	RequestHeaders  map[string]string `json:"request_headers,omitempty"`
+	RequestBody     string            `json:"request_body,omitempty"`
+	StatusCode      int               `json:"status_code,omitempty"`
+	ResponseHeaders map[string]string `json:"response_headers,omitempty"`
+	ResponseBody    string            `json:"response_body,omitempty"`
+	Truncated       bool              `json:"truncated,omitempty"`
+	Error           string            `json:"error,omitempty"`
+}
+
+// Sink receives wire log records
+type Sink interface {
+	// Write records an exchange
+	Write(ctx context.Context, record Record) error
+}
+
+// LoggerSink writes records to a logging.Logger at debug level
+type LoggerSink struct {
+	logger logging.Logger
+}
+
+// NewLoggerSink creates a sink that writes to the logger
+func NewLoggerSink(logger logging.Logger) *LoggerSink {
+	return &LoggerSink{logger: logger}
+}
+
+// Write records an exchange
+func (s *LoggerSink) Write(ctx context.Context, record Record) error {
+	s.logger.Debug(ctx, "LLM provider exchange", map[string]interface{}{
+		"provider":      record.Provider,
+		"method":        record.Method,
+		"url":           record.URL,
+		"status_code":   record.StatusCode,
+		"duration_ms":   record.Duration.Milliseconds(),
+		"request_body":  record.RequestBody,
+		"response_body": record.ResponseBody,
+		"truncated":     record.Truncated,
+		"error":         record.Error,
+	})
+	return nil
+}
+
+// WriterSink writes records as JSON lines to an io.Writer
+type WriterSink struct {
+	w  io.Writer
+	mu sync.Mutex
+}
+
+// NewWriterSink creates a sink that writes JSON lines to w
+func NewWriterSink(w io.Writer) *WriterSink {
+	return &WriterSink{w: w}
+}
+
+// Write records an exchange
+func (s *WriterSink) Write(ctx context.Context, record Record) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = s.w.Write(append(data, '\n'))
+	return err
+}
+
+// Transport is an http.RoundTripper that logs full request and response
+// bodies to a sink, with secrets redacted
+type Transport struct {
+	base          http.RoundTripper
+	sink          Sink
+	provider      string
+	sampleRate    float64
+	maxBodySize   int
+	redactHeaders map[string]bool
+	redactFields  map[string]bool
+	redactParams  map[string]bool
+}
+
+// Option represents an option for configuring the transport
+type Option func(*Transport)
+
+// WithBase sets the underlying transport
+func WithBase(base http.RoundTripper) Option {
+	return func(t *Transport) {
+		t.base = base
+	}
+}
+
+// WithProvider sets the provider name attached to every record
+func WithProvider(provider string) Option {
+	return func(t *Transport) {
+		t.provider = provider
+	}
+}
+
+// WithSampleRate sets the fraction of exchanges that are logged, between 0 and 1
+func WithSampleRate(rate float64) Option {
+	return func(t *Transport) {
+		t.sampleRate = rate
+	}
+}
+
+// WithMaxBodySize caps the number of bytes logged for each request and response body
+func WithMaxBodySize(size int) Option {
+	return func(t *Transport) {
+		t.maxBodySize = size
+	}
+}
+
+// WithRedactedHeaders adds headers whose values are redacted
+func WithRedactedHeaders(headers ...string) Option {
+	return func(t *Transport) {
+		for _, header := range headers {
+			t.redactHeaders[strings.ToLower(header)] = true
+		}
+	}
+}
+
+// WithRedactedFields adds JSON body fields whose values are redacted at any depth
+func WithRedactedFields(fields ...string) Option {
+	return func(t *Transport) {
+		for _, field := range fields {
+			t.redactFields[field] = true
+		}
+	}
+}
+
+// NewTransport creates a new logging transport. API key headers and query
+// parameters are always redacted.
+func NewTransport(sink Sink, options ...Option) *Transport {
+	transport := &Transport{
+		base:        http.DefaultTransport,
+		sink:        sink,
+		sampleRate:  1.0,
+		maxBodySize: 64 * 1024,
+		redactHeaders: map[string]bool{
+			"authorization":  true,
+			"x-api-key":      true,
+			"api-key":        true,
+			"x-goog-api-key": true,
+			"cookie":         true,
+			"set-cookie":     true,
+		},
+		redactFields: map[string]bool{
+			"api_key": true,
+			"apiKey":  true,
+		},
+		redactParams: map[string]bool{
+			"key":     true,
+			"api_key": true,
+		},
+	}
+
+	for _, option := range options {
+		option(transport)
+	}
+
+	return transport
+}
+
+// NewHTTPClient creates an HTTP client that logs through a new transport.
+// It sets no overall timeout, which would also cut off long streamed
+// responses; requests are limited by the request timeouts of the clients.
+func NewHTTPClient(sink Sink, options ...Option) *http.Client {
+	return &http.Client{
+		Transport: NewTransport(sink, options...),
+	}
+}
+
+// RoundTrip executes a single HTTP transaction and logs it if sampled
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.sampleRate < 1.0 && rand.Float64() >= t.sampleRate {
+		return t.base.RoundTrip(req)
+	}
+
+	record := Record{
+		Provider:       t.provider,
+		Timestamp:      time.Now(),
+		Method:         req.Method,
+		URL:            t.redactURL(req.URL),
+		RequestHeaders: t.redactHeaderValues(req.Header),
+	}
+
+	// Capture the request body and restore it for the base transport
+	if req.Body != nil && req.Body != http.NoBody {
+		body, err := io.ReadAll(req.Body)
+		_ = req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		record.RequestBody, record.Truncated = t.formatBody(body)
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		record.Duration = time.Since(record.Timestamp)
+		record.Error = err.Error()
+		_ = t.sink.Write(req.Context(), record)
+		return nil, err
+	}
+
+	record.StatusCode = resp.StatusCode
+	record.ResponseHeaders = t.redactHeaderValues(resp.Header)
+
+	// Tee the response body so streaming responses are not buffered; the
+	// record is written when the caller closes the body
+	resp.Body = &loggingBody{
+		ReadCloser: resp.Body,
+		transport:  t,
+		ctx:        req.Context(),
+		record:     record,
+	}
+
+	return resp, nil
+}
+
+// loggingBody captures a response body as it is read and writes the record on close
+type loggingBody struct {
+	io.ReadCloser
+	transport *Transport
+	ctx       context.Context
+	record    Record
+	buf       bytes.Buffer
+	overflow  bool
+	once      sync.Once
+}
+
+// Read reads from the underlying body, capturing up to the maximum body size
+func (b *loggingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if n > 0 {
+		remaining := b.transport.maxBodySize - b.buf.Len()
+		if b.transport.maxBodySize <= 0 {
+			remaining = n
+		}
+		switch {
+		case remaining >= n:
+			b.buf.Write(p[:n])
+		case remaining > 0:
+			b.buf.Write(p[:remaining])
+			b.overflow = true
+		default:
+			b.overflow = true
+		}
+	}
+	return n, err
+}
+
+// Close closes the underlying body and writes the record
+func (b *loggingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(func() {
+		b.record.Duration = time.Since(b.record.Timestamp)
+		body, truncated := b.transport.formatBody(b.buf.Bytes())
+		b.record.ResponseBody = body
+		b.record.Truncated = b.record.Truncated || truncated || b.overflow
+		_ = b.transport.sink.Write(b.ctx, b.record)
+	})
+	return err
+}
+
+// formatBody redacts JSON fields and applies the size cap
+func (t *Transport) formatBody(body []byte) (string, bool) {
+	if len(t.redactFields) > 0 {
+		var value interface{}
+		if err := json.Unmarshal(body, &value); err == nil {
+			if redactedBody, err := json.Marshal(t.redactValue(value)); err == nil {
+				body = redactedBody
+			}
+		}
+	}
+
+	if t.maxBodySize > 0 && len(body) > t.maxBodySize {
+		return string(body[:t.maxBodySize]), true
+	}
+	return string(body), false
+}
+
+// redactValue replaces the values of redacted fields at any depth
+func (t *Transport) redactValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			if t.redactFields[key] {
+				v[key] = redacted
+			} else {
+				v[key] = t.redactValue(child)
+			}
+		}
+		return v
+	case []interface{}:
+		for i, child := range v {
+			v[i] = t.redactValue(child)
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+// redactHeaderValues flattens headers, redacting sensitive values
+func (t *Transport) redactHeaderValues(header http.Header) map[string]string {
+	values := make(map[string]string, len(header))
+	for key, vals := range header {
+		if t.redactHeaders[strings.ToLower(key)] {
+			values[key] = redacted
+			continue
+		}
+		values[key] = strings.Join(vals, ", ")
+	}
+	return values
+}
+
+// redactURL returns the URL with sensitive query parameters redacted
+func (t *Transport) redactURL(u *url.URL) string {
+	if u.RawQuery == "" {
+		return u.String()
+	}
+
+	redactedURL := *u
+	query := redactedURL.Query()
+	for param := range query {
+		if t.redactParams[strings.ToLower(param)] {
+			query.Set(param, redacted)
+		}
+	}
+	redactedURL.RawQuery = query.Encode()
+	return redactedURL.String()
+}
@@ -0,0 +1,108 @@
+package wirelog_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/run-bigpig/llm-agent/pkg/llm/wirelog"
+)
+
+type memorySink struct {
+	mu      sync.Mutex
+	records []wirelog.Record
+}
+
+func (s *memorySink) Write(ctx context.Context, record wirelog.Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records = append(s.records, record)
+	return nil
+}
+
+func TestTransportRedactsSecrets(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if !strings.Contains(string(body), "sk-secret") {
+			t.Errorf("Expected the server to receive the original body, got %s", body)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"resp-1","content":"hello world"}`))
+	}))
+	defer server.Close()
+
+	sink := &memorySink{}
+	client := wirelog.NewHTTPClient(sink, wirelog.WithProvider("test"), wirelog.WithRedactedFields("user"))
+
+	req, _ := http.NewRequest("POST", server.URL+"/v1/messages?key=abc", strings.NewReader(`{"api_key":"sk-secret","user":"alice","prompt":"hi"}`))
+	req.Header.Set("Authorization", "Bearer sk-secret")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if string(body) != `{"id":"resp-1","content":"hello world"}` {
+		t.Errorf("Response body was altered: %s", body)
+	}
+
+	if len(sink.records) != 1 {
+		t.Fatalf("Expected 1 record, got %d", len(sink.records))
+	}
+	record := sink.records[0]
+
+	if strings.Contains(record.RequestBody, "sk-secret") || strings.Contains(record.RequestBody, "alice") {
+		t.Errorf("Expected secrets to be redacted from request body, got %s", record.RequestBody)
+	}
+	if !strings.Contains(record.RequestBody, `"prompt":"hi"`) {
+		t.Errorf("Expected non-sensitive fields to be kept, got %s", record.RequestBody)
+	}
+	if record.RequestHeaders["Authorization"] != "[REDACTED]" {
+		t.Errorf("Expected Authorization header to be redacted, got %s", record.RequestHeaders["Authorization"])
+	}
+	if strings.Contains(record.URL, "abc") {
+		t.Errorf("Expected key query parameter to be redacted, got %s", record.URL)
+	}
+	if !strings.Contains(record.ResponseBody, "hello world") || record.StatusCode != http.StatusOK {
+		t.Errorf("Unexpected response record: %d %s", record.StatusCode, record.ResponseBody)
+	}
+}
+
+func TestTransportSamplingAndSizeCap(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(strings.Repeat("x", 100)))
+	}))
+	defer server.Close()
+
+	sink := &memorySink{}
+
+	// Nothing is logged with a zero sample rate
+	unsampled := wirelog.NewHTTPClient(sink, wirelog.WithSampleRate(0))
+	resp, err := unsampled.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	_ = resp.Body.Close()
+	if len(sink.records) != 0 {
+		t.Fatalf("Expected no records, got %d", len(sink.records))
+	}
+
+	capped := wirelog.NewHTTPClient(sink, wirelog.WithMaxBodySize(10))
+	resp, err = capped.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if len(body) != 100 {
+		t.Errorf("Expected full body to be returned to the caller, got %d bytes", len(body))
+	}
+	if len(sink.records) != 1 || len(sink.records[0].ResponseBody) != 10 || !sink.records[0].Truncated {
+		t.Errorf("Expected a truncated 10 byte response body in the record, got %+v", sink.records)
+	}
+}
@@ -6,6 +6,8 @@ import (
 	"time"
 
 	"github.com/rs/zerolog"
+
+	"github.com/run-bigpig/llm-agent/pkg/runctx"
 )
 
 // Logger is an interface for logging
@@ -55,9 +57,9 @@ func (l *ZeroLogger) Info(ctx context.Context, msg string, fields map[string]int
 		event = event.Str("trace_id", traceID)
 	}
 
-	// Add organization ID if available
-	if orgID, ok := ctx.Value("org_id").(string); ok {
-		event = event.Str("org_id", orgID)
+	// Add organization, user, conversation and request IDs if available
+	for k, v := range runctx.From(ctx).Fields() {
+		event = event.Str(k, v)
 	}
 
 	// Add all fields
@@ -77,9 +79,9 @@ func (l *ZeroLogger) Warn(ctx context.Context, msg string, fields map[string]int
 		event = event.Str("trace_id", traceID)
 	}
 
-	// Add organization ID if available
-	if orgID, ok := ctx.Value("org_id").(string); ok {
-		event = event.Str("org_id", orgID)
+	// Add organization, user, conversation and request IDs if available
+	for k, v := range runctx.From(ctx).Fields() {
+		event = event.Str(k, v)
 	}
 
 	// Add all fields
@@ -99,9 +101,9 @@ func (l *ZeroLogger) Error(ctx context.Context, msg string, fields map[string]in
 		event = event.Str("trace_id", traceID)
 	}
 
-	// Add organization ID if available
-	if orgID, ok := ctx.Value("org_id").(string); ok {
-		event = event.Str("org_id", orgID)
+	// Add organization, user, conversation and request IDs if available
+	for k, v := range runctx.From(ctx).Fields() {
+		event = event.Str(k, v)
 	}
 
 	// Add all fields
@@ -121,9 +123,9 @@ func (l *ZeroLogger) Debug(ctx context.Context, msg string, fields map[string]in
 		event = event.Str("trace_id", traceID)
 	}
 
-	// Add organization ID if available
-	if orgID, ok := ctx.Value("org_id").(string); ok {
-		event = event.Str("org_id", orgID)
+	// Add organization, user, conversation and request IDs if available
+	for k, v := range runctx.From(ctx).Fields() {
+		event = event.Str(k, v)
 	}
 
 	// Add all fields
@@ -0,0 +1,106 @@
+package mcp
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+)
+
+// CachedServer wraps an MCP server and caches its tool list, so that callers
+// can list the tools on every request without a round trip to the server
+type CachedServer struct {
+	interfaces.MCPServer
+
+	ttl time.Duration
+	now func() time.Time
+
+	mu         sync.Mutex
+	tools      []interfaces.MCPTool
+	listedAt   time.Time
+	cached     bool
+	refreshing bool
+	generation uint64 // Incremented by Invalidate to discard lists fetched before
+}
+
+// CacheOption configures a CachedServer
+type CacheOption func(*CachedServer)
+
+// WithToolsTTL sets how long a tool list is served before it is refreshed. A
+// TTL of zero keeps the list until Invalidate is called. Defaults to 5 minutes.
+func WithToolsTTL(ttl time.Duration) CacheOption {
+	return func(s *CachedServer) {
+		if ttl >= 0 {
+			s.ttl = ttl
+		}
+	}
+}
+
+// NewCachedServer wraps an MCP server with a tool list cache
+func NewCachedServer(server interfaces.MCPServer, options ...CacheOption) *CachedServer {
+	s := &CachedServer{
+		MCPServer: server,
+		ttl:       5 * time.Minute,
+		now:       time.Now,
+	}
+	for _, option := range options {
+		option(s)
+	}
+	return s
+}
+
+// ListTools returns the cached tool list. The first call lists the tools of
+// the server. Once the TTL has passed, the expired list is still returned while
+// it is refreshed in the background, so that callers never wait for a refresh.
+func (s *CachedServer) ListTools(ctx context.Context) ([]interfaces.MCPTool, error) {
+	s.mu.Lock()
+	if !s.cached {
+		s.mu.Unlock()
+		return s.Refresh(ctx)
+	}
+	tools := s.tools
+	if s.ttl > 0 && s.now().Sub(s.listedAt) >= s.ttl && !s.refreshing {
+		s.refreshing = true
+		go func() {
+			_, _ = s.Refresh(context.WithoutCancel(ctx))
+		}()
+	}
+	s.mu.Unlock()
+	return tools, nil
+}
+
+// Refresh lists the tools of the server and caches them. On error the cached
+// list, if any, is kept.
+func (s *CachedServer) Refresh(ctx context.Context) ([]interfaces.MCPTool, error) {
+	s.mu.Lock()
+	generation := s.generation
+	s.mu.Unlock()
+
+	tools, err := s.MCPServer.ListTools(ctx)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.refreshing = false
+	if err != nil {
+		return nil, err
+	}
+	if generation == s.generation {
+		s.tools = tools
+		s.listedAt = s.now()
+		s.cached = true
+	}
+	return tools, nil
+}
+
+// Invalidate discards the cached tool list, so that the next call to
+// ListTools lists the tools of the server again. Call it when the server
+// reports that its tools changed, e.g. on a notifications/tools/list_changed
+// notification.
+func (s *CachedServer) Invalidate() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tools = nil
+	s.cached = false
+	s.generation++
+}
@@ -0,0 +1,123 @@
+package mcp_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+	"github.com/run-bigpig/llm-agent/pkg/mcp"
+)
+
+// listingServer returns its current tools and counts ListTools calls
+type listingServer struct {
+	interfaces.MCPServer
+	mu    sync.Mutex
+	tools []string
+	calls int
+	err   error
+	block chan struct{}
+}
+
+func (s *listingServer) ListTools(ctx context.Context) ([]interfaces.MCPTool, error) {
+	if s.block != nil {
+		<-s.block
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls++
+	if s.err != nil {
+		return nil, s.err
+	}
+	tools := make([]interfaces.MCPTool, len(s.tools))
+	for i, name := range s.tools {
+		tools[i] = interfaces.MCPTool{Name: name}
+	}
+	return tools, nil
+}
+
+func (s *listingServer) set(tools ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tools = tools
+}
+
+func (s *listingServer) callCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls
+}
+
+func names(tools []interfaces.MCPTool) []string {
+	var result []string
+	for _, tool := range tools {
+		result = append(result, tool.Name)
+	}
+	return result
+}
+
+func TestCachedServerCachesTools(t *testing.T) {
+	server := &listingServer{tools: []string{"read_file"}}
+	cached := mcp.NewCachedServer(server)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		tools, err := cached.ListTools(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"read_file"}, names(tools))
+	}
+	assert.Equal(t, 1, server.callCount())
+
+	// Invalidation makes the next call list the new tools
+	server.set("read_file", "write_file")
+	cached.Invalidate()
+	tools, err := cached.ListTools(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"read_file", "write_file"}, names(tools))
+	assert.Equal(t, 2, server.callCount())
+}
+
+func TestCachedServerRefreshesInBackground(t *testing.T) {
+	server := &listingServer{tools: []string{"read_file"}}
+	cached := mcp.NewCachedServer(server, mcp.WithToolsTTL(10*time.Millisecond))
+	ctx := context.Background()
+
+	_, err := cached.ListTools(ctx)
+	require.NoError(t, err)
+
+	// Once expired, the old list is served while the new one is fetched
+	server.set("write_file")
+	server.block = make(chan struct{})
+	time.Sleep(20 * time.Millisecond)
+	tools, err := cached.ListTools(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"read_file"}, names(tools))
+	close(server.block)
+
+	assert.Eventually(t, func() bool {
+		tools, err := cached.ListTools(ctx)
+		return err == nil && len(tools) == 1 && tools[0].Name == "write_file"
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestCachedServerKeepsToolsOnRefreshError(t *testing.T) {
+	server := &listingServer{tools: []string{"read_file"}}
+	cached := mcp.NewCachedServer(server, mcp.WithToolsTTL(0))
+	ctx := context.Background()
+
+	_, err := cached.ListTools(ctx)
+	require.NoError(t, err)
+
+	server.err = errors.New("connection refused")
+	_, err = cached.Refresh(ctx)
+	require.Error(t, err)
+
+	tools, err := cached.ListTools(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"read_file"}, names(tools))
+}
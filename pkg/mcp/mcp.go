@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"sync"
+	"time"
 
 	mcplib "github.com/metoro-io/mcp-golang"
 	"github.com/metoro-io/mcp-golang/transport"
@@ -45,6 +47,9 @@ type ToolResponse struct {
 // MCPServerImpl is the implementation of interfaces.MCPServer
 type MCPServerImpl struct {
 	client *mcplib.Client
+
+	mu  sync.Mutex
+	cmd *exec.Cmd // subprocess for stdio servers
 }
 
 // NewMCPServer creates a new MCPServer with the given transport
@@ -103,10 +108,41 @@ func (s *MCPServerImpl) CallTool(ctx context.Context, name string, args interfac
 	}, nil
 }
 
-// Close closes the connection to the MCP server
+// Ping checks that the MCP server is reachable
+func (s *MCPServerImpl) Ping(ctx context.Context) error {
+	return s.client.Ping(ctx)
+}
+
+// Close closes the connection to the MCP server. It is safe to call
+// concurrently, e.g. from a shutdown hook while the server is in use.
 func (s *MCPServerImpl) Close() error {
-	// The mcp-golang client doesn't have a Close method yet
-	// We'll implement this when it becomes available
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// The mcp-golang client doesn't have a Close method yet, but stdio
+	// servers run as a subprocess that must be stopped
+	cmd := s.cmd
+	if cmd == nil || cmd.Process == nil {
+		return nil
+	}
+
+	// Ask the server to exit, and kill it if it does not exit in time
+	_ = cmd.Process.Signal(os.Interrupt)
+	exited := make(chan error, 1)
+	go func() {
+		exited <- cmd.Wait()
+	}()
+
+	select {
+	case <-exited:
+	case <-time.After(5 * time.Second):
+		if err := cmd.Process.Kill(); err != nil {
+			return fmt.Errorf("failed to kill MCP server process: %w", err)
+		}
+		<-exited
+	}
+
+	s.cmd = nil
 	return nil
 }
 
@@ -160,6 +196,11 @@ func NewStdioServer(ctx context.Context, config StdioServerConfig) (interfaces.M
 		return nil, err
 	}
 
+	impl := server.(*MCPServerImpl)
+	impl.mu.Lock()
+	impl.cmd = cmd
+	impl.mu.Unlock()
+
 	return server, nil
 }
 
@@ -0,0 +1,31 @@
+package mcp
+
+import (
+	"os/exec"
+	"sync"
+	"testing"
+)
+
+func TestCloseConcurrently(t *testing.T) {
+	cmd := exec.Command("sleep", "30")
+	if err := cmd.Start(); err != nil {
+		t.Skipf("failed to start subprocess: %v", err)
+	}
+	server := &MCPServerImpl{cmd: cmd}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := server.Close(); err != nil {
+				t.Errorf("failed to close server: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if server.cmd != nil || cmd.ProcessState == nil {
+		t.Error("expected the subprocess to be stopped")
+	}
+}
@@ -0,0 +1,27 @@
+package memory
+
+import (
+	"context"
+
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+)
+
+// BatchMemory is implemented by memories that can add several messages in a
+// single operation
+type BatchMemory interface {
+	AddMessages(ctx context.Context, messages []interfaces.Message) error
+}
+
+// AddMessages adds the messages to the memory in one operation if it
+// implements BatchMemory, and one at a time otherwise
+func AddMessages(ctx context.Context, mem interfaces.Memory, messages []interfaces.Message) error {
+	if batch, ok := mem.(BatchMemory); ok {
+		return batch.AddMessages(ctx, messages)
+	}
+	for _, message := range messages {
+		if err := mem.AddMessage(ctx, message); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package memory
+
+import (
+	"fmt"
+
+	"github.com/run-bigpig/llm-agent/pkg/config"
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+)
+
+// FromConfig creates the memory selected by cfg.Memory.Type: "buffer" for an
+// in-process conversation buffer or "redis" for Redis-backed memory. A nil
+// cfg uses config.Get().
+func FromConfig(cfg *config.Config) (interfaces.Memory, error) {
+	if cfg == nil {
+		cfg = config.Get()
+	}
+
+	switch cfg.Memory.Type {
+	case "", "buffer":
+		var options []Option
+		if cfg.Memory.MaxSize > 0 {
+			options = append(options, WithMaxSize(cfg.Memory.MaxSize))
+		}
+		return NewConversationBuffer(options...), nil
+
+	case "redis":
+		return NewRedisMemoryFromConfig(RedisConfig{
+			URL:      cfg.Memory.Redis.URL,
+			Password: cfg.Memory.Redis.Password,
+			DB:       cfg.Memory.Redis.DB,
+		})
+
+	default:
+		return nil, fmt.Errorf("unknown memory type %q", cfg.Memory.Type)
+	}
+}
@@ -0,0 +1,43 @@
+package memory_test
+
+import (
+	"testing"
+
+	"github.com/run-bigpig/llm-agent/pkg/config"
+	"github.com/run-bigpig/llm-agent/pkg/memory"
+)
+
+func TestFromConfigBuffer(t *testing.T) {
+	for _, memoryType := range []string{"", "buffer"} {
+		cfg := &config.Config{}
+		cfg.Memory.Type = memoryType
+		cfg.Memory.MaxSize = 2
+
+		mem, err := memory.FromConfig(cfg)
+		if err != nil {
+			t.Fatalf("%q: FromConfig failed: %v", memoryType, err)
+		}
+		if _, ok := mem.(*memory.ConversationBuffer); !ok {
+			t.Fatalf("%q: expected a conversation buffer, got %T", memoryType, mem)
+		}
+
+		ctx := conversationContext("conv")
+		addMessages(t, mem, ctx, 3)
+		messages, err := mem.GetMessages(ctx)
+		if err != nil {
+			t.Fatalf("failed to get messages: %v", err)
+		}
+		if len(messages) != 2 || messages[0].Content != "message 1" {
+			t.Errorf("%q: expected the buffer to keep MaxSize messages, got %+v", memoryType, messages)
+		}
+	}
+}
+
+func TestFromConfigUnknownType(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Memory.Type = "postgres"
+
+	if _, err := memory.FromConfig(cfg); err == nil || err.Error() != `unknown memory type "postgres"` {
+		t.Errorf("expected an unknown memory type error, got %v", err)
+	}
+}
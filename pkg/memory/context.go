@@ -2,21 +2,23 @@ package memory
 
 import (
 	"context"
+
+	"github.com/run-bigpig/llm-agent/pkg/runctx"
 )
 
 // Key type for context values
 type contextKey string
 
 // ConversationIDKey is the key used to store conversation ID in context
-const ConversationIDKey contextKey = "conversation_id"
+const ConversationIDKey = runctx.ConversationIDKey
 
 // WithConversationID adds a conversation ID to the context
 func WithConversationID(ctx context.Context, conversationID string) context.Context {
-	return context.WithValue(ctx, ConversationIDKey, conversationID)
+	return runctx.WithConversationID(ctx, conversationID)
 }
 
 // GetConversationID retrieves the conversation ID from the context
 func GetConversationID(ctx context.Context) (string, bool) {
-	id, ok := ctx.Value(ConversationIDKey).(string)
-	return id, ok
+	id := runctx.ConversationID(ctx)
+	return id, id != ""
 }
@@ -3,16 +3,29 @@ package memory
 import (
 	"context"
 	"fmt"
+	"maps"
+	"strings"
 	"sync"
 
 	"github.com/run-bigpig/llm-agent/pkg/interfaces"
 	"github.com/run-bigpig/llm-agent/pkg/multitenancy"
+	"github.com/run-bigpig/llm-agent/pkg/runctx"
 )
 
-// ConversationBuffer implements a simple in-memory conversation buffer
+// ConversationBuffer implements a simple in-memory conversation buffer. It is
+// safe for concurrent use: each conversation has its own lock, so calls for
+// different conversations don't wait for each other, and messages are copied
+// on the way in and out so callers never share them with the buffer.
 type ConversationBuffer struct {
-	messages map[string][]interfaces.Message
-	maxSize  int
+	conversations map[string]*conversation
+	maxSize       int
+	mu            sync.RWMutex // guards conversations
+}
+
+// conversation holds the messages of a single conversation
+type conversation struct {
+	messages []interfaces.Message
+	removed  bool // set when Clear removes the conversation from the buffer
 	mu       sync.RWMutex
 }
 
@@ -29,8 +42,8 @@ func WithMaxSize(size int) Option {
 // NewConversationBuffer creates a new conversation buffer
 func NewConversationBuffer(options ...Option) *ConversationBuffer {
 	buffer := &ConversationBuffer{
-		messages: make(map[string][]interfaces.Message),
-		maxSize:  100, // Default max size
+		conversations: make(map[string]*conversation),
+		maxSize:       100, // Default max size
 	}
 
 	for _, option := range options {
@@ -42,43 +55,68 @@ func NewConversationBuffer(options ...Option) *ConversationBuffer {
 
 // AddMessage adds a message to the buffer
 func (c *ConversationBuffer) AddMessage(ctx context.Context, message interfaces.Message) error {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
 	// Get conversation ID from context
 	conversationID, err := getConversationID(ctx)
 	if err != nil {
 		return err
 	}
 
+	conv := c.lockConversation(conversationID)
+	defer conv.mu.Unlock()
+
 	// Add message to buffer
-	c.messages[conversationID] = append(c.messages[conversationID], message)
+	conv.messages = append(conv.messages, copyMessage(message))
 
-	// Trim buffer if it exceeds max size
-	if c.maxSize > 0 && len(c.messages[conversationID]) > c.maxSize {
-		c.messages[conversationID] = c.messages[conversationID][len(c.messages[conversationID])-c.maxSize:]
+	// Trim buffer if it exceeds max size, keeping pinned messages
+	if c.maxSize > 0 && len(conv.messages) > c.maxSize {
+		conv.messages = trimPreservingPinned(conv.messages, c.maxSize)
 	}
 
 	return nil
 }
 
-// GetMessages retrieves messages from the buffer
-func (c *ConversationBuffer) GetMessages(ctx context.Context, options ...interfaces.GetMessagesOption) ([]interfaces.Message, error) {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
+// AddMessages adds several messages to the buffer at once, so that no other
+// message is stored between them
+func (c *ConversationBuffer) AddMessages(ctx context.Context, messages []interfaces.Message) error {
+	// Get conversation ID from context
+	conversationID, err := getConversationID(ctx)
+	if err != nil {
+		return err
+	}
+
+	conv := c.lockConversation(conversationID)
+	defer conv.mu.Unlock()
+
+	for _, message := range messages {
+		conv.messages = append(conv.messages, copyMessage(message))
+	}
+	if c.maxSize > 0 && len(conv.messages) > c.maxSize {
+		conv.messages = trimPreservingPinned(conv.messages, c.maxSize)
+	}
+
+	return nil
+}
 
+// GetMessages retrieves a copy of the messages from the buffer
+func (c *ConversationBuffer) GetMessages(ctx context.Context, options ...interfaces.GetMessagesOption) ([]interfaces.Message, error) {
 	// Get conversation ID from context
 	conversationID, err := getConversationID(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	// Get messages for conversation
-	messages, ok := c.messages[conversationID]
-	if !ok {
+	conv := c.conversation(conversationID, false)
+	if conv == nil {
 		return []interfaces.Message{}, nil
 	}
 
+	conv.mu.RLock()
+	messages := make([]interfaces.Message, len(conv.messages))
+	for i, msg := range conv.messages {
+		messages[i] = copyMessage(msg)
+	}
+	conv.mu.RUnlock()
+
 	// Apply options
 	opts := &interfaces.GetMessagesOptions{}
 	for _, option := range options {
@@ -99,9 +137,9 @@ func (c *ConversationBuffer) GetMessages(ctx context.Context, options ...interfa
 		messages = filtered
 	}
 
-	// Apply limit if specified
+	// Apply limit if specified, keeping pinned messages
 	if opts.Limit > 0 && opts.Limit < len(messages) {
-		messages = messages[len(messages)-opts.Limit:]
+		messages = trimPreservingPinned(messages, opts.Limit)
 	}
 
 	return messages, nil
@@ -109,9 +147,6 @@ func (c *ConversationBuffer) GetMessages(ctx context.Context, options ...interfa
 
 // Clear clears the buffer for a conversation
 func (c *ConversationBuffer) Clear(ctx context.Context) error {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
 	// Get conversation ID from context
 	conversationID, err := getConversationID(ctx)
 	if err != nil {
@@ -119,12 +154,66 @@ func (c *ConversationBuffer) Clear(ctx context.Context) error {
 	}
 
 	// Clear messages for conversation
-	delete(c.messages, conversationID)
+	c.mu.Lock()
+	conv, ok := c.conversations[conversationID]
+	delete(c.conversations, conversationID)
+	c.mu.Unlock()
+
+	if ok {
+		conv.mu.Lock()
+		conv.removed = true
+		conv.messages = nil
+		conv.mu.Unlock()
+	}
 
 	return nil
 }
 
-// Helper function to get conversation ID from context
+// conversation returns the conversation with the given ID, creating it if
+// create is set. Without create, it returns nil for an unknown conversation.
+func (c *ConversationBuffer) conversation(conversationID string, create bool) *conversation {
+	c.mu.RLock()
+	conv, ok := c.conversations[conversationID]
+	c.mu.RUnlock()
+	if ok || !create {
+		return conv
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if conv, ok := c.conversations[conversationID]; ok {
+		return conv
+	}
+	conv = &conversation{}
+	c.conversations[conversationID] = conv
+	return conv
+}
+
+// lockConversation returns the conversation with the given ID, creating it
+// if needed, with its lock held for writing
+func (c *ConversationBuffer) lockConversation(conversationID string) *conversation {
+	for {
+		conv := c.conversation(conversationID, true)
+		conv.mu.Lock()
+		if !conv.removed {
+			return conv
+		}
+		// The conversation was cleared after it was looked up
+		conv.mu.Unlock()
+	}
+}
+
+// copyMessage returns a copy of the message with its own metadata map
+func copyMessage(message interfaces.Message) interfaces.Message {
+	if message.Metadata != nil {
+		message.Metadata = maps.Clone(message.Metadata)
+	}
+	return message
+}
+
+// Helper function to get conversation ID from context. When the context has
+// a user ID, the conversation is also scoped to that user so that users of the
+// same organization cannot read each other's conversations.
 func getConversationID(ctx context.Context) (string, error) {
 	// Get organization ID from context
 	orgID, err := multitenancy.GetOrgID(ctx)
@@ -138,6 +227,19 @@ func getConversationID(ctx context.Context) (string, error) {
 		return "", fmt.Errorf("conversation ID not found in context")
 	}
 
-	// Combine organization ID and conversation ID
-	return fmt.Sprintf("%s:%s", orgID, conversationID), nil
+	// Combine organization ID, user ID and conversation ID
+	if userID := runctx.UserID(ctx); userID != "" {
+		return fmt.Sprintf("%s:%s:%s", escapeKeyPart(orgID), escapeKeyPart(userID), escapeKeyPart(conversationID)), nil
+	}
+	return fmt.Sprintf("%s:%s", escapeKeyPart(orgID), escapeKeyPart(conversationID)), nil
+}
+
+// keyPartEscaper escapes the separator so that IDs containing it cannot
+// collide, e.g. org "a:b" with user "c" and org "a" with user "b:c"
+var keyPartEscaper = strings.NewReplacer("%", "%25", ":", "%3A")
+
+// escapeKeyPart escapes a part of a conversation key. IDs without "%" or ":"
+// are left unchanged so existing keys stay valid.
+func escapeKeyPart(part string) string {
+	return keyPartEscaper.Replace(part)
 }
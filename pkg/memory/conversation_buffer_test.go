@@ -0,0 +1,216 @@
+package memory_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+	"github.com/run-bigpig/llm-agent/pkg/memory"
+	"github.com/run-bigpig/llm-agent/pkg/multitenancy"
+	"github.com/run-bigpig/llm-agent/pkg/runctx"
+)
+
+func conversationContext(conversationID string) context.Context {
+	ctx := multitenancy.WithOrgID(context.Background(), "org-1")
+	return memory.WithConversationID(ctx, conversationID)
+}
+
+func TestConversationBufferConcurrentAdds(t *testing.T) {
+	buffer := memory.NewConversationBuffer(memory.WithMaxSize(0))
+
+	const conversations, writers, messages = 4, 8, 50
+	var wg sync.WaitGroup
+	for c := 0; c < conversations; c++ {
+		ctx := conversationContext(fmt.Sprintf("conv-%d", c))
+		for w := 0; w < writers; w++ {
+			wg.Add(1)
+			go func(w int) {
+				defer wg.Done()
+				for m := 0; m < messages; m++ {
+					if err := buffer.AddMessage(ctx, interfaces.Message{Role: "user", Content: fmt.Sprintf("%d-%d", w, m)}); err != nil {
+						t.Errorf("failed to add message: %v", err)
+						return
+					}
+					if _, err := buffer.GetMessages(ctx, interfaces.WithLimit(10)); err != nil {
+						t.Errorf("failed to get messages: %v", err)
+						return
+					}
+				}
+			}(w)
+		}
+	}
+	wg.Wait()
+
+	for c := 0; c < conversations; c++ {
+		got, err := buffer.GetMessages(conversationContext(fmt.Sprintf("conv-%d", c)))
+		if err != nil {
+			t.Fatalf("failed to get messages: %v", err)
+		}
+		if len(got) != writers*messages {
+			t.Errorf("conversation %d: expected %d messages, got %d", c, writers*messages, len(got))
+		}
+
+		// Each writer's messages keep their order
+		next := make(map[int]int)
+		for _, msg := range got {
+			var w, m int
+			if _, err := fmt.Sscanf(msg.Content, "%d-%d", &w, &m); err != nil {
+				t.Fatalf("unexpected message %q", msg.Content)
+			}
+			if m != next[w] {
+				t.Fatalf("conversation %d: writer %d message %d out of order", c, w, m)
+			}
+			next[w]++
+		}
+	}
+}
+
+func TestConversationBufferCopyOnRead(t *testing.T) {
+	buffer := memory.NewConversationBuffer()
+	ctx := conversationContext("conv-1")
+
+	metadata := map[string]interface{}{"source": "web"}
+	if err := buffer.AddMessage(ctx, interfaces.Message{Role: "user", Content: "hello", Metadata: metadata}); err != nil {
+		t.Fatalf("failed to add message: %v", err)
+	}
+	metadata["source"] = "changed after adding"
+
+	got, err := buffer.GetMessages(ctx)
+	if err != nil {
+		t.Fatalf("failed to get messages: %v", err)
+	}
+	got[0].Content = "changed after reading"
+	got[0].Metadata["source"] = "changed after reading"
+
+	again, err := buffer.GetMessages(ctx)
+	if err != nil {
+		t.Fatalf("failed to get messages: %v", err)
+	}
+	if again[0].Content != "hello" || again[0].Metadata["source"] != "web" {
+		t.Errorf("expected the stored message to be unchanged, got %+v", again[0])
+	}
+}
+
+func TestConversationBufferConcurrentClear(t *testing.T) {
+	buffer := memory.NewConversationBuffer()
+	ctx := conversationContext("conv-1")
+
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for m := 0; m < 100; m++ {
+				_ = buffer.AddMessage(ctx, interfaces.Message{Role: "user", Content: "hello"})
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for m := 0; m < 20; m++ {
+				_ = buffer.Clear(ctx)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Messages added after the last clear are kept
+	if err := buffer.Clear(ctx); err != nil {
+		t.Fatalf("failed to clear: %v", err)
+	}
+	if err := buffer.AddMessage(ctx, interfaces.Message{Role: "user", Content: "after"}); err != nil {
+		t.Fatalf("failed to add message: %v", err)
+	}
+	got, err := buffer.GetMessages(ctx)
+	if err != nil || len(got) != 1 || got[0].Content != "after" {
+		t.Errorf("expected only the message added after clearing, got %+v (%v)", got, err)
+	}
+}
+
+func TestAddMessagesBatch(t *testing.T) {
+	buffer := memory.NewConversationBuffer(memory.WithMaxSize(3))
+	ctx := conversationContext("conv-1")
+
+	batch := []interfaces.Message{
+		{Role: "user", Content: "one"},
+		{Role: "assistant", Content: "two"},
+		{Role: "user", Content: "three"},
+		{Role: "assistant", Content: "four"},
+	}
+	if err := memory.AddMessages(ctx, buffer, batch); err != nil {
+		t.Fatalf("failed to add messages: %v", err)
+	}
+
+	got, err := buffer.GetMessages(ctx)
+	if err != nil {
+		t.Fatalf("failed to get messages: %v", err)
+	}
+	if len(got) != 3 || got[0].Content != "two" || got[2].Content != "four" {
+		t.Errorf("expected the last 3 messages in order, got %+v", got)
+	}
+}
+
+func TestConversationBufferUserScoping(t *testing.T) {
+	buffer := memory.NewConversationBuffer()
+	conversation := conversationContext("shared-id")
+	alice := runctx.WithUserID(conversation, "alice")
+	bob := runctx.WithUserID(conversation, "bob")
+
+	for ctx, content := range map[context.Context]string{alice: "alice's secret", bob: "bob's secret", conversation: "no user"} {
+		if err := buffer.AddMessage(ctx, interfaces.Message{Role: "user", Content: content}); err != nil {
+			t.Fatalf("failed to add message: %v", err)
+		}
+	}
+
+	// Users with the same conversation ID only see their own messages
+	for ctx, expected := range map[context.Context]string{alice: "alice's secret", bob: "bob's secret", conversation: "no user"} {
+		messages, err := buffer.GetMessages(ctx)
+		if err != nil {
+			t.Fatalf("failed to get messages: %v", err)
+		}
+		if len(messages) != 1 || messages[0].Content != expected {
+			t.Errorf("expected only %q, got %+v", expected, messages)
+		}
+	}
+
+	// Clearing one user's conversation leaves the others
+	if err := buffer.Clear(alice); err != nil {
+		t.Fatalf("failed to clear: %v", err)
+	}
+	if messages, _ := buffer.GetMessages(alice); len(messages) != 0 {
+		t.Errorf("expected alice's conversation to be cleared, got %+v", messages)
+	}
+	if messages, _ := buffer.GetMessages(bob); len(messages) != 1 {
+		t.Errorf("expected bob's conversation to be kept, got %+v", messages)
+	}
+}
+
+func TestConversationBufferSeparatorInIDs(t *testing.T) {
+	buffer := memory.NewConversationBuffer()
+	contextFor := func(orgID, userID, conversationID string) context.Context {
+		ctx := runctx.WithUserID(multitenancy.WithOrgID(context.Background(), orgID), userID)
+		return memory.WithConversationID(ctx, conversationID)
+	}
+	// Joined with ":" without escaping, these would all share one key
+	contexts := []context.Context{
+		contextFor("a:b", "c", "d"),
+		contextFor("a", "b:c", "d"),
+		contextFor("a", "b", "c:d"),
+	}
+
+	for i, ctx := range contexts {
+		if err := buffer.AddMessage(ctx, interfaces.Message{Role: "user", Content: fmt.Sprint(i)}); err != nil {
+			t.Fatalf("failed to add message: %v", err)
+		}
+	}
+	for i, ctx := range contexts {
+		messages, err := buffer.GetMessages(ctx)
+		if err != nil {
+			t.Fatalf("failed to get messages: %v", err)
+		}
+		if len(messages) != 1 || messages[0].Content != fmt.Sprint(i) {
+			t.Errorf("expected only message %d, got %+v", i, messages)
+		}
+	}
+}
@@ -5,8 +5,10 @@ import (
 	"fmt"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+	"github.com/run-bigpig/llm-agent/pkg/prompts"
 )
 
 // ConversationSummary implements a memory that summarizes old messages
@@ -15,10 +17,30 @@ type ConversationSummary struct {
 	llmClient       interfaces.LLM
 	maxBufferSize   int
 	summaryMessages map[string]interfaces.Message
+	summaryVersions map[string][]SummaryVersion
 	summaryParams   map[string]interface{}
+	template        *prompts.Template
+	language        string
+	maxTokens       int
+	tracer          interfaces.Tracer
 	mu              sync.RWMutex
 }
 
+// SummaryVersion is a stored revision of a conversation summary
+type SummaryVersion struct {
+	// Version is the revision number, starting at 1
+	Version int
+
+	// Content is the summary text
+	Content string
+
+	// MessageCount is the total number of messages covered by the summary
+	MessageCount int
+
+	// CreatedAt is when the summary was generated
+	CreatedAt time.Time
+}
+
 // SummaryOption represents an option for configuring the conversation summary
 type SummaryOption func(*ConversationSummary)
 
@@ -40,6 +62,52 @@ func WithSummaryLength(wordCount int) SummaryOption {
 	}
 }
 
+// WithSummaryLLM sets the LLM used to generate summaries, allowing a cheaper
+// model than the one passed to NewConversationSummary
+func WithSummaryLLM(llmClient interfaces.LLM) SummaryOption {
+	return func(c *ConversationSummary) {
+		c.llmClient = llmClient
+	}
+}
+
+// WithSummaryTemplate replaces the summarization prompt with a template from
+// the prompts package. It is rendered with:
+//
+//   - Messages: the new messages, one "role: content" line each
+//   - PreviousSummary: the summary to extend, empty for the first summary
+//   - SummaryLength: the target length in words
+//   - Language: the language set with WithSummaryLanguage, or empty
+//   - MaxTokens: the limit set with WithSummaryMaxTokens, or 0
+func WithSummaryTemplate(tmpl *prompts.Template) SummaryOption {
+	return func(c *ConversationSummary) {
+		c.template = tmpl
+	}
+}
+
+// WithSummaryLanguage sets the language summaries are written in, e.g.
+// "German", regardless of the language of the conversation
+func WithSummaryLanguage(language string) SummaryOption {
+	return func(c *ConversationSummary) {
+		c.language = language
+	}
+}
+
+// WithSummaryMaxTokens asks the LLM to keep summaries within a number of
+// tokens
+func WithSummaryMaxTokens(maxTokens int) SummaryOption {
+	return func(c *ConversationSummary) {
+		c.maxTokens = maxTokens
+	}
+}
+
+// WithSummaryTracer records every summarization call as a
+// "memory.summarize" span
+func WithSummaryTracer(tracer interfaces.Tracer) SummaryOption {
+	return func(c *ConversationSummary) {
+		c.tracer = tracer
+	}
+}
+
 // NewConversationSummary creates a new conversation summary memory
 func NewConversationSummary(llmClient interfaces.LLM, options ...SummaryOption) *ConversationSummary {
 	summary := &ConversationSummary{
@@ -47,6 +115,7 @@ func NewConversationSummary(llmClient interfaces.LLM, options ...SummaryOption)
 		llmClient:       llmClient,
 		maxBufferSize:   10, // Default max buffer size
 		summaryMessages: make(map[string]interfaces.Message),
+		summaryVersions: make(map[string][]SummaryVersion),
 		summaryParams:   make(map[string]interface{}),
 	}
 
@@ -79,27 +148,57 @@ func (c *ConversationSummary) AddMessage(ctx context.Context, message interfaces
 		return err
 	}
 
-	if len(messages) >= c.maxBufferSize {
-		// Summarize messages
-		summary, err := c.summarize(ctx, messages)
+	// Pinned messages stay in the buffer and are not summarized
+	var unpinned []interfaces.Message
+	for _, msg := range messages {
+		if !IsPinned(msg) {
+			unpinned = append(unpinned, msg)
+		}
+	}
+
+	if len(unpinned) >= c.maxBufferSize {
+		// Fold only the new messages into the previous summary
+		versions := c.summaryVersions[conversationID]
+		var previous *SummaryVersion
+		if len(versions) > 0 {
+			previous = &versions[len(versions)-1]
+		}
+
+		summary, err := c.traceSummarize(ctx, conversationID, previous, unpinned)
 		if err != nil {
 			return err
 		}
 
+		version := SummaryVersion{
+			Version:      1,
+			Content:      summary,
+			MessageCount: len(unpinned),
+			CreatedAt:    time.Now(),
+		}
+		if previous != nil {
+			version.Version = previous.Version + 1
+			version.MessageCount += previous.MessageCount
+		}
+		c.summaryVersions[conversationID] = append(versions, version)
+
 		// Store summary
 		c.summaryMessages[conversationID] = interfaces.Message{
 			Role:    "system",
 			Content: summary,
 			Metadata: map[string]interface{}{
 				"is_summary": true,
-				"count":      len(messages),
+				"count":      version.MessageCount,
+				"version":    version.Version,
 			},
 		}
 
-		// Clear buffer
+		// Clear buffer, carrying pinned messages over so they are never summarized away
 		if err := c.buffer.Clear(ctx); err != nil {
 			return err
 		}
+		if err := c.buffer.AddMessages(ctx, pinnedMessages(messages)); err != nil {
+			return err
+		}
 	}
 
 	return nil
@@ -153,15 +252,66 @@ func (c *ConversationSummary) Clear(ctx context.Context) error {
 
 	// Clear summary
 	delete(c.summaryMessages, conversationID)
+	delete(c.summaryVersions, conversationID)
 
 	return nil
 }
 
-// summarize summarizes a list of messages
-func (c *ConversationSummary) summarize(ctx context.Context, messages []interfaces.Message) (string, error) {
-	// Format messages for summarization
-	var sb strings.Builder
+// GetSummary returns the latest summary of the conversation, or an empty
+// string if the conversation has not been summarized yet
+func (c *ConversationSummary) GetSummary(ctx context.Context) (string, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
 
+	conversationID, err := getConversationID(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	return c.summaryMessages[conversationID].Content, nil
+}
+
+// GetSummaryVersions returns every stored revision of the conversation summary, oldest first
+func (c *ConversationSummary) GetSummaryVersions(ctx context.Context) ([]SummaryVersion, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	conversationID, err := getConversationID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	versions := make([]SummaryVersion, len(c.summaryVersions[conversationID]))
+	copy(versions, c.summaryVersions[conversationID])
+
+	return versions, nil
+}
+
+// traceSummarize summarizes messages in a span if a tracer is set
+func (c *ConversationSummary) traceSummarize(ctx context.Context, conversationID string, previous *SummaryVersion, messages []interfaces.Message) (string, error) {
+	if c.tracer == nil {
+		return c.summarize(ctx, previous, messages)
+	}
+
+	ctx, span := c.tracer.StartSpan(ctx, "memory.summarize")
+	defer span.End()
+	span.SetAttribute("conversation_id", conversationID)
+	span.SetAttribute("messages", len(messages))
+	if previous != nil {
+		span.SetAttribute("previous_version", previous.Version)
+	}
+
+	summary, err := c.summarize(ctx, previous, messages)
+	if err != nil {
+		span.AddEvent("error", map[string]interface{}{"error": err.Error()})
+		return "", err
+	}
+	span.SetAttribute("summary_length", len(summary))
+	return summary, nil
+}
+
+// summaryPrompt builds the summarization prompt, from the template if one is set
+func (c *ConversationSummary) summaryPrompt(previous *SummaryVersion, messages []interfaces.Message) (string, error) {
 	// Get configured summary length or use default
 	summaryLength := 100
 	if c.summaryParams != nil {
@@ -170,14 +320,64 @@ func (c *ConversationSummary) summarize(ctx context.Context, messages []interfac
 		}
 	}
 
-	sb.WriteString(fmt.Sprintf("Summarize the following conversation in a concise summary (about %d words maximum):\n\n", summaryLength))
+	var transcript strings.Builder
 	for _, msg := range messages {
-		sb.WriteString(fmt.Sprintf("%s: %s\n", msg.Role, msg.Content))
+		transcript.WriteString(fmt.Sprintf("%s: %s\n", msg.Role, msg.Content))
+	}
+
+	if c.template != nil {
+		data := map[string]interface{}{
+			"Messages":        transcript.String(),
+			"PreviousSummary": "",
+			"SummaryLength":   summaryLength,
+			"Language":        c.language,
+			"MaxTokens":       c.maxTokens,
+		}
+		if previous != nil {
+			data["PreviousSummary"] = previous.Content
+		}
+		prompt, err := c.template.Render(data)
+		if err != nil {
+			return "", fmt.Errorf("failed to render summary prompt: %w", err)
+		}
+		return prompt, nil
+	}
+
+	var sb strings.Builder
+	if previous != nil {
+		sb.WriteString(fmt.Sprintf("Update the existing summary of a conversation with the new messages below. Return a single concise summary (about %d words maximum) covering both.", summaryLength))
+	} else {
+		sb.WriteString(fmt.Sprintf("Summarize the following conversation in a concise summary (about %d words maximum):", summaryLength))
+	}
+	if c.maxTokens > 0 {
+		sb.WriteString(fmt.Sprintf(" Keep the summary under %d tokens.", c.maxTokens))
+	}
+	if c.language != "" {
+		sb.WriteString(fmt.Sprintf(" Write the summary in %s.", c.language))
+	}
+	sb.WriteString("\n\n")
+	if previous != nil {
+		sb.WriteString("Existing summary:\n")
+		sb.WriteString(previous.Content)
+		sb.WriteString("\n\nNew messages:\n")
 	}
+	sb.WriteString(transcript.String())
 	sb.WriteString("\nSummary:")
+	return sb.String(), nil
+}
+
+// summarize summarizes a list of messages, extending the previous summary if there is one
+func (c *ConversationSummary) summarize(ctx context.Context, previous *SummaryVersion, messages []interfaces.Message) (string, error) {
+	prompt, err := c.summaryPrompt(previous, messages)
+	if err != nil {
+		return "", err
+	}
 
 	// Generate summary with default options instead of nil
-	summary, err := c.llmClient.Generate(ctx, sb.String(), func(o *interfaces.GenerateOptions) {
+	summary, err := c.llmClient.Generate(ctx, prompt, func(o *interfaces.GenerateOptions) {
+		if o.LLMConfig == nil {
+			o.LLMConfig = &interfaces.LLMConfig{}
+		}
 		o.LLMConfig.Temperature = 0.7
 	})
 	if err != nil {
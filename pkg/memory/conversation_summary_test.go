@@ -0,0 +1,185 @@
+package memory_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+	"github.com/run-bigpig/llm-agent/pkg/memory"
+	"github.com/run-bigpig/llm-agent/pkg/prompts"
+)
+
+// summaryLLM records prompts and returns numbered summaries
+type summaryLLM struct {
+	interfaces.LLM
+	prompts []string
+}
+
+func (l *summaryLLM) Generate(ctx context.Context, prompt string, options ...interfaces.GenerateOption) (string, error) {
+	l.prompts = append(l.prompts, prompt)
+	return fmt.Sprintf("summary %d", len(l.prompts)), nil
+}
+
+// recordingTracer records span names and attributes
+type recordingTracer struct {
+	spans []*recordingSpan
+}
+
+type recordingSpan struct {
+	name       string
+	attributes map[string]interface{}
+	ended      bool
+}
+
+func (t *recordingTracer) StartSpan(ctx context.Context, name string) (context.Context, interfaces.Span) {
+	span := &recordingSpan{name: name, attributes: map[string]interface{}{}}
+	t.spans = append(t.spans, span)
+	return ctx, span
+}
+
+func (s *recordingSpan) End()                                                    { s.ended = true }
+func (s *recordingSpan) AddEvent(name string, attributes map[string]interface{}) {}
+func (s *recordingSpan) SetAttribute(key string, value interface{})              { s.attributes[key] = value }
+
+func addMessages(t *testing.T, mem interfaces.Memory, ctx context.Context, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		if err := mem.AddMessage(ctx, interfaces.Message{Role: "user", Content: fmt.Sprintf("message %d", i)}); err != nil {
+			t.Fatalf("failed to add message: %v", err)
+		}
+	}
+}
+
+func TestConversationSummaryLanguageAndMaxTokens(t *testing.T) {
+	llm := &summaryLLM{}
+	tracer := &recordingTracer{}
+	mem := memory.NewConversationSummary(llm,
+		memory.WithMaxBufferSize(2),
+		memory.WithSummaryLanguage("German"),
+		memory.WithSummaryMaxTokens(80),
+		memory.WithSummaryTracer(tracer),
+	)
+	addMessages(t, mem, conversationContext("conv"), 4)
+
+	if len(llm.prompts) != 2 {
+		t.Fatalf("expected 2 summaries, got %d", len(llm.prompts))
+	}
+	for _, prompt := range llm.prompts {
+		if !strings.Contains(prompt, "Write the summary in German.") || !strings.Contains(prompt, "under 80 tokens") {
+			t.Errorf("expected language and token limit in prompt:\n%s", prompt)
+		}
+	}
+	if !strings.Contains(llm.prompts[1], "Existing summary:\nsummary 1") {
+		t.Errorf("expected the second prompt to extend the first summary:\n%s", llm.prompts[1])
+	}
+
+	if len(tracer.spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(tracer.spans))
+	}
+	span := tracer.spans[1]
+	if span.name != "memory.summarize" || !span.ended || span.attributes["conversation_id"] != "org-1:conv" ||
+		span.attributes["messages"] != 2 || span.attributes["previous_version"] != 1 {
+		t.Errorf("unexpected span: %+v", span)
+	}
+}
+
+func TestConversationSummaryTemplate(t *testing.T) {
+	llm := &summaryLLM{}
+	tmpl := prompts.New("summary", "Summary", "Résume en {{.Language}} ({{.SummaryLength}} mots){{if .PreviousSummary}}, en partant de: {{.PreviousSummary}}{{end}}\n{{.Messages}}")
+	mem := memory.NewConversationSummary(llm,
+		memory.WithMaxBufferSize(2),
+		memory.WithSummaryLength(50),
+		memory.WithSummaryLanguage("français"),
+		memory.WithSummaryTemplate(tmpl),
+	)
+	addMessages(t, mem, conversationContext("conv"), 4)
+
+	want := []string{
+		"Résume en français (50 mots)\nuser: message 0\nuser: message 1\n",
+		"Résume en français (50 mots), en partant de: summary 1\nuser: message 2\nuser: message 3\n",
+	}
+	for i, prompt := range llm.prompts {
+		if prompt != want[i] {
+			t.Errorf("prompt %d: expected %q, got %q", i, want[i], prompt)
+		}
+	}
+}
+
+func TestConversationSummaryVersions(t *testing.T) {
+	llm := &summaryLLM{}
+	mem := memory.NewConversationSummary(llm, memory.WithMaxBufferSize(2))
+	ctx := conversationContext("conv")
+	addMessages(t, mem, ctx, 5)
+
+	// Each summary only sends the messages added since the previous one
+	if len(llm.prompts) != 2 {
+		t.Fatalf("expected 2 summaries, got %d", len(llm.prompts))
+	}
+	if strings.Contains(llm.prompts[1], "message 0") || !strings.Contains(llm.prompts[1], "user: message 3") {
+		t.Errorf("expected only the new messages in the second prompt:\n%s", llm.prompts[1])
+	}
+
+	versions, err := mem.GetSummaryVersions(ctx)
+	if err != nil {
+		t.Fatalf("failed to get summary versions: %v", err)
+	}
+	if len(versions) != 2 {
+		t.Fatalf("expected 2 versions, got %d", len(versions))
+	}
+	for i, version := range versions {
+		if version.Version != i+1 || version.Content != fmt.Sprintf("summary %d", i+1) ||
+			version.MessageCount != 2*(i+1) || version.CreatedAt.IsZero() {
+			t.Errorf("unexpected version %d: %+v", i, version)
+		}
+	}
+
+	summary, err := mem.GetSummary(ctx)
+	if err != nil {
+		t.Fatalf("failed to get summary: %v", err)
+	}
+	if summary != "summary 2" {
+		t.Errorf("expected the latest summary, got %q", summary)
+	}
+
+	messages, err := mem.GetMessages(ctx)
+	if err != nil {
+		t.Fatalf("failed to get messages: %v", err)
+	}
+	if len(messages) != 2 || messages[0].Content != "summary 2" || messages[1].Content != "message 4" {
+		t.Fatalf("expected the summary followed by the unsummarized message, got %+v", messages)
+	}
+	if messages[0].Metadata["version"] != 2 || messages[0].Metadata["count"] != 4 {
+		t.Errorf("unexpected summary metadata: %v", messages[0].Metadata)
+	}
+
+	// Other conversations have their own summaries
+	other, err := mem.GetSummaryVersions(conversationContext("other"))
+	if err != nil {
+		t.Fatalf("failed to get summary versions: %v", err)
+	}
+	if len(other) != 0 {
+		t.Errorf("expected no versions for another conversation, got %+v", other)
+	}
+
+	if err := mem.Clear(ctx); err != nil {
+		t.Fatalf("failed to clear memory: %v", err)
+	}
+	versions, _ = mem.GetSummaryVersions(ctx)
+	summary, _ = mem.GetSummary(ctx)
+	if len(versions) != 0 || summary != "" {
+		t.Errorf("expected Clear to remove the summaries, got %q and %+v", summary, versions)
+	}
+}
+
+func TestConversationSummaryLLM(t *testing.T) {
+	main := &summaryLLM{}
+	cheap := &summaryLLM{}
+	mem := memory.NewConversationSummary(main, memory.WithMaxBufferSize(2), memory.WithSummaryLLM(cheap))
+	addMessages(t, mem, conversationContext("conv"), 2)
+
+	if len(main.prompts) != 0 || len(cheap.prompts) != 1 {
+		t.Errorf("expected the summary LLM to summarize, got %d and %d prompts", len(main.prompts), len(cheap.prompts))
+	}
+}
@@ -0,0 +1,130 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrConversationNotFound is returned when a conversation is not in the store
+var ErrConversationNotFound = errors.New("conversation not found")
+
+// Conversation describes a conversation for history lists in chat UIs
+type Conversation struct {
+	ID     string `json:"id"`
+	OrgID  string `json:"org_id,omitempty"`
+	UserID string `json:"user_id,omitempty"`
+
+	// Title is a short title of the conversation, e.g. "Refund for order 1234"
+	Title string `json:"title,omitempty"`
+
+	// Topics are short lowercase topic tags, e.g. "billing"
+	Topics []string `json:"topics,omitempty"`
+
+	// Turns is the number of answered inputs
+	Turns int `json:"turns"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ConversationFilter selects stored conversations. Empty fields match
+// everything.
+type ConversationFilter struct {
+	OrgID  string
+	UserID string
+	Topic  string
+}
+
+// Matches reports whether a conversation matches the filter
+func (f ConversationFilter) Matches(c Conversation) bool {
+	if f.OrgID != "" && c.OrgID != f.OrgID || f.UserID != "" && c.UserID != f.UserID {
+		return false
+	}
+	if f.Topic == "" {
+		return true
+	}
+	for _, topic := range c.Topics {
+		if topic == f.Topic {
+			return true
+		}
+	}
+	return false
+}
+
+// ConversationStore persists the titles and topics of conversations
+type ConversationStore interface {
+	// Save saves a conversation, replacing the one with the same org and ID
+	Save(ctx context.Context, conversation Conversation) error
+
+	// Get returns a conversation of an org, or ErrConversationNotFound
+	Get(ctx context.Context, orgID, conversationID string) (Conversation, error)
+
+	// List returns the conversations matching the filter, most recently
+	// updated first
+	List(ctx context.Context, filter ConversationFilter) ([]Conversation, error)
+}
+
+// MemoryConversationStore keeps conversations in memory, for tests and
+// single-process use
+type MemoryConversationStore struct {
+	mu            sync.RWMutex
+	conversations map[conversationKey]Conversation
+}
+
+// conversationKey identifies a conversation; IDs are only unique within an org
+type conversationKey struct {
+	orgID string
+	id    string
+}
+
+// NewMemoryConversationStore creates an empty in-memory conversation store
+func NewMemoryConversationStore() *MemoryConversationStore {
+	return &MemoryConversationStore{conversations: make(map[conversationKey]Conversation)}
+}
+
+// Save saves a conversation, replacing the one with the same org and ID
+func (s *MemoryConversationStore) Save(ctx context.Context, conversation Conversation) error {
+	conversation.Topics = append([]string(nil), conversation.Topics...)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.conversations[conversationKey{orgID: conversation.OrgID, id: conversation.ID}] = conversation
+	return nil
+}
+
+// Get returns a conversation of an org, or ErrConversationNotFound
+func (s *MemoryConversationStore) Get(ctx context.Context, orgID, conversationID string) (Conversation, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	conversation, ok := s.conversations[conversationKey{orgID: orgID, id: conversationID}]
+	if !ok {
+		return Conversation{}, ErrConversationNotFound
+	}
+	conversation.Topics = append([]string(nil), conversation.Topics...)
+	return conversation, nil
+}
+
+// List returns the conversations matching the filter, most recently updated
+// first
+func (s *MemoryConversationStore) List(ctx context.Context, filter ConversationFilter) ([]Conversation, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var result []Conversation
+	for _, conversation := range s.conversations {
+		if filter.Matches(conversation) {
+			conversation.Topics = append([]string(nil), conversation.Topics...)
+			result = append(result, conversation)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if !result[i].UpdatedAt.Equal(result[j].UpdatedAt) {
+			return result[i].UpdatedAt.After(result[j].UpdatedAt)
+		}
+		return result[i].ID < result[j].ID
+	})
+	return result, nil
+}
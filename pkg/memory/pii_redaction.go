@@ -0,0 +1,322 @@
+package memory
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+)
+
+// piiPattern is a named pattern of personally identifiable information
+type piiPattern struct {
+	name    string
+	pattern *regexp.Regexp
+}
+
+// defaultPIIPatterns are applied in order, so more specific patterns come first
+func defaultPIIPatterns() []piiPattern {
+	return []piiPattern{
+		{"email", regexp.MustCompile(`\b[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}\b`)},
+		{"credit_card", regexp.MustCompile(`\b\d{4}[- ]?\d{4}[- ]?\d{4}[- ]?\d{4}\b`)},
+		{"ssn", regexp.MustCompile(`\b\d{3}-\d{2}-\d{4}\b`)},
+		{"phone", regexp.MustCompile(`(\+\d{1,2}\s)?\(?\b\d{3}\)?[\s.-]?\d{3}[\s.-]?\d{4}\b`)},
+	}
+}
+
+// tokenPattern matches tokens produced by reversible tokenization
+var tokenPattern = regexp.MustCompile(`\[PII:[^:\]]+:[0-9a-f]{16}\]`)
+
+// TokenVault stores the original values behind PII tokens
+type TokenVault interface {
+	// Store saves the original value for a token
+	Store(ctx context.Context, token, value string) error
+
+	// Lookup returns the original value for a token
+	Lookup(ctx context.Context, token string) (string, bool, error)
+}
+
+// InMemoryTokenVault implements an in-memory token vault
+type InMemoryTokenVault struct {
+	values map[string]string
+	mu     sync.RWMutex
+}
+
+// NewInMemoryTokenVault creates a new in-memory token vault
+func NewInMemoryTokenVault() *InMemoryTokenVault {
+	return &InMemoryTokenVault{
+		values: make(map[string]string),
+	}
+}
+
+// Store saves the original value for a token
+func (v *InMemoryTokenVault) Store(ctx context.Context, token, value string) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.values[token] = value
+	return nil
+}
+
+// Lookup returns the original value for a token
+func (v *InMemoryTokenVault) Lookup(ctx context.Context, token string) (string, bool, error) {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	value, ok := v.values[token]
+	return value, ok, nil
+}
+
+// piiAccessKey is the context key that authorizes PII detokenization
+const piiAccessKey contextKey = "pii_access"
+
+// WithPIIAccess marks the context as authorized to read the original values of tokenized PII
+func WithPIIAccess(ctx context.Context) context.Context {
+	return context.WithValue(ctx, piiAccessKey, true)
+}
+
+// hasPIIAccess reports whether the context is authorized to read tokenized PII
+func hasPIIAccess(ctx context.Context) bool {
+	allowed, _ := ctx.Value(piiAccessKey).(bool)
+	return allowed
+}
+
+// PIIRedactor is a memory decorator that removes PII from message content
+// before it is persisted by the wrapped memory
+type PIIRedactor struct {
+	memory         interfaces.Memory
+	patterns       []piiPattern
+	customPatterns [][2]string
+	vault          TokenVault
+}
+
+// RedactorOption represents an option for configuring the PII redactor
+type RedactorOption func(*PIIRedactor)
+
+// WithPIIPattern adds a custom pattern that is redacted under the given
+// name. NewPIIRedactor returns an error if the pattern doesn't compile.
+func WithPIIPattern(name, pattern string) RedactorOption {
+	return func(r *PIIRedactor) {
+		r.customPatterns = append(r.customPatterns, [2]string{name, pattern})
+	}
+}
+
+// WithTokenVault enables reversible tokenization. Matched values are replaced
+// with tokens and stored in the vault so that callers with PII access (see
+// WithPIIAccess) can read the original content.
+func WithTokenVault(vault TokenVault) RedactorOption {
+	return func(r *PIIRedactor) {
+		r.vault = vault
+	}
+}
+
+// NewPIIRedactor wraps a memory so that emails, phone numbers, credit card
+// numbers, SSNs and custom patterns are redacted before they are stored
+func NewPIIRedactor(memory interfaces.Memory, options ...RedactorOption) (*PIIRedactor, error) {
+	redactor := &PIIRedactor{
+		memory:   memory,
+		patterns: defaultPIIPatterns(),
+	}
+
+	for _, option := range options {
+		option(redactor)
+	}
+
+	for _, custom := range redactor.customPatterns {
+		pattern, err := regexp.Compile(custom[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid PII pattern %q: %w", custom[0], err)
+		}
+		redactor.patterns = append(redactor.patterns, piiPattern{name: custom[0], pattern: pattern})
+	}
+
+	return redactor, nil
+}
+
+// AddMessage redacts the message content and the string values in its
+// metadata, such as tool call arguments, and adds it to the wrapped memory.
+// Other metadata values, like numbers, are stored as they are.
+func (r *PIIRedactor) AddMessage(ctx context.Context, message interfaces.Message) error {
+	redacted, err := r.Redact(ctx, message.Content)
+	if err != nil {
+		return err
+	}
+	message.Content = redacted
+
+	if message.Metadata != nil {
+		metadata, err := transformStrings(ctx, message.Metadata, r.Redact)
+		if err != nil {
+			return err
+		}
+		message.Metadata = metadata.(map[string]interface{})
+	}
+
+	return r.memory.AddMessage(ctx, message)
+}
+
+// GetMessages retrieves messages from the wrapped memory. Tokenized values are
+// restored only when the context has PII access.
+func (r *PIIRedactor) GetMessages(ctx context.Context, options ...interfaces.GetMessagesOption) ([]interfaces.Message, error) {
+	messages, err := r.memory.GetMessages(ctx, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.vault == nil || !hasPIIAccess(ctx) {
+		return messages, nil
+	}
+
+	restored := make([]interfaces.Message, len(messages))
+	for i, message := range messages {
+		content, err := r.Detokenize(ctx, message.Content)
+		if err != nil {
+			return nil, err
+		}
+		message.Content = content
+		if message.Metadata != nil {
+			metadata, err := transformStrings(ctx, message.Metadata, r.Detokenize)
+			if err != nil {
+				return nil, err
+			}
+			message.Metadata = metadata.(map[string]interface{})
+		}
+		restored[i] = message
+	}
+
+	return restored, nil
+}
+
+// Clear clears the wrapped memory
+func (r *PIIRedactor) Clear(ctx context.Context) error {
+	return r.memory.Clear(ctx)
+}
+
+// Redact replaces PII in the text with tokens (when a vault is configured) or redaction markers
+func (r *PIIRedactor) Redact(ctx context.Context, text string) (string, error) {
+	var vaultErr error
+
+	for _, p := range r.patterns {
+		replace := func(match string) string {
+			if r.vault == nil {
+				return "[REDACTED " + p.name + "]"
+			}
+			if vaultErr != nil {
+				return match
+			}
+
+			token, err := newPIIToken(p.name)
+			if err != nil {
+				vaultErr = err
+				return match
+			}
+			if err := r.vault.Store(ctx, token, match); err != nil {
+				vaultErr = fmt.Errorf("failed to store PII token: %w", err)
+				return match
+			}
+			return token
+		}
+
+		// Skip tokens produced by earlier patterns so their IDs are never re-matched
+		text = replaceOutsideTokens(text, func(segment string) string {
+			return p.pattern.ReplaceAllStringFunc(segment, replace)
+		})
+		if vaultErr != nil {
+			return "", vaultErr
+		}
+	}
+
+	return text, nil
+}
+
+// Detokenize restores the original values of PII tokens in the text
+func (r *PIIRedactor) Detokenize(ctx context.Context, text string) (string, error) {
+	if r.vault == nil {
+		return text, nil
+	}
+
+	var lookupErr error
+	text = tokenPattern.ReplaceAllStringFunc(text, func(token string) string {
+		if lookupErr != nil {
+			return token
+		}
+		value, ok, err := r.vault.Lookup(ctx, token)
+		if err != nil {
+			lookupErr = fmt.Errorf("failed to look up PII token: %w", err)
+			return token
+		}
+		if !ok {
+			return token
+		}
+		return value
+	})
+	if lookupErr != nil {
+		return "", lookupErr
+	}
+
+	return text, nil
+}
+
+// transformStrings returns a copy of a metadata value with transform applied
+// to its strings, including those in nested maps and slices
+func transformStrings(ctx context.Context, value interface{}, transform func(context.Context, string) (string, error)) (interface{}, error) {
+	switch v := value.(type) {
+	case string:
+		return transform(ctx, v)
+	case map[string]interface{}:
+		copied := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			transformed, err := transformStrings(ctx, item, transform)
+			if err != nil {
+				return nil, err
+			}
+			copied[key] = transformed
+		}
+		return copied, nil
+	case []interface{}:
+		copied := make([]interface{}, len(v))
+		for i, item := range v {
+			transformed, err := transformStrings(ctx, item, transform)
+			if err != nil {
+				return nil, err
+			}
+			copied[i] = transformed
+		}
+		return copied, nil
+	case []string:
+		copied := make([]string, len(v))
+		for i, item := range v {
+			transformed, err := transform(ctx, item)
+			if err != nil {
+				return nil, err
+			}
+			copied[i] = transformed
+		}
+		return copied, nil
+	default:
+		return value, nil
+	}
+}
+
+// replaceOutsideTokens applies replace to the parts of text that are not PII tokens
+func replaceOutsideTokens(text string, replace func(string) string) string {
+	var sb strings.Builder
+	last := 0
+	for _, loc := range tokenPattern.FindAllStringIndex(text, -1) {
+		sb.WriteString(replace(text[last:loc[0]]))
+		sb.WriteString(text[loc[0]:loc[1]])
+		last = loc[1]
+	}
+	sb.WriteString(replace(text[last:]))
+	return sb.String()
+}
+
+// newPIIToken generates a random token for a PII value
+func newPIIToken(name string) (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate PII token: %w", err)
+	}
+	return fmt.Sprintf("[PII:%s:%s]", strings.ToLower(name), hex.EncodeToString(b)), nil
+}
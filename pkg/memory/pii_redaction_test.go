@@ -0,0 +1,120 @@
+package memory_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+	"github.com/run-bigpig/llm-agent/pkg/memory"
+)
+
+func TestPIIRedactorDefaultPatterns(t *testing.T) {
+	redactor, err := memory.NewPIIRedactor(memory.NewConversationBuffer())
+	if err != nil {
+		t.Fatalf("failed to create redactor: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		text     string
+		expected string
+	}{
+		{"email", "mail jane.doe+news@example.co.uk today", "mail [REDACTED email] today"},
+		{"credit card", "card 4111 1111 1111 1111 on file", "card [REDACTED credit_card] on file"},
+		{"credit card with dashes", "card 4111-1111-1111-1111", "card [REDACTED credit_card]"},
+		{"ssn", "ssn 123-45-6789", "ssn [REDACTED ssn]"},
+		{"phone", "call 555-123-4567", "call [REDACTED phone]"},
+		{"phone with area code", "call (555) 123-4567", "call [REDACTED phone]"},
+		{"no pii", "order 42 shipped", "order 42 shipped"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			redacted, err := redactor.Redact(context.Background(), tt.text)
+			if err != nil {
+				t.Fatalf("failed to redact: %v", err)
+			}
+			if redacted != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, redacted)
+			}
+		})
+	}
+}
+
+func TestPIIRedactorInvalidPattern(t *testing.T) {
+	if _, err := memory.NewPIIRedactor(memory.NewConversationBuffer(), memory.WithPIIPattern("account_id", `ACCT-(\d{6}`)); err == nil || !strings.Contains(err.Error(), "account_id") {
+		t.Errorf("expected an error naming the invalid pattern, got %v", err)
+	}
+
+	redactor, err := memory.NewPIIRedactor(memory.NewConversationBuffer(), memory.WithPIIPattern("account_id", `ACCT-\d{6}`))
+	if err != nil {
+		t.Fatalf("failed to create redactor: %v", err)
+	}
+	if redacted, _ := redactor.Redact(context.Background(), "account ACCT-123456"); redacted != "account [REDACTED account_id]" {
+		t.Errorf("expected the custom pattern to be redacted, got %q", redacted)
+	}
+}
+
+func TestPIIRedactorReversibleTokens(t *testing.T) {
+	buffer := memory.NewConversationBuffer()
+	redactor, err := memory.NewPIIRedactor(buffer, memory.WithTokenVault(memory.NewInMemoryTokenVault()))
+	if err != nil {
+		t.Fatalf("failed to create redactor: %v", err)
+	}
+
+	tests := []string{
+		"reach me at jane@example.com",
+		"card 4111 1111 1111 1111 and ssn 123-45-6789",
+		"call 555-123-4567 or mail bob@example.org",
+	}
+	ctx := conversationContext("pii")
+	for _, text := range tests {
+		if err := redactor.AddMessage(ctx, interfaces.Message{
+			Role:     "user",
+			Content:  text,
+			Metadata: map[string]interface{}{"tool_arguments": map[string]interface{}{"to": []interface{}{"jane@example.com"}}, "attempt": 1},
+		}); err != nil {
+			t.Fatalf("failed to add message: %v", err)
+		}
+	}
+
+	// The wrapped memory only stores tokens
+	stored, err := buffer.GetMessages(ctx)
+	if err != nil {
+		t.Fatalf("failed to get stored messages: %v", err)
+	}
+	for i, message := range stored {
+		if message.Content == tests[i] || !strings.Contains(message.Content, "[PII:") {
+			t.Errorf("expected message %d to be tokenized, got %q", i, message.Content)
+		}
+		to := message.Metadata["tool_arguments"].(map[string]interface{})["to"].([]interface{})[0].(string)
+		if !strings.HasPrefix(to, "[PII:email:") || message.Metadata["attempt"] != 1 {
+			t.Errorf("expected the metadata strings to be tokenized, got %v", message.Metadata)
+		}
+	}
+
+	// Without PII access the tokens are returned
+	messages, err := redactor.GetMessages(ctx)
+	if err != nil {
+		t.Fatalf("failed to get messages: %v", err)
+	}
+	for i, message := range messages {
+		if message.Content != stored[i].Content {
+			t.Errorf("expected tokens without PII access, got %q", message.Content)
+		}
+	}
+
+	// With PII access the original content is restored
+	messages, err = redactor.GetMessages(memory.WithPIIAccess(ctx))
+	if err != nil {
+		t.Fatalf("failed to get messages: %v", err)
+	}
+	for i, message := range messages {
+		if message.Content != tests[i] {
+			t.Errorf("expected %q, got %q", tests[i], message.Content)
+		}
+		if to := message.Metadata["tool_arguments"].(map[string]interface{})["to"].([]interface{})[0]; to != "jane@example.com" {
+			t.Errorf("expected the metadata to be restored, got %v", to)
+		}
+	}
+}
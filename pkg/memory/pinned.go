@@ -0,0 +1,71 @@
+package memory
+
+import (
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+)
+
+// PinnedMetadataKey is the message metadata key that marks a message as pinned.
+// Pinned messages (system facts, user preferences, ...) are never evicted when
+// a memory trims its window.
+const PinnedMetadataKey = "pinned"
+
+// PinMessage returns a copy of the message marked as pinned
+func PinMessage(message interfaces.Message) interfaces.Message {
+	metadata := make(map[string]interface{}, len(message.Metadata)+1)
+	for k, v := range message.Metadata {
+		metadata[k] = v
+	}
+	metadata[PinnedMetadataKey] = true
+	message.Metadata = metadata
+	return message
+}
+
+// IsPinned reports whether the message is marked as pinned
+func IsPinned(message interfaces.Message) bool {
+	pinned, _ := message.Metadata[PinnedMetadataKey].(bool)
+	return pinned
+}
+
+// trimPreservingPinned keeps at most limit messages, evicting the oldest
+// unpinned messages first. Pinned messages are always kept, even if they alone
+// exceed the limit. The original order is preserved.
+func trimPreservingPinned(messages []interfaces.Message, limit int) []interfaces.Message {
+	if limit <= 0 || len(messages) <= limit {
+		return messages
+	}
+
+	pinned := 0
+	for _, msg := range messages {
+		if IsPinned(msg) {
+			pinned++
+		}
+	}
+
+	// Number of unpinned messages to evict, oldest first
+	evict := len(messages) - limit
+	if unpinned := len(messages) - pinned; evict > unpinned {
+		evict = unpinned
+	}
+
+	trimmed := make([]interfaces.Message, 0, len(messages)-evict)
+	for _, msg := range messages {
+		if evict > 0 && !IsPinned(msg) {
+			evict--
+			continue
+		}
+		trimmed = append(trimmed, msg)
+	}
+
+	return trimmed
+}
+
+// pinnedMessages returns the pinned messages in order
+func pinnedMessages(messages []interfaces.Message) []interfaces.Message {
+	var pinned []interfaces.Message
+	for _, msg := range messages {
+		if IsPinned(msg) {
+			pinned = append(pinned, msg)
+		}
+	}
+	return pinned
+}
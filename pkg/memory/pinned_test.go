@@ -0,0 +1,120 @@
+package memory_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+	"github.com/run-bigpig/llm-agent/pkg/memory"
+)
+
+func contents(messages []interfaces.Message) []string {
+	result := make([]string, len(messages))
+	for i, msg := range messages {
+		result[i] = msg.Content
+	}
+	return result
+}
+
+func addContents(t *testing.T, mem interfaces.Memory, ctx context.Context, messages ...interfaces.Message) {
+	t.Helper()
+	for _, msg := range messages {
+		if err := mem.AddMessage(ctx, msg); err != nil {
+			t.Fatalf("failed to add message: %v", err)
+		}
+	}
+}
+
+func TestPinMessage(t *testing.T) {
+	message := interfaces.Message{Role: "system", Content: "prefers metric units", Metadata: map[string]interface{}{"source": "profile"}}
+	pinned := memory.PinMessage(message)
+
+	if !memory.IsPinned(pinned) || pinned.Metadata["source"] != "profile" {
+		t.Errorf("expected a pinned copy with the original metadata, got %v", pinned.Metadata)
+	}
+	if memory.IsPinned(message) {
+		t.Error("expected the original message not to be pinned")
+	}
+	if memory.IsPinned(interfaces.Message{Metadata: map[string]interface{}{memory.PinnedMetadataKey: "yes"}}) {
+		t.Error("expected only a true flag to pin a message")
+	}
+}
+
+func TestConversationBufferKeepsPinnedMessages(t *testing.T) {
+	buffer := memory.NewConversationBuffer(memory.WithMaxSize(3))
+	ctx := conversationContext("conv")
+
+	addContents(t, buffer, ctx,
+		memory.PinMessage(interfaces.Message{Role: "system", Content: "fact"}),
+		interfaces.Message{Role: "user", Content: "one"},
+		interfaces.Message{Role: "assistant", Content: "two"},
+		interfaces.Message{Role: "user", Content: "three"},
+		interfaces.Message{Role: "assistant", Content: "four"},
+	)
+
+	messages, err := buffer.GetMessages(ctx)
+	if err != nil {
+		t.Fatalf("failed to get messages: %v", err)
+	}
+	if got := fmt.Sprint(contents(messages)); got != "[fact three four]" {
+		t.Errorf("expected the pinned message and the newest messages, got %s", got)
+	}
+
+	messages, err = buffer.GetMessages(ctx, interfaces.WithLimit(2))
+	if err != nil {
+		t.Fatalf("failed to get messages: %v", err)
+	}
+	if got := fmt.Sprint(contents(messages)); got != "[fact four]" {
+		t.Errorf("expected the limit to keep the pinned message, got %s", got)
+	}
+}
+
+func TestConversationBufferKeepsPinnedMessagesOverLimit(t *testing.T) {
+	buffer := memory.NewConversationBuffer(memory.WithMaxSize(2))
+	ctx := conversationContext("conv")
+
+	addContents(t, buffer, ctx,
+		memory.PinMessage(interfaces.Message{Role: "system", Content: "a"}),
+		interfaces.Message{Role: "user", Content: "b"},
+		memory.PinMessage(interfaces.Message{Role: "system", Content: "c"}),
+		memory.PinMessage(interfaces.Message{Role: "system", Content: "d"}),
+	)
+
+	messages, err := buffer.GetMessages(ctx)
+	if err != nil {
+		t.Fatalf("failed to get messages: %v", err)
+	}
+	if got := fmt.Sprint(contents(messages)); got != "[a c d]" {
+		t.Errorf("expected every pinned message to be kept, got %s", got)
+	}
+}
+
+func TestConversationSummaryKeepsPinnedMessages(t *testing.T) {
+	llm := &summaryLLM{}
+	mem := memory.NewConversationSummary(llm, memory.WithMaxBufferSize(2))
+	ctx := conversationContext("conv")
+
+	addContents(t, mem, ctx,
+		memory.PinMessage(interfaces.Message{Role: "system", Content: "fact"}),
+		interfaces.Message{Role: "user", Content: "one"},
+		interfaces.Message{Role: "assistant", Content: "two"},
+		interfaces.Message{Role: "user", Content: "three"},
+	)
+
+	if len(llm.prompts) != 1 {
+		t.Fatalf("expected 1 summary, got %d", len(llm.prompts))
+	}
+	if prompt := llm.prompts[0]; strings.Contains(prompt, "system: fact") {
+		t.Errorf("expected the pinned message not to be summarized:\n%s", prompt)
+	}
+
+	messages, err := mem.GetMessages(ctx)
+	if err != nil {
+		t.Fatalf("failed to get messages: %v", err)
+	}
+	if got := fmt.Sprint(contents(messages)); got != "[summary 1 fact three]" {
+		t.Errorf("expected the summary, the pinned message and the new message, got %s", got)
+	}
+}
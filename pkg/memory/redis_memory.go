@@ -3,12 +3,14 @@ package memory
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"math"
 	"time"
 
 	"github.com/go-redis/redis/v8"
 
+	"github.com/run-bigpig/llm-agent/pkg/crypto"
 	"github.com/run-bigpig/llm-agent/pkg/interfaces"
 	"github.com/run-bigpig/llm-agent/pkg/multitenancy"
 )
@@ -20,8 +22,11 @@ type RedisMemory struct {
 	keyPrefix          string
 	compressionEnabled bool
 	encryptionKey      []byte
+	encryptor          *crypto.Encryptor
+	encryptorErr       error
 	maxMessageSize     int
 	retryOptions       *RetryOptions
+	conflictPolicy     ConflictPolicy
 }
 
 // RetryOptions configures retry behavior for Redis operations
@@ -55,13 +60,22 @@ func WithCompression(enabled bool) RedisOption {
 	}
 }
 
-// WithEncryption enables encryption for stored messages
+// WithEncryption enables encryption for stored messages using a local master
+// key of at least 32 bytes. Each organization is encrypted with its own derived key.
 func WithEncryption(key []byte) RedisOption {
 	return func(r *RedisMemory) {
 		r.encryptionKey = key
 	}
 }
 
+// WithEncryptor enables envelope encryption of stored message content, e.g.
+// with data keys managed by a KMS
+func WithEncryptor(encryptor *crypto.Encryptor) RedisOption {
+	return func(r *RedisMemory) {
+		r.encryptor = encryptor
+	}
+}
+
 // WithMaxMessageSize sets the maximum size for stored messages
 func WithMaxMessageSize(size int) RedisOption {
 	return func(r *RedisMemory) {
@@ -76,6 +90,14 @@ func WithRetryOptions(options *RetryOptions) RedisOption {
 	}
 }
 
+// WithConflictPolicy sets what happens when a message is added with a stale
+// expected sequence number (see WithExpectedSequence). The default is ConflictMerge.
+func WithConflictPolicy(policy ConflictPolicy) RedisOption {
+	return func(r *RedisMemory) {
+		r.conflictPolicy = policy
+	}
+}
+
 // RedisConfig contains configuration for Redis
 type RedisConfig struct {
 	// URL is the Redis URL (e.g., "localhost:6379")
@@ -101,50 +123,66 @@ func NewRedisMemory(client *redis.Client, options ...RedisOption) *RedisMemory {
 			RetryInterval: 100 * time.Millisecond,
 			BackoffFactor: 2.0,
 		},
+		conflictPolicy: ConflictMerge,
 	}
 
 	for _, option := range options {
 		option(memory)
 	}
 
+	// Build an encryptor from the local key if no encryptor was provided
+	if memory.encryptor == nil && memory.encryptionKey != nil {
+		keyManager, err := crypto.NewLocalKeyManager(memory.encryptionKey)
+		if err != nil {
+			memory.encryptorErr = err
+		} else {
+			memory.encryptor = crypto.NewEncryptor(keyManager)
+		}
+	}
+
 	return memory
 }
 
-// AddMessage adds a message to the memory with improved error handling and retry logic
+// AddMessage adds a message to the memory with improved error handling and
+// retry logic. The message is stored with the conversation's next sequence
+// number in its metadata (see Sequence).
 func (r *RedisMemory) AddMessage(ctx context.Context, message interfaces.Message) error {
-	// Get conversation ID from context
-	conversationID, err := getConversationID(ctx)
-	if err != nil {
-		return err
-	}
+	return r.AddMessages(ctx, []interfaces.Message{message})
+}
 
-	// Get organization ID from context for multi-tenancy support
-	orgID, err := multitenancy.GetOrgID(ctx)
-	if err != nil {
-		// If no organization ID is found, use a default
-		orgID = "default"
+// AddMessages adds several messages to the memory in a single transaction,
+// numbering them in order. Either all of the messages are stored or none are.
+func (r *RedisMemory) AddMessages(ctx context.Context, messages []interfaces.Message) error {
+	if len(messages) == 0 {
+		return nil
 	}
 
 	// Create Redis key with org and conversation IDs for proper isolation
-	key := fmt.Sprintf("%s%s:%s", r.keyPrefix, orgID, conversationID)
+	key, err := r.conversationKey(ctx)
+	if err != nil {
+		return err
+	}
 
-	// Validate message size if configured
-	if r.maxMessageSize > 0 {
-		messageBytes, err := json.Marshal(message)
-		if err != nil {
-			return fmt.Errorf("failed to marshal message: %w", err)
-		}
-		if len(messageBytes) > r.maxMessageSize {
-			return fmt.Errorf("message size exceeds maximum allowed size of %d bytes", r.maxMessageSize)
+	processedMessages := make([]interfaces.Message, len(messages))
+	for i, message := range messages {
+		// Validate message size if configured
+		if r.maxMessageSize > 0 {
+			messageBytes, err := json.Marshal(message)
+			if err != nil {
+				return fmt.Errorf("failed to marshal message: %w", err)
+			}
+			if len(messageBytes) > r.maxMessageSize {
+				return fmt.Errorf("message size exceeds maximum allowed size of %d bytes", r.maxMessageSize)
+			}
 		}
-	}
 
-	// Process message content (compression/encryption) if enabled
-	processedMessage := message
-	if r.compressionEnabled || r.encryptionKey != nil {
-		processedMessage, err = r.processMessage(message)
-		if err != nil {
-			return fmt.Errorf("failed to process message: %w", err)
+		// Process message content (compression/encryption) if enabled
+		processedMessages[i] = message
+		if r.compressionEnabled || r.encryptionKey != nil || r.encryptor != nil {
+			processedMessages[i], err = r.processMessage(ctx, message)
+			if err != nil {
+				return fmt.Errorf("failed to process message: %w", err)
+			}
 		}
 	}
 
@@ -158,29 +196,114 @@ func (r *RedisMemory) AddMessage(ctx context.Context, message interfaces.Message
 			time.Sleep(backoffDuration)
 		}
 
-		// Serialize message to JSON
-		messageJSON, err := json.Marshal(processedMessage)
-		if err != nil {
-			return fmt.Errorf("failed to marshal message: %w", err)
-		}
-
-		// Add message to Redis list
-		err = r.client.RPush(ctx, key, messageJSON).Err()
-		if err == nil {
-			// Set TTL on the key if not already set
-			r.client.Expire(ctx, key, r.ttl)
-			return nil
+		// Add messages to Redis list
+		err := r.append(ctx, key, processedMessages)
+		if err == nil || errors.Is(err, ErrConflict) {
+			return err
 		}
 
 		retryErr = err
 	}
 
-	return fmt.Errorf("failed to add message to Redis after %d attempts: %w",
+	return fmt.Errorf("failed to add messages to Redis after %d attempts: %w",
 		r.retryOptions.MaxRetries, retryErr)
 }
 
+// append stores the messages with the conversation's next sequence numbers.
+// The sequence number is read and incremented in a transaction, so messages
+// from concurrent writers are numbered in the order they are stored. The
+// messages are pushed in one RPUSH, and the TTL is only set when the
+// conversation is created.
+func (r *RedisMemory) append(ctx context.Context, key string, messages []interfaces.Message) error {
+	seqKey := sequenceKey(key)
+	for {
+		err := r.client.Watch(ctx, func(tx *redis.Tx) error {
+			last, err := tx.Get(ctx, seqKey).Int64()
+			created := err == redis.Nil
+			if err != nil && !created {
+				return err
+			}
+
+			if expected, ok := ExpectedSequence(ctx); ok && expected != last && r.conflictPolicy == ConflictReject {
+				return fmt.Errorf("%w: expected sequence %d, conversation is at %d", ErrConflict, expected, last)
+			}
+
+			// Serialize messages to JSON
+			values := make([]interface{}, len(messages))
+			for i, message := range messages {
+				messageJSON, err := json.Marshal(withSequence(message, last+int64(i)+1))
+				if err != nil {
+					return fmt.Errorf("failed to marshal message: %w", err)
+				}
+				values[i] = messageJSON
+			}
+
+			_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+				pipe.RPush(ctx, key, values...)
+				if created {
+					pipe.Set(ctx, seqKey, last+int64(len(messages)), r.ttl)
+					pipe.Expire(ctx, key, r.ttl)
+				} else {
+					pipe.Set(ctx, seqKey, last+int64(len(messages)), redis.KeepTTL)
+				}
+				return nil
+			})
+			return err
+		}, seqKey)
+		if err != redis.TxFailedErr {
+			return err
+		}
+
+		// Another writer added a message first; try again with its sequence number
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+}
+
+// LastSequence returns the sequence number of the last message in the
+// conversation, or 0 if it is empty. Pass it to WithExpectedSequence to add a
+// message only if nobody else has added one since.
+func (r *RedisMemory) LastSequence(ctx context.Context) (int64, error) {
+	key, err := r.conversationKey(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	last, err := r.client.Get(ctx, sequenceKey(key)).Int64()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to get sequence from Redis: %w", err)
+	}
+	return last, nil
+}
+
+// conversationKey returns the Redis key of the conversation in the context
+func (r *RedisMemory) conversationKey(ctx context.Context) (string, error) {
+	conversationID, err := getConversationID(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to get conversation ID: %w", err)
+	}
+
+	// Get organization ID from context for multi-tenancy support
+	orgID, err := multitenancy.GetOrgID(ctx)
+	if err != nil {
+		// If no organization ID is found, use a default
+		orgID = "default"
+	}
+
+	return fmt.Sprintf("%s%s:%s", r.keyPrefix, orgID, conversationID), nil
+}
+
+// sequenceKey returns the key of a conversation's last sequence number
+func sequenceKey(key string) string {
+	return key + ":seq"
+}
+
 // processMessage handles compression and encryption of messages
-func (r *RedisMemory) processMessage(message interfaces.Message) (interfaces.Message, error) {
+func (r *RedisMemory) processMessage(ctx context.Context, message interfaces.Message) (interfaces.Message, error) {
 	// Create a copy of the message to avoid modifying the original
 	processedMessage := message
 
@@ -192,10 +315,15 @@ func (r *RedisMemory) processMessage(message interfaces.Message) (interfaces.Mes
 	}
 
 	// Apply encryption if enabled
-	if r.encryptionKey != nil {
-		// TODO: Implement encryption in the future
-		// No-op to avoid empty branch warning
-		_ = len(r.encryptionKey)
+	if r.encryptorErr != nil {
+		return message, fmt.Errorf("invalid encryption configuration: %w", r.encryptorErr)
+	}
+	if r.encryptor != nil {
+		encrypted, err := r.encryptor.EncryptString(ctx, message.Content)
+		if err != nil {
+			return message, err
+		}
+		processedMessage.Content = encrypted
 	}
 
 	return processedMessage, nil
@@ -206,22 +334,12 @@ func (r *RedisMemory) GetMessages(ctx context.Context, options ...interfaces.Get
 	// ... implement with similar improvements to AddMessage
 	// Include support for pagination, filtering by role, etc.
 
-	// Get conversation ID from context
-	conversationID, err := getConversationID(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get conversation ID: %w", err)
-	}
-
-	// Get organization ID from context for multi-tenancy support
-	orgID, err := multitenancy.GetOrgID(ctx)
+	// Create Redis key with org and conversation IDs
+	key, err := r.conversationKey(ctx)
 	if err != nil {
-		// If no organization ID is found, use a default
-		orgID = "default"
+		return nil, err
 	}
 
-	// Create Redis key with org and conversation IDs
-	key := fmt.Sprintf("%s%s:%s", r.keyPrefix, orgID, conversationID)
-
 	// Apply options
 	opts := &interfaces.GetMessagesOptions{}
 	for _, option := range options {
@@ -241,6 +359,16 @@ func (r *RedisMemory) GetMessages(ctx context.Context, options ...interfaces.Get
 		if err := json.Unmarshal([]byte(result), &message); err != nil {
 			return nil, fmt.Errorf("failed to unmarshal message: %w", err)
 		}
+		if crypto.IsEncrypted(message.Content) {
+			if r.encryptor == nil {
+				return nil, fmt.Errorf("message is encrypted but no encryptor is configured")
+			}
+			content, err := r.encryptor.DecryptString(ctx, message.Content)
+			if err != nil {
+				return nil, fmt.Errorf("failed to decrypt message: %w", err)
+			}
+			message.Content = content
+		}
 		messages = append(messages, message)
 	}
 
@@ -258,9 +386,9 @@ func (r *RedisMemory) GetMessages(ctx context.Context, options ...interfaces.Get
 		messages = filtered
 	}
 
-	// Apply limit if specified
+	// Apply limit if specified, keeping pinned messages
 	if opts.Limit > 0 && opts.Limit < len(messages) {
-		messages = messages[len(messages)-opts.Limit:]
+		messages = trimPreservingPinned(messages, opts.Limit)
 	}
 
 	return messages, nil
@@ -270,24 +398,14 @@ func (r *RedisMemory) GetMessages(ctx context.Context, options ...interfaces.Get
 func (r *RedisMemory) Clear(ctx context.Context) error {
 	// ... implement with improved error handling and multi-tenancy support
 
-	// Get conversation ID from context
-	conversationID, err := getConversationID(ctx)
-	if err != nil {
-		return fmt.Errorf("failed to get conversation ID: %w", err)
-	}
-
-	// Get organization ID from context for multi-tenancy support
-	orgID, err := multitenancy.GetOrgID(ctx)
+	// Create Redis key with org and conversation IDs
+	key, err := r.conversationKey(ctx)
 	if err != nil {
-		// If no organization ID is found, use a default
-		orgID = "default"
+		return err
 	}
 
-	// Create Redis key with org and conversation IDs
-	key := fmt.Sprintf("%s%s:%s", r.keyPrefix, orgID, conversationID)
-
-	// Delete the key from Redis
-	err = r.client.Del(ctx, key).Err()
+	// Delete the key and its sequence number from Redis
+	err = r.client.Del(ctx, key, sequenceKey(key)).Err()
 	if err != nil {
 		return fmt.Errorf("failed to clear memory in Redis: %w", err)
 	}
@@ -297,6 +415,14 @@ func (r *RedisMemory) Clear(ctx context.Context) error {
 
 // ... additional methods for advanced Redis operations ...
 
+// Ping checks that the Redis server is reachable
+func (r *RedisMemory) Ping(ctx context.Context) error {
+	if err := r.client.Ping(ctx).Err(); err != nil {
+		return fmt.Errorf("failed to reach Redis: %w", err)
+	}
+	return nil
+}
+
 // NewRedisMemoryFromConfig creates a new Redis memory from configuration
 func NewRedisMemoryFromConfig(config RedisConfig, options ...RedisOption) (*RedisMemory, error) {
 	// Create Redis client
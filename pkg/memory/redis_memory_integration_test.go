@@ -0,0 +1,246 @@
+//go:build integration
+
+package memory_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/run-bigpig/llm-agent/internal/testsupport"
+	"github.com/run-bigpig/llm-agent/pkg/config"
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+	"github.com/run-bigpig/llm-agent/pkg/memory"
+)
+
+func TestIntegrationRedisMemory(t *testing.T) {
+	client := testsupport.Redis(t)
+	mem := memory.NewRedisMemory(client, memory.WithKeyPrefix("test:"+t.Name()+":"), memory.WithCompression(true))
+	ctx := conversationContext("conv-1")
+
+	if err := mem.AddMessages(ctx, []interfaces.Message{
+		{Role: "user", Content: "Hello"},
+		{Role: "assistant", Content: "Hi there"},
+	}); err != nil {
+		t.Fatalf("failed to add messages: %v", err)
+	}
+
+	messages, err := mem.GetMessages(ctx)
+	if err != nil {
+		t.Fatalf("failed to get messages: %v", err)
+	}
+	if len(messages) != 2 || messages[0].Content != "Hello" || messages[1].Content != "Hi there" {
+		t.Fatalf("unexpected messages: %+v", messages)
+	}
+
+	if other, _ := mem.GetMessages(conversationContext("conv-2")); len(other) != 0 {
+		t.Fatalf("expected conversations to be separate, got %+v", other)
+	}
+
+	if err := mem.Clear(ctx); err != nil {
+		t.Fatalf("failed to clear memory: %v", err)
+	}
+	if messages, _ := mem.GetMessages(ctx); len(messages) != 0 {
+		t.Fatalf("expected no messages after clearing, got %+v", messages)
+	}
+}
+
+func TestIntegrationRedisMemoryConcurrentAdds(t *testing.T) {
+	client := testsupport.Redis(t)
+	mem := memory.NewRedisMemory(client, memory.WithKeyPrefix("test:"+t.Name()+":"))
+	ctx := conversationContext("conv-1")
+
+	const writers, messages = 4, 10
+	var wg sync.WaitGroup
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for m := 0; m < messages; m++ {
+				if err := mem.AddMessage(ctx, interfaces.Message{Role: "user", Content: fmt.Sprintf("%d-%d", w, m)}); err != nil {
+					t.Errorf("failed to add message: %v", err)
+					return
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	got, err := mem.GetMessages(ctx)
+	if err != nil {
+		t.Fatalf("failed to get messages: %v", err)
+	}
+	if len(got) != writers*messages {
+		t.Fatalf("expected %d messages, got %d", writers*messages, len(got))
+	}
+	last, err := mem.LastSequence(ctx)
+	if err != nil || last != writers*messages {
+		t.Fatalf("expected the last sequence to be %d, got %d (%v)", writers*messages, last, err)
+	}
+}
+
+func TestIntegrationRedisSequenceNumbers(t *testing.T) {
+	client := testsupport.Redis(t)
+	mem := memory.NewRedisMemory(client, memory.WithKeyPrefix("test:"+t.Name()+":"))
+	ctx := conversationContext("conv-1")
+
+	const writers, messages = 4, 10
+	var wg sync.WaitGroup
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for m := 0; m < messages; m += 2 {
+				batch := []interfaces.Message{
+					{Role: "user", Content: fmt.Sprintf("%d-%d", w, m)},
+					{Role: "assistant", Content: fmt.Sprintf("%d-%d", w, m+1)},
+				}
+				if err := mem.AddMessages(ctx, batch); err != nil {
+					t.Errorf("failed to add messages: %v", err)
+					return
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	got, err := mem.GetMessages(ctx)
+	if err != nil {
+		t.Fatalf("failed to get messages: %v", err)
+	}
+	for i, message := range got {
+		seq, ok := memory.Sequence(message)
+		if !ok || seq != int64(i+1) {
+			t.Fatalf("expected message %d to have sequence %d, got %d (%v)", i, i+1, seq, ok)
+		}
+		// Batches are stored together, so a user message is followed by its answer
+		if i%2 == 1 {
+			var w, m, prevW, prevM int
+			_, _ = fmt.Sscanf(message.Content, "%d-%d", &w, &m)
+			_, _ = fmt.Sscanf(got[i-1].Content, "%d-%d", &prevW, &prevM)
+			if w != prevW || m != prevM+1 {
+				t.Errorf("expected batch messages to be adjacent, got %q after %q", message.Content, got[i-1].Content)
+			}
+		}
+	}
+}
+
+func TestIntegrationRedisConflictPolicy(t *testing.T) {
+	client := testsupport.Redis(t)
+	ctx := conversationContext("conv-1")
+
+	reject := memory.NewRedisMemory(client, memory.WithKeyPrefix("test:"+t.Name()+":"), memory.WithConflictPolicy(memory.ConflictReject))
+	if err := reject.AddMessage(memory.WithExpectedSequence(ctx, 0), interfaces.Message{Role: "user", Content: "first"}); err != nil {
+		t.Fatalf("failed to add the first message: %v", err)
+	}
+
+	// A writer that hasn't seen the first message is rejected
+	err := reject.AddMessage(memory.WithExpectedSequence(ctx, 0), interfaces.Message{Role: "user", Content: "stale"})
+	if !errors.Is(err, memory.ErrConflict) {
+		t.Fatalf("expected ErrConflict, got %v", err)
+	}
+	if err := reject.AddMessage(memory.WithExpectedSequence(ctx, 1), interfaces.Message{Role: "assistant", Content: "second"}); err != nil {
+		t.Fatalf("failed to add with the current sequence: %v", err)
+	}
+
+	// The merge policy appends stale writes after the others
+	merge := memory.NewRedisMemory(client, memory.WithKeyPrefix("test:"+t.Name()+":"))
+	if err := merge.AddMessage(memory.WithExpectedSequence(ctx, 0), interfaces.Message{Role: "user", Content: "merged"}); err != nil {
+		t.Fatalf("failed to merge a stale write: %v", err)
+	}
+
+	got, err := merge.GetMessages(ctx)
+	if err != nil {
+		t.Fatalf("failed to get messages: %v", err)
+	}
+	if len(got) != 3 || got[0].Content != "first" || got[1].Content != "second" || got[2].Content != "merged" {
+		t.Fatalf("unexpected messages: %+v", got)
+	}
+	if seq, _ := memory.Sequence(got[2]); seq != 3 {
+		t.Errorf("expected the merged message to have sequence 3, got %d", seq)
+	}
+}
+
+func TestIntegrationRedisKeepsTTL(t *testing.T) {
+	client := testsupport.Redis(t)
+	prefix := "test:" + t.Name() + ":"
+	mem := memory.NewRedisMemory(client, memory.WithKeyPrefix(prefix), memory.WithTTL(time.Hour))
+	ctx := conversationContext("conv-1")
+
+	if err := mem.AddMessage(ctx, interfaces.Message{Role: "user", Content: "Hello"}); err != nil {
+		t.Fatalf("failed to add message: %v", err)
+	}
+
+	// The conversation is created with the TTL
+	key := prefix + "org-1:org-1:conv-1"
+	for _, k := range []string{key, key + ":seq"} {
+		if ttl := client.TTL(context.Background(), k).Val(); ttl <= 59*time.Minute || ttl > time.Hour {
+			t.Fatalf("expected %s to expire in an hour, got %v", k, ttl)
+		}
+	}
+
+	// Later messages keep the remaining TTL instead of extending it
+	client.Expire(context.Background(), key+":seq", 10*time.Minute)
+	if err := mem.AddMessage(ctx, interfaces.Message{Role: "assistant", Content: "Hi"}); err != nil {
+		t.Fatalf("failed to add message: %v", err)
+	}
+	if ttl := client.TTL(context.Background(), key+":seq").Val(); ttl <= 0 || ttl > 10*time.Minute {
+		t.Errorf("expected the sequence key to keep its TTL, got %v", ttl)
+	}
+}
+
+func TestIntegrationRedisLimitKeepsPinnedMessages(t *testing.T) {
+	client := testsupport.Redis(t)
+	mem := memory.NewRedisMemory(client, memory.WithKeyPrefix("test:"+t.Name()+":"))
+	ctx := conversationContext("conv-1")
+
+	if err := mem.AddMessages(ctx, []interfaces.Message{
+		memory.PinMessage(interfaces.Message{Role: "system", Content: "fact"}),
+		{Role: "user", Content: "one"},
+		{Role: "assistant", Content: "two"},
+	}); err != nil {
+		t.Fatalf("failed to add messages: %v", err)
+	}
+
+	messages, err := mem.GetMessages(ctx, interfaces.WithLimit(2))
+	if err != nil {
+		t.Fatalf("failed to get messages: %v", err)
+	}
+	if len(messages) != 2 || messages[0].Content != "fact" || !memory.IsPinned(messages[0]) || messages[1].Content != "two" {
+		t.Fatalf("expected the pinned message and the newest message, got %+v", messages)
+	}
+}
+
+func TestIntegrationRedisMemoryFromConfig(t *testing.T) {
+	client := testsupport.Redis(t)
+
+	cfg := &config.Config{}
+	cfg.Memory.Type = "redis"
+	cfg.Memory.Redis.URL = client.Options().Addr
+	mem, err := memory.FromConfig(cfg)
+	if err != nil {
+		t.Fatalf("FromConfig failed: %v", err)
+	}
+	redisMemory, ok := mem.(*memory.RedisMemory)
+	if !ok {
+		t.Fatalf("expected Redis memory, got %T", mem)
+	}
+	defer redisMemory.Close()
+
+	ctx := conversationContext("conv-" + t.Name())
+	if err := mem.AddMessage(ctx, interfaces.Message{Role: "user", Content: "Hello"}); err != nil {
+		t.Fatalf("failed to add message: %v", err)
+	}
+	defer mem.Clear(ctx)
+	if messages, err := mem.GetMessages(ctx); err != nil || len(messages) != 1 {
+		t.Fatalf("expected the stored message, got %+v, %v", messages, err)
+	}
+
+	cfg.Memory.Redis.URL = "127.0.0.1:1"
+	if _, err := memory.FromConfig(cfg); err == nil {
+		t.Error("expected an error for an unreachable server")
+	}
+}
@@ -0,0 +1,320 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+	"github.com/run-bigpig/llm-agent/pkg/multitenancy"
+	"github.com/run-bigpig/llm-agent/pkg/runctx"
+)
+
+// CachedAnswer is an answer returned from the semantic cache
+type CachedAnswer struct {
+	// Query is the cached query the answer was given to
+	Query string `json:"query"`
+
+	// Answer is the cached answer
+	Answer string `json:"answer"`
+
+	// Similarity is the cosine similarity of the cached query to the new one
+	Similarity float64 `json:"similarity"`
+
+	// CreatedAt is when the answer was stored
+	CreatedAt time.Time `json:"created_at"`
+
+	// ExpiresAt is when the answer becomes stale
+	ExpiresAt time.Time `json:"expires_at"`
+
+	// Tools lists the tools called to produce the answer
+	Tools []string `json:"tools,omitempty"`
+}
+
+// SemanticCacheStats counts semantic cache lookups
+type SemanticCacheStats struct {
+	// Hits is the number of lookups that returned a cached answer
+	Hits int64 `json:"hits"`
+
+	// Misses is the number of lookups that found no fresh, similar answer
+	Misses int64 `json:"misses"`
+
+	// Entries is the number of answers currently stored
+	Entries int `json:"entries"`
+}
+
+// SemanticCacheScope decides which requests share cached answers
+type SemanticCacheScope int
+
+const (
+	// SemanticCacheUserScope shares answers between the requests of one user
+	// of an organization. It is the default.
+	SemanticCacheUserScope SemanticCacheScope = iota
+
+	// SemanticCacheOrgScope shares answers between all users of an
+	// organization. Only use it when answers don't depend on who asks.
+	SemanticCacheOrgScope
+)
+
+// SemanticCache stores (query, answer) pairs per user and returns the
+// answer of a previous query that is similar enough to a new one, so that
+// agents answering the same questions over and over, like FAQ bots, don't
+// call the LLM for each of them. Queries are compared by the cosine
+// similarity of their embeddings.
+type SemanticCache struct {
+	embedder   interfaces.Embedder
+	threshold  float64
+	ttl        time.Duration
+	maxEntries int
+	scope      SemanticCacheScope
+	now        func() time.Time
+
+	mu      sync.Mutex
+	entries map[string][]semanticEntry
+	pending map[string][]float32
+	hits    int64
+	misses  int64
+}
+
+// semanticEntry is a stored (query, answer) pair
+type semanticEntry struct {
+	query     string
+	answer    string
+	vector    []float32
+	tools     []string
+	createdAt time.Time
+	expiresAt time.Time
+}
+
+// SemanticCacheOption represents an option for configuring the semantic cache
+type SemanticCacheOption func(*SemanticCache)
+
+// WithSemanticCacheThreshold sets the cosine similarity at or above which a
+// cached answer is returned. Defaults to 0.95; lower values return more
+// cached answers at the risk of answering a different question.
+func WithSemanticCacheThreshold(threshold float64) SemanticCacheOption {
+	return func(c *SemanticCache) {
+		c.threshold = threshold
+	}
+}
+
+// WithSemanticCacheTTL sets how long answers stay fresh. Defaults to 24 hours.
+func WithSemanticCacheTTL(ttl time.Duration) SemanticCacheOption {
+	return func(c *SemanticCache) {
+		c.ttl = ttl
+	}
+}
+
+// WithSemanticCacheScope sets which requests share cached answers. Defaults
+// to SemanticCacheUserScope.
+func WithSemanticCacheScope(scope SemanticCacheScope) SemanticCacheOption {
+	return func(c *SemanticCache) {
+		c.scope = scope
+	}
+}
+
+// WithMaxSemanticCacheEntries caps the number of answers kept per scope.
+// Defaults to 1000.
+func WithMaxSemanticCacheEntries(max int) SemanticCacheOption {
+	return func(c *SemanticCache) {
+		c.maxEntries = max
+	}
+}
+
+// NewSemanticCache creates a new in-memory semantic cache that embeds queries
+// with the embedder
+func NewSemanticCache(embedder interfaces.Embedder, options ...SemanticCacheOption) *SemanticCache {
+	cache := &SemanticCache{
+		embedder:   embedder,
+		threshold:  0.95,
+		ttl:        24 * time.Hour,
+		maxEntries: 1000,
+		now:        time.Now,
+		entries:    make(map[string][]semanticEntry),
+		pending:    make(map[string][]float32),
+	}
+
+	for _, option := range options {
+		option(cache)
+	}
+
+	return cache
+}
+
+// Lookup returns the fresh answer whose query is most similar to the query,
+// if the similarity reaches the threshold. The embedding of a missed query is
+// kept so that storing its answer does not embed it again.
+func (c *SemanticCache) Lookup(ctx context.Context, query string) (CachedAnswer, bool, error) {
+	vector, err := c.embedder.Embed(ctx, query)
+	if err != nil {
+		return CachedAnswer{}, false, fmt.Errorf("failed to embed query: %w", err)
+	}
+	scope := c.scopeKey(ctx)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	var best CachedAnswer
+	found := false
+	for _, entry := range c.entries[scope] {
+		if !now.Before(entry.expiresAt) {
+			continue
+		}
+		similarity := cosineSimilarity(vector, entry.vector)
+		if similarity >= c.threshold && (!found || similarity > best.Similarity) {
+			best = CachedAnswer{
+				Query:      entry.query,
+				Answer:     entry.answer,
+				Similarity: similarity,
+				CreatedAt:  entry.createdAt,
+				ExpiresAt:  entry.expiresAt,
+				Tools:      slices.Clone(entry.tools),
+			}
+			found = true
+		}
+	}
+
+	if !found {
+		c.misses++
+		if len(c.pending) >= c.maxEntries {
+			c.pending = make(map[string][]float32)
+		}
+		c.pending[pendingKey(scope, query)] = vector
+		return CachedAnswer{}, false, nil
+	}
+	c.hits++
+	return best, true, nil
+}
+
+// Store stores the answer to a query, along with the tools called to produce
+// it, so that callers who may not use those tools can skip the answer
+func (c *SemanticCache) Store(ctx context.Context, query, answer string, tools ...string) error {
+	if c.ttl <= 0 || answer == "" {
+		return nil
+	}
+	scope := c.scopeKey(ctx)
+	key := pendingKey(scope, query)
+
+	c.mu.Lock()
+	vector, ok := c.pending[key]
+	delete(c.pending, key)
+	c.mu.Unlock()
+
+	if !ok {
+		var err error
+		vector, err = c.embedder.Embed(ctx, query)
+		if err != nil {
+			return fmt.Errorf("failed to embed query: %w", err)
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	c.entries[scope] = append(c.entries[scope], semanticEntry{
+		query:     query,
+		answer:    answer,
+		vector:    vector,
+		tools:     slices.Compact(slices.Sorted(slices.Values(tools))),
+		createdAt: now,
+		expiresAt: now.Add(c.ttl),
+	})
+	c.evict(scope)
+	return nil
+}
+
+// Clear removes the answers stored for the organization in the context and
+// all of its users, e.g. after the documents the answers were based on changed
+func (c *SemanticCache) Clear(ctx context.Context) {
+	org := orgScopeKey(ctx)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for scope := range c.entries {
+		if scope == org || strings.HasPrefix(scope, org+":") {
+			delete(c.entries, scope)
+		}
+	}
+}
+
+// Stats returns the lookup counts and the number of stored answers
+func (c *SemanticCache) Stats() SemanticCacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := SemanticCacheStats{Hits: c.hits, Misses: c.misses}
+	for _, entries := range c.entries {
+		stats.Entries += len(entries)
+	}
+	return stats
+}
+
+// evict drops expired answers of the scope and then the oldest answers over
+// the limit. It must be called with the lock held.
+func (c *SemanticCache) evict(scope string) {
+	now := c.now()
+	entries := c.entries[scope][:0]
+	for _, entry := range c.entries[scope] {
+		if now.Before(entry.expiresAt) {
+			entries = append(entries, entry)
+		}
+	}
+	if c.maxEntries > 0 && len(entries) > c.maxEntries {
+		// Entries are appended in creation order, so the oldest come first
+		entries = append(entries[:0], entries[len(entries)-c.maxEntries:]...)
+	}
+	c.entries[scope] = entries
+}
+
+// scopeKey keys the answers cached for the context: its organization, and
+// its user unless answers are shared across the organization. Requests
+// without a user share the answers of the organization.
+func (c *SemanticCache) scopeKey(ctx context.Context) string {
+	org := orgScopeKey(ctx)
+	if c.scope == SemanticCacheOrgScope {
+		return org
+	}
+	if userID := runctx.UserID(ctx); userID != "" {
+		return org + ":" + escapeKeyPart(userID)
+	}
+	return org
+}
+
+// orgScopeKey keys the answers of the organization in the context. Without an
+// organization all answers share one scope.
+func orgScopeKey(ctx context.Context) string {
+	orgID, err := multitenancy.GetOrgID(ctx)
+	if err != nil {
+		return ""
+	}
+	return escapeKeyPart(orgID)
+}
+
+// pendingKey keys the embedding of a missed query
+func pendingKey(scope, query string) string {
+	return scope + "\x00" + query
+}
+
+// cosineSimilarity returns the cosine similarity of two vectors, or 0 if their
+// lengths differ or either is zero
+func cosineSimilarity(a, b []float32) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
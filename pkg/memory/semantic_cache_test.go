@@ -0,0 +1,167 @@
+package memory_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+	"github.com/run-bigpig/llm-agent/pkg/memory"
+	"github.com/run-bigpig/llm-agent/pkg/multitenancy"
+	"github.com/run-bigpig/llm-agent/pkg/runctx"
+)
+
+// fixedEmbedder returns fixed vectors for known texts and counts the calls
+type fixedEmbedder struct {
+	interfaces.Embedder
+	vectors map[string][]float32
+	calls   int
+}
+
+func (e *fixedEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	e.calls++
+	if vector, ok := e.vectors[text]; ok {
+		return vector, nil
+	}
+	return []float32{0, 0, 1}, nil
+}
+
+func newFixedEmbedder() *fixedEmbedder {
+	return &fixedEmbedder{vectors: map[string][]float32{
+		"What are your opening hours?": {1, 0, 0},
+		"When are you open?":           {0.99, 0.1, 0},
+		"How do I reset my password?":  {0, 1, 0},
+	}}
+}
+
+func TestSemanticCacheReturnsSimilarAnswer(t *testing.T) {
+	embedder := newFixedEmbedder()
+	cache := memory.NewSemanticCache(embedder)
+	ctx := multitenancy.WithOrgID(context.Background(), "org-1")
+
+	if _, ok, err := cache.Lookup(ctx, "What are your opening hours?"); err != nil || ok {
+		t.Fatalf("expected a miss on an empty cache, got ok=%v err=%v", ok, err)
+	}
+	if err := cache.Store(ctx, "What are your opening hours?", "9 to 5"); err != nil {
+		t.Fatalf("failed to store answer: %v", err)
+	}
+	if embedder.calls != 1 {
+		t.Fatalf("expected the missed query's embedding to be reused, got %d embed calls", embedder.calls)
+	}
+
+	answer, ok, err := cache.Lookup(ctx, "When are you open?")
+	if err != nil || !ok {
+		t.Fatalf("expected a hit for a similar query, got ok=%v err=%v", ok, err)
+	}
+	if answer.Answer != "9 to 5" || answer.Query != "What are your opening hours?" {
+		t.Fatalf("unexpected cached answer: %+v", answer)
+	}
+	if answer.Similarity < 0.95 {
+		t.Fatalf("expected a similarity above the threshold, got %f", answer.Similarity)
+	}
+
+	if _, ok, _ := cache.Lookup(ctx, "How do I reset my password?"); ok {
+		t.Fatal("expected a miss for a different question")
+	}
+
+	stats := cache.Stats()
+	if stats.Hits != 1 || stats.Misses != 2 || stats.Entries != 1 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+}
+
+func TestSemanticCacheThreshold(t *testing.T) {
+	cache := memory.NewSemanticCache(newFixedEmbedder(), memory.WithSemanticCacheThreshold(0.999))
+	ctx := context.Background()
+
+	if err := cache.Store(ctx, "What are your opening hours?", "9 to 5"); err != nil {
+		t.Fatalf("failed to store answer: %v", err)
+	}
+	if _, ok, _ := cache.Lookup(ctx, "When are you open?"); ok {
+		t.Fatal("expected a miss below the threshold")
+	}
+	if _, ok, _ := cache.Lookup(ctx, "What are your opening hours?"); !ok {
+		t.Fatal("expected a hit for the same query")
+	}
+}
+
+func TestSemanticCacheExpiresAnswers(t *testing.T) {
+	cache := memory.NewSemanticCache(newFixedEmbedder(), memory.WithSemanticCacheTTL(20*time.Millisecond))
+	ctx := context.Background()
+
+	if err := cache.Store(ctx, "What are your opening hours?", "9 to 5"); err != nil {
+		t.Fatalf("failed to store answer: %v", err)
+	}
+	time.Sleep(40 * time.Millisecond)
+	if _, ok, _ := cache.Lookup(ctx, "What are your opening hours?"); ok {
+		t.Fatal("expected a stale answer to be skipped")
+	}
+}
+
+func TestSemanticCacheScopesByOrganization(t *testing.T) {
+	cache := memory.NewSemanticCache(newFixedEmbedder())
+	org1 := multitenancy.WithOrgID(context.Background(), "org-1")
+	org2 := multitenancy.WithOrgID(context.Background(), "org-2")
+
+	if err := cache.Store(org1, "What are your opening hours?", "9 to 5"); err != nil {
+		t.Fatalf("failed to store answer: %v", err)
+	}
+	if _, ok, _ := cache.Lookup(org2, "What are your opening hours?"); ok {
+		t.Fatal("expected answers of one organization to be hidden from another")
+	}
+
+	cache.Clear(org1)
+	if _, ok, _ := cache.Lookup(org1, "What are your opening hours?"); ok {
+		t.Fatal("expected Clear to remove the organization's answers")
+	}
+}
+
+func TestSemanticCacheScopesByUser(t *testing.T) {
+	org := multitenancy.WithOrgID(context.Background(), "org-1")
+	alice := runctx.WithUserID(org, "alice")
+	bob := runctx.WithUserID(org, "bob")
+
+	cache := memory.NewSemanticCache(newFixedEmbedder())
+	if err := cache.Store(alice, "What are your opening hours?", "9 to 5", "crm_lookup", "crm_lookup"); err != nil {
+		t.Fatalf("failed to store answer: %v", err)
+	}
+	if _, ok, _ := cache.Lookup(bob, "What are your opening hours?"); ok {
+		t.Fatal("expected answers of one user to be hidden from another by default")
+	}
+	cached, ok, _ := cache.Lookup(alice, "What are your opening hours?")
+	if !ok || len(cached.Tools) != 1 || cached.Tools[0] != "crm_lookup" {
+		t.Fatalf("expected alice's answer with its tools, got %+v", cached)
+	}
+
+	// Clearing the organization drops the answers of all its users
+	cache.Clear(org)
+	if _, ok, _ := cache.Lookup(alice, "What are your opening hours?"); ok {
+		t.Fatal("expected Clear to remove the answers of the organization's users")
+	}
+
+	shared := memory.NewSemanticCache(newFixedEmbedder(), memory.WithSemanticCacheScope(memory.SemanticCacheOrgScope))
+	if err := shared.Store(alice, "What are your opening hours?", "9 to 5"); err != nil {
+		t.Fatalf("failed to store answer: %v", err)
+	}
+	if _, ok, _ := shared.Lookup(bob, "What are your opening hours?"); !ok {
+		t.Fatal("expected answers to be shared across the organization")
+	}
+}
+
+func TestSemanticCacheCapsEntries(t *testing.T) {
+	cache := memory.NewSemanticCache(newFixedEmbedder(), memory.WithMaxSemanticCacheEntries(1))
+	ctx := context.Background()
+
+	if err := cache.Store(ctx, "What are your opening hours?", "9 to 5"); err != nil {
+		t.Fatalf("failed to store answer: %v", err)
+	}
+	if err := cache.Store(ctx, "How do I reset my password?", "Use the reset link"); err != nil {
+		t.Fatalf("failed to store answer: %v", err)
+	}
+	if _, ok, _ := cache.Lookup(ctx, "What are your opening hours?"); ok {
+		t.Fatal("expected the oldest answer to be evicted")
+	}
+	if answer, ok, _ := cache.Lookup(ctx, "How do I reset my password?"); !ok || answer.Answer != "Use the reset link" {
+		t.Fatalf("expected the newest answer to be kept, got %+v", answer)
+	}
+}
@@ -0,0 +1,77 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+)
+
+// SequenceKey is the metadata key of a message's sequence number. Memories
+// that support sequence numbers number the messages of a conversation 1, 2,
+// 3... in the order they were stored.
+const SequenceKey = "seq"
+
+// ErrConflict is returned when a message is added with an expected sequence
+// number that is no longer the last one in the conversation, and the conflict
+// policy is ConflictReject. HTTP handlers should answer it with 409 Conflict.
+var ErrConflict = errors.New("conversation has changed")
+
+// ConflictPolicy decides what happens when a message is added with a stale
+// expected sequence number
+type ConflictPolicy string
+
+const (
+	// ConflictMerge appends the message after the messages added in the
+	// meantime
+	ConflictMerge ConflictPolicy = "merge"
+
+	// ConflictReject rejects the message with ErrConflict
+	ConflictReject ConflictPolicy = "reject"
+)
+
+// expectedSequenceKey is the context key for the expected sequence number
+const expectedSequenceKey contextKey = "expected_sequence"
+
+// WithExpectedSequence returns a context that adds messages only if the
+// conversation's last sequence number is still seq, i.e. the caller has seen
+// the whole conversation. Use 0 for a conversation the caller expects to be empty.
+func WithExpectedSequence(ctx context.Context, seq int64) context.Context {
+	return context.WithValue(ctx, expectedSequenceKey, seq)
+}
+
+// ExpectedSequence returns the expected sequence number from the context, if
+// there is one
+func ExpectedSequence(ctx context.Context) (int64, bool) {
+	seq, ok := ctx.Value(expectedSequenceKey).(int64)
+	return seq, ok
+}
+
+// Sequence returns the sequence number of a message, if it has one
+func Sequence(message interfaces.Message) (int64, bool) {
+	switch seq := message.Metadata[SequenceKey].(type) {
+	case int64:
+		return seq, true
+	case int:
+		return int64(seq), true
+	case float64:
+		// Numbers in decoded JSON metadata
+		return int64(seq), true
+	case json.Number:
+		n, err := seq.Int64()
+		return n, err == nil
+	}
+	return 0, false
+}
+
+// withSequence returns a copy of the message with the given sequence number
+func withSequence(message interfaces.Message, seq int64) interfaces.Message {
+	metadata := make(map[string]interface{}, len(message.Metadata)+1)
+	for k, v := range message.Metadata {
+		metadata[k] = v
+	}
+	metadata[SequenceKey] = seq
+	message.Metadata = metadata
+	return message
+}
@@ -0,0 +1,51 @@
+package memory_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+	"github.com/run-bigpig/llm-agent/pkg/memory"
+)
+
+func TestSequence(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    interface{}
+		expected int64
+		ok       bool
+	}{
+		{"int64", int64(7), 7, true},
+		{"int", 7, 7, true},
+		{"decoded JSON", float64(7), 7, true},
+		{"json.Number", json.Number("7"), 7, true},
+		{"invalid json.Number", json.Number("seven"), 0, false},
+		{"string", "7", 0, false},
+		{"missing", nil, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			message := interfaces.Message{Role: "user", Metadata: map[string]interface{}{}}
+			if tt.value != nil {
+				message.Metadata[memory.SequenceKey] = tt.value
+			}
+			seq, ok := memory.Sequence(message)
+			if seq != tt.expected || ok != tt.ok {
+				t.Errorf("expected (%d, %v), got (%d, %v)", tt.expected, tt.ok, seq, ok)
+			}
+		})
+	}
+}
+
+func TestExpectedSequence(t *testing.T) {
+	if _, ok := memory.ExpectedSequence(context.Background()); ok {
+		t.Error("expected no sequence in a plain context")
+	}
+
+	// Zero is a valid expectation: the conversation is empty
+	seq, ok := memory.ExpectedSequence(memory.WithExpectedSequence(context.Background(), 0))
+	if !ok || seq != 0 {
+		t.Errorf("expected sequence 0, got (%d, %v)", seq, ok)
+	}
+}
@@ -0,0 +1,281 @@
+package memory
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+)
+
+// ToolResult is a stored tool result
+type ToolResult struct {
+	// ToolName is the name of the tool that produced the result
+	ToolName string `json:"tool_name"`
+
+	// Args are the arguments the tool was called with
+	Args string `json:"args"`
+
+	// Output is the tool output
+	Output string `json:"output"`
+
+	// CreatedAt is when the result was stored
+	CreatedAt time.Time `json:"created_at"`
+
+	// ExpiresAt is when the result becomes stale
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// ToolResultStore stores tool results per conversation so that follow-up
+// questions can reuse them without invoking the tool again
+type ToolResultStore struct {
+	defaultTTL time.Duration
+	toolTTLs   map[string]time.Duration
+	maxEntries int
+	now        func() time.Time
+
+	mu      sync.Mutex
+	results map[string]map[string]ToolResult
+}
+
+// ToolResultOption represents an option for configuring the tool result store
+type ToolResultOption func(*ToolResultStore)
+
+// WithDefaultToolResultTTL sets how long results stay fresh unless a tool has its own TTL
+func WithDefaultToolResultTTL(ttl time.Duration) ToolResultOption {
+	return func(s *ToolResultStore) {
+		s.defaultTTL = ttl
+	}
+}
+
+// WithToolResultTTL sets how long results of the named tool stay fresh. A
+// zero TTL disables storing results of the tool, e.g. for tools with side
+// effects.
+func WithToolResultTTL(toolName string, ttl time.Duration) ToolResultOption {
+	return func(s *ToolResultStore) {
+		s.toolTTLs[toolName] = ttl
+	}
+}
+
+// WithMaxToolResults caps the number of results kept per conversation
+func WithMaxToolResults(max int) ToolResultOption {
+	return func(s *ToolResultStore) {
+		s.maxEntries = max
+	}
+}
+
+// NewToolResultStore creates a new in-memory tool result store
+func NewToolResultStore(options ...ToolResultOption) *ToolResultStore {
+	store := &ToolResultStore{
+		defaultTTL: 10 * time.Minute,
+		toolTTLs:   make(map[string]time.Duration),
+		maxEntries: 100,
+		now:        time.Now,
+		results:    make(map[string]map[string]ToolResult),
+	}
+
+	for _, option := range options {
+		option(store)
+	}
+
+	return store
+}
+
+// Get returns the fresh result of a previous call with the same arguments in
+// the conversation
+func (s *ToolResultStore) Get(ctx context.Context, toolName, args string) (ToolResult, bool) {
+	conversationID, err := getConversationID(ctx)
+	if err != nil {
+		return ToolResult{}, false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result, ok := s.results[conversationID][toolResultKey(toolName, args)]
+	if !ok || !s.now().Before(result.ExpiresAt) {
+		return ToolResult{}, false
+	}
+	return result, true
+}
+
+// Put stores a tool result in the conversation
+func (s *ToolResultStore) Put(ctx context.Context, toolName, args, output string) {
+	ttl := s.ttl(toolName)
+	if ttl <= 0 {
+		return
+	}
+
+	conversationID, err := getConversationID(ctx)
+	if err != nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	results, ok := s.results[conversationID]
+	if !ok {
+		results = make(map[string]ToolResult)
+		s.results[conversationID] = results
+	}
+
+	now := s.now()
+	results[toolResultKey(toolName, args)] = ToolResult{
+		ToolName:  toolName,
+		Args:      args,
+		Output:    output,
+		CreatedAt: now,
+		ExpiresAt: now.Add(ttl),
+	}
+
+	s.evict(results)
+}
+
+// List returns the fresh results stored in the conversation, oldest first
+func (s *ToolResultStore) List(ctx context.Context) []ToolResult {
+	conversationID, err := getConversationID(ctx)
+	if err != nil {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	var list []ToolResult
+	for _, result := range s.results[conversationID] {
+		if now.Before(result.ExpiresAt) {
+			list = append(list, result)
+		}
+	}
+	sortToolResults(list)
+	return list
+}
+
+// Clear removes the stored results of the conversation
+func (s *ToolResultStore) Clear(ctx context.Context) error {
+	conversationID, err := getConversationID(ctx)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.results, conversationID)
+	return nil
+}
+
+// Wrap returns a tool that reuses stored results for identical calls in the
+// same conversation and stores new results
+func (s *ToolResultStore) Wrap(tool interfaces.Tool) interfaces.Tool {
+	return &cachedTool{tool: tool, store: s}
+}
+
+// ttl returns the TTL for the tool
+func (s *ToolResultStore) ttl(toolName string) time.Duration {
+	if ttl, ok := s.toolTTLs[toolName]; ok {
+		return ttl
+	}
+	return s.defaultTTL
+}
+
+// evict drops expired results and then the oldest results over the limit.
+// It must be called with the lock held.
+func (s *ToolResultStore) evict(results map[string]ToolResult) {
+	now := s.now()
+	for key, result := range results {
+		if !now.Before(result.ExpiresAt) {
+			delete(results, key)
+		}
+	}
+
+	for s.maxEntries > 0 && len(results) > s.maxEntries {
+		var oldestKey string
+		var oldest time.Time
+		for key, result := range results {
+			if oldestKey == "" || result.CreatedAt.Before(oldest) {
+				oldestKey, oldest = key, result.CreatedAt
+			}
+		}
+		delete(results, oldestKey)
+	}
+}
+
+// toolResultKey hashes the tool name and arguments. JSON arguments are
+// normalized so that key order and whitespace do not matter.
+func toolResultKey(toolName, args string) string {
+	normalized := args
+	var value interface{}
+	if err := json.Unmarshal([]byte(args), &value); err == nil {
+		if data, err := json.Marshal(value); err == nil {
+			normalized = string(data)
+		}
+	}
+
+	sum := sha256.Sum256([]byte(toolName + "\x00" + normalized))
+	return hex.EncodeToString(sum[:])
+}
+
+// sortToolResults sorts results by creation time
+func sortToolResults(results []ToolResult) {
+	for i := 1; i < len(results); i++ {
+		for j := i; j > 0 && results[j].CreatedAt.Before(results[j-1].CreatedAt); j-- {
+			results[j], results[j-1] = results[j-1], results[j]
+		}
+	}
+}
+
+// cachedTool reuses stored results for identical calls
+type cachedTool struct {
+	tool  interfaces.Tool
+	store *ToolResultStore
+}
+
+// Name returns the name of the tool
+func (t *cachedTool) Name() string {
+	return t.tool.Name()
+}
+
+// Description returns a description of what the tool does
+func (t *cachedTool) Description() string {
+	return t.tool.Description()
+}
+
+// Parameters returns the parameters that the tool accepts
+func (t *cachedTool) Parameters() map[string]interfaces.ParameterSpec {
+	return t.tool.Parameters()
+}
+
+// Examples returns the example invocations of the wrapped tool
+func (t *cachedTool) Examples() []interfaces.ToolExample {
+	return interfaces.ToolExamples(t.tool)
+}
+
+// Run executes the tool with the given input
+func (t *cachedTool) Run(ctx context.Context, input string) (string, error) {
+	return t.call(ctx, input, t.tool.Run)
+}
+
+// Execute executes the tool with the given arguments
+func (t *cachedTool) Execute(ctx context.Context, args string) (string, error) {
+	return t.call(ctx, args, t.tool.Execute)
+}
+
+// call returns a stored result or calls the tool and stores its result
+func (t *cachedTool) call(ctx context.Context, args string, fn func(context.Context, string) (string, error)) (string, error) {
+	if result, ok := t.store.Get(ctx, t.tool.Name(), args); ok {
+		return result.Output, nil
+	}
+
+	output, err := fn(ctx, args)
+	if err != nil {
+		return "", err
+	}
+
+	t.store.Put(ctx, t.tool.Name(), args, output)
+	return output, nil
+}
@@ -0,0 +1,149 @@
+package memory_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+	"github.com/run-bigpig/llm-agent/pkg/memory"
+)
+
+// countingTool counts its calls and returns numbered results
+type countingTool struct {
+	interfaces.Tool
+	name  string
+	calls int
+	err   error
+}
+
+func (t *countingTool) Name() string { return t.name }
+
+func (t *countingTool) Execute(ctx context.Context, args string) (string, error) {
+	t.calls++
+	if t.err != nil {
+		return "", t.err
+	}
+	return fmt.Sprintf("result %d", t.calls), nil
+}
+
+func TestToolResultStoreWrap(t *testing.T) {
+	store := memory.NewToolResultStore()
+	tool := &countingTool{name: "weather"}
+	wrapped := store.Wrap(tool)
+	ctx := conversationContext("conv")
+
+	first, err := wrapped.Execute(ctx, `{"city":"Paris","units":"metric"}`)
+	if err != nil {
+		t.Fatalf("failed to execute tool: %v", err)
+	}
+
+	// Key order and whitespace of JSON arguments don't matter
+	second, err := wrapped.Execute(ctx, `{ "units": "metric", "city": "Paris" }`)
+	if err != nil {
+		t.Fatalf("failed to execute tool: %v", err)
+	}
+	if first != "result 1" || second != "result 1" || tool.calls != 1 {
+		t.Errorf("expected the stored result to be reused, got %q, %q after %d calls", first, second, tool.calls)
+	}
+
+	// Different arguments and other conversations call the tool
+	if result, _ := wrapped.Execute(ctx, `{"city":"Oslo"}`); result != "result 2" {
+		t.Errorf("expected a new call for other arguments, got %q", result)
+	}
+	if result, _ := wrapped.Execute(conversationContext("other"), `{"city":"Paris","units":"metric"}`); result != "result 3" {
+		t.Errorf("expected a new call in another conversation, got %q", result)
+	}
+
+	results := store.List(ctx)
+	if len(results) != 2 || results[0].Args != `{"city":"Paris","units":"metric"}` || results[1].Output != "result 2" {
+		t.Errorf("unexpected results: %+v", results)
+	}
+
+	if err := store.Clear(ctx); err != nil {
+		t.Fatalf("failed to clear results: %v", err)
+	}
+	if _, ok := store.Get(ctx, "weather", `{"city":"Oslo"}`); ok {
+		t.Error("expected Clear to remove the results")
+	}
+	if _, ok := store.Get(conversationContext("other"), "weather", `{"city":"Paris","units":"metric"}`); !ok {
+		t.Error("expected Clear to keep the results of other conversations")
+	}
+}
+
+func TestToolResultStoreErrorsAreNotStored(t *testing.T) {
+	store := memory.NewToolResultStore()
+	tool := &countingTool{name: "weather", err: errors.New("unavailable")}
+	wrapped := store.Wrap(tool)
+	ctx := conversationContext("conv")
+
+	for i := 0; i < 2; i++ {
+		if _, err := wrapped.Execute(ctx, "{}"); err == nil {
+			t.Fatal("expected the tool error")
+		}
+	}
+	if tool.calls != 2 || len(store.List(ctx)) != 0 {
+		t.Errorf("expected failed calls not to be stored, got %d calls and %+v", tool.calls, store.List(ctx))
+	}
+}
+
+func TestToolResultStoreTTL(t *testing.T) {
+	store := memory.NewToolResultStore(
+		memory.WithDefaultToolResultTTL(time.Hour),
+		memory.WithToolResultTTL("stock_price", 20*time.Millisecond),
+		memory.WithToolResultTTL("send_email", 0),
+	)
+	ctx := conversationContext("conv")
+
+	store.Put(ctx, "stock_price", "ACME", "42")
+	store.Put(ctx, "send_email", "ops", "sent")
+	store.Put(ctx, "weather", "Paris", "sunny")
+
+	if _, ok := store.Get(ctx, "send_email", "ops"); ok {
+		t.Error("expected results of tools with a zero TTL not to be stored")
+	}
+	result, ok := store.Get(ctx, "stock_price", "ACME")
+	if !ok || result.Output != "42" || result.ExpiresAt.Sub(result.CreatedAt) != 20*time.Millisecond {
+		t.Errorf("unexpected result: %+v", result)
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	if _, ok := store.Get(ctx, "stock_price", "ACME"); ok {
+		t.Error("expected the result to expire")
+	}
+	if _, ok := store.Get(ctx, "weather", "Paris"); !ok {
+		t.Error("expected results with the default TTL to stay fresh")
+	}
+	if results := store.List(ctx); len(results) != 1 || results[0].ToolName != "weather" {
+		t.Errorf("expected List to skip expired results, got %+v", results)
+	}
+}
+
+func TestToolResultStoreMaxResults(t *testing.T) {
+	store := memory.NewToolResultStore(memory.WithMaxToolResults(2))
+	ctx := conversationContext("conv")
+
+	for _, city := range []string{"Paris", "Oslo", "Rome"} {
+		store.Put(ctx, "weather", city, city)
+		time.Sleep(time.Millisecond)
+	}
+
+	results := store.List(ctx)
+	if len(results) != 2 || results[0].Args != "Oslo" || results[1].Args != "Rome" {
+		t.Errorf("expected the oldest result to be evicted, got %+v", results)
+	}
+}
+
+func TestToolResultStoreWithoutConversation(t *testing.T) {
+	store := memory.NewToolResultStore()
+	store.Put(context.Background(), "weather", "Paris", "sunny")
+
+	if _, ok := store.Get(context.Background(), "weather", "Paris"); ok {
+		t.Error("expected results outside of a conversation not to be stored")
+	}
+	if err := store.Clear(context.Background()); err == nil {
+		t.Error("expected Clear to fail outside of a conversation")
+	}
+}
@@ -3,6 +3,7 @@ package memory
 import (
 	"context"
 	"fmt"
+	"strings"
 	"sync"
 
 	"github.com/run-bigpig/llm-agent/pkg/interfaces"
@@ -12,12 +13,28 @@ import (
 type VectorStoreRetriever struct {
 	buffer      *ConversationBuffer
 	vectorStore interfaces.VectorStore
+	condenser   interfaces.LLM
+	turns       int
 	mu          sync.RWMutex
 }
 
 // RetrieverOption represents an option for configuring the vector store retriever
 type RetrieverOption func(*VectorStoreRetriever)
 
+// WithConversationContext condenses the last turns of the conversation and
+// the query into a standalone query with the LLM before searching, so that
+// follow-ups like "what about the second one?" find the right messages.
+// Queries without earlier turns are searched as they are.
+func WithConversationContext(llm interfaces.LLM, turns int) RetrieverOption {
+	return func(v *VectorStoreRetriever) {
+		if turns <= 0 {
+			turns = 6
+		}
+		v.condenser = llm
+		v.turns = turns
+	}
+}
+
 // NewVectorStoreRetriever creates a new vector store retriever memory
 func NewVectorStoreRetriever(vectorStore interfaces.VectorStore, options ...RetrieverOption) *VectorStoreRetriever {
 	retriever := &VectorStoreRetriever{
@@ -75,8 +92,16 @@ func (v *VectorStoreRetriever) GetMessages(ctx context.Context, options ...inter
 		return v.buffer.GetMessages(ctx, options...)
 	}
 
+	query := opts.Query
+	if v.condenser != nil {
+		var err error
+		if query, err = v.condenseQuery(ctx, query); err != nil {
+			return nil, err
+		}
+	}
+
 	// Search for relevant messages in vector store
-	results, err := v.vectorStore.Search(ctx, opts.Query, opts.Limit)
+	results, err := v.vectorStore.Search(ctx, query, opts.Limit)
 	if err != nil {
 		return nil, fmt.Errorf("failed to search vector store: %w", err)
 	}
@@ -100,6 +125,47 @@ func (v *VectorStoreRetriever) GetMessages(ctx context.Context, options ...inter
 	return messages, nil
 }
 
+// condenseQuery rewrites a follow-up query into a standalone query using the
+// last turns of the conversation
+func (v *VectorStoreRetriever) condenseQuery(ctx context.Context, query string) (string, error) {
+	history, err := v.buffer.GetMessages(ctx, interfaces.WithRoles("user", "assistant"), interfaces.WithLimit(v.turns+1))
+	if err != nil {
+		return "", err
+	}
+	// The query is usually the latest user message, which is already in the buffer
+	if n := len(history); n > 0 && history[n-1].Role == "user" && history[n-1].Content == query {
+		history = history[:n-1]
+	}
+	if len(history) > v.turns {
+		history = history[len(history)-v.turns:]
+	}
+	if len(history) == 0 {
+		return query, nil
+	}
+
+	var sb strings.Builder
+	sb.WriteString("Rewrite the follow-up question below as a standalone question that can be understood without the conversation. Resolve references like \"it\", \"that\" or \"the second one\" using the conversation. Return only the question.\n\n")
+	sb.WriteString("Conversation:\n")
+	for _, msg := range history {
+		sb.WriteString(fmt.Sprintf("%s: %s\n", msg.Role, msg.Content))
+	}
+	sb.WriteString(fmt.Sprintf("\nFollow-up question: %s\n\nStandalone question:", query))
+
+	condensed, err := v.condenser.Generate(ctx, sb.String(), func(o *interfaces.GenerateOptions) {
+		if o.LLMConfig == nil {
+			o.LLMConfig = &interfaces.LLMConfig{}
+		}
+		o.LLMConfig.Temperature = 0
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to condense query: %w", err)
+	}
+	if condensed = strings.TrimSpace(condensed); condensed == "" {
+		return query, nil
+	}
+	return condensed, nil
+}
+
 // Clear clears the memory
 func (v *VectorStoreRetriever) Clear(ctx context.Context) error {
 	v.mu.Lock()
@@ -0,0 +1,75 @@
+package memory_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+	"github.com/run-bigpig/llm-agent/pkg/memory"
+)
+
+// searchStore records search queries
+type searchStore struct {
+	interfaces.VectorStore
+	queries []string
+}
+
+func (s *searchStore) Store(ctx context.Context, documents []interfaces.Document, options ...interfaces.StoreOption) error {
+	return nil
+}
+
+func (s *searchStore) Search(ctx context.Context, query string, limit int, options ...interfaces.SearchOption) ([]interfaces.SearchResult, error) {
+	s.queries = append(s.queries, query)
+	return nil, nil
+}
+
+// condenseLLM records the prompt and returns a standalone question
+type condenseLLM struct {
+	interfaces.LLM
+	prompts []string
+}
+
+func (l *condenseLLM) Generate(ctx context.Context, prompt string, options ...interfaces.GenerateOption) (string, error) {
+	l.prompts = append(l.prompts, prompt)
+	return " What is the price of the Pro plan? \n", nil
+}
+
+func TestVectorStoreRetrieverConversationContext(t *testing.T) {
+	store := &searchStore{}
+	llm := &condenseLLM{}
+	retriever := memory.NewVectorStoreRetriever(store, memory.WithConversationContext(llm, 2))
+	ctx := conversationContext("conv")
+
+	// Without earlier turns the query is searched as it is
+	if _, err := retriever.GetMessages(ctx, interfaces.WithQuery("Which plans do you offer?")); err != nil {
+		t.Fatalf("failed to get messages: %v", err)
+	}
+	if len(llm.prompts) != 0 || store.queries[0] != "Which plans do you offer?" {
+		t.Fatalf("expected the first query to be searched unchanged, got %v", store.queries)
+	}
+
+	for _, msg := range []interfaces.Message{
+		{Role: "user", Content: "Which plans do you offer?"},
+		{Role: "assistant", Content: "We offer Basic and Pro."},
+		{Role: "user", Content: "What about the second one?"},
+	} {
+		if err := retriever.AddMessage(ctx, msg); err != nil {
+			t.Fatalf("failed to add message: %v", err)
+		}
+	}
+
+	if _, err := retriever.GetMessages(ctx, interfaces.WithQuery("What about the second one?")); err != nil {
+		t.Fatalf("failed to get messages: %v", err)
+	}
+	if store.queries[1] != "What is the price of the Pro plan?" {
+		t.Errorf("expected the condensed query to be searched, got %q", store.queries[1])
+	}
+
+	// The prompt has the last 2 turns before the follow-up, not the follow-up itself
+	prompt := llm.prompts[0]
+	if !strings.Contains(prompt, "user: Which plans do you offer?") || !strings.Contains(prompt, "assistant: We offer Basic and Pro.") ||
+		strings.Contains(prompt, "user: What about the second one?") || !strings.Contains(prompt, "Follow-up question: What about the second one?") {
+		t.Errorf("unexpected prompt:\n%s", prompt)
+	}
+}
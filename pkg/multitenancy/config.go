@@ -25,10 +25,38 @@ type TenantConfig struct {
 	// DataStoreConfig contains data store configuration
 	DataStoreConfig map[string]interface{}
 
+	// OAuthTokens maps integration providers (e.g., "github", "google", "jira") to OAuth access tokens
+	OAuthTokens map[string]string
+
 	// Custom contains custom configuration values
 	Custom map[string]interface{}
 }
 
+// TokenProvider resolves OAuth access tokens for the organization in the context
+type TokenProvider interface {
+	// GetOAuthToken returns the access token for the given integration provider
+	GetOAuthToken(ctx context.Context, provider string) (string, error)
+}
+
+// StaticTokens is a TokenProvider that maps organization IDs to a fixed
+// access token for every integration provider
+type StaticTokens map[string]string
+
+// GetOAuthToken returns the token of the organization in the context
+func (t StaticTokens) GetOAuthToken(ctx context.Context, provider string) (string, error) {
+	orgID, err := GetOrgID(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	token, ok := t[orgID]
+	if !ok {
+		return "", errors.New("OAuth token not found for organization: " + orgID)
+	}
+
+	return token, nil
+}
+
 // ConfigManager manages tenant configurations
 type ConfigManager struct {
 	configs map[string]*TenantConfig
@@ -88,6 +116,21 @@ func (m *ConfigManager) GetLLMAPIKey(ctx context.Context, provider string) (stri
 	return apiKey, nil
 }
 
+// GetOAuthToken returns the OAuth access token for the given integration provider
+func (m *ConfigManager) GetOAuthToken(ctx context.Context, provider string) (string, error) {
+	config, err := m.GetTenantConfig(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	token, ok := config.OAuthTokens[provider]
+	if !ok {
+		return "", errors.New("OAuth token not found for provider: " + provider)
+	}
+
+	return token, nil
+}
+
 // GetVectorStoreConfig returns the vector store configuration
 func (m *ConfigManager) GetVectorStoreConfig(ctx context.Context) (map[string]interface{}, error) {
 	config, err := m.GetTenantConfig(ctx)
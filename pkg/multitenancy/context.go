@@ -3,13 +3,8 @@ package multitenancy
 import (
 	"context"
 	"errors"
-)
-
-type contextKey string
 
-const (
-	// orgIDKey is the context key for the organization ID
-	orgIDKey contextKey = "org_id"
+	"github.com/run-bigpig/llm-agent/pkg/runctx"
 )
 
 var (
@@ -19,13 +14,13 @@ var (
 
 // WithOrgID returns a new context with the given organization ID
 func WithOrgID(ctx context.Context, orgID string) context.Context {
-	return context.WithValue(ctx, orgIDKey, orgID)
+	return runctx.WithOrgID(ctx, orgID)
 }
 
 // GetOrgID returns the organization ID from the context
 func GetOrgID(ctx context.Context) (string, error) {
-	orgID, ok := ctx.Value(orgIDKey).(string)
-	if !ok || orgID == "" {
+	orgID := runctx.OrgID(ctx)
+	if orgID == "" {
 		return "", ErrNoOrgID
 	}
 	return orgID, nil
@@ -171,3 +171,18 @@ func TestMultiTenancy(t *testing.T) {
 		t.Errorf("Document IDs not stored correctly by organization")
 	}
 }
+
+func TestStaticTokens(t *testing.T) {
+	tokens := multitenancy.StaticTokens{"org-a": "token-a"}
+
+	token, err := tokens.GetOAuthToken(multitenancy.WithOrgID(context.Background(), "org-a"), "github")
+	if err != nil || token != "token-a" {
+		t.Errorf("Expected the organization's token, got %q, %v", token, err)
+	}
+	if _, err := tokens.GetOAuthToken(multitenancy.WithOrgID(context.Background(), "org-b"), "github"); err == nil {
+		t.Error("Expected an error for an organization without a token")
+	}
+	if _, err := tokens.GetOAuthToken(context.Background(), "github"); err == nil {
+		t.Error("Expected an error without an organization")
+	}
+}
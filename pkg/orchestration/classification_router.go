@@ -0,0 +1,98 @@
+package orchestration
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/run-bigpig/llm-agent/pkg/classification"
+	"github.com/run-bigpig/llm-agent/pkg/logging"
+)
+
+// ClassificationRouter routes requests by the intent or topic of the query.
+// It uses the classification already in the context, e.g. from an agent with
+// an input classifier, and otherwise classifies the query itself.
+type ClassificationRouter struct {
+	classifier   classification.Classifier
+	intentRoutes map[string]string
+	topicRoutes  map[string]string
+	fallback     Router
+	logger       logging.Logger
+}
+
+// NewClassificationRouter creates a new classification router. Queries that
+// match no route go to the fallback router, if it is not nil.
+func NewClassificationRouter(classifier classification.Classifier, fallback Router) *ClassificationRouter {
+	return &ClassificationRouter{
+		classifier:   classifier,
+		intentRoutes: make(map[string]string),
+		topicRoutes:  make(map[string]string),
+		fallback:     fallback,
+		logger:       logging.New(), // Default logger
+	}
+}
+
+// WithLogger sets the logger for the router
+func (r *ClassificationRouter) WithLogger(logger logging.Logger) *ClassificationRouter {
+	r.logger = logger
+	return r
+}
+
+// AddIntentRoute routes queries with an intent to an agent
+func (r *ClassificationRouter) AddIntentRoute(intent string, agentID string) {
+	r.intentRoutes[intent] = agentID
+}
+
+// AddTopicRoute routes queries about a topic to an agent. Intent routes take
+// precedence.
+func (r *ClassificationRouter) AddTopicRoute(topic string, agentID string) {
+	r.topicRoutes[topic] = agentID
+}
+
+// Route determines which agent should handle a request. The classification
+// is added to the request context under classification.MetadataKey, so that
+// it is passed on with handoffs.
+func (r *ClassificationRouter) Route(ctx context.Context, query string, context map[string]interface{}) (string, error) {
+	result, err := r.classify(ctx, query, context)
+	if err != nil {
+		return "", err
+	}
+	if context != nil {
+		context[classification.MetadataKey] = result.Metadata()
+	}
+
+	if agentID, ok := r.intentRoutes[result.Intent]; ok && result.Intent != "" {
+		r.logger.Info(ctx, "Query routed by intent", map[string]interface{}{
+			"agent_id": agentID,
+			"intent":   result.Intent,
+		})
+		return agentID, nil
+	}
+	if agentID, ok := r.topicRoutes[result.Topic]; ok && result.Topic != "" {
+		r.logger.Info(ctx, "Query routed by topic", map[string]interface{}{
+			"agent_id": agentID,
+			"topic":    result.Topic,
+		})
+		return agentID, nil
+	}
+
+	if r.fallback != nil {
+		return r.fallback.Route(classification.WithClassification(ctx, result), query, context)
+	}
+	return "", fmt.Errorf("no agent found for intent %q and topic %q", result.Intent, result.Topic)
+}
+
+// classify returns the classification of the query from the context, or
+// classifies it
+func (r *ClassificationRouter) classify(ctx context.Context, query string, context map[string]interface{}) (*classification.Classification, error) {
+	if result, ok := classification.FromContext(ctx); ok {
+		return result, nil
+	}
+	if result, ok := classification.FromMetadata(context); ok {
+		return result, nil
+	}
+	result, err := r.classifier.Classify(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to classify query: %w", err)
+	}
+	return result, nil
+}
@@ -0,0 +1,49 @@
+package orchestration_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/run-bigpig/llm-agent/pkg/classification"
+	"github.com/run-bigpig/llm-agent/pkg/orchestration"
+)
+
+func TestClassificationRouter(t *testing.T) {
+	fallback := orchestration.NewSimpleRouter()
+	fallback.AddRoute("weather", "weather-agent")
+
+	router := orchestration.NewClassificationRouter(classification.NewRuleClassifier(
+		classification.WithIntentKeywords("refund_request", "refund"),
+		classification.WithTopicKeywords("shipping", "package", "delivery"),
+	), fallback)
+	router.AddIntentRoute("refund_request", "billing-agent")
+	router.AddTopicRoute("shipping", "shipping-agent")
+
+	routeContext := map[string]interface{}{}
+	agentID, err := router.Route(context.Background(), "I need a refund for my package", routeContext)
+	require.NoError(t, err)
+	assert.Equal(t, "billing-agent", agentID, "intent routes take precedence")
+	stored, ok := classification.FromMetadata(routeContext)
+	require.True(t, ok)
+	assert.Equal(t, "refund_request", stored.Intent)
+
+	agentID, err = router.Route(context.Background(), "Where is my package?", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "shipping-agent", agentID)
+
+	agentID, err = router.Route(context.Background(), "What's the weather like?", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "weather-agent", agentID)
+
+	// A classification in the context is used instead of classifying again
+	ctx := classification.WithClassification(context.Background(), &classification.Classification{Topic: "shipping"})
+	agentID, err = router.Route(ctx, "I need a refund", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "shipping-agent", agentID)
+
+	_, err = router.Route(context.Background(), "Tell me a joke", nil)
+	assert.Error(t, err)
+}
@@ -3,7 +3,9 @@ package orchestration
 import (
 	"context"
 	"fmt"
-	"sync"
+
+	"github.com/run-bigpig/llm-agent/pkg/lifecycle"
+	"github.com/run-bigpig/llm-agent/pkg/parallel"
 )
 
 // TaskStatus represents the status of a task
@@ -91,7 +93,8 @@ func (w *Workflow) SetFinalTask(id string) {
 
 // CodeOrchestrator orchestrates agents using code-defined workflows
 type CodeOrchestrator struct {
-	registry *AgentRegistry
+	registry  *AgentRegistry
+	lifecycle *lifecycle.Manager
 }
 
 // NewCodeOrchestrator creates a new code orchestrator
@@ -101,83 +104,87 @@ func NewCodeOrchestrator(registry *AgentRegistry) *CodeOrchestrator {
 	}
 }
 
-// ExecuteWorkflow executes a workflow
+// WithLifecycle tracks workflow executions in the lifecycle manager so that
+// shutdown waits for them, and cancels those still running at the deadline
+func (o *CodeOrchestrator) WithLifecycle(manager *lifecycle.Manager) *CodeOrchestrator {
+	o.lifecycle = manager
+	return o
+}
+
+// ExecuteWorkflow executes a workflow. Tasks run concurrently as soon as all
+// of their dependencies have completed; tasks that depend on a failed task
+// are not run.
 func (o *CodeOrchestrator) ExecuteWorkflow(ctx context.Context, workflow *Workflow) (string, error) {
-	// Create a wait group to wait for all tasks
-	var wg sync.WaitGroup
+	if o.lifecycle != nil {
+		done, err := o.lifecycle.Begin("workflow")
+		if err != nil {
+			return "", err
+		}
+		defer done()
 
-	// Create a channel to signal task completion
-	taskCompletionCh := make(chan string)
+		var cancel context.CancelFunc
+		ctx, cancel = o.lifecycle.Bind(ctx)
+		defer cancel()
+	}
 
-	// Create a map to track completed tasks
 	completedTasks := make(map[string]bool)
-	var completedTasksMu sync.Mutex
-
-	// Create a context with cancellation
-	ctx, cancel := context.WithCancel(ctx)
-	defer cancel()
-
-	// Start a goroutine to monitor task completion
-	go func() {
-		for {
-			select {
-			case taskID := <-taskCompletionCh:
-				// Mark task as completed
-				completedTasksMu.Lock()
-				completedTasks[taskID] = true
-				completedTasksMu.Unlock()
-
-				// Check if all tasks are completed
-				allCompleted := true
-				for _, task := range workflow.Tasks {
-					if task.Status != TaskCompleted && task.Status != TaskFailed {
-						allCompleted = false
-						break
-					}
-				}
 
-				if allCompleted {
-					// All tasks are completed, cancel the context
-					cancel()
-					return
-				}
+	for {
+		// Find the pending tasks whose dependencies have all completed
+		var ready []*Task
+		for _, task := range workflow.Tasks {
+			if task.Status != TaskPending {
+				continue
+			}
 
-				// Check if any tasks can now be executed
-				for _, task := range workflow.Tasks {
-					if task.Status == TaskPending {
-						// Check if all dependencies are completed
-						allDepsCompleted := true
-						for _, depID := range task.Dependencies {
-							if !completedTasks[depID] {
-								allDepsCompleted = false
-								break
-							}
-						}
-
-						if allDepsCompleted {
-							// All dependencies are completed, execute the task
-							wg.Add(1)
-							go o.executeTask(ctx, task, workflow, &wg, taskCompletionCh)
-						}
-					}
+			allDepsCompleted := true
+			for _, depID := range task.Dependencies {
+				if !completedTasks[depID] {
+					allDepsCompleted = false
+					break
 				}
-			case <-ctx.Done():
-				// Context is cancelled, exit
-				return
+			}
+
+			if allDepsCompleted {
+				ready = append(ready, task)
 			}
 		}
-	}()
 
-	// Start tasks with no dependencies
-	for _, task := range workflow.Tasks {
-		if len(task.Dependencies) == 0 {
-			wg.Add(1)
-			go o.executeTask(ctx, task, workflow, &wg, taskCompletionCh)
+		if len(ready) == 0 {
+			break
 		}
-	}
 
-	// Wait for all tasks to complete
-	wg.Wait()
+		// Run the ready tasks; a failed task does not stop the others
+		results := make([]string, len(ready))
+		errs := make([]error, len(ready))
+		err := parallel.ForEach(ctx, ready, func(ctx context.Context, i int, task *Task) error {
+			task.Status = TaskRunning
+			results[i], errs[i] = o.executeTask(ctx, task, workflow)
+			return nil
+		})
+		if err != nil {
+			return "", err
+		}
+
+		// Record the results
+		for i, task := range ready {
+			if errs[i] != nil {
+				task.Status = TaskFailed
+				task.Error = errs[i]
+				workflow.Errors[task.ID] = errs[i]
+				continue
+			}
+
+			task.Status = TaskCompleted
+			task.Result = results[i]
+			workflow.Results[task.ID] = results[i]
+			completedTasks[task.ID] = true
+		}
+
+		if ctx.Err() != nil {
+			return "", context.Cause(ctx)
+		}
+	}
 
 	// Check if the final task completed successfully
 	if workflow.FinalTaskID != "" {
@@ -196,21 +203,12 @@ func (o *CodeOrchestrator) ExecuteWorkflow(ctx context.Context, workflow *Workfl
 	return "", nil
 }
 
-// executeTask executes a task
-func (o *CodeOrchestrator) executeTask(ctx context.Context, task *Task, workflow *Workflow, wg *sync.WaitGroup, completionCh chan<- string) {
-	defer wg.Done()
-
-	// Update task status
-	task.Status = TaskRunning
-
+// executeTask runs the task's agent with the results of its dependencies
+func (o *CodeOrchestrator) executeTask(ctx context.Context, task *Task, workflow *Workflow) (string, error) {
 	// Get the agent
 	agent, ok := o.registry.Get(task.AgentID)
 	if !ok {
-		task.Status = TaskFailed
-		task.Error = fmt.Errorf("agent not found: %s", task.AgentID)
-		workflow.Errors[task.ID] = task.Error
-		completionCh <- task.ID
-		return
+		return "", fmt.Errorf("agent not found: %s", task.AgentID)
 	}
 
 	// Prepare input with results from dependencies
@@ -224,18 +222,8 @@ func (o *CodeOrchestrator) executeTask(ctx context.Context, task *Task, workflow
 	// Execute the agent
 	result, err := agent.Run(ctx, input)
 	if err != nil {
-		task.Status = TaskFailed
-		task.Error = fmt.Errorf("agent execution failed: %w", err)
-		workflow.Errors[task.ID] = task.Error
-		completionCh <- task.ID
-		return
+		return "", fmt.Errorf("agent execution failed: %w", err)
 	}
 
-	// Update task status and result
-	task.Status = TaskCompleted
-	task.Result = result
-	workflow.Results[task.ID] = result
-
-	// Signal task completion
-	completionCh <- task.ID
+	return result, nil
 }
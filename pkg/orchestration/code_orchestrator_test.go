@@ -0,0 +1,69 @@
+package orchestration_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/run-bigpig/llm-agent/pkg/agent"
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+	"github.com/run-bigpig/llm-agent/pkg/lifecycle"
+	"github.com/run-bigpig/llm-agent/pkg/orchestration"
+)
+
+// hangingLLM blocks until its context is done
+type hangingLLM struct {
+	started chan struct{}
+}
+
+func (m *hangingLLM) Generate(ctx context.Context, prompt string, options ...interfaces.GenerateOption) (string, error) {
+	close(m.started)
+	<-ctx.Done()
+	return "", context.Cause(ctx)
+}
+
+func (m *hangingLLM) GenerateWithTools(ctx context.Context, prompt string, tools []interfaces.Tool, options ...interfaces.GenerateOption) (string, error) {
+	return m.Generate(ctx, prompt, options...)
+}
+
+func (m *hangingLLM) Name() string { return "hanging" }
+
+func TestCodeOrchestratorLifecycle(t *testing.T) {
+	manager := lifecycle.NewManager(lifecycle.WithTimeout(20 * time.Millisecond))
+	llm := &hangingLLM{started: make(chan struct{})}
+	worker, err := agent.NewAgent(agent.WithLLM(llm))
+	require.NoError(t, err)
+
+	registry := orchestration.NewAgentRegistry()
+	registry.Register("worker", worker)
+	orchestrator := orchestration.NewCodeOrchestrator(registry).WithLifecycle(manager)
+
+	workflow := orchestration.NewWorkflow()
+	workflow.AddTask("report", "worker", "Write the report", nil)
+	workflow.SetFinalTask("report")
+
+	runErr := make(chan error, 1)
+	go func() {
+		_, err := orchestrator.ExecuteWorkflow(context.Background(), workflow)
+		runErr <- err
+	}()
+	<-llm.started
+	assert.Len(t, manager.InFlight(), 1)
+
+	// Shutdown waits for the workflow and cancels it at the deadline
+	assert.Error(t, manager.Shutdown(context.Background()))
+
+	select {
+	case err := <-runErr:
+		assert.True(t, errors.Is(err, lifecycle.ErrShuttingDown), "expected the workflow to be cancelled by shutdown, got %v", err)
+	case <-time.After(time.Second):
+		t.Fatal("expected the in-flight workflow to be cancelled at the shutdown deadline")
+	}
+
+	_, err = orchestrator.ExecuteWorkflow(context.Background(), orchestration.NewWorkflow())
+	assert.ErrorIs(t, err, lifecycle.ErrShuttingDown)
+}
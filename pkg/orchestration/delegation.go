@@ -7,6 +7,7 @@ import (
 
 	"github.com/run-bigpig/llm-agent/pkg/agent"
 	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+	"github.com/run-bigpig/llm-agent/pkg/memory"
 )
 
 // DelegationAgent is an agent that can delegate tasks to other agents
@@ -77,11 +78,8 @@ func (a *DelegationAgent) copyMemory(ctx context.Context, targetAgent *agent.Age
 	}
 
 	// Add messages to target memory
-	for _, msg := range messages {
-		err := targetMemory.AddMessage(ctx, msg)
-		if err != nil {
-			return fmt.Errorf("failed to add message to target memory: %w", err)
-		}
+	if err := memory.AddMessages(ctx, targetMemory, messages); err != nil {
+		return fmt.Errorf("failed to add messages to target memory: %w", err)
 	}
 
 	return nil
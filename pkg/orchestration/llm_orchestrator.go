@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+	"github.com/run-bigpig/llm-agent/pkg/lifecycle"
 	"github.com/run-bigpig/llm-agent/pkg/logging"
 )
 
@@ -39,9 +40,10 @@ type Plan struct {
 
 // LLMOrchestrator orchestrates the execution of a query using multiple agents
 type LLMOrchestrator struct {
-	registry *AgentRegistry
-	planner  interfaces.LLM
-	logger   logging.Logger
+	registry  *AgentRegistry
+	planner   interfaces.LLM
+	logger    logging.Logger
+	lifecycle *lifecycle.Manager
 }
 
 // NewLLMOrchestrator creates a new LLM orchestrator
@@ -59,8 +61,27 @@ func (o *LLMOrchestrator) WithLogger(logger logging.Logger) *LLMOrchestrator {
 	return o
 }
 
+// WithLifecycle tracks executions in the lifecycle manager so that shutdown
+// waits for them, and cancels those still running at the deadline
+func (o *LLMOrchestrator) WithLifecycle(manager *lifecycle.Manager) *LLMOrchestrator {
+	o.lifecycle = manager
+	return o
+}
+
 // Execute executes a query using the orchestrator
 func (o *LLMOrchestrator) Execute(ctx context.Context, query string) (string, error) {
+	if o.lifecycle != nil {
+		done, err := o.lifecycle.Begin("orchestrator")
+		if err != nil {
+			return "", err
+		}
+		defer done()
+
+		var cancel context.CancelFunc
+		ctx, cancel = o.lifecycle.Bind(ctx)
+		defer cancel()
+	}
+
 	o.logger.Info(ctx, "Starting execution for query", map[string]interface{}{"query": query})
 
 	// Create a plan
@@ -0,0 +1,213 @@
+package parallel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sync"
+)
+
+// ItemError is the error returned for a single item
+type ItemError struct {
+	// Index is the index of the item that failed
+	Index int
+
+	// Err is the error returned for the item
+	Err error
+}
+
+// Error returns the error message
+func (e *ItemError) Error() string {
+	return fmt.Sprintf("item %d: %v", e.Index, e.Err)
+}
+
+// Unwrap returns the underlying error
+func (e *ItemError) Unwrap() error {
+	return e.Err
+}
+
+// PanicError is returned when a function panics
+type PanicError struct {
+	Value interface{}
+	Stack []byte
+}
+
+// Error returns the error message
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// config holds the options for a parallel operation
+type config struct {
+	concurrency int
+	collectAll  bool
+}
+
+// Option represents an option for a parallel operation
+type Option func(*config)
+
+// WithConcurrency limits the number of items processed at once. Zero or a
+// negative value means no limit.
+func WithConcurrency(n int) Option {
+	return func(c *config) {
+		c.concurrency = n
+	}
+}
+
+// WithCollectAll processes every item even if some fail, and returns all the
+// errors joined together. By default the first error cancels the context
+// passed to the remaining items and is returned on its own.
+func WithCollectAll() Option {
+	return func(c *config) {
+		c.collectAll = true
+	}
+}
+
+// Map calls fn for each item concurrently and returns the results in the
+// order of the items. Each error is wrapped in an *ItemError, and panics are
+// returned as a *PanicError.
+func Map[T, R any](ctx context.Context, items []T, fn func(ctx context.Context, index int, item T) (R, error), options ...Option) ([]R, error) {
+	cfg := &config{}
+	for _, option := range options {
+		option(cfg)
+	}
+
+	results := make([]R, len(items))
+	if len(items) == 0 {
+		return results, nil
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		errs     []error
+		firstErr error
+	)
+
+	var sem chan struct{}
+	if cfg.concurrency > 0 {
+		sem = make(chan struct{}, cfg.concurrency)
+	}
+
+	for i, item := range items {
+		if sem != nil {
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+			}
+		}
+
+		// Stop starting new items once the context is done
+		if ctx.Err() != nil {
+			mu.Lock()
+			if firstErr == nil && !cfg.collectAll {
+				firstErr = ctx.Err()
+			}
+			if cfg.collectAll {
+				for j := i; j < len(items); j++ {
+					errs = append(errs, &ItemError{Index: j, Err: ctx.Err()})
+				}
+			}
+			mu.Unlock()
+			break
+		}
+
+		wg.Add(1)
+		go func(i int, item T) {
+			defer wg.Done()
+			if sem != nil {
+				defer func() { <-sem }()
+			}
+
+			result, err := call(ctx, i, item, fn)
+			if err == nil {
+				results[i] = result
+				return
+			}
+
+			itemErr := &ItemError{Index: i, Err: err}
+			mu.Lock()
+			defer mu.Unlock()
+			if cfg.collectAll {
+				errs = append(errs, itemErr)
+				return
+			}
+			if firstErr == nil {
+				firstErr = itemErr
+				cancel()
+			}
+		}(i, item)
+	}
+
+	wg.Wait()
+
+	if cfg.collectAll {
+		return results, errors.Join(errs...)
+	}
+	return results, firstErr
+}
+
+// call calls fn, converting a panic into an error
+func call[T, R any](ctx context.Context, index int, item T, fn func(ctx context.Context, index int, item T) (R, error)) (result R, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = &PanicError{Value: r, Stack: debug.Stack()}
+		}
+	}()
+	return fn(ctx, index, item)
+}
+
+// ForEach calls fn for each item concurrently
+func ForEach[T any](ctx context.Context, items []T, fn func(ctx context.Context, index int, item T) error, options ...Option) error {
+	_, err := Map(ctx, items, func(ctx context.Context, index int, item T) (struct{}, error) {
+		return struct{}{}, fn(ctx, index, item)
+	}, options...)
+	return err
+}
+
+// MapReduce maps the items concurrently and then folds the results in the
+// order of the items. With WithCollectAll, results of failed items are
+// skipped and the joined errors are returned alongside the reduced value.
+func MapReduce[T, R, A any](ctx context.Context, items []T, mapFn func(ctx context.Context, index int, item T) (R, error), reduceFn func(acc A, result R) A, initial A, options ...Option) (A, error) {
+	results, err := Map(ctx, items, mapFn, options...)
+
+	cfg := &config{}
+	for _, option := range options {
+		option(cfg)
+	}
+	if err != nil && !cfg.collectAll {
+		return initial, err
+	}
+
+	failed := make(map[int]bool)
+	var itemErr *ItemError
+	for _, e := range unwrapJoined(err) {
+		if errors.As(e, &itemErr) {
+			failed[itemErr.Index] = true
+		}
+	}
+
+	acc := initial
+	for i, result := range results {
+		if failed[i] {
+			continue
+		}
+		acc = reduceFn(acc, result)
+	}
+	return acc, err
+}
+
+// unwrapJoined returns the errors joined with errors.Join
+func unwrapJoined(err error) []error {
+	if err == nil {
+		return nil
+	}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		return joined.Unwrap()
+	}
+	return []error{err}
+}
@@ -0,0 +1,73 @@
+package parallel
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestMapPreservesOrderAndLimitsConcurrency(t *testing.T) {
+	var running, maxRunning atomic.Int32
+	items := []string{"a", "b", "c", "d", "e"}
+
+	results, err := Map(context.Background(), items, func(ctx context.Context, i int, item string) (string, error) {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for {
+			m := maxRunning.Load()
+			if n <= m || maxRunning.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		return strings.ToUpper(item), nil
+	}, WithConcurrency(2))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if strings.Join(results, "") != "ABCDE" {
+		t.Errorf("Expected results in item order, got %v", results)
+	}
+	if maxRunning.Load() > 2 {
+		t.Errorf("Expected at most 2 concurrent calls, got %d", maxRunning.Load())
+	}
+}
+
+func TestMapErrorSemantics(t *testing.T) {
+	boom := errors.New("boom")
+	fn := func(ctx context.Context, i int, item int) (int, error) {
+		if item%2 == 1 {
+			return 0, boom
+		}
+		if item == 4 {
+			panic("bad item")
+		}
+		return item * 10, nil
+	}
+	items := []int{0, 1, 2, 3, 4}
+
+	// First error wins by default
+	if _, err := Map(context.Background(), items, fn); !errors.Is(err, boom) && !errors.As(err, new(*PanicError)) {
+		t.Errorf("Expected an item error, got %v", err)
+	}
+
+	// Collect-all runs every item and reports every error
+	results, err := Map(context.Background(), items, fn, WithCollectAll())
+	if results[0] != 0 || results[2] != 20 {
+		t.Errorf("Expected successful results to be kept, got %v", results)
+	}
+	var panicErr *PanicError
+	if !errors.Is(err, boom) || !errors.As(err, &panicErr) {
+		t.Errorf("Expected both the error and the panic, got %v", err)
+	}
+	if strings.Count(err.Error(), "item ") != 3 {
+		t.Errorf("Expected 3 item errors, got %v", err)
+	}
+
+	sum, err := MapReduce(context.Background(), items, fn, func(acc, r int) int { return acc + r }, 0, WithCollectAll())
+	if sum != 20 || err == nil {
+		t.Errorf("Expected sum of successful items and an error, got %d, %v", sum, err)
+	}
+}
@@ -0,0 +1,174 @@
+package prompts
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Names of the standard system prompt sections
+const (
+	// SectionIdentity says who the agent is
+	SectionIdentity = "identity"
+
+	// SectionInstructions says what the agent does and how
+	SectionInstructions = "instructions"
+
+	// SectionTools says when and how to use tools
+	SectionTools = "tools"
+
+	// SectionSafety sets limits on what the agent may do or say
+	SectionSafety = "safety"
+
+	// SectionLanguage sets the language of the responses
+	SectionLanguage = "language"
+)
+
+// defaultSectionOrder is the order of the standard sections. Custom sections
+// without an order come after them.
+var defaultSectionOrder = map[string]int{
+	SectionIdentity:     100,
+	SectionInstructions: 200,
+	SectionTools:        300,
+	SectionSafety:       400,
+	SectionLanguage:     500,
+}
+
+// customSectionOrder is the order of custom sections without an order
+const customSectionOrder = 1000
+
+// Section is a part of a system prompt
+type Section struct {
+	// Name identifies the section; a section replaces one of the same name
+	Name string
+
+	// Title is rendered as a heading above the content, if set
+	Title string
+
+	// Content is the text of the section
+	Content string
+
+	// Order positions the section; lower orders come first, and sections of
+	// the same order are sorted by name. Zero uses the default order of the
+	// section's name.
+	Order int
+}
+
+// SystemPrompt composes a system prompt from named sections. Sections can be
+// replaced per tenant, and are always rendered in the same order.
+type SystemPrompt struct {
+	mu        sync.RWMutex
+	sections  map[string]Section
+	overrides map[string]map[string]Section
+}
+
+// NewSystemPrompt creates a system prompt from sections
+func NewSystemPrompt(sections ...Section) *SystemPrompt {
+	p := &SystemPrompt{
+		sections:  make(map[string]Section),
+		overrides: make(map[string]map[string]Section),
+	}
+	for _, section := range sections {
+		p.Set(section)
+	}
+	return p
+}
+
+// Set adds a section, or replaces the section of the same name
+func (p *SystemPrompt) Set(section Section) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.sections[section.Name] = section
+}
+
+// Remove removes a section
+func (p *SystemPrompt) Remove(name string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.sections, name)
+}
+
+// Section returns a section by name
+func (p *SystemPrompt) Section(name string) (Section, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	section, ok := p.sections[name]
+	return section, ok
+}
+
+// SetOverride replaces a section for one tenant. An override with empty
+// content removes the section for the tenant.
+func (p *SystemPrompt) SetOverride(orgID string, section Section) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.overrides[orgID] == nil {
+		p.overrides[orgID] = make(map[string]Section)
+	}
+	p.overrides[orgID][section.Name] = section
+}
+
+// RemoveOverride removes a tenant's override of a section
+func (p *SystemPrompt) RemoveOverride(orgID, name string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.overrides[orgID], name)
+	if len(p.overrides[orgID]) == 0 {
+		delete(p.overrides, orgID)
+	}
+}
+
+// HasOverrides reports whether a tenant overrides any section
+func (p *SystemPrompt) HasOverrides(orgID string) bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return len(p.overrides[orgID]) > 0
+}
+
+// Render renders the sections with the overrides of a tenant, separated by
+// blank lines. An empty orgID renders the sections without overrides.
+func (p *SystemPrompt) Render(orgID string) string {
+	p.mu.RLock()
+	sections := make([]Section, 0, len(p.sections))
+	for name, section := range p.sections {
+		if _, overridden := p.overrides[orgID][name]; !overridden {
+			sections = append(sections, section)
+		}
+	}
+	for _, section := range p.overrides[orgID] {
+		sections = append(sections, section)
+	}
+	p.mu.RUnlock()
+
+	sort.Slice(sections, func(i, j int) bool {
+		oi, oj := sectionOrder(sections[i]), sectionOrder(sections[j])
+		if oi != oj {
+			return oi < oj
+		}
+		return sections[i].Name < sections[j].Name
+	})
+
+	var parts []string
+	for _, section := range sections {
+		content := section.Content
+		if strings.TrimSpace(content) == "" {
+			continue
+		}
+		if section.Title != "" {
+			content = "# " + section.Title + "\n" + content
+		}
+		parts = append(parts, content)
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// sectionOrder returns the order of a section, falling back to the default
+// order of its name
+func sectionOrder(section Section) int {
+	if section.Order != 0 {
+		return section.Order
+	}
+	if order, ok := defaultSectionOrder[section.Name]; ok {
+		return order
+	}
+	return customSectionOrder
+}
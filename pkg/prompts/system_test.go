@@ -0,0 +1,50 @@
+package prompts_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/run-bigpig/llm-agent/pkg/prompts"
+)
+
+func TestSystemPromptRender(t *testing.T) {
+	prompt := prompts.NewSystemPrompt(
+		prompts.Section{Name: "glossary", Title: "Glossary", Content: "ARR: annual recurring revenue"},
+		prompts.Section{Name: prompts.SectionSafety, Content: "Never share credentials."},
+		prompts.Section{Name: prompts.SectionInstructions, Content: "Answer billing questions."},
+		prompts.Section{Name: prompts.SectionIdentity, Content: "You are Billy."},
+		prompts.Section{Name: "disclaimer", Content: "Not financial advice.", Order: 50},
+	)
+
+	assert.Equal(t, "Not financial advice.\n\n"+
+		"You are Billy.\n\n"+
+		"Answer billing questions.\n\n"+
+		"Never share credentials.\n\n"+
+		"# Glossary\nARR: annual recurring revenue", prompt.Render(""))
+
+	// Replacing a section keeps its position
+	prompt.Set(prompts.Section{Name: prompts.SectionIdentity, Content: "You are Bill."})
+	prompt.Remove("disclaimer")
+	prompt.Remove("glossary")
+	assert.Equal(t, "You are Bill.\n\nAnswer billing questions.\n\nNever share credentials.", prompt.Render(""))
+}
+
+func TestSystemPromptOverrides(t *testing.T) {
+	prompt := prompts.NewSystemPrompt(
+		prompts.Section{Name: prompts.SectionIdentity, Content: "You are Billy."},
+		prompts.Section{Name: prompts.SectionInstructions, Content: "Answer billing questions."},
+	)
+	prompt.SetOverride("acme", prompts.Section{Name: prompts.SectionIdentity, Content: "You are the Acme assistant."})
+	prompt.SetOverride("globex", prompts.Section{Name: prompts.SectionIdentity})
+
+	assert.True(t, prompt.HasOverrides("acme"))
+	assert.False(t, prompt.HasOverrides("initech"))
+	assert.Equal(t, "You are the Acme assistant.\n\nAnswer billing questions.", prompt.Render("acme"))
+	assert.Equal(t, "Answer billing questions.", prompt.Render("globex"))
+	assert.Equal(t, "You are Billy.\n\nAnswer billing questions.", prompt.Render("initech"))
+
+	prompt.RemoveOverride("acme", prompts.SectionIdentity)
+	assert.False(t, prompt.HasOverrides("acme"))
+	assert.Equal(t, "You are Billy.\n\nAnswer billing questions.", prompt.Render("acme"))
+}
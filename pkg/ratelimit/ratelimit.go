@@ -0,0 +1,255 @@
+// Package ratelimit provides per-API-key and per-organization rate limiting
+// for HTTP and WebSocket servers.
+package ratelimit
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/run-bigpig/llm-agent/pkg/runctx"
+)
+
+// Limit is a token bucket limit: RequestsPerMinute requests on average, with
+// bursts of up to Burst requests. A zero Burst allows bursts of one minute's
+// worth of requests.
+type Limit struct {
+	RequestsPerMinute int
+	Burst             int
+}
+
+// Stats counts the requests the limiter has seen, for exporting as metrics
+type Stats struct {
+	Allowed           int64
+	Throttled         int64
+	ThrottledByAPIKey int64
+	ThrottledByOrg    int64
+	TrackedAPIKeys    int
+	TrackedOrgs       int
+}
+
+// Limiter limits requests per API key and per organization
+type Limiter struct {
+	apiKeyLimit Limit
+	orgLimit    Limit
+	apiKeyFunc  func(*http.Request) string
+	orgFunc     func(*http.Request) string
+
+	mu        sync.Mutex
+	apiKeys   map[string]*bucket
+	orgs      map[string]*bucket
+	lastSweep time.Time
+	stats     Stats
+}
+
+// Option represents an option for configuring the limiter
+type Option func(*Limiter)
+
+// WithAPIKeyLimit limits the requests of each API key
+func WithAPIKeyLimit(requestsPerMinute, burst int) Option {
+	return func(l *Limiter) {
+		l.apiKeyLimit = Limit{RequestsPerMinute: requestsPerMinute, Burst: burst}
+	}
+}
+
+// WithOrgLimit limits the requests of each organization, across all its API keys
+func WithOrgLimit(requestsPerMinute, burst int) Option {
+	return func(l *Limiter) {
+		l.orgLimit = Limit{RequestsPerMinute: requestsPerMinute, Burst: burst}
+	}
+}
+
+// WithAPIKeyFunc sets how the API key is read from a request. By default it
+// is the authenticated principal, see PrincipalFromRequest. The function must
+// only return identities the server has authenticated.
+func WithAPIKeyFunc(fn func(*http.Request) string) Option {
+	return func(l *Limiter) {
+		l.apiKeyFunc = fn
+	}
+}
+
+// WithOrgFunc sets how the organization is read from a request. By default it
+// is the authenticated organization in the request context.
+func WithOrgFunc(fn func(*http.Request) string) Option {
+	return func(l *Limiter) {
+		l.orgFunc = fn
+	}
+}
+
+// New creates a new limiter. Requests are not limited on a dimension without a limit.
+func New(options ...Option) *Limiter {
+	l := &Limiter{
+		apiKeyFunc: PrincipalFromRequest,
+		orgFunc:    OrgFromRequest,
+		apiKeys:    make(map[string]*bucket),
+		orgs:       make(map[string]*bucket),
+	}
+	for _, option := range options {
+		option(l)
+	}
+	return l
+}
+
+// PrincipalFromRequest returns the user the authentication middleware put in
+// the request context, qualified by its organization. Requests without an
+// authenticated user are keyed by their remote address, so headers the client
+// controls can never choose or evade a bucket.
+func PrincipalFromRequest(r *http.Request) string {
+	ctx := r.Context()
+	if userID := runctx.UserID(ctx); userID != "" {
+		return "user:" + strconv.Quote(runctx.OrgID(ctx)) + strconv.Quote(userID)
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "addr:" + host
+}
+
+// OrgFromRequest returns the authenticated organization ID in the request context
+func OrgFromRequest(r *http.Request) string {
+	return runctx.OrgID(r.Context())
+}
+
+// Allow reports whether a request for the API key and organization may
+// proceed. If not, it returns how long to wait before retrying. Empty keys
+// are not limited.
+func (l *Limiter) Allow(apiKey, orgID string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.sweep(now)
+
+	var keyBucket, orgBucket *bucket
+	if apiKey != "" && l.apiKeyLimit.RequestsPerMinute > 0 {
+		keyBucket = l.bucket(l.apiKeys, apiKey, l.apiKeyLimit, now)
+	}
+	if orgID != "" && l.orgLimit.RequestsPerMinute > 0 {
+		orgBucket = l.bucket(l.orgs, orgID, l.orgLimit, now)
+	}
+
+	// Only take tokens if every limit allows the request
+	var wait time.Duration
+	if keyBucket != nil {
+		if w := keyBucket.wait(); w > 0 {
+			wait = w
+			l.stats.ThrottledByAPIKey++
+		}
+	}
+	if orgBucket != nil {
+		if w := orgBucket.wait(); w > 0 {
+			wait = max(wait, w)
+			l.stats.ThrottledByOrg++
+		}
+	}
+	if wait > 0 {
+		l.stats.Throttled++
+		return false, wait
+	}
+
+	if keyBucket != nil {
+		keyBucket.tokens--
+	}
+	if orgBucket != nil {
+		orgBucket.tokens--
+	}
+	l.stats.Allowed++
+	return true, 0
+}
+
+// Middleware returns a handler that responds 429 Too Many Requests with a
+// Retry-After header when the request exceeds a limit, and calls next
+// otherwise. WebSocket upgrades are limited like any other request.
+func (l *Limiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		allowed, wait := l.Allow(l.apiKeyFunc(r), l.orgFunc(r))
+		if !allowed {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			http.Error(w, "rate limit exceeded", http.StatusTooManyRequests)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Stats returns a snapshot of the limiter's counters
+func (l *Limiter) Stats() Stats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	stats := l.stats
+	stats.TrackedAPIKeys = len(l.apiKeys)
+	stats.TrackedOrgs = len(l.orgs)
+	return stats
+}
+
+// bucket returns the refilled bucket for key, creating a full one if needed
+func (l *Limiter) bucket(buckets map[string]*bucket, key string, limit Limit, now time.Time) *bucket {
+	b, ok := buckets[key]
+	if !ok {
+		b = newBucket(limit, now)
+		buckets[key] = b
+		return b
+	}
+	b.refill(now)
+	return b
+}
+
+// sweep drops buckets that have refilled completely, at most once a minute,
+// so that idle keys do not accumulate
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < time.Minute {
+		return
+	}
+	l.lastSweep = now
+	for _, buckets := range []map[string]*bucket{l.apiKeys, l.orgs} {
+		for key, b := range buckets {
+			b.refill(now)
+			if b.tokens >= b.capacity {
+				delete(buckets, key)
+			}
+		}
+	}
+}
+
+// bucket is a token bucket
+type bucket struct {
+	tokens   float64
+	capacity float64
+	rate     float64 // tokens per second
+	updated  time.Time
+}
+
+func newBucket(limit Limit, now time.Time) *bucket {
+	capacity := float64(limit.Burst)
+	if capacity <= 0 {
+		capacity = float64(limit.RequestsPerMinute)
+	}
+	return &bucket{
+		tokens:   capacity,
+		capacity: capacity,
+		rate:     float64(limit.RequestsPerMinute) / 60,
+		updated:  now,
+	}
+}
+
+// refill adds the tokens accrued since the last update
+func (b *bucket) refill(now time.Time) {
+	elapsed := now.Sub(b.updated).Seconds()
+	if elapsed > 0 {
+		b.tokens = math.Min(b.capacity, b.tokens+elapsed*b.rate)
+		b.updated = now
+	}
+}
+
+// wait returns how long until a token is available, or zero if one is available now
+func (b *bucket) wait() time.Duration {
+	if b.tokens >= 1 {
+		return 0
+	}
+	return time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+}
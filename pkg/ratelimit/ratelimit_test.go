@@ -0,0 +1,100 @@
+package ratelimit_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/run-bigpig/llm-agent/pkg/ratelimit"
+	"github.com/run-bigpig/llm-agent/pkg/runctx"
+)
+
+func TestMiddleware(t *testing.T) {
+	limiter := ratelimit.New(
+		ratelimit.WithAPIKeyLimit(60, 2),
+		ratelimit.WithOrgLimit(60, 3),
+	)
+	handler := limiter.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	// The identity comes from the context, as set by an authentication middleware
+	request := func(userID, orgID string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req = req.WithContext(runctx.WithUserID(runctx.WithOrgID(req.Context(), orgID), userID))
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// The user may burst to two requests
+	for i := 0; i < 2; i++ {
+		if rec := request("key-1", "org-1"); rec.Code != http.StatusOK {
+			t.Fatalf("request %d: expected 200, got %d", i+1, rec.Code)
+		}
+	}
+	rec := request("key-1", "org-1")
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429, got %d", rec.Code)
+	}
+	if rec.Header().Get("Retry-After") != "1" {
+		t.Errorf("expected Retry-After 1, got %q", rec.Header().Get("Retry-After"))
+	}
+
+	// Another user of the same organization uses the last org token
+	if rec := request("key-2", "org-1"); rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 for a second user, got %d", rec.Code)
+	}
+	if rec := request("key-3", "org-1"); rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected the organization limit to apply, got %d", rec.Code)
+	}
+
+	// Other organizations are not affected
+	if rec := request("key-4", "org-2"); rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 for another organization, got %d", rec.Code)
+	}
+
+	stats := limiter.Stats()
+	if stats.Allowed != 4 || stats.Throttled != 2 || stats.ThrottledByAPIKey != 1 || stats.ThrottledByOrg != 1 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+}
+
+func TestMiddlewareIgnoresClientHeaders(t *testing.T) {
+	limiter := ratelimit.New(
+		ratelimit.WithAPIKeyLimit(60, 1),
+		ratelimit.WithOrgLimit(60, 10),
+	)
+	handler := limiter.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	// Unauthenticated requests claim a fresh key and organization each time
+	request := func(i int, remoteAddr string) int {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = remoteAddr
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer key-%d", i))
+		req.Header.Set("X-API-Key", fmt.Sprintf("key-%d", i))
+		req.Header.Set("X-Org-ID", fmt.Sprintf("org-%d", i))
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if code := request(1, "192.0.2.1:1234"); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	// A new port and new headers still count against the same address
+	if code := request(2, "192.0.2.1:5678"); code != http.StatusTooManyRequests {
+		t.Fatalf("expected the remote address to be limited, got %d", code)
+	}
+	if code := request(3, "192.0.2.2:1234"); code != http.StatusOK {
+		t.Fatalf("expected 200 for another address, got %d", code)
+	}
+
+	stats := limiter.Stats()
+	if stats.TrackedOrgs != 0 {
+		t.Errorf("expected the X-Org-ID header to be ignored, got %d organizations", stats.TrackedOrgs)
+	}
+}
@@ -0,0 +1,78 @@
+package rbac
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+)
+
+// FilterDocuments returns the documents the caller may read
+func FilterDocuments(ctx context.Context, authorizer interfaces.DocumentAuthorizer, documents []interfaces.Document) ([]interfaces.Document, error) {
+	readable := make([]interfaces.Document, 0, len(documents))
+	for _, document := range documents {
+		allowed, err := authorizer.CanReadDocument(ctx, document)
+		if err != nil {
+			return nil, fmt.Errorf("failed to authorize document %s: %w", document.ID, err)
+		}
+		if allowed {
+			readable = append(readable, document)
+		}
+	}
+	return readable, nil
+}
+
+// FilterResults returns the search results the caller may read
+func FilterResults(ctx context.Context, authorizer interfaces.DocumentAuthorizer, results []interfaces.SearchResult) ([]interfaces.SearchResult, error) {
+	readable := make([]interfaces.SearchResult, 0, len(results))
+	for _, result := range results {
+		allowed, err := authorizer.CanReadDocument(ctx, result.Document)
+		if err != nil {
+			return nil, fmt.Errorf("failed to authorize document %s: %w", result.Document.ID, err)
+		}
+		if allowed {
+			readable = append(readable, result)
+		}
+	}
+	return readable, nil
+}
+
+// WrapVectorStore returns a vector store whose searches and gets only return
+// the documents the caller may read. Searches may return fewer results than
+// the limit.
+func WrapVectorStore(authorizer interfaces.DocumentAuthorizer, store interfaces.VectorStore) interfaces.VectorStore {
+	return &authorizedStore{VectorStore: store, authorizer: authorizer}
+}
+
+// authorizedStore is a vector store that filters the documents it returns
+type authorizedStore struct {
+	interfaces.VectorStore
+	authorizer interfaces.DocumentAuthorizer
+}
+
+// Search searches for similar documents the caller may read
+func (s *authorizedStore) Search(ctx context.Context, query string, limit int, options ...interfaces.SearchOption) ([]interfaces.SearchResult, error) {
+	results, err := s.VectorStore.Search(ctx, query, limit, options...)
+	if err != nil {
+		return nil, err
+	}
+	return FilterResults(ctx, s.authorizer, results)
+}
+
+// SearchByVector searches for similar documents the caller may read
+func (s *authorizedStore) SearchByVector(ctx context.Context, vector []float32, limit int, options ...interfaces.SearchOption) ([]interfaces.SearchResult, error) {
+	results, err := s.VectorStore.SearchByVector(ctx, vector, limit, options...)
+	if err != nil {
+		return nil, err
+	}
+	return FilterResults(ctx, s.authorizer, results)
+}
+
+// Get retrieves the documents the caller may read by their IDs
+func (s *authorizedStore) Get(ctx context.Context, ids []string) ([]interfaces.Document, error) {
+	documents, err := s.VectorStore.Get(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	return FilterDocuments(ctx, s.authorizer, documents)
+}
@@ -0,0 +1,265 @@
+package rbac
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+	"github.com/run-bigpig/llm-agent/pkg/runctx"
+)
+
+// Actions sent in the input of OPA policy decisions
+const (
+	ActionRunAgent     = "run_agent"
+	ActionUseTool      = "use_tool"
+	ActionCallTool     = "call_tool"
+	ActionReadDocument = "read_document"
+)
+
+// PolicyInput is the input document of an OPA policy decision
+type PolicyInput struct {
+	Action         string                 `json:"action"`
+	OrgID          string                 `json:"org,omitempty"`
+	UserID         string                 `json:"user,omitempty"`
+	Roles          []string               `json:"roles,omitempty"`
+	ConversationID string                 `json:"conversation_id,omitempty"`
+	RequestID      string                 `json:"request_id,omitempty"`
+	Tags           map[string]string      `json:"tags,omitempty"`
+	Agent          string                 `json:"agent,omitempty"`
+	Tool           string                 `json:"tool,omitempty"`
+	Arguments      interface{}            `json:"arguments,omitempty"`
+	Document       *PolicyDocument        `json:"document,omitempty"`
+	Extra          map[string]interface{} `json:"extra,omitempty"`
+}
+
+// PolicyDocument describes a document in a read_document decision. The
+// content is left out.
+type PolicyDocument struct {
+	ID       string                 `json:"id"`
+	Metadata map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// PolicyEvaluator evaluates an OPA decision, identified by its path such as
+// "agents/allow", for an input and returns whether it allows the action. It
+// is implemented by OPAClient for a remote OPA server; to evaluate policies
+// in-process, implement it with a prepared rego query.
+type PolicyEvaluator interface {
+	Evaluate(ctx context.Context, path string, input PolicyInput) (bool, error)
+}
+
+// OPA authorizes agents, tools, tool calls and document reads by consulting
+// OPA policies. It implements interfaces.Authorizer,
+// interfaces.ToolCallAuthorizer and interfaces.DocumentAuthorizer, so it can
+// be passed to agent.WithAuthorizer and WrapVectorStore. Every decision gets
+// the caller's organization, user and roles from the context.
+type OPA struct {
+	evaluator    PolicyEvaluator
+	agentPath    string
+	toolPath     string
+	toolCallPath string
+	documentPath string
+	extra        func(ctx context.Context) map[string]interface{}
+}
+
+// OPAOption configures an OPA authorizer
+type OPAOption func(*OPA)
+
+// WithAgentDecision sets the decision path for running agents. Defaults to
+// "agent/authz/allow".
+func WithAgentDecision(path string) OPAOption {
+	return func(o *OPA) {
+		o.agentPath = path
+	}
+}
+
+// WithToolDecision sets the decision path for offering tools to the LLM.
+// Defaults to "agent/authz/allow".
+func WithToolDecision(path string) OPAOption {
+	return func(o *OPA) {
+		o.toolPath = path
+	}
+}
+
+// WithToolCallDecision sets the decision path for tool calls, whose input
+// includes the arguments. Defaults to "agent/authz/allow".
+func WithToolCallDecision(path string) OPAOption {
+	return func(o *OPA) {
+		o.toolCallPath = path
+	}
+}
+
+// WithDocumentDecision sets the decision path for reading documents.
+// Defaults to "agent/authz/allow".
+func WithDocumentDecision(path string) OPAOption {
+	return func(o *OPA) {
+		o.documentPath = path
+	}
+}
+
+// WithPolicyInput adds extra fields from the context to the input of every
+// decision, under "extra"
+func WithPolicyInput(extra func(ctx context.Context) map[string]interface{}) OPAOption {
+	return func(o *OPA) {
+		o.extra = extra
+	}
+}
+
+// NewOPA creates an authorizer that consults the evaluator. All actions use
+// the decision "agent/authz/allow" unless configured otherwise; policies can
+// tell them apart by input.action.
+func NewOPA(evaluator PolicyEvaluator, options ...OPAOption) *OPA {
+	o := &OPA{
+		evaluator:    evaluator,
+		agentPath:    "agent/authz/allow",
+		toolPath:     "agent/authz/allow",
+		toolCallPath: "agent/authz/allow",
+		documentPath: "agent/authz/allow",
+	}
+	for _, option := range options {
+		option(o)
+	}
+	return o
+}
+
+// CanRunAgent reports whether the policy allows the caller to run the agent
+func (o *OPA) CanRunAgent(ctx context.Context, agentName string) (bool, error) {
+	input := o.input(ctx, ActionRunAgent)
+	input.Agent = agentName
+	return o.evaluator.Evaluate(ctx, o.agentPath, input)
+}
+
+// CanUseTool reports whether the policy allows the caller to use the tool
+func (o *OPA) CanUseTool(ctx context.Context, toolName string) (bool, error) {
+	input := o.input(ctx, ActionUseTool)
+	input.Tool = toolName
+	return o.evaluator.Evaluate(ctx, o.toolPath, input)
+}
+
+// CanCallTool reports whether the policy allows the caller to call the tool
+// with the arguments. JSON arguments are passed as a JSON value, anything
+// else as a string.
+func (o *OPA) CanCallTool(ctx context.Context, toolName, args string) (bool, error) {
+	input := o.input(ctx, ActionCallTool)
+	input.Tool = toolName
+	var arguments interface{}
+	if err := json.Unmarshal([]byte(args), &arguments); err == nil {
+		input.Arguments = arguments
+	} else if args != "" {
+		input.Arguments = args
+	}
+	return o.evaluator.Evaluate(ctx, o.toolCallPath, input)
+}
+
+// CanReadDocument reports whether the policy allows the caller to read the
+// document
+func (o *OPA) CanReadDocument(ctx context.Context, document interfaces.Document) (bool, error) {
+	input := o.input(ctx, ActionReadDocument)
+	input.Document = &PolicyDocument{ID: document.ID, Metadata: document.Metadata}
+	return o.evaluator.Evaluate(ctx, o.documentPath, input)
+}
+
+// input returns the input of a decision with the caller from the context
+func (o *OPA) input(ctx context.Context, action string) PolicyInput {
+	identity := IdentityFromContext(ctx)
+	input := PolicyInput{
+		Action:         action,
+		OrgID:          identity.OrgID,
+		UserID:         identity.UserID,
+		Roles:          identity.Roles,
+		ConversationID: runctx.ConversationID(ctx),
+		RequestID:      runctx.RequestID(ctx),
+		Tags:           runctx.Tags(ctx),
+	}
+	if o.extra != nil {
+		input.Extra = o.extra(ctx)
+	}
+	return input
+}
+
+// OPAClient evaluates decisions with the data API of an OPA server
+type OPAClient struct {
+	baseURL    string
+	httpClient *http.Client
+	headers    map[string]string
+}
+
+// OPAClientOption configures an OPAClient
+type OPAClientOption func(*OPAClient)
+
+// WithOPAHTTPClient sets the HTTP client
+func WithOPAHTTPClient(httpClient *http.Client) OPAClientOption {
+	return func(c *OPAClient) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithOPABearerToken authenticates requests with a bearer token
+func WithOPABearerToken(token string) OPAClientOption {
+	return func(c *OPAClient) {
+		c.headers["Authorization"] = "Bearer " + token
+	}
+}
+
+// NewOPAClient creates a client for the OPA server at baseURL, e.g.
+// "http://localhost:8181"
+func NewOPAClient(baseURL string, options ...OPAClientOption) *OPAClient {
+	c := &OPAClient{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: http.DefaultClient,
+		headers:    make(map[string]string),
+	}
+	for _, option := range options {
+		option(c)
+	}
+	return c
+}
+
+// Evaluate queries the decision at path. A decision that is undefined for
+// the input denies the action; one that is not a boolean is an error.
+func (c *OPAClient) Evaluate(ctx context.Context, path string, input PolicyInput) (bool, error) {
+	body, err := json.Marshal(map[string]interface{}{"input": input})
+	if err != nil {
+		return false, fmt.Errorf("failed to encode policy input: %w", err)
+	}
+
+	url := c.baseURL + "/v1/data/" + strings.TrimLeft(path, "/")
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return false, fmt.Errorf("failed to create policy request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range c.headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("failed to query policy %s: %w", path, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return false, fmt.Errorf("policy %s returned status %d: %s", path, resp.StatusCode, strings.TrimSpace(string(message)))
+	}
+
+	var decision struct {
+		Result *json.RawMessage `json:"result"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&decision); err != nil {
+		return false, fmt.Errorf("failed to decode decision of policy %s: %w", path, err)
+	}
+	if decision.Result == nil {
+		return false, nil
+	}
+	var allowed bool
+	if err := json.Unmarshal(*decision.Result, &allowed); err != nil {
+		return false, fmt.Errorf("decision of policy %s is not a boolean: %s", path, string(*decision.Result))
+	}
+	return allowed, nil
+}
@@ -0,0 +1,147 @@
+package rbac_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+	"github.com/run-bigpig/llm-agent/pkg/rbac"
+	"github.com/run-bigpig/llm-agent/pkg/runctx"
+)
+
+// fakeOPA serves OPA's data API with a fixed policy: admins may do anything,
+// the shell tool may only run "ls", and documents marked confidential are
+// only readable within their organization
+func fakeOPA(t *testing.T, inputs *[]rbac.PolicyInput) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/data/undefined" {
+			w.Write([]byte(`{}`))
+			return
+		}
+		if r.URL.Path != "/v1/data/agent/authz/allow" {
+			http.Error(w, "unknown path", http.StatusNotFound)
+			return
+		}
+		var body struct {
+			Input rbac.PolicyInput `json:"input"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("failed to decode input: %v", err)
+		}
+		input := body.Input
+		*inputs = append(*inputs, input)
+
+		allowed := false
+		for _, role := range input.Roles {
+			allowed = allowed || role == "admin"
+		}
+		switch input.Action {
+		case rbac.ActionRunAgent:
+			allowed = allowed || input.Agent == "faq"
+		case rbac.ActionUseTool:
+			allowed = allowed || input.Tool == "shell"
+		case rbac.ActionCallTool:
+			args, _ := input.Arguments.(map[string]interface{})
+			allowed = allowed || args["command"] == "ls"
+		case rbac.ActionReadDocument:
+			allowed = input.Document.Metadata["confidential"] != true || input.Document.Metadata["org"] == input.OrgID
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"result": allowed})
+	}))
+}
+
+func TestOPA(t *testing.T) {
+	var inputs []rbac.PolicyInput
+	server := fakeOPA(t, &inputs)
+	defer server.Close()
+
+	opa := rbac.NewOPA(rbac.NewOPAClient(server.URL))
+	ctx := runctx.WithUserID(runctx.WithOrgID(context.Background(), "acme"), "bob")
+
+	allowed, err := opa.CanRunAgent(ctx, "faq")
+	if err != nil || !allowed {
+		t.Errorf("expected faq to be allowed, got %v (%v)", allowed, err)
+	}
+	if allowed, _ := opa.CanRunAgent(ctx, "admin-console"); allowed {
+		t.Error("expected admin-console to be denied")
+	}
+	if allowed, _ := opa.CanRunAgent(rbac.WithRoles(ctx, "admin"), "admin-console"); !allowed {
+		t.Error("expected admins to run admin-console")
+	}
+	if inputs[0].OrgID != "acme" || inputs[0].UserID != "bob" || inputs[0].Action != rbac.ActionRunAgent {
+		t.Errorf("unexpected policy input: %+v", inputs[0])
+	}
+
+	// Tool calls are decided on their arguments
+	tool := rbac.Wrap(opa, &echoTool{})
+	if _, err := tool.Execute(ctx, `{"command":"ls"}`); err != nil {
+		t.Errorf("expected ls to be allowed, got %v", err)
+	}
+	if _, err := tool.Execute(ctx, `{"command":"rm -rf /"}`); !errors.Is(err, rbac.ErrDenied) {
+		t.Errorf("expected ErrDenied, got %v", err)
+	}
+
+	// Documents are filtered by the policy
+	documents, err := rbac.FilterDocuments(ctx, opa, []interfaces.Document{
+		{ID: "public"},
+		{ID: "ours", Metadata: map[string]interface{}{"confidential": true, "org": "acme"}},
+		{ID: "theirs", Metadata: map[string]interface{}{"confidential": true, "org": "globex"}},
+	})
+	if err != nil {
+		t.Fatalf("failed to filter documents: %v", err)
+	}
+	if len(documents) != 2 || documents[0].ID != "public" || documents[1].ID != "ours" {
+		t.Errorf("unexpected documents: %+v", documents)
+	}
+}
+
+func TestOPAClientDecisions(t *testing.T) {
+	var inputs []rbac.PolicyInput
+	server := fakeOPA(t, &inputs)
+	defer server.Close()
+	client := rbac.NewOPAClient(server.URL)
+
+	// Undefined decisions deny
+	allowed, err := client.Evaluate(context.Background(), "undefined", rbac.PolicyInput{Action: rbac.ActionUseTool})
+	if err != nil || allowed {
+		t.Errorf("expected an undefined decision to deny, got %v (%v)", allowed, err)
+	}
+
+	// Server errors are returned
+	if _, err := client.Evaluate(context.Background(), "missing", rbac.PolicyInput{}); err == nil {
+		t.Error("expected an error for an unknown decision path")
+	}
+}
+
+// searchStore returns fixed search results
+type searchStore struct {
+	interfaces.VectorStore
+	results []interfaces.SearchResult
+}
+
+func (s *searchStore) Search(ctx context.Context, query string, limit int, options ...interfaces.SearchOption) ([]interfaces.SearchResult, error) {
+	return s.results, nil
+}
+
+func TestWrapVectorStore(t *testing.T) {
+	var inputs []rbac.PolicyInput
+	server := fakeOPA(t, &inputs)
+	defer server.Close()
+
+	store := rbac.WrapVectorStore(rbac.NewOPA(rbac.NewOPAClient(server.URL)), &searchStore{results: []interfaces.SearchResult{
+		{Document: interfaces.Document{ID: "public"}},
+		{Document: interfaces.Document{ID: "theirs", Metadata: map[string]interface{}{"confidential": true, "org": "globex"}}},
+	}})
+
+	results, err := store.Search(runctx.WithOrgID(context.Background(), "acme"), "pricing", 5)
+	if err != nil {
+		t.Fatalf("failed to search: %v", err)
+	}
+	if len(results) != 1 || results[0].Document.ID != "public" {
+		t.Errorf("unexpected results: %+v", results)
+	}
+}
@@ -0,0 +1,122 @@
+package rbac
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Permissions lists the agents and tools a role may use. Entries are names or
+// glob patterns such as "research-*"; "*" matches everything.
+type Permissions struct {
+	Agents []string `yaml:"agents"`
+	Tools  []string `yaml:"tools"`
+}
+
+// Policy is an authorizer that grants permissions to roles. A caller's roles
+// are those in the context, those assigned to its user ID and the default
+// roles. Anything not granted to one of these roles is denied.
+type Policy struct {
+	Roles        map[string]Permissions `yaml:"roles"`
+	Users        map[string][]string    `yaml:"users"`
+	DefaultRoles []string               `yaml:"default_roles"`
+}
+
+// LoadPolicy loads a policy from a YAML file:
+//
+//	roles:
+//	  admin:
+//	    agents: ["*"]
+//	    tools: ["*"]
+//	  analyst:
+//	    agents: ["research-*"]
+//	    tools: ["websearch", "calculator"]
+//	users:
+//	  alice: [admin]
+//	default_roles: [analyst]
+func LoadPolicy(filePath string) (*Policy, error) {
+	data, err := os.ReadFile(filePath) // #nosec G304 - The policy file is chosen by the application
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policy file: %w", err)
+	}
+	return ParsePolicy(data)
+}
+
+// ParsePolicy parses and validates a YAML policy
+func ParsePolicy(data []byte) (*Policy, error) {
+	var policy Policy
+	if err := yaml.Unmarshal(data, &policy); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal policy: %w", err)
+	}
+	if err := policy.Validate(); err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+// Validate checks that all referenced roles exist and all patterns are valid
+func (p *Policy) Validate() error {
+	for role, permissions := range p.Roles {
+		for _, pattern := range append(append([]string{}, permissions.Agents...), permissions.Tools...) {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("role %s has invalid pattern %q: %w", role, pattern, err)
+			}
+		}
+	}
+	for user, roles := range p.Users {
+		for _, role := range roles {
+			if _, ok := p.Roles[role]; !ok {
+				return fmt.Errorf("user %s has unknown role %s", user, role)
+			}
+		}
+	}
+	for _, role := range p.DefaultRoles {
+		if _, ok := p.Roles[role]; !ok {
+			return fmt.Errorf("unknown default role %s", role)
+		}
+	}
+	return nil
+}
+
+// CanRunAgent reports whether one of the caller's roles may run the agent
+func (p *Policy) CanRunAgent(ctx context.Context, agentName string) (bool, error) {
+	return p.allowed(ctx, agentName, func(permissions Permissions) []string {
+		return permissions.Agents
+	}), nil
+}
+
+// CanUseTool reports whether one of the caller's roles may use the tool
+func (p *Policy) CanUseTool(ctx context.Context, toolName string) (bool, error) {
+	return p.allowed(ctx, toolName, func(permissions Permissions) []string {
+		return permissions.Tools
+	}), nil
+}
+
+// allowed reports whether a pattern granted to one of the caller's roles matches name
+func (p *Policy) allowed(ctx context.Context, name string, patterns func(Permissions) []string) bool {
+	for _, role := range p.roles(ctx) {
+		permissions, ok := p.Roles[role]
+		if !ok {
+			continue
+		}
+		for _, pattern := range patterns(permissions) {
+			if matched, _ := path.Match(pattern, name); matched {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// roles returns the roles of the caller in the context
+func (p *Policy) roles(ctx context.Context) []string {
+	identity := IdentityFromContext(ctx)
+	roles := append([]string{}, identity.Roles...)
+	if identity.UserID != "" {
+		roles = append(roles, p.Users[identity.UserID]...)
+	}
+	return append(roles, p.DefaultRoles...)
+}
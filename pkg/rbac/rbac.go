@@ -0,0 +1,148 @@
+// Package rbac provides role-based authorization for agents and tools.
+package rbac
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+	"github.com/run-bigpig/llm-agent/pkg/runctx"
+)
+
+// ErrDenied is returned when the caller is not allowed to run an agent or use a tool
+var ErrDenied = errors.New("permission denied")
+
+type contextKey string
+
+const rolesKey contextKey = "roles"
+
+// Identity describes the caller of an agent or tool
+type Identity struct {
+	OrgID  string
+	UserID string
+	Roles  []string
+}
+
+// WithRoles returns a new context with the given roles for the caller
+func WithRoles(ctx context.Context, roles ...string) context.Context {
+	return context.WithValue(ctx, rolesKey, roles)
+}
+
+// GetRoles returns the roles of the caller from the context
+func GetRoles(ctx context.Context) []string {
+	roles, _ := ctx.Value(rolesKey).([]string)
+	return roles
+}
+
+// IdentityFromContext returns the organization, user and roles of the caller
+func IdentityFromContext(ctx context.Context) Identity {
+	return Identity{
+		OrgID:  runctx.OrgID(ctx),
+		UserID: runctx.UserID(ctx),
+		Roles:  GetRoles(ctx),
+	}
+}
+
+// AuthorizeAgent returns an error wrapping ErrDenied if the caller may not run the agent
+func AuthorizeAgent(ctx context.Context, authorizer interfaces.Authorizer, agentName string) error {
+	allowed, err := authorizer.CanRunAgent(ctx, agentName)
+	if err != nil {
+		return fmt.Errorf("failed to authorize agent %s: %w", agentName, err)
+	}
+	if !allowed {
+		return fmt.Errorf("%w: may not run agent %s", ErrDenied, agentName)
+	}
+	return nil
+}
+
+// AuthorizeTool returns an error wrapping ErrDenied if the caller may not use the tool
+func AuthorizeTool(ctx context.Context, authorizer interfaces.Authorizer, toolName string) error {
+	allowed, err := authorizer.CanUseTool(ctx, toolName)
+	if err != nil {
+		return fmt.Errorf("failed to authorize tool %s: %w", toolName, err)
+	}
+	if !allowed {
+		return fmt.Errorf("%w: may not use tool %s", ErrDenied, toolName)
+	}
+	return nil
+}
+
+// AuthorizeToolCall returns an error wrapping ErrDenied if the caller may not
+// call the tool with the arguments. Authorizers implementing
+// interfaces.ToolCallAuthorizer decide on the call; others on the tool.
+func AuthorizeToolCall(ctx context.Context, authorizer interfaces.Authorizer, toolName, args string) error {
+	callAuthorizer, ok := authorizer.(interfaces.ToolCallAuthorizer)
+	if !ok {
+		return AuthorizeTool(ctx, authorizer, toolName)
+	}
+	allowed, err := callAuthorizer.CanCallTool(ctx, toolName, args)
+	if err != nil {
+		return fmt.Errorf("failed to authorize call of tool %s: %w", toolName, err)
+	}
+	if !allowed {
+		return fmt.Errorf("%w: may not call tool %s with these arguments", ErrDenied, toolName)
+	}
+	return nil
+}
+
+// Wrap returns a tool that checks with the authorizer before every call
+func Wrap(authorizer interfaces.Authorizer, tool interfaces.Tool) interfaces.Tool {
+	return &authorizedTool{tool: tool, authorizer: authorizer}
+}
+
+// authorizedTool is a tool that checks authorization before running
+type authorizedTool struct {
+	tool       interfaces.Tool
+	authorizer interfaces.Authorizer
+}
+
+// Name returns the name of the tool
+func (t *authorizedTool) Name() string {
+	return t.tool.Name()
+}
+
+// Description returns a description of what the tool does
+func (t *authorizedTool) Description() string {
+	return t.tool.Description()
+}
+
+// Parameters returns the parameters that the tool accepts
+func (t *authorizedTool) Parameters() map[string]interfaces.ParameterSpec {
+	return t.tool.Parameters()
+}
+
+// Examples returns the example invocations of the wrapped tool
+func (t *authorizedTool) Examples() []interfaces.ToolExample {
+	return interfaces.ToolExamples(t.tool)
+}
+
+// Run executes the tool with the given input
+func (t *authorizedTool) Run(ctx context.Context, input string) (string, error) {
+	if err := AuthorizeToolCall(ctx, t.authorizer, t.tool.Name(), input); err != nil {
+		return "", err
+	}
+	return t.tool.Run(ctx, input)
+}
+
+// Execute executes the tool with the given arguments
+func (t *authorizedTool) Execute(ctx context.Context, args string) (string, error) {
+	if err := AuthorizeToolCall(ctx, t.authorizer, t.tool.Name(), args); err != nil {
+		return "", err
+	}
+	return t.tool.Execute(ctx, args)
+}
+
+// HasSideEffects reports whether the wrapped tool changes external systems
+func (t *authorizedTool) HasSideEffects(args string) bool {
+	return interfaces.HasSideEffects(t.tool, args)
+}
+
+// DryRun checks authorization and simulates a call of the wrapped tool, so
+// that a dry run shows the calls that would be denied
+func (t *authorizedTool) DryRun(ctx context.Context, args string) (string, error) {
+	if err := AuthorizeToolCall(ctx, t.authorizer, t.tool.Name(), args); err != nil {
+		return "", err
+	}
+	return interfaces.SimulateToolCall(ctx, t.tool, args)
+}
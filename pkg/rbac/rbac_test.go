@@ -0,0 +1,108 @@
+package rbac_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+	"github.com/run-bigpig/llm-agent/pkg/rbac"
+	"github.com/run-bigpig/llm-agent/pkg/runctx"
+)
+
+const testPolicy = `
+roles:
+  admin:
+    agents: ["*"]
+    tools: ["*"]
+  analyst:
+    agents: ["research-*"]
+    tools: ["websearch"]
+  viewer:
+    agents: ["faq"]
+users:
+  alice: [admin]
+  bob: [analyst]
+default_roles: [viewer]
+`
+
+type echoTool struct{}
+
+func (t *echoTool) Name() string                                    { return "shell" }
+func (t *echoTool) Description() string                             { return "Echoes its input" }
+func (t *echoTool) Parameters() map[string]interfaces.ParameterSpec { return nil }
+func (t *echoTool) Run(ctx context.Context, input string) (string, error) {
+	return input, nil
+}
+func (t *echoTool) Execute(ctx context.Context, args string) (string, error) {
+	return args, nil
+}
+
+func TestPolicy(t *testing.T) {
+	policy, err := rbac.ParsePolicy([]byte(testPolicy))
+	if err != nil {
+		t.Fatalf("failed to parse policy: %v", err)
+	}
+
+	alice := runctx.WithUserID(context.Background(), "alice")
+	bob := runctx.WithUserID(context.Background(), "bob")
+	anonymous := context.Background()
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		agent   string
+		tool    string
+		allowed bool
+	}{
+		{"admin runs any agent", alice, "ops", "", true},
+		{"admin uses any tool", alice, "", "shell", true},
+		{"analyst runs matching agent", bob, "research-web", "", true},
+		{"analyst cannot run other agents", bob, "ops", "", false},
+		{"analyst uses granted tool", bob, "", "websearch", true},
+		{"analyst cannot use other tools", bob, "", "shell", false},
+		{"default role applies to everyone", anonymous, "faq", "", true},
+		{"anonymous cannot use tools", anonymous, "", "websearch", false},
+		{"roles from context", rbac.WithRoles(anonymous, "analyst"), "", "websearch", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var allowed bool
+			if tt.agent != "" {
+				allowed, err = policy.CanRunAgent(tt.ctx, tt.agent)
+			} else {
+				allowed, err = policy.CanUseTool(tt.ctx, tt.tool)
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if allowed != tt.allowed {
+				t.Errorf("expected allowed=%v, got %v", tt.allowed, allowed)
+			}
+		})
+	}
+}
+
+func TestParsePolicyUnknownRole(t *testing.T) {
+	if _, err := rbac.ParsePolicy([]byte("users:\n  alice: [root]\n")); err == nil {
+		t.Error("expected an error for an unknown role")
+	}
+}
+
+func TestWrap(t *testing.T) {
+	policy, err := rbac.ParsePolicy([]byte(testPolicy))
+	if err != nil {
+		t.Fatalf("failed to parse policy: %v", err)
+	}
+	tool := rbac.Wrap(policy, &echoTool{})
+
+	if _, err := tool.Execute(runctx.WithUserID(context.Background(), "bob"), "ls"); !errors.Is(err, rbac.ErrDenied) {
+		t.Errorf("expected ErrDenied, got %v", err)
+	}
+
+	output, err := tool.Execute(runctx.WithUserID(context.Background(), "alice"), "ls")
+	if err != nil || output != "ls" {
+		t.Errorf("expected output ls, got %q (%v)", output, err)
+	}
+}
@@ -0,0 +1,109 @@
+package retrieval_test
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+	"github.com/run-bigpig/llm-agent/pkg/retrieval"
+)
+
+var now = time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+
+func result(id string, score float32, metadata map[string]interface{}) interfaces.SearchResult {
+	return interfaces.SearchResult{Document: interfaces.Document{ID: id, Metadata: metadata}, Score: score}
+}
+
+func TestScorer(t *testing.T) {
+	scorer := retrieval.NewScorer(
+		retrieval.WithRecency("updated_at", 24*time.Hour, 1),
+		retrieval.WithBoost(interfaces.Eq("source", "handbook"), 2),
+		retrieval.WithBoost(interfaces.Eq("draft", true), 0.5),
+		retrieval.WithClock(func() time.Time { return now }),
+	)
+
+	tests := []struct {
+		name     string
+		metadata map[string]interface{}
+		want     retrieval.Score
+	}{
+		{
+			name:     "new document",
+			metadata: map[string]interface{}{"updated_at": now},
+			want:     retrieval.Score{Similarity: 0.5, Recency: 1, Boost: 1, Total: 0.75},
+		},
+		{
+			name:     "one half-life old, as RFC 3339",
+			metadata: map[string]interface{}{"updated_at": now.Add(-24 * time.Hour).Format(time.RFC3339)},
+			want:     retrieval.Score{Similarity: 0.5, Recency: 0.5, Boost: 1, Total: 0.5},
+		},
+		{
+			name:     "two half-lives old, as Unix seconds",
+			metadata: map[string]interface{}{"updated_at": float64(now.Add(-48 * time.Hour).Unix())},
+			want:     retrieval.Score{Similarity: 0.5, Recency: 0.25, Boost: 1, Total: 0.375},
+		},
+		{
+			name:     "no timestamp",
+			metadata: map[string]interface{}{},
+			want:     retrieval.Score{Similarity: 0.5, Recency: 0, Boost: 1, Total: 0.25},
+		},
+		{
+			name:     "boosted and demoted",
+			metadata: map[string]interface{}{"updated_at": now.UnixMilli(), "source": "handbook", "draft": true},
+			want:     retrieval.Score{Similarity: 0.5, Recency: 1, Boost: 1, Total: 0.75},
+		},
+		{
+			name:     "boosted",
+			metadata: map[string]interface{}{"updated_at": now, "source": "handbook"},
+			want:     retrieval.Score{Similarity: 0.5, Recency: 1, Boost: 2, Total: 1.5},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := scorer.Score(result("doc", 0.5, tt.metadata))
+			if math.Abs(got.Similarity-tt.want.Similarity) > 1e-6 || math.Abs(got.Recency-tt.want.Recency) > 1e-6 ||
+				math.Abs(got.Boost-tt.want.Boost) > 1e-6 || math.Abs(got.Total-tt.want.Total) > 1e-6 {
+				t.Errorf("expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+// fakeStore returns fixed search results
+type fakeStore struct {
+	interfaces.VectorStore
+	results []interfaces.SearchResult
+	limit   int
+}
+
+func (s *fakeStore) Search(ctx context.Context, query string, limit int, options ...interfaces.SearchOption) ([]interfaces.SearchResult, error) {
+	s.limit = limit
+	return s.results, nil
+}
+
+func TestRetrieverSearch(t *testing.T) {
+	store := &fakeStore{results: []interfaces.SearchResult{
+		result("stale", 0.9, map[string]interface{}{"updated_at": now.AddDate(-1, 0, 0)}),
+		result("fresh", 0.8, map[string]interface{}{"updated_at": now.Add(-time.Hour)}),
+		result("official", 0.7, map[string]interface{}{"updated_at": now.AddDate(0, -1, 0), "source": "handbook"}),
+	}}
+	retriever := retrieval.NewRetriever(store, retrieval.NewScorer(
+		retrieval.WithRecency("updated_at", 7*24*time.Hour, 0.5),
+		retrieval.WithBoost(interfaces.Eq("source", "handbook"), 1.5),
+		retrieval.WithClock(func() time.Time { return now }),
+	), retrieval.WithCandidates(4))
+
+	results, err := retriever.Search(context.Background(), "query", 2)
+	if err != nil {
+		t.Fatalf("failed to search: %v", err)
+	}
+	if store.limit != 8 {
+		t.Errorf("expected 8 candidates to be fetched, got %d", store.limit)
+	}
+	if len(results) != 2 || results[0].Document.ID != "fresh" || results[1].Document.ID != "official" {
+		t.Errorf("expected fresh and official documents first, got %+v", results)
+	}
+}
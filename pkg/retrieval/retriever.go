@@ -0,0 +1,215 @@
+package retrieval
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/run-bigpig/llm-agent/pkg/embedding"
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+	"github.com/run-bigpig/llm-agent/pkg/logging"
+)
+
+// Retriever is a vector store whose searches are reranked by a Scorer. It
+// fetches more candidates than asked for, scores them and returns the best,
+// with the total score as each result's Score. Everything else is passed
+// through to the wrapped store, so a Retriever can be used wherever an
+// interfaces.VectorStore is expected, e.g. by memory.NewVectorStoreRetriever.
+type Retriever struct {
+	interfaces.VectorStore
+	scorer     *Scorer
+	candidates int
+	rewriters  []QueryRewriter
+	embedder   embedding.Client
+	sanitizer  interfaces.ContentSanitizer
+	logger     logging.Logger
+}
+
+// Option configures a Retriever
+type Option func(*Retriever)
+
+// WithCandidates sets how many results are fetched per result returned, so
+// that fresh or boosted documents just below the top can move up. Defaults
+// to 3.
+func WithCandidates(factor int) Option {
+	return func(r *Retriever) {
+		if factor > 0 {
+			r.candidates = factor
+		}
+	}
+}
+
+// WithQueryRewriter rewrites the query before searching, e.g. with MultiQuery
+// or HyDE. It can be given several times; the searches of all rewriters are
+// run and merged. If a rewriter fails, the error is logged and the original
+// query is searched.
+func WithQueryRewriter(rewriter QueryRewriter) Option {
+	return func(r *Retriever) {
+		r.rewriters = append(r.rewriters, rewriter)
+	}
+}
+
+// WithEmbedder embeds queries that are written like documents, such as HyDE
+// passages, as documents and searches by vector. Without it, every query is
+// passed to the store's Search.
+func WithEmbedder(embedder embedding.Client) Option {
+	return func(r *Retriever) {
+		r.embedder = embedder
+	}
+}
+
+// WithSanitizer passes the content of the returned documents through a
+// sanitizer, e.g. a guardrails.InjectionScanner that neutralizes instructions
+// hidden in them, before it can reach a prompt
+func WithSanitizer(sanitizer interfaces.ContentSanitizer) Option {
+	return func(r *Retriever) {
+		r.sanitizer = sanitizer
+	}
+}
+
+// WithLogger sets the logger
+func WithLogger(logger logging.Logger) Option {
+	return func(r *Retriever) {
+		r.logger = logger
+	}
+}
+
+// NewRetriever wraps a vector store so that its searches are scored by scorer
+func NewRetriever(store interfaces.VectorStore, scorer *Scorer, options ...Option) *Retriever {
+	r := &Retriever{
+		VectorStore: store,
+		scorer:      scorer,
+		candidates:  3,
+		logger:      logging.New(),
+	}
+	for _, option := range options {
+		option(r)
+	}
+	return r
+}
+
+// Search rewrites the query, searches the store and reranks the results
+func (r *Retriever) Search(ctx context.Context, query string, limit int, options ...interfaces.SearchOption) ([]interfaces.SearchResult, error) {
+	queries := r.rewrite(ctx, query)
+	if len(queries) == 1 && !queries[0].Document {
+		results, err := r.VectorStore.Search(ctx, query, limit*r.candidates, options...)
+		if err != nil {
+			return nil, err
+		}
+		return r.sanitize(ctx, r.Rerank(results, limit))
+	}
+
+	// Run all searches and keep each document's best similarity
+	var mu sync.Mutex
+	best := make(map[string]interfaces.SearchResult)
+	var order []string
+	group, groupCtx := errgroup.WithContext(ctx)
+	for _, q := range queries {
+		group.Go(func() error {
+			results, err := r.search(groupCtx, q, limit*r.candidates, options)
+			if err != nil {
+				return err
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			for _, result := range results {
+				previous, seen := best[result.Document.ID]
+				if !seen {
+					order = append(order, result.Document.ID)
+				}
+				if !seen || result.Score > previous.Score {
+					best[result.Document.ID] = result
+				}
+			}
+			return nil
+		})
+	}
+	if err := group.Wait(); err != nil {
+		return nil, err
+	}
+
+	merged := make([]interfaces.SearchResult, len(order))
+	for i, id := range order {
+		merged[i] = best[id]
+	}
+	return r.sanitize(ctx, r.Rerank(merged, limit))
+}
+
+// rewrite returns the searches for a query: the queries of all rewriters
+// without repeats, or just the query without rewriters
+func (r *Retriever) rewrite(ctx context.Context, query string) []Query {
+	queries := []Query{{Text: query}}
+	seen := map[Query]bool{queries[0]: true}
+	for _, rewriter := range r.rewriters {
+		rewritten, err := rewriter.Rewrite(ctx, query)
+		if err != nil {
+			r.logger.Warn(ctx, "Failed to rewrite query, searching the original", map[string]interface{}{
+				"error": err.Error(),
+			})
+			continue
+		}
+		for _, q := range rewritten {
+			if !seen[q] {
+				seen[q] = true
+				queries = append(queries, q)
+			}
+		}
+	}
+	return queries
+}
+
+// search runs one search of a rewritten query
+func (r *Retriever) search(ctx context.Context, q Query, limit int, options []interfaces.SearchOption) ([]interfaces.SearchResult, error) {
+	if !q.Document || r.embedder == nil {
+		return r.VectorStore.Search(ctx, q.Text, limit, options...)
+	}
+	vector, err := r.embedder.Embed(embedding.WithInputType(ctx, embedding.InputTypeDocument), q.Text)
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed query: %w", err)
+	}
+	return r.VectorStore.SearchByVector(ctx, vector, limit, options...)
+}
+
+// SearchByVector searches the store by vector and reranks the results
+func (r *Retriever) SearchByVector(ctx context.Context, vector []float32, limit int, options ...interfaces.SearchOption) ([]interfaces.SearchResult, error) {
+	results, err := r.VectorStore.SearchByVector(ctx, vector, limit*r.candidates, options...)
+	if err != nil {
+		return nil, err
+	}
+	return r.sanitize(ctx, r.Rerank(results, limit))
+}
+
+// sanitize sanitizes the content of the results if a sanitizer is set
+func (r *Retriever) sanitize(ctx context.Context, results []interfaces.SearchResult) ([]interfaces.SearchResult, error) {
+	if r.sanitizer == nil {
+		return results, nil
+	}
+	for i := range results {
+		content, err := r.sanitizer.Sanitize(ctx, results[i].Document.Content)
+		if err != nil {
+			return nil, fmt.Errorf("failed to sanitize document %s: %w", results[i].Document.ID, err)
+		}
+		results[i].Document.Content = content
+	}
+	return results, nil
+}
+
+// Rerank scores results, sorts them by total score and returns at most limit
+// of them. A limit of 0 or less returns all of them.
+func (r *Retriever) Rerank(results []interfaces.SearchResult, limit int) []interfaces.SearchResult {
+	reranked := make([]interfaces.SearchResult, len(results))
+	for i, result := range results {
+		result.Score = float32(r.scorer.Score(result).Total)
+		reranked[i] = result
+	}
+	sort.SliceStable(reranked, func(i, j int) bool {
+		return reranked[i].Score > reranked[j].Score
+	})
+	if limit > 0 && len(reranked) > limit {
+		reranked = reranked[:limit]
+	}
+	return reranked
+}
@@ -0,0 +1,89 @@
+package retrieval
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+)
+
+// Query is one search run for a user query
+type Query struct {
+	// Text is the text to search for
+	Text string
+
+	// Document marks text written like a stored document rather than a
+	// question, such as a HyDE passage. With an embedder set by WithEmbedder,
+	// it is embedded as a document instead of as a query.
+	Document bool
+}
+
+// QueryRewriter turns a user query into the searches to run. The results of
+// all searches are merged before scoring.
+type QueryRewriter interface {
+	Rewrite(ctx context.Context, query string) ([]Query, error)
+}
+
+// QueryRewriterFunc adapts a function to a QueryRewriter
+type QueryRewriterFunc func(ctx context.Context, query string) ([]Query, error)
+
+// Rewrite calls f(ctx, query)
+func (f QueryRewriterFunc) Rewrite(ctx context.Context, query string) ([]Query, error) {
+	return f(ctx, query)
+}
+
+const multiQueryPrompt = `Write %d different search queries that could find documents answering the question below. Vary the wording and the angle, and spell out anything the question leaves vague. Write one query per line, without numbering or any other text.
+
+Question: %s`
+
+// MultiQuery expands the query into n alternative phrasings written by the
+// LLM, which helps vague or conversational questions match documents that use
+// different words. The original query is searched too.
+func MultiQuery(llm interfaces.LLM, n int) QueryRewriter {
+	if n <= 0 {
+		n = 3
+	}
+	return QueryRewriterFunc(func(ctx context.Context, query string) ([]Query, error) {
+		response, err := llm.Generate(ctx, fmt.Sprintf(multiQueryPrompt, n, query))
+		if err != nil {
+			return nil, fmt.Errorf("failed to expand query: %w", err)
+		}
+
+		queries := []Query{{Text: query}}
+		for _, line := range strings.Split(response, "\n") {
+			line = strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(line), "-*0123456789.) "))
+			if line == "" || strings.EqualFold(line, query) {
+				continue
+			}
+			queries = append(queries, Query{Text: line})
+			if len(queries) > n {
+				break
+			}
+		}
+		return queries, nil
+	})
+}
+
+const hydePrompt = `Write a short passage, as it would appear in a reference document, that answers the question below. Write only the passage. If you don't know the answer, write a plausible one; it is only used to find similar documents.
+
+Question: %s`
+
+// HyDE searches with a hypothetical document the LLM writes to answer the
+// query (Hypothetical Document Embeddings). The passage is closer to stored
+// documents than a short question is, even if its facts are wrong. The
+// original query is searched too.
+func HyDE(llm interfaces.LLM) QueryRewriter {
+	return QueryRewriterFunc(func(ctx context.Context, query string) ([]Query, error) {
+		passage, err := llm.Generate(ctx, fmt.Sprintf(hydePrompt, query))
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate hypothetical document: %w", err)
+		}
+
+		queries := []Query{{Text: query}}
+		if passage = strings.TrimSpace(passage); passage != "" {
+			queries = append(queries, Query{Text: passage, Document: true})
+		}
+		return queries, nil
+	})
+}
@@ -0,0 +1,130 @@
+package retrieval_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/run-bigpig/llm-agent/pkg/embedding"
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+	"github.com/run-bigpig/llm-agent/pkg/retrieval"
+)
+
+// fakeLLM answers prompts with a fixed response
+type fakeLLM struct {
+	interfaces.LLM
+	response string
+	err      error
+}
+
+func (l fakeLLM) Generate(ctx context.Context, prompt string, options ...interfaces.GenerateOption) (string, error) {
+	return l.response, l.err
+}
+
+// queryStore returns a result per query text and records the searches
+type queryStore struct {
+	interfaces.VectorStore
+	mu       sync.Mutex
+	queries  []string
+	vectors  int
+	byQuery  map[string][]interfaces.SearchResult
+	byVector []interfaces.SearchResult
+}
+
+func (s *queryStore) Search(ctx context.Context, query string, limit int, options ...interfaces.SearchOption) ([]interfaces.SearchResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.queries = append(s.queries, query)
+	return s.byQuery[query], nil
+}
+
+func (s *queryStore) SearchByVector(ctx context.Context, vector []float32, limit int, options ...interfaces.SearchOption) ([]interfaces.SearchResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.vectors++
+	return s.byVector, nil
+}
+
+// fakeEmbedder records the input type it was called with
+type fakeEmbedder struct {
+	embedding.Client
+	inputType string
+}
+
+func (e *fakeEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	e.inputType = embedding.InputType(ctx)
+	return []float32{1, 0}, nil
+}
+
+func TestMultiQuery(t *testing.T) {
+	llm := fakeLLM{response: "1. pricing of the pro plan\n- pro plan cost per month\n\nhow much is it\nenterprise discounts\n"}
+	queries, err := retrieval.MultiQuery(llm, 3).Rewrite(context.Background(), "how much is it")
+	if err != nil {
+		t.Fatalf("failed to rewrite: %v", err)
+	}
+
+	want := []string{"how much is it", "pricing of the pro plan", "pro plan cost per month", "enterprise discounts"}
+	if len(queries) != len(want) {
+		t.Fatalf("expected %v, got %+v", want, queries)
+	}
+	for i, q := range queries {
+		if q.Text != want[i] || q.Document {
+			t.Errorf("expected query %q, got %+v", want[i], q)
+		}
+	}
+}
+
+func TestRetrieverRewrite(t *testing.T) {
+	store := &queryStore{
+		byQuery: map[string][]interfaces.SearchResult{
+			"how much is it":          {result("faq", 0.4, nil)},
+			"pricing of the pro plan": {result("pricing", 0.8, nil), result("faq", 0.6, nil)},
+		},
+		byVector: []interfaces.SearchResult{result("pricing", 0.9, nil), result("plans", 0.7, nil)},
+	}
+	embedder := &fakeEmbedder{}
+	retriever := retrieval.NewRetriever(store, retrieval.NewScorer(),
+		retrieval.WithQueryRewriter(retrieval.MultiQuery(fakeLLM{response: "pricing of the pro plan"}, 1)),
+		retrieval.WithQueryRewriter(retrieval.HyDE(fakeLLM{response: "The pro plan costs $20 per user per month."})),
+		retrieval.WithEmbedder(embedder),
+	)
+
+	results, err := retriever.Search(context.Background(), "how much is it", 3)
+	if err != nil {
+		t.Fatalf("failed to search: %v", err)
+	}
+
+	// The original and the expanded query are searched once each, the HyDE
+	// passage by vector as a document
+	if len(store.queries) != 2 || store.vectors != 1 || embedder.inputType != embedding.InputTypeDocument {
+		t.Errorf("expected 2 text searches and 1 document vector search, got %v, %d, %q", store.queries, store.vectors, embedder.inputType)
+	}
+
+	// Each document keeps its best similarity
+	want := map[string]float32{"pricing": 0.9, "plans": 0.7, "faq": 0.6}
+	if len(results) != 3 || results[0].Document.ID != "pricing" {
+		t.Fatalf("expected pricing first, got %+v", results)
+	}
+	for _, r := range results {
+		if r.Score != want[r.Document.ID] {
+			t.Errorf("expected %s to score %v, got %v", r.Document.ID, want[r.Document.ID], r.Score)
+		}
+	}
+}
+
+func TestRetrieverRewriteFailure(t *testing.T) {
+	store := &queryStore{byQuery: map[string][]interfaces.SearchResult{"question": {result("doc", 0.5, nil)}}}
+	retriever := retrieval.NewRetriever(store, retrieval.NewScorer(),
+		retrieval.WithQueryRewriter(retrieval.HyDE(fakeLLM{err: errors.New("rate limited")})),
+	)
+
+	results, err := retriever.Search(context.Background(), "question", 1)
+	if err != nil {
+		t.Fatalf("expected the original query to be searched, got %v", err)
+	}
+	if len(results) != 1 || strings.Join(store.queries, ",") != "question" {
+		t.Errorf("expected one search for the original query, got %v and %+v", store.queries, results)
+	}
+}
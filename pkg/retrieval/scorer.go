@@ -0,0 +1,154 @@
+// Package retrieval improves vector store searches by rewriting queries and by
+// reranking results on similarity, document age and metadata boosts.
+package retrieval
+
+import (
+	"math"
+	"time"
+
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+)
+
+// Score is the breakdown of a result's score
+type Score struct {
+	// Similarity is the score the vector store returned
+	Similarity float64
+
+	// Recency is the age decay, from 1 for a new document towards 0 for old
+	// ones. It is 0 for documents without a timestamp.
+	Recency float64
+
+	// Boost is the product of the factors of all matching boosts
+	Boost float64
+
+	// Total is the weighted mean of similarity and recency, times the boost
+	Total float64
+}
+
+// boost multiplies the score of documents that match a filter
+type boost struct {
+	filter interfaces.Filter
+	factor float64
+}
+
+// Scorer scores search results by similarity, age and metadata
+type Scorer struct {
+	similarityWeight float64
+	recencyWeight    float64
+	halfLife         time.Duration
+	timeField        string
+	boosts           []boost
+	now              func() time.Time
+}
+
+// ScorerOption configures a Scorer
+type ScorerOption func(*Scorer)
+
+// WithSimilarityWeight sets the weight of the similarity in the total.
+// Defaults to 1.
+func WithSimilarityWeight(weight float64) ScorerOption {
+	return func(s *Scorer) {
+		if weight >= 0 {
+			s.similarityWeight = weight
+		}
+	}
+}
+
+// WithRecency weighs in the age of documents, read from a metadata field that
+// holds a time.Time, an RFC 3339 string or a Unix timestamp in seconds or
+// milliseconds. A document's recency halves every halfLife. With a weight of
+// 0.3 and the default similarity weight, the total is 70% similarity and 30%
+// recency.
+func WithRecency(field string, halfLife time.Duration, weight float64) ScorerOption {
+	return func(s *Scorer) {
+		if halfLife > 0 && weight >= 0 {
+			s.timeField = field
+			s.halfLife = halfLife
+			s.recencyWeight = weight
+		}
+	}
+}
+
+// WithBoost multiplies the total of documents matching the filter by factor,
+// e.g. WithBoost(interfaces.Eq("source", "handbook"), 1.5) to prefer
+// authoritative sources. A factor below 1 demotes documents. The factors of
+// all matching boosts are multiplied.
+func WithBoost(filter interfaces.Filter, factor float64) ScorerOption {
+	return func(s *Scorer) {
+		if factor >= 0 {
+			s.boosts = append(s.boosts, boost{filter: filter, factor: factor})
+		}
+	}
+}
+
+// WithClock sets the function that returns the current time, for tests
+func WithClock(now func() time.Time) ScorerOption {
+	return func(s *Scorer) {
+		s.now = now
+	}
+}
+
+// NewScorer creates a Scorer. Without options it scores by similarity alone.
+func NewScorer(options ...ScorerOption) *Scorer {
+	s := &Scorer{
+		similarityWeight: 1,
+		now:              time.Now,
+	}
+	for _, option := range options {
+		option(s)
+	}
+	return s
+}
+
+// Score scores a search result
+func (s *Scorer) Score(result interfaces.SearchResult) Score {
+	score := Score{Similarity: float64(result.Score), Boost: 1}
+
+	weights := s.similarityWeight
+	total := s.similarityWeight * score.Similarity
+	if s.recencyWeight > 0 {
+		if t, ok := metadataTime(result.Document.Metadata[s.timeField]); ok {
+			age := s.now().Sub(t)
+			if age < 0 {
+				age = 0
+			}
+			score.Recency = math.Pow(0.5, float64(age)/float64(s.halfLife))
+		}
+		weights += s.recencyWeight
+		total += s.recencyWeight * score.Recency
+	}
+	if weights > 0 {
+		total /= weights
+	}
+
+	for _, b := range s.boosts {
+		if b.filter.Matches(result.Document.Metadata) {
+			score.Boost *= b.factor
+		}
+	}
+	score.Total = total * score.Boost
+	return score
+}
+
+// metadataTime reads a time from a metadata value
+func metadataTime(value interface{}) (time.Time, bool) {
+	switch v := value.(type) {
+	case time.Time:
+		return v, true
+	case string:
+		t, err := time.Parse(time.RFC3339Nano, v)
+		return t, err == nil
+	case nil:
+		return time.Time{}, false
+	}
+
+	n, ok := interfaces.FilterNumber(value)
+	if !ok || n <= 0 {
+		return time.Time{}, false
+	}
+	if n > 1e12 {
+		// Milliseconds
+		return time.UnixMilli(int64(n)), true
+	}
+	return time.Unix(int64(n), 0), true
+}
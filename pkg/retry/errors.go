@@ -0,0 +1,46 @@
+package retry
+
+import (
+	"errors"
+	"time"
+)
+
+// PermanentError wraps an error that must not be retried
+type PermanentError struct {
+	Err error
+}
+
+// Error returns the wrapped error message
+func (e *PermanentError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the wrapped error
+func (e *PermanentError) Unwrap() error {
+	return e.Err
+}
+
+// Permanent marks the error as not retryable. The executor stops and returns
+// the wrapped error.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &PermanentError{Err: err}
+}
+
+// RetryAfterError is implemented by errors that know how long to wait before
+// the next attempt, such as rate limit errors with a Retry-After header
+type RetryAfterError interface {
+	error
+	RetryAfter() time.Duration
+}
+
+// retryAfter returns the delay requested by the error, if any
+func retryAfter(err error) (time.Duration, bool) {
+	var retryErr RetryAfterError
+	if errors.As(err, &retryErr) && retryErr.RetryAfter() > 0 {
+		return retryErr.RetryAfter(), true
+	}
+	return 0, false
+}
@@ -2,6 +2,7 @@ package retry
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"github.com/run-bigpig/llm-agent/pkg/logging"
@@ -47,6 +48,15 @@ func (e *Executor) Execute(ctx context.Context, operation func() error) error {
 				})
 				return nil
 			} else {
+				var permanent *PermanentError
+				if errors.As(err, &permanent) {
+					e.logger.Debug(ctx, "Operation failed with a permanent error", map[string]interface{}{
+						"attempt": attempt + 1,
+						"error":   err.Error(),
+					})
+					return permanent.Err
+				}
+
 				lastErr = err
 				attempt++
 
@@ -64,10 +74,19 @@ func (e *Executor) Execute(ctx context.Context, operation func() error) error {
 					nextInterval = e.policy.MaximumInterval
 				}
 
+				// Honor the delay requested by the server, e.g. for rate limits
+				delay := currentInterval
+				if after, ok := retryAfter(err); ok {
+					delay = after
+					if delay > e.policy.MaximumInterval {
+						delay = e.policy.MaximumInterval
+					}
+				}
+
 				e.logger.Debug(ctx, "Operation failed, scheduling retry", map[string]interface{}{
 					"attempt":          attempt,
 					"error":            err.Error(),
-					"current_interval": currentInterval,
+					"current_interval": delay,
 					"next_interval":    nextInterval,
 				})
 
@@ -78,7 +97,7 @@ func (e *Executor) Execute(ctx context.Context, operation func() error) error {
 						"error":   ctx.Err(),
 					})
 					return ctx.Err()
-				case <-time.After(currentInterval):
+				case <-time.After(delay):
 					currentInterval = nextInterval
 				}
 			}
@@ -0,0 +1,239 @@
+// Package runctx carries the identity of a run through a context: the
+// organization, user, conversation and request it belongs to, its attribution
+// tags, its deadline and whether it is a dry run.
+// The multitenancy and memory context helpers store their values here, so IDs
+// set through either package are visible to the others.
+package runctx
+
+import (
+	"context"
+	"maps"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type contextKey string
+
+const (
+	// OrgIDKey is the context key for the organization ID
+	OrgIDKey contextKey = "org_id"
+
+	// UserIDKey is the context key for the user ID
+	UserIDKey contextKey = "user_id"
+
+	// ConversationIDKey is the context key for the conversation ID
+	ConversationIDKey contextKey = "conversation_id"
+
+	// RequestIDKey is the context key for the request ID
+	RequestIDKey contextKey = "request_id"
+
+	// TraceIDKey is the context key for the ID of the trace the run is
+	// recorded in
+	TraceIDKey contextKey = "trace_id"
+
+	// TagsKey is the context key for the attribution tags
+	TagsKey contextKey = "tags"
+
+	// DryRunKey is the context key for the dry-run flag
+	DryRunKey contextKey = "dry_run"
+)
+
+// TagPrefix prefixes the names of attribution tags in Fields
+const TagPrefix = "tag."
+
+// RunContext bundles the identifiers of a run
+type RunContext struct {
+	OrgID          string
+	UserID         string
+	ConversationID string
+	RequestID      string
+	TraceID        string
+
+	// Tags attribute the run's usage and cost, e.g. to a team, feature or
+	// experiment
+	Tags map[string]string
+
+	// Deadline is the time by which the run must finish; zero means none
+	Deadline time.Time
+
+	// DryRun reports whether side effects of the run are simulated instead
+	// of applied
+	DryRun bool
+}
+
+// With returns a context carrying the non-empty fields of rc. If rc has a
+// deadline, the context is cancelled when it passes; the returned cancel
+// function must always be called to release its resources.
+func With(ctx context.Context, rc RunContext) (context.Context, context.CancelFunc) {
+	if rc.OrgID != "" {
+		ctx = WithOrgID(ctx, rc.OrgID)
+	}
+	if rc.UserID != "" {
+		ctx = WithUserID(ctx, rc.UserID)
+	}
+	if rc.ConversationID != "" {
+		ctx = WithConversationID(ctx, rc.ConversationID)
+	}
+	if rc.RequestID != "" {
+		ctx = WithRequestID(ctx, rc.RequestID)
+	}
+	if rc.TraceID != "" {
+		ctx = WithTraceID(ctx, rc.TraceID)
+	}
+	if len(rc.Tags) > 0 {
+		ctx = WithTags(ctx, rc.Tags)
+	}
+	if rc.DryRun {
+		ctx = WithDryRun(ctx, true)
+	}
+	if !rc.Deadline.IsZero() {
+		return context.WithDeadline(ctx, rc.Deadline)
+	}
+	return ctx, func() {}
+}
+
+// From returns the run identifiers, tags and deadline stored in the context
+func From(ctx context.Context) RunContext {
+	deadline, _ := ctx.Deadline()
+	return RunContext{
+		OrgID:          OrgID(ctx),
+		UserID:         UserID(ctx),
+		ConversationID: ConversationID(ctx),
+		RequestID:      RequestID(ctx),
+		TraceID:        TraceID(ctx),
+		Tags:           Tags(ctx),
+		Deadline:       deadline,
+		DryRun:         DryRun(ctx),
+	}
+}
+
+// Fields returns the non-empty identifiers keyed by their log and trace
+// attribute names (org_id, user_id, conversation_id, request_id and
+// trace_id), and the tags keyed by their name with the "tag." prefix
+func (rc RunContext) Fields() map[string]string {
+	fields := make(map[string]string, 5+len(rc.Tags))
+	if rc.OrgID != "" {
+		fields[string(OrgIDKey)] = rc.OrgID
+	}
+	if rc.UserID != "" {
+		fields[string(UserIDKey)] = rc.UserID
+	}
+	if rc.ConversationID != "" {
+		fields[string(ConversationIDKey)] = rc.ConversationID
+	}
+	if rc.RequestID != "" {
+		fields[string(RequestIDKey)] = rc.RequestID
+	}
+	if rc.TraceID != "" {
+		fields[string(TraceIDKey)] = rc.TraceID
+	}
+	for k, v := range rc.Tags {
+		fields[TagPrefix+k] = v
+	}
+	return fields
+}
+
+// WithOrgID returns a new context with the given organization ID
+func WithOrgID(ctx context.Context, orgID string) context.Context {
+	return context.WithValue(ctx, OrgIDKey, orgID)
+}
+
+// OrgID returns the organization ID from the context, or "" if there is none
+func OrgID(ctx context.Context) string {
+	return stringValue(ctx, OrgIDKey)
+}
+
+// WithUserID returns a new context with the given user ID
+func WithUserID(ctx context.Context, userID string) context.Context {
+	return context.WithValue(ctx, UserIDKey, userID)
+}
+
+// UserID returns the user ID from the context, or "" if there is none
+func UserID(ctx context.Context) string {
+	return stringValue(ctx, UserIDKey)
+}
+
+// WithConversationID returns a new context with the given conversation ID
+func WithConversationID(ctx context.Context, conversationID string) context.Context {
+	return context.WithValue(ctx, ConversationIDKey, conversationID)
+}
+
+// ConversationID returns the conversation ID from the context, or "" if there is none
+func ConversationID(ctx context.Context) string {
+	return stringValue(ctx, ConversationIDKey)
+}
+
+// WithRequestID returns a new context with the given request ID
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, RequestIDKey, requestID)
+}
+
+// RequestID returns the request ID from the context, or "" if there is none
+func RequestID(ctx context.Context) string {
+	return stringValue(ctx, RequestIDKey)
+}
+
+// WithTraceID returns a new context with the given trace ID. Tracers set it
+// when they start a trace, so that feedback can be tied to the trace later.
+func WithTraceID(ctx context.Context, traceID string) context.Context {
+	return context.WithValue(ctx, TraceIDKey, traceID)
+}
+
+// TraceID returns the trace ID from the context, or "" if there is none
+func TraceID(ctx context.Context) string {
+	return stringValue(ctx, TraceIDKey)
+}
+
+// WithTags returns a new context with the given attribution tags added to
+// any already in the context. A tag that is already set is overwritten.
+func WithTags(ctx context.Context, tags map[string]string) context.Context {
+	merged := Tags(ctx)
+	if merged == nil {
+		merged = make(map[string]string, len(tags))
+	}
+	maps.Copy(merged, tags)
+	return context.WithValue(ctx, TagsKey, merged)
+}
+
+// WithTag returns a new context with the given attribution tag added
+func WithTag(ctx context.Context, name, value string) context.Context {
+	return WithTags(ctx, map[string]string{name: value})
+}
+
+// Tags returns a copy of the attribution tags in the context, or nil if
+// there are none
+func Tags(ctx context.Context) map[string]string {
+	tags, _ := ctx.Value(TagsKey).(map[string]string)
+	if len(tags) == 0 {
+		return nil
+	}
+	return maps.Clone(tags)
+}
+
+// WithDryRun returns a new context in which tools that change external
+// systems simulate their calls instead of making them, for safe previews of
+// agents and plans
+func WithDryRun(ctx context.Context, dryRun bool) context.Context {
+	return context.WithValue(ctx, DryRunKey, dryRun)
+}
+
+// DryRun reports whether the context is a dry run
+func DryRun(ctx context.Context) bool {
+	dryRun, _ := ctx.Value(DryRunKey).(bool)
+	return dryRun
+}
+
+// EnsureRequestID returns the context unchanged if it already has a request
+// ID, and otherwise adds a newly generated one
+func EnsureRequestID(ctx context.Context) context.Context {
+	if RequestID(ctx) != "" {
+		return ctx
+	}
+	return WithRequestID(ctx, uuid.New().String())
+}
+
+func stringValue(ctx context.Context, key contextKey) string {
+	value, _ := ctx.Value(key).(string)
+	return value
+}
@@ -0,0 +1,95 @@
+package runctx_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/run-bigpig/llm-agent/pkg/memory"
+	"github.com/run-bigpig/llm-agent/pkg/multitenancy"
+	"github.com/run-bigpig/llm-agent/pkg/runctx"
+)
+
+func TestWith(t *testing.T) {
+	deadline := time.Now().Add(time.Minute)
+	ctx, cancel := runctx.With(context.Background(), runctx.RunContext{
+		OrgID:          "org-1",
+		UserID:         "user-1",
+		ConversationID: "conv-1",
+		RequestID:      "req-1",
+		Deadline:       deadline,
+	})
+	defer cancel()
+
+	rc := runctx.From(ctx)
+	if rc.OrgID != "org-1" || rc.UserID != "user-1" || rc.ConversationID != "conv-1" || rc.RequestID != "req-1" {
+		t.Errorf("unexpected run context: %+v", rc)
+	}
+	if !rc.Deadline.Equal(deadline) {
+		t.Errorf("expected deadline %v, got %v", deadline, rc.Deadline)
+	}
+
+	// Values are shared with the multitenancy and memory helpers
+	if orgID, err := multitenancy.GetOrgID(ctx); err != nil || orgID != "org-1" {
+		t.Errorf("expected multitenancy org ID org-1, got %q (%v)", orgID, err)
+	}
+	if conversationID, ok := memory.GetConversationID(ctx); !ok || conversationID != "conv-1" {
+		t.Errorf("expected memory conversation ID conv-1, got %q", conversationID)
+	}
+
+	ctx = multitenancy.WithOrgID(ctx, "org-2")
+	ctx = memory.WithConversationID(ctx, "conv-2")
+	if runctx.OrgID(ctx) != "org-2" || runctx.ConversationID(ctx) != "conv-2" {
+		t.Errorf("expected IDs set through other packages, got %+v", runctx.From(ctx))
+	}
+
+	fields := runctx.From(ctx).Fields()
+	if len(fields) != 4 || fields["user_id"] != "user-1" || fields["request_id"] != "req-1" {
+		t.Errorf("unexpected fields: %v", fields)
+	}
+}
+
+func TestEnsureRequestID(t *testing.T) {
+	ctx := runctx.EnsureRequestID(context.Background())
+	requestID := runctx.RequestID(ctx)
+	if requestID == "" {
+		t.Fatal("expected a generated request ID")
+	}
+	if got := runctx.RequestID(runctx.EnsureRequestID(ctx)); got != requestID {
+		t.Errorf("expected request ID %q to be kept, got %q", requestID, got)
+	}
+
+	if fields := runctx.From(context.Background()).Fields(); len(fields) != 0 {
+		t.Errorf("expected no fields for an empty context, got %v", fields)
+	}
+}
+
+func TestTraceID(t *testing.T) {
+	ctx, cancel := runctx.With(context.Background(), runctx.RunContext{TraceID: "trace-1"})
+	defer cancel()
+
+	if got := runctx.TraceID(ctx); got != "trace-1" {
+		t.Errorf("expected trace ID trace-1, got %q", got)
+	}
+	if fields := runctx.From(ctx).Fields(); fields["trace_id"] != "trace-1" {
+		t.Errorf("expected the trace ID in the fields, got %v", fields)
+	}
+}
+
+func TestTags(t *testing.T) {
+	ctx := runctx.WithTags(context.Background(), map[string]string{"team": "search", "feature": "autocomplete"})
+	child := runctx.WithTag(ctx, "team", "ranking")
+
+	if tags := runctx.Tags(ctx); tags["team"] != "search" {
+		t.Errorf("expected the parent's tags to be unchanged, got %v", tags)
+	}
+	tags := runctx.Tags(child)
+	if len(tags) != 2 || tags["team"] != "ranking" || tags["feature"] != "autocomplete" {
+		t.Errorf("unexpected tags: %v", tags)
+	}
+
+	fields := runctx.From(child).Fields()
+	if fields["tag.team"] != "ranking" || fields["tag.feature"] != "autocomplete" {
+		t.Errorf("expected the tags in the fields, got %v", fields)
+	}
+}
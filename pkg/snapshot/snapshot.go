@@ -0,0 +1,196 @@
+// Package snapshot compares rendered prompts, assembled messages and
+// formatted execution plans in tests against golden files, so that changes
+// to prompt assembly show up as reviewable diffs. Values that change from
+// run to run, like timestamps and IDs, are normalized before comparing.
+//
+// Snapshots are stored in testdata/snapshots of the package under test. Run
+// the tests with UPDATE_SNAPSHOTS=1 to create or update them:
+//
+//	UPDATE_SNAPSHOTS=1 go test ./pkg/agent/...
+package snapshot
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/run-bigpig/llm-agent/pkg/executionplan"
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+)
+
+// UpdateEnv is the environment variable that makes Match write snapshots
+// instead of comparing against them
+const UpdateEnv = "UPDATE_SNAPSHOTS"
+
+// Default normalizations, applied in order
+var (
+	timestampPattern = regexp.MustCompile(`\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[+-]\d{2}:?\d{2})?`)
+	uuidPattern      = regexp.MustCompile(`(?i)[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}`)
+)
+
+// options configures a comparison
+type options struct {
+	dir         string
+	normalizers []func(string) string
+	defaults    bool
+}
+
+// Option configures a comparison
+type Option func(*options)
+
+// WithDir stores snapshots in dir instead of testdata/snapshots
+func WithDir(dir string) Option {
+	return func(o *options) {
+		o.dir = dir
+	}
+}
+
+// WithNormalizer applies a function to the value before it is compared,
+// after the default normalizations
+func WithNormalizer(normalize func(string) string) Option {
+	return func(o *options) {
+		o.normalizers = append(o.normalizers, normalize)
+	}
+}
+
+// WithReplacement replaces the matches of a regular expression, e.g. a
+// duration or a generated file name, with a placeholder. The replacement may
+// refer to groups like regexp.ReplaceAllString.
+func WithReplacement(pattern, replacement string) Option {
+	re := regexp.MustCompile(pattern)
+	return WithNormalizer(func(s string) string {
+		return re.ReplaceAllString(s, replacement)
+	})
+}
+
+// WithoutDefaultNormalizers compares values without replacing timestamps and
+// UUIDs
+func WithoutDefaultNormalizers() Option {
+	return func(o *options) {
+		o.defaults = false
+	}
+}
+
+// Normalize applies the default normalizations: line endings become "\n",
+// trailing whitespace is removed from lines, RFC 3339 timestamps become
+// <TIMESTAMP> and UUIDs become <UUID>
+func Normalize(s string) string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t")
+	}
+	s = strings.Join(lines, "\n")
+	s = timestampPattern.ReplaceAllString(s, "<TIMESTAMP>")
+	return uuidPattern.ReplaceAllString(s, "<UUID>")
+}
+
+// Match compares got with the snapshot of the given name and reports a diff
+// if they differ. A missing snapshot fails the test unless UPDATE_SNAPSHOTS
+// is set, in which case the snapshot is written.
+func Match(t testing.TB, name, got string, opts ...Option) {
+	t.Helper()
+
+	o := options{dir: filepath.Join("testdata", "snapshots"), defaults: true}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.defaults {
+		got = Normalize(got)
+	}
+	for _, normalize := range o.normalizers {
+		got = normalize(got)
+	}
+	if !strings.HasSuffix(got, "\n") {
+		got += "\n"
+	}
+
+	path := filepath.Join(o.dir, fileName(name))
+	if os.Getenv(UpdateEnv) != "" {
+		if err := os.MkdirAll(o.dir, 0o755); err != nil {
+			t.Fatalf("failed to create snapshot directory: %v", err)
+			return
+		}
+		if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+			t.Fatalf("failed to write snapshot %s: %v", path, err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path) // #nosec G304 - the path is built from the snapshot name in a test
+	if os.IsNotExist(err) {
+		t.Fatalf("snapshot %s does not exist; run the test with %s=1 to create it", path, UpdateEnv)
+		return
+	}
+	if err != nil {
+		t.Fatalf("failed to read snapshot %s: %v", path, err)
+		return
+	}
+	if string(want) != got {
+		t.Errorf("snapshot %s does not match (-want +got); run the test with %s=1 if the change is intended:\n%s",
+			path, UpdateEnv, Diff(string(want), got))
+	}
+}
+
+// MatchPlan compares an execution plan, formatted as it is shown to users,
+// with a snapshot
+func MatchPlan(t testing.TB, name string, plan *executionplan.ExecutionPlan, opts ...Option) {
+	t.Helper()
+	Match(t, name, executionplan.FormatExecutionPlan(plan), opts...)
+}
+
+// MatchMessages compares a system prompt and conversation messages with a
+// snapshot
+func MatchMessages(t testing.TB, name, systemPrompt string, messages []interfaces.Message, opts ...Option) {
+	t.Helper()
+	Match(t, name, FormatMessages(systemPrompt, messages), opts...)
+}
+
+// MatchJSON compares the indented JSON encoding of a value with a snapshot
+func MatchJSON(t testing.TB, name string, v interface{}, opts ...Option) {
+	t.Helper()
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		t.Fatalf("failed to encode %s as JSON: %v", name, err)
+		return
+	}
+	Match(t, name, string(data), opts...)
+}
+
+// FormatMessages formats a system prompt and messages as sections headed by
+// their role, readable in diffs
+func FormatMessages(systemPrompt string, messages []interfaces.Message) string {
+	var sb strings.Builder
+	if systemPrompt != "" {
+		sb.WriteString("=== system ===\n")
+		sb.WriteString(strings.TrimSpace(systemPrompt))
+		sb.WriteString("\n")
+	}
+	for _, message := range messages {
+		if sb.Len() > 0 {
+			sb.WriteString("\n")
+		}
+		fmt.Fprintf(&sb, "=== %s ===\n", message.Role)
+		sb.WriteString(strings.TrimSpace(message.Content))
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// fileName turns a snapshot name, e.g. a test name with subtests, into a
+// file name
+func fileName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+			return r
+		default:
+			return '_'
+		}
+	}, name)
+	return name + ".snap"
+}
@@ -0,0 +1,99 @@
+package snapshot_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/run-bigpig/llm-agent/pkg/executionplan"
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+	"github.com/run-bigpig/llm-agent/pkg/snapshot"
+)
+
+// recordingT records failures instead of failing the test
+type recordingT struct {
+	testing.TB
+	failures []string
+}
+
+func (t *recordingT) Helper() {}
+
+func (t *recordingT) Errorf(format string, args ...interface{}) {
+	t.failures = append(t.failures, fmt.Sprintf(format, args...))
+}
+
+func (t *recordingT) Fatalf(format string, args ...interface{}) {
+	t.failures = append(t.failures, fmt.Sprintf(format, args...))
+}
+
+func TestMatch(t *testing.T) {
+	dir := t.TempDir()
+	rt := &recordingT{TB: t}
+
+	// A missing snapshot fails
+	snapshot.Match(rt, "TestMatch/prompt", "You are helpful.", snapshot.WithDir(dir))
+	require.Len(t, rt.failures, 1)
+	assert.Contains(t, rt.failures[0], "UPDATE_SNAPSHOTS=1")
+
+	// Updating writes it
+	t.Setenv(snapshot.UpdateEnv, "1")
+	rt.failures = nil
+	snapshot.Match(rt, "TestMatch/prompt", "You are helpful.", snapshot.WithDir(dir))
+	assert.Empty(t, rt.failures)
+	data, err := os.ReadFile(filepath.Join(dir, "TestMatch_prompt.snap"))
+	require.NoError(t, err)
+	assert.Equal(t, "You are helpful.\n", string(data))
+
+	// Matching values pass and changed values fail with a diff
+	t.Setenv(snapshot.UpdateEnv, "")
+	snapshot.Match(rt, "TestMatch/prompt", "You are helpful.", snapshot.WithDir(dir))
+	assert.Empty(t, rt.failures)
+	snapshot.Match(rt, "TestMatch/prompt", "You are very helpful.", snapshot.WithDir(dir))
+	require.Len(t, rt.failures, 1)
+	assert.Contains(t, rt.failures[0], "- You are helpful.\n+ You are very helpful.")
+}
+
+func TestNormalize(t *testing.T) {
+	got := snapshot.Normalize("Task ID: 0b7b3c1e-9f4e-4d0a-8c55-3e2d6f1a2b4c  \r\nCreated: 2024-06-01T12:30:45.123Z\nUpdated: 2024-06-01 12:30:45+02:00")
+	assert.Equal(t, "Task ID: <UUID>\nCreated: <TIMESTAMP>\nUpdated: <TIMESTAMP>", got)
+}
+
+func TestMatchWithReplacement(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv(snapshot.UpdateEnv, "1")
+	snapshot.Match(t, "duration", "took 1.5s", snapshot.WithDir(dir), snapshot.WithReplacement(`\d+(\.\d+)?m?s`, "<DURATION>"))
+	t.Setenv(snapshot.UpdateEnv, "")
+	snapshot.Match(t, "duration", "took 20ms", snapshot.WithDir(dir), snapshot.WithReplacement(`\d+(\.\d+)?m?s`, "<DURATION>"))
+}
+
+func TestMatchPlanAndMessages(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv(snapshot.UpdateEnv, "1")
+
+	plan := executionplan.NewExecutionPlan("Restart the API", []executionplan.ExecutionStep{
+		{ToolName: "kubectl", Description: "Restart the deployment", Input: `{"deployment":"api"}`},
+	})
+	snapshot.MatchPlan(t, "plan", plan, snapshot.WithDir(dir))
+	data, err := os.ReadFile(filepath.Join(dir, "plan.snap"))
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "Task ID: <UUID>\n")
+	assert.Contains(t, string(data), "## Step 1: Restart the deployment\n")
+
+	snapshot.MatchMessages(t, "messages", "You are an operator.", []interfaces.Message{
+		{Role: "user", Content: "Restart the API"},
+		{Role: "assistant", Content: "Done."},
+	}, snapshot.WithDir(dir))
+	data, err = os.ReadFile(filepath.Join(dir, "messages.snap"))
+	require.NoError(t, err)
+	assert.Equal(t, "=== system ===\nYou are an operator.\n\n=== user ===\nRestart the API\n\n=== assistant ===\nDone.\n", string(data))
+}
+
+func TestDiff(t *testing.T) {
+	want := "a\nb\nc\nd\ne\nf\ng\nh\ni\n"
+	got := "a\nb\nc\nd\ne\nF\ng\nh\ni\n"
+	assert.Equal(t, "  ...\n  c\n  d\n  e\n- f\n+ F\n  g\n  h\n  i", snapshot.Diff(want, got))
+}
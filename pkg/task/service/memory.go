@@ -7,6 +7,8 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/run-bigpig/llm-agent/pkg/crypto"
+	"github.com/run-bigpig/llm-agent/pkg/events"
 	"github.com/run-bigpig/llm-agent/pkg/interfaces"
 	"github.com/run-bigpig/llm-agent/pkg/logging"
 	"github.com/run-bigpig/llm-agent/pkg/task"
@@ -20,6 +22,8 @@ type InMemoryTaskService struct {
 	taskHistories map[string][]string
 	planner       interfaces.TaskPlanner
 	executor      interfaces.TaskExecutor
+	publisher     events.Publisher
+	encryptor     *crypto.Encryptor
 }
 
 // NewInMemoryTaskService creates a new in-memory task service
@@ -34,6 +38,32 @@ func NewInMemoryTaskService(logger logging.Logger, planner interfaces.TaskPlanne
 	}
 }
 
+// SetEventPublisher publishes an event whenever a task changes status.
+// Events are published while the task is locked, so wrap remote publishers
+// in events.NewAsyncPublisher.
+func (s *InMemoryTaskService) SetEventPublisher(publisher events.Publisher) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.publisher = publisher
+}
+
+// setStatus changes the status of a task and publishes the change. It must
+// be called with the lock held.
+func (s *InMemoryTaskService) setStatus(ctx context.Context, t *task.Task, status task.Status) {
+	previous := t.Status
+	t.Status = status
+	if s.publisher == nil || previous == status {
+		return
+	}
+	event := events.TaskStatusChanged(ctx, "task-service", t.ID, string(previous), string(status))
+	if err := s.publisher.Publish(ctx, event); err != nil {
+		s.logger.Error(ctx, "Failed to publish task status change", map[string]interface{}{
+			"task_id": t.ID,
+			"error":   err.Error(),
+		})
+	}
+}
+
 // CreateTask creates a new task
 func (s *InMemoryTaskService) CreateTask(ctx context.Context, req task.CreateTaskRequest) (*task.Task, error) {
 	taskID := uuid.New().String()
@@ -194,7 +224,7 @@ func (s *InMemoryTaskService) ApproveTaskPlan(ctx context.Context, taskID string
 
 	// If approved, start executing
 	if req.Approved {
-		t.Status = task.StatusExecuting
+		s.setStatus(ctx, t, task.StatusExecuting)
 		startTime := time.Now()
 		t.StartedAt = &startTime
 
@@ -209,7 +239,7 @@ func (s *InMemoryTaskService) ApproveTaskPlan(ctx context.Context, taskID string
 
 					// Update task status to failed
 					s.mutex.Lock()
-					t.Status = task.StatusFailed
+					s.setStatus(context.Background(), t, task.StatusFailed)
 					failedTime := time.Now()
 					t.CompletedAt = &failedTime
 					s.mutex.Unlock()
@@ -218,7 +248,7 @@ func (s *InMemoryTaskService) ApproveTaskPlan(ctx context.Context, taskID string
 		}
 	} else {
 		// If rejected, replan with feedback
-		t.Status = task.StatusPlanning
+		s.setStatus(ctx, t, task.StatusPlanning)
 		if s.planner != nil {
 			go s.replanTask(context.Background(), t, req.Feedback)
 		}
@@ -339,7 +369,7 @@ func (s *InMemoryTaskService) UpdateTask(ctx context.Context, taskID string, upd
 
 		case "update_status":
 			// Update task status
-			t.Status = task.Status(update.Status)
+			s.setStatus(ctx, t, task.Status(update.Status))
 
 			// Add log entry
 			logEntry := task.LogEntry{
@@ -403,7 +433,7 @@ func (s *InMemoryTaskService) AddTaskLog(ctx context.Context, taskID string, mes
 // planTask handles the planning of a task
 func (s *InMemoryTaskService) planTask(ctx context.Context, t *task.Task) {
 	s.mutex.Lock()
-	t.Status = task.StatusPlanning
+	s.setStatus(ctx, t, task.StatusPlanning)
 	s.mutex.Unlock()
 
 	s.logger.Info(ctx, "Starting task planning", map[string]interface{}{
@@ -429,7 +459,7 @@ func (s *InMemoryTaskService) planTask(ctx context.Context, t *task.Task) {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
-	t.Status = task.StatusApproval
+	s.setStatus(ctx, t, task.StatusApproval)
 	t.UpdatedAt = time.Now()
 
 	// Add log entry
@@ -448,7 +478,7 @@ func (s *InMemoryTaskService) handlePlanningFailure(t *task.Task, err error) {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
-	t.Status = task.StatusFailed
+	s.setStatus(context.Background(), t, task.StatusFailed)
 	t.UpdatedAt = time.Now()
 
 	// Add log entry
@@ -465,7 +495,7 @@ func (s *InMemoryTaskService) handlePlanningFailure(t *task.Task, err error) {
 // replanTask handles the replanning of a task with feedback
 func (s *InMemoryTaskService) replanTask(ctx context.Context, t *task.Task, feedback string) {
 	s.mutex.Lock()
-	t.Status = task.StatusPlanning
+	s.setStatus(ctx, t, task.StatusPlanning)
 	s.mutex.Unlock()
 
 	s.logger.Info(ctx, "Replanning task with feedback", map[string]interface{}{
@@ -492,7 +522,7 @@ func (s *InMemoryTaskService) replanTask(ctx context.Context, t *task.Task, feed
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
-	t.Status = task.StatusApproval
+	s.setStatus(ctx, t, task.StatusApproval)
 	t.UpdatedAt = time.Now()
 
 	// Add log entry
@@ -0,0 +1,84 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/run-bigpig/llm-agent/pkg/crypto"
+	"github.com/run-bigpig/llm-agent/pkg/task"
+)
+
+// snapshot is the serialized form of the tasks of an InMemoryTaskService
+type snapshot struct {
+	Tasks         map[string]*task.Task `json:"tasks"`
+	TaskHistories map[string][]string   `json:"task_histories"`
+}
+
+// SetEncryptor encrypts the snapshots written by SaveSnapshot with the key
+// for the context, so that persisted tasks are encrypted at rest
+func (s *InMemoryTaskService) SetEncryptor(encryptor *crypto.Encryptor) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.encryptor = encryptor
+}
+
+// SaveSnapshot writes all tasks to w, e.g. to keep them across restarts.
+// Snapshots are encrypted if an encryptor is set.
+func (s *InMemoryTaskService) SaveSnapshot(ctx context.Context, w io.Writer) error {
+	s.mutex.RLock()
+	data, err := json.Marshal(snapshot{Tasks: s.tasks, TaskHistories: s.taskHistories})
+	encryptor := s.encryptor
+	s.mutex.RUnlock()
+	if err != nil {
+		return fmt.Errorf("failed to marshal tasks: %w", err)
+	}
+
+	if encryptor != nil {
+		if data, err = encryptor.Encrypt(ctx, data); err != nil {
+			return fmt.Errorf("failed to encrypt tasks: %w", err)
+		}
+	}
+
+	if _, err := w.Write(data); err != nil {
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	return nil
+}
+
+// LoadSnapshot replaces the tasks with those in a snapshot written by
+// SaveSnapshot. Encrypted snapshots need the encryptor they were written with.
+func (s *InMemoryTaskService) LoadSnapshot(ctx context.Context, r io.Reader) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("failed to read snapshot: %w", err)
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var loaded snapshot
+	if err := json.Unmarshal(data, &loaded); err != nil || loaded.Tasks == nil {
+		if s.encryptor == nil {
+			return fmt.Errorf("snapshot is encrypted or invalid and no encryptor is set")
+		}
+		if data, err = s.encryptor.Decrypt(ctx, data); err != nil {
+			return fmt.Errorf("failed to decrypt tasks: %w", err)
+		}
+		loaded = snapshot{}
+		if err := json.Unmarshal(data, &loaded); err != nil {
+			return fmt.Errorf("failed to unmarshal tasks: %w", err)
+		}
+	}
+
+	s.tasks = loaded.Tasks
+	if s.tasks == nil {
+		s.tasks = make(map[string]*task.Task)
+	}
+	s.taskHistories = loaded.TaskHistories
+	if s.taskHistories == nil {
+		s.taskHistories = make(map[string][]string)
+	}
+	return nil
+}
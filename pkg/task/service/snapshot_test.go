@@ -0,0 +1,75 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/run-bigpig/llm-agent/pkg/crypto"
+	"github.com/run-bigpig/llm-agent/pkg/logging"
+	"github.com/run-bigpig/llm-agent/pkg/multitenancy"
+	"github.com/run-bigpig/llm-agent/pkg/task"
+)
+
+func TestSnapshotEncryption(t *testing.T) {
+	keyManager, err := crypto.NewLocalKeyManager(bytes.Repeat([]byte("k"), 32))
+	if err != nil {
+		t.Fatalf("Failed to create key manager: %v", err)
+	}
+	ctx := multitenancy.WithOrgID(context.Background(), "org-1")
+
+	svc := NewInMemoryTaskService(logging.New(), nil, nil)
+	svc.SetEncryptor(crypto.NewEncryptor(keyManager))
+	created, err := svc.CreateTask(ctx, task.CreateTaskRequest{Description: "Rotate the database password", UserID: "user-1"})
+	if err != nil {
+		t.Fatalf("Failed to create task: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := svc.SaveSnapshot(ctx, &buf); err != nil {
+		t.Fatalf("Failed to save snapshot: %v", err)
+	}
+	if bytes.Contains(buf.Bytes(), []byte("database password")) {
+		t.Error("Expected the snapshot to be encrypted")
+	}
+
+	// Without the encryptor the snapshot can't be loaded
+	if err := NewInMemoryTaskService(logging.New(), nil, nil).LoadSnapshot(ctx, bytes.NewReader(buf.Bytes())); err == nil {
+		t.Error("Expected loading an encrypted snapshot without an encryptor to fail")
+	}
+
+	restored := NewInMemoryTaskService(logging.New(), nil, nil)
+	restored.SetEncryptor(crypto.NewEncryptor(keyManager))
+	if err := restored.LoadSnapshot(ctx, &buf); err != nil {
+		t.Fatalf("Failed to load snapshot: %v", err)
+	}
+	loaded, err := restored.GetTask(ctx, created.ID)
+	if err != nil {
+		t.Fatalf("Failed to get restored task: %v", err)
+	}
+	if loaded.Description != "Rotate the database password" || loaded.UserID != "user-1" {
+		t.Errorf("Unexpected restored task %+v", loaded)
+	}
+}
+
+func TestSnapshotWithoutEncryption(t *testing.T) {
+	ctx := context.Background()
+	svc := NewInMemoryTaskService(logging.New(), nil, nil)
+	if _, err := svc.CreateTask(ctx, task.CreateTaskRequest{Description: "Plain task"}); err != nil {
+		t.Fatalf("Failed to create task: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := svc.SaveSnapshot(ctx, &buf); err != nil {
+		t.Fatalf("Failed to save snapshot: %v", err)
+	}
+
+	restored := NewInMemoryTaskService(logging.New(), nil, nil)
+	if err := restored.LoadSnapshot(ctx, &buf); err != nil {
+		t.Fatalf("Failed to load snapshot: %v", err)
+	}
+	tasks, err := restored.ListTasks(ctx, task.TaskFilter{})
+	if err != nil || len(tasks) != 1 || tasks[0].Description != "Plain task" {
+		t.Errorf("Expected the saved task, got %+v (%v)", tasks, err)
+	}
+}
@@ -0,0 +1,173 @@
+// Package artifactreader provides a tool that reads artifacts in parts, e.g.
+// large tool results that an agent saved instead of adding them to the
+// conversation.
+package artifactreader
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/run-bigpig/llm-agent/pkg/artifact"
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+)
+
+// Name is the name of the tool
+const Name = "read_artifact"
+
+// Defaults for the size of what is returned
+const (
+	DefaultLength = 8000
+	MaxLength     = 32000
+)
+
+// Tool reads part of an artifact, or the lines of an artifact that contain a
+// search term. It uses the artifact manager of the run, see
+// artifact.WithManager.
+type Tool struct{}
+
+// New creates a tool that reads artifacts
+func New() *Tool {
+	return &Tool{}
+}
+
+// Name returns the name of the tool
+func (t *Tool) Name() string {
+	return Name
+}
+
+// Description returns a description of what the tool does
+func (t *Tool) Description() string {
+	return "Reads a saved artifact, such as a large tool output, in parts. Give an offset and length in bytes to read a range, or a query to find the lines that contain it."
+}
+
+// Parameters returns the parameters that the tool accepts
+func (t *Tool) Parameters() map[string]interfaces.ParameterSpec {
+	return map[string]interfaces.ParameterSpec{
+		"artifact": {
+			Type:        "string",
+			Description: "The artifact URI, e.g. artifact://..., or ID",
+			Required:    true,
+		},
+		"offset": {
+			Type:        "integer",
+			Description: "The byte offset to start reading at",
+			Default:     0,
+		},
+		"length": {
+			Type:        "integer",
+			Description: fmt.Sprintf("The number of bytes to read, at most %d", MaxLength),
+			Default:     DefaultLength,
+		},
+		"query": {
+			Type:        "string",
+			Description: "Return the lines that contain this text, case-insensitively, instead of a range",
+		},
+	}
+}
+
+// args are the arguments of a call
+type args struct {
+	Artifact string `json:"artifact"`
+	Offset   int64  `json:"offset"`
+	Length   int    `json:"length"`
+	Query    string `json:"query"`
+}
+
+// Run reads the artifact with the given URI
+func (t *Tool) Run(ctx context.Context, input string) (string, error) {
+	return t.read(ctx, args{Artifact: input})
+}
+
+// Execute reads the artifact with the given arguments
+func (t *Tool) Execute(ctx context.Context, argsJSON string) (string, error) {
+	var a args
+	if err := json.Unmarshal([]byte(argsJSON), &a); err != nil {
+		return "", fmt.Errorf("invalid arguments: %w", err)
+	}
+	return t.read(ctx, a)
+}
+
+// read returns the requested part of the artifact
+func (t *Tool) read(ctx context.Context, a args) (string, error) {
+	manager, ok := artifact.ManagerFromContext(ctx)
+	if !ok {
+		return "", fmt.Errorf("no artifact store is available")
+	}
+	if a.Length <= 0 {
+		a.Length = DefaultLength
+	}
+	a.Length = min(a.Length, MaxLength)
+	if a.Offset < 0 {
+		a.Offset = 0
+	}
+
+	meta, r, err := manager.Open(ctx, a.Artifact)
+	if err != nil {
+		return "", fmt.Errorf("failed to open artifact: %w", err)
+	}
+	defer r.Close()
+
+	if a.Query != "" {
+		return search(meta, r, a.Query, a.Length)
+	}
+
+	if _, err := io.CopyN(io.Discard, r, a.Offset); err != nil && err != io.EOF {
+		return "", fmt.Errorf("failed to read artifact: %w", err)
+	}
+	buf := make([]byte, a.Length)
+	n, err := io.ReadFull(r, buf)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return "", fmt.Errorf("failed to read artifact: %w", err)
+	}
+
+	end := a.Offset + int64(n)
+	header := fmt.Sprintf("[%s, bytes %d-%d of %d]", meta.URI(), a.Offset, end, meta.Size)
+	if end < meta.Size {
+		header += fmt.Sprintf(" Continue with offset %d.", end)
+	}
+	return header + "\n" + string(buf[:n]), nil
+}
+
+// search returns the numbered lines that contain the query, up to limit bytes
+func search(meta *artifact.Artifact, r io.Reader, query string, limit int) (string, error) {
+	query = strings.ToLower(query)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+
+	var sb strings.Builder
+	matches, line := 0, 0
+	truncated := false
+	for scanner.Scan() {
+		line++
+		text := scanner.Text()
+		if !strings.Contains(strings.ToLower(text), query) {
+			continue
+		}
+		matches++
+		if truncated {
+			continue
+		}
+		entry := fmt.Sprintf("%d: %s\n", line, text)
+		if sb.Len()+len(entry) > limit {
+			truncated = true
+			continue
+		}
+		sb.WriteString(entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return "", fmt.Errorf("failed to search artifact: %w", err)
+	}
+
+	if matches == 0 {
+		return fmt.Sprintf("[%s] No lines contain %q.", meta.URI(), query), nil
+	}
+	header := fmt.Sprintf("[%s, %d matching lines]", meta.URI(), matches)
+	if truncated {
+		header += " Only the first matches are shown; use a more specific query."
+	}
+	return header + "\n" + sb.String(), nil
+}
@@ -0,0 +1,64 @@
+package artifactreader_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/run-bigpig/llm-agent/pkg/artifact"
+	"github.com/run-bigpig/llm-agent/pkg/tools/artifactreader"
+)
+
+func TestReadArtifact(t *testing.T) {
+	manager := artifact.NewManager(artifact.NewLocalStore(t.TempDir()))
+	ctx := artifact.WithManager(context.Background(), manager)
+
+	lines := make([]string, 100)
+	for i := range lines {
+		lines[i] = fmt.Sprintf("line %02d", i)
+	}
+	saved, err := manager.Save(ctx, "out.txt", "text/plain", strings.NewReader(strings.Join(lines, "\n")))
+	require.NoError(t, err)
+
+	tool := artifactreader.New()
+
+	t.Run("range", func(t *testing.T) {
+		result, err := tool.Execute(ctx, fmt.Sprintf(`{"artifact":%q,"offset":16,"length":15}`, saved.URI()))
+		require.NoError(t, err)
+		assert.Equal(t, fmt.Sprintf("[%s, bytes 16-31 of 799] Continue with offset 31.\nline 02\nline 03", saved.URI()), result)
+	})
+
+	t.Run("end", func(t *testing.T) {
+		result, err := tool.Execute(ctx, fmt.Sprintf(`{"artifact":%q,"offset":792}`, saved.ID))
+		require.NoError(t, err)
+		assert.Equal(t, fmt.Sprintf("[%s, bytes 792-799 of 799]\nline 99", saved.URI()), result)
+	})
+
+	t.Run("query", func(t *testing.T) {
+		result, err := tool.Execute(ctx, fmt.Sprintf(`{"artifact":%q,"query":"LINE 5"}`, saved.URI()))
+		require.NoError(t, err)
+		assert.Contains(t, result, "10 matching lines")
+		assert.Contains(t, result, "\n51: line 50\n")
+		assert.NotContains(t, result, "line 49")
+
+		result, err = tool.Execute(ctx, fmt.Sprintf(`{"artifact":%q,"query":"line","length":20}`, saved.URI()))
+		require.NoError(t, err)
+		assert.Contains(t, result, "100 matching lines] Only the first matches are shown")
+		assert.True(t, strings.HasSuffix(result, "query.\n1: line 00\n"), result)
+
+		result, err = tool.Execute(ctx, fmt.Sprintf(`{"artifact":%q,"query":"missing"}`, saved.URI()))
+		require.NoError(t, err)
+		assert.Contains(t, result, `No lines contain "missing"`)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		_, err := tool.Execute(ctx, `{"artifact":"artifact://not-an-id"}`)
+		assert.Error(t, err)
+		_, err = tool.Execute(context.Background(), fmt.Sprintf(`{"artifact":%q}`, saved.URI()))
+		assert.ErrorContains(t, err, "no artifact store")
+	})
+}
@@ -0,0 +1,326 @@
+package askuser
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+	"github.com/run-bigpig/llm-agent/pkg/memory"
+	"github.com/run-bigpig/llm-agent/pkg/multitenancy"
+)
+
+// ErrNoAnswer is returned when the user does not answer before the timeout
+var ErrNoAnswer = errors.New("the user did not answer")
+
+// Question is a question the agent asks the user
+type Question struct {
+	// ID identifies the question so that an answer can be matched to it
+	ID string `json:"id"`
+
+	// Text is the question
+	Text string `json:"text"`
+
+	// Options are suggested answers, if any
+	Options []string `json:"options,omitempty"`
+
+	// OrgID is the organization of the run asking the question
+	OrgID string `json:"org_id,omitempty"`
+
+	// ConversationID is the conversation of the run asking the question
+	ConversationID string `json:"conversation_id,omitempty"`
+
+	// AskedAt is when the question was asked
+	AskedAt time.Time `json:"asked_at"`
+}
+
+// Frontend delivers questions to the user and returns their answers
+type Frontend interface {
+	// Ask asks the user the question and blocks until it is answered or ctx is done
+	Ask(ctx context.Context, question Question) (string, error)
+}
+
+// FrontendFunc adapts a function to the Frontend interface
+type FrontendFunc func(ctx context.Context, question Question) (string, error)
+
+// Ask calls f(ctx, question)
+func (f FrontendFunc) Ask(ctx context.Context, question Question) (string, error) {
+	return f(ctx, question)
+}
+
+// Tool lets an agent ask the user for clarification mid-run
+type Tool struct {
+	frontend      Frontend
+	timeout       time.Duration
+	defaultAnswer string
+}
+
+// Input represents the input for the ask_user tool
+type Input struct {
+	Question string   `json:"question"`
+	Options  []string `json:"options,omitempty"`
+}
+
+// Option represents an option for configuring the tool
+type Option func(*Tool)
+
+// WithTimeout sets how long to wait for an answer
+func WithTimeout(timeout time.Duration) Option {
+	return func(t *Tool) {
+		t.timeout = timeout
+	}
+}
+
+// WithDefaultAnswer sets the answer returned to the agent when the user does
+// not answer in time. Without it, a timeout is returned as an error.
+func WithDefaultAnswer(answer string) Option {
+	return func(t *Tool) {
+		t.defaultAnswer = answer
+	}
+}
+
+// New creates a new ask_user tool with the frontend
+func New(frontend Frontend, options ...Option) *Tool {
+	tool := &Tool{
+		frontend: frontend,
+		timeout:  5 * time.Minute,
+	}
+
+	for _, option := range options {
+		option(tool)
+	}
+
+	return tool
+}
+
+// Name returns the name of the tool
+func (t *Tool) Name() string {
+	return "ask_user"
+}
+
+// Description returns a description of what the tool does
+func (t *Tool) Description() string {
+	return "Ask the user a clarifying question and wait for their answer. Use this instead of guessing when the request is ambiguous or missing information"
+}
+
+// Parameters returns the parameters that the tool accepts
+func (t *Tool) Parameters() map[string]interfaces.ParameterSpec {
+	return map[string]interfaces.ParameterSpec{
+		"question": {
+			Type:        "string",
+			Description: "The question to ask the user",
+			Required:    true,
+		},
+		"options": {
+			Type:        "array",
+			Description: "Suggested answers the user can choose from",
+			Required:    false,
+			Items:       &interfaces.ParameterSpec{Type: "string"},
+		},
+	}
+}
+
+// Run executes the tool with the given input
+func (t *Tool) Run(ctx context.Context, input string) (string, error) {
+	var params Input
+	if err := json.Unmarshal([]byte(input), &params); err != nil {
+		// If not JSON, treat the input as the question
+		params = Input{Question: input}
+	}
+
+	return t.ask(ctx, params)
+}
+
+// Execute executes the tool with the given arguments
+func (t *Tool) Execute(ctx context.Context, args string) (string, error) {
+	var params Input
+	if err := json.Unmarshal([]byte(args), &params); err != nil {
+		return "", fmt.Errorf("failed to parse args: %w", err)
+	}
+
+	return t.ask(ctx, params)
+}
+
+// ask sends the question to the frontend and waits for the answer
+func (t *Tool) ask(ctx context.Context, params Input) (string, error) {
+	if strings.TrimSpace(params.Question) == "" {
+		return "", fmt.Errorf("question parameter is required")
+	}
+
+	question := Question{
+		ID:      uuid.New().String(),
+		Text:    params.Question,
+		Options: params.Options,
+		AskedAt: time.Now(),
+	}
+	question.OrgID, _ = multitenancy.GetOrgID(ctx)
+	question.ConversationID, _ = memory.GetConversationID(ctx)
+
+	if t.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, t.timeout)
+		defer cancel()
+	}
+
+	answer, err := t.frontend.Ask(ctx, question)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, ErrNoAnswer) {
+			if t.defaultAnswer != "" {
+				return t.defaultAnswer, nil
+			}
+			return "", ErrNoAnswer
+		}
+		return "", fmt.Errorf("failed to ask user: %w", err)
+	}
+
+	return answer, nil
+}
+
+// ChannelFrontend delivers questions on a channel and waits for answers
+// submitted with Answer. It suits servers that forward questions to clients,
+// for example as WebSocket frames, and receive the answers on a later
+// request. Pending lists the unanswered questions so a reconnecting client
+// can resume.
+type ChannelFrontend struct {
+	questions chan Question
+
+	mu      sync.Mutex
+	pending map[string]*pendingQuestion
+}
+
+// pendingQuestion is a question waiting for an answer
+type pendingQuestion struct {
+	question Question
+	answer   chan string
+}
+
+// NewChannelFrontend creates a channel frontend. The buffer is the number of
+// questions that can be queued before Ask blocks.
+func NewChannelFrontend(buffer int) *ChannelFrontend {
+	return &ChannelFrontend{
+		questions: make(chan Question, buffer),
+		pending:   make(map[string]*pendingQuestion),
+	}
+}
+
+// Questions returns the channel on which questions are delivered
+func (f *ChannelFrontend) Questions() <-chan Question {
+	return f.questions
+}
+
+// Ask delivers the question and waits for the answer
+func (f *ChannelFrontend) Ask(ctx context.Context, question Question) (string, error) {
+	p := &pendingQuestion{question: question, answer: make(chan string, 1)}
+
+	f.mu.Lock()
+	f.pending[question.ID] = p
+	f.mu.Unlock()
+	defer func() {
+		f.mu.Lock()
+		delete(f.pending, question.ID)
+		f.mu.Unlock()
+	}()
+
+	select {
+	case f.questions <- question:
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+
+	select {
+	case answer := <-p.answer:
+		return answer, nil
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// Answer submits the answer to a pending question
+func (f *ChannelFrontend) Answer(questionID, answer string) error {
+	f.mu.Lock()
+	p, ok := f.pending[questionID]
+	if ok {
+		delete(f.pending, questionID)
+	}
+	f.mu.Unlock()
+
+	if !ok {
+		return fmt.Errorf("no pending question with ID %s", questionID)
+	}
+
+	p.answer <- answer
+	return nil
+}
+
+// Pending returns the questions that have not been answered yet
+func (f *ChannelFrontend) Pending() []Question {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	questions := make([]Question, 0, len(f.pending))
+	for _, p := range f.pending {
+		questions = append(questions, p.question)
+	}
+	return questions
+}
+
+// CLIFrontend asks questions on a terminal
+type CLIFrontend struct {
+	in  *bufio.Reader
+	out io.Writer
+	mu  sync.Mutex
+}
+
+// NewCLIFrontend creates a frontend that writes questions to out and reads
+// answers from in, e.g. os.Stdout and os.Stdin
+func NewCLIFrontend(in io.Reader, out io.Writer) *CLIFrontend {
+	return &CLIFrontend{
+		in:  bufio.NewReader(in),
+		out: out,
+	}
+}
+
+// Ask prints the question and reads a line. When options are offered, the
+// user can answer with the option number.
+func (f *CLIFrontend) Ask(ctx context.Context, question Question) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	fmt.Fprintf(f.out, "\n%s\n", question.Text)
+	for i, option := range question.Options {
+		fmt.Fprintf(f.out, "  %d. %s\n", i+1, option)
+	}
+	fmt.Fprint(f.out, "> ")
+
+	type result struct {
+		line string
+		err  error
+	}
+	ch := make(chan result, 1)
+	go func() {
+		line, err := f.in.ReadString('\n')
+		ch <- result{line, err}
+	}()
+
+	select {
+	case r := <-ch:
+		if r.err != nil && r.line == "" {
+			return "", fmt.Errorf("failed to read answer: %w", r.err)
+		}
+		answer := strings.TrimSpace(r.line)
+		if n, err := strconv.Atoi(answer); err == nil && n >= 1 && n <= len(question.Options) {
+			answer = question.Options[n-1]
+		}
+		return answer, nil
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
@@ -0,0 +1,133 @@
+package askuser_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/run-bigpig/llm-agent/pkg/memory"
+	"github.com/run-bigpig/llm-agent/pkg/multitenancy"
+	"github.com/run-bigpig/llm-agent/pkg/tools/askuser"
+)
+
+func TestAskUser(t *testing.T) {
+	var asked askuser.Question
+	tool := askuser.New(askuser.FrontendFunc(func(ctx context.Context, question askuser.Question) (string, error) {
+		asked = question
+		_, hasDeadline := ctx.Deadline()
+		assert.True(t, hasDeadline, "expected the timeout to bound the question")
+		return "staging", nil
+	}))
+
+	ctx := multitenancy.WithOrgID(context.Background(), "org-1")
+	ctx = memory.WithConversationID(ctx, "conv-1")
+	answer, err := tool.Execute(ctx, `{"question":"Which environment?","options":["staging","production"]}`)
+	require.NoError(t, err)
+	assert.Equal(t, "staging", answer)
+
+	assert.NotEmpty(t, asked.ID)
+	assert.Equal(t, "Which environment?", asked.Text)
+	assert.Equal(t, []string{"staging", "production"}, asked.Options)
+	assert.Equal(t, "org-1", asked.OrgID)
+	assert.Equal(t, "conv-1", asked.ConversationID)
+	assert.False(t, asked.AskedAt.IsZero())
+
+	// Run accepts a plain question
+	_, err = tool.Run(ctx, "Which region?")
+	require.NoError(t, err)
+	assert.Equal(t, "Which region?", asked.Text)
+
+	_, err = tool.Execute(ctx, `{"question":"  "}`)
+	assert.EqualError(t, err, "question parameter is required")
+	_, err = tool.Execute(ctx, `not json`)
+	assert.Error(t, err)
+}
+
+func TestAskUserTimeout(t *testing.T) {
+	wait := askuser.FrontendFunc(func(ctx context.Context, question askuser.Question) (string, error) {
+		<-ctx.Done()
+		return "", ctx.Err()
+	})
+
+	_, err := askuser.New(wait, askuser.WithTimeout(10*time.Millisecond)).Execute(context.Background(), `{"question":"Proceed?"}`)
+	assert.ErrorIs(t, err, askuser.ErrNoAnswer)
+
+	answer, err := askuser.New(wait, askuser.WithTimeout(10*time.Millisecond), askuser.WithDefaultAnswer("no")).
+		Execute(context.Background(), `{"question":"Proceed?"}`)
+	require.NoError(t, err)
+	assert.Equal(t, "no", answer)
+
+	// Other frontend errors are not replaced by the default answer
+	failing := askuser.FrontendFunc(func(ctx context.Context, question askuser.Question) (string, error) {
+		return "", errors.New("client disconnected")
+	})
+	_, err = askuser.New(failing, askuser.WithDefaultAnswer("no")).Execute(context.Background(), `{"question":"Proceed?"}`)
+	assert.EqualError(t, err, "failed to ask user: client disconnected")
+}
+
+func TestChannelFrontend(t *testing.T) {
+	frontend := askuser.NewChannelFrontend(1)
+	tool := askuser.New(frontend)
+
+	type result struct {
+		answer string
+		err    error
+	}
+	done := make(chan result, 1)
+	go func() {
+		answer, err := tool.Execute(context.Background(), `{"question":"Which environment?"}`)
+		done <- result{answer, err}
+	}()
+
+	question := <-frontend.Questions()
+	assert.Equal(t, "Which environment?", question.Text)
+	require.Eventually(t, func() bool { return len(frontend.Pending()) == 1 }, time.Second, time.Millisecond)
+	assert.Equal(t, question.ID, frontend.Pending()[0].ID)
+
+	assert.Error(t, frontend.Answer("unknown", "staging"))
+	require.NoError(t, frontend.Answer(question.ID, "staging"))
+
+	r := <-done
+	require.NoError(t, r.err)
+	assert.Equal(t, "staging", r.answer)
+	assert.Empty(t, frontend.Pending())
+
+	// A question can only be answered once
+	assert.Error(t, frontend.Answer(question.ID, "production"))
+}
+
+func TestChannelFrontendCancelled(t *testing.T) {
+	frontend := askuser.NewChannelFrontend(0)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := frontend.Ask(ctx, askuser.Question{ID: "q-1", Text: "Proceed?"})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Empty(t, frontend.Pending())
+}
+
+func TestCLIFrontend(t *testing.T) {
+	var out bytes.Buffer
+	frontend := askuser.NewCLIFrontend(strings.NewReader("2\n"), &out)
+
+	answer, err := frontend.Ask(context.Background(), askuser.Question{Text: "Which environment?", Options: []string{"staging", "production"}})
+	require.NoError(t, err)
+	assert.Equal(t, "production", answer)
+	assert.Equal(t, "\nWhich environment?\n  1. staging\n  2. production\n> ", out.String())
+
+	// Answers that are not option numbers are returned as typed
+	frontend = askuser.NewCLIFrontend(strings.NewReader("  eu-west-1  \n"), &out)
+	answer, err = frontend.Ask(context.Background(), askuser.Question{Text: "Which region?", Options: []string{"us-east-1"}})
+	require.NoError(t, err)
+	assert.Equal(t, "eu-west-1", answer)
+
+	frontend = askuser.NewCLIFrontend(strings.NewReader(""), &out)
+	_, err = frontend.Ask(context.Background(), askuser.Question{Text: "Anyone there?"})
+	assert.Error(t, err)
+}
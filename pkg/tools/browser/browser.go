@@ -0,0 +1,375 @@
+package browser
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/run-bigpig/llm-agent/pkg/artifact"
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+	"github.com/run-bigpig/llm-agent/pkg/memory"
+	"github.com/run-bigpig/llm-agent/pkg/multitenancy"
+	"github.com/run-bigpig/llm-agent/pkg/runctx"
+)
+
+// ScreenshotHandler stores a screenshot and returns a reference for the model,
+// such as a URL or artifact ID
+type ScreenshotHandler func(ctx context.Context, png []byte) (string, error)
+
+// ImageContent is a multimodal content block returned for screenshots when no
+// screenshot handler is configured
+type ImageContent struct {
+	Type      string `json:"type"`
+	MediaType string `json:"media_type"`
+	Data      string `json:"data"`
+}
+
+// Tool automates a headless browser with navigate, click, type, extract and
+// screenshot actions
+type Tool struct {
+	driver            Driver
+	allowedDomains    []string
+	maxSteps          int
+	maxExtractSize    int
+	screenshotHandler ScreenshotHandler
+
+	// driverMu serializes actions since a driver controls a single page
+	driverMu sync.Mutex
+
+	mu    sync.Mutex
+	steps map[string]int
+}
+
+// Input represents the input for the browser tool
+type Input struct {
+	Action   string `json:"action"`
+	URL      string `json:"url,omitempty"`
+	Selector string `json:"selector,omitempty"`
+	Text     string `json:"text,omitempty"`
+}
+
+// Option represents an option for configuring the tool
+type Option func(*Tool)
+
+// WithAllowedDomains restricts the domains the browser may visit. Subdomains
+// of an allowed domain are allowed too. When no domains are configured,
+// every domain is allowed.
+func WithAllowedDomains(domains ...string) Option {
+	return func(t *Tool) {
+		t.allowedDomains = domains
+	}
+}
+
+// WithMaxSteps sets the maximum number of actions per conversation
+func WithMaxSteps(steps int) Option {
+	return func(t *Tool) {
+		t.maxSteps = steps
+	}
+}
+
+// WithMaxExtractSize caps the number of characters returned by extract
+func WithMaxExtractSize(size int) Option {
+	return func(t *Tool) {
+		t.maxExtractSize = size
+	}
+}
+
+// WithScreenshotHandler stores screenshots with the handler and returns its
+// reference instead of the inline image
+func WithScreenshotHandler(handler ScreenshotHandler) Option {
+	return func(t *Tool) {
+		t.screenshotHandler = handler
+	}
+}
+
+// New creates a new browser tool using the driver
+func New(driver Driver, options ...Option) *Tool {
+	tool := &Tool{
+		driver:         driver,
+		maxSteps:       20,
+		maxExtractSize: 8000,
+		steps:          make(map[string]int),
+	}
+
+	for _, option := range options {
+		option(tool)
+	}
+
+	return tool
+}
+
+// Name returns the name of the tool
+func (t *Tool) Name() string {
+	return "browser"
+}
+
+// Description returns a description of what the tool does
+func (t *Tool) Description() string {
+	description := "Control a web browser: navigate to a URL, click or type into elements by CSS selector, extract page text, or take a screenshot"
+	if len(t.allowedDomains) > 0 {
+		description += ". Only these domains may be visited: " + strings.Join(t.allowedDomains, ", ")
+	}
+	return description
+}
+
+// Parameters returns the parameters that the tool accepts
+func (t *Tool) Parameters() map[string]interfaces.ParameterSpec {
+	return map[string]interfaces.ParameterSpec{
+		"action": {
+			Type:        "string",
+			Description: "The action to perform",
+			Required:    true,
+			Enum:        []interface{}{"navigate", "click", "type", "extract", "screenshot"},
+		},
+		"url": {
+			Type:        "string",
+			Description: "The URL to navigate to (for navigate)",
+			Required:    false,
+		},
+		"selector": {
+			Type:        "string",
+			Description: "CSS selector of the element (for click, type and extract; extract defaults to the whole page)",
+			Required:    false,
+		},
+		"text": {
+			Type:        "string",
+			Description: "The text to type (for type)",
+			Required:    false,
+		},
+	}
+}
+
+// Run executes the tool with the given input
+func (t *Tool) Run(ctx context.Context, input string) (string, error) {
+	return t.Execute(ctx, input)
+}
+
+// Execute executes the tool with the given arguments. In a dry run clicks
+// and typing are simulated instead of performed.
+func (t *Tool) Execute(ctx context.Context, args string) (string, error) {
+	if runctx.DryRun(ctx) && t.HasSideEffects(args) {
+		return t.DryRun(ctx, args)
+	}
+
+	var params Input
+	if err := json.Unmarshal([]byte(args), &params); err != nil {
+		return "", fmt.Errorf("failed to parse args: %w", err)
+	}
+
+	if err := t.countStep(ctx); err != nil {
+		return "", err
+	}
+
+	t.driverMu.Lock()
+	defer t.driverMu.Unlock()
+
+	switch params.Action {
+	case "navigate":
+		return t.navigate(ctx, params.URL)
+	case "click":
+		if params.Selector == "" {
+			return "", fmt.Errorf("selector parameter is required for click")
+		}
+		if err := t.driver.Click(ctx, params.Selector); err != nil {
+			return "", err
+		}
+		// A click can navigate away from the allowed domains
+		current, err := t.checkCurrentURL(ctx)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Clicked %s. Current page: %s", params.Selector, current), nil
+	case "type":
+		if params.Selector == "" {
+			return "", fmt.Errorf("selector parameter is required for type")
+		}
+		if err := t.driver.Type(ctx, params.Selector, params.Text); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Typed into %s", params.Selector), nil
+	case "extract":
+		text, err := t.driver.Extract(ctx, params.Selector)
+		if err != nil {
+			return "", err
+		}
+		if t.maxExtractSize > 0 && len(text) > t.maxExtractSize {
+			text = text[:t.maxExtractSize] + "\n[text truncated]"
+		}
+		return text, nil
+	case "screenshot":
+		return t.screenshot(ctx)
+	default:
+		return "", fmt.Errorf("unknown action: %s", params.Action)
+	}
+}
+
+// HasSideEffects reports whether the call clicks or types, which can submit
+// forms on the page. Calls that can't be parsed are treated as having side
+// effects.
+func (t *Tool) HasSideEffects(args string) bool {
+	var params Input
+	if err := json.Unmarshal([]byte(args), &params); err != nil {
+		return true
+	}
+	return params.Action == "click" || params.Action == "type"
+}
+
+// DryRun validates a click or typing and describes it without touching the
+// page. The other actions only read, so they run as usual.
+func (t *Tool) DryRun(ctx context.Context, args string) (string, error) {
+	var params Input
+	if err := json.Unmarshal([]byte(args), &params); err != nil {
+		return "", fmt.Errorf("failed to parse args: %w", err)
+	}
+
+	switch params.Action {
+	case "click":
+		if params.Selector == "" {
+			return "", fmt.Errorf("selector parameter is required for click")
+		}
+		return fmt.Sprintf("[dry run] Would have clicked %s", params.Selector), nil
+	case "type":
+		if params.Selector == "" {
+			return "", fmt.Errorf("selector parameter is required for type")
+		}
+		return fmt.Sprintf("[dry run] Would have typed %q into %s", params.Text, params.Selector), nil
+	default:
+		return t.Execute(ctx, args)
+	}
+}
+
+// Reset clears the step count for the conversation in the context
+func (t *Tool) Reset(ctx context.Context) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.steps, stepKey(ctx))
+}
+
+// Close closes the browser driver
+func (t *Tool) Close() error {
+	return t.driver.Close()
+}
+
+// countStep enforces the per-conversation step limit
+func (t *Tool) countStep(ctx context.Context) error {
+	if t.maxSteps <= 0 {
+		return nil
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	key := stepKey(ctx)
+	if t.steps[key] >= t.maxSteps {
+		return fmt.Errorf("browser step limit of %d reached", t.maxSteps)
+	}
+	t.steps[key]++
+	return nil
+}
+
+// stepKey scopes step counts to the organization and conversation
+func stepKey(ctx context.Context) string {
+	orgID, _ := multitenancy.GetOrgID(ctx)
+	conversationID, _ := memory.GetConversationID(ctx)
+	return orgID + ":" + conversationID
+}
+
+// navigate loads an allowed URL
+func (t *Tool) navigate(ctx context.Context, rawURL string) (string, error) {
+	if rawURL == "" {
+		return "", fmt.Errorf("url parameter is required for navigate")
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return "", fmt.Errorf("url must be an http or https URL")
+	}
+	if !t.isDomainAllowed(u.Hostname()) {
+		return "", fmt.Errorf("domain %s is not in the list of allowed domains", u.Hostname())
+	}
+
+	interfaces.ReportToolProgress(ctx, interfaces.ToolProgress{Message: "Loading " + rawURL})
+	if err := t.driver.Navigate(ctx, rawURL); err != nil {
+		return "", err
+	}
+
+	// Redirects can leave the allowed domains
+	current, err := t.checkCurrentURL(ctx)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Navigated to %s", current), nil
+}
+
+// checkCurrentURL returns the current URL, leaving the page if it is not allowed
+func (t *Tool) checkCurrentURL(ctx context.Context) (string, error) {
+	current, err := t.driver.URL(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	u, err := url.Parse(current)
+	if err == nil && (u.Scheme == "about" || t.isDomainAllowed(u.Hostname())) {
+		return current, nil
+	}
+
+	_ = t.driver.Navigate(ctx, "about:blank")
+	return "", fmt.Errorf("page left the allowed domains (%s)", current)
+}
+
+// isDomainAllowed checks the host against the allowlist
+func (t *Tool) isDomainAllowed(host string) bool {
+	if len(t.allowedDomains) == 0 {
+		return true
+	}
+
+	host = strings.ToLower(host)
+	for _, domain := range t.allowedDomains {
+		domain = strings.ToLower(strings.TrimPrefix(domain, "."))
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// screenshot captures the page and returns it as a reference from the
+// screenshot handler or artifact store, or as an image content block
+func (t *Tool) screenshot(ctx context.Context) (string, error) {
+	png, err := t.driver.Screenshot(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	if t.screenshotHandler != nil {
+		ref, err := t.screenshotHandler(ctx, png)
+		if err != nil {
+			return "", fmt.Errorf("failed to store screenshot: %w", err)
+		}
+		return fmt.Sprintf("Screenshot saved: %s", ref), nil
+	}
+
+	// Keep the image out of the prompt when the run has an artifact store
+	if manager, ok := artifact.ManagerFromContext(ctx); ok {
+		a, err := manager.Save(ctx, "screenshot.png", "image/png", bytes.NewReader(png))
+		if err != nil {
+			return "", fmt.Errorf("failed to store screenshot: %w", err)
+		}
+		return fmt.Sprintf("Screenshot saved: %s", a), nil
+	}
+
+	data, err := json.Marshal(ImageContent{
+		Type:      "image",
+		MediaType: "image/png",
+		Data:      base64.StdEncoding.EncodeToString(png),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal screenshot: %w", err)
+	}
+	return string(data), nil
+}
@@ -0,0 +1,225 @@
+package browser_test
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/run-bigpig/llm-agent/pkg/artifact"
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+	"github.com/run-bigpig/llm-agent/pkg/memory"
+	"github.com/run-bigpig/llm-agent/pkg/runctx"
+	"github.com/run-bigpig/llm-agent/pkg/tools/browser"
+)
+
+// fakeDriver simulates a page; navigating to a URL in redirects lands on the
+// redirect target, and clicking a selector in links navigates to its URL
+type fakeDriver struct {
+	url       string
+	redirects map[string]string
+	links     map[string]string
+	text      string
+	typed     map[string]string
+	navigated []string
+	closed    bool
+}
+
+func newFakeDriver() *fakeDriver {
+	return &fakeDriver{url: "about:blank", redirects: map[string]string{}, links: map[string]string{}, typed: map[string]string{}}
+}
+
+func (d *fakeDriver) Navigate(ctx context.Context, url string) error {
+	d.navigated = append(d.navigated, url)
+	if target, ok := d.redirects[url]; ok {
+		url = target
+	}
+	d.url = url
+	return nil
+}
+
+func (d *fakeDriver) Click(ctx context.Context, selector string) error {
+	if target, ok := d.links[selector]; ok {
+		d.url = target
+	}
+	return nil
+}
+
+func (d *fakeDriver) Type(ctx context.Context, selector, text string) error {
+	d.typed[selector] = text
+	return nil
+}
+
+func (d *fakeDriver) Extract(ctx context.Context, selector string) (string, error) {
+	return d.text, nil
+}
+
+func (d *fakeDriver) Screenshot(ctx context.Context) ([]byte, error) {
+	return []byte("png"), nil
+}
+
+func (d *fakeDriver) URL(ctx context.Context) (string, error) { return d.url, nil }
+
+func (d *fakeDriver) Close() error {
+	d.closed = true
+	return nil
+}
+
+func TestBrowserActions(t *testing.T) {
+	driver := newFakeDriver()
+	driver.links["#next"] = "https://docs.example.com/page/2"
+	driver.text = "Welcome to the docs"
+	tool := browser.New(driver, browser.WithAllowedDomains("example.com"))
+	ctx := context.Background()
+
+	result, err := tool.Execute(ctx, `{"action":"navigate","url":"https://docs.example.com"}`)
+	require.NoError(t, err)
+	assert.Equal(t, "Navigated to https://docs.example.com", result)
+
+	result, err = tool.Execute(ctx, `{"action":"click","selector":"#next"}`)
+	require.NoError(t, err)
+	assert.Equal(t, "Clicked #next. Current page: https://docs.example.com/page/2", result)
+
+	result, err = tool.Execute(ctx, `{"action":"type","selector":"#q","text":"pricing"}`)
+	require.NoError(t, err)
+	assert.Equal(t, "Typed into #q", result)
+	assert.Equal(t, "pricing", driver.typed["#q"])
+
+	result, err = tool.Execute(ctx, `{"action":"extract"}`)
+	require.NoError(t, err)
+	assert.Equal(t, "Welcome to the docs", result)
+
+	_, err = tool.Execute(ctx, `{"action":"click"}`)
+	assert.EqualError(t, err, "selector parameter is required for click")
+	_, err = tool.Execute(ctx, `{"action":"scroll"}`)
+	assert.EqualError(t, err, "unknown action: scroll")
+
+	require.NoError(t, tool.Close())
+	assert.True(t, driver.closed)
+}
+
+func TestBrowserDryRun(t *testing.T) {
+	driver := newFakeDriver()
+	driver.links["#delete"] = "https://example.com/deleted"
+	driver.text = "Account settings"
+	tool := browser.New(driver)
+	var _ interfaces.ToolWithSideEffects = tool
+	var _ interfaces.ToolWithDryRun = tool
+	ctx := runctx.WithDryRun(context.Background(), true)
+
+	assert.True(t, tool.HasSideEffects(`{"action":"click","selector":"#delete"}`))
+	assert.True(t, tool.HasSideEffects(`{"action":"type","selector":"#name","text":"x"}`))
+	assert.False(t, tool.HasSideEffects(`{"action":"extract"}`))
+
+	result, err := tool.Execute(ctx, `{"action":"click","selector":"#delete"}`)
+	require.NoError(t, err)
+	assert.Equal(t, "[dry run] Would have clicked #delete", result)
+	result, err = tool.Execute(ctx, `{"action":"type","selector":"#name","text":"Alice"}`)
+	require.NoError(t, err)
+	assert.Equal(t, `[dry run] Would have typed "Alice" into #name`, result)
+	assert.Equal(t, "about:blank", driver.url)
+	assert.Empty(t, driver.typed)
+
+	// Reading the page still works
+	result, err = tool.Execute(ctx, `{"action":"extract"}`)
+	require.NoError(t, err)
+	assert.Equal(t, "Account settings", result)
+
+	_, err = tool.Execute(ctx, `{"action":"click"}`)
+	assert.Error(t, err)
+}
+
+func TestBrowserAllowedDomains(t *testing.T) {
+	driver := newFakeDriver()
+	driver.redirects["https://example.com/login"] = "https://evil.test/phish"
+	driver.links["#out"] = "https://other.test"
+	tool := browser.New(driver, browser.WithAllowedDomains(".Example.com"))
+	ctx := context.Background()
+
+	assert.Contains(t, tool.Description(), "Only these domains may be visited: .Example.com")
+
+	for _, url := range []string{"https://example.org", "https://notexample.com", "file:///etc/passwd"} {
+		_, err := tool.Execute(ctx, fmt.Sprintf(`{"action":"navigate","url":%q}`, url))
+		assert.Error(t, err, url)
+	}
+	assert.Empty(t, driver.navigated)
+
+	// Redirects and clicks that leave the allowed domains go back to a blank page
+	_, err := tool.Execute(ctx, `{"action":"navigate","url":"https://example.com/login"}`)
+	assert.EqualError(t, err, "page left the allowed domains (https://evil.test/phish)")
+	assert.Equal(t, "about:blank", driver.url)
+
+	_, err = tool.Execute(ctx, `{"action":"navigate","url":"https://WWW.example.com"}`)
+	require.NoError(t, err)
+	_, err = tool.Execute(ctx, `{"action":"click","selector":"#out"}`)
+	assert.EqualError(t, err, "page left the allowed domains (https://other.test)")
+	assert.Equal(t, "about:blank", driver.url)
+}
+
+func TestBrowserStepLimit(t *testing.T) {
+	tool := browser.New(newFakeDriver(), browser.WithMaxSteps(2))
+	ctx := memory.WithConversationID(context.Background(), "conv-1")
+
+	for i := 0; i < 2; i++ {
+		_, err := tool.Execute(ctx, `{"action":"extract"}`)
+		require.NoError(t, err)
+	}
+	_, err := tool.Execute(ctx, `{"action":"extract"}`)
+	assert.EqualError(t, err, "browser step limit of 2 reached")
+
+	// Steps are counted per conversation
+	_, err = tool.Execute(memory.WithConversationID(context.Background(), "conv-2"), `{"action":"extract"}`)
+	assert.NoError(t, err)
+
+	tool.Reset(ctx)
+	_, err = tool.Execute(ctx, `{"action":"extract"}`)
+	assert.NoError(t, err)
+}
+
+func TestBrowserExtractTruncates(t *testing.T) {
+	driver := newFakeDriver()
+	driver.text = strings.Repeat("a", 20)
+	tool := browser.New(driver, browser.WithMaxExtractSize(5))
+
+	result, err := tool.Execute(context.Background(), `{"action":"extract","selector":"main"}`)
+	require.NoError(t, err)
+	assert.Equal(t, "aaaaa\n[text truncated]", result)
+}
+
+func TestBrowserScreenshot(t *testing.T) {
+	t.Run("inline", func(t *testing.T) {
+		tool := browser.New(newFakeDriver())
+		result, err := tool.Execute(context.Background(), `{"action":"screenshot"}`)
+		require.NoError(t, err)
+
+		var image browser.ImageContent
+		require.NoError(t, json.Unmarshal([]byte(result), &image))
+		assert.Equal(t, browser.ImageContent{Type: "image", MediaType: "image/png", Data: base64.StdEncoding.EncodeToString([]byte("png"))}, image)
+	})
+
+	t.Run("handler", func(t *testing.T) {
+		tool := browser.New(newFakeDriver(), browser.WithScreenshotHandler(func(ctx context.Context, png []byte) (string, error) {
+			assert.Equal(t, []byte("png"), png)
+			return "https://files.example.com/shot.png", nil
+		}))
+		result, err := tool.Execute(context.Background(), `{"action":"screenshot"}`)
+		require.NoError(t, err)
+		assert.Equal(t, "Screenshot saved: https://files.example.com/shot.png", result)
+	})
+
+	t.Run("artifact store", func(t *testing.T) {
+		manager := artifact.NewManager(artifact.NewLocalStore(t.TempDir()))
+		ctx := artifact.WithManager(context.Background(), manager)
+
+		tool := browser.New(newFakeDriver())
+		result, err := tool.Execute(ctx, `{"action":"screenshot"}`)
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(result, "Screenshot saved: "), result)
+		assert.NotContains(t, result, base64.StdEncoding.EncodeToString([]byte("png")))
+	})
+}
@@ -0,0 +1,342 @@
+package browser
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+// Driver controls a single browser page
+type Driver interface {
+	// Navigate loads the URL and waits for the page to finish loading
+	Navigate(ctx context.Context, url string) error
+
+	// Click clicks the first element matching the CSS selector
+	Click(ctx context.Context, selector string) error
+
+	// Type sets the value of the first input matching the CSS selector
+	Type(ctx context.Context, selector, text string) error
+
+	// Extract returns the visible text of the first element matching the
+	// CSS selector, or of the whole page if the selector is empty
+	Extract(ctx context.Context, selector string) (string, error)
+
+	// Screenshot captures the visible page as a PNG image
+	Screenshot(ctx context.Context) ([]byte, error)
+
+	// URL returns the URL of the current page
+	URL(ctx context.Context) (string, error)
+
+	// Close closes the page
+	Close() error
+}
+
+// cdpResponse is a Chrome DevTools Protocol response or event
+type cdpResponse struct {
+	ID     int64           `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// CDPDriver drives a Chromium page over the Chrome DevTools Protocol. It
+// works with any browser exposing a CDP endpoint, such as Chrome started with
+// --remote-debugging-port or a Playwright Chromium server.
+type CDPDriver struct {
+	conn     *websocket.Conn
+	targetID string
+	baseURL  string
+	nextID   atomic.Int64
+
+	mu      sync.Mutex
+	pending map[int64]chan cdpResponse
+	closed  chan struct{}
+	readErr error
+}
+
+// ConnectCDP opens a new page in the browser whose DevTools HTTP endpoint is
+// debugURL, e.g. http://localhost:9222
+func ConnectCDP(ctx context.Context, debugURL string) (*CDPDriver, error) {
+	debugURL = strings.TrimSuffix(debugURL, "/")
+
+	// Newer Chrome versions require PUT to open a new target
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, debugURL+"/json/new?about:blank", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open browser page: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to open browser page: status code %d", resp.StatusCode)
+	}
+
+	var target struct {
+		ID                   string `json:"id"`
+		WebSocketDebuggerURL string `json:"webSocketDebuggerUrl"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&target); err != nil {
+		return nil, fmt.Errorf("failed to parse browser target: %w", err)
+	}
+
+	driver, err := DialCDP(target.WebSocketDebuggerURL)
+	if err != nil {
+		return nil, err
+	}
+	driver.targetID = target.ID
+	driver.baseURL = debugURL
+
+	return driver, nil
+}
+
+// DialCDP connects to the DevTools WebSocket URL of an existing page
+func DialCDP(wsURL string) (*CDPDriver, error) {
+	conn, err := websocket.Dial(wsURL, "", "http://localhost/")
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to browser: %w", err)
+	}
+
+	driver := &CDPDriver{
+		conn:    conn,
+		pending: make(map[int64]chan cdpResponse),
+		closed:  make(chan struct{}),
+	}
+	go driver.readLoop()
+
+	return driver, nil
+}
+
+// readLoop dispatches responses to the waiting calls; events are ignored
+func (d *CDPDriver) readLoop() {
+	for {
+		var msg cdpResponse
+		if err := websocket.JSON.Receive(d.conn, &msg); err != nil {
+			d.mu.Lock()
+			d.readErr = err
+			d.mu.Unlock()
+			close(d.closed)
+			return
+		}
+		if msg.ID == 0 {
+			continue
+		}
+
+		d.mu.Lock()
+		ch, ok := d.pending[msg.ID]
+		delete(d.pending, msg.ID)
+		d.mu.Unlock()
+		if ok {
+			ch <- msg
+		}
+	}
+}
+
+// call sends a CDP command and decodes its result
+func (d *CDPDriver) call(ctx context.Context, method string, params interface{}, result interface{}) error {
+	id := d.nextID.Add(1)
+	ch := make(chan cdpResponse, 1)
+
+	d.mu.Lock()
+	d.pending[id] = ch
+	d.mu.Unlock()
+	defer func() {
+		d.mu.Lock()
+		delete(d.pending, id)
+		d.mu.Unlock()
+	}()
+
+	if err := websocket.JSON.Send(d.conn, map[string]interface{}{
+		"id":     id,
+		"method": method,
+		"params": params,
+	}); err != nil {
+		return fmt.Errorf("failed to send %s: %w", method, err)
+	}
+
+	select {
+	case msg := <-ch:
+		if msg.Error != nil {
+			return fmt.Errorf("%s failed: %s", method, msg.Error.Message)
+		}
+		if result != nil {
+			if err := json.Unmarshal(msg.Result, result); err != nil {
+				return fmt.Errorf("failed to parse %s result: %w", method, err)
+			}
+		}
+		return nil
+	case <-d.closed:
+		return fmt.Errorf("browser connection closed: %v", d.readErr)
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// evaluate runs a JavaScript expression and decodes its value
+func (d *CDPDriver) evaluate(ctx context.Context, expression string, value interface{}) error {
+	var result struct {
+		Result struct {
+			Value json.RawMessage `json:"value"`
+		} `json:"result"`
+		ExceptionDetails *struct {
+			Text string `json:"text"`
+		} `json:"exceptionDetails"`
+	}
+	if err := d.call(ctx, "Runtime.evaluate", map[string]interface{}{
+		"expression":    expression,
+		"returnByValue": true,
+		"awaitPromise":  true,
+	}, &result); err != nil {
+		return err
+	}
+	if result.ExceptionDetails != nil {
+		return fmt.Errorf("script error: %s", result.ExceptionDetails.Text)
+	}
+	if value != nil && len(result.Result.Value) > 0 {
+		return json.Unmarshal(result.Result.Value, value)
+	}
+	return nil
+}
+
+// Navigate loads the URL and waits for the page to finish loading
+func (d *CDPDriver) Navigate(ctx context.Context, url string) error {
+	var result struct {
+		ErrorText string `json:"errorText"`
+	}
+	if err := d.call(ctx, "Page.navigate", map[string]string{"url": url}, &result); err != nil {
+		return err
+	}
+	if result.ErrorText != "" {
+		return fmt.Errorf("navigation failed: %s", result.ErrorText)
+	}
+	return d.waitForLoad(ctx)
+}
+
+// waitForLoad polls the document ready state until the page has loaded
+func (d *CDPDriver) waitForLoad(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	for {
+		var state string
+		if err := d.evaluate(ctx, "document.readyState", &state); err == nil && state == "complete" {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("timed out waiting for page to load")
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
+
+// Click clicks the first element matching the CSS selector
+func (d *CDPDriver) Click(ctx context.Context, selector string) error {
+	var found bool
+	script := fmt.Sprintf(`(() => { const el = document.querySelector(%s); if (!el) return false; el.click(); return true; })()`, jsString(selector))
+	if err := d.evaluate(ctx, script, &found); err != nil {
+		return err
+	}
+	if !found {
+		return fmt.Errorf("no element matches selector %s", selector)
+	}
+	return d.waitForLoad(ctx)
+}
+
+// Type sets the value of the first input matching the CSS selector
+func (d *CDPDriver) Type(ctx context.Context, selector, text string) error {
+	var found bool
+	script := fmt.Sprintf(`(() => {
+		const el = document.querySelector(%s);
+		if (!el) return false;
+		el.focus();
+		el.value = %s;
+		el.dispatchEvent(new Event('input', { bubbles: true }));
+		el.dispatchEvent(new Event('change', { bubbles: true }));
+		return true;
+	})()`, jsString(selector), jsString(text))
+	if err := d.evaluate(ctx, script, &found); err != nil {
+		return err
+	}
+	if !found {
+		return fmt.Errorf("no element matches selector %s", selector)
+	}
+	return nil
+}
+
+// Extract returns the visible text of the first element matching the CSS
+// selector, or of the whole page if the selector is empty
+func (d *CDPDriver) Extract(ctx context.Context, selector string) (string, error) {
+	if selector == "" {
+		selector = "body"
+	}
+
+	var text *string
+	script := fmt.Sprintf(`(() => { const el = document.querySelector(%s); return el ? el.innerText : null; })()`, jsString(selector))
+	if err := d.evaluate(ctx, script, &text); err != nil {
+		return "", err
+	}
+	if text == nil {
+		return "", fmt.Errorf("no element matches selector %s", selector)
+	}
+	return *text, nil
+}
+
+// Screenshot captures the visible page as a PNG image
+func (d *CDPDriver) Screenshot(ctx context.Context) ([]byte, error) {
+	var result struct {
+		Data string `json:"data"`
+	}
+	if err := d.call(ctx, "Page.captureScreenshot", map[string]string{"format": "png"}, &result); err != nil {
+		return nil, err
+	}
+
+	data, err := base64.StdEncoding.DecodeString(result.Data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode screenshot: %w", err)
+	}
+	return data, nil
+}
+
+// URL returns the URL of the current page
+func (d *CDPDriver) URL(ctx context.Context) (string, error) {
+	var url string
+	if err := d.evaluate(ctx, "location.href", &url); err != nil {
+		return "", err
+	}
+	return url, nil
+}
+
+// Close closes the page and the connection
+func (d *CDPDriver) Close() error {
+	err := d.conn.Close()
+
+	// Close the page if it was opened by ConnectCDP
+	if d.targetID != "" {
+		resp, closeErr := http.Get(d.baseURL + "/json/close/" + d.targetID)
+		if closeErr == nil {
+			resp.Body.Close()
+		}
+	}
+
+	return err
+}
+
+// jsString quotes a Go string as a JavaScript string literal
+func jsString(s string) string {
+	data, _ := json.Marshal(s)
+	return string(data)
+}
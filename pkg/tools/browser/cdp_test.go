@@ -0,0 +1,169 @@
+package browser_test
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/websocket"
+
+	"github.com/run-bigpig/llm-agent/pkg/tools/browser"
+)
+
+// fakeChrome serves the DevTools endpoints of a browser with a single page
+type fakeChrome struct {
+	mu       sync.Mutex
+	methods  []string
+	scripts  []string
+	url      string
+	closedID string
+}
+
+func (c *fakeChrome) handler(t *testing.T) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/json/new", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut {
+			t.Errorf("expected PUT to open a page, got %s", r.Method)
+		}
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"id":                   "page-1",
+			"webSocketDebuggerUrl": "ws://" + r.Host + "/devtools/page/page-1",
+		})
+	})
+	mux.HandleFunc("/json/close/", func(w http.ResponseWriter, r *http.Request) {
+		c.mu.Lock()
+		c.closedID = strings.TrimPrefix(r.URL.Path, "/json/close/")
+		c.mu.Unlock()
+	})
+	mux.Handle("/devtools/page/page-1", websocket.Handler(func(conn *websocket.Conn) {
+		for {
+			var msg struct {
+				ID     int64                  `json:"id"`
+				Method string                 `json:"method"`
+				Params map[string]interface{} `json:"params"`
+			}
+			if err := websocket.JSON.Receive(conn, &msg); err != nil {
+				return
+			}
+
+			// Events without an ID must be ignored by the driver
+			_ = websocket.JSON.Send(conn, map[string]interface{}{"method": "Page.loadEventFired"})
+			_ = websocket.JSON.Send(conn, map[string]interface{}{"id": msg.ID, "result": c.respond(msg.Method, msg.Params)})
+		}
+	}))
+	return mux
+}
+
+// respond returns the result of a CDP command
+func (c *fakeChrome) respond(method string, params map[string]interface{}) interface{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.methods = append(c.methods, method)
+
+	switch method {
+	case "Page.navigate":
+		c.url = params["url"].(string)
+		return map[string]string{}
+	case "Page.captureScreenshot":
+		return map[string]string{"data": base64.StdEncoding.EncodeToString([]byte("png"))}
+	case "Runtime.evaluate":
+		expression := params["expression"].(string)
+		c.scripts = append(c.scripts, expression)
+		var value interface{}
+		switch {
+		case expression == "document.readyState":
+			value = "complete"
+		case expression == "location.href":
+			value = c.url
+		case strings.Contains(expression, "#missing"):
+			value = nil
+		case strings.Contains(expression, "innerText"):
+			value = "Hello"
+		default:
+			value = true
+		}
+		return map[string]interface{}{"result": map[string]interface{}{"value": value}}
+	}
+	return map[string]string{}
+}
+
+func TestCDPDriver(t *testing.T) {
+	chrome := &fakeChrome{}
+	server := httptest.NewServer(chrome.handler(t))
+	defer server.Close()
+
+	ctx := context.Background()
+	driver, err := browser.ConnectCDP(ctx, server.URL+"/")
+	require.NoError(t, err)
+
+	require.NoError(t, driver.Navigate(ctx, "https://example.com"))
+	url, err := driver.URL(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com", url)
+
+	require.NoError(t, driver.Click(ctx, "a.next"))
+	require.NoError(t, driver.Type(ctx, `input[name="q"]`, `say "hi"`))
+
+	text, err := driver.Extract(ctx, "")
+	require.NoError(t, err)
+	assert.Equal(t, "Hello", text)
+
+	_, err = driver.Extract(ctx, "#missing")
+	assert.EqualError(t, err, "no element matches selector #missing")
+	assert.EqualError(t, driver.Click(ctx, "#missing"), "no element matches selector #missing")
+
+	png, err := driver.Screenshot(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("png"), png)
+
+	require.NoError(t, driver.Close())
+
+	chrome.mu.Lock()
+	defer chrome.mu.Unlock()
+	assert.Equal(t, "page-1", chrome.closedID)
+	assert.Contains(t, chrome.methods, "Page.captureScreenshot")
+
+	// Selectors and text are quoted as JavaScript strings
+	var typed string
+	for _, script := range chrome.scripts {
+		if strings.Contains(script, "el.value") {
+			typed = script
+		}
+	}
+	assert.Contains(t, typed, `document.querySelector("input[name=\"q\"]")`)
+	assert.Contains(t, typed, `el.value = "say \"hi\""`)
+}
+
+func TestCDPDriverCommandError(t *testing.T) {
+	server := httptest.NewServer(websocket.Handler(func(conn *websocket.Conn) {
+		var msg struct {
+			ID int64 `json:"id"`
+		}
+		if err := websocket.JSON.Receive(conn, &msg); err != nil {
+			return
+		}
+		_ = websocket.JSON.Send(conn, map[string]interface{}{
+			"id":    msg.ID,
+			"error": map[string]interface{}{"code": -32000, "message": "Cannot navigate to invalid URL"},
+		})
+	}))
+	defer server.Close()
+
+	driver, err := browser.DialCDP("ws" + strings.TrimPrefix(server.URL, "http"))
+	require.NoError(t, err)
+	defer driver.Close()
+
+	err = driver.Navigate(context.Background(), "nope")
+	assert.EqualError(t, err, "Page.navigate failed: Cannot navigate to invalid URL")
+
+	// The connection is closed by the server after the first command
+	_, err = driver.URL(context.Background())
+	assert.Error(t, err)
+}
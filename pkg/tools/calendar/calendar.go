@@ -0,0 +1,339 @@
+package calendar
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+	"github.com/run-bigpig/llm-agent/pkg/multitenancy"
+)
+
+// Tool implements a Google Calendar tool for listing and creating events
+type Tool struct {
+	token         string
+	tokenProvider multitenancy.TokenProvider
+	baseURL       string
+	httpClient    *http.Client
+}
+
+// Input represents the input for the calendar tool
+type Input struct {
+	Action      string   `json:"action"`
+	CalendarID  string   `json:"calendar_id,omitempty"`
+	TimeMin     string   `json:"time_min,omitempty"`
+	TimeMax     string   `json:"time_max,omitempty"`
+	MaxResults  int      `json:"max_results,omitempty"`
+	Summary     string   `json:"summary,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Location    string   `json:"location,omitempty"`
+	Start       string   `json:"start,omitempty"`
+	End         string   `json:"end,omitempty"`
+	Attendees   []string `json:"attendees,omitempty"`
+}
+
+// Option represents an option for configuring the tool
+type Option func(*Tool)
+
+// WithHTTPClient sets the HTTP client for the tool
+func WithHTTPClient(client *http.Client) Option {
+	return func(t *Tool) {
+		t.httpClient = client
+	}
+}
+
+// WithBaseURL sets the base URL for the Calendar API
+func WithBaseURL(baseURL string) Option {
+	return func(t *Tool) {
+		t.baseURL = strings.TrimSuffix(baseURL, "/")
+	}
+}
+
+// WithTokenProvider resolves the access token per organization instead of using the static token
+func WithTokenProvider(provider multitenancy.TokenProvider) Option {
+	return func(t *Tool) {
+		t.tokenProvider = provider
+	}
+}
+
+// New creates a new Google Calendar tool using the given OAuth access token
+func New(token string, options ...Option) *Tool {
+	tool := &Tool{
+		token:      token,
+		baseURL:    "https://www.googleapis.com/calendar/v3",
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+
+	for _, option := range options {
+		option(tool)
+	}
+
+	return tool
+}
+
+// Name returns the name of the tool
+func (t *Tool) Name() string {
+	return "google_calendar"
+}
+
+// Description returns a description of what the tool does
+func (t *Tool) Description() string {
+	return "List upcoming Google Calendar events and create new events"
+}
+
+// Parameters returns the parameters that the tool accepts
+func (t *Tool) Parameters() map[string]interfaces.ParameterSpec {
+	return map[string]interfaces.ParameterSpec{
+		"action": {
+			Type:        "string",
+			Description: "The action to perform",
+			Required:    true,
+			Enum:        []interface{}{"list", "create"},
+		},
+		"calendar_id": {
+			Type:        "string",
+			Description: "The calendar ID",
+			Required:    false,
+			Default:     "primary",
+		},
+		"time_min": {
+			Type:        "string",
+			Description: "RFC3339 lower bound for listed events (defaults to now)",
+			Required:    false,
+		},
+		"time_max": {
+			Type:        "string",
+			Description: "RFC3339 upper bound for listed events",
+			Required:    false,
+		},
+		"max_results": {
+			Type:        "number",
+			Description: "Maximum number of events to list",
+			Required:    false,
+			Default:     10,
+		},
+		"summary": {
+			Type:        "string",
+			Description: "The event title (required for create)",
+			Required:    false,
+		},
+		"description": {
+			Type:        "string",
+			Description: "The event description",
+			Required:    false,
+		},
+		"location": {
+			Type:        "string",
+			Description: "The event location",
+			Required:    false,
+		},
+		"start": {
+			Type:        "string",
+			Description: "RFC3339 start time (required for create)",
+			Required:    false,
+		},
+		"end": {
+			Type:        "string",
+			Description: "RFC3339 end time (required for create)",
+			Required:    false,
+		},
+		"attendees": {
+			Type:        "array",
+			Description: "Email addresses of attendees",
+			Required:    false,
+			Items: &interfaces.ParameterSpec{
+				Type: "string",
+			},
+		},
+	}
+}
+
+// Run executes the tool with the given input
+func (t *Tool) Run(ctx context.Context, input string) (string, error) {
+	return t.Execute(ctx, input)
+}
+
+// HasSideEffects reports whether the call creates events
+func (t *Tool) HasSideEffects(args string) bool {
+	var params Input
+	if err := json.Unmarshal([]byte(args), &params); err != nil {
+		// Assume the worst for arguments that can't be read
+		return true
+	}
+	return params.Action == "create"
+}
+
+// Execute executes the tool with the given arguments
+func (t *Tool) Execute(ctx context.Context, args string) (string, error) {
+	var params Input
+	if err := json.Unmarshal([]byte(args), &params); err != nil {
+		return "", fmt.Errorf("failed to parse args: %w", err)
+	}
+
+	if params.CalendarID == "" {
+		params.CalendarID = "primary"
+	}
+
+	switch params.Action {
+	case "list":
+		return t.list(ctx, params)
+	case "create":
+		return t.create(ctx, params)
+	default:
+		return "", fmt.Errorf("unknown action: %s", params.Action)
+	}
+}
+
+// event is the subset of the Calendar API event resource used by the tool
+type event struct {
+	ID          string          `json:"id,omitempty"`
+	Summary     string          `json:"summary,omitempty"`
+	Description string          `json:"description,omitempty"`
+	Location    string          `json:"location,omitempty"`
+	HTMLLink    string          `json:"htmlLink,omitempty"`
+	Start       eventTime       `json:"start"`
+	End         eventTime       `json:"end"`
+	Attendees   []eventAttendee `json:"attendees,omitempty"`
+}
+
+type eventTime struct {
+	DateTime string `json:"dateTime,omitempty"`
+	Date     string `json:"date,omitempty"`
+}
+
+type eventAttendee struct {
+	Email string `json:"email"`
+}
+
+// list lists events in the given time range
+func (t *Tool) list(ctx context.Context, params Input) (string, error) {
+	if params.TimeMin == "" {
+		params.TimeMin = time.Now().Format(time.RFC3339)
+	}
+	if params.MaxResults <= 0 {
+		params.MaxResults = 10
+	}
+
+	query := url.Values{}
+	query.Set("timeMin", params.TimeMin)
+	if params.TimeMax != "" {
+		query.Set("timeMax", params.TimeMax)
+	}
+	query.Set("maxResults", strconv.Itoa(params.MaxResults))
+	query.Set("singleEvents", "true")
+	query.Set("orderBy", "startTime")
+
+	var result struct {
+		Items []event `json:"items"`
+	}
+	path := "/calendars/" + url.PathEscape(params.CalendarID) + "/events?" + query.Encode()
+	if err := t.do(ctx, "GET", path, nil, &result); err != nil {
+		return "", err
+	}
+
+	if len(result.Items) == 0 {
+		return "No upcoming events found.", nil
+	}
+
+	var sb strings.Builder
+	sb.WriteString("Events:\n\n")
+	for i, item := range result.Items {
+		start := item.Start.DateTime
+		if start == "" {
+			start = item.Start.Date
+		}
+		sb.WriteString(fmt.Sprintf("%d. %s\n", i+1, item.Summary))
+		sb.WriteString(fmt.Sprintf("   Start: %s\n", start))
+		if item.Location != "" {
+			sb.WriteString(fmt.Sprintf("   Location: %s\n", item.Location))
+		}
+	}
+
+	return sb.String(), nil
+}
+
+// create creates a new event
+func (t *Tool) create(ctx context.Context, params Input) (string, error) {
+	if params.Summary == "" || params.Start == "" || params.End == "" {
+		return "", fmt.Errorf("summary, start and end parameters are required for create")
+	}
+
+	for _, value := range []string{params.Start, params.End} {
+		if _, err := time.Parse(time.RFC3339, value); err != nil {
+			return "", fmt.Errorf("invalid RFC3339 time %s: %w", value, err)
+		}
+	}
+
+	req := event{
+		Summary:     params.Summary,
+		Description: params.Description,
+		Location:    params.Location,
+		Start:       eventTime{DateTime: params.Start},
+		End:         eventTime{DateTime: params.End},
+	}
+	for _, email := range params.Attendees {
+		req.Attendees = append(req.Attendees, eventAttendee{Email: email})
+	}
+
+	var result event
+	path := "/calendars/" + url.PathEscape(params.CalendarID) + "/events"
+	if err := t.do(ctx, "POST", path, req, &result); err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("Created event %q (%s)", result.Summary, result.HTMLLink), nil
+}
+
+// do sends an authenticated request to the Calendar API and decodes the response
+func (t *Tool) do(ctx context.Context, method, path string, body interface{}, out interface{}) error {
+	token := t.token
+	if t.tokenProvider != nil {
+		orgToken, err := t.tokenProvider.GetOAuthToken(ctx, "google")
+		if err != nil {
+			return fmt.Errorf("failed to resolve Google token: %w", err)
+		}
+		token = orgToken
+	}
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to marshal request: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, t.baseURL+path, reader)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := t.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("calendar API returned status code %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	return nil
+}
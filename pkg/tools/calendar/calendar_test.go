@@ -0,0 +1,60 @@
+package calendar_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/run-bigpig/llm-agent/pkg/multitenancy"
+	"github.com/run-bigpig/llm-agent/pkg/tools/calendar"
+)
+
+func TestCalendarCreate(t *testing.T) {
+	var authorization string
+	var event map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/calendars/primary/events" {
+			t.Errorf("Unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		authorization = r.Header.Get("Authorization")
+		if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+			t.Errorf("Failed to decode event: %v", err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"summary": "Review", "htmlLink": "https://calendar.google.com/event?eid=1"}`))
+	}))
+	defer server.Close()
+
+	tool := calendar.New("static-token", calendar.WithBaseURL(server.URL), calendar.WithTokenProvider(multitenancy.StaticTokens{"org-a": "token-a"}))
+	ctx := multitenancy.WithOrgID(context.Background(), "org-a")
+	result, err := tool.Execute(ctx, `{"action": "create", "summary": "Review", "start": "2025-03-01T10:00:00Z", "end": "2025-03-01T11:00:00Z", "attendees": ["jane@example.com"]}`)
+	if err != nil {
+		t.Fatalf("Failed to execute tool: %v", err)
+	}
+	if !strings.Contains(result, "eid=1") {
+		t.Errorf("Expected the event link, got %s", result)
+	}
+	if authorization != "Bearer token-a" {
+		t.Errorf("Expected the organization's token, got %q", authorization)
+	}
+	if event["start"].(map[string]interface{})["dateTime"] != "2025-03-01T10:00:00Z" || len(event["attendees"].([]interface{})) != 1 {
+		t.Errorf("Unexpected event %v", event)
+	}
+
+	// An organization without a token fails instead of using the static token
+	authorization = ""
+	if _, err := tool.Execute(multitenancy.WithOrgID(context.Background(), "org-b"), `{"action": "create", "summary": "Review", "start": "2025-03-01T10:00:00Z", "end": "2025-03-01T11:00:00Z"}`); err == nil {
+		t.Error("Expected an error for an organization without a token")
+	}
+	if authorization != "" {
+		t.Errorf("Expected no request to be sent, got one with %q", authorization)
+	}
+
+	// Invalid times are rejected before a request is sent
+	if _, err := tool.Execute(ctx, `{"action": "create", "summary": "Review", "start": "tomorrow", "end": "2025-03-01T11:00:00Z"}`); err == nil {
+		t.Error("Expected an error for an invalid start time")
+	}
+}
@@ -0,0 +1,462 @@
+package email
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+	"github.com/run-bigpig/llm-agent/pkg/runctx"
+)
+
+// Message represents an outgoing email
+type Message struct {
+	From    string
+	To      []string
+	Subject string
+	Body    string
+}
+
+// Sender delivers email messages
+type Sender interface {
+	// Send delivers the message
+	Send(ctx context.Context, msg Message) error
+}
+
+// SMTPSender sends email through an SMTP server
+type SMTPSender struct {
+	addr string
+	host string
+	auth smtp.Auth
+}
+
+// NewSMTPSender creates a new SMTP sender. addr is host:port; username and
+// password are used for PLAIN authentication when username is not empty.
+func NewSMTPSender(addr, username, password string) *SMTPSender {
+	host := addr
+	if i := strings.LastIndex(addr, ":"); i >= 0 {
+		host = addr[:i]
+	}
+
+	var auth smtp.Auth
+	if username != "" {
+		auth = smtp.PlainAuth("", username, password, host)
+	}
+
+	return &SMTPSender{
+		addr: addr,
+		host: host,
+		auth: auth,
+	}
+}
+
+// NewSESSender creates a sender that delivers through the Amazon SES SMTP
+// interface for the given region using SES SMTP credentials
+func NewSESSender(region, username, password string) *SMTPSender {
+	return NewSMTPSender(fmt.Sprintf("email-smtp.%s.amazonaws.com:587", region), username, password)
+}
+
+// Send delivers the message
+func (s *SMTPSender) Send(ctx context.Context, msg Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	data, err := buildMessage(msg)
+	if err != nil {
+		return err
+	}
+
+	from, err := mail.ParseAddress(msg.From)
+	if err != nil {
+		return fmt.Errorf("invalid sender %q: %w", msg.From, err)
+	}
+	to, err := parseAddresses(msg.To)
+	if err != nil {
+		return err
+	}
+	recipients := make([]string, len(to))
+	for i, addr := range to {
+		recipients[i] = addr.Address
+	}
+
+	if err := s.send(ctx, from.Address, recipients, data); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+
+	return nil
+}
+
+// send delivers the message like smtp.SendMail, but bounds the connection by
+// ctx so that cancellation and shutdown interrupt a hung server
+func (s *SMTPSender) send(ctx context.Context, from string, to []string, data []byte) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", s.addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := conn.SetDeadline(deadline); err != nil {
+			return err
+		}
+	}
+	stop := context.AfterFunc(ctx, func() {
+		_ = conn.SetDeadline(time.Now())
+	})
+	defer stop()
+
+	client, err := smtp.NewClient(conn, s.host)
+	if err != nil {
+		return contextError(ctx, err)
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: s.host}); err != nil {
+			return contextError(ctx, err)
+		}
+	}
+	if s.auth != nil {
+		if err := client.Auth(s.auth); err != nil {
+			return contextError(ctx, err)
+		}
+	}
+	if err := client.Mail(from); err != nil {
+		return contextError(ctx, err)
+	}
+	for _, addr := range to {
+		if err := client.Rcpt(addr); err != nil {
+			return contextError(ctx, err)
+		}
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return contextError(ctx, err)
+	}
+	if _, err := w.Write(data); err != nil {
+		return contextError(ctx, err)
+	}
+	if err := w.Close(); err != nil {
+		return contextError(ctx, err)
+	}
+
+	return contextError(ctx, client.Quit())
+}
+
+// contextError reports the context error instead of the I/O error it caused
+func contextError(ctx context.Context, err error) error {
+	if err != nil && ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
+
+// buildMessage formats the message as an RFC 5322 plain text email. The
+// addresses and subject may come from the model, so values with line breaks,
+// which could add headers such as Bcc, are rejected.
+func buildMessage(msg Message) ([]byte, error) {
+	from, err := mail.ParseAddress(msg.From)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sender %q: %w", msg.From, err)
+	}
+	to, err := parseAddresses(msg.To)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkHeader("subject", msg.Subject); err != nil {
+		return nil, err
+	}
+
+	recipients := make([]string, len(to))
+	for i, addr := range to {
+		recipients[i] = addr.String()
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("From: " + from.String() + "\r\n")
+	buf.WriteString("To: " + strings.Join(recipients, ", ") + "\r\n")
+	buf.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", msg.Subject) + "\r\n")
+	buf.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=\"utf-8\"\r\n")
+	buf.WriteString("\r\n")
+	buf.WriteString(msg.Body)
+	return buf.Bytes(), nil
+}
+
+// parseAddresses parses the email addresses, rejecting header injection
+func parseAddresses(addresses []string) ([]*mail.Address, error) {
+	parsed := make([]*mail.Address, len(addresses))
+	for i, address := range addresses {
+		if err := checkHeader("recipient", address); err != nil {
+			return nil, err
+		}
+		addr, err := mail.ParseAddress(address)
+		if err != nil {
+			return nil, fmt.Errorf("invalid recipient %q: %w", address, err)
+		}
+		parsed[i] = addr
+	}
+	return parsed, nil
+}
+
+// checkHeader rejects header values with line breaks
+func checkHeader(name, value string) error {
+	if strings.ContainsAny(value, "\r\n") {
+		return fmt.Errorf("%s must not contain line breaks", name)
+	}
+	return nil
+}
+
+// Tool implements an email notification tool
+type Tool struct {
+	sender            Sender
+	from              string
+	allowedRecipients []string
+	templates         map[string]*template.Template
+	dryRun            bool
+	err               error
+}
+
+// Input represents the input for the email tool
+type Input struct {
+	To        []string               `json:"to"`
+	Subject   string                 `json:"subject"`
+	Body      string                 `json:"body"`
+	Template  string                 `json:"template,omitempty"`
+	Variables map[string]interface{} `json:"variables,omitempty"`
+}
+
+// Option represents an option for configuring the tool
+type Option func(*Tool)
+
+// WithAllowedRecipients restricts who the tool may send email to. Entries are
+// either full addresses or domains prefixed with "@" (e.g., "@example.com").
+// When no recipients are configured, every recipient is allowed.
+func WithAllowedRecipients(recipients ...string) Option {
+	return func(t *Tool) {
+		t.allowedRecipients = recipients
+	}
+}
+
+// WithTemplate registers a named body template using Go text/template syntax.
+// A template that fails to parse is reported by Err and by every call.
+func WithTemplate(name, text string) Option {
+	return func(t *Tool) {
+		tmpl, err := template.New(name).Parse(text)
+		if err != nil {
+			if t.err == nil {
+				t.err = fmt.Errorf("invalid template %s: %w", name, err)
+			}
+			return
+		}
+		t.templates[name] = tmpl
+	}
+}
+
+// WithDryRun enables dry-run mode, in which messages are rendered and validated but not sent
+func WithDryRun(dryRun bool) Option {
+	return func(t *Tool) {
+		t.dryRun = dryRun
+	}
+}
+
+// New creates a new email notification tool
+func New(sender Sender, from string, options ...Option) *Tool {
+	tool := &Tool{
+		sender:    sender,
+		from:      from,
+		templates: make(map[string]*template.Template),
+	}
+
+	for _, option := range options {
+		option(tool)
+	}
+
+	return tool
+}
+
+// Err returns the error from configuring the tool, if any
+func (t *Tool) Err() error {
+	return t.err
+}
+
+// Name returns the name of the tool
+func (t *Tool) Name() string {
+	return "send_email"
+}
+
+// Description returns a description of what the tool does
+func (t *Tool) Description() string {
+	return "Send a notification or report by email"
+}
+
+// Parameters returns the parameters that the tool accepts
+func (t *Tool) Parameters() map[string]interfaces.ParameterSpec {
+	params := map[string]interfaces.ParameterSpec{
+		"to": {
+			Type:        "array",
+			Description: "The recipient email addresses",
+			Required:    true,
+			Items: &interfaces.ParameterSpec{
+				Type: "string",
+			},
+		},
+		"subject": {
+			Type:        "string",
+			Description: "The email subject",
+			Required:    true,
+		},
+		"body": {
+			Type:        "string",
+			Description: "The plain text email body. Ignored when a template is used",
+			Required:    false,
+		},
+		"variables": {
+			Type:        "object",
+			Description: "Variables used to render the template",
+			Required:    false,
+		},
+	}
+
+	if len(t.templates) > 0 {
+		names := make([]interface{}, 0, len(t.templates))
+		for name := range t.templates {
+			names = append(names, name)
+		}
+		params["template"] = interfaces.ParameterSpec{
+			Type:        "string",
+			Description: "The name of a registered body template",
+			Required:    false,
+			Enum:        names,
+		}
+	}
+
+	return params
+}
+
+// Run executes the tool with the given input
+func (t *Tool) Run(ctx context.Context, input string) (string, error) {
+	return t.Execute(ctx, input)
+}
+
+// Execute executes the tool with the given arguments
+func (t *Tool) Execute(ctx context.Context, args string) (string, error) {
+	if t.err != nil {
+		return "", t.err
+	}
+
+	var params Input
+	if err := json.Unmarshal([]byte(args), &params); err != nil {
+		return "", fmt.Errorf("failed to parse args: %w", err)
+	}
+
+	if len(params.To) == 0 {
+		return "", fmt.Errorf("at least one recipient is required")
+	}
+	if params.Subject == "" {
+		return "", fmt.Errorf("subject parameter is required")
+	}
+
+	if err := checkHeader("subject", params.Subject); err != nil {
+		return "", err
+	}
+	recipients, err := parseAddresses(params.To)
+	if err != nil {
+		return "", err
+	}
+
+	// Check the address itself, so that a display name can't pass the allowlist
+	for _, recipient := range recipients {
+		if !t.isRecipientAllowed(recipient.Address) {
+			return "", fmt.Errorf("recipient %s is not in the list of allowed recipients", recipient.Address)
+		}
+	}
+
+	body, err := t.render(params)
+	if err != nil {
+		return "", err
+	}
+
+	msg := Message{
+		From:    t.from,
+		To:      params.To,
+		Subject: params.Subject,
+		Body:    body,
+	}
+
+	if t.dryRun || runctx.DryRun(ctx) {
+		return fmt.Sprintf("[dry run] Email to %s was not sent:\nSubject: %s\n\n%s",
+			strings.Join(msg.To, ", "), msg.Subject, msg.Body), nil
+	}
+
+	if err := t.sender.Send(ctx, msg); err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("Email sent to %s", strings.Join(msg.To, ", ")), nil
+}
+
+// HasSideEffects reports that the tool sends email
+func (t *Tool) HasSideEffects(args string) bool {
+	return true
+}
+
+// DryRun validates and renders the message without sending it
+func (t *Tool) DryRun(ctx context.Context, args string) (string, error) {
+	return t.Execute(runctx.WithDryRun(ctx, true), args)
+}
+
+// render returns the message body, rendering the named template if one was requested
+func (t *Tool) render(params Input) (string, error) {
+	if params.Template == "" {
+		return params.Body, nil
+	}
+
+	tmpl, ok := t.templates[params.Template]
+	if !ok {
+		return "", fmt.Errorf("unknown template: %s", params.Template)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, params.Variables); err != nil {
+		return "", fmt.Errorf("failed to render template %s: %w", params.Template, err)
+	}
+
+	return buf.String(), nil
+}
+
+// isRecipientAllowed checks the recipient against the allowlist
+func (t *Tool) isRecipientAllowed(recipient string) bool {
+	if len(t.allowedRecipients) == 0 {
+		return true
+	}
+
+	recipient = strings.ToLower(strings.TrimSpace(recipient))
+	for _, allowed := range t.allowedRecipients {
+		allowed = strings.ToLower(allowed)
+		if strings.HasPrefix(allowed, "@") {
+			if strings.HasSuffix(recipient, allowed) {
+				return true
+			}
+			continue
+		}
+		if recipient == allowed {
+			return true
+		}
+	}
+
+	return false
+}
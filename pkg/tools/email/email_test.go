@@ -0,0 +1,155 @@
+package email
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// recordingSender records the messages it is asked to send
+type recordingSender struct {
+	sent []Message
+}
+
+func (s *recordingSender) Send(ctx context.Context, msg Message) error {
+	s.sent = append(s.sent, msg)
+	return nil
+}
+
+func TestBuildMessage(t *testing.T) {
+	data, err := buildMessage(Message{
+		From:    "Alerts <alerts@example.com>",
+		To:      []string{"ops@example.com", "Jane Doe <jane@example.com>"},
+		Subject: "Déploiement terminé",
+		Body:    "All services are up.",
+	})
+	if err != nil {
+		t.Fatalf("Failed to build message: %v", err)
+	}
+
+	message := string(data)
+	for _, header := range []string{
+		"From: \"Alerts\" <alerts@example.com>\r\n",
+		"To: <ops@example.com>, \"Jane Doe\" <jane@example.com>\r\n",
+		"Subject: =?utf-8?q?D=C3=A9ploiement_termin=C3=A9?=\r\n",
+	} {
+		if !strings.Contains(message, header) {
+			t.Errorf("Expected header %q in message:\n%s", header, message)
+		}
+	}
+	if !strings.HasSuffix(message, "\r\n\r\nAll services are up.") {
+		t.Errorf("Expected body after the headers, got:\n%s", message)
+	}
+}
+
+func TestBuildMessageRejectsHeaderInjection(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  Message
+	}{
+		{"subject with CRLF", Message{From: "a@example.com", To: []string{"b@example.com"}, Subject: "Hi\r\nBcc: evil@example.com"}},
+		{"subject with LF", Message{From: "a@example.com", To: []string{"b@example.com"}, Subject: "Hi\nBcc: evil@example.com"}},
+		{"recipient with CRLF", Message{From: "a@example.com", To: []string{"b@example.com\r\nBcc: evil@example.com"}, Subject: "Hi"}},
+		{"invalid recipient", Message{From: "a@example.com", To: []string{"not an address"}, Subject: "Hi"}},
+		{"invalid sender", Message{From: "a@example.com\nBcc: evil@example.com", To: []string{"b@example.com"}, Subject: "Hi"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if data, err := buildMessage(tt.msg); err == nil {
+				t.Errorf("Expected an error, got message:\n%s", data)
+			}
+		})
+	}
+}
+
+func TestExecuteRejectsHeaderInjection(t *testing.T) {
+	sender := &recordingSender{}
+	tool := New(sender, "alerts@example.com", WithAllowedRecipients("@example.com"))
+
+	for _, args := range []string{
+		`{"to": ["ops@example.com"], "subject": "Hi\r\nBcc: evil@attacker.com", "body": "x"}`,
+		`{"to": ["ops@example.com\r\nBcc: evil@attacker.com"], "subject": "Hi", "body": "x"}`,
+		// The display name can't smuggle an address past the allowlist
+		`{"to": ["ops@example.com <evil@attacker.com>"], "subject": "Hi", "body": "x"}`,
+	} {
+		if _, err := tool.Execute(context.Background(), args); err == nil {
+			t.Errorf("Expected an error for %s", args)
+		}
+		if _, err := tool.DryRun(context.Background(), args); err == nil {
+			t.Errorf("Expected a dry-run error for %s", args)
+		}
+	}
+	if len(sender.sent) != 0 {
+		t.Errorf("Expected no email to be sent, got %v", sender.sent)
+	}
+}
+
+func TestDryRun(t *testing.T) {
+	sender := &recordingSender{}
+	tool := New(sender, "alerts@example.com", WithTemplate("deploy", "Deployed {{.service}}"))
+
+	result, err := tool.DryRun(context.Background(), `{"to": ["ops@example.com"], "subject": "Deploy", "template": "deploy", "variables": {"service": "api"}}`)
+	if err != nil {
+		t.Fatalf("Dry run failed: %v", err)
+	}
+	if !strings.Contains(result, "[dry run]") || !strings.Contains(result, "Deployed api") {
+		t.Errorf("Expected the rendered dry-run message, got %q", result)
+	}
+	if len(sender.sent) != 0 {
+		t.Errorf("Expected a dry run not to send, got %v", sender.sent)
+	}
+
+	// Without dry run, the message is sent
+	if _, err := tool.Execute(context.Background(), `{"to": ["ops@example.com"], "subject": "Deploy", "body": "done"}`); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if len(sender.sent) != 1 || sender.sent[0].Body != "done" {
+		t.Errorf("Expected one sent message, got %v", sender.sent)
+	}
+}
+
+func TestInvalidTemplate(t *testing.T) {
+	sender := &recordingSender{}
+	tool := New(sender, "alerts@example.com", WithTemplate("deploy", "Deployed {{.service"))
+
+	if tool.Err() == nil {
+		t.Fatal("Expected the template parse error")
+	}
+	if _, err := tool.Execute(context.Background(), `{"to": ["ops@example.com"], "subject": "Deploy", "body": "done"}`); err == nil {
+		t.Error("Expected Execute to report the template parse error")
+	}
+	if len(sender.sent) != 0 {
+		t.Errorf("Expected no email to be sent, got %v", sender.sent)
+	}
+}
+
+func TestSMTPSenderHonoursContext(t *testing.T) {
+	// The server accepts the connection but never greets the client
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer listener.Close()
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_, _ = io.Copy(io.Discard, conn)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	sender := NewSMTPSender(listener.Addr().String(), "", "")
+	err = sender.Send(ctx, Message{From: "alerts@example.com", To: []string{"ops@example.com"}, Subject: "Hi", Body: "x"})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the deadline to interrupt the send, got %v", err)
+	}
+}
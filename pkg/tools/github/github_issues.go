@@ -0,0 +1,394 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/google/go-github/v45/github"
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+	"github.com/run-bigpig/llm-agent/pkg/multitenancy"
+	"github.com/run-bigpig/llm-agent/pkg/runctx"
+	"golang.org/x/oauth2"
+)
+
+// GitHubIssuesTool is a tool for reading issues and pull requests and posting comments
+type GitHubIssuesTool struct {
+	token         string
+	tokenProvider multitenancy.TokenProvider
+	baseURL       string
+}
+
+// IssuesOption represents an option for configuring the GitHubIssuesTool
+type IssuesOption func(*GitHubIssuesTool)
+
+// WithTokenProvider resolves the access token per organization instead of using the static token
+func WithTokenProvider(provider multitenancy.TokenProvider) IssuesOption {
+	return func(t *GitHubIssuesTool) {
+		t.tokenProvider = provider
+	}
+}
+
+// WithBaseURL sets the API base URL, e.g. for GitHub Enterprise
+func WithBaseURL(baseURL string) IssuesOption {
+	return func(t *GitHubIssuesTool) {
+		if !strings.HasSuffix(baseURL, "/") {
+			baseURL += "/"
+		}
+		t.baseURL = baseURL
+	}
+}
+
+// IssuesParams represents the input for the GitHubIssuesTool
+type IssuesParams struct {
+	Action     string `json:"action"`
+	Repository string `json:"repository"`
+	Number     int    `json:"number,omitempty"`
+	State      string `json:"state,omitempty"`
+	Body       string `json:"body,omitempty"`
+	Limit      int    `json:"limit,omitempty"`
+}
+
+// NewGitHubIssuesTool creates a new instance of GitHubIssuesTool
+func NewGitHubIssuesTool(token string, options ...IssuesOption) *GitHubIssuesTool {
+	tool := &GitHubIssuesTool{
+		token: token,
+	}
+
+	for _, option := range options {
+		option(tool)
+	}
+
+	return tool
+}
+
+// Name returns the name of the tool
+func (t *GitHubIssuesTool) Name() string {
+	return "github_issues"
+}
+
+// Description returns the description of the tool
+func (t *GitHubIssuesTool) Description() string {
+	return "Reads GitHub issues and pull requests and posts comments on them"
+}
+
+// Parameters returns the parameters that the tool accepts
+func (t *GitHubIssuesTool) Parameters() map[string]interfaces.ParameterSpec {
+	return map[string]interfaces.ParameterSpec{
+		"action": {
+			Type:        "string",
+			Description: "The action to perform",
+			Required:    true,
+			Enum:        []interface{}{"list_issues", "get_issue", "list_pull_requests", "get_pull_request", "comment"},
+		},
+		"repository": {
+			Type:        "string",
+			Description: "The repository in owner/name form",
+			Required:    true,
+		},
+		"number": {
+			Type:        "number",
+			Description: "The issue or pull request number (required for get_issue, get_pull_request and comment)",
+			Required:    false,
+		},
+		"state": {
+			Type:        "string",
+			Description: "Filter by state when listing",
+			Required:    false,
+			Enum:        []interface{}{"open", "closed", "all"},
+			Default:     "open",
+		},
+		"body": {
+			Type:        "string",
+			Description: "The comment body (required for comment)",
+			Required:    false,
+		},
+		"limit": {
+			Type:        "number",
+			Description: "Maximum number of results when listing",
+			Required:    false,
+			Default:     10,
+		},
+	}
+}
+
+// Run executes the tool with the given input. In a dry run comments are
+// simulated instead of posted.
+func (t *GitHubIssuesTool) Run(ctx context.Context, input string) (string, error) {
+	if runctx.DryRun(ctx) && t.HasSideEffects(input) {
+		return t.DryRun(ctx, input)
+	}
+
+	params, owner, repo, err := parseIssuesParams(input)
+	if err != nil {
+		return "", err
+	}
+
+	client, err := t.client(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	var result interface{}
+	switch params.Action {
+	case "list_issues":
+		result, err = t.listIssues(ctx, client, owner, repo, params)
+	case "get_issue":
+		if params.Number == 0 {
+			return "", fmt.Errorf("number parameter is required for get_issue")
+		}
+		result, err = t.getIssue(ctx, client, owner, repo, params.Number)
+	case "list_pull_requests":
+		result, err = t.listPullRequests(ctx, client, owner, repo, params)
+	case "get_pull_request":
+		if params.Number == 0 {
+			return "", fmt.Errorf("number parameter is required for get_pull_request")
+		}
+		result, err = t.getPullRequest(ctx, client, owner, repo, params.Number)
+	case "comment":
+		if err := validateComment(params); err != nil {
+			return "", err
+		}
+		result, err = t.comment(ctx, client, owner, repo, params.Number, params.Body)
+	default:
+		return "", fmt.Errorf("unknown action: %s", params.Action)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	output, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal results: %w", err)
+	}
+
+	return string(output), nil
+}
+
+// Execute implements the tool interface
+func (t *GitHubIssuesTool) Execute(ctx context.Context, args string) (string, error) {
+	return t.Run(ctx, args)
+}
+
+// HasSideEffects reports whether the call posts a comment. Calls that can't be
+// parsed are treated as having side effects.
+func (t *GitHubIssuesTool) HasSideEffects(args string) bool {
+	var params IssuesParams
+	if err := json.Unmarshal([]byte(args), &params); err != nil {
+		return true
+	}
+	return params.Action == "comment"
+}
+
+// DryRun validates a comment and returns it without posting it or contacting
+// GitHub. The other actions only read, so they run as usual.
+func (t *GitHubIssuesTool) DryRun(ctx context.Context, args string) (string, error) {
+	params, _, _, err := parseIssuesParams(args)
+	if err != nil {
+		return "", err
+	}
+	if params.Action != "comment" {
+		return t.Run(ctx, args)
+	}
+	if err := validateComment(params); err != nil {
+		return "", err
+	}
+
+	output, err := json.MarshalIndent(map[string]interface{}{
+		"dry_run":    true,
+		"action":     params.Action,
+		"repository": params.Repository,
+		"number":     params.Number,
+		"body":       params.Body,
+	}, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal results: %w", err)
+	}
+	return string(output), nil
+}
+
+// parseIssuesParams parses the input and splits the repository into owner and name
+func parseIssuesParams(input string) (IssuesParams, string, string, error) {
+	var params IssuesParams
+	if err := json.Unmarshal([]byte(input), &params); err != nil {
+		return params, "", "", fmt.Errorf("failed to parse input: %w", err)
+	}
+
+	owner, repo, found := strings.Cut(params.Repository, "/")
+	if !found || owner == "" || repo == "" {
+		return params, "", "", fmt.Errorf("invalid repository format %s, expected owner/name", params.Repository)
+	}
+
+	if params.State == "" {
+		params.State = "open"
+	}
+	if params.Limit <= 0 {
+		params.Limit = 10
+	}
+	return params, owner, repo, nil
+}
+
+// validateComment checks the parameters of a comment
+func validateComment(params IssuesParams) error {
+	if params.Number == 0 || params.Body == "" {
+		return fmt.Errorf("number and body parameters are required for comment")
+	}
+	return nil
+}
+
+// client creates a GitHub client authenticated for the organization in the context
+func (t *GitHubIssuesTool) client(ctx context.Context) (*github.Client, error) {
+	token := t.token
+	if t.tokenProvider != nil {
+		orgToken, err := t.tokenProvider.GetOAuthToken(ctx, "github")
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve GitHub token: %w", err)
+		}
+		token = orgToken
+	}
+
+	var client *github.Client
+	if token != "" {
+		ts := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token})
+		client = github.NewClient(oauth2.NewClient(ctx, ts))
+	} else {
+		client = github.NewClient(nil)
+	}
+
+	if t.baseURL != "" {
+		baseURL, err := url.Parse(t.baseURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid base URL: %w", err)
+		}
+		client.BaseURL = baseURL
+	}
+
+	return client, nil
+}
+
+// IssueSummary is a condensed view of an issue or pull request
+type IssueSummary struct {
+	Number    int      `json:"number"`
+	Title     string   `json:"title"`
+	State     string   `json:"state"`
+	Author    string   `json:"author"`
+	URL       string   `json:"url"`
+	Labels    []string `json:"labels,omitempty"`
+	Body      string   `json:"body,omitempty"`
+	Comments  int      `json:"comments"`
+	CreatedAt string   `json:"created_at"`
+}
+
+func (t *GitHubIssuesTool) listIssues(ctx context.Context, client *github.Client, owner, repo string, params IssuesParams) ([]IssueSummary, error) {
+	issues, _, err := client.Issues.ListByRepo(ctx, owner, repo, &github.IssueListByRepoOptions{
+		State:       params.State,
+		ListOptions: github.ListOptions{PerPage: params.Limit},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list issues: %w", err)
+	}
+
+	summaries := make([]IssueSummary, 0, len(issues))
+	for _, issue := range issues {
+		// The issues endpoint also returns pull requests
+		if issue.IsPullRequest() {
+			continue
+		}
+		summary := summarizeIssue(issue)
+		summary.Body = ""
+		summaries = append(summaries, summary)
+	}
+
+	return summaries, nil
+}
+
+func (t *GitHubIssuesTool) getIssue(ctx context.Context, client *github.Client, owner, repo string, number int) (IssueSummary, error) {
+	issue, _, err := client.Issues.Get(ctx, owner, repo, number)
+	if err != nil {
+		return IssueSummary{}, fmt.Errorf("failed to get issue: %w", err)
+	}
+
+	return summarizeIssue(issue), nil
+}
+
+func (t *GitHubIssuesTool) listPullRequests(ctx context.Context, client *github.Client, owner, repo string, params IssuesParams) ([]IssueSummary, error) {
+	prs, _, err := client.PullRequests.List(ctx, owner, repo, &github.PullRequestListOptions{
+		State:       params.State,
+		ListOptions: github.ListOptions{PerPage: params.Limit},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pull requests: %w", err)
+	}
+
+	summaries := make([]IssueSummary, 0, len(prs))
+	for _, pr := range prs {
+		summary := summarizePullRequest(pr)
+		summary.Body = ""
+		summaries = append(summaries, summary)
+	}
+
+	return summaries, nil
+}
+
+func (t *GitHubIssuesTool) getPullRequest(ctx context.Context, client *github.Client, owner, repo string, number int) (IssueSummary, error) {
+	pr, _, err := client.PullRequests.Get(ctx, owner, repo, number)
+	if err != nil {
+		return IssueSummary{}, fmt.Errorf("failed to get pull request: %w", err)
+	}
+
+	return summarizePullRequest(pr), nil
+}
+
+func (t *GitHubIssuesTool) comment(ctx context.Context, client *github.Client, owner, repo string, number int, body string) (map[string]interface{}, error) {
+	comment, _, err := client.Issues.CreateComment(ctx, owner, repo, number, &github.IssueComment{
+		Body: github.String(body),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create comment: %w", err)
+	}
+
+	return map[string]interface{}{
+		"id":  comment.GetID(),
+		"url": comment.GetHTMLURL(),
+	}, nil
+}
+
+func summarizeIssue(issue *github.Issue) IssueSummary {
+	labels := make([]string, 0, len(issue.Labels))
+	for _, label := range issue.Labels {
+		labels = append(labels, label.GetName())
+	}
+
+	return IssueSummary{
+		Number:    issue.GetNumber(),
+		Title:     issue.GetTitle(),
+		State:     issue.GetState(),
+		Author:    issue.GetUser().GetLogin(),
+		URL:       issue.GetHTMLURL(),
+		Labels:    labels,
+		Body:      issue.GetBody(),
+		Comments:  issue.GetComments(),
+		CreatedAt: issue.GetCreatedAt().String(),
+	}
+}
+
+func summarizePullRequest(pr *github.PullRequest) IssueSummary {
+	labels := make([]string, 0, len(pr.Labels))
+	for _, label := range pr.Labels {
+		labels = append(labels, label.GetName())
+	}
+
+	return IssueSummary{
+		Number:    pr.GetNumber(),
+		Title:     pr.GetTitle(),
+		State:     pr.GetState(),
+		Author:    pr.GetUser().GetLogin(),
+		URL:       pr.GetHTMLURL(),
+		Labels:    labels,
+		Body:      pr.GetBody(),
+		Comments:  pr.GetComments(),
+		CreatedAt: pr.GetCreatedAt().String(),
+	}
+}
@@ -0,0 +1,120 @@
+package github_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+	"github.com/run-bigpig/llm-agent/pkg/multitenancy"
+	"github.com/run-bigpig/llm-agent/pkg/runctx"
+	"github.com/run-bigpig/llm-agent/pkg/tools/github"
+)
+
+func TestGitHubIssuesComment(t *testing.T) {
+	var authorization string
+	var comment map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/repos/acme/api/issues/7/comments" {
+			t.Errorf("Unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		authorization = r.Header.Get("Authorization")
+		if err := json.NewDecoder(r.Body).Decode(&comment); err != nil {
+			t.Errorf("Failed to decode comment: %v", err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id": 42, "html_url": "https://github.com/acme/api/issues/7#issuecomment-42"}`))
+	}))
+	defer server.Close()
+
+	tool := github.NewGitHubIssuesTool("static-token",
+		github.WithBaseURL(server.URL),
+		github.WithTokenProvider(multitenancy.StaticTokens{"org-a": "token-a"}),
+	)
+
+	ctx := multitenancy.WithOrgID(context.Background(), "org-a")
+	result, err := tool.Execute(ctx, `{"action": "comment", "repository": "acme/api", "number": 7, "body": "Fixed in #8"}`)
+	if err != nil {
+		t.Fatalf("Failed to execute tool: %v", err)
+	}
+	if !strings.Contains(result, "issuecomment-42") {
+		t.Errorf("Expected the comment URL, got %s", result)
+	}
+	if authorization != "Bearer token-a" {
+		t.Errorf("Expected the organization's token, got %q", authorization)
+	}
+	if comment["body"] != "Fixed in #8" {
+		t.Errorf("Unexpected comment %v", comment)
+	}
+
+	// An organization without a token fails instead of using the static token
+	authorization = ""
+	if _, err := tool.Execute(multitenancy.WithOrgID(context.Background(), "org-b"), `{"action": "comment", "repository": "acme/api", "number": 7, "body": "Fixed in #8"}`); err == nil {
+		t.Error("Expected an error for an organization without a token")
+	}
+	if authorization != "" {
+		t.Errorf("Expected no request to be sent, got one with %q", authorization)
+	}
+}
+
+func TestGitHubIssuesStaticToken(t *testing.T) {
+	var authorization string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"number": 7, "title": "Bug"}`))
+	}))
+	defer server.Close()
+
+	// Without a provider the static token is used
+	tool := github.NewGitHubIssuesTool("static-token", github.WithBaseURL(server.URL))
+	ctx := multitenancy.WithOrgID(context.Background(), "org-b")
+	if _, err := tool.Execute(ctx, `{"action": "get_issue", "repository": "acme/api", "number": 7}`); err != nil {
+		t.Fatalf("Failed to execute tool: %v", err)
+	}
+	if authorization != "Bearer static-token" {
+		t.Errorf("Expected the static token, got %q", authorization)
+	}
+}
+
+func TestGitHubIssuesCommentDryRun(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id": 42}`))
+	}))
+	defer server.Close()
+
+	tool := github.NewGitHubIssuesTool("static-token", github.WithBaseURL(server.URL))
+	var _ interfaces.ToolWithSideEffects = tool
+	var _ interfaces.ToolWithDryRun = tool
+
+	args := `{"action": "comment", "repository": "acme/api", "number": 7, "body": "Fixed in #8"}`
+	if !tool.HasSideEffects(args) {
+		t.Error("Expected a comment to have side effects")
+	}
+	if tool.HasSideEffects(`{"action": "get_issue", "repository": "acme/api", "number": 7}`) {
+		t.Error("Expected reading an issue to have no side effects")
+	}
+
+	ctx := runctx.WithDryRun(context.Background(), true)
+	result, err := tool.Execute(ctx, args)
+	if err != nil {
+		t.Fatalf("Failed to execute tool: %v", err)
+	}
+	if !strings.Contains(result, `"dry_run": true`) || !strings.Contains(result, "Fixed in #8") {
+		t.Errorf("Expected the simulated comment, got %s", result)
+	}
+	if requests != 0 {
+		t.Errorf("Expected no request to be sent, got %d", requests)
+	}
+
+	// Invalid comments fail as they would for real
+	if _, err := tool.Execute(ctx, `{"action": "comment", "repository": "acme/api", "number": 7}`); err == nil {
+		t.Error("Expected an error for a comment without a body")
+	}
+}
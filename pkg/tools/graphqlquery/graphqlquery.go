@@ -0,0 +1,506 @@
+package graphqlquery
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+	"github.com/run-bigpig/llm-agent/pkg/multitenancy"
+)
+
+// introspectionQuery fetches the types, fields and arguments of the schema
+const introspectionQuery = `query IntrospectionQuery {
+  __schema {
+    queryType { name }
+    types {
+      kind
+      name
+      description
+      fields {
+        name
+        description
+        args { name type { ...TypeRef } }
+        type { ...TypeRef }
+      }
+    }
+  }
+}
+
+fragment TypeRef on __Type {
+  kind
+  name
+  ofType { kind name ofType { kind name ofType { kind name ofType { kind name } } } }
+}`
+
+// typeRef is a reference to a type in the introspection result
+type typeRef struct {
+	Kind   string   `json:"kind"`
+	Name   string   `json:"name"`
+	OfType *typeRef `json:"ofType"`
+}
+
+// named returns the name of the innermost named type
+func (t *typeRef) named() string {
+	for t != nil {
+		if t.Name != "" {
+			return t.Name
+		}
+		t = t.OfType
+	}
+	return ""
+}
+
+// String formats the type reference in GraphQL syntax, e.g. [User!]!
+func (t *typeRef) String() string {
+	if t == nil {
+		return ""
+	}
+	switch t.Kind {
+	case "NON_NULL":
+		return t.OfType.String() + "!"
+	case "LIST":
+		return "[" + t.OfType.String() + "]"
+	default:
+		return t.Name
+	}
+}
+
+// schemaField is a field of an object type
+type schemaField struct {
+	Name        string  `json:"name"`
+	Description string  `json:"description"`
+	Type        typeRef `json:"type"`
+	Args        []struct {
+		Name string  `json:"name"`
+		Type typeRef `json:"type"`
+	} `json:"args"`
+}
+
+// schemaType is a type in the schema
+type schemaType struct {
+	Kind        string        `json:"kind"`
+	Name        string        `json:"name"`
+	Description string        `json:"description"`
+	Fields      []schemaField `json:"fields"`
+}
+
+// schema is the part of the introspection result used for validation
+type schema struct {
+	queryType string
+	types     map[string]*schemaType
+}
+
+// field returns the named field of the type
+func (s *schema) field(typeName, fieldName string) (*schemaField, bool) {
+	t, ok := s.types[typeName]
+	if !ok {
+		return nil, false
+	}
+	for i := range t.Fields {
+		if t.Fields[i].Name == fieldName {
+			return &t.Fields[i], true
+		}
+	}
+	return nil, false
+}
+
+// Tool queries a GraphQL endpoint with queries constrained to allowed types
+// and fields and a maximum depth
+type Tool struct {
+	endpoint      string
+	httpClient    *http.Client
+	headers       map[string]string
+	allowedTypes  map[string]bool
+	allowedFields map[string]bool
+	maxDepth      int
+	maxResultSize int
+
+	mu     sync.RWMutex
+	schema *schema
+}
+
+// Input represents the input for the GraphQL tool
+type Input struct {
+	Query     string                 `json:"query"`
+	Variables map[string]interface{} `json:"variables,omitempty"`
+}
+
+// Option represents an option for configuring the tool
+type Option func(*Tool)
+
+// WithHTTPClient sets the HTTP client for the tool
+func WithHTTPClient(client *http.Client) Option {
+	return func(t *Tool) {
+		t.httpClient = client
+	}
+}
+
+// WithHeader sets a header sent with every request, e.g. Authorization
+func WithHeader(key, value string) Option {
+	return func(t *Tool) {
+		t.headers[key] = value
+	}
+}
+
+// WithAllowedTypes allows every field of the given types, including the root
+// query type (usually "Query"). When no types or fields are allowed
+// explicitly, the whole schema may be queried.
+func WithAllowedTypes(types ...string) Option {
+	return func(t *Tool) {
+		for _, typ := range types {
+			t.allowedTypes[typ] = true
+		}
+	}
+}
+
+// WithAllowedFields allows individual fields, written as "Type.field"
+func WithAllowedFields(fields ...string) Option {
+	return func(t *Tool) {
+		for _, f := range fields {
+			t.allowedFields[f] = true
+		}
+	}
+}
+
+// WithMaxDepth sets the maximum selection depth of a query
+func WithMaxDepth(depth int) Option {
+	return func(t *Tool) {
+		t.maxDepth = depth
+	}
+}
+
+// WithMaxResultSize caps the number of result bytes returned to the agent
+func WithMaxResultSize(size int) Option {
+	return func(t *Tool) {
+		t.maxResultSize = size
+	}
+}
+
+// New creates a new GraphQL query tool for the endpoint
+func New(endpoint string, options ...Option) *Tool {
+	tool := &Tool{
+		endpoint:      endpoint,
+		httpClient:    &http.Client{Timeout: 30 * time.Second},
+		headers:       make(map[string]string),
+		allowedTypes:  make(map[string]bool),
+		allowedFields: make(map[string]bool),
+		maxDepth:      5,
+		maxResultSize: 32 * 1024,
+	}
+
+	for _, option := range options {
+		option(tool)
+	}
+
+	return tool
+}
+
+// Name returns the name of the tool
+func (t *Tool) Name() string {
+	return "graphql_query"
+}
+
+// Description returns a description of what the tool does. Once the schema
+// has been introspected it includes the queryable types and fields.
+func (t *Tool) Description() string {
+	description := fmt.Sprintf("Run a read-only GraphQL query (no mutations or fragments, maximum depth %d)", t.maxDepth)
+
+	t.mu.RLock()
+	s := t.schema
+	t.mu.RUnlock()
+	if s == nil {
+		return description
+	}
+
+	return description + ". Available schema:\n" + t.describeSchema(s)
+}
+
+// Parameters returns the parameters that the tool accepts
+func (t *Tool) Parameters() map[string]interfaces.ParameterSpec {
+	return map[string]interfaces.ParameterSpec{
+		"query": {
+			Type:        "string",
+			Description: "The GraphQL query",
+			Required:    true,
+		},
+		"variables": {
+			Type:        "object",
+			Description: "Variables referenced by the query",
+			Required:    false,
+		},
+	}
+}
+
+// Run executes the tool with the given input
+func (t *Tool) Run(ctx context.Context, input string) (string, error) {
+	var params Input
+	if err := json.Unmarshal([]byte(input), &params); err != nil {
+		// If not JSON, treat the input as the query
+		params = Input{Query: input}
+	}
+
+	return t.query(ctx, params)
+}
+
+// Execute executes the tool with the given arguments
+func (t *Tool) Execute(ctx context.Context, args string) (string, error) {
+	var params Input
+	if err := json.Unmarshal([]byte(args), &params); err != nil {
+		return "", fmt.Errorf("failed to parse args: %w", err)
+	}
+
+	return t.query(ctx, params)
+}
+
+// Introspect loads the schema from the endpoint. It is called automatically
+// before the first query, but calling it up front lets Description include
+// the schema.
+func (t *Tool) Introspect(ctx context.Context) error {
+	var result struct {
+		Schema struct {
+			QueryType struct {
+				Name string `json:"name"`
+			} `json:"queryType"`
+			Types []*schemaType `json:"types"`
+		} `json:"__schema"`
+	}
+
+	data, err := t.do(ctx, Input{Query: introspectionQuery})
+	if err != nil {
+		return fmt.Errorf("failed to introspect schema: %w", err)
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return fmt.Errorf("failed to parse introspection result: %w", err)
+	}
+
+	s := &schema{
+		queryType: result.Schema.QueryType.Name,
+		types:     make(map[string]*schemaType, len(result.Schema.Types)),
+	}
+	for _, typ := range result.Schema.Types {
+		s.types[typ.Name] = typ
+	}
+	if s.queryType == "" {
+		return fmt.Errorf("schema has no query type")
+	}
+
+	t.mu.Lock()
+	t.schema = s
+	t.mu.Unlock()
+
+	return nil
+}
+
+// query validates and runs a query
+func (t *Tool) query(ctx context.Context, params Input) (string, error) {
+	if strings.TrimSpace(params.Query) == "" {
+		return "", fmt.Errorf("query parameter is required")
+	}
+
+	t.mu.RLock()
+	s := t.schema
+	t.mu.RUnlock()
+	if s == nil {
+		if err := t.Introspect(ctx); err != nil {
+			return "", err
+		}
+		t.mu.RLock()
+		s = t.schema
+		t.mu.RUnlock()
+	}
+
+	selections, err := parseQuery(params.Query)
+	if err != nil {
+		return "", fmt.Errorf("invalid query: %w", err)
+	}
+	if err := t.validate(s, s.queryType, selections, 1); err != nil {
+		return "", fmt.Errorf("query not allowed: %w", err)
+	}
+
+	data, err := t.do(ctx, params)
+	if err != nil {
+		return "", err
+	}
+
+	return t.format(data), nil
+}
+
+// validate checks the selections against the schema, allowlists and depth limit
+func (t *Tool) validate(s *schema, typeName string, selections []*field, depth int) error {
+	if depth > t.maxDepth {
+		return fmt.Errorf("query exceeds the maximum depth of %d", t.maxDepth)
+	}
+
+	for _, sel := range selections {
+		if sel.name == "__typename" {
+			continue
+		}
+		if strings.HasPrefix(sel.name, "__") {
+			return fmt.Errorf("introspection field %s is not allowed", sel.name)
+		}
+
+		f, ok := s.field(typeName, sel.name)
+		if !ok {
+			return fmt.Errorf("type %s has no field %s", typeName, sel.name)
+		}
+		if !t.isAllowed(typeName, sel.name) {
+			return fmt.Errorf("field %s.%s is not allowed", typeName, sel.name)
+		}
+
+		fieldType := f.Type.named()
+		target := s.types[fieldType]
+		isComposite := target != nil && (target.Kind == "OBJECT" || target.Kind == "INTERFACE" || target.Kind == "UNION")
+
+		switch {
+		case isComposite && len(sel.selections) == 0:
+			return fmt.Errorf("field %s.%s of type %s must have a selection of subfields", typeName, sel.name, fieldType)
+		case !isComposite && len(sel.selections) > 0:
+			return fmt.Errorf("field %s.%s of type %s cannot have subfields", typeName, sel.name, fieldType)
+		case isComposite:
+			if err := t.validate(s, fieldType, sel.selections, depth+1); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// isAllowed reports whether the field may be queried
+func (t *Tool) isAllowed(typeName, fieldName string) bool {
+	if len(t.allowedTypes) == 0 && len(t.allowedFields) == 0 {
+		return true
+	}
+	return t.allowedTypes[typeName] || t.allowedFields[typeName+"."+fieldName]
+}
+
+// do sends a GraphQL request and returns the data, or an error if the
+// response only contains errors
+func (t *Tool) do(ctx context.Context, params Input) (json.RawMessage, error) {
+	body, err := json.Marshal(params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", t.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	for key, value := range t.headers {
+		req.Header.Set(key, value)
+	}
+
+	// Add organization ID to request headers if available
+	if orgID, _ := multitenancy.GetOrgID(ctx); orgID != "" {
+		req.Header.Set("X-Organization-ID", orgID)
+	}
+
+	resp, err := t.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	var result struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("GraphQL endpoint returned status code %d", resp.StatusCode)
+		}
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	if len(result.Errors) > 0 && (len(result.Data) == 0 || string(result.Data) == "null") {
+		messages := make([]string, len(result.Errors))
+		for i, e := range result.Errors {
+			messages[i] = e.Message
+		}
+		return nil, fmt.Errorf("GraphQL errors: %s", strings.Join(messages, "; "))
+	}
+
+	return result.Data, nil
+}
+
+// format indents the result data for the model and applies the size cap
+func (t *Tool) format(data json.RawMessage) string {
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, data, "", "  "); err != nil {
+		buf.Reset()
+		buf.Write(data)
+	}
+
+	result := buf.String()
+	if t.maxResultSize > 0 && len(result) > t.maxResultSize {
+		result = result[:t.maxResultSize] + "\n[result truncated]"
+	}
+	return result
+}
+
+// describeSchema lists the queryable fields of the types reachable from the
+// query type, so the model can write valid queries
+func (t *Tool) describeSchema(s *schema) string {
+	var lines []string
+	visited := make(map[string]bool)
+	queue := []string{s.queryType}
+
+	for len(queue) > 0 {
+		typeName := queue[0]
+		queue = queue[1:]
+		if visited[typeName] {
+			continue
+		}
+		visited[typeName] = true
+
+		typ, ok := s.types[typeName]
+		if !ok {
+			continue
+		}
+
+		var fields []string
+		for _, f := range typ.Fields {
+			if !t.isAllowed(typeName, f.Name) {
+				continue
+			}
+
+			signature := f.Name
+			if len(f.Args) > 0 {
+				args := make([]string, len(f.Args))
+				for i, arg := range f.Args {
+					args[i] = arg.Name + ": " + arg.Type.String()
+				}
+				signature += "(" + strings.Join(args, ", ") + ")"
+			}
+			fields = append(fields, signature+": "+f.Type.String())
+
+			if target, ok := s.types[f.Type.named()]; ok && len(target.Fields) > 0 {
+				queue = append(queue, target.Name)
+			}
+		}
+
+		if len(fields) > 0 {
+			sort.Strings(fields)
+			lines = append(lines, fmt.Sprintf("type %s { %s }", typeName, strings.Join(fields, ", ")))
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
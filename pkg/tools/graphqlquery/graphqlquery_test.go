@@ -0,0 +1,75 @@
+package graphqlquery
+
+import (
+	"strings"
+	"testing"
+)
+
+func testSchema() *schema {
+	object := func(name string) typeRef { return typeRef{Kind: "OBJECT", Name: name} }
+	scalar := func(name string) typeRef { return typeRef{Kind: "SCALAR", Name: name} }
+	list := func(of typeRef) typeRef { return typeRef{Kind: "LIST", OfType: &of} }
+
+	return &schema{
+		queryType: "Query",
+		types: map[string]*schemaType{
+			"Query": {Kind: "OBJECT", Name: "Query", Fields: []schemaField{
+				{Name: "user", Type: object("User")},
+				{Name: "billing", Type: object("Billing")},
+			}},
+			"User": {Kind: "OBJECT", Name: "User", Fields: []schemaField{
+				{Name: "name", Type: scalar("String")},
+				{Name: "friends", Type: list(object("User"))},
+			}},
+			"Billing": {Kind: "OBJECT", Name: "Billing", Fields: []schemaField{
+				{Name: "cardNumber", Type: scalar("String")},
+			}},
+			"String": {Kind: "SCALAR", Name: "String"},
+		},
+	}
+}
+
+func TestValidateQuery(t *testing.T) {
+	tool := New("http://example.com/graphql",
+		WithAllowedFields("Query.user"),
+		WithAllowedTypes("User"),
+		WithMaxDepth(3),
+	)
+	s := testSchema()
+
+	tests := []struct {
+		query string
+		err   string
+	}{
+		{`query GetUser($id: ID! = "1") { me: user(id: $id, filter: {active: true, tags: ["a", "b"]}) { name __typename } }`, ""},
+		{`{ user { friends { name } } }`, ""},
+		{`{ user { friends { friends { name } } } }`, "maximum depth"},
+		{`{ billing { cardNumber } }`, "Query.billing is not allowed"},
+		{`{ user { email } }`, "has no field email"},
+		{`{ user }`, "must have a selection"},
+		{`{ user { name { first } } }`, "cannot have subfields"},
+		{`{ __schema { types { name } } }`, "introspection"},
+		{`mutation { deleteUser(id: 1) { name } }`, "only queries"},
+		{`{ user { ...UserFields } }`, "fragments"},
+		{`{ user { name }`, "unexpected end"},
+	}
+
+	for _, tt := range tests {
+		selections, err := parseQuery(tt.query)
+		if err == nil {
+			err = tool.validate(s, s.queryType, selections, 1)
+		}
+
+		switch {
+		case tt.err == "" && err != nil:
+			t.Errorf("Expected %q to be valid, got %v", tt.query, err)
+		case tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)):
+			t.Errorf("Expected error containing %q for %q, got %v", tt.err, tt.query, err)
+		}
+	}
+
+	description := tool.describeSchema(s)
+	if strings.Contains(description, "billing") || !strings.Contains(description, "friends: [User]") {
+		t.Errorf("Expected schema description to only list allowed fields, got %s", description)
+	}
+}
@@ -0,0 +1,399 @@
+package graphqlquery
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// field is a field selection in a parsed query
+type field struct {
+	name       string
+	selections []*field
+}
+
+// tokenKind is the kind of a lexical token
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenPunct
+	tokenName
+	tokenNumber
+	tokenString
+)
+
+// token is a lexical token
+type token struct {
+	kind  tokenKind
+	value string
+	pos   int
+}
+
+// lex splits a GraphQL document into tokens, skipping whitespace, commas and comments
+func lex(src string) ([]token, error) {
+	var tokens []token
+	i := 0
+	for i < len(src) {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',':
+			i++
+		case c == '#':
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+		case strings.HasPrefix(src[i:], "..."):
+			tokens = append(tokens, token{kind: tokenPunct, value: "...", pos: i})
+			i += 3
+		case strings.ContainsRune("!$():=@[]{}|&", rune(c)):
+			tokens = append(tokens, token{kind: tokenPunct, value: string(c), pos: i})
+			i++
+		case c == '_' || unicode.IsLetter(rune(c)):
+			start := i
+			for i < len(src) && (src[i] == '_' || unicode.IsLetter(rune(src[i])) || unicode.IsDigit(rune(src[i]))) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenName, value: src[start:i], pos: start})
+		case c == '-' || unicode.IsDigit(rune(c)):
+			start := i
+			i++
+			for i < len(src) && strings.ContainsRune("0123456789.eE+-", rune(src[i])) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenNumber, value: src[start:i], pos: start})
+		case c == '"':
+			start := i
+			if strings.HasPrefix(src[i:], `"""`) {
+				end := strings.Index(src[i+3:], `"""`)
+				if end < 0 {
+					return nil, fmt.Errorf("unterminated block string at position %d", start)
+				}
+				i += end + 6
+			} else {
+				i++
+				for i < len(src) && src[i] != '"' {
+					if src[i] == '\\' {
+						i++
+					}
+					if i < len(src) && src[i] == '\n' {
+						return nil, fmt.Errorf("unterminated string at position %d", start)
+					}
+					i++
+				}
+				if i >= len(src) {
+					return nil, fmt.Errorf("unterminated string at position %d", start)
+				}
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenString, value: src[start:i], pos: start})
+		default:
+			return nil, fmt.Errorf("unexpected character %q at position %d", c, i)
+		}
+	}
+	tokens = append(tokens, token{kind: tokenEOF, pos: len(src)})
+	return tokens, nil
+}
+
+// parser is a recursive descent parser for GraphQL query documents. Only a
+// single query operation without fragments is accepted.
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+// parseQuery parses a query document and returns its top-level selections
+func parseQuery(src string) ([]*field, error) {
+	tokens, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	selections, err := p.parseOperation()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.peek().kind != tokenEOF {
+		t := p.peek()
+		if t.kind == tokenName && t.value == "fragment" {
+			return nil, fmt.Errorf("fragments are not supported")
+		}
+		return nil, fmt.Errorf("only a single query operation is allowed")
+	}
+
+	return selections, nil
+}
+
+// peek returns the current token
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+// next consumes and returns the current token
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+// isPunct reports whether the current token is the punctuator
+func (p *parser) isPunct(value string) bool {
+	t := p.peek()
+	return t.kind == tokenPunct && t.value == value
+}
+
+// expectPunct consumes the punctuator or returns an error
+func (p *parser) expectPunct(value string) error {
+	t := p.next()
+	if t.kind != tokenPunct || t.value != value {
+		return p.unexpected(t, fmt.Sprintf("%q", value))
+	}
+	return nil
+}
+
+// expectName consumes a name or returns an error
+func (p *parser) expectName() (string, error) {
+	t := p.next()
+	if t.kind != tokenName {
+		return "", p.unexpected(t, "a name")
+	}
+	return t.value, nil
+}
+
+// unexpected returns a syntax error for the token
+func (p *parser) unexpected(t token, expected string) error {
+	if t.kind == tokenEOF {
+		return fmt.Errorf("unexpected end of query, expected %s", expected)
+	}
+	return fmt.Errorf("unexpected %q at position %d, expected %s", t.value, t.pos, expected)
+}
+
+// parseOperation parses a query operation or the shorthand selection set
+func (p *parser) parseOperation() ([]*field, error) {
+	if p.isPunct("{") {
+		return p.parseSelectionSet()
+	}
+
+	t := p.next()
+	if t.kind != tokenName {
+		return nil, p.unexpected(t, "a query")
+	}
+	switch t.value {
+	case "query":
+	case "mutation", "subscription":
+		return nil, fmt.Errorf("only queries are allowed, got %s", t.value)
+	case "fragment":
+		return nil, fmt.Errorf("fragments are not supported")
+	default:
+		return nil, p.unexpected(t, "a query")
+	}
+
+	// Optional operation name
+	if p.peek().kind == tokenName {
+		p.next()
+	}
+
+	if p.isPunct("(") {
+		if err := p.parseVariableDefinitions(); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := p.parseDirectives(); err != nil {
+		return nil, err
+	}
+
+	return p.parseSelectionSet()
+}
+
+// parseVariableDefinitions parses ($name: Type = default, ...)
+func (p *parser) parseVariableDefinitions() error {
+	if err := p.expectPunct("("); err != nil {
+		return err
+	}
+	for !p.isPunct(")") {
+		if err := p.expectPunct("$"); err != nil {
+			return err
+		}
+		if _, err := p.expectName(); err != nil {
+			return err
+		}
+		if err := p.expectPunct(":"); err != nil {
+			return err
+		}
+		if err := p.parseType(); err != nil {
+			return err
+		}
+		if p.isPunct("=") {
+			p.next()
+			if err := p.parseValue(); err != nil {
+				return err
+			}
+		}
+	}
+	return p.expectPunct(")")
+}
+
+// parseType parses a type reference such as [ID!]!
+func (p *parser) parseType() error {
+	if p.isPunct("[") {
+		p.next()
+		if err := p.parseType(); err != nil {
+			return err
+		}
+		if err := p.expectPunct("]"); err != nil {
+			return err
+		}
+	} else if _, err := p.expectName(); err != nil {
+		return err
+	}
+
+	if p.isPunct("!") {
+		p.next()
+	}
+	return nil
+}
+
+// parseSelectionSet parses { field ... }
+func (p *parser) parseSelectionSet() ([]*field, error) {
+	if err := p.expectPunct("{"); err != nil {
+		return nil, err
+	}
+
+	var selections []*field
+	for !p.isPunct("}") {
+		if p.isPunct("...") {
+			return nil, fmt.Errorf("fragments are not supported")
+		}
+
+		f, err := p.parseField()
+		if err != nil {
+			return nil, err
+		}
+		selections = append(selections, f)
+	}
+	p.next()
+
+	if len(selections) == 0 {
+		return nil, fmt.Errorf("selection set cannot be empty")
+	}
+	return selections, nil
+}
+
+// parseField parses alias: name(args) @directives { selections }
+func (p *parser) parseField() (*field, error) {
+	name, err := p.expectName()
+	if err != nil {
+		return nil, err
+	}
+
+	// The first name was an alias
+	if p.isPunct(":") {
+		p.next()
+		if name, err = p.expectName(); err != nil {
+			return nil, err
+		}
+	}
+
+	f := &field{name: name}
+
+	if p.isPunct("(") {
+		if err := p.parseArguments(); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := p.parseDirectives(); err != nil {
+		return nil, err
+	}
+
+	if p.isPunct("{") {
+		if f.selections, err = p.parseSelectionSet(); err != nil {
+			return nil, err
+		}
+	}
+
+	return f, nil
+}
+
+// parseArguments parses (name: value, ...)
+func (p *parser) parseArguments() error {
+	if err := p.expectPunct("("); err != nil {
+		return err
+	}
+	for !p.isPunct(")") {
+		if _, err := p.expectName(); err != nil {
+			return err
+		}
+		if err := p.expectPunct(":"); err != nil {
+			return err
+		}
+		if err := p.parseValue(); err != nil {
+			return err
+		}
+	}
+	return p.expectPunct(")")
+}
+
+// parseDirectives parses @name(args) ...
+func (p *parser) parseDirectives() error {
+	for p.isPunct("@") {
+		p.next()
+		if _, err := p.expectName(); err != nil {
+			return err
+		}
+		if p.isPunct("(") {
+			if err := p.parseArguments(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// parseValue parses a literal, variable, list or object value
+func (p *parser) parseValue() error {
+	t := p.peek()
+	switch {
+	case t.kind == tokenPunct && t.value == "$":
+		p.next()
+		_, err := p.expectName()
+		return err
+	case t.kind == tokenPunct && t.value == "[":
+		p.next()
+		for !p.isPunct("]") {
+			if p.peek().kind == tokenEOF {
+				return p.unexpected(p.peek(), `"]"`)
+			}
+			if err := p.parseValue(); err != nil {
+				return err
+			}
+		}
+		p.next()
+		return nil
+	case t.kind == tokenPunct && t.value == "{":
+		p.next()
+		for !p.isPunct("}") {
+			if _, err := p.expectName(); err != nil {
+				return err
+			}
+			if err := p.expectPunct(":"); err != nil {
+				return err
+			}
+			if err := p.parseValue(); err != nil {
+				return err
+			}
+		}
+		p.next()
+		return nil
+	case t.kind == tokenName || t.kind == tokenNumber || t.kind == tokenString:
+		p.next()
+		return nil
+	default:
+		return p.unexpected(t, "a value")
+	}
+}
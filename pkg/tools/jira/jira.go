@@ -0,0 +1,298 @@
+package jira
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+	"github.com/run-bigpig/llm-agent/pkg/multitenancy"
+)
+
+// Tool implements a Jira tool for searching and creating issues
+type Tool struct {
+	baseURL       string
+	token         string
+	email         string
+	tokenProvider multitenancy.TokenProvider
+	httpClient    *http.Client
+}
+
+// Input represents the input for the Jira tool
+type Input struct {
+	Action      string `json:"action"`
+	JQL         string `json:"jql,omitempty"`
+	MaxResults  int    `json:"max_results,omitempty"`
+	Project     string `json:"project,omitempty"`
+	Summary     string `json:"summary,omitempty"`
+	Description string `json:"description,omitempty"`
+	IssueType   string `json:"issue_type,omitempty"`
+}
+
+// Option represents an option for configuring the tool
+type Option func(*Tool)
+
+// WithHTTPClient sets the HTTP client for the tool
+func WithHTTPClient(client *http.Client) Option {
+	return func(t *Tool) {
+		t.httpClient = client
+	}
+}
+
+// WithBasicAuth authenticates with an account email and API token instead of a bearer token
+func WithBasicAuth(email string) Option {
+	return func(t *Tool) {
+		t.email = email
+	}
+}
+
+// WithTokenProvider resolves the access token per organization instead of using the static token
+func WithTokenProvider(provider multitenancy.TokenProvider) Option {
+	return func(t *Tool) {
+		t.tokenProvider = provider
+	}
+}
+
+// New creates a new Jira tool. baseURL is the site URL (e.g., https://example.atlassian.net)
+// or the OAuth API gateway URL (https://api.atlassian.com/ex/jira/<cloud-id>).
+func New(baseURL, token string, options ...Option) *Tool {
+	tool := &Tool{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		token:      token,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+
+	for _, option := range options {
+		option(tool)
+	}
+
+	return tool
+}
+
+// Name returns the name of the tool
+func (t *Tool) Name() string {
+	return "jira"
+}
+
+// Description returns a description of what the tool does
+func (t *Tool) Description() string {
+	return "Search Jira issues with JQL and create new issues"
+}
+
+// Parameters returns the parameters that the tool accepts
+func (t *Tool) Parameters() map[string]interfaces.ParameterSpec {
+	return map[string]interfaces.ParameterSpec{
+		"action": {
+			Type:        "string",
+			Description: "The action to perform",
+			Required:    true,
+			Enum:        []interface{}{"search", "create"},
+		},
+		"jql": {
+			Type:        "string",
+			Description: "The JQL query (required for search)",
+			Required:    false,
+		},
+		"max_results": {
+			Type:        "number",
+			Description: "Maximum number of issues to return when searching",
+			Required:    false,
+			Default:     10,
+		},
+		"project": {
+			Type:        "string",
+			Description: "The project key (required for create)",
+			Required:    false,
+		},
+		"summary": {
+			Type:        "string",
+			Description: "The issue summary (required for create)",
+			Required:    false,
+		},
+		"description": {
+			Type:        "string",
+			Description: "The issue description",
+			Required:    false,
+		},
+		"issue_type": {
+			Type:        "string",
+			Description: "The issue type",
+			Required:    false,
+			Default:     "Task",
+		},
+	}
+}
+
+// Run executes the tool with the given input
+func (t *Tool) Run(ctx context.Context, input string) (string, error) {
+	return t.Execute(ctx, input)
+}
+
+// HasSideEffects reports whether the call creates issues
+func (t *Tool) HasSideEffects(args string) bool {
+	var params Input
+	if err := json.Unmarshal([]byte(args), &params); err != nil {
+		// Assume the worst for arguments that can't be read
+		return true
+	}
+	return params.Action == "create"
+}
+
+// Execute executes the tool with the given arguments
+func (t *Tool) Execute(ctx context.Context, args string) (string, error) {
+	var params Input
+	if err := json.Unmarshal([]byte(args), &params); err != nil {
+		return "", fmt.Errorf("failed to parse args: %w", err)
+	}
+
+	switch params.Action {
+	case "search":
+		return t.search(ctx, params)
+	case "create":
+		return t.create(ctx, params)
+	default:
+		return "", fmt.Errorf("unknown action: %s", params.Action)
+	}
+}
+
+// search runs a JQL search and formats the matching issues
+func (t *Tool) search(ctx context.Context, params Input) (string, error) {
+	if params.JQL == "" {
+		return "", fmt.Errorf("jql parameter is required for search")
+	}
+	if params.MaxResults <= 0 {
+		params.MaxResults = 10
+	}
+
+	query := url.Values{}
+	query.Set("jql", params.JQL)
+	query.Set("maxResults", strconv.Itoa(params.MaxResults))
+	query.Set("fields", "summary,status,assignee,issuetype,priority")
+
+	var result struct {
+		Total  int `json:"total"`
+		Issues []struct {
+			Key    string `json:"key"`
+			Fields struct {
+				Summary string `json:"summary"`
+				Status  struct {
+					Name string `json:"name"`
+				} `json:"status"`
+				Assignee *struct {
+					DisplayName string `json:"displayName"`
+				} `json:"assignee"`
+				IssueType struct {
+					Name string `json:"name"`
+				} `json:"issuetype"`
+			} `json:"fields"`
+		} `json:"issues"`
+	}
+	if err := t.do(ctx, "GET", "/rest/api/2/search?"+query.Encode(), nil, &result); err != nil {
+		return "", err
+	}
+
+	if len(result.Issues) == 0 {
+		return "No issues found.", nil
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Found %d issues (showing %d):\n\n", result.Total, len(result.Issues)))
+	for _, issue := range result.Issues {
+		assignee := "Unassigned"
+		if issue.Fields.Assignee != nil {
+			assignee = issue.Fields.Assignee.DisplayName
+		}
+		sb.WriteString(fmt.Sprintf("%s [%s] %s\n", issue.Key, issue.Fields.Status.Name, issue.Fields.Summary))
+		sb.WriteString(fmt.Sprintf("   Type: %s, Assignee: %s\n", issue.Fields.IssueType.Name, assignee))
+	}
+
+	return sb.String(), nil
+}
+
+// create creates a new issue
+func (t *Tool) create(ctx context.Context, params Input) (string, error) {
+	if params.Project == "" || params.Summary == "" {
+		return "", fmt.Errorf("project and summary parameters are required for create")
+	}
+	if params.IssueType == "" {
+		params.IssueType = "Task"
+	}
+
+	body := map[string]interface{}{
+		"fields": map[string]interface{}{
+			"project":     map[string]string{"key": params.Project},
+			"summary":     params.Summary,
+			"description": params.Description,
+			"issuetype":   map[string]string{"name": params.IssueType},
+		},
+	}
+
+	var result struct {
+		Key  string `json:"key"`
+		Self string `json:"self"`
+	}
+	if err := t.do(ctx, "POST", "/rest/api/2/issue", body, &result); err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("Created issue %s (%s/browse/%s)", result.Key, t.baseURL, result.Key), nil
+}
+
+// do sends an authenticated request to the Jira REST API and decodes the response
+func (t *Tool) do(ctx context.Context, method, path string, body interface{}, out interface{}) error {
+	token := t.token
+	if t.tokenProvider != nil {
+		orgToken, err := t.tokenProvider.GetOAuthToken(ctx, "jira")
+		if err != nil {
+			return fmt.Errorf("failed to resolve Jira token: %w", err)
+		}
+		token = orgToken
+	}
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to marshal request: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, t.baseURL+path, reader)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if t.email != "" {
+		req.SetBasicAuth(t.email, token)
+	} else {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := t.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("jira API returned status code %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	return nil
+}
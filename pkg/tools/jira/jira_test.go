@@ -0,0 +1,76 @@
+package jira_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/run-bigpig/llm-agent/pkg/multitenancy"
+	"github.com/run-bigpig/llm-agent/pkg/tools/jira"
+)
+
+func TestJiraCreate(t *testing.T) {
+	var authorization string
+	var body struct {
+		Fields map[string]interface{} `json:"fields"`
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/rest/api/2/issue" {
+			t.Errorf("Unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		authorization = r.Header.Get("Authorization")
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("Failed to decode issue: %v", err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"key": "OPS-12"}`))
+	}))
+	defer server.Close()
+
+	tool := jira.New(server.URL, "static-token", jira.WithTokenProvider(multitenancy.StaticTokens{"org-a": "token-a"}))
+	ctx := multitenancy.WithOrgID(context.Background(), "org-a")
+	result, err := tool.Execute(ctx, `{"action": "create", "project": "OPS", "summary": "Disk full"}`)
+	if err != nil {
+		t.Fatalf("Failed to execute tool: %v", err)
+	}
+	if !strings.Contains(result, "OPS-12") {
+		t.Errorf("Expected the issue key, got %s", result)
+	}
+	if authorization != "Bearer token-a" {
+		t.Errorf("Expected the organization's token, got %q", authorization)
+	}
+	if body.Fields["summary"] != "Disk full" || body.Fields["issuetype"].(map[string]interface{})["name"] != "Task" {
+		t.Errorf("Unexpected issue fields %v", body.Fields)
+	}
+
+	// An organization without a token fails instead of using the static token
+	authorization = ""
+	if _, err := tool.Execute(multitenancy.WithOrgID(context.Background(), "org-b"), `{"action": "create", "project": "OPS", "summary": "Disk full"}`); err == nil {
+		t.Error("Expected an error for an organization without a token")
+	}
+	if authorization != "" {
+		t.Errorf("Expected no request to be sent, got one with %q", authorization)
+	}
+}
+
+func TestJiraBasicAuth(t *testing.T) {
+	var authorization string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"total": 0, "issues": []}`))
+	}))
+	defer server.Close()
+
+	// Basic auth uses the static token with the account email
+	tool := jira.New(server.URL, "api-token", jira.WithBasicAuth("bot@example.com"))
+	if _, err := tool.Execute(context.Background(), `{"action": "search", "jql": "project = OPS"}`); err != nil {
+		t.Fatalf("Failed to execute tool: %v", err)
+	}
+	if !strings.HasPrefix(authorization, "Basic ") {
+		t.Errorf("Expected basic auth, got %q", authorization)
+	}
+}
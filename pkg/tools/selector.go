@@ -0,0 +1,236 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/google/uuid"
+
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+)
+
+// SemanticSelector implements the ToolSelector interface by embedding tool
+// descriptions and returning the top-k tools most similar to the query
+type SemanticSelector struct {
+	embedder      interfaces.Embedder
+	store         interfaces.VectorStore
+	topK          int
+	minScore      float32
+	alwaysInclude map[string]bool
+
+	mu      sync.Mutex
+	vectors map[string]toolVector
+}
+
+// toolVector caches the embedding of a tool description
+type toolVector struct {
+	text   string
+	vector []float32
+}
+
+// SelectorOption represents an option for configuring a SemanticSelector
+type SelectorOption func(*SemanticSelector)
+
+// WithTopK sets the maximum number of tools returned per query
+func WithTopK(k int) SelectorOption {
+	return func(s *SemanticSelector) {
+		s.topK = k
+	}
+}
+
+// WithMinScore sets the minimum similarity score for a tool to be selected
+func WithMinScore(score float32) SelectorOption {
+	return func(s *SemanticSelector) {
+		s.minScore = score
+	}
+}
+
+// WithAlwaysInclude sets tools that are always passed to the LLM regardless of similarity
+func WithAlwaysInclude(names ...string) SelectorOption {
+	return func(s *SemanticSelector) {
+		for _, name := range names {
+			s.alwaysInclude[name] = true
+		}
+	}
+}
+
+// WithVectorStore indexes tool descriptions in a vector store instead of in memory
+func WithVectorStore(store interfaces.VectorStore) SelectorOption {
+	return func(s *SemanticSelector) {
+		s.store = store
+	}
+}
+
+// NewSemanticSelector creates a new semantic tool selector
+func NewSemanticSelector(embedder interfaces.Embedder, options ...SelectorOption) *SemanticSelector {
+	selector := &SemanticSelector{
+		embedder:      embedder,
+		topK:          5,
+		alwaysInclude: make(map[string]bool),
+		vectors:       make(map[string]toolVector),
+	}
+
+	for _, option := range options {
+		option(selector)
+	}
+
+	return selector
+}
+
+// Select returns the tools most relevant to the query. If the number of tools
+// does not exceed the configured top-k, all tools are returned unchanged.
+func (s *SemanticSelector) Select(ctx context.Context, query string, tools []interfaces.Tool) ([]interfaces.Tool, error) {
+	if len(tools) <= s.topK {
+		return tools, nil
+	}
+
+	if err := s.index(ctx, tools); err != nil {
+		return nil, err
+	}
+
+	queryVector, err := s.embedder.Embed(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed query: %w", err)
+	}
+
+	scores, err := s.score(ctx, queryVector, tools)
+	if err != nil {
+		return nil, err
+	}
+
+	// Order candidates by descending similarity
+	candidates := make([]interfaces.Tool, 0, len(tools))
+	for _, tool := range tools {
+		if _, ok := scores[tool.Name()]; ok && !s.alwaysInclude[tool.Name()] {
+			candidates = append(candidates, tool)
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return scores[candidates[i].Name()] > scores[candidates[j].Name()]
+	})
+
+	selected := make([]interfaces.Tool, 0, s.topK)
+	for _, tool := range tools {
+		if s.alwaysInclude[tool.Name()] {
+			selected = append(selected, tool)
+		}
+	}
+	for _, tool := range candidates {
+		if len(selected) >= s.topK {
+			break
+		}
+		if scores[tool.Name()] < s.minScore {
+			break
+		}
+		selected = append(selected, tool)
+	}
+
+	return selected, nil
+}
+
+// Index embeds the tool descriptions ahead of the first Select, so that the
+// first query doesn't wait for them
+func (s *SemanticSelector) Index(ctx context.Context, tools []interfaces.Tool) error {
+	return s.index(ctx, tools)
+}
+
+// index embeds descriptions of tools that are new or whose description changed
+func (s *SemanticSelector) index(ctx context.Context, tools []interfaces.Tool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var pending []interfaces.Tool
+	var texts []string
+	for _, tool := range tools {
+		text := toolText(tool)
+		if cached, ok := s.vectors[tool.Name()]; ok && cached.text == text {
+			continue
+		}
+		pending = append(pending, tool)
+		texts = append(texts, text)
+	}
+
+	if len(pending) == 0 {
+		return nil
+	}
+
+	vectors, err := s.embedder.EmbedBatch(ctx, texts)
+	if err != nil {
+		return fmt.Errorf("failed to embed tool descriptions: %w", err)
+	}
+	if len(vectors) != len(pending) {
+		return fmt.Errorf("embedder returned %d vectors for %d tools", len(vectors), len(pending))
+	}
+
+	if s.store != nil {
+		docs := make([]interfaces.Document, len(pending))
+		for i, tool := range pending {
+			docs[i] = interfaces.Document{
+				ID:      toolDocumentID(tool.Name()),
+				Content: texts[i],
+				Vector:  vectors[i],
+				Metadata: map[string]interface{}{
+					"tool_name": tool.Name(),
+				},
+			}
+		}
+		if err := s.store.Store(ctx, docs); err != nil {
+			return fmt.Errorf("failed to store tool descriptions: %w", err)
+		}
+	}
+
+	for i, tool := range pending {
+		s.vectors[tool.Name()] = toolVector{text: texts[i], vector: vectors[i]}
+	}
+
+	return nil
+}
+
+// toolDocumentID returns the ID of a tool's document in the vector store. It
+// is a UUID, which stores like Weaviate require, derived from the tool name
+// so that re-indexing a tool replaces its document.
+func toolDocumentID(name string) string {
+	return uuid.NewSHA1(uuid.NameSpaceURL, []byte("tool:"+name)).String()
+}
+
+// score returns the similarity between the query and each tool, keyed by tool name
+func (s *SemanticSelector) score(ctx context.Context, queryVector []float32, tools []interfaces.Tool) (map[string]float32, error) {
+	scores := make(map[string]float32, len(tools))
+
+	if s.store != nil {
+		results, err := s.store.SearchByVector(ctx, queryVector, len(tools))
+		if err != nil {
+			return nil, fmt.Errorf("failed to search tool descriptions: %w", err)
+		}
+		for _, result := range results {
+			if name, ok := result.Document.Metadata["tool_name"].(string); ok {
+				scores[name] = result.Score
+			}
+		}
+		return scores, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, tool := range tools {
+		cached, ok := s.vectors[tool.Name()]
+		if !ok {
+			continue
+		}
+		similarity, err := s.embedder.CalculateSimilarity(queryVector, cached.vector, "cosine")
+		if err != nil {
+			return nil, fmt.Errorf("failed to calculate similarity: %w", err)
+		}
+		scores[tool.Name()] = similarity
+	}
+
+	return scores, nil
+}
+
+// toolText returns the text that is embedded for a tool
+func toolText(tool interfaces.Tool) string {
+	return tool.Name() + ": " + tool.Description()
+}
@@ -0,0 +1,179 @@
+package tools_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+	"github.com/run-bigpig/llm-agent/pkg/tools"
+)
+
+// keywordEmbedder embeds text as a bag of known keywords
+type keywordEmbedder struct {
+	keywords []string
+	calls    int
+}
+
+func (e *keywordEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	vector := make([]float32, len(e.keywords))
+	for i, keyword := range e.keywords {
+		if strings.Contains(strings.ToLower(text), keyword) {
+			vector[i] = 1
+		}
+	}
+	return vector, nil
+}
+
+func (e *keywordEmbedder) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	e.calls++
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vectors[i], _ = e.Embed(ctx, text)
+	}
+	return vectors, nil
+}
+
+func (e *keywordEmbedder) CalculateSimilarity(vec1, vec2 []float32, metric string) (float32, error) {
+	var score float32
+	for i := range vec1 {
+		score += vec1[i] * vec2[i]
+	}
+	return score, nil
+}
+
+type stubTool struct {
+	name        string
+	description string
+}
+
+func (t *stubTool) Name() string        { return t.name }
+func (t *stubTool) Description() string { return t.description }
+func (t *stubTool) Run(ctx context.Context, input string) (string, error) {
+	return "", nil
+}
+func (t *stubTool) Parameters() map[string]interfaces.ParameterSpec { return nil }
+func (t *stubTool) Execute(ctx context.Context, args string) (string, error) {
+	return "", nil
+}
+
+func TestSemanticSelector(t *testing.T) {
+	embedder := &keywordEmbedder{keywords: []string{"weather", "calendar", "email", "math"}}
+	allTools := []interfaces.Tool{
+		&stubTool{name: "forecast", description: "Get the weather forecast"},
+		&stubTool{name: "events", description: "List calendar events"},
+		&stubTool{name: "mailer", description: "Send an email"},
+		&stubTool{name: "calculator", description: "Evaluate math expressions"},
+	}
+
+	selector := tools.NewSemanticSelector(embedder, tools.WithTopK(2), tools.WithAlwaysInclude("calculator"))
+
+	selected, err := selector.Select(context.Background(), "what is on my calendar tomorrow?", allTools)
+	if err != nil {
+		t.Fatalf("Failed to select tools: %v", err)
+	}
+	if len(selected) != 2 {
+		t.Fatalf("Expected 2 tools, got %d", len(selected))
+	}
+	if selected[0].Name() != "calculator" || selected[1].Name() != "events" {
+		t.Errorf("Expected [calculator events], got [%s %s]", selected[0].Name(), selected[1].Name())
+	}
+
+	// Tool descriptions are embedded only once
+	if _, err := selector.Select(context.Background(), "send an email", allTools); err != nil {
+		t.Fatalf("Failed to select tools: %v", err)
+	}
+	if embedder.calls != 1 {
+		t.Errorf("Expected tool descriptions to be embedded once, got %d batches", embedder.calls)
+	}
+
+	// Small tool sets are returned unchanged
+	selected, err = selector.Select(context.Background(), "anything", allTools[:2])
+	if err != nil {
+		t.Fatalf("Failed to select tools: %v", err)
+	}
+	if len(selected) != 2 {
+		t.Errorf("Expected all tools to be returned, got %d", len(selected))
+	}
+}
+
+// memoryStore is a vector store that keeps documents by ID
+type memoryStore struct {
+	embedder *keywordEmbedder
+	docs     map[string]interfaces.Document
+}
+
+func (s *memoryStore) Store(ctx context.Context, documents []interfaces.Document, options ...interfaces.StoreOption) error {
+	for _, doc := range documents {
+		s.docs[doc.ID] = doc
+	}
+	return nil
+}
+
+func (s *memoryStore) Search(ctx context.Context, query string, limit int, options ...interfaces.SearchOption) ([]interfaces.SearchResult, error) {
+	return nil, nil
+}
+
+func (s *memoryStore) SearchByVector(ctx context.Context, vector []float32, limit int, options ...interfaces.SearchOption) ([]interfaces.SearchResult, error) {
+	var results []interfaces.SearchResult
+	for _, doc := range s.docs {
+		score, _ := s.embedder.CalculateSimilarity(vector, doc.Vector, "")
+		results = append(results, interfaces.SearchResult{Document: doc, Score: score})
+	}
+	return results, nil
+}
+
+func (s *memoryStore) Delete(ctx context.Context, ids []string, options ...interfaces.DeleteOption) error {
+	return nil
+}
+
+func (s *memoryStore) Get(ctx context.Context, ids []string) ([]interfaces.Document, error) {
+	return nil, nil
+}
+
+func TestSemanticSelectorStoreIDs(t *testing.T) {
+	embedder := &keywordEmbedder{keywords: []string{"weather", "calendar", "email"}}
+	store := &memoryStore{embedder: embedder, docs: make(map[string]interfaces.Document)}
+	allTools := []interfaces.Tool{
+		&stubTool{name: "forecast", description: "Get the weather forecast"},
+		&stubTool{name: "events", description: "List calendar events"},
+		&stubTool{name: "mailer", description: "Send an email"},
+	}
+
+	selector := tools.NewSemanticSelector(embedder, tools.WithTopK(1), tools.WithVectorStore(store))
+	selected, err := selector.Select(context.Background(), "is it going to rain? check the weather", allTools)
+	if err != nil {
+		t.Fatalf("Failed to select tools: %v", err)
+	}
+	if len(selected) != 1 || selected[0].Name() != "forecast" {
+		t.Fatalf("Expected [forecast], got %d tools", len(selected))
+	}
+
+	if len(store.docs) != 3 {
+		t.Fatalf("Expected 3 stored tools, got %d", len(store.docs))
+	}
+	for id, doc := range store.docs {
+		parsed, err := uuid.Parse(id)
+		if err != nil {
+			t.Errorf("Expected a UUID for tool %v, got %q", doc.Metadata["tool_name"], id)
+			continue
+		}
+		// IDs are derived from the tool name, so re-indexing replaces documents
+		expected := uuid.NewSHA1(uuid.NameSpaceURL, []byte("tool:"+doc.Metadata["tool_name"].(string)))
+		if parsed != expected {
+			t.Errorf("Expected ID %s for tool %v, got %s", expected, doc.Metadata["tool_name"], id)
+		}
+	}
+
+	// A changed description replaces the tool's document
+	allTools[2] = &stubTool{name: "mailer", description: "Send an email or a calendar invite"}
+	selector = tools.NewSemanticSelector(embedder, tools.WithTopK(1), tools.WithVectorStore(store))
+	if _, err := selector.Select(context.Background(), "send an email", allTools); err != nil {
+		t.Fatalf("Failed to select tools: %v", err)
+	}
+	if len(store.docs) != 3 {
+		t.Errorf("Expected re-indexed tools to replace their documents, got %d documents", len(store.docs))
+	}
+}
@@ -0,0 +1,302 @@
+package slack
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+	"github.com/run-bigpig/llm-agent/pkg/multitenancy"
+	"github.com/run-bigpig/llm-agent/pkg/runctx"
+)
+
+// Tool implements a Slack notification tool
+type Tool struct {
+	token           string
+	baseURL         string
+	httpClient      *http.Client
+	defaultChannel  string
+	allowedChannels []string
+	templates       map[string]*template.Template
+	dryRun          bool
+	err             error
+}
+
+// Input represents the input for the Slack tool
+type Input struct {
+	Channel   string                 `json:"channel"`
+	Text      string                 `json:"text"`
+	Template  string                 `json:"template,omitempty"`
+	Variables map[string]interface{} `json:"variables,omitempty"`
+}
+
+// Option represents an option for configuring the tool
+type Option func(*Tool)
+
+// WithHTTPClient sets the HTTP client for the tool
+func WithHTTPClient(client *http.Client) Option {
+	return func(t *Tool) {
+		t.httpClient = client
+	}
+}
+
+// WithBaseURL sets the base URL for the Slack Web API
+func WithBaseURL(baseURL string) Option {
+	return func(t *Tool) {
+		t.baseURL = strings.TrimSuffix(baseURL, "/")
+	}
+}
+
+// WithDefaultChannel sets the channel used when the input does not specify one
+func WithDefaultChannel(channel string) Option {
+	return func(t *Tool) {
+		t.defaultChannel = channel
+	}
+}
+
+// WithAllowedChannels restricts the channels the tool may post to.
+// When no channels are configured, every channel is allowed.
+func WithAllowedChannels(channels ...string) Option {
+	return func(t *Tool) {
+		t.allowedChannels = channels
+	}
+}
+
+// WithTemplate registers a named message template using Go text/template syntax.
+// A template that fails to parse is reported by Err and by every call.
+func WithTemplate(name, text string) Option {
+	return func(t *Tool) {
+		tmpl, err := template.New(name).Parse(text)
+		if err != nil {
+			if t.err == nil {
+				t.err = fmt.Errorf("invalid template %s: %w", name, err)
+			}
+			return
+		}
+		t.templates[name] = tmpl
+	}
+}
+
+// WithDryRun enables dry-run mode, in which messages are rendered and validated but not sent
+func WithDryRun(dryRun bool) Option {
+	return func(t *Tool) {
+		t.dryRun = dryRun
+	}
+}
+
+// New creates a new Slack notification tool
+func New(token string, options ...Option) *Tool {
+	tool := &Tool{
+		token:      token,
+		baseURL:    "https://slack.com/api",
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+		templates:  make(map[string]*template.Template),
+	}
+
+	for _, option := range options {
+		option(tool)
+	}
+
+	return tool
+}
+
+// Err returns the error from configuring the tool, if any
+func (t *Tool) Err() error {
+	return t.err
+}
+
+// Name returns the name of the tool
+func (t *Tool) Name() string {
+	return "slack_notification"
+}
+
+// Description returns a description of what the tool does
+func (t *Tool) Description() string {
+	return "Send a notification or report to a Slack channel"
+}
+
+// Parameters returns the parameters that the tool accepts
+func (t *Tool) Parameters() map[string]interfaces.ParameterSpec {
+	params := map[string]interfaces.ParameterSpec{
+		"channel": {
+			Type:        "string",
+			Description: "The Slack channel to post to (e.g., '#alerts' or a channel ID)",
+			Required:    t.defaultChannel == "",
+		},
+		"text": {
+			Type:        "string",
+			Description: "The message text. Ignored when a template is used",
+			Required:    false,
+		},
+		"variables": {
+			Type:        "object",
+			Description: "Variables used to render the template",
+			Required:    false,
+		},
+	}
+
+	if len(t.templates) > 0 {
+		names := make([]interface{}, 0, len(t.templates))
+		for name := range t.templates {
+			names = append(names, name)
+		}
+		params["template"] = interfaces.ParameterSpec{
+			Type:        "string",
+			Description: "The name of a registered message template",
+			Required:    false,
+			Enum:        names,
+		}
+	}
+
+	return params
+}
+
+// Run executes the tool with the given input
+func (t *Tool) Run(ctx context.Context, input string) (string, error) {
+	var params Input
+	if err := json.Unmarshal([]byte(input), &params); err != nil {
+		// If not JSON, treat the input as the message text
+		params = Input{Text: input}
+	}
+
+	return t.send(ctx, params)
+}
+
+// Execute executes the tool with the given arguments
+func (t *Tool) Execute(ctx context.Context, args string) (string, error) {
+	if t.err != nil {
+		return "", t.err
+	}
+
+	var params Input
+	if err := json.Unmarshal([]byte(args), &params); err != nil {
+		return "", fmt.Errorf("failed to parse args: %w", err)
+	}
+
+	return t.send(ctx, params)
+}
+
+// HasSideEffects reports that the tool posts messages
+func (t *Tool) HasSideEffects(args string) bool {
+	return true
+}
+
+// DryRun validates and renders the message without posting it
+func (t *Tool) DryRun(ctx context.Context, args string) (string, error) {
+	return t.Execute(runctx.WithDryRun(ctx, true), args)
+}
+
+// send renders, validates and posts a message
+func (t *Tool) send(ctx context.Context, params Input) (string, error) {
+	channel := params.Channel
+	if channel == "" {
+		channel = t.defaultChannel
+	}
+	if channel == "" {
+		return "", fmt.Errorf("channel parameter is required")
+	}
+
+	if !t.isChannelAllowed(channel) {
+		return "", fmt.Errorf("channel %s is not in the list of allowed channels", channel)
+	}
+
+	text, err := t.render(params)
+	if err != nil {
+		return "", err
+	}
+	if text == "" {
+		return "", fmt.Errorf("message text is required")
+	}
+
+	if t.dryRun || runctx.DryRun(ctx) {
+		return fmt.Sprintf("[dry run] Message to %s was not sent:\n%s", channel, text), nil
+	}
+
+	body, err := json.Marshal(map[string]string{
+		"channel": channel,
+		"text":    text,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", t.baseURL+"/chat.postMessage", bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	req.Header.Set("Authorization", "Bearer "+t.token)
+
+	// Add organization ID to request headers if available
+	if orgID, _ := multitenancy.GetOrgID(ctx); orgID != "" {
+		req.Header.Set("X-Organization-ID", orgID)
+	}
+
+	resp, err := t.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer func() {
+		if closeErr := resp.Body.Close(); closeErr != nil {
+			err = fmt.Errorf("failed to close response body: %w", closeErr)
+		}
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("slack API returned status code %d", resp.StatusCode)
+	}
+
+	var result struct {
+		OK    bool   `json:"ok"`
+		Error string `json:"error"`
+		TS    string `json:"ts"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("failed to parse response: %w", err)
+	}
+	if !result.OK {
+		return "", fmt.Errorf("slack API error: %s", result.Error)
+	}
+
+	return fmt.Sprintf("Message sent to %s (ts: %s)", channel, result.TS), nil
+}
+
+// render returns the message text, rendering the named template if one was requested
+func (t *Tool) render(params Input) (string, error) {
+	if params.Template == "" {
+		return params.Text, nil
+	}
+
+	tmpl, ok := t.templates[params.Template]
+	if !ok {
+		return "", fmt.Errorf("unknown template: %s", params.Template)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, params.Variables); err != nil {
+		return "", fmt.Errorf("failed to render template %s: %w", params.Template, err)
+	}
+
+	return buf.String(), nil
+}
+
+// isChannelAllowed checks the channel against the allowlist
+func (t *Tool) isChannelAllowed(channel string) bool {
+	if len(t.allowedChannels) == 0 {
+		return true
+	}
+
+	normalized := strings.TrimPrefix(channel, "#")
+	for _, allowed := range t.allowedChannels {
+		if strings.EqualFold(strings.TrimPrefix(allowed, "#"), normalized) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,92 @@
+package slack_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/run-bigpig/llm-agent/pkg/tools/slack"
+)
+
+func TestSlackNotification(t *testing.T) {
+	var received map[string]string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/chat.postMessage" {
+			t.Errorf("Expected /chat.postMessage, got %s", r.URL.Path)
+		}
+		if r.Header.Get("Authorization") != "Bearer test-token" {
+			t.Errorf("Unexpected Authorization header: %s", r.Header.Get("Authorization"))
+		}
+
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			t.Fatalf("Failed to decode request: %v", err)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"ok": true,
+			"ts": "1234.5678",
+		})
+	}))
+	defer server.Close()
+
+	tool := slack.New(
+		"test-token",
+		slack.WithBaseURL(server.URL),
+		slack.WithAllowedChannels("#alerts"),
+		slack.WithTemplate("deploy", "Deployed {{.service}} to {{.env}}"),
+	)
+
+	result, err := tool.Execute(context.Background(), `{"channel": "#alerts", "template": "deploy", "variables": {"service": "api", "env": "prod"}}`)
+	if err != nil {
+		t.Fatalf("Failed to execute tool: %v", err)
+	}
+	if !strings.Contains(result, "1234.5678") {
+		t.Errorf("Expected result to contain message timestamp, got '%s'", result)
+	}
+	if received["text"] != "Deployed api to prod" {
+		t.Errorf("Expected rendered template, got '%s'", received["text"])
+	}
+
+	// Channels outside the allowlist are rejected
+	if _, err := tool.Execute(context.Background(), `{"channel": "#general", "text": "hi"}`); err == nil {
+		t.Error("Expected error for channel outside allowlist")
+	}
+}
+
+func TestSlackDryRun(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("Dry run should not call the Slack API")
+	}))
+	defer server.Close()
+
+	tool := slack.New(
+		"test-token",
+		slack.WithBaseURL(server.URL),
+		slack.WithDefaultChannel("#alerts"),
+		slack.WithDryRun(true),
+	)
+
+	result, err := tool.Run(context.Background(), "build finished")
+	if err != nil {
+		t.Fatalf("Failed to run tool: %v", err)
+	}
+	if !strings.Contains(result, "[dry run]") || !strings.Contains(result, "build finished") {
+		t.Errorf("Unexpected dry run result: '%s'", result)
+	}
+}
+
+func TestSlackInvalidTemplate(t *testing.T) {
+	tool := slack.New("test-token", slack.WithTemplate("deploy", "Deployed {{.service"))
+
+	if tool.Err() == nil {
+		t.Fatal("Expected the template parse error")
+	}
+	if _, err := tool.Execute(context.Background(), `{"channel": "#alerts", "text": "hi"}`); err == nil {
+		t.Error("Expected Execute to report the template parse error")
+	}
+}
@@ -0,0 +1,499 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+	"github.com/run-bigpig/llm-agent/pkg/multitenancy"
+	"gopkg.in/yaml.v3"
+)
+
+// Parameter locations
+const (
+	InPath   = "path"
+	InQuery  = "query"
+	InBody   = "body"
+	InHeader = "header"
+)
+
+// Config is the YAML configuration for a set of webhooks
+type Config struct {
+	Webhooks []EndpointConfig `yaml:"webhooks"`
+}
+
+// EndpointConfig describes a single registered endpoint
+type EndpointConfig struct {
+	// Name is the tool name exposed to the agent
+	Name string `yaml:"name"`
+
+	// Description tells the agent what the endpoint does
+	Description string `yaml:"description"`
+
+	// URL is the endpoint URL. Path parameters are written as {name}.
+	URL string `yaml:"url"`
+
+	// Method is the HTTP method, GET by default
+	Method string `yaml:"method"`
+
+	// Headers are static headers sent with every request. Values may
+	// reference environment variables as ${NAME}.
+	Headers map[string]string `yaml:"headers"`
+
+	// Auth configures authentication
+	Auth AuthConfig `yaml:"auth"`
+
+	// Timeout is the request timeout, e.g. "10s"
+	Timeout string `yaml:"timeout"`
+
+	// Parameters are the parameters the agent may pass
+	Parameters map[string]ParameterConfig `yaml:"parameters"`
+}
+
+// AuthConfig configures endpoint authentication. Secret values may reference
+// environment variables as ${NAME} so they are not stored in the file.
+type AuthConfig struct {
+	// Type is one of "bearer", "basic" or "header"
+	Type string `yaml:"type"`
+
+	// Token is the bearer token or header value
+	Token string `yaml:"token"`
+
+	// Header is the header name for the "header" type
+	Header string `yaml:"header"`
+
+	// Username and Password are used for the "basic" type
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+}
+
+// ParameterConfig describes a single parameter
+type ParameterConfig struct {
+	Type        string        `yaml:"type"`
+	Description string        `yaml:"description"`
+	Required    bool          `yaml:"required"`
+	Default     interface{}   `yaml:"default"`
+	Enum        []interface{} `yaml:"enum"`
+
+	// In is where the parameter is sent: path, query, body or header. By
+	// default parameters are sent in the query for GET and DELETE requests
+	// and in the JSON body otherwise.
+	In string `yaml:"in"`
+}
+
+// pathParamPattern matches {name} placeholders in the URL
+var pathParamPattern = regexp.MustCompile(`\{([a-zA-Z0-9_]+)\}`)
+
+// Tool calls a registered HTTP endpoint
+type Tool struct {
+	config          EndpointConfig
+	httpClient      *http.Client
+	maxResponseSize int
+}
+
+// Option represents an option for configuring webhook tools
+type Option func(*Tool)
+
+// WithHTTPClient sets the HTTP client for the tool
+func WithHTTPClient(client *http.Client) Option {
+	return func(t *Tool) {
+		t.httpClient = client
+	}
+}
+
+// WithMaxResponseSize caps the number of response bytes returned to the agent
+func WithMaxResponseSize(size int) Option {
+	return func(t *Tool) {
+		t.maxResponseSize = size
+	}
+}
+
+// New creates a webhook tool from an endpoint configuration
+func New(config EndpointConfig, options ...Option) (*Tool, error) {
+	if err := validate(&config); err != nil {
+		return nil, fmt.Errorf("invalid webhook %q: %w", config.Name, err)
+	}
+
+	timeout := 30 * time.Second
+	if config.Timeout != "" {
+		parsed, err := time.ParseDuration(config.Timeout)
+		if err != nil {
+			return nil, fmt.Errorf("invalid webhook %q: invalid timeout: %w", config.Name, err)
+		}
+		timeout = parsed
+	}
+
+	tool := &Tool{
+		config:          config,
+		httpClient:      &http.Client{Timeout: timeout},
+		maxResponseSize: 32 * 1024,
+	}
+
+	for _, option := range options {
+		option(tool)
+	}
+
+	return tool, nil
+}
+
+// Load creates webhook tools from YAML configuration
+func Load(data []byte, options ...Option) ([]interfaces.Tool, error) {
+	var config Config
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal webhook config: %w", err)
+	}
+
+	names := make(map[string]bool)
+	tools := make([]interfaces.Tool, 0, len(config.Webhooks))
+	for _, endpoint := range config.Webhooks {
+		if names[endpoint.Name] {
+			return nil, fmt.Errorf("duplicate webhook name: %s", endpoint.Name)
+		}
+		names[endpoint.Name] = true
+
+		tool, err := New(endpoint, options...)
+		if err != nil {
+			return nil, err
+		}
+		tools = append(tools, tool)
+	}
+
+	return tools, nil
+}
+
+// LoadFile creates webhook tools from a YAML file
+func LoadFile(path string, options ...Option) ([]interfaces.Tool, error) {
+	data, err := os.ReadFile(path) // #nosec G304 - the path is provided by the operator
+	if err != nil {
+		return nil, fmt.Errorf("failed to read webhook config file: %w", err)
+	}
+	return Load(data, options...)
+}
+
+// validate checks the configuration and fills in defaults
+func validate(config *EndpointConfig) error {
+	if config.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+
+	u, err := url.Parse(config.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("url must be an absolute http or https URL")
+	}
+
+	config.Method = strings.ToUpper(config.Method)
+	if config.Method == "" {
+		config.Method = http.MethodGet
+	}
+
+	switch config.Auth.Type {
+	case "", "bearer", "basic":
+	case "header":
+		if config.Auth.Header == "" {
+			return fmt.Errorf("auth header name is required for header auth")
+		}
+	default:
+		return fmt.Errorf("unsupported auth type: %s", config.Auth.Type)
+	}
+
+	for _, match := range pathParamPattern.FindAllStringSubmatch(config.URL, -1) {
+		param, ok := config.Parameters[match[1]]
+		if !ok {
+			return fmt.Errorf("path parameter %s is not declared", match[1])
+		}
+		param.In = InPath
+		param.Required = true
+		config.Parameters[match[1]] = param
+	}
+
+	for name, param := range config.Parameters {
+		if param.Type == "" {
+			param.Type = "string"
+		}
+		if param.In == "" {
+			if config.Method == http.MethodGet || config.Method == http.MethodDelete {
+				param.In = InQuery
+			} else {
+				param.In = InBody
+			}
+		}
+		switch param.In {
+		case InPath, InQuery, InBody, InHeader:
+		default:
+			return fmt.Errorf("parameter %s has unsupported location: %s", name, param.In)
+		}
+		config.Parameters[name] = param
+	}
+
+	return nil
+}
+
+// Name returns the name of the tool
+func (t *Tool) Name() string {
+	return t.config.Name
+}
+
+// Description returns a description of what the tool does
+func (t *Tool) Description() string {
+	return t.config.Description
+}
+
+// Parameters returns the parameters that the tool accepts
+func (t *Tool) Parameters() map[string]interfaces.ParameterSpec {
+	params := make(map[string]interfaces.ParameterSpec, len(t.config.Parameters))
+	for name, param := range t.config.Parameters {
+		params[name] = interfaces.ParameterSpec{
+			Type:        param.Type,
+			Description: param.Description,
+			Required:    param.Required,
+			Default:     param.Default,
+			Enum:        param.Enum,
+		}
+	}
+	return params
+}
+
+// Run executes the tool with the given input
+func (t *Tool) Run(ctx context.Context, input string) (string, error) {
+	return t.Execute(ctx, input)
+}
+
+// Execute executes the tool with the given arguments
+func (t *Tool) Execute(ctx context.Context, args string) (string, error) {
+	params := make(map[string]interface{})
+	if strings.TrimSpace(args) != "" {
+		if err := json.Unmarshal([]byte(args), &params); err != nil {
+			return "", fmt.Errorf("failed to parse args: %w", err)
+		}
+	}
+
+	if err := t.validateArgs(params); err != nil {
+		return "", err
+	}
+
+	req, err := t.buildRequest(ctx, params)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := t.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, int64(t.maxResponseSize)+1))
+	if err != nil {
+		return "", fmt.Errorf("failed to read response: %w", err)
+	}
+
+	truncated := false
+	if t.maxResponseSize > 0 && len(body) > t.maxResponseSize {
+		body = body[:t.maxResponseSize]
+		truncated = true
+	}
+
+	if resp.StatusCode >= 400 {
+		return "", fmt.Errorf("%s returned status code %d: %s", t.config.Name, resp.StatusCode, string(body))
+	}
+
+	result := string(body)
+	if truncated {
+		result += "\n[response truncated]"
+	}
+	if result == "" {
+		result = fmt.Sprintf("%s returned status code %d", t.config.Name, resp.StatusCode)
+	}
+
+	return result, nil
+}
+
+// HasSideEffects reports whether the webhook's method may change the remote
+// system, which is the case for all methods but GET and HEAD
+func (t *Tool) HasSideEffects(args string) bool {
+	return t.config.Method != http.MethodGet && t.config.Method != http.MethodHead
+}
+
+// DryRun validates the arguments and builds the request without sending it
+func (t *Tool) DryRun(ctx context.Context, args string) (string, error) {
+	params := make(map[string]interface{})
+	if strings.TrimSpace(args) != "" {
+		if err := json.Unmarshal([]byte(args), &params); err != nil {
+			return "", fmt.Errorf("failed to parse args: %w", err)
+		}
+	}
+	if err := t.validateArgs(params); err != nil {
+		return "", err
+	}
+	req, err := t.buildRequest(ctx, params)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("[dry run] %s %s was not sent", req.Method, req.URL.Redacted()), nil
+}
+
+// validateArgs applies defaults and checks arguments against the parameter schema
+func (t *Tool) validateArgs(params map[string]interface{}) error {
+	for name := range params {
+		if _, ok := t.config.Parameters[name]; !ok {
+			return fmt.Errorf("unknown parameter: %s", name)
+		}
+	}
+
+	for name, spec := range t.config.Parameters {
+		value, ok := params[name]
+		if !ok || value == nil {
+			if spec.Default != nil {
+				params[name] = spec.Default
+				continue
+			}
+			if spec.Required {
+				return fmt.Errorf("%s parameter is required", name)
+			}
+			delete(params, name)
+			continue
+		}
+
+		if !matchesType(value, spec.Type) {
+			return fmt.Errorf("%s parameter must be of type %s", name, spec.Type)
+		}
+
+		if len(spec.Enum) > 0 && !inEnum(value, spec.Enum) {
+			return fmt.Errorf("%s parameter must be one of %v", name, spec.Enum)
+		}
+	}
+
+	return nil
+}
+
+// matchesType reports whether a decoded JSON value matches a schema type
+func matchesType(value interface{}, typ string) bool {
+	switch typ {
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	case "integer":
+		f, ok := value.(float64)
+		return ok && f == float64(int64(f))
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "array":
+		_, ok := value.([]interface{})
+		return ok
+	case "object":
+		_, ok := value.(map[string]interface{})
+		return ok
+	default:
+		return true
+	}
+}
+
+// inEnum reports whether the value is one of the allowed values
+func inEnum(value interface{}, enum []interface{}) bool {
+	for _, allowed := range enum {
+		if fmt.Sprint(allowed) == fmt.Sprint(value) {
+			return true
+		}
+	}
+	return false
+}
+
+// buildRequest builds the HTTP request from the validated arguments
+func (t *Tool) buildRequest(ctx context.Context, params map[string]interface{}) (*http.Request, error) {
+	rawURL := t.config.URL
+	query := url.Values{}
+	headers := make(map[string]string)
+	body := make(map[string]interface{})
+
+	// Iterate in a stable order so requests are reproducible
+	names := make([]string, 0, len(params))
+	for name := range params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		value := params[name]
+		switch t.config.Parameters[name].In {
+		case InPath:
+			rawURL = strings.ReplaceAll(rawURL, "{"+name+"}", url.PathEscape(fmt.Sprint(value)))
+		case InQuery:
+			query.Set(name, fmt.Sprint(value))
+		case InHeader:
+			headers[name] = fmt.Sprint(value)
+		case InBody:
+			body[name] = value
+		}
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build URL: %w", err)
+	}
+	if len(query) > 0 {
+		existing := u.Query()
+		for key, values := range query {
+			existing[key] = values
+		}
+		u.RawQuery = existing.Encode()
+	}
+
+	var reader io.Reader
+	if len(body) > 0 {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal request body: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, t.config.Method, u.String(), reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	if reader != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+
+	for key, value := range t.config.Headers {
+		req.Header.Set(key, os.ExpandEnv(value))
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	auth := t.config.Auth
+	switch auth.Type {
+	case "bearer":
+		req.Header.Set("Authorization", "Bearer "+os.ExpandEnv(auth.Token))
+	case "basic":
+		credentials := os.ExpandEnv(auth.Username) + ":" + os.ExpandEnv(auth.Password)
+		req.Header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(credentials)))
+	case "header":
+		req.Header.Set(auth.Header, os.ExpandEnv(auth.Token))
+	}
+
+	// Add organization ID to request headers if available
+	if orgID, _ := multitenancy.GetOrgID(ctx); orgID != "" {
+		req.Header.Set("X-Organization-ID", orgID)
+	}
+
+	return req, nil
+}
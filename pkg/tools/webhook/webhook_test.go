@@ -0,0 +1,105 @@
+package webhook_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/run-bigpig/llm-agent/pkg/tools/webhook"
+)
+
+func TestWebhookTool(t *testing.T) {
+	t.Setenv("TICKETS_TOKEN", "secret-token")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.EscapedPath() != "/projects/ops%2F1/tickets" {
+			t.Errorf("Unexpected request: %s %s", r.Method, r.URL.EscapedPath())
+		}
+		if r.Header.Get("Authorization") != "Bearer secret-token" {
+			t.Errorf("Expected bearer token from the environment, got %q", r.Header.Get("Authorization"))
+		}
+		if r.URL.Query().Get("notify") != "true" {
+			t.Errorf("Expected notify query parameter, got %q", r.URL.RawQuery)
+		}
+
+		body, _ := io.ReadAll(r.Body)
+		var payload map[string]interface{}
+		_ = json.Unmarshal(body, &payload)
+		if payload["title"] != "Disk full" || payload["priority"] != "low" {
+			t.Errorf("Unexpected body: %s", body)
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"id":42}`))
+	}))
+	defer server.Close()
+
+	config := `
+webhooks:
+  - name: create_ticket
+    description: Create a ticket in the ops tracker
+    url: ` + server.URL + `/projects/{project}/tickets
+    method: post
+    auth:
+      type: bearer
+      token: ${TICKETS_TOKEN}
+    parameters:
+      project:
+        description: Project key
+      title:
+        required: true
+      priority:
+        enum: [low, high]
+        default: low
+      notify:
+        type: boolean
+        in: query
+`
+	tools, err := webhook.Load([]byte(config))
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if len(tools) != 1 || tools[0].Name() != "create_ticket" {
+		t.Fatalf("Unexpected tools: %v", tools)
+	}
+	tool := tools[0]
+
+	if !tool.Parameters()["project"].Required {
+		t.Error("Expected path parameters to be required")
+	}
+
+	result, err := tool.Execute(context.Background(), `{"project":"ops/1","title":"Disk full","notify":true}`)
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if result != `{"id":42}` {
+		t.Errorf("Unexpected result: %s", result)
+	}
+
+	for args, want := range map[string]string{
+		`{"project":"ops"}`: "title parameter is required",
+		`{"project":"ops","title":"x","priority":"urgent"}`:   "must be one of",
+		`{"project":"ops","title":"x","notify":"yes"}`:        "must be of type boolean",
+		`{"project":"ops","title":"x","assignee":"somebody"}`: "unknown parameter",
+	} {
+		if _, err := tool.Execute(context.Background(), args); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected error containing %q for %s, got %v", want, args, err)
+		}
+	}
+}
+
+func TestLoadRejectsInvalidConfig(t *testing.T) {
+	for _, config := range []string{
+		"webhooks:\n  - name: a\n    url: ftp://example.com",
+		"webhooks:\n  - name: a\n    url: https://example.com/{id}",
+		"webhooks:\n  - name: a\n    url: https://example.com\n    auth:\n      type: oauth",
+	} {
+		if _, err := webhook.Load([]byte(config)); err == nil {
+			t.Errorf("Expected an error for config %q", config)
+		}
+	}
+}
@@ -9,7 +9,6 @@ import (
 	"github.com/henomis/langfuse-go/model"
 	"github.com/run-bigpig/llm-agent/pkg/config"
 	"github.com/run-bigpig/llm-agent/pkg/interfaces"
-	"github.com/run-bigpig/llm-agent/pkg/multitenancy"
 )
 
 // LangfuseTracer implements tracing using Langfuse
@@ -80,19 +79,17 @@ func NewLangfuseTracer(customConfig ...LangfuseConfig) (*LangfuseTracer, error)
 
 // TraceGeneration traces an LLM generation
 func (t *LangfuseTracer) TraceGeneration(ctx context.Context, modelName string, prompt string, response string, startTime time.Time, endTime time.Time, metadata map[string]interface{}) (string, error) {
+	return t.traceGeneration(ctx, modelName, prompt, response, startTime, endTime, metadata, nil)
+}
+
+// traceGeneration traces an LLM generation with its token usage, if known
+func (t *LangfuseTracer) traceGeneration(ctx context.Context, modelName string, prompt string, response string, startTime time.Time, endTime time.Time, metadata map[string]interface{}, usage *interfaces.Usage) (string, error) {
 	if !t.enabled {
 		return "", nil
 	}
 
-	// Get organization ID from context
-	orgID, _ := multitenancy.GetOrgID(ctx)
-
-	// Add organization ID to metadata
-	if metadata == nil {
-		metadata = make(map[string]interface{})
-	}
-	metadata["org_id"] = orgID
-	metadata["environment"] = t.environment
+	// Add organization, user, conversation and request IDs to metadata
+	metadata = t.runMetadata(ctx, metadata)
 
 	// Convert metadata to model.M
 	metadataM := make(model.M)
@@ -116,9 +113,17 @@ func (t *LangfuseTracer) TraceGeneration(ctx context.Context, modelName string,
 		},
 		Metadata: metadataM,
 	}
+	if usage != nil {
+		generation.Usage = model.Usage{
+			Input:  usage.InputTokens,
+			Output: usage.OutputTokens,
+			Total:  usage.TotalTokens(),
+			Unit:   model.ModelUsageUnitTokens,
+		}
+	}
+	generation.TraceID, generation.ParentObservationID = t.parent(ctx, generation.Name)
 
-	var id string
-	generationID, err := t.client.Generation(generation, &id)
+	generationID, err := t.client.Generation(generation, nil)
 	if err != nil {
 		return "", fmt.Errorf("failed to create Langfuse generation: %w", err)
 	}
@@ -132,15 +137,8 @@ func (t *LangfuseTracer) TraceSpan(ctx context.Context, name string, startTime t
 		return "", nil
 	}
 
-	// Get organization ID from context
-	orgID, _ := multitenancy.GetOrgID(ctx)
-
-	// Add organization ID to metadata
-	if metadata == nil {
-		metadata = make(map[string]interface{})
-	}
-	metadata["org_id"] = orgID
-	metadata["environment"] = t.environment
+	// Add organization, user, conversation and request IDs to metadata
+	metadata = t.runMetadata(ctx, metadata)
 
 	// Create span
 	span := &model.Span{
@@ -149,12 +147,12 @@ func (t *LangfuseTracer) TraceSpan(ctx context.Context, name string, startTime t
 		EndTime:   &endTime,
 		Metadata:  metadata,
 	}
+	span.TraceID, span.ParentObservationID = t.parent(ctx, name)
 	if parentID != "" {
 		span.ParentObservationID = parentID
 	}
 
-	var id string
-	spanID, err := t.client.Span(span, &id)
+	spanID, err := t.client.Span(span, nil)
 	if err != nil {
 		return "", fmt.Errorf("failed to create Langfuse span: %w", err)
 	}
@@ -168,15 +166,8 @@ func (t *LangfuseTracer) TraceEvent(ctx context.Context, name string, input inte
 		return "", nil
 	}
 
-	// Get organization ID from context
-	orgID, _ := multitenancy.GetOrgID(ctx)
-
-	// Add organization ID to metadata
-	if metadata == nil {
-		metadata = make(map[string]interface{})
-	}
-	metadata["org_id"] = orgID
-	metadata["environment"] = t.environment
+	// Add organization, user, conversation and request IDs to metadata
+	metadata = t.runMetadata(ctx, metadata)
 
 	// Create event
 	event := &model.Event{
@@ -186,12 +177,12 @@ func (t *LangfuseTracer) TraceEvent(ctx context.Context, name string, input inte
 		Level:    model.ObservationLevel(level),
 		Metadata: metadata,
 	}
+	event.TraceID, event.ParentObservationID = t.parent(ctx, name)
 	if parentID != "" {
 		event.ParentObservationID = parentID
 	}
 
-	var id string
-	eventID, err := t.client.Event(event, &id)
+	eventID, err := t.client.Event(event, nil)
 	if err != nil {
 		return "", fmt.Errorf("failed to create Langfuse event: %w", err)
 	}
@@ -199,6 +190,24 @@ func (t *LangfuseTracer) TraceEvent(ctx context.Context, name string, input inte
 	return eventID.ID, nil
 }
 
+// Score attaches a score to a trace, e.g. a user's rating of the response.
+// It implements feedback.Scorer.
+func (t *LangfuseTracer) Score(ctx context.Context, traceID, name string, value float64, comment string) error {
+	if !t.enabled {
+		return nil
+	}
+
+	if _, err := t.client.Score(&model.Score{
+		TraceID: traceID,
+		Name:    name,
+		Value:   value,
+		Comment: comment,
+	}); err != nil {
+		return fmt.Errorf("failed to create Langfuse score: %w", err)
+	}
+	return nil
+}
+
 // Flush flushes the Langfuse client
 func (t *LangfuseTracer) Flush() error {
 	if !t.enabled {
@@ -229,12 +238,17 @@ func (m *LLMMiddleware) Generate(ctx context.Context, prompt string, options ...
 	startTime := time.Now()
 
 	// Call the underlying LLM
+	ctx, usage := recordUsage(ctx)
 	response, err := m.llm.Generate(ctx, prompt, options...)
 
 	endTime := time.Now()
 
-	// Extract model from options
+	// Take the model from the usage the LLM reported
 	model := "unknown"
+	total, reported := usage.total()
+	if reported && total.Model != "" {
+		model = total.Model
+	}
 	// Create metadata from options
 	metadata := map[string]interface{}{
 		"options": fmt.Sprintf("%v", options),
@@ -242,7 +256,11 @@ func (m *LLMMiddleware) Generate(ctx context.Context, prompt string, options ...
 
 	// Trace the generation
 	if err == nil {
-		_, traceErr := m.tracer.TraceGeneration(ctx, model, prompt, response, startTime, endTime, metadata)
+		var generationUsage *interfaces.Usage
+		if reported {
+			generationUsage = &total
+		}
+		_, traceErr := m.tracer.traceGeneration(ctx, model, prompt, response, startTime, endTime, metadata, generationUsage)
 		if traceErr != nil {
 			// Log the error but don't fail the request
 			fmt.Printf("Failed to trace generation: %v\n", traceErr)
@@ -267,3 +285,8 @@ func (m *LLMMiddleware) Generate(ctx context.Context, prompt string, options ...
 func (m *LLMMiddleware) Name() string {
 	return m.llm.Name()
 }
+
+// Unwrap returns the wrapped LLM
+func (m *LLMMiddleware) Unwrap() interfaces.LLM {
+	return m.llm
+}
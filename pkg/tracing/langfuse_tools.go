@@ -0,0 +1,214 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/henomis/langfuse-go/model"
+	"github.com/run-bigpig/llm-agent/pkg/executionplan"
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+	"github.com/run-bigpig/llm-agent/pkg/runctx"
+)
+
+type langfuseKey string
+
+// langfuseParentKey is the context key for the current Langfuse observation
+const langfuseParentKey langfuseKey = "langfuse_parent"
+
+// langfuseParent identifies the observation that new observations nest under
+type langfuseParent struct {
+	traceID       string
+	observationID string
+}
+
+// LangfuseObservation is a Langfuse span that has started and not yet ended
+type LangfuseObservation struct {
+	tracer *LangfuseTracer
+	span   *model.Span
+}
+
+// StartObservation starts a span with the given input. Observations started
+// with the returned context, including generations, nest under it. Without a
+// parent in the context, a new trace is created for the user and
+// conversation in the context.
+func (t *LangfuseTracer) StartObservation(ctx context.Context, name string, input interface{}, metadata map[string]interface{}) (context.Context, *LangfuseObservation) {
+	if !t.enabled {
+		return ctx, nil
+	}
+
+	startTime := time.Now()
+	span := &model.Span{
+		ID:        uuid.New().String(),
+		Name:      name,
+		StartTime: &startTime,
+		Input:     input,
+		Metadata:  t.runMetadata(ctx, metadata),
+	}
+	span.TraceID, span.ParentObservationID = t.parent(ctx, name)
+
+	if _, err := t.client.Span(span, nil); err != nil {
+		// Log the error but don't fail the request
+		fmt.Printf("Failed to start Langfuse span: %v\n", err)
+		return ctx, nil
+	}
+
+	ctx = context.WithValue(ctx, langfuseParentKey, langfuseParent{traceID: span.TraceID, observationID: span.ID})
+	if span.TraceID != "" {
+		ctx = runctx.WithTraceID(ctx, span.TraceID)
+	}
+	return ctx, &LangfuseObservation{tracer: t, span: span}
+}
+
+// End ends the observation with its output. An error marks it as failed.
+func (o *LangfuseObservation) End(output interface{}, err error) {
+	if o == nil {
+		return
+	}
+
+	endTime := time.Now()
+	o.span.EndTime = &endTime
+	o.span.Output = output
+	if err != nil {
+		o.span.Level = model.ObservationLevelError
+		o.span.StatusMessage = err.Error()
+	}
+
+	if _, endErr := o.tracer.client.SpanEnd(o.span); endErr != nil {
+		// Log the error but don't fail the request
+		fmt.Printf("Failed to end Langfuse span: %v\n", endErr)
+	}
+}
+
+// StartPlan starts an observation for an execution plan. It implements
+// executionplan.Observer, so the tracer can be passed to executionplan.WithObserver.
+func (t *LangfuseTracer) StartPlan(ctx context.Context, plan *executionplan.ExecutionPlan) (context.Context, func(string, error)) {
+	ctx, observation := t.StartObservation(ctx, "execution_plan", plan.Description, map[string]interface{}{
+		"task_id": plan.TaskID,
+		"steps":   len(plan.Steps),
+	})
+	return ctx, func(result string, err error) {
+		observation.End(result, err)
+	}
+}
+
+// StartStep starts an observation for a step of an execution plan
+func (t *LangfuseTracer) StartStep(ctx context.Context, plan *executionplan.ExecutionPlan, index int, step executionplan.ExecutionStep) (context.Context, func(string, error)) {
+	ctx, observation := t.StartObservation(ctx, fmt.Sprintf("step %d: %s", index+1, step.ToolName), step.Input, map[string]interface{}{
+		"task_id":     plan.TaskID,
+		"step":        index + 1,
+		"tool":        step.ToolName,
+		"description": step.Description,
+	})
+	return ctx, func(result string, err error) {
+		observation.End(result, err)
+	}
+}
+
+// runMetadata adds the run identifiers and environment to the metadata
+func (t *LangfuseTracer) runMetadata(ctx context.Context, metadata map[string]interface{}) map[string]interface{} {
+	if metadata == nil {
+		metadata = make(map[string]interface{})
+	}
+	metadata["org_id"] = runctx.OrgID(ctx)
+	for k, v := range runctx.From(ctx).Fields() {
+		metadata[k] = v
+	}
+	metadata["environment"] = t.environment
+	return metadata
+}
+
+// parent returns the trace and observation that a new observation belongs
+// to. Without a parent in the context, a new trace is created.
+func (t *LangfuseTracer) parent(ctx context.Context, name string) (string, string) {
+	if parent, ok := ctx.Value(langfuseParentKey).(langfuseParent); ok {
+		return parent.traceID, parent.observationID
+	}
+
+	trace, err := t.client.Trace(&model.Trace{
+		Name:      name,
+		UserID:    runctx.UserID(ctx),
+		SessionID: runctx.ConversationID(ctx),
+		Tags:      traceTags(ctx),
+	})
+	if err != nil {
+		return "", ""
+	}
+	return trace.ID, ""
+}
+
+// traceTags returns the attribution tags in the context as "name:value"
+// trace tags, so traces can be filtered by them in Langfuse
+func traceTags(ctx context.Context) []string {
+	tags := runctx.Tags(ctx)
+	if len(tags) == 0 {
+		return nil
+	}
+	traceTags := make([]string, 0, len(tags))
+	for k, v := range tags {
+		traceTags = append(traceTags, k+":"+v)
+	}
+	sort.Strings(traceTags)
+	return traceTags
+}
+
+// ToolMiddleware implements middleware for tools with Langfuse tracing
+type ToolMiddleware struct {
+	tool   interfaces.Tool
+	tracer *LangfuseTracer
+}
+
+// NewToolMiddleware creates a new tool middleware with Langfuse tracing. Each
+// call appears as a span with the tool's input and output.
+func NewToolMiddleware(tool interfaces.Tool, tracer *LangfuseTracer) *ToolMiddleware {
+	return &ToolMiddleware{
+		tool:   tool,
+		tracer: tracer,
+	}
+}
+
+// NewToolMiddlewares wraps each of the tools with Langfuse tracing
+func NewToolMiddlewares(tools []interfaces.Tool, tracer *LangfuseTracer) []interfaces.Tool {
+	traced := make([]interfaces.Tool, len(tools))
+	for i, tool := range tools {
+		traced[i] = NewToolMiddleware(tool, tracer)
+	}
+	return traced
+}
+
+// Name returns the name of the tool
+func (m *ToolMiddleware) Name() string {
+	return m.tool.Name()
+}
+
+// Description returns a description of what the tool does
+func (m *ToolMiddleware) Description() string {
+	return m.tool.Description()
+}
+
+// Parameters returns the parameters that the tool accepts
+func (m *ToolMiddleware) Parameters() map[string]interfaces.ParameterSpec {
+	return m.tool.Parameters()
+}
+
+// Run executes the tool with the given input
+func (m *ToolMiddleware) Run(ctx context.Context, input string) (string, error) {
+	return m.trace(ctx, input, m.tool.Run)
+}
+
+// Execute executes the tool with the given arguments
+func (m *ToolMiddleware) Execute(ctx context.Context, args string) (string, error) {
+	return m.trace(ctx, args, m.tool.Execute)
+}
+
+// trace calls the tool inside a Langfuse observation
+func (m *ToolMiddleware) trace(ctx context.Context, input string, call func(context.Context, string) (string, error)) (string, error) {
+	ctx, observation := m.tracer.StartObservation(ctx, "tool:"+m.tool.Name(), input, map[string]interface{}{
+		"tool": m.tool.Name(),
+	})
+	output, err := call(ctx, input)
+	observation.End(output, err)
+	return output, err
+}
@@ -0,0 +1,449 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/run-bigpig/llm-agent/pkg/config"
+	"github.com/run-bigpig/llm-agent/pkg/executionplan"
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+	"github.com/run-bigpig/llm-agent/pkg/runctx"
+)
+
+// LangSmith run types
+const (
+	LangSmithRunLLM   = "llm"
+	LangSmithRunTool  = "tool"
+	LangSmithRunChain = "chain"
+)
+
+// langsmithQueueSize is the number of runs that can wait to be sent
+const langsmithQueueSize = 1000
+
+type langsmithKey string
+
+// langsmithParentKey is the context key for the current LangSmith run
+const langsmithParentKey langsmithKey = "langsmith_parent"
+
+// LangSmithTracer implements tracing using the LangSmith API. Runs are sent
+// in the background, in the order they were started and ended.
+type LangSmithTracer struct {
+	enabled  bool
+	endpoint string
+	apiKey   string
+	project  string
+	client   *http.Client
+
+	queue     chan langsmithRequest
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// LangSmithConfig contains configuration for LangSmith
+type LangSmithConfig struct {
+	// Enabled determines whether LangSmith tracing is enabled
+	Enabled bool
+
+	// APIKey is the LangSmith API key
+	APIKey string
+
+	// Endpoint is the LangSmith API endpoint (optional)
+	Endpoint string
+
+	// Project is the LangSmith project that runs are logged to (optional)
+	Project string
+
+	// HTTPClient is the client used to call the API (optional)
+	HTTPClient *http.Client
+}
+
+// langsmithRequest is a call to the LangSmith API. A request without a method
+// is a flush marker.
+type langsmithRequest struct {
+	method  string
+	path    string
+	body    map[string]interface{}
+	flushed chan struct{}
+}
+
+// NewLangSmithTracer creates a new LangSmith tracer
+func NewLangSmithTracer(customConfig ...LangSmithConfig) (*LangSmithTracer, error) {
+	// Use custom config if provided, otherwise use global config
+	var tracerConfig LangSmithConfig
+	if len(customConfig) > 0 {
+		tracerConfig = customConfig[0]
+	} else {
+		cfg := config.Get()
+		tracerConfig = LangSmithConfig{
+			Enabled:  cfg.Tracing.LangSmith.Enabled,
+			APIKey:   cfg.Tracing.LangSmith.APIKey,
+			Endpoint: cfg.Tracing.LangSmith.Endpoint,
+			Project:  cfg.Tracing.LangSmith.Project,
+		}
+	}
+
+	if !tracerConfig.Enabled {
+		return &LangSmithTracer{
+			enabled: false,
+		}, nil
+	}
+
+	if tracerConfig.APIKey == "" {
+		return nil, fmt.Errorf("LangSmith API key is required")
+	}
+	if tracerConfig.Endpoint == "" {
+		tracerConfig.Endpoint = "https://api.smith.langchain.com"
+	}
+	if tracerConfig.Project == "" {
+		tracerConfig.Project = "default"
+	}
+	if tracerConfig.HTTPClient == nil {
+		tracerConfig.HTTPClient = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	t := &LangSmithTracer{
+		enabled:  true,
+		endpoint: strings.TrimSuffix(tracerConfig.Endpoint, "/"),
+		apiKey:   tracerConfig.APIKey,
+		project:  tracerConfig.Project,
+		client:   tracerConfig.HTTPClient,
+		queue:    make(chan langsmithRequest, langsmithQueueSize),
+		done:     make(chan struct{}),
+	}
+	go t.run()
+
+	return t, nil
+}
+
+// run sends queued requests until the tracer is closed
+func (t *LangSmithTracer) run() {
+	for {
+		select {
+		case req := <-t.queue:
+			t.send(req)
+		case <-t.done:
+			return
+		}
+	}
+}
+
+// send calls the LangSmith API
+func (t *LangSmithTracer) send(req langsmithRequest) {
+	if req.flushed != nil {
+		close(req.flushed)
+		return
+	}
+
+	body, err := json.Marshal(req.body)
+	if err != nil {
+		fmt.Printf("Failed to encode LangSmith run: %v\n", err)
+		return
+	}
+
+	httpReq, err := http.NewRequest(req.method, t.endpoint+req.path, bytes.NewReader(body))
+	if err != nil {
+		fmt.Printf("Failed to create LangSmith request: %v\n", err)
+		return
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("x-api-key", t.apiKey)
+
+	resp, err := t.client.Do(httpReq)
+	if err != nil {
+		// Log the error but don't fail the request
+		fmt.Printf("Failed to send LangSmith run: %v\n", err)
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		fmt.Printf("Failed to send LangSmith run: status %d\n", resp.StatusCode)
+	}
+}
+
+// enqueue queues a request, dropping it if the queue is full
+func (t *LangSmithTracer) enqueue(req langsmithRequest) {
+	select {
+	case t.queue <- req:
+	default:
+		fmt.Printf("Dropping LangSmith run: queue is full\n")
+	}
+}
+
+// Flush waits until the runs queued so far have been sent
+func (t *LangSmithTracer) Flush() error {
+	if !t.enabled {
+		return nil
+	}
+
+	flushed := make(chan struct{})
+	select {
+	case t.queue <- langsmithRequest{flushed: flushed}:
+	case <-t.done:
+		return nil
+	}
+	select {
+	case <-flushed:
+	case <-t.done:
+	}
+	return nil
+}
+
+// Close flushes remaining runs and stops the tracer
+func (t *LangSmithTracer) Close() error {
+	if !t.enabled {
+		return nil
+	}
+
+	err := t.Flush()
+	t.closeOnce.Do(func() {
+		close(t.done)
+	})
+	return err
+}
+
+// langsmithParent identifies the run that new runs nest under
+type langsmithParent struct {
+	traceID     string
+	runID       string
+	dottedOrder string
+}
+
+// LangSmithRun is a LangSmith run that has started and not yet ended
+type LangSmithRun struct {
+	tracer *LangSmithTracer
+	id     string
+}
+
+// StartRun starts a run of the given type with its inputs. Runs started with
+// the returned context nest under it.
+func (t *LangSmithTracer) StartRun(ctx context.Context, name string, runType string, inputs map[string]interface{}, metadata map[string]interface{}) (context.Context, *LangSmithRun) {
+	if !t.enabled {
+		return ctx, nil
+	}
+
+	startTime := time.Now().UTC()
+	id := uuid.New().String()
+	// The dotted order sorts a run after its parent and earlier siblings
+	dottedOrder := startTime.Format("20060102T150405") + fmt.Sprintf("%06dZ", startTime.Nanosecond()/1000) + id
+
+	body := map[string]interface{}{
+		"id":           id,
+		"name":         name,
+		"run_type":     runType,
+		"inputs":       inputs,
+		"start_time":   startTime.Format(time.RFC3339Nano),
+		"session_name": t.project,
+		"extra": map[string]interface{}{
+			"metadata": langsmithMetadata(ctx, metadata),
+		},
+	}
+
+	parent := langsmithParent{traceID: id, runID: id, dottedOrder: dottedOrder}
+	if p, ok := ctx.Value(langsmithParentKey).(langsmithParent); ok {
+		body["parent_run_id"] = p.runID
+		parent.traceID = p.traceID
+		parent.dottedOrder = p.dottedOrder + "." + dottedOrder
+	}
+	body["trace_id"] = parent.traceID
+	body["dotted_order"] = parent.dottedOrder
+
+	t.enqueue(langsmithRequest{method: http.MethodPost, path: "/runs", body: body})
+
+	ctx = context.WithValue(ctx, langsmithParentKey, parent)
+	return ctx, &LangSmithRun{tracer: t, id: id}
+}
+
+// End ends the run with its outputs. An error marks it as failed.
+func (r *LangSmithRun) End(outputs map[string]interface{}, err error) {
+	if r == nil {
+		return
+	}
+
+	body := map[string]interface{}{
+		"outputs":  outputs,
+		"end_time": time.Now().UTC().Format(time.RFC3339Nano),
+	}
+	if err != nil {
+		body["error"] = err.Error()
+	}
+
+	r.tracer.enqueue(langsmithRequest{method: http.MethodPatch, path: "/runs/" + r.id, body: body})
+}
+
+// langsmithMetadata adds the run identifiers to the metadata
+func langsmithMetadata(ctx context.Context, metadata map[string]interface{}) map[string]interface{} {
+	result := make(map[string]interface{}, len(metadata)+4)
+	for k, v := range metadata {
+		result[k] = v
+	}
+	for k, v := range runctx.From(ctx).Fields() {
+		result[k] = v
+	}
+	return result
+}
+
+// StartPlan starts a run for an execution plan. It implements
+// executionplan.Observer, so the tracer can be passed to executionplan.WithObserver.
+func (t *LangSmithTracer) StartPlan(ctx context.Context, plan *executionplan.ExecutionPlan) (context.Context, func(string, error)) {
+	ctx, run := t.StartRun(ctx, "execution_plan", LangSmithRunChain, map[string]interface{}{
+		"input": plan.Description,
+	}, map[string]interface{}{
+		"task_id": plan.TaskID,
+		"steps":   len(plan.Steps),
+	})
+	return ctx, func(result string, err error) {
+		run.End(map[string]interface{}{"output": result}, err)
+	}
+}
+
+// StartStep starts a run for a step of an execution plan
+func (t *LangSmithTracer) StartStep(ctx context.Context, plan *executionplan.ExecutionPlan, index int, step executionplan.ExecutionStep) (context.Context, func(string, error)) {
+	ctx, run := t.StartRun(ctx, fmt.Sprintf("step %d: %s", index+1, step.ToolName), LangSmithRunChain, map[string]interface{}{
+		"input": step.Input,
+	}, map[string]interface{}{
+		"task_id":     plan.TaskID,
+		"step":        index + 1,
+		"tool":        step.ToolName,
+		"description": step.Description,
+	})
+	return ctx, func(result string, err error) {
+		run.End(map[string]interface{}{"output": result}, err)
+	}
+}
+
+// LangSmithLLMMiddleware implements middleware for LLM calls with LangSmith tracing
+type LangSmithLLMMiddleware struct {
+	llm    interfaces.LLM
+	tracer *LangSmithTracer
+}
+
+// NewLangSmithLLMMiddleware creates a new LLM middleware with LangSmith tracing
+func NewLangSmithLLMMiddleware(llm interfaces.LLM, tracer *LangSmithTracer) *LangSmithLLMMiddleware {
+	return &LangSmithLLMMiddleware{
+		llm:    llm,
+		tracer: tracer,
+	}
+}
+
+// Generate implements interfaces.LLM.Generate
+func (m *LangSmithLLMMiddleware) Generate(ctx context.Context, prompt string, options ...interfaces.GenerateOption) (string, error) {
+	ctx, run := m.startRun(ctx, "llm.generate", prompt, nil)
+	ctx, usage := recordUsage(ctx)
+	response, err := m.llm.Generate(ctx, prompt, options...)
+	run.End(llmOutputs(response, usage), err)
+	return response, err
+}
+
+// GenerateWithTools implements interfaces.LLM.GenerateWithTools
+func (m *LangSmithLLMMiddleware) GenerateWithTools(ctx context.Context, prompt string, tools []interfaces.Tool, options ...interfaces.GenerateOption) (string, error) {
+	ctx, run := m.startRun(ctx, "llm.generate_with_tools", prompt, map[string]interface{}{
+		"tools_count": len(tools),
+	})
+	ctx, usage := recordUsage(ctx)
+	response, err := m.llm.GenerateWithTools(ctx, prompt, tools, options...)
+	run.End(llmOutputs(response, usage), err)
+	return response, err
+}
+
+// Name implements interfaces.LLM.Name
+func (m *LangSmithLLMMiddleware) Name() string {
+	return m.llm.Name()
+}
+
+// Unwrap returns the wrapped LLM
+func (m *LangSmithLLMMiddleware) Unwrap() interfaces.LLM {
+	return m.llm
+}
+
+// startRun starts an LLM run for the prompt
+func (m *LangSmithLLMMiddleware) startRun(ctx context.Context, name string, prompt string, metadata map[string]interface{}) (context.Context, *LangSmithRun) {
+	if metadata == nil {
+		metadata = make(map[string]interface{})
+	}
+	metadata["ls_provider"] = m.llm.Name()
+	return m.tracer.StartRun(ctx, name, LangSmithRunLLM, map[string]interface{}{
+		"prompts": []string{prompt},
+	}, metadata)
+}
+
+// llmOutputs returns the outputs of an LLM run in the format LangSmith
+// displays, with the token usage if the LLM reported it
+func llmOutputs(response string, usage *usageRecorder) map[string]interface{} {
+	outputs := map[string]interface{}{
+		"generations": [][]map[string]string{{{"text": response}}},
+	}
+	if total, ok := usage.total(); ok {
+		outputs["usage_metadata"] = map[string]int{
+			"input_tokens":  total.InputTokens,
+			"output_tokens": total.OutputTokens,
+			"total_tokens":  total.TotalTokens(),
+		}
+	}
+	return outputs
+}
+
+// LangSmithToolMiddleware implements middleware for tools with LangSmith tracing
+type LangSmithToolMiddleware struct {
+	tool   interfaces.Tool
+	tracer *LangSmithTracer
+}
+
+// NewLangSmithToolMiddleware creates a new tool middleware with LangSmith tracing
+func NewLangSmithToolMiddleware(tool interfaces.Tool, tracer *LangSmithTracer) *LangSmithToolMiddleware {
+	return &LangSmithToolMiddleware{
+		tool:   tool,
+		tracer: tracer,
+	}
+}
+
+// NewLangSmithToolMiddlewares wraps each of the tools with LangSmith tracing
+func NewLangSmithToolMiddlewares(tools []interfaces.Tool, tracer *LangSmithTracer) []interfaces.Tool {
+	traced := make([]interfaces.Tool, len(tools))
+	for i, tool := range tools {
+		traced[i] = NewLangSmithToolMiddleware(tool, tracer)
+	}
+	return traced
+}
+
+// Name returns the name of the tool
+func (m *LangSmithToolMiddleware) Name() string {
+	return m.tool.Name()
+}
+
+// Description returns a description of what the tool does
+func (m *LangSmithToolMiddleware) Description() string {
+	return m.tool.Description()
+}
+
+// Parameters returns the parameters that the tool accepts
+func (m *LangSmithToolMiddleware) Parameters() map[string]interfaces.ParameterSpec {
+	return m.tool.Parameters()
+}
+
+// Run executes the tool with the given input
+func (m *LangSmithToolMiddleware) Run(ctx context.Context, input string) (string, error) {
+	return m.trace(ctx, input, m.tool.Run)
+}
+
+// Execute executes the tool with the given arguments
+func (m *LangSmithToolMiddleware) Execute(ctx context.Context, args string) (string, error) {
+	return m.trace(ctx, args, m.tool.Execute)
+}
+
+// trace calls the tool inside a LangSmith run
+func (m *LangSmithToolMiddleware) trace(ctx context.Context, input string, call func(context.Context, string) (string, error)) (string, error) {
+	ctx, run := m.tracer.StartRun(ctx, m.tool.Name(), LangSmithRunTool, map[string]interface{}{
+		"input": input,
+	}, nil)
+	output, err := call(ctx, input)
+	run.End(map[string]interface{}{"output": output}, err)
+	return output, err
+}
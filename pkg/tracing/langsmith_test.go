@@ -0,0 +1,117 @@
+package tracing_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+	"github.com/run-bigpig/llm-agent/pkg/runctx"
+	"github.com/run-bigpig/llm-agent/pkg/tracing"
+)
+
+type echoTool struct{}
+
+func (t *echoTool) Name() string                                    { return "echo" }
+func (t *echoTool) Description() string                             { return "Echoes its input" }
+func (t *echoTool) Parameters() map[string]interfaces.ParameterSpec { return nil }
+func (t *echoTool) Run(ctx context.Context, input string) (string, error) {
+	return t.Execute(ctx, input)
+}
+func (t *echoTool) Execute(ctx context.Context, args string) (string, error) {
+	if args == "fail" {
+		return "", errors.New("echo failed")
+	}
+	return args, nil
+}
+
+type langsmithCall struct {
+	method string
+	path   string
+	body   map[string]interface{}
+}
+
+func TestLangSmithTracer(t *testing.T) {
+	var mu sync.Mutex
+	var calls []langsmithCall
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("x-api-key") != "ls-key" {
+			t.Errorf("unexpected API key %q", r.Header.Get("x-api-key"))
+		}
+		var body map[string]interface{}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		mu.Lock()
+		calls = append(calls, langsmithCall{method: r.Method, path: r.URL.Path, body: body})
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	tracer, err := tracing.NewLangSmithTracer(tracing.LangSmithConfig{
+		Enabled:  true,
+		APIKey:   "ls-key",
+		Endpoint: server.URL,
+		Project:  "agents",
+	})
+	if err != nil {
+		t.Fatalf("failed to create tracer: %v", err)
+	}
+	defer tracer.Close()
+
+	ctx := runctx.WithUserID(context.Background(), "user-1")
+	ctx, parent := tracer.StartRun(ctx, "agent", tracing.LangSmithRunChain, map[string]interface{}{"input": "hi"}, nil)
+	tool := tracing.NewLangSmithToolMiddleware(&echoTool{}, tracer)
+	if _, err := tool.Run(ctx, "fail"); err == nil {
+		t.Fatal("expected the tool to fail")
+	}
+	parent.End(map[string]interface{}{"output": "bye"}, nil)
+
+	if err := tracer.Flush(); err != nil {
+		t.Fatalf("failed to flush: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(calls) != 4 {
+		t.Fatalf("expected 4 calls, got %d: %+v", len(calls), calls)
+	}
+
+	root, child := calls[0], calls[1]
+	if root.method != http.MethodPost || root.path != "/runs" || root.body["session_name"] != "agents" || root.body["run_type"] != "chain" {
+		t.Errorf("unexpected root run: %+v", root)
+	}
+	if metadata := root.body["extra"].(map[string]interface{})["metadata"].(map[string]interface{}); metadata["user_id"] != "user-1" {
+		t.Errorf("expected the user ID in the metadata, got %v", metadata)
+	}
+	if child.body["parent_run_id"] != root.body["id"] || child.body["trace_id"] != root.body["id"] || child.body["run_type"] != "tool" {
+		t.Errorf("expected the tool run to nest under the root run: %+v", child)
+	}
+	if !strings.HasPrefix(child.body["dotted_order"].(string), root.body["dotted_order"].(string)+".") {
+		t.Errorf("expected the child dotted order to extend the parent's: %v", child.body["dotted_order"])
+	}
+
+	childEnd := calls[2]
+	if childEnd.method != http.MethodPatch || childEnd.path != "/runs/"+child.body["id"].(string) || childEnd.body["error"] != "echo failed" {
+		t.Errorf("unexpected tool run end: %+v", childEnd)
+	}
+	if rootEnd := calls[3]; rootEnd.path != "/runs/"+root.body["id"].(string) || rootEnd.body["error"] != nil {
+		t.Errorf("unexpected root run end: %+v", rootEnd)
+	}
+}
+
+func TestLangSmithTracerDisabled(t *testing.T) {
+	tracer, err := tracing.NewLangSmithTracer(tracing.LangSmithConfig{})
+	if err != nil {
+		t.Fatalf("failed to create tracer: %v", err)
+	}
+
+	ctx, run := tracer.StartRun(context.Background(), "agent", tracing.LangSmithRunChain, nil, nil)
+	if run != nil || ctx != context.Background() {
+		t.Error("expected a disabled tracer to start no runs")
+	}
+	run.End(nil, nil)
+}
@@ -2,6 +2,7 @@ package tracing
 
 import (
 	"context"
+	"sync"
 
 	"github.com/run-bigpig/llm-agent/pkg/interfaces"
 )
@@ -10,3 +11,30 @@ import (
 func (m *LLMMiddleware) GenerateWithTools(ctx context.Context, prompt string, tools []interfaces.Tool, options ...interfaces.GenerateOption) (string, error) {
 	return m.llm.GenerateWithTools(ctx, prompt, tools, options...)
 }
+
+// usageRecorder adds up the usage the LLM reports during a call
+type usageRecorder struct {
+	mu       sync.Mutex
+	usage    interfaces.Usage
+	reported bool
+}
+
+// recordUsage returns a context in which the usage of LLM requests is added
+// up by the recorder and passed on to the usage callback of ctx
+func recordUsage(ctx context.Context) (context.Context, *usageRecorder) {
+	r := &usageRecorder{}
+	return interfaces.WithUsageCallback(ctx, func(usage interfaces.Usage) {
+		r.mu.Lock()
+		r.usage.Add(usage)
+		r.reported = true
+		r.mu.Unlock()
+		interfaces.ReportUsage(ctx, usage)
+	}), r
+}
+
+// total returns the usage of the call and whether the LLM reported any
+func (r *usageRecorder) total() (interfaces.Usage, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.usage, r.reported
+}
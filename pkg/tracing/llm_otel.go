@@ -6,6 +6,7 @@ import (
 
 	"github.com/run-bigpig/llm-agent/pkg/interfaces"
 	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // LLMOTelMiddleware wraps an LLM with OpenTelemetry tracing
@@ -26,25 +27,24 @@ func NewLLMOTelMiddleware(llm interfaces.LLM, tracer *OTelTracer) *LLMOTelMiddle
 func (m *LLMOTelMiddleware) Generate(ctx context.Context, prompt string, options ...interfaces.GenerateOption) (string, error) {
 	// Create attributes
 	attributes := map[string]string{
-		"prompt.length": fmt.Sprintf("%d", len(prompt)),
-		"model":         "unknown", // We can't easily extract the model from options anymore
+		"prompt.length":       fmt.Sprintf("%d", len(prompt)),
+		"model":               "unknown", // We can't easily extract the model from options anymore
+		OpenInferenceProvider: m.llm.Name(),
 	}
 
 	// Start span
-	ctx, span := m.tracer.StartSpan(ctx, "llm.generate", attributes)
-	defer func() {
-		m.tracer.EndSpan(span, nil)
-	}()
+	ctx, span := m.tracer.startOpenInferenceSpan(ctx, "llm.generate", OpenInferenceKindLLM, prompt, attributes)
 
 	// Call the underlying LLM
+	ctx, usage := recordUsage(ctx)
 	response, err := m.llm.Generate(ctx, prompt, options...)
 
 	// Record response attributes
 	if err == nil {
 		span.SetAttributes(attribute.Int("response.length", len(response)))
-	} else {
-		span.RecordError(err)
 	}
+	setUsageAttributes(span, usage)
+	m.tracer.endOpenInferenceSpan(span, response, err)
 
 	return response, err
 }
@@ -53,30 +53,50 @@ func (m *LLMOTelMiddleware) Generate(ctx context.Context, prompt string, options
 func (m *LLMOTelMiddleware) GenerateWithTools(ctx context.Context, prompt string, tools []interfaces.Tool, options ...interfaces.GenerateOption) (string, error) {
 	// Create attributes
 	attributes := map[string]string{
-		"prompt.length": fmt.Sprintf("%d", len(prompt)),
-		"tools.count":   fmt.Sprintf("%d", len(tools)),
+		"prompt.length":       fmt.Sprintf("%d", len(prompt)),
+		"tools.count":         fmt.Sprintf("%d", len(tools)),
+		OpenInferenceProvider: m.llm.Name(),
 	}
 
 	// Start span
-	ctx, span := m.tracer.StartSpan(ctx, "llm.generate_with_tools", attributes)
-	defer func() {
-		m.tracer.EndSpan(span, nil)
-	}()
+	ctx, span := m.tracer.startOpenInferenceSpan(ctx, "llm.generate_with_tools", OpenInferenceKindLLM, prompt, attributes)
 
 	// Call the underlying LLM
+	ctx, usage := recordUsage(ctx)
 	response, err := m.llm.GenerateWithTools(ctx, prompt, tools, options...)
 
 	// Record response attributes
 	if err == nil {
 		span.SetAttributes(attribute.Int("response.length", len(response)))
-	} else {
-		span.RecordError(err)
 	}
+	setUsageAttributes(span, usage)
+	m.tracer.endOpenInferenceSpan(span, response, err)
 
 	return response, err
 }
 
+// setUsageAttributes records the token usage the LLM reported on the span
+func setUsageAttributes(span trace.Span, usage *usageRecorder) {
+	total, ok := usage.total()
+	if !ok {
+		return
+	}
+	span.SetAttributes(
+		attribute.Int(OpenInferenceTokenCountPrompt, total.InputTokens),
+		attribute.Int(OpenInferenceTokenCountCompletion, total.OutputTokens),
+		attribute.Int(OpenInferenceTokenCountTotal, total.TotalTokens()),
+	)
+	if total.Model != "" {
+		span.SetAttributes(attribute.String(OpenInferenceModelName, total.Model))
+	}
+}
+
 // Name implements interfaces.LLM.Name
 func (m *LLMOTelMiddleware) Name() string {
 	return m.llm.Name()
 }
+
+// Unwrap returns the wrapped LLM
+func (m *LLMOTelMiddleware) Unwrap() interfaces.LLM {
+	return m.llm
+}
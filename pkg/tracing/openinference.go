@@ -0,0 +1,147 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/run-bigpig/llm-agent/pkg/executionplan"
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+	"github.com/run-bigpig/llm-agent/pkg/runctx"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// OpenInference semantic convention attributes
+// (https://github.com/Arize-ai/openinference)
+const (
+	OpenInferenceSpanKind = "openinference.span.kind"
+	OpenInferenceInput    = "input.value"
+	OpenInferenceOutput   = "output.value"
+	OpenInferenceProvider = "llm.provider"
+	OpenInferenceToolName = "tool.name"
+	OpenInferenceUserID   = "user.id"
+	OpenInferenceSession  = "session.id"
+
+	OpenInferenceModelName            = "llm.model_name"
+	OpenInferenceTokenCountPrompt     = "llm.token_count.prompt"
+	OpenInferenceTokenCountCompletion = "llm.token_count.completion"
+	OpenInferenceTokenCountTotal      = "llm.token_count.total"
+)
+
+// OpenInference span kinds
+const (
+	OpenInferenceKindLLM   = "LLM"
+	OpenInferenceKindTool  = "TOOL"
+	OpenInferenceKindChain = "CHAIN"
+)
+
+// startOpenInferenceSpan starts a span of the given OpenInference kind. The
+// OpenInference attributes are only added when the tracer is configured to
+// emit them.
+func (t *OTelTracer) startOpenInferenceSpan(ctx context.Context, name string, kind string, input string, attributes map[string]string) (context.Context, trace.Span) {
+	ctx, span := t.StartSpan(ctx, name, attributes)
+	if t.enabled && t.openInference {
+		span.SetAttributes(
+			attribute.String(OpenInferenceSpanKind, kind),
+			attribute.String(OpenInferenceInput, input),
+		)
+		if userID := runctx.UserID(ctx); userID != "" {
+			span.SetAttributes(attribute.String(OpenInferenceUserID, userID))
+		}
+		if conversationID := runctx.ConversationID(ctx); conversationID != "" {
+			span.SetAttributes(attribute.String(OpenInferenceSession, conversationID))
+		}
+	}
+	return ctx, span
+}
+
+// endOpenInferenceSpan records the output or error and ends the span
+func (t *OTelTracer) endOpenInferenceSpan(span trace.Span, output string, err error) {
+	if t.enabled && t.openInference && err == nil {
+		span.SetAttributes(attribute.String(OpenInferenceOutput, output))
+	}
+	t.EndSpan(span, err)
+}
+
+// StartPlan starts a span for an execution plan. It implements
+// executionplan.Observer, so the tracer can be passed to executionplan.WithObserver.
+func (t *OTelTracer) StartPlan(ctx context.Context, plan *executionplan.ExecutionPlan) (context.Context, func(string, error)) {
+	ctx, span := t.startOpenInferenceSpan(ctx, "execution_plan", OpenInferenceKindChain, plan.Description, map[string]string{
+		"plan.task_id": plan.TaskID,
+		"plan.steps":   fmt.Sprintf("%d", len(plan.Steps)),
+	})
+	return ctx, func(result string, err error) {
+		t.endOpenInferenceSpan(span, result, err)
+	}
+}
+
+// StartStep starts a span for a step of an execution plan
+func (t *OTelTracer) StartStep(ctx context.Context, plan *executionplan.ExecutionPlan, index int, step executionplan.ExecutionStep) (context.Context, func(string, error)) {
+	ctx, span := t.startOpenInferenceSpan(ctx, fmt.Sprintf("step %d: %s", index+1, step.ToolName), OpenInferenceKindChain, step.Input, map[string]string{
+		"plan.task_id":     plan.TaskID,
+		"step.index":       fmt.Sprintf("%d", index+1),
+		"step.tool":        step.ToolName,
+		"step.description": step.Description,
+	})
+	return ctx, func(result string, err error) {
+		t.endOpenInferenceSpan(span, result, err)
+	}
+}
+
+// ToolOTelMiddleware implements middleware for tools with OpenTelemetry tracing
+type ToolOTelMiddleware struct {
+	tool   interfaces.Tool
+	tracer *OTelTracer
+}
+
+// NewToolOTelMiddleware creates a new tool middleware with OpenTelemetry tracing
+func NewToolOTelMiddleware(tool interfaces.Tool, tracer *OTelTracer) *ToolOTelMiddleware {
+	return &ToolOTelMiddleware{
+		tool:   tool,
+		tracer: tracer,
+	}
+}
+
+// NewToolOTelMiddlewares wraps each of the tools with OpenTelemetry tracing
+func NewToolOTelMiddlewares(tools []interfaces.Tool, tracer *OTelTracer) []interfaces.Tool {
+	traced := make([]interfaces.Tool, len(tools))
+	for i, tool := range tools {
+		traced[i] = NewToolOTelMiddleware(tool, tracer)
+	}
+	return traced
+}
+
+// Name returns the name of the tool
+func (m *ToolOTelMiddleware) Name() string {
+	return m.tool.Name()
+}
+
+// Description returns a description of what the tool does
+func (m *ToolOTelMiddleware) Description() string {
+	return m.tool.Description()
+}
+
+// Parameters returns the parameters that the tool accepts
+func (m *ToolOTelMiddleware) Parameters() map[string]interfaces.ParameterSpec {
+	return m.tool.Parameters()
+}
+
+// Run executes the tool with the given input
+func (m *ToolOTelMiddleware) Run(ctx context.Context, input string) (string, error) {
+	return m.trace(ctx, input, m.tool.Run)
+}
+
+// Execute executes the tool with the given arguments
+func (m *ToolOTelMiddleware) Execute(ctx context.Context, args string) (string, error) {
+	return m.trace(ctx, args, m.tool.Execute)
+}
+
+// trace calls the tool inside a span
+func (m *ToolOTelMiddleware) trace(ctx context.Context, input string, call func(context.Context, string) (string, error)) (string, error) {
+	ctx, span := m.tracer.startOpenInferenceSpan(ctx, "tool."+m.tool.Name(), OpenInferenceKindTool, input, map[string]string{
+		OpenInferenceToolName: m.tool.Name(),
+	})
+	output, err := call(ctx, input)
+	m.tracer.endOpenInferenceSpan(span, output, err)
+	return output, err
+}
@@ -5,7 +5,7 @@ import (
 	"fmt"
 
 	"github.com/run-bigpig/llm-agent/pkg/interfaces"
-	"github.com/run-bigpig/llm-agent/pkg/multitenancy"
+	"github.com/run-bigpig/llm-agent/pkg/runctx"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
@@ -19,8 +19,12 @@ import (
 // OTelTracer implements tracing using OpenTelemetry
 type OTelTracer struct {
 	tracer      trace.Tracer
+	provider    *sdktrace.TracerProvider
 	enabled     bool
 	serviceName string
+
+	// openInference adds OpenInference attributes to LLM, tool and plan spans
+	openInference bool
 }
 
 // OTelConfig contains configuration for OpenTelemetry
@@ -33,6 +37,10 @@ type OTelConfig struct {
 
 	// CollectorEndpoint is the endpoint of the OpenTelemetry collector
 	CollectorEndpoint string
+
+	// OpenInference adds OpenInference semantic convention attributes to
+	// spans, so backends such as Arize Phoenix can display them
+	OpenInference bool
 }
 
 // NewOTelTracer creates a new OpenTelemetry tracer
@@ -77,9 +85,11 @@ func NewOTelTracer(config OTelConfig) (*OTelTracer, error) {
 	tracer := tp.Tracer(config.ServiceName)
 
 	return &OTelTracer{
-		tracer:      tracer,
-		enabled:     true,
-		serviceName: config.ServiceName,
+		tracer:        tracer,
+		provider:      tp,
+		enabled:       true,
+		serviceName:   config.ServiceName,
+		openInference: config.OpenInference,
 	}, nil
 }
 
@@ -95,14 +105,33 @@ func (t *OTelTracer) StartSpan(ctx context.Context, name string, attributes map[
 		attrs = append(attrs, attribute.String(k, v))
 	}
 
-	// Get organization ID from context
-	orgID, _ := multitenancy.GetOrgID(ctx)
-	if orgID != "" {
-		attrs = append(attrs, attribute.String("org_id", orgID))
+	// Add organization, user, conversation and request IDs
+	for k, v := range runctx.From(ctx).Fields() {
+		attrs = append(attrs, attribute.String(k, v))
 	}
 
-	// Start span
-	return t.tracer.Start(ctx, name, trace.WithAttributes(attrs...))
+	// Start span, and remember its trace so feedback can be tied to it
+	ctx, span := t.tracer.Start(ctx, name, trace.WithAttributes(attrs...))
+	if sc := span.SpanContext(); sc.HasTraceID() && runctx.TraceID(ctx) == "" {
+		ctx = runctx.WithTraceID(ctx, sc.TraceID().String())
+	}
+	return ctx, span
+}
+
+// Flush exports any spans that have not been exported yet
+func (t *OTelTracer) Flush() error {
+	if !t.enabled || t.provider == nil {
+		return nil
+	}
+	return t.provider.ForceFlush(context.Background())
+}
+
+// Close flushes remaining spans and shuts down the tracer provider
+func (t *OTelTracer) Close() error {
+	if !t.enabled || t.provider == nil {
+		return nil
+	}
+	return t.provider.Shutdown(context.Background())
 }
 
 // EndSpan ends a span
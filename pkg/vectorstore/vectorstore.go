@@ -0,0 +1,61 @@
+package vectorstore
+
+import (
+	"fmt"
+	"net/url"
+
+	"github.com/run-bigpig/llm-agent/pkg/config"
+	"github.com/run-bigpig/llm-agent/pkg/embedding"
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+	"github.com/run-bigpig/llm-agent/pkg/vectorstore/weaviate"
+)
+
+// FromConfig creates a Weaviate vector store from cfg.VectorStore.Weaviate.
+// WEAVIATE_URL takes precedence over WEAVIATE_SCHEME and WEAVIATE_HOST.
+// Documents are stored over gRPC when WEAVIATE_GRPC_HOST is set, and are
+// embedded with OpenAI when an OpenAI API key is configured. A
+// nil cfg uses config.Get().
+func FromConfig(cfg *config.Config) (interfaces.VectorStore, error) {
+	if cfg == nil {
+		cfg = config.Get()
+	}
+
+	c := cfg.VectorStore.Weaviate
+	storeConfig := &interfaces.VectorStoreConfig{
+		Host:   c.Host,
+		Scheme: c.Scheme,
+		APIKey: c.APIKey,
+	}
+
+	if c.URL != "" {
+		u, err := url.Parse(c.URL)
+		if err != nil || u.Host == "" {
+			return nil, fmt.Errorf("invalid WEAVIATE_URL %q", c.URL)
+		}
+		storeConfig.Host = u.Host
+		storeConfig.Scheme = u.Scheme
+	}
+	if storeConfig.Host == "" {
+		return nil, fmt.Errorf("WEAVIATE_HOST or WEAVIATE_URL is required")
+	}
+
+	var options []weaviate.Option
+	if c.ClassName != "" {
+		options = append(options, weaviate.WithClassPrefix(c.ClassName))
+	}
+	if c.GRPCHost != "" {
+		options = append(options, weaviate.WithGRPC(c.GRPCHost, storeConfig.Scheme == "https"))
+	}
+	if c.NativeTenancy {
+		options = append(options, weaviate.WithNativeMultiTenancy())
+	}
+	if cfg.LLM.OpenAI.APIKey != "" {
+		options = append(options, weaviate.WithEmbedder(embedding.NewOpenAIEmbedder(cfg.LLM.OpenAI.APIKey, cfg.LLM.OpenAI.EmbeddingModel)))
+	}
+
+	store := weaviate.New(storeConfig, options...)
+	if store == nil {
+		return nil, fmt.Errorf("failed to create Weaviate client for %s", storeConfig.Host)
+	}
+	return store, nil
+}
@@ -0,0 +1,72 @@
+package vectorstore_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/run-bigpig/llm-agent/pkg/config"
+	"github.com/run-bigpig/llm-agent/pkg/multitenancy"
+	"github.com/run-bigpig/llm-agent/pkg/vectorstore"
+	"github.com/run-bigpig/llm-agent/pkg/vectorstore/weaviate"
+)
+
+func TestFromConfigURL(t *testing.T) {
+	var mu sync.Mutex
+	var authorization []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		authorization = append(authorization, r.Header.Get("Authorization"))
+		mu.Unlock()
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+
+	// The URL takes precedence over the scheme and host
+	cfg := &config.Config{}
+	cfg.VectorStore.Weaviate.URL = server.URL
+	cfg.VectorStore.Weaviate.Scheme = "https"
+	cfg.VectorStore.Weaviate.Host = "127.0.0.1:1"
+	cfg.VectorStore.Weaviate.APIKey = "weaviate-key"
+
+	store, err := vectorstore.FromConfig(cfg)
+	if err != nil {
+		t.Fatalf("FromConfig failed: %v", err)
+	}
+	if _, ok := store.(*weaviate.Store); !ok {
+		t.Fatalf("Expected a Weaviate store, got %T", store)
+	}
+
+	ctx := multitenancy.WithOrgID(context.Background(), "org-1")
+	_, _ = store.Get(ctx, []string{"6a1f0a1e-4b5c-4d6e-8f70-8192a3b4c5d6"})
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(authorization) == 0 {
+		t.Fatal("Expected the store to call the server at the configured URL")
+	}
+	if authorization[0] != "Bearer weaviate-key" {
+		t.Errorf("Expected the API key to be sent, got %q", authorization[0])
+	}
+}
+
+func TestFromConfigErrors(t *testing.T) {
+	cfg := &config.Config{}
+	if _, err := vectorstore.FromConfig(cfg); err == nil || err.Error() != "WEAVIATE_HOST or WEAVIATE_URL is required" {
+		t.Errorf("Expected a missing host error, got %v", err)
+	}
+
+	cfg.VectorStore.Weaviate.URL = "localhost:8080"
+	if _, err := vectorstore.FromConfig(cfg); err == nil || err.Error() != `invalid WEAVIATE_URL "localhost:8080"` {
+		t.Errorf("Expected an invalid URL error, got %v", err)
+	}
+
+	cfg.VectorStore.Weaviate.URL = ""
+	cfg.VectorStore.Weaviate.Host = "localhost:8080"
+	cfg.VectorStore.Weaviate.Scheme = "http"
+	if _, err := vectorstore.FromConfig(cfg); err != nil {
+		t.Errorf("Expected the host to be enough, got %v", err)
+	}
+}
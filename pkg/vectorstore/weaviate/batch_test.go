@@ -0,0 +1,129 @@
+package weaviate_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+	"github.com/run-bigpig/llm-agent/pkg/multitenancy"
+	"github.com/run-bigpig/llm-agent/pkg/retry"
+	weaviatestore "github.com/run-bigpig/llm-agent/pkg/vectorstore/weaviate"
+)
+
+// fakeWeaviate answers the REST endpoints used by Store. batchStatus returns
+// the status code for the n-th batch request.
+func fakeWeaviate(t *testing.T, batchStatus func(n int32) int) (*httptest.Server, *atomic.Int32, *atomic.Int32) {
+	t.Helper()
+	var batches, stored atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.URL.Path == "/v1/meta":
+			fmt.Fprint(w, `{"version":"1.25.0"}`)
+		case r.URL.Path == "/v1/schema" && r.Method == http.MethodGet:
+			fmt.Fprint(w, `{"classes":[]}`)
+		case r.URL.Path == "/v1/schema" && r.Method == http.MethodPost:
+			fmt.Fprint(w, `{}`)
+		case r.URL.Path == "/v1/batch/objects":
+			n := batches.Add(1)
+			if code := batchStatus(n); code != http.StatusOK {
+				w.WriteHeader(code)
+				fmt.Fprint(w, `{"error":[{"message":"try again"}]}`)
+				return
+			}
+			var body struct {
+				Objects []map[string]interface{} `json:"objects"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				t.Errorf("failed to decode batch: %v", err)
+			}
+			stored.Add(int32(len(body.Objects)))
+			fmt.Fprint(w, `[]`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, &batches, &stored
+}
+
+func newTestStore(t *testing.T, server *httptest.Server, options ...weaviatestore.Option) *weaviatestore.Store {
+	t.Helper()
+	options = append([]weaviatestore.Option{
+		weaviatestore.WithEmbedder(&MockEmbedder{}),
+		weaviatestore.WithRetry(retry.WithInitialInterval(time.Millisecond), retry.WithMaxAttempts(3)),
+	}, options...)
+	store := weaviatestore.New(&interfaces.VectorStoreConfig{
+		Host:   strings.TrimPrefix(server.URL, "http://"),
+		Scheme: "http",
+	}, options...)
+	if store == nil {
+		t.Fatal("failed to create store")
+	}
+	return store
+}
+
+func testDocuments(n int) []interfaces.Document {
+	docs := make([]interfaces.Document, n)
+	for i := range docs {
+		docs[i] = interfaces.Document{
+			ID:      fmt.Sprintf("00000000-0000-0000-0000-%012d", i),
+			Content: fmt.Sprintf("document %d", i),
+		}
+	}
+	return docs
+}
+
+func TestStoreRetriesTransientErrors(t *testing.T) {
+	server, batches, stored := fakeWeaviate(t, func(n int32) int {
+		switch n {
+		case 1:
+			return http.StatusServiceUnavailable
+		case 2:
+			return http.StatusTooManyRequests
+		}
+		return http.StatusOK
+	})
+	store := newTestStore(t, server)
+	ctx := multitenancy.WithOrgID(context.Background(), "org-1")
+
+	if err := store.Store(ctx, testDocuments(5)); err != nil {
+		t.Fatalf("expected the batch to succeed after retries, got %v", err)
+	}
+	if batches.Load() != 3 || stored.Load() != 5 {
+		t.Errorf("expected 3 batch requests storing 5 documents, got %d requests and %d documents", batches.Load(), stored.Load())
+	}
+}
+
+func TestStoreDoesNotRetryClientErrors(t *testing.T) {
+	server, batches, _ := fakeWeaviate(t, func(int32) int { return http.StatusUnprocessableEntity })
+	store := newTestStore(t, server)
+	ctx := multitenancy.WithOrgID(context.Background(), "org-1")
+
+	if err := store.Store(ctx, testDocuments(1)); err == nil {
+		t.Fatal("expected an error")
+	}
+	if batches.Load() != 1 {
+		t.Errorf("expected 1 batch request, got %d", batches.Load())
+	}
+}
+
+func TestStoreParallelBatches(t *testing.T) {
+	server, batches, stored := fakeWeaviate(t, func(int32) int { return http.StatusOK })
+	store := newTestStore(t, server, weaviatestore.WithParallelism(3))
+	ctx := multitenancy.WithOrgID(context.Background(), "org-1")
+
+	if err := store.Store(ctx, testDocuments(25), interfaces.WithBatchSize(10)); err != nil {
+		t.Fatalf("failed to store documents: %v", err)
+	}
+	if batches.Load() != 3 || stored.Load() != 25 {
+		t.Errorf("expected 3 batch requests storing 25 documents, got %d requests and %d documents", batches.Load(), stored.Load())
+	}
+}
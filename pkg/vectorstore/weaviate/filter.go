@@ -0,0 +1,268 @@
+package weaviate
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/weaviate/weaviate-go-client/v5/weaviate/filters"
+	"github.com/weaviate/weaviate-go-client/v5/weaviate/graphql"
+	"github.com/weaviate/weaviate/entities/models"
+
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+)
+
+// filterOperators maps comparison operators to Weaviate operators
+var filterOperators = map[interfaces.FilterOperator]filters.WhereOperator{
+	interfaces.FilterEq:  filters.Equal,
+	interfaces.FilterNeq: filters.NotEqual,
+	interfaces.FilterGt:  filters.GreaterThan,
+	interfaces.FilterGte: filters.GreaterThanEqual,
+	interfaces.FilterLt:  filters.LessThan,
+	interfaces.FilterLte: filters.LessThanEqual,
+	interfaces.FilterIn:  filters.ContainsAny,
+}
+
+// whereFromFilter translates a filter into a Weaviate where filter. An empty
+// And returns nil, i.e. no filter.
+func whereFromFilter(f interfaces.Filter) (*filters.WhereBuilder, error) {
+	switch f.Operator {
+	case interfaces.FilterAnd, interfaces.FilterOr:
+		var operands []*filters.WhereBuilder
+		for _, operand := range f.Operands {
+			where, err := whereFromFilter(operand)
+			if err != nil {
+				return nil, err
+			}
+			if where != nil {
+				operands = append(operands, where)
+			}
+		}
+		switch {
+		case len(operands) == 0:
+			return nil, nil
+		case len(operands) == 1:
+			return operands[0], nil
+		case f.Operator == interfaces.FilterAnd:
+			return filters.Where().WithOperator(filters.And).WithOperands(operands), nil
+		default:
+			return filters.Where().WithOperator(filters.Or).WithOperands(operands), nil
+		}
+	}
+
+	operator, ok := filterOperators[f.Operator]
+	if !ok {
+		return nil, fmt.Errorf("unsupported filter operator %q", f.Operator)
+	}
+	values := []interface{}{f.Value}
+	if f.Operator == interfaces.FilterIn {
+		values, _ = f.Value.([]interface{})
+	}
+
+	where := filters.Where().WithPath([]string{f.Field}).WithOperator(operator)
+	return withValues(where, f.Field, values)
+}
+
+// withValues sets the filter values with the value type Weaviate expects.
+// Numbers are sent as numbers, which is how the store's auto-schema types
+// numeric metadata.
+func withValues(where *filters.WhereBuilder, field string, values []interface{}) (*filters.WhereBuilder, error) {
+	if len(values) == 0 {
+		return nil, fmt.Errorf("filter on %s has no value", field)
+	}
+
+	switch values[0].(type) {
+	case string:
+		strs := make([]string, len(values))
+		for i, v := range values {
+			s, ok := v.(string)
+			if !ok {
+				return nil, fmt.Errorf("filter on %s mixes value types", field)
+			}
+			strs[i] = s
+		}
+		return where.WithValueString(strs...), nil
+	case bool:
+		bools := make([]bool, len(values))
+		for i, v := range values {
+			b, ok := v.(bool)
+			if !ok {
+				return nil, fmt.Errorf("filter on %s mixes value types", field)
+			}
+			bools[i] = b
+		}
+		return where.WithValueBoolean(bools...), nil
+	case time.Time:
+		times := make([]time.Time, len(values))
+		for i, v := range values {
+			t, ok := v.(time.Time)
+			if !ok {
+				return nil, fmt.Errorf("filter on %s mixes value types", field)
+			}
+			times[i] = t
+		}
+		return where.WithValueDate(times...), nil
+	}
+
+	numbers := make([]float64, len(values))
+	for i, v := range values {
+		n, ok := interfaces.FilterNumber(v)
+		if !ok {
+			return nil, fmt.Errorf("unsupported value %v (%T) in filter on %s", v, v, field)
+		}
+		numbers[i] = n
+	}
+	return where.WithValueNumber(numbers...), nil
+}
+
+// searchFilter builds the where filter of a search from the store-specific
+// filter map and the typed filter, combining them if both are set
+func (s *Store) searchFilter(opts *interfaces.SearchOptions) (*filters.WhereBuilder, error) {
+	where := s.buildWhereFilter(opts.Filters)
+	if opts.Filter == nil {
+		return where, nil
+	}
+
+	if err := opts.Filter.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid filter: %w", err)
+	}
+	typed, err := whereFromFilter(*opts.Filter)
+	if err != nil {
+		return nil, fmt.Errorf("invalid filter: %w", err)
+	}
+	switch {
+	case typed == nil:
+		return where, nil
+	case where == nil:
+		return typed, nil
+	}
+	return filters.Where().WithOperator(filters.And).WithOperands([]*filters.WhereBuilder{where, typed}), nil
+}
+
+// defaultGetByFilterLimit is the number of documents GetByFilter returns when
+// no limit is given
+const defaultGetByFilterLimit = 100
+
+// requiredFilter translates a filter that must match only some documents, so
+// that an empty filter cannot select every document by accident
+func requiredFilter(f interfaces.Filter) (*filters.WhereBuilder, error) {
+	if err := f.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid filter: %w", err)
+	}
+	where, err := whereFromFilter(f)
+	if err != nil {
+		return nil, fmt.Errorf("invalid filter: %w", err)
+	}
+	if where == nil {
+		return nil, fmt.Errorf("invalid filter: the filter is empty")
+	}
+	return where, nil
+}
+
+// GetByFilter returns up to limit documents whose metadata matches the
+// filter, e.g. all documents of a source. A limit of zero returns up to 100
+// documents.
+func (s *Store) GetByFilter(ctx context.Context, filter interfaces.Filter, limit int, options ...interfaces.SearchOption) ([]interfaces.Document, error) {
+	opts := &interfaces.SearchOptions{}
+	for _, option := range options {
+		option(opts)
+	}
+	if limit <= 0 {
+		limit = defaultGetByFilterLimit
+	}
+
+	className, tenant, err := s.getClassName(ctx, opts.Class)
+	if err != nil {
+		return nil, err
+	}
+	where, err := requiredFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	// Find the IDs of the matching documents, then get them with all their
+	// metadata
+	get := s.client.GraphQL().Get().
+		WithClassName(className).
+		WithFields(graphql.Field{Name: "_additional { id }"}).
+		WithWhere(s.scopeToUser(ctx, where)).
+		WithLimit(limit).
+		WithTenant(tenant)
+	var result *models.GraphQLResponse
+	err = s.withRetry(ctx, func() error {
+		var err error
+		result, err = get.Do(ctx)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get documents by filter: %w", err)
+	}
+	if len(result.Errors) > 0 {
+		return nil, fmt.Errorf("failed to get documents by filter: %s", result.Errors[0].Message)
+	}
+
+	var ids []string
+	getMap, _ := result.Data["Get"].(map[string]interface{})
+	objects, _ := getMap[className].([]interface{})
+	for _, object := range objects {
+		fields, _ := object.(map[string]interface{})
+		additional, _ := fields["_additional"].(map[string]interface{})
+		if id, ok := additional["id"].(string); ok {
+			ids = append(ids, id)
+		}
+	}
+	return s.getObjects(ctx, className, tenant, ids)
+}
+
+// DeleteByFilter deletes all documents whose metadata matches the filter,
+// e.g. those of a source or older than a date, and returns how many were
+// deleted. With WithUserScope and a user in the context, only that user's
+// documents are deleted.
+func (s *Store) DeleteByFilter(ctx context.Context, filter interfaces.Filter, options ...interfaces.DeleteOption) (int, error) {
+	opts := &interfaces.DeleteOptions{}
+	for _, option := range options {
+		option(opts)
+	}
+
+	className, tenant, err := s.getClassName(ctx, opts.Class)
+	if err != nil {
+		return 0, err
+	}
+	where, err := requiredFilter(filter)
+	if err != nil {
+		return 0, err
+	}
+	where = s.scopeToUser(ctx, where)
+
+	// Weaviate deletes at most QUERY_MAXIMUM_RESULTS objects per request, so
+	// repeat until no more objects match
+	deleted := 0
+	for {
+		var response *models.BatchDeleteResponse
+		err := s.withRetry(ctx, func() error {
+			var err error
+			response, err = s.client.Batch().ObjectsBatchDeleter().
+				WithClassName(className).
+				WithWhere(where).
+				WithTenant(tenant).
+				WithOutput("minimal").
+				Do(ctx)
+			return err
+		})
+		if err != nil {
+			return deleted, fmt.Errorf("failed to delete documents by filter: %w", err)
+		}
+		if response == nil || response.Results == nil {
+			return deleted, nil
+		}
+
+		results := response.Results
+		deleted += int(results.Successful)
+		if results.Failed > 0 {
+			return deleted, fmt.Errorf("failed to delete %d of %d matching documents", results.Failed, results.Matches)
+		}
+		if results.Successful == 0 || results.Limit <= 0 || results.Matches < results.Limit {
+			return deleted, nil
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package weaviate_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+	"github.com/run-bigpig/llm-agent/pkg/multitenancy"
+	"github.com/run-bigpig/llm-agent/pkg/runctx"
+	weaviatestore "github.com/run-bigpig/llm-agent/pkg/vectorstore/weaviate"
+)
+
+func TestSearchWithFilter(t *testing.T) {
+	var query string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/v1/meta":
+			fmt.Fprint(w, `{"version":"1.25.0"}`)
+		case "/v1/graphql":
+			var body struct {
+				Query string `json:"query"`
+			}
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			query = body.Query
+			fmt.Fprint(w, `{"data":{"Get":{"Document_org1":[]}}}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	store := newTestStore(t, server)
+	ctx := multitenancy.WithOrgID(context.Background(), "org1")
+
+	filter := interfaces.And(
+		interfaces.Eq("type", "article"),
+		interfaces.Gte("pages", 10),
+		interfaces.In("lang", "en", "de"),
+	)
+	if _, err := store.SearchByVector(ctx, []float32{0.1}, 5, interfaces.WithFilter(filter)); err != nil {
+		t.Fatalf("failed to search: %v", err)
+	}
+
+	for _, want := range []string{
+		`operator: And`,
+		`{operator: Equal path: ["type"] valueString: "article"}`,
+		`{operator: GreaterThanEqual path: ["pages"] valueNumber: 10}`,
+		`{operator: ContainsAny path: ["lang"] valueString: ["en","de"]}`,
+	} {
+		if !strings.Contains(query, want) {
+			t.Errorf("expected the query to contain %s, got %s", want, query)
+		}
+	}
+
+	if _, err := store.SearchByVector(ctx, []float32{0.1}, 5, interfaces.WithFilter(interfaces.Gt("draft", true))); err == nil {
+		t.Error("expected an invalid filter to be rejected")
+	}
+}
+
+func TestGetByFilter(t *testing.T) {
+	var query string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.URL.Path == "/v1/meta":
+			fmt.Fprint(w, `{"version":"1.25.0"}`)
+		case r.URL.Path == "/v1/graphql":
+			var body struct {
+				Query string `json:"query"`
+			}
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			query = body.Query
+			fmt.Fprint(w, `{"data":{"Get":{"Document_org1":[{"_additional":{"id":"00000000-0000-0000-0000-000000000001"}}]}}}`)
+		case strings.HasPrefix(r.URL.Path, "/v1/objects/"):
+			fmt.Fprint(w, `{"class":"Document_org1","id":"00000000-0000-0000-0000-000000000001","properties":{"content":"hello","source":"wiki"}}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	store := newTestStore(t, server)
+	ctx := multitenancy.WithOrgID(context.Background(), "org1")
+
+	docs, err := store.GetByFilter(ctx, interfaces.Eq("source", "wiki"), 0)
+	if err != nil {
+		t.Fatalf("failed to get documents: %v", err)
+	}
+	if len(docs) != 1 || docs[0].Content != "hello" || docs[0].Metadata["source"] != "wiki" {
+		t.Fatalf("unexpected documents: %+v", docs)
+	}
+	for _, want := range []string{`{operator: Equal path: ["source"] valueString: "wiki"}`, `limit: 100`} {
+		if !strings.Contains(query, want) {
+			t.Errorf("expected the query to contain %s, got %s", want, query)
+		}
+	}
+
+	if _, err := store.GetByFilter(ctx, interfaces.And(), 10); err == nil {
+		t.Error("expected an empty filter to be rejected")
+	}
+}
+
+func TestDeleteByFilter(t *testing.T) {
+	var requests atomic.Int32
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.URL.Path == "/v1/meta":
+			fmt.Fprint(w, `{"version":"1.25.0"}`)
+		case r.URL.Path == "/v1/batch/objects" && r.Method == http.MethodDelete:
+			var body map[string]interface{}
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			encoded, _ := json.Marshal(body["match"])
+			bodies = append(bodies, string(encoded))
+			// The first round hits the limit, the second deletes the rest
+			if requests.Add(1) == 1 {
+				fmt.Fprint(w, `{"results":{"matches":2,"limit":2,"successful":2,"failed":0}}`)
+			} else {
+				fmt.Fprint(w, `{"results":{"matches":1,"limit":2,"successful":1,"failed":0}}`)
+			}
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	var store interfaces.VectorStoreWithFilters = newTestStore(t, server, weaviatestore.WithUserScope())
+	ctx := runctx.WithUserID(multitenancy.WithOrgID(context.Background(), "org1"), "alice")
+
+	deleted, err := store.DeleteByFilter(ctx, interfaces.Lt("created", "2024-01-01"))
+	if err != nil {
+		t.Fatalf("failed to delete documents: %v", err)
+	}
+	if deleted != 3 || requests.Load() != 2 {
+		t.Fatalf("expected 3 documents deleted in 2 rounds, got %d in %d", deleted, requests.Load())
+	}
+	for _, want := range []string{`"class":"Document_org1"`, `"path":["created"]`, `"path":["user_id"]`, `"valueString":"alice"`} {
+		if !strings.Contains(bodies[0], want) {
+			t.Errorf("expected the delete request to contain %s, got %s", want, bodies[0])
+		}
+	}
+
+	if _, err := store.DeleteByFilter(ctx, interfaces.Or()); err == nil {
+		t.Error("expected an empty filter to be rejected")
+	}
+}
@@ -0,0 +1,142 @@
+package weaviate
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/weaviate/weaviate/entities/models"
+)
+
+// MigrationOptions configures MigrateToNativeTenancy
+type MigrationOptions struct {
+	// Class is the class prefix to migrate. Defaults to the store's class
+	// prefix.
+	Class string
+
+	// OrgIDs are the organizations to migrate. Defaults to every class named
+	// <Class>_<orgID> in the schema.
+	OrgIDs []string
+
+	// PageSize is the number of objects copied per batch. Defaults to 100.
+	PageSize int
+
+	// DeleteSource deletes an organization's class once all its objects have
+	// been copied
+	DeleteSource bool
+}
+
+// MigrateToNativeTenancy copies documents from the class-per-organization
+// layout (<Class>_<orgID>) into the multi-tenant class <Class>, with one
+// tenant per organization. Vectors are copied, so nothing is re-embedded.
+// Object IDs are kept, so a migration that failed halfway can be run again.
+// It returns the number of objects copied per organization.
+func (s *Store) MigrateToNativeTenancy(ctx context.Context, opts MigrationOptions) (map[string]int, error) {
+	if !s.nativeTenancy {
+		return nil, fmt.Errorf("native multi-tenancy is not enabled, use WithNativeMultiTenancy")
+	}
+	if opts.Class == "" {
+		opts.Class = s.classPrefix
+	}
+	if opts.PageSize <= 0 {
+		opts.PageSize = 100
+	}
+
+	orgIDs := opts.OrgIDs
+	if len(orgIDs) == 0 {
+		var err error
+		if orgIDs, err = s.orgClasses(ctx, opts.Class); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.ensureClass(ctx, opts.Class); err != nil {
+		return nil, fmt.Errorf("failed to ensure class exists: %w", err)
+	}
+
+	copied := make(map[string]int, len(orgIDs))
+	for _, orgID := range orgIDs {
+		n, err := s.migrateOrg(ctx, opts.Class, orgID, opts.PageSize)
+		copied[orgID] = n
+		if err != nil {
+			return copied, fmt.Errorf("failed to migrate organization %s: %w", orgID, err)
+		}
+
+		if opts.DeleteSource {
+			if err := s.client.Schema().ClassDeleter().WithClassName(orgClassName(opts.Class, orgID)).Do(ctx); err != nil {
+				return copied, fmt.Errorf("failed to delete class of organization %s: %w", orgID, err)
+			}
+		}
+
+		s.logger.Info(ctx, "Migrated organization to native tenancy", map[string]interface{}{
+			"className": opts.Class,
+			"tenant":    orgID,
+			"objects":   n,
+		})
+	}
+
+	return copied, nil
+}
+
+// orgClasses returns the organization IDs of the classes named <class>_<orgID>
+func (s *Store) orgClasses(ctx context.Context, class string) ([]string, error) {
+	schema, err := s.client.Schema().Getter().Do(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get schema: %w", err)
+	}
+
+	prefix := class + "_"
+	var orgIDs []string
+	for _, c := range schema.Classes {
+		if c.MultiTenancyConfig != nil && c.MultiTenancyConfig.Enabled {
+			continue
+		}
+		if orgID, ok := strings.CutPrefix(c.Class, prefix); ok && orgID != "" {
+			orgIDs = append(orgIDs, orgID)
+		}
+	}
+	return orgIDs, nil
+}
+
+// migrateOrg copies all objects of an organization's class into its tenant,
+// one page at a time
+func (s *Store) migrateOrg(ctx context.Context, class, orgID string, pageSize int) (int, error) {
+	if err := s.ensureTenant(ctx, class, orgID); err != nil {
+		return 0, err
+	}
+
+	source := orgClassName(class, orgID)
+	copied := 0
+	after := ""
+	for {
+		page, err := s.client.Data().ObjectsGetter().
+			WithClassName(source).
+			WithVector().
+			WithLimit(pageSize).
+			WithAfter(after).
+			Do(ctx)
+		if err != nil {
+			return copied, fmt.Errorf("failed to read %s: %w", source, err)
+		}
+		if len(page) == 0 {
+			return copied, nil
+		}
+
+		objects := make([]*models.Object, len(page))
+		for i, obj := range page {
+			objects[i] = &models.Object{
+				Class:      class,
+				Tenant:     orgID,
+				ID:         obj.ID,
+				Properties: obj.Properties,
+				Vector:     obj.Vector,
+			}
+		}
+		if err := s.storeObjects(ctx, objects); err != nil {
+			return copied, err
+		}
+
+		copied += len(page)
+		after = string(page[len(page)-1].ID)
+	}
+}
@@ -0,0 +1,131 @@
+package weaviate_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+	weaviatestore "github.com/run-bigpig/llm-agent/pkg/vectorstore/weaviate"
+)
+
+func TestMigrateToNativeTenancy(t *testing.T) {
+	source := map[string][]string{
+		"Document_org1": {"00000000-0000-0000-0000-000000000001", "00000000-0000-0000-0000-000000000002", "00000000-0000-0000-0000-000000000003"},
+		"Document_org2": {"00000000-0000-0000-0000-000000000004"},
+	}
+
+	var mu sync.Mutex
+	var multiTenantClass bool
+	tenants := map[string]bool{}
+	copied := map[string][]string{} // tenant -> object IDs
+	var deleted []string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.URL.Path == "/v1/meta":
+			fmt.Fprint(w, `{"version":"1.25.0"}`)
+		case r.URL.Path == "/v1/schema" && r.Method == http.MethodGet:
+			fmt.Fprint(w, `{"classes":[{"class":"Document_org1"},{"class":"Document_org2"},{"class":"Other_org3"}]}`)
+		case r.URL.Path == "/v1/schema" && r.Method == http.MethodPost:
+			var class struct {
+				MultiTenancyConfig struct {
+					Enabled bool `json:"enabled"`
+				} `json:"multiTenancyConfig"`
+			}
+			_ = json.NewDecoder(r.Body).Decode(&class)
+			multiTenantClass = class.MultiTenancyConfig.Enabled
+			fmt.Fprint(w, `{}`)
+		case strings.HasPrefix(r.URL.Path, "/v1/schema/Document/tenants/") && r.Method == http.MethodHead:
+			if !tenants[strings.TrimPrefix(r.URL.Path, "/v1/schema/Document/tenants/")] {
+				w.WriteHeader(http.StatusNotFound)
+			}
+		case r.URL.Path == "/v1/schema/Document/tenants" && r.Method == http.MethodPost:
+			var created []struct{ Name string }
+			_ = json.NewDecoder(r.Body).Decode(&created)
+			for _, tenant := range created {
+				tenants[tenant.Name] = true
+			}
+			fmt.Fprint(w, `[]`)
+		case strings.HasPrefix(r.URL.Path, "/v1/schema/") && r.Method == http.MethodDelete:
+			deleted = append(deleted, strings.TrimPrefix(r.URL.Path, "/v1/schema/"))
+		case r.URL.Path == "/v1/objects":
+			// Cursor API: objects after the given ID, up to the limit
+			var page []map[string]interface{}
+			after := r.URL.Query().Get("after")
+			for _, id := range source[r.URL.Query().Get("class")] {
+				if id > after && len(page) < 2 {
+					page = append(page, map[string]interface{}{"id": id, "properties": map[string]interface{}{"content": "doc " + id}, "vector": []float32{0.1}})
+				}
+			}
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"objects": page})
+		case r.URL.Path == "/v1/batch/objects":
+			var body struct {
+				Objects []struct {
+					Class  string `json:"class"`
+					Tenant string `json:"tenant"`
+					ID     string `json:"id"`
+				} `json:"objects"`
+			}
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			for _, obj := range body.Objects {
+				if obj.Class != "Document" {
+					t.Errorf("expected objects to be copied into Document, got %s", obj.Class)
+				}
+				copied[obj.Tenant] = append(copied[obj.Tenant], obj.ID)
+			}
+			fmt.Fprint(w, `[]`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	store := weaviatestore.New(&interfaces.VectorStoreConfig{
+		Host:   strings.TrimPrefix(server.URL, "http://"),
+		Scheme: "http",
+	}, weaviatestore.WithNativeMultiTenancy())
+
+	counts, err := store.MigrateToNativeTenancy(context.Background(), weaviatestore.MigrationOptions{
+		PageSize:     2,
+		DeleteSource: true,
+	})
+	if err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+
+	if counts["org1"] != 3 || counts["org2"] != 1 || len(counts) != 2 {
+		t.Errorf("expected 3 objects for org1 and 1 for org2, got %v", counts)
+	}
+	if !multiTenantClass {
+		t.Error("expected the target class to be created with multi-tenancy enabled")
+	}
+	if !tenants["org1"] || !tenants["org2"] {
+		t.Errorf("expected tenants for both organizations, got %v", tenants)
+	}
+	if strings.Join(copied["org1"], ",") != strings.Join(source["Document_org1"], ",") || len(copied["org2"]) != 1 {
+		t.Errorf("unexpected copied objects: %v", copied)
+	}
+	sort.Strings(deleted)
+	if strings.Join(deleted, ",") != "Document_org1,Document_org2" {
+		t.Errorf("expected both source classes to be deleted, got %v", deleted)
+	}
+}
+
+func TestMigrateRequiresNativeTenancy(t *testing.T) {
+	server, _, _ := fakeWeaviate(t, func(int32) int { return http.StatusOK })
+	store := newTestStore(t, server)
+
+	if _, err := store.MigrateToNativeTenancy(context.Background(), weaviatestore.MigrationOptions{}); err == nil {
+		t.Error("expected an error without native multi-tenancy")
+	}
+}
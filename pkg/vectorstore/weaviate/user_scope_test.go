@@ -0,0 +1,164 @@
+package weaviate_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+	"github.com/run-bigpig/llm-agent/pkg/multitenancy"
+	"github.com/run-bigpig/llm-agent/pkg/runctx"
+	weaviatestore "github.com/run-bigpig/llm-agent/pkg/vectorstore/weaviate"
+)
+
+const (
+	aliceDocID = "00000000-0000-0000-0000-000000000001"
+	bobDocID   = "00000000-0000-0000-0000-000000000002"
+)
+
+// scopedWeaviate answers the REST endpoints used by Store, with one document
+// owned by alice and one by bob, and records the requests it gets
+type scopedWeaviate struct {
+	mu         sync.Mutex
+	queries    []string
+	storedUser []interface{}
+	deleted    []string
+}
+
+func (f *scopedWeaviate) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	owners := map[string]string{aliceDocID: "alice", bobDocID: "bob"}
+	switch {
+	case r.URL.Path == "/v1/meta":
+		fmt.Fprint(w, `{"version":"1.25.0"}`)
+	case r.URL.Path == "/v1/schema" && r.Method == http.MethodGet:
+		fmt.Fprint(w, `{"classes":[]}`)
+	case r.URL.Path == "/v1/schema" && r.Method == http.MethodPost:
+		fmt.Fprint(w, `{}`)
+	case r.URL.Path == "/v1/batch/objects":
+		var body struct {
+			Objects []struct {
+				Properties map[string]interface{} `json:"properties"`
+			} `json:"objects"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		for _, object := range body.Objects {
+			f.storedUser = append(f.storedUser, object.Properties[weaviatestore.UserIDProperty])
+		}
+		fmt.Fprint(w, `[]`)
+	case r.URL.Path == "/v1/graphql":
+		var body struct {
+			Query string `json:"query"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		f.queries = append(f.queries, body.Query)
+		fmt.Fprint(w, `{"data":{"Get":{"Document_org1":[]}}}`)
+	case strings.HasPrefix(r.URL.Path, "/v1/objects/"):
+		id := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+		if r.Method == http.MethodDelete {
+			f.deleted = append(f.deleted, id)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		fmt.Fprintf(w, `{"class":"Document_org1","id":%q,"properties":{"content":"notes of %s","user_id":%q}}`, id, owners[id], owners[id])
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func TestUserScoping(t *testing.T) {
+	fake := &scopedWeaviate{}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	store := newTestStore(t, server, weaviatestore.WithUserScope())
+	orgCtx := multitenancy.WithOrgID(context.Background(), "org1")
+	alice := runctx.WithUserID(orgCtx, "alice")
+
+	// Documents are stored with the user in the context
+	if err := store.Store(alice, []interfaces.Document{{ID: aliceDocID, Content: "notes of alice"}}); err != nil {
+		t.Fatalf("failed to store: %v", err)
+	}
+	if len(fake.storedUser) != 1 || fake.storedUser[0] != "alice" {
+		t.Errorf("expected the document to be stored for alice, got %v", fake.storedUser)
+	}
+
+	// Searches only see the user's documents, in addition to other filters
+	userFilter := `{operator: Equal path: ["user_id"] valueString: "alice"}`
+	if _, err := store.SearchByVector(alice, []float32{0.1}, 5); err != nil {
+		t.Fatalf("failed to search by vector: %v", err)
+	}
+	if _, err := store.Search(alice, "notes", 5, interfaces.WithFilter(interfaces.Eq("source", "wiki"))); err != nil {
+		t.Fatalf("failed to search: %v", err)
+	}
+	if _, err := store.SearchByVector(orgCtx, []float32{0.1}, 5); err != nil {
+		t.Fatalf("failed to search without a user: %v", err)
+	}
+	if len(fake.queries) != 3 {
+		t.Fatalf("expected 3 queries, got %d", len(fake.queries))
+	}
+	if !strings.Contains(fake.queries[0], userFilter) {
+		t.Errorf("expected the vector search to be scoped to alice, got %s", fake.queries[0])
+	}
+	if !strings.Contains(fake.queries[1], userFilter) || !strings.Contains(fake.queries[1], "operator: And") {
+		t.Errorf("expected the search to combine its filter with the user, got %s", fake.queries[1])
+	}
+	if strings.Contains(fake.queries[2], "user_id") {
+		t.Errorf("expected searches without a user not to be scoped, got %s", fake.queries[2])
+	}
+
+	// Gets and deletes skip other users' documents
+	docs, err := store.Get(alice, []string{aliceDocID, bobDocID})
+	if err != nil {
+		t.Fatalf("failed to get: %v", err)
+	}
+	if len(docs) != 1 || docs[0].ID != aliceDocID {
+		t.Errorf("expected only alice's document, got %+v", docs)
+	}
+	if err := store.Delete(alice, []string{aliceDocID, bobDocID}); err != nil {
+		t.Fatalf("failed to delete: %v", err)
+	}
+	if len(fake.deleted) != 1 || fake.deleted[0] != aliceDocID {
+		t.Errorf("expected only alice's document to be deleted, got %v", fake.deleted)
+	}
+}
+
+func TestUserScopingIsOptIn(t *testing.T) {
+	fake := &scopedWeaviate{}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	store := newTestStore(t, server)
+	alice := runctx.WithUserID(multitenancy.WithOrgID(context.Background(), "org1"), "alice")
+
+	// Documents still record the user who stored them
+	if err := store.Store(alice, []interfaces.Document{{ID: aliceDocID, Content: "notes of alice"}}); err != nil {
+		t.Fatalf("failed to store: %v", err)
+	}
+	if len(fake.storedUser) != 1 || fake.storedUser[0] != "alice" {
+		t.Errorf("expected the document to be stored for alice, got %v", fake.storedUser)
+	}
+
+	// Searches and gets see every document of the organization
+	if _, err := store.SearchByVector(alice, []float32{0.1}, 5); err != nil {
+		t.Fatalf("failed to search by vector: %v", err)
+	}
+	if len(fake.queries) != 1 || strings.Contains(fake.queries[0], "user_id") {
+		t.Errorf("expected the search not to be scoped to the user, got %v", fake.queries)
+	}
+	docs, err := store.Get(alice, []string{aliceDocID, bobDocID})
+	if err != nil {
+		t.Fatalf("failed to get: %v", err)
+	}
+	if len(docs) != 2 {
+		t.Errorf("expected both documents, got %+v", docs)
+	}
+}
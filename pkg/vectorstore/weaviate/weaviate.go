@@ -2,19 +2,30 @@ package weaviate
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/weaviate/weaviate-go-client/v5/weaviate"
+	"github.com/weaviate/weaviate-go-client/v5/weaviate/fault"
 	"github.com/weaviate/weaviate-go-client/v5/weaviate/filters"
 	"github.com/weaviate/weaviate-go-client/v5/weaviate/graphql"
+	weaviategrpc "github.com/weaviate/weaviate-go-client/v5/weaviate/grpc"
 	"github.com/weaviate/weaviate/entities/models"
+	"golang.org/x/sync/errgroup"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	"github.com/go-openapi/strfmt"
 	"github.com/run-bigpig/llm-agent/pkg/embedding"
 	"github.com/run-bigpig/llm-agent/pkg/interfaces"
 	"github.com/run-bigpig/llm-agent/pkg/logging"
 	"github.com/run-bigpig/llm-agent/pkg/multitenancy"
+	"github.com/run-bigpig/llm-agent/pkg/retry"
+	"github.com/run-bigpig/llm-agent/pkg/runctx"
 )
 
 // Store implements the VectorStore interface for Weaviate
@@ -24,6 +35,12 @@ type Store struct {
 	embedder       embedding.Client
 	distanceMetric string
 	logger         logging.Logger
+	grpcHost       string
+	grpcSecured    bool
+	retryExecutor  *retry.Executor
+	parallelism    int
+	nativeTenancy  bool
+	userScope      bool
 }
 
 // Option represents an option for configuring the Weaviate store
@@ -57,6 +74,52 @@ func WithLogger(logger logging.Logger) Option {
 	}
 }
 
+// WithGRPC stores documents with gRPC batch requests instead of REST. host is
+// Weaviate's gRPC address, e.g. "localhost:50051"; secured enables TLS.
+func WithGRPC(host string, secured bool) Option {
+	return func(s *Store) {
+		s.grpcHost = host
+		s.grpcSecured = secured
+	}
+}
+
+// WithRetry sets the retry policy for batches and searches. Requests are
+// retried when Weaviate is overloaded or unavailable (HTTP 429/502/503/504,
+// gRPC RESOURCE_EXHAUSTED/UNAVAILABLE). Pass retry.WithMaxAttempts(1) to
+// disable retries.
+func WithRetry(opts ...retry.Option) Option {
+	return func(s *Store) {
+		s.retryExecutor = retry.NewExecutor(retry.NewPolicy(opts...))
+	}
+}
+
+// WithParallelism sets how many batches Store sends at the same time
+func WithParallelism(n int) Option {
+	return func(s *Store) {
+		if n > 0 {
+			s.parallelism = n
+		}
+	}
+}
+
+// WithNativeMultiTenancy stores all organizations in one multi-tenant class
+// per class prefix, with one Weaviate tenant per organization, instead of one
+// class per organization. Use MigrateToNativeTenancy to move existing data.
+func WithNativeMultiTenancy() Option {
+	return func(s *Store) {
+		s.nativeTenancy = true
+	}
+}
+
+// WithUserScope restricts searches, gets and deletes to the documents of the
+// user in the context. Documents stored without a user, such as those shared
+// with the whole organization, are then hidden from users.
+func WithUserScope() Option {
+	return func(s *Store) {
+		s.userScope = true
+	}
+}
+
 // New creates a new Weaviate store
 func New(config *interfaces.VectorStoreConfig, options ...Option) *Store {
 	// Create store with default options
@@ -64,6 +127,12 @@ func New(config *interfaces.VectorStoreConfig, options ...Option) *Store {
 		classPrefix:    "Document",
 		distanceMetric: "cosine",
 		logger:         logging.New(),
+		retryExecutor: retry.NewExecutor(retry.NewPolicy(
+			retry.WithInitialInterval(500*time.Millisecond),
+			retry.WithMaximumInterval(30*time.Second),
+			retry.WithMaxAttempts(5),
+		)),
+		parallelism: 1,
 	}
 
 	// Apply options
@@ -83,6 +152,12 @@ func New(config *interfaces.VectorStoreConfig, options ...Option) *Store {
 			"Authorization": "Bearer " + config.APIKey,
 		}
 	}
+	if store.grpcHost != "" {
+		cfg.GrpcConfig = &weaviategrpc.Config{
+			Host:    store.grpcHost,
+			Secured: store.grpcSecured,
+		}
+	}
 
 	client, err := weaviate.NewClient(cfg)
 	if err != nil {
@@ -95,12 +170,18 @@ func New(config *interfaces.VectorStoreConfig, options ...Option) *Store {
 	return store
 }
 
-// getClassName returns the class name for the current organization
-func (s *Store) getClassName(ctx context.Context, class string) (string, error) {
+// UserIDProperty is the property that records which user stored a document.
+// When the context has a user ID, documents are stored with it. With
+// WithUserScope, searches, gets and deletes only see that user's documents.
+const UserIDProperty = "user_id"
+
+// getClassName returns the class name and tenant for the current
+// organization. The tenant is empty unless native multi-tenancy is enabled.
+func (s *Store) getClassName(ctx context.Context, class string) (string, string, error) {
 	// Get organization ID from context
 	orgID, err := multitenancy.GetOrgID(ctx)
 	if err != nil {
-		return "", fmt.Errorf("failed to get organization ID: %w", err)
+		return "", "", fmt.Errorf("failed to get organization ID: %w", err)
 	}
 
 	// If class is provided, use it; otherwise use default
@@ -108,8 +189,18 @@ func (s *Store) getClassName(ctx context.Context, class string) (string, error)
 		class = s.classPrefix
 	}
 
+	if s.nativeTenancy {
+		return class, orgID, nil
+	}
+
 	// Create class name with organization ID
-	return fmt.Sprintf("%s_%s", class, orgID), nil
+	return orgClassName(class, orgID), "", nil
+}
+
+// orgClassName returns the name of an organization's class in the
+// class-per-organization layout
+func orgClassName(class, orgID string) string {
+	return fmt.Sprintf("%s_%s", class, orgID)
 }
 
 // Store stores documents in Weaviate
@@ -123,7 +214,7 @@ func (s *Store) Store(ctx context.Context, documents []interfaces.Document, opti
 	}
 
 	// Get class name
-	className, err := s.getClassName(ctx, opts.Class)
+	className, tenant, err := s.getClassName(ctx, opts.Class)
 	if err != nil {
 		return err
 	}
@@ -132,17 +223,44 @@ func (s *Store) Store(ctx context.Context, documents []interfaces.Document, opti
 	if err := s.ensureClass(ctx, className); err != nil {
 		return fmt.Errorf("failed to ensure class exists: %w", err)
 	}
+	if err := s.ensureTenant(ctx, className, tenant); err != nil {
+		return fmt.Errorf("failed to ensure tenant exists: %w", err)
+	}
 
-	// Store documents in batches
-	batch := s.client.Batch().ObjectsBatcher()
 	batchSize := opts.BatchSize
-	batchCount := 0
+	if batchSize <= 0 {
+		batchSize = 100
+	}
+
+	// Store documents in batches, sending up to parallelism batches at once
+	group, groupCtx := errgroup.WithContext(ctx)
+	group.SetLimit(s.parallelism)
+	for start := 0; start < len(documents); start += batchSize {
+		end := start + batchSize
+		if end > len(documents) {
+			end = len(documents)
+		}
+		batch := documents[start:end]
+		group.Go(func() error {
+			return s.storeBatch(groupCtx, className, tenant, batch)
+		})
+	}
+
+	return group.Wait()
+}
 
+// storeBatch embeds and stores one batch of documents
+func (s *Store) storeBatch(ctx context.Context, className, tenant string, documents []interfaces.Document) error {
+	embedCtx := embedding.WithInputType(ctx, embedding.InputTypeDocument)
+	objects := make([]*models.Object, 0, len(documents))
 	for _, doc := range documents {
-		// Generate embedding for the document content
-		vector, err := s.embedder.Embed(ctx, doc.Content)
-		if err != nil {
-			return fmt.Errorf("failed to generate embedding: %w", err)
+		// Generate embedding for the document content, unless it has one
+		vector := doc.Vector
+		if len(vector) == 0 {
+			var err error
+			if vector, err = s.embedder.Embed(embedCtx, doc.Content); err != nil {
+				return fmt.Errorf("failed to generate embedding: %w", err)
+			}
 		}
 
 		properties := map[string]interface{}{
@@ -151,37 +269,86 @@ func (s *Store) Store(ctx context.Context, documents []interfaces.Document, opti
 		for k, v := range doc.Metadata {
 			properties[k] = v
 		}
+		if userID := runctx.UserID(ctx); userID != "" {
+			properties[UserIDProperty] = userID
+		}
 
-		obj := &models.Object{
+		objects = append(objects, &models.Object{
 			Class:      className,
+			Tenant:     tenant,
 			ID:         strfmt.UUID(doc.ID),
 			Properties: properties,
 			Vector:     vector, // Use the generated vector
-		}
-		batch.WithObjects(obj)
-		batchCount++
+		})
+	}
 
-		// Execute batch when it reaches the batch size
-		if batchCount >= batchSize {
-			if _, err := batch.Do(ctx); err != nil {
-				return fmt.Errorf("failed to store batch: %w", err)
-			}
-			// Reset batch and count
-			batch = s.client.Batch().ObjectsBatcher()
-			batchCount = 0
-		}
+	return s.storeObjects(ctx, objects)
+}
+
+// storeObjects sends one batch of objects, retrying the request on transient
+// errors
+func (s *Store) storeObjects(ctx context.Context, objects []*models.Object) error {
+	var responses []models.ObjectsGetResponse
+	err := s.withRetry(ctx, func() error {
+		var err error
+		responses, err = s.client.Batch().ObjectsBatcher().WithObjects(objects...).Do(ctx)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to store batch: %w", err)
 	}
 
-	// Final batch
-	if batchCount > 0 {
-		if _, err := batch.Do(ctx); err != nil {
-			return fmt.Errorf("failed to store final batch: %w", err)
+	// A successful batch request can still reject individual objects
+	var failed []string
+	for _, response := range responses {
+		if response.Result == nil || response.Result.Errors == nil {
+			continue
+		}
+		for _, item := range response.Result.Errors.Error {
+			failed = append(failed, fmt.Sprintf("%s: %s", response.ID, item.Message))
 		}
 	}
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to store %d documents: %s", len(failed), strings.Join(failed, "; "))
+	}
 
 	return nil
 }
 
+// withRetry runs op with the store's retry policy. Only transient errors are
+// retried.
+func (s *Store) withRetry(ctx context.Context, op func() error) error {
+	return s.retryExecutor.Execute(ctx, func() error {
+		err := op()
+		if err != nil && !isRetryable(err) {
+			return retry.Permanent(err)
+		}
+		return err
+	})
+}
+
+// isRetryable reports whether err means Weaviate is overloaded or temporarily
+// unavailable
+func isRetryable(err error) bool {
+	var clientErr *fault.WeaviateClientError
+	if errors.As(err, &clientErr) {
+		switch clientErr.StatusCode {
+		case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		}
+		if clientErr.DerivedFromError != nil {
+			return isRetryable(clientErr.DerivedFromError)
+		}
+		return false
+	}
+
+	switch status.Code(err) {
+	case codes.ResourceExhausted, codes.Unavailable:
+		return true
+	}
+	return false
+}
+
 // Search searches for similar documents
 func (s *Store) Search(ctx context.Context, query string, limit int, options ...interfaces.SearchOption) ([]interfaces.SearchResult, error) {
 	// Apply options
@@ -193,23 +360,27 @@ func (s *Store) Search(ctx context.Context, query string, limit int, options ...
 	}
 
 	// Get class name
-	className, err := s.getClassName(ctx, opts.Class)
+	className, tenant, err := s.getClassName(ctx, opts.Class)
 	if err != nil {
 		return nil, err
 	}
 
 	// Generate embedding for the query
-	vector, err := s.embedder.Embed(ctx, query)
+	vector, err := s.embedder.Embed(embedding.WithInputType(ctx, embedding.InputTypeQuery), query)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate embedding for query: %w", err)
 	}
 
 	// Build query
-	whereFilter := s.buildWhereFilter(opts.Filters)
+	where, err := s.searchFilter(opts)
+	if err != nil {
+		return nil, err
+	}
+	whereFilter := s.scopeToUser(ctx, where)
 
 	// Debug log for filter
-	if len(opts.Filters) > 0 {
-		s.logger.Info(ctx, "Applying filters", map[string]interface{}{"filters": opts.Filters})
+	if len(opts.Filters) > 0 || opts.Filter != nil {
+		s.logger.Info(ctx, "Applying filters", map[string]interface{}{"filters": opts.Filters, "filter": opts.Filter})
 		if whereFilter != nil {
 			s.logger.Info(ctx, "Built where filter", map[string]interface{}{"filter": whereFilter})
 		} else {
@@ -225,7 +396,7 @@ func (s *Store) Search(ctx context.Context, query string, limit int, options ...
 	})
 
 	// Try a simpler query first
-	result, err := s.client.GraphQL().Get().
+	get := s.client.GraphQL().Get().
 		WithClassName(className).
 		WithFields(graphql.Field{
 			Name: "content _additional { certainty id }",
@@ -233,8 +404,16 @@ func (s *Store) Search(ctx context.Context, query string, limit int, options ...
 		WithNearVector(s.client.GraphQL().NearVectorArgBuilder().
 			WithVector(vector)).
 		WithLimit(limit).
-		Do(ctx)
-
+		WithTenant(tenant)
+	if whereFilter != nil {
+		get = get.WithWhere(whereFilter)
+	}
+	var result *models.GraphQLResponse
+	err = s.withRetry(ctx, func() error {
+		var err error
+		result, err = get.Do(ctx)
+		return err
+	})
 	if err != nil {
 		s.logger.Error(ctx, "GraphQL query failed", map[string]interface{}{
 			"error": err.Error(),
@@ -276,25 +455,37 @@ func (s *Store) SearchByVector(ctx context.Context, vector []float32, limit int,
 	}
 
 	// Get class name
-	className, err := s.getClassName(ctx, opts.Class)
+	className, tenant, err := s.getClassName(ctx, opts.Class)
 	if err != nil {
 		return nil, err
 	}
 
 	// Build query
-	whereFilter := s.buildWhereFilter(opts.Filters)
+	where, err := s.searchFilter(opts)
+	if err != nil {
+		return nil, err
+	}
+	whereFilter := s.scopeToUser(ctx, where)
 
 	// Use vector search
-	result, err := s.client.GraphQL().Get().
+	get := s.client.GraphQL().Get().
 		WithClassName(className).
 		WithFields(graphql.Field{
 			Name: "_additional { certainty id } content source type",
 		}).
 		WithNearVector(s.client.GraphQL().NearVectorArgBuilder().
 			WithVector(vector)).
-		WithWhere(whereFilter).
 		WithLimit(limit).
-		Do(ctx)
+		WithTenant(tenant)
+	if whereFilter != nil {
+		get = get.WithWhere(whereFilter)
+	}
+	var result *models.GraphQLResponse
+	err = s.withRetry(ctx, func() error {
+		var err error
+		result, err = get.Do(ctx)
+		return err
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute vector search: %w", err)
 	}
@@ -303,6 +494,18 @@ func (s *Store) SearchByVector(ctx context.Context, vector []float32, limit int,
 	return s.parseSearchResults(result, className)
 }
 
+// Ping checks that the Weaviate instance is reachable and ready
+func (s *Store) Ping(ctx context.Context) error {
+	ready, err := s.client.Misc().ReadyChecker().Do(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to reach Weaviate: %w", err)
+	}
+	if !ready {
+		return fmt.Errorf("weaviate is not ready")
+	}
+	return nil
+}
+
 // Delete removes documents from Weaviate
 func (s *Store) Delete(ctx context.Context, ids []string, options ...interfaces.DeleteOption) error {
 	// Apply options
@@ -312,16 +515,33 @@ func (s *Store) Delete(ctx context.Context, ids []string, options ...interfaces.
 	}
 
 	// Get class name
-	className, err := s.getClassName(ctx, opts.Class)
+	className, tenant, err := s.getClassName(ctx, opts.Class)
 	if err != nil {
 		return err
 	}
 
 	// Delete objects
+	userID := s.scopedUserID(ctx)
 	for _, id := range ids {
+		if userID != "" {
+			// Only delete documents owned by the user
+			result, err := s.client.Data().ObjectsGetter().
+				WithClassName(className).
+				WithID(id).
+				WithTenant(tenant).
+				Do(ctx)
+			if err != nil {
+				return fmt.Errorf("failed to get document %s: %w", id, err)
+			}
+			if len(result) == 0 || !ownedBy(result[0].Properties, userID) {
+				continue
+			}
+		}
+
 		if err := s.client.Data().Deleter().
 			WithClassName(className).
 			WithID(id).
+			WithTenant(tenant).
 			Do(ctx); err != nil {
 			return fmt.Errorf("failed to delete document %s: %w", id, err)
 		}
@@ -333,16 +553,22 @@ func (s *Store) Delete(ctx context.Context, ids []string, options ...interfaces.
 // Get retrieves documents by their IDs
 func (s *Store) Get(ctx context.Context, ids []string) ([]interfaces.Document, error) {
 	// Get class name (use default since we're getting by ID)
-	className, err := s.getClassName(ctx, "")
+	className, tenant, err := s.getClassName(ctx, "")
 	if err != nil {
 		return nil, err
 	}
+	return s.getObjects(ctx, className, tenant, ids)
+}
 
+// getObjects retrieves the documents with the IDs from a class, skipping
+// missing documents and those of other users
+func (s *Store) getObjects(ctx context.Context, className, tenant string, ids []string) ([]interfaces.Document, error) {
 	var documents []interfaces.Document
 	for _, id := range ids {
 		result, err := s.client.Data().ObjectsGetter().
 			WithClassName(className).
 			WithID(id).
+			WithTenant(tenant).
 			Do(ctx)
 
 		if err != nil {
@@ -352,6 +578,9 @@ func (s *Store) Get(ctx context.Context, ids []string) ([]interfaces.Document, e
 		if len(result) == 0 {
 			continue // Skip if document not found
 		}
+		if userID := s.scopedUserID(ctx); userID != "" && !ownedBy(result[0].Properties, userID) {
+			continue // Skip documents of other users
+		}
 
 		doc := interfaces.Document{
 			ID:       id,
@@ -417,9 +646,19 @@ func (s *Store) ensureClass(ctx context.Context, className string) error {
 				Name:     "content",
 				DataType: []string{"text"},
 			},
+			{
+				Name:     UserIDProperty,
+				DataType: []string{"text"},
+			},
 			// Add more default properties as needed
 		},
 	}
+	if s.nativeTenancy {
+		class.MultiTenancyConfig = &models.MultiTenancyConfig{
+			Enabled:              true,
+			AutoTenantActivation: true,
+		}
+	}
 
 	if err := s.client.Schema().ClassCreator().WithClass(class).Do(ctx); err != nil {
 		s.logger.Error(ctx, "Failed to create class", map[string]interface{}{"error": err.Error()})
@@ -430,6 +669,72 @@ func (s *Store) ensureClass(ctx context.Context, className string) error {
 	return nil
 }
 
+// ensureTenant creates the tenant in a multi-tenant class if it doesn't exist.
+// It does nothing for an empty tenant.
+func (s *Store) ensureTenant(ctx context.Context, className, tenant string) error {
+	if tenant == "" {
+		return nil
+	}
+
+	exists, err := s.client.Schema().TenantsExists().
+		WithClassName(className).
+		WithTenant(tenant).
+		Do(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to check tenant %s: %w", tenant, err)
+	}
+	if exists {
+		return nil
+	}
+
+	s.logger.Info(ctx, "Creating new tenant", map[string]interface{}{"className": className, "tenant": tenant})
+	if err := s.client.Schema().TenantsCreator().
+		WithClassName(className).
+		WithTenants(models.Tenant{Name: tenant}).
+		Do(ctx); err != nil {
+		return fmt.Errorf("failed to create tenant %s: %w", tenant, err)
+	}
+	return nil
+}
+
+// scopedUserID returns the user whose documents the context may see, or an
+// empty string if every document of the organization is visible
+func (s *Store) scopedUserID(ctx context.Context) string {
+	if !s.userScope {
+		return ""
+	}
+	return runctx.UserID(ctx)
+}
+
+// scopeToUser restricts a where filter to the documents of the user in the
+// context. The filter is returned unchanged if there is no user or the store
+// is not scoped to users.
+func (s *Store) scopeToUser(ctx context.Context, where *filters.WhereBuilder) *filters.WhereBuilder {
+	userID := s.scopedUserID(ctx)
+	if userID == "" {
+		return where
+	}
+
+	userFilter := filters.Where().
+		WithPath([]string{UserIDProperty}).
+		WithOperator(filters.Equal).
+		WithValueString(userID)
+	if where == nil {
+		return userFilter
+	}
+	return filters.Where().WithOperator(filters.And).WithOperands([]*filters.WhereBuilder{where, userFilter})
+}
+
+// ownedBy reports whether the object properties belong to the user
+func ownedBy(properties models.PropertySchema, userID string) bool {
+	props, ok := properties.(map[string]interface{})
+	if !ok {
+		return false
+	}
+	owner, _ := props[UserIDProperty].(string)
+	return owner == userID
+}
+
 func (s *Store) buildWhereFilter(filterMap map[string]interface{}) *filters.WhereBuilder {
 	if len(filterMap) == 0 {
 		return nil
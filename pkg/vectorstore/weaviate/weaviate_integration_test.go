@@ -0,0 +1,100 @@
+//go:build integration
+
+package weaviate_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/run-bigpig/llm-agent/internal/testsupport"
+	"github.com/run-bigpig/llm-agent/pkg/interfaces"
+	"github.com/run-bigpig/llm-agent/pkg/multitenancy"
+	"github.com/run-bigpig/llm-agent/pkg/runctx"
+	weaviatestore "github.com/run-bigpig/llm-agent/pkg/vectorstore/weaviate"
+)
+
+func newIntegrationStore(t *testing.T, options ...weaviatestore.Option) *weaviatestore.Store {
+	t.Helper()
+	options = append([]weaviatestore.Option{weaviatestore.WithEmbedder(&MockEmbedder{})}, options...)
+	store := weaviatestore.New(&interfaces.VectorStoreConfig{
+		Host:   testsupport.Weaviate(t),
+		Scheme: "http",
+	}, options...)
+	if store == nil {
+		t.Fatal("failed to create store")
+	}
+	return store
+}
+
+func TestIntegrationStoreSearchDelete(t *testing.T) {
+	store := newIntegrationStore(t)
+	ctx := multitenancy.WithOrgID(context.Background(), "org1")
+
+	docs := []interfaces.Document{
+		{ID: "00000000-0000-0000-0000-000000000001", Content: "north", Vector: []float32{1, 0, 0}, Metadata: map[string]interface{}{"type": "note"}},
+		{ID: "00000000-0000-0000-0000-000000000002", Content: "east", Vector: []float32{0, 1, 0}, Metadata: map[string]interface{}{"type": "article"}},
+		{ID: "00000000-0000-0000-0000-000000000003", Content: "up", Vector: []float32{0, 0, 1}, Metadata: map[string]interface{}{"type": "article"}},
+	}
+	if err := store.Store(ctx, docs); err != nil {
+		t.Fatalf("failed to store documents: %v", err)
+	}
+
+	results, err := store.SearchByVector(ctx, []float32{0.9, 0.1, 0}, 1)
+	if err != nil {
+		t.Fatalf("failed to search: %v", err)
+	}
+	if len(results) != 1 || results[0].Document.Content != "north" {
+		t.Fatalf("expected the nearest document to be found, got %+v", results)
+	}
+
+	results, err = store.SearchByVector(ctx, []float32{1, 0, 0}, 3, interfaces.WithFilter(interfaces.Eq("type", "article")))
+	if err != nil {
+		t.Fatalf("failed to search with filter: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("expected 2 articles, got %+v", results)
+	}
+
+	got, err := store.Get(ctx, []string{docs[1].ID})
+	if err != nil || len(got) != 1 || got[0].Content != "east" {
+		t.Fatalf("expected to get the document, got %+v (%v)", got, err)
+	}
+
+	if err := store.Delete(ctx, []string{docs[1].ID}); err != nil {
+		t.Fatalf("failed to delete document: %v", err)
+	}
+	if got, err := store.Get(ctx, []string{docs[1].ID}); err == nil && len(got) != 0 {
+		t.Fatalf("expected the document to be deleted, got %+v", got)
+	}
+
+	// Other organizations don't see the documents
+	other := multitenancy.WithOrgID(context.Background(), "org2")
+	if err := store.Store(other, []interfaces.Document{{ID: "00000000-0000-0000-0000-000000000004", Content: "other", Vector: []float32{1, 0, 0}}}); err != nil {
+		t.Fatalf("failed to store document of another organization: %v", err)
+	}
+	results, err = store.SearchByVector(other, []float32{1, 0, 0}, 10)
+	if err != nil {
+		t.Fatalf("failed to search other organization: %v", err)
+	}
+	if len(results) != 1 || results[0].Document.Content != "other" {
+		t.Fatalf("expected organizations to be isolated, got %+v", results)
+	}
+}
+
+func TestIntegrationNativeTenancyUserScoping(t *testing.T) {
+	store := newIntegrationStore(t, weaviatestore.WithNativeMultiTenancy(), weaviatestore.WithClassPrefix("Tenanted"))
+	ctx := multitenancy.WithOrgID(context.Background(), "org1")
+	alice := runctx.WithUserID(ctx, "alice")
+	bob := runctx.WithUserID(ctx, "bob")
+
+	if err := store.Store(alice, []interfaces.Document{{ID: "00000000-0000-0000-0000-00000000000a", Content: "alice's note", Vector: []float32{1, 0, 0}}}); err != nil {
+		t.Fatalf("failed to store document: %v", err)
+	}
+
+	if got, err := store.Get(bob, []string{"00000000-0000-0000-0000-00000000000a"}); err != nil || len(got) != 0 {
+		t.Fatalf("expected other users not to see the document, got %+v (%v)", got, err)
+	}
+	if got, err := store.Get(alice, []string{"00000000-0000-0000-0000-00000000000a"}); err != nil || len(got) != 1 {
+		t.Fatalf("expected the owner to see the document, got %+v (%v)", got, err)
+	}
+}